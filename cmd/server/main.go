@@ -20,6 +20,7 @@ import (
 	"github.com/intelifox/click-deploy/internal/k8s"
 	"github.com/intelifox/click-deploy/internal/migrate"
 	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/tracing"
 	"github.com/intelifox/click-deploy/internal/worker"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -32,6 +33,12 @@ func main() {
 		log.Fatal("Failed to load config:", err)
 	}
 
+	// Set up tracing (spans are only exported when OTLP_ENDPOINT is set)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		log.Fatal("Failed to set up tracing:", err)
+	}
+
 	// Connect to database with optimized connection pool
 	poolConfig := store.PoolConfig{
 		MaxOpenConns:    cfg.DBMaxOpenConns,
@@ -71,9 +78,11 @@ func main() {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
+	r.Use(api.TracingMiddleware)                       // Root span per request
+	r.Use(api.MaxBodySizeMiddleware(cfg.MaxRequestBodyBytes)) // 413 on oversized bodies
 	r.Use(api.CORSMiddlewareFromEnv(cfg.CORSOrigins)) // CORS support
 	r.Use(api.SecurityHeadersMiddleware)               // Security headers
-	r.Use(api.CompressionMiddleware)                   // Enable response compression
+	r.Use(api.CompressionMiddleware)                   // zstd/gzip response compression
 	
 	// Add panic recovery with detailed logging
 	r.Use(func(next http.Handler) http.Handler {
@@ -82,7 +91,9 @@ func main() {
 				if err := recover(); err != nil {
 					log.Printf("Panic recovered: %v\n", err)
 					// Log stack trace
-					log.Printf("Stack trace:\n%s", string(runtime.Stack(nil, false)))
+					buf := make([]byte, 4096)
+					n := runtime.Stack(buf, false)
+					log.Printf("Stack trace:\n%s", buf[:n])
 					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 				}
 			}()
@@ -166,6 +177,49 @@ func main() {
 	// Note: /auth/me is registered in RegisterCustomAuthRoutes with auth middleware
 	_ = customAuthHandler // Suppress unused warning if not using custom auth
 
+	// Initialize k8s clients (optional, used for deployments and live status).
+	// Everything about a project goes to the cluster of the project's region.
+	var k8sClients *k8s.ClientRegistry
+	if cfg.UseK8s {
+		k8sCfg := k8s.Config{
+			InCluster:          cfg.K8sInCluster,
+			KubeconfigPath:     cfg.K8sKubeconfigPath,
+			BaseDomain:         cfg.K8sBaseDomain,
+			IngressClass:       cfg.K8sIngressClass,
+			CertIssuer:         cfg.K8sCertIssuer,
+			NamespacePrefix:    "zyndra-",
+			ReservedSubdomains: cfg.ReservedSubdomainList(),
+			BlockedWords:       cfg.BlockedSubdomainWordList(),
+			StorageClass:       cfg.DefaultStorageClass,
+			RegistryMirror:     cfg.K8sRegistryMirror,
+		}
+		k8sClients = k8s.NewClientRegistry(cfg.K8sDefaultRegion)
+		for _, region := range cfg.K8sRegions() {
+			regionCfg := k8sCfg
+			if kubeconfig, ok := cfg.K8sRegionKubeconfigs[region]; ok {
+				regionCfg.InCluster = false
+				regionCfg.KubeconfigPath = kubeconfig
+			}
+
+			client, err := k8s.NewClient(regionCfg)
+			if err != nil {
+				log.Printf("Warning: Could not initialize k8s client for region %s: %v", region, err)
+				continue
+			}
+			metricsClient, err := k8s.NewMetricsClient(regionCfg)
+			if err != nil {
+				log.Printf("Warning: Could not initialize k8s metrics client for region %s: %v", region, err)
+			}
+			k8sClients.Register(region, client, metricsClient)
+		}
+		if len(k8sClients.Regions()) == 0 {
+			k8sClients = nil
+		}
+	}
+
+	// Initialize build worker (it will log errors if BuildKit is not available)
+	buildWorker, _ := worker.NewBuildWorker(db, cfg)
+
 	// API routes (require authentication)
 	r.Route("/v1/click-deploy", func(r chi.Router) {
 		// Apply authentication middleware to all API routes
@@ -174,46 +228,44 @@ func main() {
 		r.Use(api.PerUserRateLimitMiddleware(100, time.Minute))
 
 		// Projects endpoints
-		projectHandler := api.NewProjectHandler(db, cfg)
+		projectHandler := api.NewProjectHandler(db, cfg, k8sClients)
 		r.Get("/projects", projectHandler.ListProjects)
 		r.Post("/projects", projectHandler.CreateProject)
 		r.Get("/projects/{id}", projectHandler.GetProject)
 		r.Patch("/projects/{id}", projectHandler.UpdateProject)
 		r.Delete("/projects/{id}", projectHandler.DeleteProject)
+		r.Post("/projects/{id}/transfer", projectHandler.TransferProject)
 
 		// Services endpoints
-		serviceHandler := api.NewServiceHandler(db, cfg)
+		serviceHandler := api.NewServiceHandler(db, cfg, k8sClients)
 		r.Get("/projects/{id}/services", serviceHandler.ListServices)
+		r.Get("/projects/{id}/services/status", serviceHandler.ListServiceStatuses)
+		r.Get("/projects/{id}/overview", serviceHandler.GetProjectOverview)
 		r.Post("/projects/{id}/services", serviceHandler.CreateService)
+		r.Post("/projects/{id}/services/batch", serviceHandler.CreateServices)
+		r.Post("/projects/{id}/services/import", serviceHandler.ImportService)
 		r.Get("/services/{id}", serviceHandler.GetService)
 		r.Patch("/services/{id}", serviceHandler.UpdateService)
 		r.Patch("/services/{id}/position", serviceHandler.UpdateServicePosition)
+		r.Get("/services/{id}/events", serviceHandler.ListServiceEvents)
+		r.Get("/services/{id}/logs", serviceHandler.GetServiceLogs)
+		r.Get("/services/{id}/runtime-config", serviceHandler.GetServiceRuntimeConfig)
+		r.Post("/services/{id}/subdomain", serviceHandler.UpdateServiceSubdomain)
+		r.Get("/services/{id}/scaling-schedule", serviceHandler.GetScalingSchedule)
+		r.Put("/services/{id}/scaling-schedule", serviceHandler.ReplaceScalingSchedule)
 		r.Delete("/services/{id}", serviceHandler.DeleteService)
 
+		// Organization membership endpoints
+		api.RegisterOrgRoutes(r, db, cfg)
+
 		// Git endpoints
 		api.RegisterGitRoutes(r, db, cfg)
 
-		// Initialize k8s client and build worker for deployments
-		var k8sClient *k8s.Client
-		var buildWorker *worker.BuildWorker
-		
-		if cfg.UseK8s {
-			k8sCfg := k8s.Config{
-				InCluster:       cfg.K8sInCluster,
-				KubeconfigPath:  cfg.K8sKubeconfigPath,
-				BaseDomain:      cfg.K8sBaseDomain,
-			}
-			k8sClient, _ = k8s.NewClient(k8sCfg)
-		}
-		
-		// Initialize build worker (it will log errors if BuildKit is not available)
-		buildWorker, _ = worker.NewBuildWorker(db, cfg)
-		
 		// Deployment endpoints
-		api.RegisterDeploymentRoutes(r, db, cfg, buildWorker, k8sClient)
+		api.RegisterDeploymentRoutes(r, db, cfg, buildWorker, k8sClients)
 
 		// Database endpoints
-		api.RegisterDatabaseRoutes(r, db, cfg)
+		api.RegisterDatabaseRoutes(r, db, cfg, k8sClients)
 
 		// Volume endpoints
 		api.RegisterVolumeRoutes(r, db, cfg)
@@ -228,38 +280,45 @@ func main() {
 		api.RegisterRollbackRoutes(r, db, cfg)
 
 		// Custom domain endpoints
-		api.RegisterCustomDomainRoutes(r, db, cfg)
+		api.RegisterCustomDomainRoutes(r, db, cfg, k8sClients)
 
 		// Pending changes endpoints
-		api.RegisterPendingChangesRoutes(r, db, cfg)
+		api.RegisterPendingChangesRoutes(r, db, cfg, buildWorker, k8sClients)
 
 		// Metrics endpoints (k8s metrics client is optional)
-		var metricsClient *k8s.MetricsClient
-		if cfg.UseK8s {
-			k8sCfg := k8s.Config{
-				InCluster:       cfg.K8sInCluster,
-				KubeconfigPath:  cfg.K8sKubeconfigPath,
-				BaseDomain:      cfg.K8sBaseDomain,
-				IngressClass:    cfg.K8sIngressClass,
-				CertIssuer:      cfg.K8sCertIssuer,
-				NamespacePrefix: "zyndra-",
-			}
-			var err error
-			metricsClient, err = k8s.NewMetricsClient(k8sCfg)
-			if err != nil {
-				log.Printf("Warning: Could not initialize k8s metrics client: %v", err)
-			}
-		}
-		api.RegisterMetricsRoutes(r, db, cfg, metricsClient)
+		api.RegisterMetricsRoutes(r, db, cfg, k8sClients)
+
+		// Usage aggregation endpoints (billing)
+		api.RegisterUsageRoutes(r, db, cfg)
+
+		// Background job status endpoints
+		api.RegisterJobRoutes(r, db, cfg)
 	})
 
 	// Webhook endpoints (public, but validated via signature)
-	api.RegisterWebhookRoutes(r, db, cfg)
+	api.RegisterWebhookRoutes(r, db, cfg, buildWorker, k8sClients)
+
+	// Background jobs
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	go worker.NewDomainVerificationWorker(db, cfg).Start(bgCtx)
+	go worker.NewDomainActivationWorker(db, cfg, k8sClients).Start(bgCtx)
+	go worker.NewCustomDomainRouteWorker(db, cfg).Start(bgCtx)
+	go worker.NewImageRetentionWorker(db, cfg).Start(bgCtx)
+	go worker.NewDeployQueueWorker(db, cfg, buildWorker, k8sClients).Start(bgCtx)
+	go worker.NewOrphanReconcileWorker(db, cfg).Start(bgCtx)
+	go worker.NewScalingScheduleWorker(db, k8sClients).Start(bgCtx)
+	go worker.NewLiveStatusWorker(db, cfg, k8sClients).Start(bgCtx)
+	go worker.NewServiceStatusReconcileWorker(db, cfg, k8sClients).Start(bgCtx)
 
 	// Start server
 	srv := &http.Server{
-		Addr:    ":" + cfg.Port,
-		Handler: r,
+		Addr:              ":" + cfg.Port,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout, // Guards against slowloris
+		ReadTimeout:       cfg.ReadTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
 	}
 
 	// Graceful shutdown
@@ -277,6 +336,7 @@ func main() {
 	<-quit
 
 	fmt.Println("Shutting down server...")
+	stopBackground()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -285,6 +345,10 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
+
 	fmt.Println("Server exited")
 }
 
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// Access policy
+//
+// 404 NOT_FOUND: the resource belongs to another org. The response is the same as
+// for a resource that doesn't exist, so IDs can't be probed across orgs. Ownership
+// helpers (getOwnedService, getOwnedDeployment, ...) check this with checkOrgAccess.
+//
+// 403 FORBIDDEN: the resource belongs to the caller's org, but the caller's role
+// doesn't allow the action. The caller can already see the resource, so the message
+// says which role is needed. Checked with requireRole, always after checkOrgAccess:
+// a caller outside the org gets a 404 whatever their role.
+//
+// Checks on the state of the resource (e.g. 409 when a deployment isn't awaiting
+// approval) come after both.
+//
+// Organization endpoints follow the same policy: a caller who isn't a member gets a
+// 404 for the organization, a member without the role a 403.
+
+// checkOrgAccess checks the project a resource belongs to is one of the caller's org.
+// It writes a 404 for the resource and returns false when it isn't, or when there
+// is no project.
+func checkOrgAccess(w http.ResponseWriter, project *store.Project, orgID, resource string) bool {
+	if project == nil || !project.BelongsToOrg(orgID) {
+		WriteError(w, domain.NewNotFoundError(resource))
+		return false
+	}
+	return true
+}
+
+// requestUser returns the caller, recorded as the creator or last editor of what the
+// request creates or changes. NULL when the request has no user.
+func requestUser(r *http.Request) sql.NullString {
+	return store.StringToNullString(auth.GetUserID(r.Context()))
+}
+
+// requireRole checks the caller holds one of roles in their org. It writes a 403
+// saying which roles can perform action and returns false when they don't.
+func requireRole(w http.ResponseWriter, r *http.Request, action string, roles ...string) bool {
+	if hasRole(r.Context(), roles...) {
+		return true
+	}
+	WriteError(w, domain.NewForbiddenError(fmt.Sprintf("%s requires the %s role", action, strings.Join(roles, " or "))))
+	return false
+}
+
+// hasRole reports whether the caller holds one of roles
+func hasRole(ctx context.Context, roles ...string) bool {
+	for _, role := range auth.GetRoles(ctx) {
+		for _, allowed := range roles {
+			if role == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestAccessPolicy_NotFoundVsForbidden(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	handler := NewDeploymentHandler(dbStore, &config.Config{}, nil, nil)
+	serviceHandler := NewServiceHandler(dbStore, &config.Config{}, nil)
+
+	orgID := "test-org-access"
+	ctx := testutil.MockAuthContext(context.Background(), "requester", orgID)
+	project := &store.Project{
+		Name:              "Test Project",
+		Slug:              "test-project",
+		CasdoorOrgID:      orgID,
+		OpenStackTenantID: "test-tenant-123",
+	}
+	if err := dbStore.CreateProject(ctx, project); err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+	service := &store.Service{
+		ProjectID:        project.ID,
+		Name:             "Production API",
+		Type:             "app",
+		Status:           "pending",
+		InstanceSize:     "medium",
+		Port:             8080,
+		RequiresApproval: true,
+	}
+	if err := dbStore.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+
+	newDeployment := func(status string) string {
+		d := &store.Deployment{
+			ServiceID:   service.ID,
+			Status:      status,
+			TriggeredBy: "manual",
+			RequestedBy: store.StringToNullString("requester"),
+		}
+		if err := dbStore.CreateDeployment(ctx, d); err != nil {
+			t.Fatalf("Failed to create test deployment: %v", err)
+		}
+		return d.ID.String()
+	}
+	awaiting := newDeployment(deploymentStatusAwaitingApproval)
+	approved := newDeployment("success")
+
+	// send calls a handler as a user of callerOrgID with the given roles
+	send := func(h http.HandlerFunc, id, callerOrgID string, roles []string) *httptest.ResponseRecorder {
+		req, _ := testutil.MockRequestWithURLParamAndAuth(t, "POST", "/v1/click-deploy/deployments/"+id,
+			map[string]string{"id": id}, bytes.NewReader([]byte("{}")), "reviewer", callerOrgID)
+		req = req.WithContext(context.WithValue(req.Context(), auth.RolesKey, roles))
+		w := testutil.MockResponseRecorder()
+		h(w, req)
+		return w
+	}
+
+	admin := []string{"admin"}
+	member := []string{"member"}
+	tests := []struct {
+		name         string
+		handler      http.HandlerFunc
+		id           string
+		orgID        string
+		roles        []string
+		expectedCode domain.ErrorCode
+		expectedHTTP int
+	}{
+		// Another org's resources look like they don't exist, whatever the caller's role
+		{"other org admin approves", handler.ApproveDeployment, awaiting, "other-org", admin, domain.ErrCodeNotFound, http.StatusNotFound},
+		{"other org reads service", serviceHandler.GetService, service.ID.String(), "other-org", admin, domain.ErrCodeNotFound, http.StatusNotFound},
+		{"unknown deployment", handler.ApproveDeployment, project.ID.String(), orgID, admin, domain.ErrCodeNotFound, http.StatusNotFound},
+		// Within the org, a missing role is reported as such
+		{"member approves", handler.ApproveDeployment, awaiting, orgID, member, domain.ErrCodeForbidden, http.StatusForbidden},
+		{"member rejects", handler.RejectDeployment, awaiting, orgID, member, domain.ErrCodeForbidden, http.StatusForbidden},
+		// The role is checked before the state of the deployment
+		{"member approves finished deployment", handler.ApproveDeployment, approved, orgID, member, domain.ErrCodeForbidden, http.StatusForbidden},
+		{"admin approves finished deployment", handler.ApproveDeployment, approved, orgID, admin, domain.ErrCodeConflict, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := send(tt.handler, tt.id, tt.orgID, tt.roles)
+			if w.Code != tt.expectedHTTP {
+				t.Fatalf("Expected status %d, got %d. Response: %s", tt.expectedHTTP, w.Code, w.Body.String())
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode error response: %v", err)
+			}
+			if resp.Error != tt.expectedCode {
+				t.Errorf("Expected error code %s, got %s", tt.expectedCode, resp.Error)
+			}
+		})
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/intelifox/click-deploy/internal/auth"
 	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/email"
 	"github.com/intelifox/click-deploy/internal/store"
 )
 
@@ -17,6 +19,7 @@ type CustomAuthHandler struct {
 	db         *store.DB
 	jwtService *auth.JWTService
 	config     *config.Config
+	mailer     *email.MailtrapClient // Sends login alerts; nil when email isn't configured
 }
 
 // NewCustomAuthHandler creates a new custom auth handler
@@ -28,11 +31,17 @@ func NewCustomAuthHandler(db *store.DB, cfg *config.Config) *CustomAuthHandler {
 	if cfg.JWTRefreshExpiry > 0 {
 		jwtConfig.RefreshExpiry = cfg.JWTRefreshExpiry
 	}
-	
+
+	var mailer *email.MailtrapClient
+	if cfg.MailtrapAPIToken != "" {
+		mailer = email.NewMailtrapClient(cfg.MailtrapAPIToken, cfg.MailtrapSenderEmail, cfg.MailtrapSenderName)
+	}
+
 	return &CustomAuthHandler{
 		db:         db,
 		jwtService: auth.NewJWTService(jwtConfig),
 		config:     cfg,
+		mailer:     mailer,
 	}
 }
 
@@ -81,6 +90,15 @@ type OrgResponse struct {
 	Role string `json:"role"`
 }
 
+// sessionInfo returns the device a request starts a session from. Its IP is the
+// client's, without port, as the trusted proxies in cfg forwarded it.
+func sessionInfo(r *http.Request, cfg *config.Config) store.SessionInfo {
+	return store.SessionInfo{
+		UserAgent: r.UserAgent(),
+		IPAddress: clientIP(r, cfg),
+	}
+}
+
 // Register handles user registration
 func (h *CustomAuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
@@ -140,7 +158,8 @@ func (h *CustomAuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate tokens
-	tokenPair, err := h.jwtService.GenerateTokenPair(user.ID, user.Email, user.Name, org.ID, org.Slug, "owner")
+	sessionID := uuid.NewString()
+	tokenPair, err := h.jwtService.GenerateTokenPair(user.ID, user.Email, user.Name, org.ID, org.Slug, "owner", sessionID)
 	if err != nil {
 		http.Error(w, "Failed to generate tokens", http.StatusInternalServerError)
 		return
@@ -148,7 +167,7 @@ func (h *CustomAuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	// Store refresh token
 	expiresAt := time.Now().Add(h.jwtService.RefreshExpiry())
-	_, err = h.db.CreateRefreshToken(r.Context(), user.ID, tokenPair.RefreshToken, expiresAt)
+	_, err = h.db.CreateRefreshToken(r.Context(), sessionID, user.ID, tokenPair.RefreshToken, expiresAt, sessionInfo(r, h.config))
 	if err != nil {
 		http.Error(w, "Failed to store refresh token", http.StatusInternalServerError)
 		return
@@ -194,18 +213,27 @@ func (h *CustomAuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Refuse while the email or the client is locked out from too many failures
+	if h.checkLoginLocked(w, r, req.Email) {
+		return
+	}
+
 	// Get user by email
 	user, err := h.db.GetUserByEmail(r.Context(), req.Email)
 	if err != nil {
+		h.recordLoginFailure(r, req.Email, nil)
 		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
 		return
 	}
 
 	// Check password
 	if !auth.CheckPassword(req.Password, user.PasswordHash) {
+		h.recordLoginFailure(r, req.Email, user)
 		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
 		return
 	}
+	h.resetLoginFailures(r, req.Email)
+	h.checkNewSignIn(r, user)
 
 	// Get user's organizations
 	orgs, err := h.db.ListUserOrganizations(r.Context(), user.ID)
@@ -229,7 +257,8 @@ func (h *CustomAuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		orgSlug = org.Slug
 	}
 	
-	tokenPair, err := h.jwtService.GenerateTokenPair(user.ID, user.Email, user.Name, orgID, orgSlug, role)
+	sessionID := uuid.NewString()
+	tokenPair, err := h.jwtService.GenerateTokenPair(user.ID, user.Email, user.Name, orgID, orgSlug, role, sessionID)
 	if err != nil {
 		http.Error(w, "Failed to generate tokens", http.StatusInternalServerError)
 		return
@@ -237,7 +266,7 @@ func (h *CustomAuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	// Store refresh token
 	expiresAt := time.Now().Add(h.jwtService.RefreshExpiry())
-	_, err = h.db.CreateRefreshToken(r.Context(), user.ID, tokenPair.RefreshToken, expiresAt)
+	_, err = h.db.CreateRefreshToken(r.Context(), sessionID, user.ID, tokenPair.RefreshToken, expiresAt, sessionInfo(r, h.config))
 	if err != nil {
 		http.Error(w, "Failed to store refresh token", http.StatusInternalServerError)
 		return
@@ -320,15 +349,16 @@ func (h *CustomAuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		orgSlug = org.Slug
 	}
 	
-	tokenPair, err := h.jwtService.GenerateTokenPair(user.ID, user.Email, user.Name, orgID, orgSlug, role)
+	// The session carries on with the new tokens
+	tokenPair, err := h.jwtService.GenerateTokenPair(user.ID, user.Email, user.Name, orgID, orgSlug, role, rt.ID)
 	if err != nil {
 		http.Error(w, "Failed to generate tokens", http.StatusInternalServerError)
 		return
 	}
 
-	// Rotate refresh token (revoke old, create new)
+	// Rotate refresh token (the old one stops working)
 	expiresAt := time.Now().Add(h.jwtService.RefreshExpiry())
-	_, err = h.db.RotateRefreshToken(r.Context(), req.RefreshToken, tokenPair.RefreshToken, user.ID, expiresAt)
+	_, err = h.db.RotateRefreshToken(r.Context(), req.RefreshToken, tokenPair.RefreshToken, expiresAt)
 	if err != nil {
 		http.Error(w, "Failed to rotate refresh token", http.StatusInternalServerError)
 		return
@@ -456,6 +486,11 @@ func RegisterCustomAuthRoutes(r chi.Router, db *store.DB, cfg *config.Config, au
 		r.Group(func(r chi.Router) {
 			r.Use(auth.Middleware(authValidator))
 			r.Get("/me", handler.Me)
+			r.Get("/sessions", handler.ListSessions)
+			r.Delete("/sessions", handler.RevokeOtherSessions)
+			r.Delete("/sessions/{id}", handler.RevokeSession)
+			r.Get("/login-alerts", handler.GetLoginAlerts)
+			r.Put("/login-alerts", handler.UpdateLoginAlerts)
 		})
 	})
 
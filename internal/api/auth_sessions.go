@@ -0,0 +1,118 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+)
+
+// SessionResponse is one of the user's sessions, a device holding a refresh token
+type SessionResponse struct {
+	ID         string     `json:"id"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	IPAddress  string     `json:"ip_address,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Current    bool       `json:"current"` // The session of the calling access token
+}
+
+// ListSessions handles GET /auth/sessions
+// Lists the caller's active sessions, most recently used first.
+func (h *CustomAuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.db.ListUserSessions(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	currentID := auth.GetSessionID(r.Context())
+	resp := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		session := SessionResponse{
+			ID:        s.ID,
+			UserAgent: s.UserAgent.String,
+			IPAddress: s.IPAddress.String,
+			CreatedAt: s.CreatedAt,
+			ExpiresAt: s.ExpiresAt,
+			Current:   s.ID == currentID,
+		}
+		if s.LastUsedAt.Valid {
+			session.LastUsedAt = &s.LastUsedAt.Time
+		}
+		resp = append(resp, session)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newListResponse(resp))
+}
+
+// RevokeSession handles DELETE /auth/sessions/:id
+// Revokes one of the caller's sessions; its refresh token stops working. Access
+// tokens already issued to it stay valid until they expire.
+func (h *CustomAuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.RevokeUserSession(r.Context(), userID, sessionID.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions handles DELETE /auth/sessions
+// Revokes all of the caller's sessions but the current one. A token that names no
+// session, issued before sessions were tracked, keeps none.
+func (h *CustomAuthHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	currentID, err := uuid.Parse(auth.GetSessionID(r.Context()))
+	if err != nil {
+		if err := h.db.RevokeAllUserRefreshTokens(r.Context(), userID); err != nil {
+			http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if _, err := h.db.RevokeOtherUserSessions(r.Context(), userID, currentID.String()); err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/intelifox/click-deploy/internal/domain"
+)
+
+// limitedBody is a request body capped by MaxBodySizeMiddleware. It remembers
+// the original body so a route-specific limit can replace the default one,
+// and whether a read went past the limit.
+type limitedBody struct {
+	io.ReadCloser
+	orig     io.ReadCloser
+	limit    int64
+	declared int64 // Content-Length of the request, -1 when unknown
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	// A body declared larger than the limit is refused on the first read rather than
+	// up front, so a route-specific limit applied further in still gets to replace it
+	if b.declared > b.limit {
+		b.exceeded = true
+		return 0, &http.MaxBytesError{Limit: b.limit}
+	}
+	n, err := b.ReadCloser.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// bodyLimitWriter turns the client error a handler writes after reading past the
+// body limit (usually a 400 "invalid body") into a 413
+type bodyLimitWriter struct {
+	http.ResponseWriter
+	body     *limitedBody
+	limit    int64
+	rejected bool
+}
+
+func (w *bodyLimitWriter) WriteHeader(statusCode int) {
+	if w.body.exceeded && statusCode >= 400 && statusCode < 500 && statusCode != http.StatusRequestEntityTooLarge {
+		w.rejected = true
+		writeAppError(w.ResponseWriter, payloadTooLargeError(w.limit))
+		return
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *bodyLimitWriter) Write(b []byte) (int, error) {
+	// Drop the handler's own error body once the 413 has been written
+	if w.rejected {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *bodyLimitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// MaxBodySizeMiddleware limits request bodies to limit bytes and answers 413 when
+// a client sends more, or declares more in its Content-Length. Applied again on a
+// sub-router or route, the inner limit replaces the outer one.
+func MaxBodySizeMiddleware(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit <= 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			orig := r.Body
+			if lb, ok := orig.(*limitedBody); ok {
+				orig = lb.orig
+			}
+			body := &limitedBody{ReadCloser: http.MaxBytesReader(w, orig, limit), orig: orig, limit: limit, declared: r.ContentLength}
+			r.Body = body
+
+			next.ServeHTTP(&bodyLimitWriter{ResponseWriter: w, body: body, limit: limit}, r)
+		})
+	}
+}
+
+// payloadTooLargeError describes the body size limit that was exceeded
+func payloadTooLargeError(limit int64) *domain.AppError {
+	return domain.NewPayloadTooLargeError(fmt.Sprintf("Request body must not exceed %d bytes", limit))
+}
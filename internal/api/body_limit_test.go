@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// decodeHandler mimics a handler that answers 400 on any decode error
+func decodeHandler(w http.ResponseWriter, r *http.Request) {
+	var body map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestMaxBodySizeMiddleware(t *testing.T) {
+	large := `{"value":"` + strings.Repeat("x", 100) + `"}`
+
+	tests := []struct {
+		name       string
+		limit      int64
+		body       string
+		chunked    bool
+		wantStatus int
+	}{
+		{name: "within limit", limit: 1024, body: large, wantStatus: http.StatusOK},
+		{name: "content length over limit", limit: 32, body: large, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "chunked body over limit", limit: 32, body: large, chunked: true, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "malformed body within limit", limit: 1024, body: "{", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+				req.Body = io.NopCloser(strings.NewReader(tt.body))
+			}
+			rec := httptest.NewRecorder()
+
+			MaxBodySizeMiddleware(tt.limit)(http.HandlerFunc(decodeHandler)).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusRequestEntityTooLarge && !strings.Contains(rec.Body.String(), "PAYLOAD_TOO_LARGE") {
+				t.Errorf("body = %q, want PAYLOAD_TOO_LARGE error", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestMaxBodySizeMiddleware_InnerLimitReplacesOuter(t *testing.T) {
+	body := `{"value":"` + strings.Repeat("x", 100) + `"}`
+
+	tests := []struct {
+		name       string
+		outer      int64
+		inner      int64
+		chunked    bool
+		wantStatus int
+	}{
+		{name: "larger inner limit", outer: 32, inner: 1024, wantStatus: http.StatusOK},
+		{name: "larger inner limit chunked", outer: 32, inner: 1024, chunked: true, wantStatus: http.StatusOK},
+		{name: "smaller inner limit", outer: 1024, inner: 32, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "smaller inner limit chunked", outer: 1024, inner: 32, chunked: true, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			if tt.chunked {
+				req.ContentLength = -1
+				req.Body = io.NopCloser(strings.NewReader(body))
+			}
+			rec := httptest.NewRecorder()
+
+			handler := MaxBodySizeMiddleware(tt.outer)(MaxBodySizeMiddleware(tt.inner)(http.HandlerFunc(decodeHandler)))
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
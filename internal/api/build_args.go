@@ -0,0 +1,239 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/secrets"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// CreateBuildArgRequest represents a request to create a build arg or secret
+type CreateBuildArgRequest struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	IsSecret bool   `json:"is_secret,omitempty"` // Mounted into RUN steps instead of passed as --build-arg
+}
+
+// UpdateBuildArgRequest represents a request to update a build arg or secret
+type UpdateBuildArgRequest struct {
+	Value    *string `json:"value,omitempty"`
+	IsSecret *bool   `json:"is_secret,omitempty"`
+}
+
+// BuildArgResponse represents a build arg in API responses. Secret values are
+// never returned.
+type BuildArgResponse struct {
+	ID        string `json:"id"`
+	ServiceID string `json:"service_id"`
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	IsSecret  bool   `json:"is_secret"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+func toBuildArgResponse(a *store.BuildArg) BuildArgResponse {
+	resp := BuildArgResponse{
+		ID:        a.ID.String(),
+		ServiceID: a.ServiceID.String(),
+		Key:       a.Key,
+		Value:     a.Value,
+		IsSecret:  a.IsSecret,
+		CreatedAt: a.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: a.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if a.IsSecret {
+		resp.Value = "***"
+	}
+	return resp
+}
+
+// ListBuildArgs handles GET /services/:id/build-args
+func (h *EnvVarHandler) ListBuildArgs(w http.ResponseWriter, r *http.Request) {
+	serviceID, ok := h.getOwnedServiceID(w, r)
+	if !ok {
+		return
+	}
+
+	args, err := h.store.ListBuildArgsByService(r.Context(), serviceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	response := make([]BuildArgResponse, 0, len(args))
+	for _, a := range args {
+		response = append(response, toBuildArgResponse(a))
+	}
+
+	WriteJSON(w, http.StatusOK, newListResponse(response))
+}
+
+// CreateBuildArg handles POST /services/:id/build-args
+func (h *EnvVarHandler) CreateBuildArg(w http.ResponseWriter, r *http.Request) {
+	serviceID, ok := h.getOwnedServiceID(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateBuildArgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body"))
+		return
+	}
+	// Values are stored verbatim, like env var values
+	req.Key = SanitizeEnvironmentVariableKey(req.Key)
+
+	if validationErrs := ValidateCreateBuildArgRequest(&req); validationErrs.HasErrors() {
+		WriteError(w, validationErrs.ToAppError())
+		return
+	}
+
+	existing, err := h.store.GetBuildArgByKey(r.Context(), serviceID, req.Key)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if existing != nil {
+		WriteError(w, domain.NewConflictError("Build arg already exists for this key"))
+		return
+	}
+
+	arg := &store.BuildArg{
+		ServiceID: serviceID,
+		Key:       req.Key,
+		IsSecret:  req.IsSecret,
+	}
+	if err := h.setBuildArgValue(arg, req.Value); err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	if err := h.store.CreateBuildArg(r.Context(), arg); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteCreated(w, toBuildArgResponse(arg))
+}
+
+// UpdateBuildArg handles PATCH /services/:id/build-args/:key
+// Turning an arg into a secret, or back, needs the value again: the stored value
+// of a secret can't be read back.
+func (h *EnvVarHandler) UpdateBuildArg(w http.ResponseWriter, r *http.Request) {
+	serviceID, ok := h.getOwnedServiceID(w, r)
+	if !ok {
+		return
+	}
+
+	arg, err := h.store.GetBuildArgByKey(r.Context(), serviceID, chi.URLParam(r, "key"))
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if arg == nil {
+		WriteError(w, domain.NewNotFoundError("Build arg"))
+		return
+	}
+
+	var req UpdateBuildArgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	if validationErrs := ValidateUpdateBuildArgRequest(&req); validationErrs.HasErrors() {
+		WriteError(w, validationErrs.ToAppError())
+		return
+	}
+
+	if req.IsSecret != nil && *req.IsSecret != arg.IsSecret && req.Value == nil {
+		WriteError(w, domain.NewValidationError("value is required when changing is_secret"))
+		return
+	}
+
+	if req.IsSecret != nil {
+		arg.IsSecret = *req.IsSecret
+	}
+	if req.Value != nil {
+		if err := h.setBuildArgValue(arg, *req.Value); err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+
+	if err := h.store.UpdateBuildArg(r.Context(), arg.ID, arg); err != nil {
+		if err == sql.ErrNoRows {
+			WriteError(w, domain.NewNotFoundError("Build arg"))
+			return
+		}
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	// Read back the updated timestamp
+	updated, err := h.store.GetBuildArgByKey(r.Context(), serviceID, arg.Key)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if updated == nil {
+		WriteError(w, domain.NewNotFoundError("Build arg"))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, toBuildArgResponse(updated))
+}
+
+// DeleteBuildArg handles DELETE /services/:id/build-args/:key
+func (h *EnvVarHandler) DeleteBuildArg(w http.ResponseWriter, r *http.Request) {
+	serviceID, ok := h.getOwnedServiceID(w, r)
+	if !ok {
+		return
+	}
+
+	arg, err := h.store.GetBuildArgByKey(r.Context(), serviceID, chi.URLParam(r, "key"))
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if arg == nil {
+		WriteError(w, domain.NewNotFoundError("Build arg"))
+		return
+	}
+
+	if err := h.store.DeleteBuildArg(r.Context(), arg.ID); err != nil {
+		if err == sql.ErrNoRows {
+			WriteError(w, domain.NewNotFoundError("Build arg"))
+			return
+		}
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteNoContent(w)
+}
+
+// setBuildArgValue sets the value to store for a build arg, encrypting secrets
+func (h *EnvVarHandler) setBuildArgValue(arg *store.BuildArg, value string) error {
+	if !arg.IsSecret {
+		arg.Value = value
+		return nil
+	}
+
+	cipher, err := secrets.NewCipher(h.config.EncryptionKey)
+	if err != nil {
+		return domain.ErrInternal.WithError(err)
+	}
+	encrypted, err := cipher.Encrypt(value)
+	if err != nil {
+		return domain.ErrInternal.WithError(err)
+	}
+	arg.Value = encrypted
+	return nil
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/intelifox/click-deploy/internal/config"
+)
+
+// clientIP returns the IP of the client of r, only taken from forwarding headers
+// set by the configured trusted proxies
+func clientIP(r *http.Request, cfg *config.Config) string {
+	var trustedProxies []string
+	if cfg != nil {
+		trustedProxies = cfg.TrustedProxyList()
+	}
+	return trustedClientIP(r, trustedProxies)
+}
+
+// trustedClientIP returns the IP of the client of r, without port. The forwarding
+// headers are only believed when the peer is one of trustedProxies, IPs or CIDRs:
+// X-Forwarded-For is then read from the right, past the trusted proxies, since
+// anything left of them may have been sent by the client itself.
+func trustedClientIP(r *http.Request, trustedProxies []string) string {
+	peer, ok := parseIP(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	trusted := parseTrustedProxies(trustedProxies)
+	if !isTrustedProxy(peer, trusted) {
+		return peer.String()
+	}
+
+	forwardedFor := r.Header.Values("X-Forwarded-For")
+	if len(forwardedFor) == 0 {
+		if ip, ok := parseIP(r.Header.Get("X-Real-IP")); ok {
+			return ip.String()
+		}
+		return peer.String()
+	}
+
+	client := peer
+	forwarded := strings.Split(strings.Join(forwardedFor, ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip, ok := parseIP(forwarded[i])
+		if !ok {
+			break
+		}
+		client = ip
+		if !isTrustedProxy(ip, trusted) {
+			return ip.String()
+		}
+	}
+	// Every hop is a trusted proxy, or the one before them isn't an IP
+	return client.String()
+}
+
+// parseIP parses an IP that may carry a port, as in RemoteAddr
+func parseIP(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	ip, err := netip.ParseAddr(strings.Trim(value, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
+
+// parseTrustedProxies parses IPs and CIDRs, skipping invalid ones
+func parseTrustedProxies(values []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if ip, err := netip.ParseAddr(value); err == nil {
+			ip = ip.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+		}
+	}
+	return prefixes
+}
+
+func isTrustedProxy(ip netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
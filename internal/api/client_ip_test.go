@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/config"
+)
+
+func TestTrustedClientIP(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "192.0.2.1"}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		want         string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:52344", want: "203.0.113.7"},
+		{name: "direct IPv6 client", remoteAddr: "[2001:db8::1]:52344", want: "2001:db8::1"},
+		{name: "headers of an untrusted peer are ignored", remoteAddr: "203.0.113.7:52344", forwardedFor: []string{"198.51.100.1"}, realIP: "198.51.100.2", want: "203.0.113.7"},
+		{name: "forwarded by a trusted proxy", remoteAddr: "10.1.2.3:443", forwardedFor: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "spoofed hops left of the client", remoteAddr: "10.1.2.3:443", forwardedFor: []string{"1.1.1.1, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "chain of trusted proxies", remoteAddr: "10.1.2.3:443", forwardedFor: []string{"1.1.1.1", "198.51.100.1, 192.0.2.1, 10.9.9.9"}, want: "198.51.100.1"},
+		{name: "forwarded with a port", remoteAddr: "10.1.2.3:443", forwardedFor: []string{"198.51.100.1:61000"}, want: "198.51.100.1"},
+		{name: "real IP of a trusted proxy", remoteAddr: "192.0.2.1:443", realIP: "198.51.100.3", want: "198.51.100.3"},
+		{name: "garbage before the proxies", remoteAddr: "10.1.2.3:443", forwardedFor: []string{"not-an-ip, 10.4.4.4"}, want: "10.4.4.4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodPost, "/auth/login", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := trustedClientIP(r, trusted); got != tt.want {
+				t.Errorf("trustedClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionInfo_IPWithoutPort(t *testing.T) {
+	cfg := &config.Config{TrustedProxies: "10.0.0.0/8"}
+
+	// New connections of one client come from different ports, a known IP must stay known
+	var ips []string
+	for _, remoteAddr := range []string{"203.0.113.7:52344", "203.0.113.7:52345"} {
+		r, _ := http.NewRequest(http.MethodPost, "/auth/login", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-For", "198.51.100.1")
+		ips = append(ips, sessionInfo(r, cfg).IPAddress)
+	}
+	if ips[0] != "203.0.113.7" || ips[1] != ips[0] {
+		t.Errorf("session IPs = %v, want 203.0.113.7 for both", ips)
+	}
+}
@@ -3,49 +3,278 @@ package api
 import (
 	"compress/gzip"
 	"io"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressionMinSize is the smallest response body worth compressing
+const compressionMinSize = 1024
+
+// compressor is the common interface of the gzip and zstd writers
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var (
+	gzipPool = sync.Pool{New: func() interface{} {
+		gz, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return gz
+	}}
+	zstdPool = sync.Pool{New: func() interface{} {
+		// API responses are small, so a 1MB window keeps pooled encoders light
+		// and stays well within what browsers accept
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithWindowSize(1<<20))
+		return enc
+	}}
 )
 
-// CompressionMiddleware compresses HTTP responses using gzip
-// This reduces bandwidth usage and improves response times for large payloads
+// CompressionMiddleware compresses HTTP responses with zstd or gzip, whichever the
+// client prefers in Accept-Encoding. Small bodies, event streams, responses that
+// already carry a Content-Encoding and binary content types are sent as-is.
 func CompressionMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Check if client accepts gzip encoding
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Skip compression for small responses or already compressed content
-		// We'll compress responses > 1KB
-		w.Header().Set("Vary", "Accept-Encoding")
-		
-		// Create gzip writer
-		gz := gzip.NewWriter(w)
-		defer gz.Close()
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
 
-		// Set content encoding header
-		w.Header().Set("Content-Encoding", "gzip")
+// DisableCompression stops CompressionMiddleware from compressing the response
+// written to w. Streaming handlers call it before writing anything.
+func DisableCompression(w http.ResponseWriter) {
+	for {
+		switch rw := w.(type) {
+		case *compressWriter:
+			rw.disabled = true
+			return
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return
+		}
+	}
+}
 
-		// Wrap response writer
-		gzw := &gzipResponseWriter{Writer: gz, ResponseWriter: w}
-		next.ServeHTTP(gzw, r)
-	})
+// negotiateEncoding picks zstd or gzip from an Accept-Encoding header, honouring
+// q-values and "*". It returns "" when neither is acceptable.
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	qvalues := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		qvalues[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	// zstd first so it wins ties
+	for _, encoding := range []string{"zstd", "gzip"} {
+		q, ok := qvalues[encoding]
+		if !ok {
+			q, ok = qvalues["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
 }
 
-// gzipResponseWriter wraps http.ResponseWriter to compress responses
-type gzipResponseWriter struct {
-	io.Writer
+// isCompressibleType reports whether a Content-Type is worth compressing.
+// Images, archives and other binary formats are usually compressed already.
+func isCompressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case mediaType == "text/event-stream":
+		// Compression buffers events and breaks streaming
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// compressWriter buffers the start of a response until it knows whether
+// compressing it is worthwhile, then either compresses or passes it through
+type compressWriter struct {
 	http.ResponseWriter
+	encoding string
+	disabled bool
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         compressor
+}
+
+func (w *compressWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	if statusCode < http.StatusOK {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	w.wroteHeader = true
+	w.status = statusCode
+
+	// Decide straight away when the headers already rule compression out
+	if !w.canCompress() {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= compressionMinSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends whatever has been written so far to the client
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
 }
 
-func (w *gzipResponseWriter) Write(b []byte) (int, error) {
-	return w.Writer.Write(b)
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
-// WriteHeader writes the header (status code)
-func (w *gzipResponseWriter) WriteHeader(statusCode int) {
-	w.ResponseWriter.WriteHeader(statusCode)
+// canCompress reports whether the response, as described by its status and
+// headers so far, may be compressed
+func (w *compressWriter) canCompress() bool {
+	if w.disabled {
+		return false
+	}
+	if w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if contentType := h.Get("Content-Type"); contentType != "" {
+		return isCompressibleType(contentType)
+	}
+	return true
 }
 
+// decide writes the status line, compressing the rest of the response when
+// compress is set and the response allows it, and sends any buffered body
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		// Sniff before compressing, or net/http would sniff the compressed bytes
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if compress && w.canCompress() {
+		if w.encoding == "zstd" {
+			w.enc = zstdPool.Get().(compressor)
+		} else {
+			w.enc = gzipPool.Get().(compressor)
+		}
+		w.enc.Reset(w.ResponseWriter)
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	if w.enc != nil {
+		_, err := w.enc.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// close finishes the response once the handler has returned
+func (w *compressWriter) close() {
+	if !w.decided && w.wroteHeader {
+		// Anything still buffered is under the size threshold
+		w.decide(false)
+	}
+	if w.enc == nil {
+		return
+	}
+
+	w.enc.Close()
+	w.enc.Reset(io.Discard)
+	if w.encoding == "zstd" {
+		zstdPool.Put(w.enc)
+	} else {
+		gzipPool.Put(w.enc)
+	}
+	w.enc = nil
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "gzip", want: "gzip"},
+		{header: "gzip, deflate, br, zstd", want: "zstd"},
+		{header: "zstd;q=0.5, gzip", want: "gzip"},
+		{header: "gzip;q=0, zstd;q=0", want: ""},
+		{header: "*", want: "zstd"},
+		{header: "*;q=0.1, gzip;q=0.8", want: "gzip"},
+		{header: "identity", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := `{"data":"` + strings.Repeat("x", 2*compressionMinSize) + `"}`
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		handler        http.HandlerFunc
+		wantEncoding   string
+		wantBody       string
+	}{
+		{
+			name:           "large json gzip",
+			acceptEncoding: "gzip",
+			handler:        jsonHandler(large),
+			wantEncoding:   "gzip",
+			wantBody:       large,
+		},
+		{
+			name:           "large json zstd",
+			acceptEncoding: "gzip, zstd",
+			handler:        jsonHandler(large),
+			wantEncoding:   "zstd",
+			wantBody:       large,
+		},
+		{
+			name:           "small body",
+			acceptEncoding: "gzip",
+			handler:        jsonHandler(`{"ok":true}`),
+			wantBody:       `{"ok":true}`,
+		},
+		{
+			name:           "client without compression",
+			acceptEncoding: "",
+			handler:        jsonHandler(large),
+			wantBody:       large,
+		},
+		{
+			name:           "already encoded",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "br")
+				w.Write([]byte(large))
+			},
+			wantEncoding: "br",
+			wantBody:     large,
+		},
+		{
+			name:           "binary content type",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				w.Write([]byte(large))
+			},
+			wantBody: large,
+		},
+		{
+			name:           "opted out",
+			acceptEncoding: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				DisableCompression(w)
+				jsonHandler(large)(w, r)
+			},
+			wantBody: large,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+
+			CompressionMiddleware(tt.handler).ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := decodeBody(t, tt.wantEncoding, rec.Body); got != tt.wantBody {
+				t.Errorf("body = %.40q..., want %.40q...", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCompressionMiddleware_EventStreamIsNotBuffered(t *testing.T) {
+	release := make(chan struct{})
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: first\n\n"))
+		http.NewResponseController(w).Flush()
+		// Keep the stream open until the client has seen the first event
+		<-release
+	}
+	srv := httptest.NewServer(CompressionMiddleware(http.HandlerFunc(handler)))
+	defer srv.Close()
+	defer close(release)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip, zstd")
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want none", got)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading first event: %v", err)
+	}
+	if line != "data: first\n" {
+		t.Errorf("first line = %q, want %q", line, "data: first\n")
+	}
+}
+
+func jsonHandler(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}
+}
+
+func decodeBody(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+
+	var reader io.Reader = body
+	switch encoding {
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatalf("gzip.NewReader() error = %v", err)
+		}
+		reader = gz
+	case "zstd":
+		dec, err := zstd.NewReader(body)
+		if err != nil {
+			t.Fatalf("zstd.NewReader() error = %v", err)
+		}
+		defer dec.Close()
+		reader = dec
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/worker"
+)
+
+// maxCustomDomainRoutes is how many path routes a custom domain can have
+const maxCustomDomainRoutes = 50
+
+// CustomDomainRouteRequest routes the requests whose path starts with PathPrefix to a service
+type CustomDomainRouteRequest struct {
+	PathPrefix string `json:"path_prefix"`
+	ServiceID  string `json:"service_id"`
+}
+
+// CustomDomainRoutesRequest represents a request to replace the routing table of a custom domain
+type CustomDomainRoutesRequest struct {
+	Routes []CustomDomainRouteRequest `json:"routes"`
+}
+
+// CustomDomainRouteResponse represents one path route of a custom domain
+type CustomDomainRouteResponse struct {
+	PathPrefix string `json:"path_prefix"`
+	ServiceID  string `json:"service_id"`
+}
+
+// CustomDomainRoutesResponse represents the routing table of a custom domain.
+// Requests matching none of the routes go to the default service, the domain's own.
+type CustomDomainRoutesResponse struct {
+	DomainID         string                      `json:"domain_id"`
+	DefaultServiceID string                      `json:"default_service_id"`
+	Routes           []CustomDomainRouteResponse `json:"routes"`
+}
+
+// GetCustomDomainRoutes handles GET /domains/:id/routes
+func (h *CustomDomainHandler) GetCustomDomainRoutes(w http.ResponseWriter, r *http.Request) {
+	customDomain, _ := h.getOwnedCustomDomain(w, r)
+	if customDomain == nil {
+		return
+	}
+
+	routes, err := h.store.ListCustomDomainRoutes(r.Context(), customDomain.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, newCustomDomainRoutesResponse(customDomain, routes))
+}
+
+// ReplaceCustomDomainRoutes handles PUT /domains/:id/routes
+// Replaces the whole routing table; an empty list of routes sends everything to the
+// domain's own service again. A prefix matches the path itself and everything under
+// it (/api matches /api and /api/users, not /apis), and the longest matching prefix
+// wins, so /api/v2 can go to another service than /api.
+func (h *CustomDomainHandler) ReplaceCustomDomainRoutes(w http.ResponseWriter, r *http.Request) {
+	customDomain, service := h.getOwnedCustomDomain(w, r)
+	if customDomain == nil {
+		return
+	}
+
+	var req CustomDomainRoutesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body: "+err.Error()))
+		return
+	}
+
+	validationErrs := &ValidationErrors{}
+	if len(req.Routes) > maxCustomDomainRoutes {
+		validationErrs.Add("routes", fmt.Sprintf("must have at most %d routes", maxCustomDomainRoutes))
+	}
+
+	routes := make([]*store.CustomDomainRoute, 0, len(req.Routes))
+	seen := make(map[string]int, len(req.Routes))
+	for i, route := range req.Routes {
+		field := fmt.Sprintf("routes[%d]", i)
+
+		prefix, err := normalizePathPrefix(route.PathPrefix)
+		if err != nil {
+			validationErrs.Add(field+".path_prefix", err.Error())
+		} else if j, ok := seen[prefix]; ok {
+			validationErrs.Add(field+".path_prefix", fmt.Sprintf("conflicts with routes[%d], both route %s", j, prefix))
+		} else {
+			seen[prefix] = i
+		}
+
+		serviceID, err := uuid.Parse(route.ServiceID)
+		if err != nil {
+			validationErrs.Add(field+".service_id", "must be a valid UUID")
+			continue
+		}
+		target, err := h.store.GetService(r.Context(), serviceID)
+		if err != nil {
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return
+		}
+		// Routes can only point at services of the project the domain's service is in
+		if target == nil || target.ProjectID != service.ProjectID {
+			validationErrs.Add(field+".service_id", "must be a service of the same project")
+			continue
+		}
+
+		routes = append(routes, &store.CustomDomainRoute{PathPrefix: prefix, ServiceID: serviceID})
+	}
+	if validationErrs.HasErrors() {
+		WriteError(w, validationErrs.ToAppError())
+		return
+	}
+
+	if err := h.store.ReplaceCustomDomainRoutes(r.Context(), customDomain.ID, routes); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	// Domains still waiting for their service pick up the routes once they go live
+	if h.config.CaddyAdminURL != "" && customDomain.CNAMETarget.Valid {
+		awaiting, err := h.store.IsCustomDomainAwaitingReady(r.Context(), customDomain.ID)
+		if err != nil {
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return
+		}
+		if !awaiting {
+			if err := worker.ApplyCustomDomainRoutes(r.Context(), h.store, h.caddy, customDomain, service); err != nil {
+				WriteError(w, domain.NewAppError(domain.ErrCodeExternalAPI, "Failed to update Caddy routes: "+err.Error(), http.StatusBadGateway))
+				return
+			}
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, newCustomDomainRoutesResponse(customDomain, routes))
+}
+
+// normalizePathPrefix checks a route's path prefix and returns it without trailing slash
+func normalizePathPrefix(prefix string) (string, error) {
+	prefix = strings.TrimSpace(prefix)
+	if !strings.HasPrefix(prefix, "/") {
+		return "", fmt.Errorf("must start with /")
+	}
+	if len(prefix) > 255 {
+		return "", fmt.Errorf("must be at most 255 characters")
+	}
+	if strings.ContainsAny(prefix, "*?# \t") {
+		return "", fmt.Errorf("must be a plain path, without wildcards, query or fragment")
+	}
+	if strings.Contains(prefix, "//") {
+		return "", fmt.Errorf("must not contain empty path segments")
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return "", fmt.Errorf("/ is served by the domain's own service, use a longer prefix")
+	}
+	return prefix, nil
+}
+
+func newCustomDomainRoutesResponse(d *store.CustomDomain, routes []*store.CustomDomainRoute) CustomDomainRoutesResponse {
+	resp := CustomDomainRoutesResponse{
+		DomainID:         d.ID.String(),
+		DefaultServiceID: d.ServiceID.String(),
+		Routes:           make([]CustomDomainRouteResponse, 0, len(routes)),
+	}
+	for _, route := range routes {
+		resp.Routes = append(resp.Routes, CustomDomainRouteResponse{
+			PathPrefix: route.PathPrefix,
+			ServiceID:  route.ServiceID.String(),
+		})
+	}
+	return resp
+}
+
+// getOwnedCustomDomain loads the custom domain from the {id} URL param and its
+// service, and checks the service belongs to the caller's org. It writes the error
+// response and returns nil when it doesn't.
+func (h *CustomDomainHandler) getOwnedCustomDomain(w http.ResponseWriter, r *http.Request) (*store.CustomDomain, *store.Service) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid domain ID"))
+		return nil, nil
+	}
+
+	customDomain, err := h.store.GetCustomDomain(r.Context(), id)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil, nil
+	}
+	if customDomain == nil {
+		WriteError(w, domain.NewNotFoundError("Custom Domain"))
+		return nil, nil
+	}
+
+	service, err := h.store.GetService(r.Context(), customDomain.ServiceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil, nil
+	}
+	if service == nil {
+		WriteError(w, domain.NewNotFoundError("Custom Domain"))
+		return nil, nil
+	}
+
+	project, err := h.store.GetProject(r.Context(), service.ProjectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil, nil
+	}
+	if !checkOrgAccess(w, project, orgID, "Custom Domain") {
+		return nil, nil
+	}
+
+	return customDomain, service
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -11,40 +12,56 @@ import (
 	"github.com/intelifox/click-deploy/internal/caddy"
 	"github.com/intelifox/click-deploy/internal/config"
 	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/k8s"
 	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/worker"
 )
 
 type CustomDomainHandler struct {
-	store  *store.DB
-	config *config.Config
-	caddy  *caddy.Client
+	store      *store.DB
+	config     *config.Config
+	caddy      *caddy.Client
+	k8sClients *k8s.ClientRegistry
 }
 
-func NewCustomDomainHandler(store *store.DB, cfg *config.Config) *CustomDomainHandler {
+func NewCustomDomainHandler(store *store.DB, cfg *config.Config, k8sClients *k8s.ClientRegistry) *CustomDomainHandler {
 	return &CustomDomainHandler{
-		store:  store,
-		config: cfg,
-		caddy:  caddy.NewClient(cfg.CaddyAdminURL),
+		store:      store,
+		config:     cfg,
+		caddy:      caddy.NewClient(cfg.CaddyAdminURL),
+		k8sClients: k8sClients,
 	}
 }
 
 // RegisterCustomDomainRoutes registers custom domain routes
-func RegisterCustomDomainRoutes(r chi.Router, db *store.DB, cfg *config.Config) {
-	h := NewCustomDomainHandler(db, cfg)
+func RegisterCustomDomainRoutes(r chi.Router, db *store.DB, cfg *config.Config, k8sClients *k8s.ClientRegistry) {
+	h := NewCustomDomainHandler(db, cfg, k8sClients)
 
 	r.Get("/services/{id}/domains", h.ListCustomDomains)
 	r.Post("/services/{id}/domains", h.AddCustomDomain)
 	r.Get("/domains/{id}", h.GetCustomDomain)
 	r.Post("/domains/{id}/verify", h.VerifyCustomDomain)
 	r.Delete("/domains/{id}", h.DeleteCustomDomain)
+
+	// Path-based routing of a domain to other services of the project
+	r.Get("/domains/{id}/routes", h.GetCustomDomainRoutes)
+	r.With(MaxBodySizeMiddleware(cfg.MaxBulkRequestBodyBytes)).Put("/domains/{id}/routes", h.ReplaceCustomDomainRoutes)
+
+	// Custom 502/503 page served for all of the service's custom domains
+	r.Get("/services/{id}/error-page", h.GetErrorPage)
+	r.Put("/services/{id}/error-page", h.SetErrorPage)
+	r.Delete("/services/{id}/error-page", h.DeleteErrorPage)
 }
 
 // AddCustomDomainRequest represents a request to add a custom domain
 type AddCustomDomainRequest struct {
 	Domain string `json:"domain" validate:"required,hostname"`
+	Force  bool   `json:"force,omitempty"` // Route the domain even if the service has no ready pod yet
 }
 
 // AddCustomDomain handles POST /services/:id/domains
+// The domain only goes live once the service has a ready pod; until then it stays
+// pending and a background job adds its route. Pass force to route it right away.
 func (h *CustomDomainHandler) AddCustomDomain(w http.ResponseWriter, r *http.Request) {
 	orgID := auth.GetOrgID(r.Context())
 	if orgID == "" {
@@ -75,8 +92,7 @@ func (h *CustomDomainHandler) AddCustomDomain(w http.ResponseWriter, r *http.Req
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		WriteError(w, domain.NewNotFoundError("Service"))
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
@@ -87,27 +103,26 @@ func (h *CustomDomainHandler) AddCustomDomain(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Sanitize input
-	req.Domain = SanitizeDomain(req.Domain)
-
-	// Validate domain
-	if validationErr := ValidateAddCustomDomainRequest(&req); validationErr != nil {
-		WriteError(w, validationErr)
+	// Validate and normalize the domain
+	if validationErrs := ValidateAddCustomDomainRequest(&req, h.config.ReservedDomainList()); validationErrs.HasErrors() {
+		WriteError(w, validationErrs.ToAppError())
 		return
 	}
 
-	// Check if domain already exists for this service
-	existingDomains, err := h.store.ListCustomDomainsByService(r.Context(), serviceID)
+	// Domains are unique platform-wide, so a domain claimed by any other
+	// service (in any organization) can't be added again
+	existingDomain, err := h.store.GetCustomDomainByDomain(r.Context(), req.Domain)
 	if err != nil {
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
-
-	for _, existingDomain := range existingDomains {
-		if existingDomain.Domain == req.Domain {
+	if existingDomain != nil {
+		if existingDomain.ServiceID == serviceID {
 			WriteError(w, domain.NewAppError(domain.ErrCodeAlreadyExists, "Domain already exists for this service", http.StatusConflict))
-			return
+		} else {
+			WriteError(w, domain.NewAppError(domain.ErrCodeAlreadyExists, "Domain is already in use", http.StatusConflict))
 		}
+		return
 	}
 
 	// Get target for CNAME - use generated URL or floating IP
@@ -142,23 +157,26 @@ func (h *CustomDomainHandler) AddCustomDomain(w http.ResponseWriter, r *http.Req
 	// Add route to Caddy (even if not verified yet, Caddy will handle it)
 	// Skip Caddy if admin URL is not configured (k3s mode uses ingress instead)
 	if h.config.CaddyAdminURL != "" {
-		if err := h.caddy.AddRoute(r.Context(), req.Domain, targetIP, service.Port, true); err != nil {
-			// Log error but don't fail - route can be added later
-			// Update status to pending (DNS verification needed)
-			customDomain.Status = "pending"
-			h.store.UpdateCustomDomain(r.Context(), customDomain.ID, customDomain)
-			// Don't return error - domain is created but needs DNS verification
-			// Frontend will show "Pending DNS" status
+		// Hold the route back while the service can't answer, it would only serve 502s
+		if !req.Force && h.k8sClients != nil {
+			ready, err := worker.ServiceReady(r.Context(), h.k8sClients, project, service)
+			if err != nil || !ready {
+				if err := h.store.SetCustomDomainAwaitingReady(r.Context(), customDomain.ID, true); err != nil {
+					WriteError(w, domain.ErrDatabase.WithError(err))
+					return
+				}
+				WriteJSON(w, http.StatusCreated, customDomain)
+				return
+			}
 		}
-	}
 
-	// If Caddy route was added successfully, update status to active
-	// Otherwise, leave as pending for DNS verification
-	if customDomain.Status != "pending" {
-		customDomain.Status = "active"
-		if err := h.store.UpdateCustomDomain(r.Context(), customDomain.ID, customDomain); err != nil {
-			WriteError(w, domain.ErrDatabase.WithError(err))
-			return
+		if err := worker.ActivateCustomDomain(r.Context(), h.store, h.caddy, customDomain, service); err != nil {
+			// Caddy is unreachable or rejected the route: the domain is saved, so it
+			// stays pending and a background job adds the route once Caddy takes it
+			log.Printf("Failed to add Caddy route for %s, queueing a retry: %v", customDomain.Domain, err)
+			if err := worker.QueueCustomDomainRoute(r.Context(), h.store, customDomain.ID); err != nil {
+				log.Printf("Failed to queue Caddy route for %s: %v", customDomain.Domain, err)
+			}
 		}
 	}
 
@@ -196,8 +214,7 @@ func (h *CustomDomainHandler) ListCustomDomains(w http.ResponseWriter, r *http.R
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		WriteError(w, domain.NewNotFoundError("Service"))
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
@@ -208,7 +225,7 @@ func (h *CustomDomainHandler) ListCustomDomains(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, domains)
+	WriteJSON(w, http.StatusOK, newListResponse(domains))
 }
 
 // GetCustomDomain handles GET /domains/:id
@@ -249,8 +266,7 @@ func (h *CustomDomainHandler) GetCustomDomain(w http.ResponseWriter, r *http.Req
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		WriteError(w, domain.NewNotFoundError("Custom Domain"))
+	if !checkOrgAccess(w, project, orgID, "Custom Domain") {
 		return
 	}
 
@@ -295,8 +311,7 @@ func (h *CustomDomainHandler) VerifyCustomDomain(w http.ResponseWriter, r *http.
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		WriteError(w, domain.NewNotFoundError("Custom Domain"))
+	if !checkOrgAccess(w, project, orgID, "Custom Domain") {
 		return
 	}
 
@@ -356,8 +371,7 @@ func (h *CustomDomainHandler) DeleteCustomDomain(w http.ResponseWriter, r *http.
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		WriteError(w, domain.NewNotFoundError("Custom Domain"))
+	if !checkOrgAccess(w, project, orgID, "Custom Domain") {
 		return
 	}
 
@@ -1,20 +1,136 @@
 package api
 
-import "github.com/intelifox/click-deploy/internal/domain"
+import (
+	"fmt"
+	"net"
+	"strings"
 
-// ValidateAddCustomDomainRequest validates an AddCustomDomainRequest
-func ValidateAddCustomDomainRequest(req *AddCustomDomainRequest) *domain.AppError {
-	if req.Domain == "" {
-		return domain.NewValidationError("Domain is required")
+	"golang.org/x/net/idna"
+)
+
+// DNS length limits (RFC 1035), applied to the punycode form of a domain
+const (
+	maxDomainLength      = 253
+	maxDomainLabelLength = 63
+)
+
+// domainProfile maps internationalized domains to punycode the way browsers look them
+// up: case folded and width mapped. Label rules are checked separately, so that each
+// violation gets its own message instead of idna's.
+var domainProfile = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.StrictDomainName(false),
+	idna.CheckHyphens(false),
+)
+
+// ValidateAddCustomDomainRequest validates an AddCustomDomainRequest and normalizes
+// its domain: lowercase, without the trailing dot of a fully qualified name, and with
+// internationalized labels in punycode (münchen.de becomes xn--mnchen-3ya.de).
+// reservedDomains lists platform domains that can't be claimed, either
+// directly or through one of their subdomains.
+func ValidateAddCustomDomainRequest(req *AddCustomDomainRequest, reservedDomains []string) *ValidationErrors {
+	errors := &ValidationErrors{}
+
+	normalized, message := normalizeDomain(req.Domain)
+	if message != "" {
+		errors.Add("domain", message)
+		return errors
 	}
+	req.Domain = normalized
 
-	// Basic hostname validation
-	if len(req.Domain) > 255 {
-		return domain.NewValidationError("Domain name too long")
+	if IsReservedDomain(req.Domain, reservedDomains) {
+		errors.Add("domain", "is reserved and cannot be used")
 	}
 
-	// More validation can be added here (e.g., regex for valid hostname)
+	return errors
+}
+
+// normalizeDomain returns the normalized form of a domain a user entered, or a message
+// saying why it isn't a valid domain name. Schemes, paths and ports are rejected rather
+// than stripped, so a typo isn't silently turned into another domain.
+func normalizeDomain(raw string) (string, string) {
+	d := strings.TrimSpace(raw)
+	if d == "" {
+		return "", "is required"
+	}
 
-	return nil
+	if i := strings.Index(d, "://"); i != -1 {
+		return "", fmt.Sprintf("must not include a scheme, remove %q", d[:i+3])
+	}
+	if strings.ContainsAny(d, "/?#") {
+		return "", "must not include a path, enter the domain name only"
+	}
+	if strings.HasPrefix(d, "[") || net.ParseIP(d) != nil {
+		return "", "must be a domain name, not an IP address"
+	}
+	if host, _, err := net.SplitHostPort(d); err == nil {
+		if net.ParseIP(host) != nil {
+			return "", "must be a domain name, not an IP address"
+		}
+		return "", "must not include a port, custom domains are served on ports 80 and 443"
+	}
+	if strings.HasPrefix(d, "*.") {
+		return "", "wildcard domains aren't supported, add each subdomain"
+	}
+
+	// A fully qualified name ends with a dot: it's the same domain
+	d = strings.TrimSuffix(d, ".")
+
+	ascii, err := domainProfile.ToASCII(d)
+	if err != nil {
+		return "", "contains characters that aren't allowed in a domain name"
+	}
+	if net.ParseIP(ascii) != nil {
+		return "", "must be a domain name, not an IP address"
+	}
+	if len(ascii) > maxDomainLength {
+		return "", fmt.Sprintf("must be at most %d characters long, punycode included", maxDomainLength)
+	}
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return "", "must include a top-level domain, e.g. example.com"
+	}
+	for _, label := range labels {
+		if message := checkDomainLabel(label); message != "" {
+			return "", message
+		}
+	}
+
+	return ascii, ""
+}
+
+// checkDomainLabel returns why a punycode label can't be part of a domain name, or ""
+func checkDomainLabel(label string) string {
+	if label == "" {
+		return "must not contain empty labels (two dots in a row, or a leading dot)"
+	}
+	if len(label) > maxDomainLabelLength {
+		return fmt.Sprintf("label %q is longer than %d characters", label, maxDomainLabelLength)
+	}
+	if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+		return fmt.Sprintf("label %q must not start or end with a hyphen", label)
+	}
+	for _, r := range label {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return fmt.Sprintf("label %q may only contain letters, digits and hyphens", label)
+		}
+	}
+	return ""
 }
 
+// IsReservedDomain reports whether d equals, or is a subdomain of, any of the reserved domains
+func IsReservedDomain(d string, reservedDomains []string) bool {
+	d = strings.TrimSuffix(strings.ToLower(d), ".")
+	for _, reserved := range reservedDomains {
+		reserved = strings.TrimSuffix(strings.ToLower(reserved), ".")
+		if reserved == "" {
+			continue
+		}
+		if d == reserved || strings.HasSuffix(d, "."+reserved) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/intelifox/click-deploy/internal/config"
@@ -19,7 +20,7 @@ func TestCustomDomainHandler_AddCustomDomain(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewCustomDomainHandler(dbStore, &config.Config{})
+	handler := NewCustomDomainHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-cd-001"
@@ -64,16 +65,16 @@ func TestCustomDomainHandler_AddCustomDomain(t *testing.T) {
 			requestBody: AddCustomDomainRequest{
 				Domain: "example.com",
 			},
-			// Will fail on Caddy call, but that's expected in tests
-			expectedStatus: http.StatusInternalServerError,
+			// Caddy is skipped when no admin URL is configured
+			expectedStatus: http.StatusCreated,
 		},
 		{
 			name: "subdomain",
 			requestBody: AddCustomDomainRequest{
 				Domain: "api.example.com",
 			},
-			// Will fail on Caddy call, but that's expected in tests
-			expectedStatus: http.StatusInternalServerError,
+			// Caddy is skipped when no admin URL is configured
+			expectedStatus: http.StatusCreated,
 		},
 		{
 			name: "missing domain",
@@ -106,7 +107,7 @@ func TestCustomDomainHandler_ListCustomDomains(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewCustomDomainHandler(dbStore, &config.Config{})
+	handler := NewCustomDomainHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-cd-002"
@@ -169,13 +170,13 @@ func TestCustomDomainHandler_ListCustomDomains(t *testing.T) {
 		t.Errorf("Expected status %d, got %d. Response: %s", http.StatusOK, w.Code, w.Body.String())
 	}
 
-	var domains []*store.CustomDomain
+	var domains ListResponse[*store.CustomDomain]
 	if err := json.NewDecoder(w.Body).Decode(&domains); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if len(domains) != 2 {
-		t.Errorf("Expected 2 domains, got %d", len(domains))
+	if len(domains.Items) != 2 {
+		t.Errorf("Expected 2 domains, got %d", len(domains.Items))
 	}
 }
 
@@ -185,7 +186,7 @@ func TestCustomDomainHandler_DeleteCustomDomain(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewCustomDomainHandler(dbStore, &config.Config{})
+	handler := NewCustomDomainHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-cd-003"
@@ -250,3 +251,193 @@ func TestCustomDomainHandler_DeleteCustomDomain(t *testing.T) {
 	}
 }
 
+
+func TestCustomDomainHandler_ReplaceCustomDomainRoutes(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	handler := NewCustomDomainHandler(dbStore, &config.Config{}, nil)
+
+	orgID := "test-org-cd-004"
+	ctx := testutil.MockAuthContext(context.Background(), "test-user-123", orgID)
+
+	var projects []*store.Project
+	for _, slug := range []string{"test-project", "other-project"} {
+		project := &store.Project{
+			Name:              "Test Project",
+			Slug:              slug,
+			CasdoorOrgID:      orgID,
+			OpenStackTenantID: "test-tenant-123",
+		}
+		if err := dbStore.CreateProject(ctx, project); err != nil {
+			t.Fatalf("Failed to create test project: %v", err)
+		}
+		projects = append(projects, project)
+	}
+
+	var services []*store.Service
+	for _, project := range []*store.Project{projects[0], projects[0], projects[1]} {
+		service := &store.Service{
+			ProjectID:    project.ID,
+			Name:         "Test Service",
+			Type:         "app",
+			Status:       "active",
+			InstanceSize: "medium",
+			Port:         8080,
+		}
+		if err := dbStore.CreateService(ctx, service); err != nil {
+			t.Fatalf("Failed to create test service: %v", err)
+		}
+		services = append(services, service)
+	}
+	web, api, otherProject := services[0], services[1], services[2]
+
+	domain := &store.CustomDomain{
+		ServiceID: web.ID,
+		Domain:    "example.com",
+		Status:    "active",
+	}
+	if err := dbStore.CreateCustomDomain(ctx, domain); err != nil {
+		t.Fatalf("Failed to create test domain: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		routes         []CustomDomainRouteRequest
+		expectedStatus int
+	}{
+		{
+			name:           "distinct prefixes",
+			routes:         []CustomDomainRouteRequest{{PathPrefix: "/api/", ServiceID: api.ID.String()}, {PathPrefix: "/api/v2", ServiceID: web.ID.String()}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "same prefix twice",
+			routes:         []CustomDomainRouteRequest{{PathPrefix: "/api", ServiceID: api.ID.String()}, {PathPrefix: "/api/", ServiceID: web.ID.String()}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "root prefix",
+			routes:         []CustomDomainRouteRequest{{PathPrefix: "/", ServiceID: api.ID.String()}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "service of another project",
+			routes:         []CustomDomainRouteRequest{{PathPrefix: "/api", ServiceID: otherProject.ID.String()}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(CustomDomainRoutesRequest{Routes: tt.routes})
+			req, _ := testutil.MockRequestWithURLParamAndAuth(t, "PUT", "/v1/click-deploy/domains/"+domain.ID.String()+"/routes",
+				map[string]string{"id": domain.ID.String()}, bytes.NewReader(body), "test-user-123", orgID)
+			w := testutil.MockResponseRecorder()
+
+			handler.ReplaceCustomDomainRoutes(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d. Response: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// Only the valid table was stored, with the trailing slash trimmed
+	routes, err := dbStore.ListCustomDomainRoutes(ctx, domain.ID)
+	if err != nil {
+		t.Fatalf("Failed to list routes: %v", err)
+	}
+	if len(routes) != 2 || routes[0].PathPrefix != "/api" || routes[0].ServiceID != api.ID || routes[1].PathPrefix != "/api/v2" {
+		t.Errorf("Unexpected routes stored: %+v", routes)
+	}
+}
+
+func TestIsReservedDomain(t *testing.T) {
+	reserved := []string{"zyndra.app", "up.zyndra.app"}
+
+	tests := []struct {
+		domain   string
+		expected bool
+	}{
+		{"zyndra.app", true},
+		{"ZYNDRA.APP", true},
+		{"zyndra.app.", true},
+		{"api.zyndra.app", true},
+		{"my-app-prod.up.zyndra.app", true},
+		{"example.com", false},
+		{"notzyndra.app", false},
+		{"zyndra.app.example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := IsReservedDomain(tt.domain, reserved); got != tt.expected {
+				t.Errorf("IsReservedDomain(%q) = %v, expected %v", tt.domain, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateAddCustomDomainRequest(t *testing.T) {
+	reserved := []string{"zyndra.app"}
+
+	tests := []struct {
+		name    string
+		domain  string
+		want    string // Normalized domain, when valid
+		message string // Error for the domain field, when invalid
+	}{
+		{"plain", "example.com", "example.com", ""},
+		{"uppercase", "API.Example.COM", "api.example.com", ""},
+		{"trailing dot", "example.com.", "example.com", ""},
+		{"surrounding spaces", "  example.com ", "example.com", ""},
+		{"unicode", "münchen.de", "xn--mnchen-3ya.de", ""},
+		{"unicode uppercase", "MÜNCHEN.de", "xn--mnchen-3ya.de", ""},
+		{"punycode", "xn--mnchen-3ya.de", "xn--mnchen-3ya.de", ""},
+		{"full-width characters", "ｅｘａｍｐｌｅ.com", "example.com", ""},
+		{"empty", "", "", "is required"},
+		{"scheme", "https://example.com", "", `must not include a scheme, remove "https://"`},
+		{"path", "example.com/app", "", "must not include a path, enter the domain name only"},
+		{"port", "example.com:8080", "", "must not include a port, custom domains are served on ports 80 and 443"},
+		{"IPv4", "192.168.1.10", "", "must be a domain name, not an IP address"},
+		{"IPv4 with port", "192.168.1.10:80", "", "must be a domain name, not an IP address"},
+		{"IPv6", "::1", "", "must be a domain name, not an IP address"},
+		{"bracketed IPv6", "[::1]:443", "", "must be a domain name, not an IP address"},
+		{"wildcard", "*.example.com", "", "wildcard domains aren't supported, add each subdomain"},
+		{"single label", "localhost", "", "must include a top-level domain, e.g. example.com"},
+		{"empty label", "api..example.com", "", "must not contain empty labels (two dots in a row, or a leading dot)"},
+		{"leading hyphen", "-api.example.com", "", `label "-api" must not start or end with a hyphen`},
+		{"underscore", "my_app.example.com", "", `label "my_app" may only contain letters, digits and hyphens`},
+		{"long label", strings.Repeat("a", 64) + ".com", "", `label "` + strings.Repeat("a", 64) + `" is longer than 63 characters`},
+		{"long domain", strings.Repeat(strings.Repeat("a", 60)+".", 5) + "com", "", "must be at most 253 characters long, punycode included"},
+		{"reserved", "api.zyndra.app", "", "is reserved and cannot be used"},
+		{"reserved in uppercase", "API.ZYNDRA.APP.", "", "is reserved and cannot be used"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &AddCustomDomainRequest{Domain: tt.domain}
+			errs := ValidateAddCustomDomainRequest(req, reserved)
+
+			if tt.message == "" {
+				if errs.HasErrors() {
+					t.Fatalf("Expected %q to be valid, got %v", tt.domain, errs)
+				}
+				if req.Domain != tt.want {
+					t.Errorf("Normalized %q to %q, expected %q", tt.domain, req.Domain, tt.want)
+				}
+				return
+			}
+
+			if len(errs.Errors) != 1 {
+				t.Fatalf("Expected one error for %q, got %v", tt.domain, errs.Errors)
+			}
+			if errs.Errors[0].Field != "domain" || errs.Errors[0].Message != tt.message {
+				t.Errorf("Error for %q = %s: %s, expected domain: %s", tt.domain, errs.Errors[0].Field, errs.Errors[0].Message, tt.message)
+			}
+		})
+	}
+}
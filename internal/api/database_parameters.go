@@ -0,0 +1,222 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// DatabaseParametersResponse lists a database's config overrides and what can be overridden
+type DatabaseParametersResponse struct {
+	DatabaseID        string            `json:"database_id"`
+	Engine            string            `json:"engine"`
+	Parameters        map[string]string `json:"parameters"`
+	AllowedParameters []string          `json:"allowed_parameters"`
+	Restarted         bool              `json:"restarted,omitempty"` // Set when a change restarted the database
+}
+
+// SetDatabaseParameterRequest sets a single database parameter
+type SetDatabaseParameterRequest struct {
+	Value string `json:"value"`
+}
+
+// GetDatabaseParameters handles GET /databases/:id/parameters
+func (h *DatabaseHandler) GetDatabaseParameters(w http.ResponseWriter, r *http.Request) {
+	database, _, ok := h.getOwnedDatabase(w, r)
+	if !ok {
+		return
+	}
+
+	params, err := h.store.GetDatabaseParameters(r.Context(), database.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, newDatabaseParametersResponse(database, params, false))
+}
+
+// ReplaceDatabaseParameters handles PUT /databases/:id/parameters
+// The body replaces all overrides, e.g. {"parameters": {"max_connections": "200"}}.
+func (h *DatabaseHandler) ReplaceDatabaseParameters(w http.ResponseWriter, r *http.Request) {
+	database, project, ok := h.getOwnedDatabase(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Parameters map[string]string `json:"parameters"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body"))
+		return
+	}
+	if req.Parameters == nil {
+		req.Parameters = map[string]string{}
+	}
+
+	h.saveDatabaseParameters(w, r, database, project, req.Parameters)
+}
+
+// SetDatabaseParameter handles PUT /databases/:id/parameters/:name
+func (h *DatabaseHandler) SetDatabaseParameter(w http.ResponseWriter, r *http.Request) {
+	database, project, ok := h.getOwnedDatabase(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetDatabaseParameterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	params, err := h.store.GetDatabaseParameters(r.Context(), database.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if params == nil {
+		params = map[string]string{}
+	}
+	params[chi.URLParam(r, "name")] = req.Value
+
+	h.saveDatabaseParameters(w, r, database, project, params)
+}
+
+// DeleteDatabaseParameter handles DELETE /databases/:id/parameters/:name
+// The setting goes back to the engine's default.
+func (h *DatabaseHandler) DeleteDatabaseParameter(w http.ResponseWriter, r *http.Request) {
+	database, project, ok := h.getOwnedDatabase(w, r)
+	if !ok {
+		return
+	}
+
+	params, err := h.store.GetDatabaseParameters(r.Context(), database.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	name := chi.URLParam(r, "name")
+	if _, exists := params[name]; !exists {
+		WriteError(w, domain.NewNotFoundError("Database parameter"))
+		return
+	}
+	delete(params, name)
+
+	h.saveDatabaseParameters(w, r, database, project, params)
+}
+
+// saveDatabaseParameters validates and stores params, then restarts a running database with them
+func (h *DatabaseHandler) saveDatabaseParameters(w http.ResponseWriter, r *http.Request, database *store.Database, project *store.Project, params map[string]string) {
+	if validationErrors := ValidateDatabaseParameters(database.Engine, params); validationErrors.HasErrors() {
+		WriteError(w, validationErrors.ToAppError())
+		return
+	}
+
+	if err := h.store.UpdateDatabaseParameters(r.Context(), database.ID, params, auth.GetUserID(r.Context())); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	// Databases that aren't running yet pick the parameters up when provisioned
+	restarted := false
+	if client := projectK8sClient(h.k8sClients, project); client != nil && database.Status == "active" {
+		if err := client.ApplyDatabaseParameters(r.Context(), project.ID.String(), database.ID.String(), database.Engine, params); err != nil {
+			WriteError(w, domain.NewAppError(domain.ErrCodeExternalAPI, "Parameters were saved but could not be applied: "+err.Error(), http.StatusBadGateway))
+			return
+		}
+		restarted = true
+	}
+
+	WriteJSON(w, http.StatusOK, newDatabaseParametersResponse(database, params, restarted))
+}
+
+// getOwnedDatabase loads the database in the URL and its project, writing an error
+// response and returning false unless it belongs to the caller's organization
+func (h *DatabaseHandler) getOwnedDatabase(w http.ResponseWriter, r *http.Request) (*store.Database, *store.Project, bool) {
+	return getOwnedDatabase(w, r, h.store)
+}
+
+// getOwnedDatabase is DatabaseHandler.getOwnedDatabase for handlers of other resources
+func getOwnedDatabase(w http.ResponseWriter, r *http.Request, db *store.DB) (*store.Database, *store.Project, bool) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return nil, nil, false
+	}
+
+	databaseID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid database ID"))
+		return nil, nil, false
+	}
+
+	database, err := db.GetDatabase(r.Context(), databaseID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil, nil, false
+	}
+	if database == nil {
+		WriteError(w, domain.NewNotFoundError("Database"))
+		return nil, nil, false
+	}
+
+	// Databases reach their project through the linked service or their volume
+	var projectID uuid.UUID
+	if serviceID, err := uuid.Parse(database.ServiceID.String); database.ServiceID.Valid && err == nil {
+		service, err := db.GetService(r.Context(), serviceID)
+		if err != nil {
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return nil, nil, false
+		}
+		if service != nil {
+			projectID = service.ProjectID
+		}
+	}
+	if volumeID, err := uuid.Parse(database.VolumeID.String); projectID == uuid.Nil && database.VolumeID.Valid && err == nil {
+		volume, err := db.GetVolume(r.Context(), volumeID)
+		if err != nil {
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return nil, nil, false
+		}
+		if volume != nil {
+			projectID = volume.ProjectID
+		}
+	}
+	if projectID == uuid.Nil {
+		WriteError(w, domain.NewNotFoundError("Database"))
+		return nil, nil, false
+	}
+
+	project, err := db.GetProject(r.Context(), projectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil, nil, false
+	}
+	if !checkOrgAccess(w, project, orgID, "Database") {
+		return nil, nil, false
+	}
+
+	return database, project, true
+}
+
+func newDatabaseParametersResponse(database *store.Database, params map[string]string, restarted bool) DatabaseParametersResponse {
+	if params == nil {
+		params = map[string]string{}
+	}
+	return DatabaseParametersResponse{
+		DatabaseID:        database.ID.String(),
+		Engine:            database.Engine,
+		Parameters:        params,
+		AllowedParameters: k8s.AllowedDatabaseParameters(database.Engine),
+		Restarted:         restarted,
+	}
+}
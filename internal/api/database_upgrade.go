@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/tracing"
+	"github.com/intelifox/click-deploy/internal/worker"
+)
+
+// UpgradeDatabaseRequest is the version to upgrade a database to
+type UpgradeDatabaseRequest struct {
+	TargetVersion string `json:"target_version"`
+}
+
+// UpgradeDatabaseResponse describes an upgrade that was started
+type UpgradeDatabaseResponse struct {
+	DatabaseID    string `json:"database_id"`
+	Engine        string `json:"engine"`
+	FromVersion   string `json:"from_version"`
+	TargetVersion string `json:"target_version"`
+	Strategy      string `json:"strategy"` // in_place or dump_restore
+	Status        string `json:"status"`
+}
+
+// UpgradeDatabase handles POST /databases/:id/upgrade
+// Moves a running database to a newer version of its engine in the background: a
+// backup is taken, then the database restarts on the new image, or for engines whose
+// data files don't carry over, the new version is restored from the backup. The
+// database is upgrading until it's done. Downgrades are rejected.
+func (h *DatabaseHandler) UpgradeDatabase(w http.ResponseWriter, r *http.Request) {
+	database, project, ok := h.getOwnedDatabase(w, r)
+	if !ok {
+		return
+	}
+	client := projectK8sClient(h.k8sClients, project)
+	if client == nil {
+		WriteError(w, domain.NewServiceUnavailableError("Kubernetes is not configured"))
+		return
+	}
+
+	var req UpgradeDatabaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body"))
+		return
+	}
+	req.TargetVersion = SanitizeName(req.TargetVersion)
+	if req.TargetVersion == "" {
+		WriteError(w, domain.NewValidationError("target_version is required"))
+		return
+	}
+
+	fromVersion := database.Version.String
+	if fromVersion == "" {
+		fromVersion = k8s.DefaultDatabaseVersion(database.Engine)
+	}
+	strategy, err := k8s.DatabaseUpgradeStrategy(database.Engine, fromVersion, req.TargetVersion)
+	if err != nil {
+		WriteError(w, domain.NewValidationError(err.Error()))
+		return
+	}
+
+	if err := h.store.StartDatabaseUpgrade(r.Context(), database.ID); err != nil {
+		if errors.Is(err, store.ErrDatabaseNotActive) {
+			WriteError(w, domain.NewConflictError("Only active databases can be upgraded, this one is "+database.Status))
+			return
+		}
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	upgrader := worker.NewK8sDatabaseWorker(h.store, client)
+	userID := auth.GetUserID(r.Context())
+	ctx := tracing.Detach(r.Context())
+	go func() {
+		if err := upgrader.UpgradeDatabase(ctx, database, project.ID, req.TargetVersion, userID); err != nil {
+			log.Printf("Database %s: %v", database.ID, err)
+		}
+	}()
+
+	WriteJSON(w, http.StatusAccepted, UpgradeDatabaseResponse{
+		DatabaseID:    database.ID.String(),
+		Engine:        database.Engine,
+		FromVersion:   fromVersion,
+		TargetVersion: req.TargetVersion,
+		Strategy:      strategy,
+		Status:        "upgrading",
+	})
+}
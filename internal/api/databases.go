@@ -11,30 +11,40 @@ import (
 
 	"github.com/intelifox/click-deploy/internal/auth"
 	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/secrets"
 	"github.com/intelifox/click-deploy/internal/store"
 )
 
 type DatabaseHandler struct {
-	store  *store.DB
-	config *config.Config
+	store      *store.DB
+	config     *config.Config
+	k8sClients *k8s.ClientRegistry
 }
 
-func NewDatabaseHandler(store *store.DB, cfg *config.Config) *DatabaseHandler {
+func NewDatabaseHandler(store *store.DB, cfg *config.Config, k8sClients *k8s.ClientRegistry) *DatabaseHandler {
 	return &DatabaseHandler{
-		store:  store,
-		config: cfg,
+		store:      store,
+		config:     cfg,
+		k8sClients: k8sClients,
 	}
 }
 
 // RegisterDatabaseRoutes registers database-related routes
-func RegisterDatabaseRoutes(r chi.Router, db *store.DB, cfg *config.Config) {
-	h := NewDatabaseHandler(db, cfg)
+func RegisterDatabaseRoutes(r chi.Router, db *store.DB, cfg *config.Config, k8sClients *k8s.ClientRegistry) {
+	h := NewDatabaseHandler(db, cfg, k8sClients)
 
 	r.Get("/projects/{id}/databases", h.ListDatabases)
 	r.Post("/projects/{id}/databases", h.CreateDatabase)
 	r.Get("/databases/{id}", h.GetDatabase)
 	r.Get("/databases/{id}/credentials", h.GetDatabaseCredentials)
 	r.Delete("/databases/{id}", h.DeleteDatabase)
+	r.Get("/databases/{id}/parameters", h.GetDatabaseParameters)
+	r.With(MaxBodySizeMiddleware(cfg.MaxBulkRequestBodyBytes)).Put("/databases/{id}/parameters", h.ReplaceDatabaseParameters)
+	r.Put("/databases/{id}/parameters/{name}", h.SetDatabaseParameter)
+	r.Delete("/databases/{id}/parameters/{name}", h.DeleteDatabaseParameter)
+	r.Post("/databases/{id}/upgrade", h.UpgradeDatabase)
 }
 
 // CreateDatabaseRequest represents a request to create a database
@@ -44,6 +54,9 @@ type CreateDatabaseRequest struct {
 	Version   string    `json:"version,omitempty"`    // Optional: e.g., "14", "8.0"
 	Size      string    `json:"size,omitempty"`        // small, medium, large (default: small)
 	VolumeSizeMB int    `json:"volume_size_mb,omitempty"` // Default: 500
+	StorageClass string `json:"storage_class,omitempty"`  // Optional: overrides the cluster's default storage class
+	DatabaseName string `json:"database_name,omitempty"`  // Optional: the engine's default (app) when empty
+	Username     string `json:"username,omitempty"`       // Optional: the engine's default (admin) when empty
 }
 
 // CreateDatabase creates a new database
@@ -64,11 +77,10 @@ func (h *DatabaseHandler) CreateDatabase(w http.ResponseWriter, r *http.Request)
 	// Verify project belongs to user's organization
 	project, err := h.store.GetProject(r.Context(), projectID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDatabaseError(w, err)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Project not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Project") {
 		return
 	}
 
@@ -78,33 +90,50 @@ func (h *DatabaseHandler) CreateDatabase(w http.ResponseWriter, r *http.Request)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	req.Engine = SanitizeName(req.Engine)
+	req.Version = SanitizeName(req.Version)
+	req.Size = SanitizeName(req.Size)
+	req.StorageClass = SanitizeName(req.StorageClass)
+	req.DatabaseName = SanitizeName(req.DatabaseName)
+	req.Username = SanitizeName(req.Username)
 
-	// Validate engine
-	if req.Engine != "postgresql" && req.Engine != "mysql" && req.Engine != "redis" {
-		http.Error(w, "Invalid engine. Must be postgresql, mysql, or redis", http.StatusBadRequest)
+	// Validate request
+	if validationErrs := ValidateCreateDatabaseRequest(&req); validationErrs.HasErrors() {
+		WriteError(w, validationErrs.ToAppError())
 		return
 	}
 
-	// Validate size
+	// Reject storage classes the project's cluster doesn't have before anything is created
+	client := projectK8sClient(h.k8sClients, project)
+	if req.StorageClass != "" && client != nil {
+		if err := client.EnsureStorageClass(r.Context(), req.StorageClass); err != nil {
+			validationErrs := &ValidationErrors{}
+			validationErrs.Add("storage_class", err.Error())
+			WriteError(w, validationErrs.ToAppError())
+			return
+		}
+	}
+
+	// Set defaults
 	if req.Size == "" {
 		req.Size = "small"
 	}
-	if req.Size != "small" && req.Size != "medium" && req.Size != "large" {
-		http.Error(w, "Invalid size. Must be small, medium, or large", http.StatusBadRequest)
-		return
-	}
-
-	// Set default volume size
 	if req.VolumeSizeMB == 0 {
 		req.VolumeSizeMB = 500
 	}
+	if req.DatabaseName == "" {
+		req.DatabaseName = k8s.DefaultDatabaseName(req.Engine)
+	}
+	if req.Username == "" {
+		req.Username = k8s.DefaultDatabaseUsername(req.Engine)
+	}
 
 	// If service_id provided, verify it belongs to the project
 	var serviceID sql.NullString
 	if req.ServiceID != uuid.Nil {
 		service, err := h.store.GetService(r.Context(), req.ServiceID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeDatabaseError(w, err)
 			return
 		}
 		if service == nil || service.ProjectID != projectID {
@@ -121,31 +150,45 @@ func (h *DatabaseHandler) CreateDatabase(w http.ResponseWriter, r *http.Request)
 		SizeMB:     req.VolumeSizeMB,
 		Status:     "pending",
 		VolumeType: "database_auto",
+		CreatedBy:  requestUser(r),
 	}
-
-	if err := h.store.CreateVolume(r.Context(), volume); err != nil {
-		http.Error(w, "Failed to create volume: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
+	volume.UpdatedBy = volume.CreatedBy
 
 	// Create database with linked volume
 	database := &store.Database{
 		ServiceID:    serviceID,
 		Engine:       req.Engine,
 		Size:         req.Size,
-		VolumeID:     sql.NullString{String: volume.ID.String(), Valid: true},
 		VolumeSizeMB: req.VolumeSizeMB,
 		Status:       "provisioning",
+		CreatedBy:    requestUser(r),
 	}
+	database.UpdatedBy = database.CreatedBy
 
 	if req.Version != "" {
 		database.Version = sql.NullString{String: req.Version, Valid: true}
 	}
+	if req.StorageClass != "" {
+		database.StorageClass = sql.NullString{String: req.StorageClass, Valid: true}
+	}
+	// Stored up front so the provisioned database gets them, see K8sDatabaseWorker
+	if req.DatabaseName != "" {
+		database.DatabaseName = sql.NullString{String: req.DatabaseName, Valid: true}
+	}
+	if req.Username != "" {
+		database.Username = sql.NullString{String: req.Username, Valid: true}
+	}
 
-	if err := h.store.CreateDatabase(r.Context(), database); err != nil {
-		// Cleanup volume on failure
-		_ = h.store.DeleteVolume(r.Context(), volume.ID)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// The volume and the database are created together, or neither is
+	requested := QuotaUsage{MemoryMB: databaseMemoryMB(req.Size), StorageMB: req.VolumeSizeMB}
+	created := createWithinQuota(w, r, h.store, orgID, requested, func(tx *store.QuotaTx) error {
+		if err := tx.CreateVolume(r.Context(), volume); err != nil {
+			return err
+		}
+		database.VolumeID = sql.NullString{String: volume.ID.String(), Valid: true}
+		return tx.CreateDatabase(r.Context(), database)
+	})
+	if !created {
 		return
 	}
 
@@ -182,29 +225,39 @@ func (h *DatabaseHandler) ListDatabases(w http.ResponseWriter, r *http.Request)
 	// Verify project belongs to user's organization
 	project, err := h.store.GetProject(r.Context(), projectID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDatabaseError(w, err)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Project not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Project") {
 		return
 	}
 
 	databases, err := h.store.ListDatabasesByProject(r.Context(), projectID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDatabaseError(w, err)
 		return
 	}
 
 	// Don't expose passwords
 	for _, db := range databases {
-		if db.Password.Valid {
-			db.Password = sql.NullString{}
-		}
+		maskDatabaseCredentials(db)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(databases)
+	json.NewEncoder(w).Encode(newListResponse(databases))
+}
+
+// writeDatabaseError writes a 500 with err's message, which may quote a database's
+// connection URL, with the password masked
+func writeDatabaseError(w http.ResponseWriter, err error) {
+	http.Error(w, secrets.RedactConnectionURL(err.Error()), http.StatusInternalServerError)
+}
+
+// maskDatabaseCredentials clears the password and the connection URL (which embeds it).
+// Credentials are only returned by GET /databases/{id}/credentials.
+func maskDatabaseCredentials(d *store.Database) {
+	d.Password = sql.NullString{}
+	d.ConnectionURL = sql.NullString{}
 }
 
 // GetDatabase retrieves a database by ID
@@ -224,7 +277,7 @@ func (h *DatabaseHandler) GetDatabase(w http.ResponseWriter, r *http.Request) {
 
 	database, err := h.store.GetDatabase(r.Context(), databaseID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDatabaseError(w, err)
 		return
 	}
 	if database == nil {
@@ -237,17 +290,16 @@ func (h *DatabaseHandler) GetDatabase(w http.ResponseWriter, r *http.Request) {
 		serviceID, _ := uuid.Parse(database.ServiceID.String)
 		service, err := h.store.GetService(r.Context(), serviceID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeDatabaseError(w, err)
 			return
 		}
 		if service != nil {
 			project, err := h.store.GetProject(r.Context(), service.ProjectID)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				writeDatabaseError(w, err)
 				return
 			}
-			if project == nil || !project.BelongsToOrg(orgID) {
-				http.Error(w, "Database not found", http.StatusNotFound)
+			if !checkOrgAccess(w, project, orgID, "Database") {
 				return
 			}
 		}
@@ -280,7 +332,7 @@ func (h *DatabaseHandler) GetDatabaseCredentials(w http.ResponseWriter, r *http.
 	// Verify database belongs to user's organization
 	database, err := h.store.GetDatabase(r.Context(), databaseID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDatabaseError(w, err)
 		return
 	}
 	if database == nil {
@@ -292,17 +344,16 @@ func (h *DatabaseHandler) GetDatabaseCredentials(w http.ResponseWriter, r *http.
 		serviceID, _ := uuid.Parse(database.ServiceID.String)
 		service, err := h.store.GetService(r.Context(), serviceID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeDatabaseError(w, err)
 			return
 		}
 		if service != nil {
 			project, err := h.store.GetProject(r.Context(), service.ProjectID)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				writeDatabaseError(w, err)
 				return
 			}
-			if project == nil || !project.BelongsToOrg(orgID) {
-				http.Error(w, "Database not found", http.StatusNotFound)
+			if !checkOrgAccess(w, project, orgID, "Database") {
 				return
 			}
 		}
@@ -310,13 +361,17 @@ func (h *DatabaseHandler) GetDatabaseCredentials(w http.ResponseWriter, r *http.
 
 	creds, err := h.store.GetDatabaseCredentials(r.Context(), databaseID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDatabaseError(w, err)
 		return
 	}
 	if creds == nil {
 		http.Error(w, "Database not found", http.StatusNotFound)
 		return
 	}
+	if creds.Hostname == "" && (database.Status == "pending" || database.Status == "provisioning") {
+		WriteError(w, domain.NewResourceProvisioningError("Database is still being provisioned, its credentials aren't available yet"))
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(creds)
@@ -340,7 +395,7 @@ func (h *DatabaseHandler) DeleteDatabase(w http.ResponseWriter, r *http.Request)
 	// Verify database belongs to user's organization
 	database, err := h.store.GetDatabase(r.Context(), databaseID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDatabaseError(w, err)
 		return
 	}
 	if database == nil {
@@ -352,17 +407,16 @@ func (h *DatabaseHandler) DeleteDatabase(w http.ResponseWriter, r *http.Request)
 		serviceID, _ := uuid.Parse(database.ServiceID.String)
 		service, err := h.store.GetService(r.Context(), serviceID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeDatabaseError(w, err)
 			return
 		}
 		if service != nil {
 			project, err := h.store.GetProject(r.Context(), service.ProjectID)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				writeDatabaseError(w, err)
 				return
 			}
-			if project == nil || !project.BelongsToOrg(orgID) {
-				http.Error(w, "Database not found", http.StatusNotFound)
+			if !checkOrgAccess(w, project, orgID, "Database") {
 				return
 			}
 		}
@@ -375,7 +429,7 @@ func (h *DatabaseHandler) DeleteDatabase(w http.ResponseWriter, r *http.Request)
 			http.Error(w, "Database not found", http.StatusNotFound)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDatabaseError(w, err)
 		return
 	}
 
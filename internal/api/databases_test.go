@@ -20,7 +20,7 @@ func TestDatabaseHandler_CreateDatabase(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewDatabaseHandler(dbStore, &config.Config{})
+	handler := NewDatabaseHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-db-001"
@@ -88,6 +88,29 @@ func TestDatabaseHandler_CreateDatabase(t *testing.T) {
 			},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name: "missing engine",
+			requestBody: CreateDatabaseRequest{
+				Size: "small",
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "invalid version",
+			requestBody: CreateDatabaseRequest{
+				Engine:  "postgresql",
+				Version: "latest; rm -rf /",
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "volume size below minimum",
+			requestBody: CreateDatabaseRequest{
+				Engine:       "postgresql",
+				VolumeSizeMB: 10,
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name: "default size and volume",
 			requestBody: CreateDatabaseRequest{
@@ -95,6 +118,39 @@ func TestDatabaseHandler_CreateDatabase(t *testing.T) {
 			},
 			expectedStatus: http.StatusCreated,
 		},
+		{
+			name: "custom database name and username",
+			requestBody: CreateDatabaseRequest{
+				Engine:       "postgresql",
+				DatabaseName: "orders",
+				Username:     "orders_app",
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "invalid postgresql database name",
+			requestBody: CreateDatabaseRequest{
+				Engine:       "postgresql",
+				DatabaseName: "Orders-DB",
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "reserved mysql username",
+			requestBody: CreateDatabaseRequest{
+				Engine:   "mysql",
+				Username: "root",
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "redis database name",
+			requestBody: CreateDatabaseRequest{
+				Engine:       "redis",
+				DatabaseName: "cache",
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -119,7 +175,7 @@ func TestDatabaseHandler_ListDatabases(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewDatabaseHandler(dbStore, &config.Config{})
+	handler := NewDatabaseHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-db-002"
@@ -183,13 +239,13 @@ func TestDatabaseHandler_ListDatabases(t *testing.T) {
 		t.Errorf("Expected status %d, got %d. Response: %s", http.StatusOK, w.Code, w.Body.String())
 	}
 
-	var databases []*store.Database
+	var databases ListResponse[*store.Database]
 	if err := json.NewDecoder(w.Body).Decode(&databases); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if len(databases) != 2 {
-		t.Errorf("Expected 2 databases, got %d", len(databases))
+	if len(databases.Items) != 2 {
+		t.Errorf("Expected 2 databases, got %d", len(databases.Items))
 	}
 }
 
@@ -199,7 +255,7 @@ func TestDatabaseHandler_GetDatabase(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewDatabaseHandler(dbStore, &config.Config{})
+	handler := NewDatabaseHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-db-003"
@@ -289,7 +345,7 @@ func TestDatabaseHandler_DeleteDatabase(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewDatabaseHandler(dbStore, &config.Config{})
+	handler := NewDatabaseHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-db-004"
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/worker"
+)
+
+// deployArchiveField is the multipart form field holding the uploaded archive
+const deployArchiveField = "archive"
+
+// DeployArchive handles POST /services/:id/deploy-archive
+// Deploys a .tar.gz of the source uploaded as the "archive" field of a multipart
+// form, built in place of a git clone, so services deploy without a git connection.
+// The archive is kept until its build and removed after.
+func (h *DeploymentHandler) DeployArchive(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	serviceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid service ID"))
+		return
+	}
+	service, err := h.store.GetService(r.Context(), serviceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if service == nil {
+		WriteError(w, domain.NewNotFoundError("Service"))
+		return
+	}
+	project, err := h.store.GetProject(r.Context(), service.ProjectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if !checkOrgAccess(w, project, orgID, "Service") {
+		return
+	}
+
+	// The server's read timeout would otherwise end large uploads partway through;
+	// MaxDeployArchiveBytes still caps the body
+	http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+	uploadPath, filename, err := h.receiveDeployArchive(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	// Until it's moved to the deployment's archive path
+	defer os.Remove(uploadPath)
+
+	// Deployments beyond the org's concurrent deployment limit wait for a free slot
+	status, err := newDeploymentStatus(r.Context(), h.store, h.config, service, orgID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	deployment := &store.Deployment{
+		ID:            uuid.New(),
+		ServiceID:     serviceID,
+		Status:        status,
+		CommitMessage: store.StringToNullString(fmt.Sprintf("Uploaded archive %s", filename)),
+		TriggeredBy:   store.DeploymentTriggerArchive,
+		RequestedBy:   requestUser(r),
+	}
+
+	// The archive is in place before the deployment exists, so no build can miss it
+	if err := os.Rename(uploadPath, worker.DeploymentArchivePath(h.config, deployment.ID)); err != nil {
+		WriteError(w, domain.ErrInternal.WithError(err))
+		return
+	}
+	if err := h.store.CreateDeployment(r.Context(), deployment); err != nil {
+		h.removeDeploymentArchive(deployment)
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	// Queue build job asynchronously, unless it has to be approved or wait for a slot first
+	startDeployment(r.Context(), h.store, h.config, h.buildWorker, h.k8sWorker, deployment)
+	if deployment.Status == "failed" {
+		h.removeDeploymentArchive(deployment)
+	}
+
+	response, err := newDeploymentResponse(r.Context(), h.store, deployment)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	WriteJSON(w, http.StatusCreated, response)
+}
+
+// receiveDeployArchive streams the uploaded archive to a file in the archives
+// directory and returns its path and the uploaded file's name. Only gzip files are
+// accepted; whether the tarball inside is valid shows when it's extracted.
+func (h *DeploymentHandler) receiveDeployArchive(r *http.Request) (string, string, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return "", "", domain.NewInvalidInputError("Expected a multipart/form-data upload")
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return "", "", domain.NewValidationError(fmt.Sprintf("Missing %q file field", deployArchiveField))
+		}
+		if err != nil {
+			return "", "", invalidUploadError(err)
+		}
+		if part.FormName() != deployArchiveField {
+			part.Close()
+			continue
+		}
+
+		path, err := saveDeployArchive(part, worker.DeploymentArchivesDir(h.config))
+		part.Close()
+		if err != nil {
+			return "", "", err
+		}
+		return path, filepath.Base(part.FileName()), nil
+	}
+}
+
+// saveDeployArchive writes an uploaded archive to a new file in dir and checks it is
+// gzip compressed
+func saveDeployArchive(src io.Reader, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", domain.ErrInternal.WithError(err)
+	}
+	f, err := os.CreateTemp(dir, "upload-*.tar.gz")
+	if err != nil {
+		return "", domain.ErrInternal.WithError(err)
+	}
+
+	_, err = io.Copy(f, src)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = domain.ErrInternal.WithError(closeErr)
+	}
+	if err == nil {
+		err = checkGzipFile(f.Name())
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", invalidUploadError(err)
+	}
+	return f.Name(), nil
+}
+
+// checkGzipFile checks a file starts with a gzip header
+func checkGzipFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return domain.ErrInternal.WithError(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return domain.NewValidationError("Archive must be a .tar.gz file")
+	}
+	return gz.Close()
+}
+
+// invalidUploadError reports a failed upload; bodies over the size limit get a 413
+func invalidUploadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return err
+	}
+	if _, ok := domain.IsAppError(err); ok {
+		return err
+	}
+	return domain.NewInvalidInputError("Invalid upload: " + err.Error())
+}
+
+// removeDeploymentArchive removes the uploaded archive of a deployment that won't be
+// built
+func (h *DeploymentHandler) removeDeploymentArchive(deployment *store.Deployment) {
+	if deployment.TriggeredBy != store.DeploymentTriggerArchive {
+		return
+	}
+	if err := worker.RemoveDeploymentArchive(h.config, deployment.ID); err != nil {
+		log.Printf("Failed to remove archive of deployment %s: %v", deployment.ID, err)
+	}
+}
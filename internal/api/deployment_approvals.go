@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// Deployment status for services that require approval before building
+const deploymentStatusAwaitingApproval = "awaiting_approval"
+
+// deploymentApproverRoles are the roles allowed to approve or reject deployments
+var deploymentApproverRoles = []string{"owner", "admin"}
+
+// RejectDeploymentRequest represents a request to reject a deployment awaiting approval
+type RejectDeploymentRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// initialDeploymentStatus is the status a new deployment of service starts in.
+// Deployments of services requiring approval wait until a second user approves them.
+func initialDeploymentStatus(service *store.Service) string {
+	if service.RequiresApproval {
+		return deploymentStatusAwaitingApproval
+	}
+	return "queued"
+}
+
+// checkAwaitingApproval checks the deployment is awaiting approval, writing a 409
+// and returning false when it isn't. Reviews check the caller's role first, so a
+// caller who can't review gets a 403 whatever the deployment's status.
+func checkAwaitingApproval(w http.ResponseWriter, deployment *store.Deployment) bool {
+	if deployment.Status != deploymentStatusAwaitingApproval {
+		WriteError(w, domain.NewConflictError("Deployment is not awaiting approval"))
+		return false
+	}
+	return true
+}
+
+// ApproveDeployment handles POST /deployments/:id/approve
+// The approver must hold an approver role and must not be the user who
+// triggered the deployment. Approving starts the build.
+func (h *DeploymentHandler) ApproveDeployment(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	userID := auth.GetUserID(r.Context())
+	if orgID == "" || userID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	deployment := h.getOwnedDeployment(w, r, "id", orgID)
+	if deployment == nil {
+		return
+	}
+
+	if !requireRole(w, r, "Approving deployments", deploymentApproverRoles...) {
+		return
+	}
+	if deployment.RequestedBy.Valid && deployment.RequestedBy.String == userID {
+		WriteError(w, domain.NewForbiddenError("Deployments must be approved by someone other than the user who triggered them"))
+		return
+	}
+	if !checkAwaitingApproval(w, deployment) {
+		return
+	}
+
+	// An approved deployment still waits for a free slot if the org is at its limit
+	status, err := admittedDeploymentStatus(r.Context(), h.store, h.config, orgID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	approved, err := h.store.ApproveDeployment(r.Context(), deployment.ID, userID, status)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if !approved {
+		WriteError(w, domain.NewConflictError("Deployment is not awaiting approval"))
+		return
+	}
+
+	h.recordReview(r.Context(), deployment, orgID, userID, store.AuditActionDeploymentApprove, "")
+
+	// Queue build job asynchronously, unless it has to wait for a slot
+	deployment.Status = status
+	startDeployment(r.Context(), h.store, h.config, h.buildWorker, h.k8sWorker, deployment)
+
+	h.writeDeployment(w, r, deployment.ID)
+}
+
+// RejectDeployment handles POST /deployments/:id/reject
+// Approvers and the user who triggered the deployment can reject it, which cancels it.
+func (h *DeploymentHandler) RejectDeployment(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	userID := auth.GetUserID(r.Context())
+	if orgID == "" || userID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	deployment := h.getOwnedDeployment(w, r, "id", orgID)
+	if deployment == nil {
+		return
+	}
+
+	isRequester := deployment.RequestedBy.Valid && deployment.RequestedBy.String == userID
+	if !isRequester && !requireRole(w, r, "Rejecting deployments", deploymentApproverRoles...) {
+		return
+	}
+	if !checkAwaitingApproval(w, deployment) {
+		return
+	}
+
+	// The body is optional
+	var req RejectDeploymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body"))
+		return
+	}
+	req.Reason = SanitizeText(req.Reason)
+	if len(req.Reason) > 1000 {
+		WriteError(w, domain.NewValidationError("reason must be at most 1000 characters"))
+		return
+	}
+
+	message := "Rejected"
+	if req.Reason != "" {
+		message = fmt.Sprintf("Rejected: %s", req.Reason)
+	}
+	rejected, err := h.store.RejectDeployment(r.Context(), deployment.ID, message)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if !rejected {
+		WriteError(w, domain.NewConflictError("Deployment is not awaiting approval"))
+		return
+	}
+
+	h.recordReview(r.Context(), deployment, orgID, userID, store.AuditActionDeploymentReject, req.Reason)
+	h.removeDeploymentArchive(deployment)
+
+	h.writeDeployment(w, r, deployment.ID)
+}
+
+// recordReview writes an approval or rejection to the deployment log and the audit log
+func (h *DeploymentHandler) recordReview(ctx context.Context, deployment *store.Deployment, orgID, userID, action, reason string) {
+	reviewer := auth.GetUserName(ctx)
+	if reviewer == "" {
+		reviewer = userID
+	}
+
+	message := fmt.Sprintf("Deployment approved by %s", reviewer)
+	if action == store.AuditActionDeploymentReject {
+		message = fmt.Sprintf("Deployment rejected by %s", reviewer)
+	}
+	h.store.AddDeploymentLog(ctx, deployment.ID, "approval", "info", message, nil)
+
+	metadata := map[string]interface{}{
+		"service_id": deployment.ServiceID.String(),
+	}
+	if deployment.RequestedBy.Valid {
+		metadata["requested_by"] = deployment.RequestedBy.String
+	}
+	if reason != "" {
+		metadata["reason"] = reason
+	}
+	entry := &store.AuditLogEntry{
+		OrgID:        orgID,
+		ActorID:      store.StringToNullString(userID),
+		Action:       action,
+		ResourceType: "deployment",
+		ResourceID:   deployment.ID.String(),
+		Metadata:     metadata,
+	}
+	if err := h.store.CreateAuditLogEntry(ctx, entry); err != nil {
+		log.Printf("Failed to write audit log entry %s for deployment %s: %v", action, deployment.ID, err)
+	}
+}
+
+// writeDeployment responds with the current state of a deployment and its queue position
+func (h *DeploymentHandler) writeDeployment(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	deployment, err := h.store.GetDeployment(r.Context(), id)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if deployment == nil {
+		WriteError(w, domain.NewNotFoundError("Deployment"))
+		return
+	}
+
+	response, err := newDeploymentResponse(r.Context(), h.store, deployment)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
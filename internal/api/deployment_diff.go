@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// DeploymentDiffResponse compares the config two deployments rolled out
+type DeploymentDiffResponse struct {
+	FromDeploymentID string `json:"from_deployment_id"`
+	ToDeploymentID   string `json:"to_deployment_id"`
+	// False if either deployment predates config snapshots, in which case its config diffs as empty
+	Complete bool `json:"complete"`
+	store.DeploymentConfigDiff
+}
+
+// DiffDeployments handles GET /deployments/:id/diff/:other_id
+// Returns how the config of deployment other_id differs from that of deployment id.
+func (h *DeploymentHandler) DiffDeployments(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	from := h.getOwnedDeployment(w, r, "id", orgID)
+	if from == nil {
+		return
+	}
+	to := h.getOwnedDeployment(w, r, "other_id", orgID)
+	if to == nil {
+		return
+	}
+	if from.ServiceID != to.ServiceID {
+		WriteError(w, domain.NewInvalidInputError("Deployments belong to different services"))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, DeploymentDiffResponse{
+		FromDeploymentID:     from.ID.String(),
+		ToDeploymentID:       to.ID.String(),
+		Complete:             from.ConfigSnapshot != nil && to.ConfigSnapshot != nil,
+		DeploymentConfigDiff: store.DiffDeploymentConfigs(from.ConfigSnapshot, to.ConfigSnapshot),
+	})
+}
+
+// getOwnedDeployment loads the deployment whose ID is in the URL param and checks
+// it belongs to the caller's org. It writes the error response and returns nil
+// when it doesn't.
+func (h *DeploymentHandler) getOwnedDeployment(w http.ResponseWriter, r *http.Request, param, orgID string) *store.Deployment {
+	deploymentID, err := uuid.Parse(chi.URLParam(r, param))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid deployment ID"))
+		return nil
+	}
+
+	deployment, err := h.store.GetDeployment(r.Context(), deploymentID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil
+	}
+	if deployment == nil {
+		WriteError(w, domain.NewNotFoundError("Deployment"))
+		return nil
+	}
+
+	service, err := h.store.GetService(r.Context(), deployment.ServiceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil
+	}
+	if service == nil {
+		WriteError(w, domain.NewNotFoundError("Deployment"))
+		return nil
+	}
+
+	project, err := h.store.GetProject(r.Context(), service.ProjectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil
+	}
+	if !checkOrgAccess(w, project, orgID, "Deployment") {
+		return nil
+	}
+
+	return deployment
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/tracing"
+	"github.com/intelifox/click-deploy/internal/worker"
+)
+
+// errDeploymentBackendNotConfigured is logged on deployments that no worker could run
+const errDeploymentBackendNotConfigured = "Deployment backend not configured: no build or Kubernetes worker is available"
+
+// DeploymentResponse is a deployment with its place in the org's deploy queue
+type DeploymentResponse struct {
+	*store.Deployment
+	QueuePosition int `json:"queue_position,omitempty"` // Set while the deployment waits for a deploy slot
+	// Set for rollbacks by GET /deployments/:id: the deployment, the one it rolled back
+	// from and so on while those are rollbacks too
+	RollbackLineage []RollbackLineageEntry `json:"rollback_lineage,omitempty"`
+}
+
+// newDeploymentStatus is the status a new deployment of service starts in: awaiting
+// approval, waiting for admission when the org's concurrent deployments are limited,
+// or queued
+func newDeploymentStatus(ctx context.Context, db *store.DB, cfg *config.Config, service *store.Service, orgID string) (string, error) {
+	status := initialDeploymentStatus(service)
+	if status != "queued" {
+		return status, nil
+	}
+
+	return admittedDeploymentStatus(ctx, db, cfg, orgID)
+}
+
+// admittedDeploymentStatus is queued when the org has no concurrent deployment limit.
+// Otherwise it is waiting: startDeployment admits the deployment if the org has a
+// free slot, atomically, so concurrent requests can't all take the last one.
+func admittedDeploymentStatus(ctx context.Context, db *store.DB, cfg *config.Config, orgID string) (string, error) {
+	limit, err := worker.OrgDeployLimit(ctx, db, cfg, orgID)
+	if err != nil {
+		return "", err
+	}
+	if limit > 0 {
+		return store.DeploymentStatusWaiting, nil
+	}
+	return "queued", nil
+}
+
+// holdDeployment admits a waiting deployment when its org has a free deploy slot,
+// and logs why a new deployment isn't starting yet otherwise. It returns false when
+// the deployment can start right away.
+func holdDeployment(ctx context.Context, db *store.DB, cfg *config.Config, deployment *store.Deployment) bool {
+	switch deployment.Status {
+	case deploymentStatusAwaitingApproval:
+		db.AddDeploymentLog(ctx, deployment.ID, "approval", "info", "Deployment is awaiting approval", nil)
+		return true
+	case store.DeploymentStatusWaiting:
+		admitted, err := admitDeployment(ctx, db, cfg, deployment)
+		if err != nil {
+			log.Printf("Failed to admit deployment %s, leaving it to the deploy queue: %v", deployment.ID, err)
+		}
+		if admitted {
+			deployment.Status = "queued"
+			return false
+		}
+		position, _ := db.GetDeploymentQueuePosition(ctx, deployment.ID)
+		message := fmt.Sprintf("Organization is at its concurrent deployment limit, deployment is queued (position %d)", position)
+		db.AddDeploymentLog(ctx, deployment.ID, "queue", "info", message, nil)
+		return true
+	}
+	return false
+}
+
+// admitDeployment starts a waiting deployment if its org has a free deploy slot
+func admitDeployment(ctx context.Context, db *store.DB, cfg *config.Config, deployment *store.Deployment) (bool, error) {
+	orgID, err := db.GetDeploymentOrgID(ctx, deployment.ID)
+	if err != nil || orgID == "" {
+		return false, err
+	}
+	return worker.AdmitWaitingDeployment(ctx, db, cfg, deployment.ID, orgID)
+}
+
+// startDeployment runs the pipeline of a new or approved deployment, unless it has
+// to be approved or wait for a slot first. Without a build or k8s worker nothing
+// would ever pick the deployment up, so it is failed rather than left queued.
+func startDeployment(ctx context.Context, db *store.DB, cfg *config.Config, buildWorker *worker.BuildWorker, k8sWorker *worker.K8sDeployWorker, deployment *store.Deployment) {
+	if deployment.Status != deploymentStatusAwaitingApproval && (buildWorker == nil || k8sWorker == nil) {
+		db.AddDeploymentLog(ctx, deployment.ID, "queue", "error", errDeploymentBackendNotConfigured, nil)
+		if err := db.UpdateDeploymentStatus(ctx, deployment.ID, "failed"); err == nil {
+			deployment.Status = "failed"
+		}
+		return
+	}
+
+	if holdDeployment(ctx, db, cfg, deployment) {
+		return
+	}
+	go worker.RunDeploymentPipeline(tracing.Detach(ctx), db, buildWorker, k8sWorker, deployment.ID)
+}
+
+// newDeploymentResponse adds the queue position to a deployment that is waiting for a deploy slot
+func newDeploymentResponse(ctx context.Context, db *store.DB, deployment *store.Deployment) (*DeploymentResponse, error) {
+	resp := &DeploymentResponse{Deployment: deployment}
+	if deployment.Status != store.DeploymentStatusWaiting {
+		return resp, nil
+	}
+
+	position, err := db.GetDeploymentQueuePosition(ctx, deployment.ID)
+	if err != nil {
+		return nil, err
+	}
+	resp.QueuePosition = position
+	return resp, nil
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// Synchronous deploys (?wait=true)
+const (
+	defaultDeployWait = 5 * time.Minute
+	// deployWaitPollInterval is how often the deployment's status is checked. The deploy
+	// worker watches the rollout and records the result in the status.
+	deployWaitPollInterval = 2 * time.Second
+	// deployWaitHeartbeatInterval is how often a 102 Processing is sent while waiting,
+	// so proxies and clients don't drop the idle connection
+	deployWaitHeartbeatInterval = 15 * time.Second
+)
+
+// isTerminalDeploymentStatus reports whether a deployment with status is over
+func isTerminalDeploymentStatus(status string) bool {
+	switch status {
+	case "success", "failed", "cancelled":
+		return true
+	}
+	return false
+}
+
+// parseDeployWait parses ?timeout= of a synchronous deploy: a duration such as 300s or
+// 10m, or a number of seconds. Returns the default when empty and caps it at max.
+func parseDeployWait(value string, max time.Duration) (time.Duration, error) {
+	timeout := defaultDeployWait
+	if value != "" {
+		var err error
+		timeout, err = time.ParseDuration(value)
+		if err != nil {
+			seconds, convErr := strconv.Atoi(value)
+			if convErr != nil {
+				return 0, fmt.Errorf("invalid timeout %q, use a duration such as 300s", value)
+			}
+			timeout = time.Duration(seconds) * time.Second
+		}
+		if timeout <= 0 {
+			return 0, fmt.Errorf("timeout must be positive")
+		}
+	}
+	if max > 0 && timeout > max {
+		timeout = max
+	}
+	return timeout, nil
+}
+
+// waitForDeployment blocks until a deployment reaches a terminal status, timeout passes
+// or the client goes away, sending a 102 Processing every heartbeat meanwhile. Returns
+// the last state of the deployment and whether it finished.
+func waitForDeployment(ctx context.Context, w http.ResponseWriter, db *store.DB, deploymentID uuid.UUID, timeout time.Duration) (*store.Deployment, bool, error) {
+	// The server's read timeout would otherwise end the request while it waits
+	http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(deployWaitPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(deployWaitHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		deployment, err := db.GetDeployment(ctx, deploymentID)
+		if err != nil {
+			return nil, false, err
+		}
+		if deployment == nil {
+			return nil, false, fmt.Errorf("deployment %s disappeared", deploymentID)
+		}
+		if isTerminalDeploymentStatus(deployment.Status) {
+			return deployment, true, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return deployment, false, ctx.Err()
+		case <-deadline.C:
+			return deployment, false, nil
+		case <-heartbeat.C:
+			w.WriteHeader(http.StatusProcessing)
+		case <-poll.C:
+		}
+	}
+}
+
+// writeDeploymentResult writes the final state of a synchronous deploy: 200 when it
+// succeeded, 422 when it failed or was cancelled, 408 when it was still running at the
+// timeout
+func writeDeploymentResult(ctx context.Context, w http.ResponseWriter, db *store.DB, deployment *store.Deployment, finished bool) {
+	response, err := newDeploymentResponse(ctx, db, deployment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	switch {
+	case !finished:
+		status = http.StatusRequestTimeout
+	case deployment.Status != "success":
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -1,11 +1,11 @@
 package api
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -24,10 +24,10 @@ type DeploymentHandler struct {
 	k8sWorker     *worker.K8sDeployWorker
 }
 
-func NewDeploymentHandler(store *store.DB, cfg *config.Config, buildWorker *worker.BuildWorker, k8sClient *k8s.Client) *DeploymentHandler {
+func NewDeploymentHandler(store *store.DB, cfg *config.Config, buildWorker *worker.BuildWorker, k8sClients *k8s.ClientRegistry) *DeploymentHandler {
 	var k8sWorker *worker.K8sDeployWorker
-	if k8sClient != nil {
-		k8sWorker = worker.NewK8sDeployWorker(store, k8sClient)
+	if k8sClients != nil {
+		k8sWorker = worker.NewK8sDeployWorker(store, cfg, k8sClients)
 	}
 	
 	return &DeploymentHandler{
@@ -39,13 +39,17 @@ func NewDeploymentHandler(store *store.DB, cfg *config.Config, buildWorker *work
 }
 
 // RegisterDeploymentRoutes registers deployment-related routes
-func RegisterDeploymentRoutes(r chi.Router, db *store.DB, cfg *config.Config, buildWorker *worker.BuildWorker, k8sClient *k8s.Client) {
-	h := NewDeploymentHandler(db, cfg, buildWorker, k8sClient)
+func RegisterDeploymentRoutes(r chi.Router, db *store.DB, cfg *config.Config, buildWorker *worker.BuildWorker, k8sClients *k8s.ClientRegistry) {
+	h := NewDeploymentHandler(db, cfg, buildWorker, k8sClients)
 
 	r.Post("/services/{id}/deploy", h.TriggerDeployment)
+	r.With(MaxBodySizeMiddleware(cfg.MaxDeployArchiveBytes)).Post("/services/{id}/deploy-archive", h.DeployArchive)
 	r.Get("/deployments/{id}", h.GetDeployment)
 	r.Get("/deployments/{id}/logs", h.GetDeploymentLogs)
+	r.Get("/deployments/{id}/diff/{other_id}", h.DiffDeployments)
 	r.Post("/deployments/{id}/cancel", h.CancelDeployment)
+	r.Post("/deployments/{id}/approve", h.ApproveDeployment)
+	r.Post("/deployments/{id}/reject", h.RejectDeployment)
 	r.Get("/services/{id}/deployments", h.ListServiceDeployments)
 }
 
@@ -55,7 +59,10 @@ type TriggerDeploymentRequest struct {
 	Branch    string `json:"branch,omitempty"`     // Optional: deploy specific branch
 }
 
-// TriggerDeployment triggers a new deployment for a service
+// TriggerDeployment triggers a new deployment for a service. With ?wait=true the
+// request is held open until the deployment succeeds, fails or ?timeout= (default 5m,
+// capped by MAX_DEPLOY_WAIT) passes, and returns the final deployment with 200, 422
+// or 408. 102 Processing heartbeats are sent meanwhile.
 func (h *DeploymentHandler) TriggerDeployment(w http.ResponseWriter, r *http.Request) {
 	orgID := auth.GetOrgID(r.Context())
 
@@ -88,8 +95,7 @@ func (h *DeploymentHandler) TriggerDeployment(w http.ResponseWriter, r *http.Req
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Service not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
@@ -100,6 +106,16 @@ func (h *DeploymentHandler) TriggerDeployment(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	wait := r.URL.Query().Get("wait") == "true"
+	var waitTimeout time.Duration
+	if wait {
+		waitTimeout, err = parseDeployWait(r.URL.Query().Get("timeout"), h.config.MaxDeployWait)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Get git source
 	gitSource, err := h.store.GetGitSourceByService(r.Context(), serviceID)
 	if err != nil {
@@ -111,11 +127,19 @@ func (h *DeploymentHandler) TriggerDeployment(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Deployments beyond the org's concurrent deployment limit wait for a free slot
+	status, err := newDeploymentStatus(r.Context(), h.store, h.config, service, orgID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	// Create deployment
 	deployment := &store.Deployment{
 		ServiceID:   serviceID,
-		Status:      "queued",
+		Status:      status,
 		TriggeredBy: "manual",
+		RequestedBy: store.StringToNullString(auth.GetUserID(r.Context())),
 	}
 
 	if req.CommitSHA != "" {
@@ -129,28 +153,28 @@ func (h *DeploymentHandler) TriggerDeployment(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Queue build job asynchronously
-	if h.buildWorker != nil && h.k8sWorker != nil {
-		go func() {
-			ctx := context.Background()
-			
-			// Run build
-			if err := h.buildWorker.ProcessBuildJob(ctx, deployment.ID); err != nil {
-				h.store.UpdateDeploymentStatus(ctx, deployment.ID, "failed")
-				return
-			}
-			
-			// Deploy to k8s after successful build
-			if err := h.k8sWorker.DeployToK8s(ctx, deployment.ID); err != nil {
-				h.store.UpdateDeploymentStatus(ctx, deployment.ID, "failed")
-				return
-			}
-		}()
+	// Queue build job asynchronously, unless it has to be approved or wait for a slot first
+	startDeployment(r.Context(), h.store, h.config, h.buildWorker, h.k8sWorker, deployment)
+
+	if wait {
+		final, finished, err := waitForDeployment(r.Context(), w, h.store, deployment.ID, waitTimeout)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeDeploymentResult(r.Context(), w, h.store, final, finished)
+		return
+	}
+
+	response, err := newDeploymentResponse(r.Context(), h.store, deployment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(deployment)
+	json.NewEncoder(w).Encode(response)
 }
 
 // GetDeployment retrieves a deployment by ID
@@ -194,16 +218,42 @@ func (h *DeploymentHandler) GetDeployment(w http.ResponseWriter, r *http.Request
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Deployment not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Deployment") {
 		return
 	}
 
+	response, err := newDeploymentResponse(r.Context(), h.store, deployment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if deployment.TriggeredBy == store.DeploymentTriggerRollback {
+		lineage, err := h.store.GetRollbackLineage(r.Context(), deployment.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response.RollbackLineage = toRollbackLineage(lineage)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(deployment)
+	json.NewEncoder(w).Encode(response)
 }
 
-// GetDeploymentLogs retrieves logs for a deployment
+// DeploymentLogResponse represents a deployment log line in API responses
+type DeploymentLogResponse struct {
+	ID           int64                  `json:"id"`
+	DeploymentID string                 `json:"deployment_id"`
+	Timestamp    string                 `json:"timestamp"`
+	Phase        string                 `json:"phase"`
+	Level        string                 `json:"level"`
+	Message      string                 `json:"message"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// GetDeploymentLogs retrieves logs for a deployment, oldest first, at most ?limit=
+// lines (default 1000). ?after= takes the ID of the last line the client has and
+// returns only the lines logged since, so polling clients fetch just the delta.
 func (h *DeploymentHandler) GetDeploymentLogs(w http.ResponseWriter, r *http.Request) {
 	orgID := auth.GetOrgID(r.Context())
 	if orgID == "" {
@@ -244,8 +294,7 @@ func (h *DeploymentHandler) GetDeploymentLogs(w http.ResponseWriter, r *http.Req
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Deployment not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Deployment") {
 		return
 	}
 
@@ -257,14 +306,37 @@ func (h *DeploymentHandler) GetDeploymentLogs(w http.ResponseWriter, r *http.Req
 		}
 	}
 
-	logs, err := h.store.GetDeploymentLogs(r.Context(), deploymentID, limit)
+	// Cursor: the ID of the last log line the client has
+	var afterID int64
+	if afterStr := r.URL.Query().Get("after"); afterStr != "" {
+		afterID, err = strconv.ParseInt(afterStr, 10, 64)
+		if err != nil || afterID < 0 {
+			http.Error(w, "Invalid after cursor, must be a log ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	logs, err := h.store.GetDeploymentLogs(r.Context(), deploymentID, afterID, limit)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	response := make([]DeploymentLogResponse, 0, len(logs))
+	for _, l := range logs {
+		response = append(response, DeploymentLogResponse{
+			ID:           l.ID,
+			DeploymentID: l.DeploymentID.String(),
+			Timestamp:    l.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+			Phase:        l.Phase,
+			Level:        l.Level,
+			Message:      l.Message,
+			Metadata:     l.Metadata,
+		})
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(logs)
+	json.NewEncoder(w).Encode(response)
 }
 
 // CancelDeployment cancels a running deployment
@@ -308,13 +380,12 @@ func (h *DeploymentHandler) CancelDeployment(w http.ResponseWriter, r *http.Requ
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Deployment not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Deployment") {
 		return
 	}
 
 	// Check if deployment can be cancelled
-	if deployment.Status != "queued" && deployment.Status != "building" && deployment.Status != "pushing" {
+	if deployment.Status != store.DeploymentStatusWaiting && deployment.Status != "queued" && deployment.Status != "building" && deployment.Status != "pushing" {
 		http.Error(w, "Deployment cannot be cancelled", http.StatusBadRequest)
 		return
 	}
@@ -327,12 +398,22 @@ func (h *DeploymentHandler) CancelDeployment(w http.ResponseWriter, r *http.Requ
 
 	// Add log entry
 	h.store.AddDeploymentLog(r.Context(), deploymentID, "deploy", "info", "Deployment cancelled by user", nil)
+	h.removeDeploymentArchive(deployment)
 
 	// TODO: Actually cancel the build process (context cancellation)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// serviceDeploymentsListSpec pages a service's deployments, newest first by default
+var serviceDeploymentsListSpec = ListSpec{
+	SortFields:   store.DeploymentSortFields,
+	DefaultSort:  "created_at",
+	DefaultDesc:  true,
+	DefaultLimit: 50,
+	MaxLimit:     200,
+}
+
 // ListServiceDeployments lists deployments for a service
 func (h *DeploymentHandler) ListServiceDeployments(w http.ResponseWriter, r *http.Request) {
 	orgID := auth.GetOrgID(r.Context())
@@ -364,26 +445,34 @@ func (h *DeploymentHandler) ListServiceDeployments(w http.ResponseWriter, r *htt
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Service not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
-	// Get limit from query parameter
-	limit := 50 // Default
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+	params, err := ParseListParams(r, serviceDeploymentsListSpec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	deployments, err := h.store.ListDeploymentsByService(r.Context(), serviceID, limit, 0)
+	deployments, err := h.store.ListDeploymentsByService(r.Context(), serviceID, params)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	total, err := h.store.CountDeploymentsByService(r.Context(), serviceID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := newListResponse(deployments)
+	response.Total = total
+	if next := params.Offset + len(deployments); next < total {
+		response.NextCursor = strconv.Itoa(next)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(deployments)
+	json.NewEncoder(w).Encode(response)
 }
 
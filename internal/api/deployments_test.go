@@ -5,9 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/intelifox/click-deploy/internal/auth"
 	"github.com/intelifox/click-deploy/internal/config"
 	"github.com/intelifox/click-deploy/internal/store"
 	"github.com/intelifox/click-deploy/internal/testutil"
@@ -19,7 +21,7 @@ func TestDeploymentHandler_TriggerDeployment(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewDeploymentHandler(dbStore, &config.Config{})
+	handler := NewDeploymentHandler(dbStore, &config.Config{}, nil, nil)
 
 	// Create a test project
 	orgID := "test-org-dep-001"
@@ -73,6 +75,7 @@ func TestDeploymentHandler_TriggerDeployment(t *testing.T) {
 
 	tests := []struct {
 		name           string
+		query          string
 		requestBody    TriggerDeploymentRequest
 		expectedStatus int
 	}{
@@ -95,12 +98,25 @@ func TestDeploymentHandler_TriggerDeployment(t *testing.T) {
 			},
 			expectedStatus: http.StatusCreated,
 		},
+		{
+			// Without a build backend the deployment fails straight away
+			name:           "wait for failed deployment",
+			query:          "?wait=true&timeout=30s",
+			requestBody:    TriggerDeploymentRequest{},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "wait with invalid timeout",
+			query:          "?wait=true&timeout=soon",
+			requestBody:    TriggerDeploymentRequest{},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			body, _ := json.Marshal(tt.requestBody)
-			req, _ := testutil.MockRequestWithURLParamAndAuth(t, "POST", "/v1/click-deploy/services/"+service.ID.String()+"/deploy",
+			req, _ := testutil.MockRequestWithURLParamAndAuth(t, "POST", "/v1/click-deploy/services/"+service.ID.String()+"/deploy"+tt.query,
 				map[string]string{"id": service.ID.String()}, bytes.NewReader(body), "test-user-123", orgID)
 			w := testutil.MockResponseRecorder()
 
@@ -119,7 +135,7 @@ func TestDeploymentHandler_GetDeployment(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewDeploymentHandler(dbStore, &config.Config{})
+	handler := NewDeploymentHandler(dbStore, &config.Config{}, nil, nil)
 
 	// Create a test project
 	orgID := "test-org-dep-002"
@@ -205,7 +221,7 @@ func TestDeploymentHandler_ListServiceDeployments(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewDeploymentHandler(dbStore, &config.Config{})
+	handler := NewDeploymentHandler(dbStore, &config.Config{}, nil, nil)
 
 	// Create a test project
 	orgID := "test-org-dep-003"
@@ -262,13 +278,168 @@ func TestDeploymentHandler_ListServiceDeployments(t *testing.T) {
 		t.Errorf("Expected status %d, got %d. Response: %s", http.StatusOK, w.Code, w.Body.String())
 	}
 
-	var deployments []*store.Deployment
+	var deployments ListResponse[*store.Deployment]
 	if err := json.NewDecoder(w.Body).Decode(&deployments); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if len(deployments) != 2 {
-		t.Errorf("Expected 2 deployments, got %d", len(deployments))
+	if len(deployments.Items) != 2 {
+		t.Errorf("Expected 2 deployments, got %d", len(deployments.Items))
+	}
+
+	// A page of one points at the next
+	req, _ = testutil.MockRequestWithURLParamAndAuth(t, "GET", "/v1/click-deploy/services/"+service.ID.String()+"/deployments?limit=1",
+		map[string]string{"id": service.ID.String()}, nil, "test-user-123", orgID)
+	w = testutil.MockResponseRecorder()
+
+	handler.ListServiceDeployments(w, req)
+
+	var page ListResponse[*store.Deployment]
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(page.Items) != 1 || page.Total != 2 || page.NextCursor != "1" {
+		t.Errorf("Expected 1 of 2 deployments with cursor 1, got %d of %d with cursor %q", len(page.Items), page.Total, page.NextCursor)
 	}
 }
 
+
+func TestDeploymentHandler_ApproveAndRejectDeployment(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	handler := NewDeploymentHandler(dbStore, &config.Config{}, nil, nil)
+
+	orgID := "test-org-dep-approval"
+	project := &store.Project{
+		Name:              "Test Project",
+		Slug:              "test-project",
+		CasdoorOrgID:      orgID,
+		OpenStackTenantID: "test-tenant-123",
+	}
+
+	ctx := testutil.MockAuthContext(context.Background(), "requester", orgID)
+	if err := dbStore.CreateProject(ctx, project); err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+
+	service := &store.Service{
+		ProjectID:        project.ID,
+		Name:             "Production API",
+		Type:             "app",
+		Status:           "pending",
+		InstanceSize:     "medium",
+		Port:             8080,
+		RequiresApproval: true,
+	}
+	if err := dbStore.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+
+	gitConn := &store.GitConnection{CasdoorOrgID: orgID, Provider: "github", AccessToken: "test-token"}
+	if err := dbStore.CreateGitConnection(ctx, gitConn); err != nil {
+		t.Fatalf("Failed to create test git connection: %v", err)
+	}
+	gitSource := &store.GitSource{
+		ServiceID:       service.ID,
+		GitConnectionID: gitConn.ID,
+		Provider:        "github",
+		RepoOwner:       "test-owner",
+		RepoName:        "test-repo",
+		Branch:          "main",
+	}
+	if err := dbStore.CreateGitSource(ctx, gitSource); err != nil {
+		t.Fatalf("Failed to create test git source: %v", err)
+	}
+
+	// send calls a deployment handler as userID with the given roles
+	send := func(h http.HandlerFunc, id, userID string, roles []string) *httptest.ResponseRecorder {
+		req, _ := testutil.MockRequestWithURLParamAndAuth(t, "POST", "/v1/click-deploy/deployments/"+id,
+			map[string]string{"id": id}, bytes.NewReader([]byte("{}")), userID, orgID)
+		req = req.WithContext(context.WithValue(req.Context(), auth.RolesKey, roles))
+		w := testutil.MockResponseRecorder()
+		h(w, req)
+		return w
+	}
+
+	// Deployments of the service start out awaiting approval
+	req, _ := testutil.MockRequestWithURLParamAndAuth(t, "POST", "/v1/click-deploy/services/"+service.ID.String()+"/deploy",
+		map[string]string{"id": service.ID.String()}, bytes.NewReader([]byte("{}")), "requester", orgID)
+	w := testutil.MockResponseRecorder()
+	handler.TriggerDeployment(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Response: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	deployments, err := dbStore.ListDeploymentsByService(ctx, service.ID, store.ListParams{Sort: "created_at", Desc: true, Limit: 10})
+	if err != nil || len(deployments) != 1 {
+		t.Fatalf("Expected one deployment, got %d (err: %v)", len(deployments), err)
+	}
+	deployment := deployments[0]
+	if deployment.Status != deploymentStatusAwaitingApproval {
+		t.Fatalf("Expected status %q, got %q", deploymentStatusAwaitingApproval, deployment.Status)
+	}
+
+	id := deployment.ID.String()
+	admin := []string{"admin"}
+	tests := []struct {
+		name           string
+		userID         string
+		roles          []string
+		expectedStatus int
+	}{
+		{name: "requester cannot approve", userID: "requester", roles: admin, expectedStatus: http.StatusForbidden},
+		{name: "member cannot approve", userID: "member", roles: []string{"member"}, expectedStatus: http.StatusForbidden},
+		{name: "second admin approves", userID: "approver", roles: admin, expectedStatus: http.StatusOK},
+		{name: "already approved", userID: "approver", roles: admin, expectedStatus: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := send(handler.ApproveDeployment, id, tt.userID, tt.roles)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d. Response: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	approved, err := dbStore.GetDeployment(ctx, deployment.ID)
+	if err != nil {
+		t.Fatalf("Failed to get deployment: %v", err)
+	}
+	// The handler has no build or k8s worker, so the approved deployment fails rather than staying queued
+	if approved.Status != "failed" || approved.ApprovedBy.String != "approver" {
+		t.Errorf("Expected failed deployment approved by approver, got status %q approved by %q", approved.Status, approved.ApprovedBy.String)
+	}
+	entries, err := dbStore.ListAuditLogByResource(ctx, "deployment", id)
+	if err != nil {
+		t.Fatalf("Failed to list audit log: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != store.AuditActionDeploymentApprove || entries[0].ActorID.String != "approver" {
+		t.Errorf("Expected one approval audit entry by approver, got %+v", entries)
+	}
+
+	// The requester can withdraw a pending deployment
+	pending := &store.Deployment{
+		ServiceID:   service.ID,
+		Status:      deploymentStatusAwaitingApproval,
+		TriggeredBy: "manual",
+		RequestedBy: store.StringToNullString("requester"),
+	}
+	if err := dbStore.CreateDeployment(ctx, pending); err != nil {
+		t.Fatalf("Failed to create test deployment: %v", err)
+	}
+	if w := send(handler.RejectDeployment, pending.ID.String(), "member", []string{"member"}); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d for member reject, got %d", http.StatusForbidden, w.Code)
+	}
+	if w := send(handler.RejectDeployment, pending.ID.String(), "requester", []string{"member"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d for requester reject, got %d. Response: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	rejected, err := dbStore.GetDeployment(ctx, pending.ID)
+	if err != nil {
+		t.Fatalf("Failed to get deployment: %v", err)
+	}
+	if rejected.Status != "cancelled" {
+		t.Errorf("Expected rejected deployment to be cancelled, got %q", rejected.Status)
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// Modes of CopyEnvVars for keys the target service already has
+const (
+	EnvCopyModeSkip      = "skip"
+	EnvCopyModeOverwrite = "overwrite"
+)
+
+// CopyEnvVarsRequest selects the env vars to copy from another service
+type CopyEnvVarsRequest struct {
+	Keys []string `json:"keys,omitempty"` // Empty copies every var
+	Mode string   `json:"mode,omitempty"` // skip (default) or overwrite
+}
+
+// SkippedEnvVarResponse is an env var that wasn't copied, and why
+type SkippedEnvVarResponse struct {
+	Key    string `json:"key"`
+	Reason string `json:"reason"`
+}
+
+// CopyEnvVarsResponse is the target service's env after a copy
+type CopyEnvVarsResponse struct {
+	EnvVars []EnvVarResponse        `json:"env_vars"`
+	Copied  []string                `json:"copied"`
+	Skipped []SkippedEnvVarResponse `json:"skipped"`
+}
+
+// CopyEnvVars handles POST /services/:id/env/copy-from/:source_service_id
+// Copies the env vars of another service of the org, all of them or the listed keys,
+// in one transaction. Keys the service already has are kept unless mode is
+// overwrite. Values are stored as they are, secrets included, so they copy verbatim;
+// secret manager references are copied as references. Links that can't resolve for
+// the target, to a database of another service, to a variable or service of another
+// project or to the target itself, are skipped.
+func (h *EnvVarHandler) CopyEnvVars(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	targetID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid service ID"))
+		return
+	}
+	sourceID, err := uuid.Parse(chi.URLParam(r, "source_service_id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid source service ID"))
+		return
+	}
+	if sourceID == targetID {
+		WriteError(w, domain.NewValidationError("Source and target services must differ"))
+		return
+	}
+
+	var req CopyEnvVarsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body"))
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = EnvCopyModeSkip
+	}
+	if req.Mode != EnvCopyModeSkip && req.Mode != EnvCopyModeOverwrite {
+		WriteError(w, domain.NewValidationError("mode must be skip or overwrite"))
+		return
+	}
+
+	target, ok := h.getOrgService(r.Context(), w, targetID, orgID, "Service")
+	if !ok {
+		return
+	}
+	source, ok := h.getOrgService(r.Context(), w, sourceID, orgID, "Source service")
+	if !ok {
+		return
+	}
+
+	envVars, err := h.store.ListEnvVarsByService(r.Context(), sourceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if len(req.Keys) > 0 {
+		var missing []string
+		for _, key := range req.Keys {
+			if !slices.ContainsFunc(envVars, func(ev *store.EnvVar) bool { return ev.Key == key }) {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			WriteError(w, domain.NewValidationError(fmt.Sprintf("Source service has no env vars %s", strings.Join(missing, ", "))))
+			return
+		}
+		envVars = slices.DeleteFunc(envVars, func(ev *store.EnvVar) bool { return !slices.Contains(req.Keys, ev.Key) })
+	}
+
+	response := CopyEnvVarsResponse{Copied: []string{}, Skipped: []SkippedEnvVarResponse{}}
+	var toCopy []*store.EnvVar
+	for _, ev := range envVars {
+		reason, err := h.envVarCopyBlocker(r.Context(), ev, source, target)
+		if err != nil {
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return
+		}
+		if reason != "" {
+			response.Skipped = append(response.Skipped, SkippedEnvVarResponse{Key: ev.Key, Reason: reason})
+			continue
+		}
+		toCopy = append(toCopy, ev)
+	}
+
+	copied, skipped, err := h.store.CopyEnvVars(r.Context(), targetID, toCopy, req.Mode == EnvCopyModeOverwrite)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	response.Copied = append(response.Copied, copied...)
+	for _, key := range skipped {
+		response.Skipped = append(response.Skipped, SkippedEnvVarResponse{Key: key, Reason: "already set on the service"})
+	}
+
+	result, err := h.store.ListEnvVarsByService(r.Context(), targetID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	response.EnvVars = make([]EnvVarResponse, len(result))
+	for i, ev := range result {
+		response.EnvVars[i] = toEnvVarResponse(ev)
+		// Don't expose secret values
+		if ev.IsSecret {
+			response.EnvVars[i].Value = "***"
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// getOrgService returns a service of the caller's org. It writes the error response
+// and returns false when the service can't be used.
+func (h *EnvVarHandler) getOrgService(ctx context.Context, w http.ResponseWriter, serviceID uuid.UUID, orgID, resource string) (*store.Service, bool) {
+	service, err := h.store.GetService(ctx, serviceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil, false
+	}
+	if service == nil {
+		WriteError(w, domain.NewNotFoundError(resource))
+		return nil, false
+	}
+
+	project, err := h.store.GetProject(ctx, service.ProjectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil, false
+	}
+	if !checkOrgAccess(w, project, orgID, resource) {
+		return nil, false
+	}
+	return service, true
+}
+
+// envVarCopyBlocker returns why an env var of source can't be copied to target, ""
+// when it can: a shared project variable or a linked service only resolves within its
+// project, and a database of a service can only be linked by that service
+func (h *EnvVarHandler) envVarCopyBlocker(ctx context.Context, ev *store.EnvVar, source, target *store.Service) (string, error) {
+	if ev.ProjectEnvVarID.Valid && source.ProjectID != target.ProjectID {
+		return "references a project variable of another project", nil
+	}
+	if ev.LinkedServiceID.Valid {
+		if source.ProjectID != target.ProjectID {
+			return "links to a service of another project", nil
+		}
+		if ev.LinkedServiceID.String == target.ID.String() {
+			return "links to the service itself", nil
+		}
+	}
+	if !ev.LinkedDatabaseID.Valid {
+		return "", nil
+	}
+
+	databaseID, err := uuid.Parse(ev.LinkedDatabaseID.String)
+	if err != nil {
+		return "links to an unknown database", nil
+	}
+	database, err := h.store.GetDatabase(ctx, databaseID)
+	if err != nil {
+		return "", err
+	}
+	if database == nil {
+		return "links to an unknown database", nil
+	}
+	if database.ServiceID.Valid && database.ServiceID.String != target.ID.String() {
+		return "links to a database of another service", nil
+	}
+	return "", nil
+}
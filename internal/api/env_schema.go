@@ -0,0 +1,267 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// CreateEnvSchemaRequest declares an environment variable a service expects
+type CreateEnvSchemaRequest struct {
+	Key      string `json:"key"`
+	Required bool   `json:"required"`
+	Type     string `json:"type,omitempty"`    // string (default), int, bool, url
+	Pattern  string `json:"pattern,omitempty"` // Optional regex the value must match
+}
+
+// UpdateEnvSchemaRequest represents a request to update an env schema entry
+type UpdateEnvSchemaRequest struct {
+	Required *bool   `json:"required,omitempty"`
+	Type     *string `json:"type,omitempty"`
+	Pattern  *string `json:"pattern,omitempty"` // Empty string removes the pattern
+}
+
+// EnvSchemaResponse represents an env schema entry in API responses
+type EnvSchemaResponse struct {
+	ID        string `json:"id"`
+	ServiceID string `json:"service_id"`
+	Key       string `json:"key"`
+	Required  bool   `json:"required"`
+	Type      string `json:"type"`
+	Pattern   string `json:"pattern,omitempty"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// EnvViolationResponse describes an env var that doesn't satisfy the schema
+type EnvViolationResponse struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// EnvValidationResponse is the result of checking a service's env against its schema
+type EnvValidationResponse struct {
+	Valid      bool                   `json:"valid"`
+	Violations []EnvViolationResponse `json:"violations"`
+}
+
+func toEnvSchemaResponse(e *store.EnvSchemaEntry) EnvSchemaResponse {
+	resp := EnvSchemaResponse{
+		ID:        e.ID.String(),
+		ServiceID: e.ServiceID.String(),
+		Key:       e.Key,
+		Required:  e.Required,
+		Type:      e.Type,
+		CreatedAt: e.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: e.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if e.Pattern.Valid {
+		resp.Pattern = e.Pattern.String
+	}
+	return resp
+}
+
+// getOwnedServiceID parses the {id} URL param and checks the service belongs to the caller's org.
+// It writes the error response and returns false when the service can't be used.
+func (h *EnvVarHandler) getOwnedServiceID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return uuid.Nil, false
+	}
+
+	serviceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid service ID"))
+		return uuid.Nil, false
+	}
+
+	if !h.serviceBelongsToOrg(r.Context(), w, serviceID, orgID) {
+		return uuid.Nil, false
+	}
+
+	return serviceID, true
+}
+
+// ListEnvSchema handles GET /services/:id/env-schema
+func (h *EnvVarHandler) ListEnvSchema(w http.ResponseWriter, r *http.Request) {
+	serviceID, ok := h.getOwnedServiceID(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.store.ListEnvSchemaByService(r.Context(), serviceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	response := make([]EnvSchemaResponse, 0, len(entries))
+	for _, e := range entries {
+		response = append(response, toEnvSchemaResponse(e))
+	}
+
+	WriteJSON(w, http.StatusOK, newListResponse(response))
+}
+
+// CreateEnvSchemaEntry handles POST /services/:id/env-schema
+func (h *EnvVarHandler) CreateEnvSchemaEntry(w http.ResponseWriter, r *http.Request) {
+	serviceID, ok := h.getOwnedServiceID(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateEnvSchemaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	req.Key = SanitizeEnvironmentVariableKey(req.Key)
+	req.Type = SanitizeName(req.Type)
+	if req.Type == "" {
+		req.Type = store.EnvTypeString
+	}
+
+	if validationErrs := ValidateCreateEnvSchemaRequest(&req); validationErrs.HasErrors() {
+		WriteError(w, validationErrs.ToAppError())
+		return
+	}
+
+	existing, err := h.store.GetEnvSchemaEntryByKey(r.Context(), serviceID, req.Key)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if existing != nil {
+		WriteError(w, domain.NewConflictError("Env schema entry already exists for this key"))
+		return
+	}
+
+	entry := &store.EnvSchemaEntry{
+		ServiceID: serviceID,
+		Key:       req.Key,
+		Required:  req.Required,
+		Type:      req.Type,
+		Pattern:   store.StringToNullString(req.Pattern),
+	}
+	if err := h.store.CreateEnvSchemaEntry(r.Context(), entry); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteCreated(w, toEnvSchemaResponse(entry))
+}
+
+// UpdateEnvSchemaEntry handles PATCH /services/:id/env-schema/:key
+func (h *EnvVarHandler) UpdateEnvSchemaEntry(w http.ResponseWriter, r *http.Request) {
+	serviceID, ok := h.getOwnedServiceID(w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := h.store.GetEnvSchemaEntryByKey(r.Context(), serviceID, chi.URLParam(r, "key"))
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if entry == nil {
+		WriteError(w, domain.NewNotFoundError("Env schema entry"))
+		return
+	}
+
+	var req UpdateEnvSchemaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body"))
+		return
+	}
+	sanitizeOptional(req.Type, SanitizeName)
+
+	if validationErrs := ValidateUpdateEnvSchemaRequest(&req); validationErrs.HasErrors() {
+		WriteError(w, validationErrs.ToAppError())
+		return
+	}
+
+	if req.Required != nil {
+		entry.Required = *req.Required
+	}
+	if req.Type != nil {
+		entry.Type = *req.Type
+	}
+	if req.Pattern != nil {
+		entry.Pattern = store.StringToNullString(*req.Pattern)
+	}
+
+	if err := h.store.UpdateEnvSchemaEntry(r.Context(), entry.ID, entry); err != nil {
+		if err == sql.ErrNoRows {
+			WriteError(w, domain.NewNotFoundError("Env schema entry"))
+			return
+		}
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, toEnvSchemaResponse(entry))
+}
+
+// DeleteEnvSchemaEntry handles DELETE /services/:id/env-schema/:key
+func (h *EnvVarHandler) DeleteEnvSchemaEntry(w http.ResponseWriter, r *http.Request) {
+	serviceID, ok := h.getOwnedServiceID(w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := h.store.GetEnvSchemaEntryByKey(r.Context(), serviceID, chi.URLParam(r, "key"))
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if entry == nil {
+		WriteError(w, domain.NewNotFoundError("Env schema entry"))
+		return
+	}
+
+	if err := h.store.DeleteEnvSchemaEntry(r.Context(), entry.ID); err != nil {
+		if err == sql.ErrNoRows {
+			WriteError(w, domain.NewNotFoundError("Env schema entry"))
+			return
+		}
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteNoContent(w)
+}
+
+// ValidateEnv handles POST /services/:id/env/validate
+// It runs the same env schema check as the deploy pipeline so problems can be
+// fixed before deploying.
+func (h *EnvVarHandler) ValidateEnv(w http.ResponseWriter, r *http.Request) {
+	serviceID, ok := h.getOwnedServiceID(w, r)
+	if !ok {
+		return
+	}
+
+	violations, err := h.store.ValidateServiceEnv(r.Context(), serviceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	response := EnvValidationResponse{
+		Valid:      len(violations) == 0,
+		Violations: make([]EnvViolationResponse, 0, len(violations)),
+	}
+	for _, v := range violations {
+		response.Violations = append(response.Violations, EnvViolationResponse{Key: v.Key, Message: v.Message})
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
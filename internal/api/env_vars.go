@@ -4,12 +4,14 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"slices"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 
 	"github.com/intelifox/click-deploy/internal/auth"
 	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/secrets"
 	"github.com/intelifox/click-deploy/internal/store"
 )
 
@@ -33,8 +35,32 @@ func RegisterEnvVarRoutes(r chi.Router, db *store.DB, cfg *config.Config) {
 	r.Post("/services/{id}/env", h.CreateEnvVar)
 	r.Patch("/services/{id}/env/{key}", h.UpdateEnvVar)
 	r.Delete("/services/{id}/env/{key}", h.DeleteEnvVar)
+	r.Get("/services/{id}/env/export", h.ExportEnvVars)
+	r.Post("/services/{id}/env/validate", h.ValidateEnv)
+	r.Post("/services/{id}/env/copy-from/{source_service_id}", h.CopyEnvVars)
+
+	// Env schema, checked against the resolved env before each deploy
+	r.Get("/services/{id}/env-schema", h.ListEnvSchema)
+	r.Post("/services/{id}/env-schema", h.CreateEnvSchemaEntry)
+	r.Patch("/services/{id}/env-schema/{key}", h.UpdateEnvSchemaEntry)
+	r.Delete("/services/{id}/env-schema/{key}", h.DeleteEnvSchemaEntry)
+
+	// Build-time args and secrets, never part of the runtime env
+	r.Get("/services/{id}/build-args", h.ListBuildArgs)
+	r.Post("/services/{id}/build-args", h.CreateBuildArg)
+	r.Patch("/services/{id}/build-args/{key}", h.UpdateBuildArg)
+	r.Delete("/services/{id}/build-args/{key}", h.DeleteBuildArg)
+
+	// Project-level env vars, inherited by every service in the project
+	r.Get("/projects/{id}/env", h.ListProjectEnvVars)
+	r.Post("/projects/{id}/env", h.CreateProjectEnvVar)
+	r.Patch("/projects/{id}/env/{key}", h.UpdateProjectEnvVar)
+	r.Delete("/projects/{id}/env/{key}", h.DeleteProjectEnvVar)
 }
 
+// errSecretInterpolation rejects interpolation of a secret, whose value is always used as is
+const errSecretInterpolation = "Secret values are never interpolated"
+
 // CreateEnvVarRequest represents a request to create an environment variable
 type CreateEnvVarRequest struct {
 	Key              string    `json:"key"`
@@ -42,6 +68,10 @@ type CreateEnvVarRequest struct {
 	IsSecret         bool      `json:"is_secret,omitempty"`
 	LinkedDatabaseID uuid.UUID `json:"linked_database_id,omitempty"` // Optional
 	LinkType         string    `json:"link_type,omitempty"`          // connection_url, host, port, username, password, database
+	ProjectEnvVarID  uuid.UUID `json:"project_env_var_id,omitempty"` // Optional, references a shared project variable
+	SecretRef        *secrets.SecretRef `json:"secret_ref,omitempty"`  // Optional, the value is read from a secret manager at deploy time
+	LinkedServiceID  uuid.UUID `json:"linked_service_id,omitempty"`  // Optional, another service of the project; link_type service_url or service_host
+	Interpolate      *bool     `json:"interpolate,omitempty"`        // Optional, expand ${VAR} references in the value at deploy time; not for secrets
 }
 
 // EnvVarResponse represents an environment variable in API responses
@@ -53,17 +83,22 @@ type EnvVarResponse struct {
 	IsSecret         bool   `json:"is_secret"`
 	LinkedDatabaseID string `json:"linked_database_id,omitempty"`
 	LinkType         string `json:"link_type,omitempty"`
+	ProjectEnvVarID  string `json:"project_env_var_id,omitempty"`
+	SecretRef        *secrets.SecretRef `json:"secret_ref,omitempty"`
+	LinkedServiceID  string `json:"linked_service_id,omitempty"`
+	Interpolate      bool   `json:"interpolate"`
 	CreatedAt        string `json:"created_at"`
 }
 
 // toEnvVarResponse converts a store.EnvVar to EnvVarResponse
 func toEnvVarResponse(ev *store.EnvVar) EnvVarResponse {
 	resp := EnvVarResponse{
-		ID:        ev.ID.String(),
-		ServiceID: ev.ServiceID.String(),
-		Key:       ev.Key,
-		IsSecret:  ev.IsSecret,
-		CreatedAt: ev.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ID:          ev.ID.String(),
+		ServiceID:   ev.ServiceID.String(),
+		Key:         ev.Key,
+		IsSecret:    ev.IsSecret,
+		Interpolate: ev.Interpolate,
+		CreatedAt:   ev.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
 	
 	if ev.Value.Valid {
@@ -78,9 +113,52 @@ func toEnvVarResponse(ev *store.EnvVar) EnvVarResponse {
 		resp.LinkType = ev.LinkType.String
 	}
 	
+	if ev.ProjectEnvVarID.Valid {
+		resp.ProjectEnvVarID = ev.ProjectEnvVarID.String
+	}
+
+	if ev.SecretRef.Valid {
+		if ref, err := secrets.ParseSecretRef(ev.SecretRef.String); err == nil {
+			resp.SecretRef = &ref
+		}
+	}
+
+	if ev.LinkedServiceID.Valid {
+		resp.LinkedServiceID = ev.LinkedServiceID.String
+	}
+	
 	return resp
 }
 
+// validServiceLinkTypes are the addresses of a service an env var can be linked to
+var validServiceLinkTypes = []string{store.EnvLinkServiceURL, store.EnvLinkServiceHost}
+
+// validateServiceLink checks that service can link an env var to the service
+// linkedServiceID, which must be another service of its project, writing the error
+// response if not
+func (h *EnvVarHandler) validateServiceLink(w http.ResponseWriter, r *http.Request, service *store.Service, linkedServiceID uuid.UUID, linkType string) bool {
+	if linkedServiceID == service.ID {
+		http.Error(w, "A service can't link to itself", http.StatusBadRequest)
+		return false
+	}
+
+	linked, err := h.store.GetService(r.Context(), linkedServiceID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if linked == nil || linked.ProjectID != service.ProjectID {
+		http.Error(w, "Linked service not found in this project", http.StatusBadRequest)
+		return false
+	}
+
+	if !slices.Contains(validServiceLinkTypes, linkType) {
+		http.Error(w, "Link type must be service_url or service_host when linking to a service", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
 // CreateEnvVar creates a new environment variable
 func (h *EnvVarHandler) CreateEnvVar(w http.ResponseWriter, r *http.Request) {
 	orgID := auth.GetOrgID(r.Context())
@@ -112,8 +190,7 @@ func (h *EnvVarHandler) CreateEnvVar(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Service not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
@@ -123,17 +200,50 @@ func (h *EnvVarHandler) CreateEnvVar(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	// Values are stored verbatim: they may legitimately hold newlines (e.g. PEM keys)
+	req.Key = SanitizeName(req.Key)
+	req.LinkType = SanitizeName(req.LinkType)
 
 	// Validation
-	if req.Key == "" {
-		http.Error(w, "Key is required", http.StatusBadRequest)
+	if errs := ValidateEnvVarKey(req.Key); errs.HasErrors() {
+		http.Error(w, errs.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// If linked to database, verify database exists and belongs to same project
 	var linkedDatabaseID sql.NullString
 	var linkType sql.NullString
-	if req.LinkedDatabaseID != uuid.Nil {
+	var projectEnvVarID sql.NullString
+	var secretRef sql.NullString
+	var linkedServiceID sql.NullString
+	if req.ProjectEnvVarID != uuid.Nil {
+		// Reference to a shared project variable, resolved at deploy time
+		shared, err := h.store.GetProjectEnvVar(r.Context(), req.ProjectEnvVarID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if shared == nil || shared.ProjectID != service.ProjectID {
+			http.Error(w, "Project environment variable not found", http.StatusBadRequest)
+			return
+		}
+
+		projectEnvVarID = sql.NullString{String: req.ProjectEnvVarID.String(), Valid: true}
+	} else if req.SecretRef != nil {
+		// Only the reference is stored, the value is read when the service deploys
+		var ok bool
+		if secretRef, ok = h.encodeSecretRef(w, project, req.SecretRef); !ok {
+			return
+		}
+		req.IsSecret = true
+	} else if req.LinkedServiceID != uuid.Nil {
+		// Resolved at deploy time to the linked service's in-cluster address
+		if !h.validateServiceLink(w, r, service, req.LinkedServiceID, req.LinkType) {
+			return
+		}
+		linkedServiceID = sql.NullString{String: req.LinkedServiceID.String(), Valid: true}
+		linkType = sql.NullString{String: req.LinkType, Valid: true}
+	} else if req.LinkedDatabaseID != uuid.Nil {
 		database, err := h.store.GetDatabase(r.Context(), req.LinkedDatabaseID)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -174,7 +284,12 @@ func (h *EnvVarHandler) CreateEnvVar(w http.ResponseWriter, r *http.Request) {
 		linkedDatabaseID = sql.NullString{String: req.LinkedDatabaseID.String(), Valid: true}
 		linkType = sql.NullString{String: req.LinkType, Valid: true}
 	} else if req.Value == "" {
-		http.Error(w, "Value is required if not linking to database", http.StatusBadRequest)
+		http.Error(w, "Value is required if not linking to a database or service", http.StatusBadRequest)
+		return
+	}
+	interpolate := req.Interpolate != nil && *req.Interpolate
+	if interpolate && req.IsSecret {
+		http.Error(w, errSecretInterpolation, http.StatusBadRequest)
 		return
 	}
 
@@ -185,9 +300,13 @@ func (h *EnvVarHandler) CreateEnvVar(w http.ResponseWriter, r *http.Request) {
 		IsSecret:        req.IsSecret,
 		LinkedDatabaseID: linkedDatabaseID,
 		LinkType:        linkType,
+		ProjectEnvVarID: projectEnvVarID,
+		SecretRef:       secretRef,
+		LinkedServiceID: linkedServiceID,
+		Interpolate:     interpolate,
 	}
 
-	if req.Value != "" {
+	if req.Value != "" && !secretRef.Valid && !linkedServiceID.Valid {
 		envVar.Value = sql.NullString{String: req.Value, Valid: true}
 	}
 
@@ -232,8 +351,7 @@ func (h *EnvVarHandler) ListEnvVars(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Service not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
@@ -254,7 +372,7 @@ func (h *EnvVarHandler) ListEnvVars(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(responses)
+	json.NewEncoder(w).Encode(newListResponse(responses))
 }
 
 // UpdateEnvVar updates an environment variable
@@ -290,8 +408,7 @@ func (h *EnvVarHandler) UpdateEnvVar(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Service not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
@@ -321,19 +438,44 @@ func (h *EnvVarHandler) UpdateEnvVar(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	req.LinkType = SanitizeName(req.LinkType)
 
-	// Update values
-	if req.Value != "" {
+	// Update values. A plain value replaces a secret reference and the other way round.
+	if req.SecretRef != nil {
+		secretRef, ok := h.encodeSecretRef(w, project, req.SecretRef)
+		if !ok {
+			return
+		}
+		envVar.SecretRef = secretRef
+		envVar.Value = sql.NullString{}
+		envVar.IsSecret = true
+	} else if req.Value != "" {
 		envVar.Value = sql.NullString{String: req.Value, Valid: true}
+		envVar.SecretRef = sql.NullString{}
 	}
 	if req.IsSecret {
 		envVar.IsSecret = req.IsSecret
 	}
 	if req.LinkedDatabaseID != uuid.Nil {
 		envVar.LinkedDatabaseID = sql.NullString{String: req.LinkedDatabaseID.String(), Valid: true}
+		envVar.LinkedServiceID = sql.NullString{}
 		if req.LinkType != "" {
 			envVar.LinkType = sql.NullString{String: req.LinkType, Valid: true}
 		}
+	} else if req.LinkedServiceID != uuid.Nil {
+		if !h.validateServiceLink(w, r, service, req.LinkedServiceID, req.LinkType) {
+			return
+		}
+		envVar.LinkedServiceID = sql.NullString{String: req.LinkedServiceID.String(), Valid: true}
+		envVar.LinkedDatabaseID = sql.NullString{}
+		envVar.LinkType = sql.NullString{String: req.LinkType, Valid: true}
+	}
+	if req.Interpolate != nil {
+		envVar.Interpolate = *req.Interpolate
+	}
+	if envVar.Interpolate && envVar.IsSecret {
+		http.Error(w, errSecretInterpolation, http.StatusBadRequest)
+		return
 	}
 
 	if err := h.store.UpdateEnvVar(r.Context(), envVar.ID, envVar); err != nil {
@@ -378,8 +520,7 @@ func (h *EnvVarHandler) DeleteEnvVar(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Service not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
@@ -415,3 +556,19 @@ func (h *EnvVarHandler) DeleteEnvVar(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+
+// encodeSecretRef validates a secret reference and encodes it for storage, writing
+// the error response if it's invalid. It must point below the path prefix of the
+// project's org: secrets are read with the platform's token.
+func (h *EnvVarHandler) encodeSecretRef(w http.ResponseWriter, project *store.Project, ref *secrets.SecretRef) (sql.NullString, bool) {
+	if err := ref.ValidateScope(secrets.OrgPathPrefix(h.config.VaultOrgPathPrefix, project.CasdoorOrgID)); err != nil {
+		http.Error(w, "Invalid secret reference: "+err.Error(), http.StatusBadRequest)
+		return sql.NullString{}, false
+	}
+	encoded, err := json.Marshal(ref)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return sql.NullString{}, false
+	}
+	return sql.NullString{String: string(encoded), Valid: true}, true
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/secrets"
 	"github.com/intelifox/click-deploy/internal/store"
 	"github.com/intelifox/click-deploy/internal/testutil"
 )
@@ -19,7 +20,7 @@ func TestEnvVarHandler_CreateEnvVar(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewEnvVarHandler(dbStore, &config.Config{})
+	handler := NewEnvVarHandler(dbStore, &config.Config{VaultOrgPathPrefix: "secret/data/zyndra/{org_id}"})
 
 	// Create a test project
 	orgID := "test-org-env-001"
@@ -48,6 +49,7 @@ func TestEnvVarHandler_CreateEnvVar(t *testing.T) {
 		t.Fatalf("Failed to create test service: %v", err)
 	}
 
+	interpolate := true
 	tests := []struct {
 		name           string
 		requestBody    CreateEnvVarRequest
@@ -70,6 +72,25 @@ func TestEnvVarHandler_CreateEnvVar(t *testing.T) {
 			},
 			expectedStatus: http.StatusCreated,
 		},
+		{
+			name: "interpolated env var",
+			requestBody: CreateEnvVarRequest{
+				Key:         "API_URL",
+				Value:       "https://${API_HOST}/v1",
+				Interpolate: &interpolate,
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "interpolated secret",
+			requestBody: CreateEnvVarRequest{
+				Key:         "TOKEN",
+				Value:       "${API_KEY}",
+				IsSecret:    true,
+				Interpolate: &interpolate,
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name: "missing key",
 			requestBody: CreateEnvVarRequest{
@@ -77,6 +98,62 @@ func TestEnvVarHandler_CreateEnvVar(t *testing.T) {
 			},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name: "lowercase key",
+			requestBody: CreateEnvVarRequest{
+				Key:   "http_proxy",
+				Value: "http://proxy:3128",
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "key with surrounding whitespace and control characters",
+			requestBody: CreateEnvVarRequest{
+				Key:   " LOG_LEVEL\x00\n",
+				Value: "debug",
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "key with a dash",
+			requestBody: CreateEnvVarRequest{
+				Key:   "API-URL",
+				Value: "https://example.com",
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "key starting with a digit",
+			requestBody: CreateEnvVarRequest{
+				Key:   "1PASSWORD",
+				Value: "value",
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "secret reference of the org",
+			requestBody: CreateEnvVarRequest{
+				Key:       "VAULT_KEY",
+				SecretRef: &secrets.SecretRef{Provider: secrets.ProviderVault, Path: "secret/data/zyndra/" + orgID + "/app", Key: "API_KEY"},
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "secret reference of another org",
+			requestBody: CreateEnvVarRequest{
+				Key:       "STOLEN_KEY",
+				SecretRef: &secrets.SecretRef{Provider: secrets.ProviderVault, Path: "secret/data/zyndra/other-org/app", Key: "API_KEY"},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "secret reference escaping the org",
+			requestBody: CreateEnvVarRequest{
+				Key:       "ESCAPED_KEY",
+				SecretRef: &secrets.SecretRef{Provider: secrets.ProviderVault, Path: "secret/data/zyndra/" + orgID + "/../other-org/app", Key: "API_KEY"},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -160,13 +237,13 @@ func TestEnvVarHandler_ListEnvVars(t *testing.T) {
 		t.Errorf("Expected status %d, got %d. Response: %s", http.StatusOK, w.Code, w.Body.String())
 	}
 
-	var envVars []*store.EnvVar
+	var envVars ListResponse[EnvVarResponse]
 	if err := json.NewDecoder(w.Body).Decode(&envVars); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if len(envVars) != 2 {
-		t.Errorf("Expected 2 env vars, got %d", len(envVars))
+	if len(envVars.Items) != 2 {
+		t.Errorf("Expected 2 env vars, got %d", len(envVars.Items))
 	}
 }
 
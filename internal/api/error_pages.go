@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/worker"
+)
+
+// maxErrorPageSize is the largest error page we push to Caddy
+const maxErrorPageSize = 256 * 1024
+
+// ErrorPageRequest represents a request to set a service's custom error page
+type ErrorPageRequest struct {
+	HTML string `json:"html"`
+}
+
+// ErrorPageResponse represents a service's custom error page
+type ErrorPageResponse struct {
+	ServiceID  string `json:"service_id"`
+	HTML       string `json:"html"`
+	Configured bool   `json:"configured"`
+}
+
+// GetErrorPage handles GET /services/:id/error-page
+func (h *CustomDomainHandler) GetErrorPage(w http.ResponseWriter, r *http.Request) {
+	service, ok := h.getOwnedService(w, r)
+	if !ok {
+		return
+	}
+
+	html, err := h.store.GetServiceErrorPage(r.Context(), service.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, ErrorPageResponse{
+		ServiceID:  service.ID.String(),
+		HTML:       html,
+		Configured: html != "",
+	})
+}
+
+// SetErrorPage handles PUT /services/:id/error-page
+func (h *CustomDomainHandler) SetErrorPage(w http.ResponseWriter, r *http.Request) {
+	service, ok := h.getOwnedService(w, r)
+	if !ok {
+		return
+	}
+
+	var req ErrorPageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body"))
+		return
+	}
+	if req.HTML == "" {
+		WriteError(w, domain.NewValidationError("html is required"))
+		return
+	}
+	if len(req.HTML) > maxErrorPageSize {
+		WriteError(w, domain.NewValidationError("html must be at most 256KB"))
+		return
+	}
+
+	if err := h.store.SetServiceErrorPage(r.Context(), service.ID, req.HTML); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	h.syncErrorPage(r, service)
+
+	WriteJSON(w, http.StatusOK, ErrorPageResponse{
+		ServiceID:  service.ID.String(),
+		HTML:       req.HTML,
+		Configured: true,
+	})
+}
+
+// DeleteErrorPage handles DELETE /services/:id/error-page
+func (h *CustomDomainHandler) DeleteErrorPage(w http.ResponseWriter, r *http.Request) {
+	service, ok := h.getOwnedService(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.SetServiceErrorPage(r.Context(), service.ID, ""); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	// Re-pushing with an empty page restores Caddy's default error responses
+	h.syncErrorPage(r, service)
+
+	WriteNoContent(w)
+}
+
+// syncErrorPage re-pushes the routes of the service's custom domains so Caddy
+// picks up the stored error page
+func (h *CustomDomainHandler) syncErrorPage(r *http.Request, service *store.Service) {
+	if h.config.CaddyAdminURL == "" {
+		return
+	}
+	worker.SyncServiceCustomDomainRoutes(r.Context(), h.store, h.caddy, service)
+}
+
+// getOwnedService loads the service from the URL and checks it belongs to the caller's org,
+// writing the error response if not
+func (h *CustomDomainHandler) getOwnedService(w http.ResponseWriter, r *http.Request) (*store.Service, bool) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return nil, false
+	}
+
+	serviceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid service ID"))
+		return nil, false
+	}
+
+	service, err := h.store.GetService(r.Context(), serviceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil, false
+	}
+	if service == nil {
+		WriteError(w, domain.NewNotFoundError("Service"))
+		return nil, false
+	}
+
+	project, err := h.store.GetProject(r.Context(), service.ProjectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil, false
+	}
+	if !checkOrgAccess(w, project, orgID, "Service") {
+		return nil, false
+	}
+
+	return service, true
+}
@@ -1,10 +1,15 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -16,15 +21,21 @@ import (
 	"github.com/intelifox/click-deploy/internal/store"
 )
 
+// listingCacheTTL is how long repository and branch listings are served from the
+// cache before the provider is called again
+const listingCacheTTL = time.Minute
+
 type GitHandler struct {
-	store  *store.DB
-	config *config.Config
+	store    *store.DB
+	config   *config.Config
+	listings *git.CachedLister
 }
 
 func NewGitHandler(store *store.DB, cfg *config.Config) *GitHandler {
 	return &GitHandler{
-		store:  store,
-		config: cfg,
+		store:    store,
+		config:   cfg,
+		listings: git.NewCachedLister(git.NewMemoryCache(), listingCacheTTL),
 	}
 }
 
@@ -50,11 +61,17 @@ func RegisterGitRoutes(r chi.Router, db *store.DB, cfg *config.Config) {
 	// Git connections management
 	r.Get("/git/connections", h.ListConnections)
 	r.Delete("/git/connections/{id}", h.DeleteConnection)
+	r.Get("/git/connections/{id}/test", h.TestConnection)
+	r.Get("/git/connections/{id}/reconnect-url", h.GetReconnectURL)
 
 	// Repository operations
 	r.Get("/git/repos", h.ListRepositories)
 	r.Get("/git/repos/{owner}/{repo}/branches", h.ListBranches)
 	r.Get("/git/repos/{owner}/{repo}/tree", h.GetRepositoryTree)
+	r.Get("/git/repos/{owner}/{repo}/file", h.GetFileContent)
+
+	// Per-service webhook management
+	r.Post("/services/{id}/git-source/rotate-webhook-secret", h.RotateWebhookSecret)
 }
 
 // GetGitHubOAuthURL returns the GitHub OAuth URL as JSON (for frontend to redirect)
@@ -198,11 +215,12 @@ func (h *GitHandler) CallbackGitHub(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse state token to get orgID and userID (callback is public, no auth context)
-	orgID, userID, err := git.ParseOAuthState(state)
+	oauthState, err := git.DecodeOAuthState(state)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Invalid or expired state token: %v", err), http.StatusBadRequest)
 		return
 	}
+	orgID, userID := oauthState.OrgID, oauthState.UserID
 
 	if orgID == "" || userID == "" {
 		http.Error(w, "Missing orgID or userID in state token", http.StatusBadRequest)
@@ -228,13 +246,6 @@ func (h *GitHandler) CallbackGitHub(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if connection already exists
-	existing, err := h.store.GetGitConnectionByOrgAndProvider(r.Context(), orgID, "github")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
 	var refreshToken sql.NullString
 	if token.RefreshToken != "" {
 		refreshToken = sql.NullString{String: token.RefreshToken, Valid: true}
@@ -256,15 +267,7 @@ func (h *GitHandler) CallbackGitHub(w http.ResponseWriter, r *http.Request) {
 		ConnectedBy:    sql.NullString{String: userID, Valid: true},
 	}
 
-	if existing != nil {
-		// Update existing connection
-		err = h.store.UpdateGitConnection(r.Context(), existing.ID, connection)
-	} else {
-		// Create new connection
-		err = h.store.CreateGitConnection(r.Context(), connection)
-	}
-
-	if err != nil {
+	if err := h.saveGitConnection(r.Context(), connection, oauthState.ConnectionID); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -294,11 +297,12 @@ func (h *GitHandler) CallbackGitLab(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse state token to get orgID and userID (callback is public, no auth context)
-	orgID, userID, err := git.ParseOAuthState(state)
+	oauthState, err := git.DecodeOAuthState(state)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Invalid or expired state token: %v", err), http.StatusBadRequest)
 		return
 	}
+	orgID, userID := oauthState.OrgID, oauthState.UserID
 
 	if orgID == "" || userID == "" {
 		http.Error(w, "Missing orgID or userID in state token", http.StatusBadRequest)
@@ -325,13 +329,6 @@ func (h *GitHandler) CallbackGitLab(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if connection already exists
-	existing, err := h.store.GetGitConnectionByOrgAndProvider(r.Context(), orgID, "gitlab")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
 	var refreshToken sql.NullString
 	if token.RefreshToken != "" {
 		refreshToken = sql.NullString{String: token.RefreshToken, Valid: true}
@@ -353,15 +350,7 @@ func (h *GitHandler) CallbackGitLab(w http.ResponseWriter, r *http.Request) {
 		ConnectedBy:    sql.NullString{String: userID, Valid: true},
 	}
 
-	if existing != nil {
-		// Update existing connection
-		err = h.store.UpdateGitConnection(r.Context(), existing.ID, connection)
-	} else {
-		// Create new connection
-		err = h.store.CreateGitConnection(r.Context(), connection)
-	}
-
-	if err != nil {
+	if err := h.saveGitConnection(r.Context(), connection, oauthState.ConnectionID); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -374,7 +363,87 @@ func (h *GitHandler) CallbackGitLab(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// ListConnections lists all git connections for the organization
+// saveGitConnection stores the connection an OAuth callback authorized. An org has
+// one connection per provider: reconnecting updates the existing row (the one being
+// reconnected, if the state named one) in place, so the git sources that reference
+// it keep working with the new token.
+func (h *GitHandler) saveGitConnection(ctx context.Context, connection *store.GitConnection, reconnectID string) error {
+	var existing *store.GitConnection
+	if id, err := uuid.Parse(reconnectID); err == nil {
+		existing, err = h.store.GetGitConnection(ctx, id)
+		if err != nil {
+			return err
+		}
+		if existing != nil && (existing.CasdoorOrgID != connection.CasdoorOrgID || existing.Provider != connection.Provider) {
+			existing = nil
+		}
+	}
+	if existing == nil {
+		var err error
+		existing, err = h.store.GetGitConnectionByOrgAndProvider(ctx, connection.CasdoorOrgID, connection.Provider)
+		if err != nil {
+			return err
+		}
+	}
+
+	if existing == nil {
+		return h.store.CreateGitConnection(ctx, connection)
+	}
+	connection.ID = existing.ID
+	connection.CreatedAt = existing.CreatedAt
+	return h.store.UpdateGitConnection(ctx, existing.ID, connection)
+}
+
+// GitConnectionResponse represents a git connection in API responses (tokens are never included)
+type GitConnectionResponse struct {
+	ID             string  `json:"id"`
+	Provider       string  `json:"provider"`
+	AccountName    string  `json:"account_name,omitempty"`
+	AccountID      string  `json:"account_id,omitempty"`
+	ConnectedBy    string  `json:"connected_by,omitempty"`
+	TokenExpiresAt *string `json:"token_expires_at,omitempty"`
+	TokenValid     bool    `json:"token_valid"`
+	Status         string  `json:"status"` // valid, expired
+	CreatedAt      string  `json:"created_at"`
+	UpdatedAt      string  `json:"updated_at"`
+}
+
+// toGitConnectionResponse converts a store.GitConnection to GitConnectionResponse.
+// Tokens without an expiry (GitHub OAuth) are considered valid.
+func toGitConnectionResponse(conn *store.GitConnection, now time.Time) GitConnectionResponse {
+	resp := GitConnectionResponse{
+		ID:         conn.ID.String(),
+		Provider:   conn.Provider,
+		TokenValid: true,
+		Status:     "valid",
+		CreatedAt:  conn.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:  conn.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+
+	if conn.AccountName.Valid {
+		resp.AccountName = conn.AccountName.String
+	}
+	if conn.AccountID.Valid {
+		resp.AccountID = conn.AccountID.String
+	}
+	if conn.ConnectedBy.Valid {
+		resp.ConnectedBy = conn.ConnectedBy.String
+	}
+	if conn.TokenExpiresAt.Valid {
+		expiresAt := conn.TokenExpiresAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		resp.TokenExpiresAt = &expiresAt
+		if !now.Before(conn.TokenExpiresAt.Time) {
+			resp.TokenValid = false
+			resp.Status = "expired"
+		}
+	}
+
+	return resp
+}
+
+// ListConnections lists git connections for the organization.
+// Supports filtering by provider and status (valid, expired), and limit/offset pagination
+// with the unpaginated count in X-Total-Count.
 func (h *GitHandler) ListConnections(w http.ResponseWriter, r *http.Request) {
 	orgID := auth.GetOrgID(r.Context())
 	if orgID == "" {
@@ -382,22 +451,196 @@ func (h *GitHandler) ListConnections(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	query := r.URL.Query()
+	provider := query.Get("provider")
+	status := query.Get("status")
+	if status != "" && status != "valid" && status != "expired" {
+		http.Error(w, "Invalid status. Must be valid or expired", http.StatusBadRequest)
+		return
+	}
+
+	params, err := ParseListParams(r, ListSpec{DefaultLimit: 100, MaxLimit: 500})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	connections, err := h.store.ListGitConnectionsByOrg(r.Context(), orgID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Don't expose access tokens
+	now := time.Now()
+	responses := make([]GitConnectionResponse, 0, len(connections))
 	for _, conn := range connections {
-		conn.AccessToken = ""
-		if conn.RefreshToken.Valid {
-			conn.RefreshToken = sql.NullString{}
+		if provider != "" && conn.Provider != provider {
+			continue
+		}
+		resp := toGitConnectionResponse(conn, now)
+		if status != "" && resp.Status != status {
+			continue
+		}
+		responses = append(responses, resp)
+	}
+
+	page := pageOf(responses, params.Offset, params.Limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	json.NewEncoder(w).Encode(page)
+}
+
+// requiredGitHubScopes are the OAuth scopes needed to deploy private repos and register webhooks
+var requiredGitHubScopes = []string{"repo", "admin:repo_hook"}
+
+// TestConnectionResponse represents the result of testing a git connection
+type TestConnectionResponse struct {
+	Valid              bool     `json:"valid"`
+	AccountName        string   `json:"account_name,omitempty"`
+	Scopes             []string `json:"scopes,omitempty"`
+	MissingScopes      []string `json:"missing_scopes,omitempty"`
+	RateLimitRemaining int      `json:"rate_limit_remaining"`
+	NeedsReconnect     bool     `json:"needs_reconnect"`
+}
+
+// TestConnection checks a git connection's token against the provider's /user endpoint.
+// An unauthorized token marks the connection as expired so the UI asks to reconnect.
+func (h *GitHandler) TestConnection(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid connection ID", http.StatusBadRequest)
+		return
+	}
+
+	connection, err := h.store.GetGitConnection(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if connection == nil || connection.CasdoorOrgID != orgID {
+		http.Error(w, "Connection not found", http.StatusNotFound)
+		return
+	}
+
+	var check *git.TokenCheck
+	switch connection.Provider {
+	case "github":
+		check, err = git.NewGitHubClient(connection.AccessToken).CheckToken(r.Context())
+	case "gitlab":
+		check, err = git.NewGitLabClient(connection.AccessToken, h.config.GitLabBaseURL).CheckToken(r.Context())
+	default:
+		http.Error(w, "Unsupported provider", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		WriteError(w, domain.NewAppError(domain.ErrCodeExternalAPI, "Failed to reach git provider: "+err.Error(), http.StatusBadGateway))
+		return
+	}
+
+	resp := TestConnectionResponse{
+		Valid:              check.Valid,
+		AccountName:        check.AccountName,
+		Scopes:             check.Scopes,
+		RateLimitRemaining: check.RateLimitRemaining,
+		NeedsReconnect:     !check.Valid,
+	}
+
+	if !check.Valid {
+		// Expire the token so ListConnections reports the connection as expired
+		connection.TokenExpiresAt = sql.NullTime{Time: time.Now(), Valid: true}
+		if err := h.store.UpdateGitConnection(r.Context(), connection.ID, connection); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else if connection.Provider == "github" && check.Scopes != nil {
+		granted := make(map[string]bool, len(check.Scopes))
+		for _, scope := range check.Scopes {
+			granted[scope] = true
+		}
+		for _, scope := range requiredGitHubScopes {
+			if !granted[scope] {
+				resp.MissingScopes = append(resp.MissingScopes, scope)
+			}
 		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(connections)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetReconnectURL returns a fresh OAuth URL for a git connection, e.g. after its
+// token was revoked. The callback updates this connection instead of creating a
+// new one, so the services using it keep their git sources.
+func (h *GitHandler) GetReconnectURL(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	userID := auth.GetUserID(r.Context())
+	if orgID == "" || userID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID or User ID not found in token"))
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid connection ID"))
+		return
+	}
+
+	connection, err := h.store.GetGitConnection(r.Context(), id)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if connection == nil || connection.CasdoorOrgID != orgID {
+		WriteError(w, domain.NewNotFoundError("Git connection"))
+		return
+	}
+
+	state, err := git.GenerateReconnectOAuthState(connection.Provider, orgID, userID, connection.ID.String())
+	if err != nil {
+		WriteError(w, domain.ErrInternal.WithError(err))
+		return
+	}
+
+	var authURL string
+	switch connection.Provider {
+	case "github":
+		if h.config.GitHubClientID == "" || h.config.GitHubClientSecret == "" {
+			WriteError(w, domain.NewInvalidInputError("GitHub OAuth is not configured. Please set GITHUB_CLIENT_ID and GITHUB_CLIENT_SECRET environment variables."))
+			return
+		}
+		authURL = git.GetGitHubOAuthURL(&git.OAuthConfig{
+			GitHubClientID:     h.config.GitHubClientID,
+			GitHubClientSecret: h.config.GitHubClientSecret,
+			GitHubRedirectURL:  h.config.GitHubRedirectURL,
+		}, state.StateToken)
+	case "gitlab":
+		if h.config.GitLabClientID == "" || h.config.GitLabClientSecret == "" {
+			WriteError(w, domain.NewInvalidInputError("GitLab OAuth is not configured. Please set GITLAB_CLIENT_ID and GITLAB_CLIENT_SECRET environment variables."))
+			return
+		}
+		authURL = git.GetGitLabOAuthURL(&git.OAuthConfig{
+			GitLabClientID:     h.config.GitLabClientID,
+			GitLabClientSecret: h.config.GitLabClientSecret,
+			GitLabRedirectURL:  h.config.GitLabRedirectURL,
+			GitLabBaseURL:      h.config.GitLabBaseURL,
+		}, state.StateToken)
+	default:
+		WriteError(w, domain.NewInvalidInputError("Unsupported provider"))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, map[string]string{
+		"auth_url":      authURL,
+		"connection_id": connection.ID.String(),
+	})
 }
 
 // DeleteConnection deletes a git connection
@@ -428,7 +671,9 @@ func (h *GitHandler) DeleteConnection(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// ListRepositories lists repositories for the authenticated user
+// ListRepositories lists repositories for the authenticated user.
+// Listings are cached per connection for a minute; ?refresh=true bypasses the cache.
+// The X-Cache response header tells whether the listing was cached (HIT, STALE) or not.
 func (h *GitHandler) ListRepositories(w http.ResponseWriter, r *http.Request) {
 	orgID := auth.GetOrgID(r.Context())
 	if orgID == "" {
@@ -449,33 +694,34 @@ func (h *GitHandler) ListRepositories(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if connection == nil {
-		WriteError(w, domain.NewNotFoundError(fmt.Sprintf("No %s connection found. Please connect your %s account first.", provider, provider)))
+		WriteError(w, domain.NewGitNotConnectedError(fmt.Sprintf("No %s connection found. Please connect your %s account first.", provider, provider)))
 		return
 	}
 
-	var repos []*git.Repository
+	var fetch func(context.Context) ([]*git.Repository, error)
 	switch provider {
 	case "github":
-		client := git.NewGitHubClient(connection.AccessToken)
-		repos, err = client.GetUserRepositories(r.Context())
+		fetch = git.NewGitHubClient(connection.AccessToken).GetUserRepositories
 	case "gitlab":
-		client := git.NewGitLabClient(connection.AccessToken, h.config.GitLabBaseURL)
-		repos, err = client.GetUserRepositories(r.Context())
+		fetch = git.NewGitLabClient(connection.AccessToken, h.config.GitLabBaseURL).GetUserRepositories
 	default:
 		http.Error(w, "Unsupported provider", http.StatusBadRequest)
 		return
 	}
 
+	refresh := r.URL.Query().Get("refresh") == "true"
+	repos, cacheStatus, err := h.listings.Repositories(r.Context(), provider, connection.ID.String(), refresh, fetch)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeListingError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(repos)
+	w.Header().Set("X-Cache", string(cacheStatus))
+	json.NewEncoder(w).Encode(newListResponse(repos))
 }
 
-// ListBranches lists branches for a repository
+// ListBranches lists branches for a repository, cached like ListRepositories
 func (h *GitHandler) ListBranches(w http.ResponseWriter, r *http.Request) {
 	orgID := auth.GetOrgID(r.Context())
 	if orgID == "" {
@@ -499,33 +745,73 @@ func (h *GitHandler) ListBranches(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if connection == nil {
-		http.Error(w, "No connection found", http.StatusNotFound)
+		WriteError(w, domain.NewGitNotConnectedError(fmt.Sprintf("No %s connection found. Please connect your %s account first.", provider, provider)))
 		return
 	}
 
-	var branches []*git.Branch
+	var fetch func(context.Context) ([]*git.Branch, error)
 	switch provider {
 	case "github":
 		client := git.NewGitHubClient(connection.AccessToken)
-		branches, err = client.GetBranches(r.Context(), owner, repo)
+		fetch = func(ctx context.Context) ([]*git.Branch, error) { return client.GetBranches(ctx, owner, repo) }
 	case "gitlab":
 		client := git.NewGitLabClient(connection.AccessToken, h.config.GitLabBaseURL)
-		branches, err = client.GetBranches(r.Context(), owner, repo)
+		fetch = func(ctx context.Context) ([]*git.Branch, error) { return client.GetBranches(ctx, owner, repo) }
 	default:
 		http.Error(w, "Unsupported provider", http.StatusBadRequest)
 		return
 	}
 
+	refresh := r.URL.Query().Get("refresh") == "true"
+	branches, cacheStatus, err := h.listings.Branches(r.Context(), provider, connection.ID.String(), owner, repo, refresh, fetch)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeListingError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(branches)
+	w.Header().Set("X-Cache", string(cacheStatus))
+	json.NewEncoder(w).Encode(newListResponse(branches))
+}
+
+// writeListingError writes the error of a repository or branch listing. A used up
+// rate limit tells the client when to retry.
+func writeListingError(w http.ResponseWriter, err error) {
+	var rateErr *git.RateLimitError
+	if errors.As(err, &rateErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(time.Until(rateErr.Reset))))
+		WriteError(w, domain.NewGitRateLimitedError("The git provider's rate limit is exceeded, please retry later"))
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// repositoryTreeMaxEntries is the most entries of a tree GetRepositoryTree lists, across
+// all pages. Larger trees are truncated, browse them a directory at a time instead.
+const repositoryTreeMaxEntries = 20000
+
+// TreeEntryResponse represents an entry of a repository tree in API responses
+type TreeEntryResponse struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"` // blob (file) or tree (directory)
+	Size int64  `json:"size"`
+	SHA  string `json:"sha"`
 }
 
-// GetRepositoryTree gets the directory tree for a repository
+// RepositoryTreeResponse is a page of a repository tree listing. A truncated listing
+// misses entries: the provider cut it short (provider_limit) or it reached the entry
+// cap (max_entries).
+type RepositoryTreeResponse struct {
+	ListResponse[TreeEntryResponse]
+	Truncated       bool   `json:"truncated"`
+	TruncatedReason string `json:"truncated_reason,omitempty"`
+}
+
+// GetRepositoryTree gets the directory tree for a repository.
+// Lists the entries below ?path=, recursively unless ?recursive=false, at most
+// ?max_depth= levels of subdirectories deep. Paginated with ?limit= and ?cursor=; the
+// listing is cached like ListBranches so later pages don't call the provider again.
 func (h *GitHandler) GetRepositoryTree(w http.ResponseWriter, r *http.Request) {
 	orgID := auth.GetOrgID(r.Context())
 	if orgID == "" {
@@ -541,7 +827,33 @@ func (h *GitHandler) GetRepositoryTree(w http.ResponseWriter, r *http.Request) {
 	owner := chi.URLParam(r, "owner")
 	repo := chi.URLParam(r, "repo")
 	branch := r.URL.Query().Get("branch")
-	path := r.URL.Query().Get("path")
+	opts := git.TreeOptions{
+		Path:       strings.Trim(r.URL.Query().Get("path"), "/"),
+		Recursive:  true,
+		MaxEntries: repositoryTreeMaxEntries,
+	}
+	if recursive := r.URL.Query().Get("recursive"); recursive != "" {
+		value, err := strconv.ParseBool(recursive)
+		if err != nil {
+			http.Error(w, "Invalid recursive. Must be true or false", http.StatusBadRequest)
+			return
+		}
+		opts.Recursive = value
+	}
+	if maxDepth := r.URL.Query().Get("max_depth"); maxDepth != "" {
+		value, err := strconv.Atoi(maxDepth)
+		if err != nil || value < 0 {
+			http.Error(w, "Invalid max_depth. Must be a non-negative number", http.StatusBadRequest)
+			return
+		}
+		opts.MaxDepth = value
+	}
+
+	params, err := ParseListParams(r, ListSpec{DefaultLimit: 1000, MaxLimit: 5000})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Get connection
 	connection, err := h.store.GetGitConnectionByOrgAndProvider(r.Context(), orgID, provider)
@@ -551,30 +863,156 @@ func (h *GitHandler) GetRepositoryTree(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if connection == nil {
-		http.Error(w, "No connection found", http.StatusNotFound)
+		WriteError(w, domain.NewGitNotConnectedError(fmt.Sprintf("No %s connection found. Please connect your %s account first.", provider, provider)))
 		return
 	}
 
-	var tree []*git.TreeEntry
+	var fetch func(context.Context) (*git.Tree, error)
 	switch provider {
 	case "github":
 		client := git.NewGitHubClient(connection.AccessToken)
-		tree, err = client.GetRepositoryTree(r.Context(), owner, repo, branch, path)
+		fetch = func(ctx context.Context) (*git.Tree, error) {
+			return client.GetRepositoryTree(ctx, owner, repo, branch, opts)
+		}
 	case "gitlab":
 		client := git.NewGitLabClient(connection.AccessToken, h.config.GitLabBaseURL)
-		tree, err = client.GetRepositoryTree(r.Context(), owner, repo, branch, path)
+		fetch = func(ctx context.Context) (*git.Tree, error) {
+			return client.GetRepositoryTree(ctx, owner, repo, branch, opts)
+		}
 	default:
 		http.Error(w, "Unsupported provider", http.StatusBadRequest)
 		return
 	}
 
+	refresh := r.URL.Query().Get("refresh") == "true"
+	tree, cacheStatus, err := h.listings.Tree(r.Context(), provider, connection.ID.String(), owner, repo, branch, opts, refresh, fetch)
+	if errors.Is(err, git.ErrNotADirectory) {
+		http.Error(w, "Directory not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
+		writeListingError(w, err)
+		return
+	}
+
+	entries := make([]TreeEntryResponse, len(tree.Entries))
+	for i, entry := range tree.Entries {
+		entries[i] = TreeEntryResponse{
+			Name: entry.Path[strings.LastIndex(entry.Path, "/")+1:],
+			Path: entry.Path,
+			Type: entry.Type,
+			Size: entry.Size,
+			SHA:  entry.SHA,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Cache", string(cacheStatus))
+	json.NewEncoder(w).Encode(RepositoryTreeResponse{
+		ListResponse:    pageOf(entries, params.Offset, params.Limit),
+		Truncated:       tree.Truncated,
+		TruncatedReason: tree.TruncatedReason,
+	})
+}
+
+// FileContentResponse represents a repository file in API responses
+type FileContentResponse struct {
+	Path    string `json:"path"`
+	Ref     string `json:"ref,omitempty"`
+	Size    int64  `json:"size"`
+	SHA     string `json:"sha"`
+	Content string `json:"content"`
+}
+
+// GetFileContent gets the content of a text file in a repository (e.g. Dockerfile, package.json)
+func (h *GitHandler) GetFileContent(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	provider := r.URL.Query().Get("provider")
+	if provider == "" {
+		provider = "github"
+	}
+
+	owner := chi.URLParam(r, "owner")
+	repo := chi.URLParam(r, "repo")
+	branch := r.URL.Query().Get("branch")
+	path := strings.Trim(r.URL.Query().Get("path"), "/")
+	if path == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+	if !isRepoFilePath(path) {
+		http.Error(w, "Invalid file path", http.StatusBadRequest)
+		return
+	}
+
+	// Get connection
+	connection, err := h.store.GetGitConnectionByOrgAndProvider(r.Context(), orgID, provider)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if connection == nil {
+		WriteError(w, domain.NewGitNotConnectedError(fmt.Sprintf("No %s connection found. Please connect your %s account first.", provider, provider)))
+		return
+	}
+
+	var file *git.FileContent
+	switch provider {
+	case "github":
+		client := git.NewGitHubClient(connection.AccessToken)
+		file, err = client.GetFileContent(r.Context(), owner, repo, branch, path)
+	case "gitlab":
+		client := git.NewGitLabClient(connection.AccessToken, h.config.GitLabBaseURL)
+		file, err = client.GetFileContent(r.Context(), owner, repo, branch, path)
+	default:
+		http.Error(w, "Unsupported provider", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case errors.Is(err, git.ErrNotAFile):
+		http.Error(w, "File not found", http.StatusNotFound)
+		return
+	case errors.Is(err, git.ErrFileTooLarge):
+		http.Error(w, fmt.Sprintf("File exceeds the %d byte limit", git.MaxFileContentSize), http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, git.ErrBinaryFile):
+		http.Error(w, "Binary files are not supported", http.StatusUnprocessableEntity)
+		return
+	case err != nil:
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tree)
+	json.NewEncoder(w).Encode(FileContentResponse{
+		Path:    file.Path,
+		Ref:     file.Ref,
+		Size:    file.Size,
+		SHA:     file.SHA,
+		Content: file.Content,
+	})
+}
+
+// isRepoFilePath reports whether path is a plain path inside the repository. The
+// providers put it in their API URLs, where "." or ".." segments and escapes could
+// make the request read another endpoint than the file.
+func isRepoFilePath(path string) bool {
+	if strings.ContainsAny(path, "?#%\\") {
+		return false
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
 }
 
 // ===== GitHub App Handlers =====
@@ -664,7 +1102,7 @@ func (h *GitHandler) ListGitHubAppInstallations(w http.ResponseWriter, r *http.R
 		})
 	}
 
-	WriteJSON(w, http.StatusOK, response)
+	WriteJSON(w, http.StatusOK, newListResponse(response))
 }
 
 // ListGitHubAppInstallationRepos lists repositories for a specific installation
@@ -728,6 +1166,6 @@ func (h *GitHandler) ListGitHubAppInstallationRepos(w http.ResponseWriter, r *ht
 		})
 	}
 
-	WriteJSON(w, http.StatusOK, response)
+	WriteJSON(w, http.StatusOK, newListResponse(response))
 }
 
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/git"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestGitHandler_ReconnectKeepsGitSources(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	handler := NewGitHandler(dbStore, &config.Config{
+		GitHubClientID:     "client-id",
+		GitHubClientSecret: "client-secret",
+	})
+
+	orgID := "test-org-git-001"
+	ctx := testutil.MockAuthContext(context.Background(), "test-user-123", orgID)
+	project := &store.Project{
+		Name:              "Test Project",
+		Slug:              "test-project",
+		CasdoorOrgID:      orgID,
+		OpenStackTenantID: "test-tenant-123",
+	}
+	if err := dbStore.CreateProject(ctx, project); err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+	service := &store.Service{
+		ProjectID:    project.ID,
+		Name:         "Test Service",
+		Type:         "app",
+		Status:       "active",
+		InstanceSize: "medium",
+		Port:         8080,
+	}
+	if err := dbStore.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+	gitConn := &store.GitConnection{
+		CasdoorOrgID: orgID,
+		Provider:     "github",
+		AccessToken:  "revoked-token",
+	}
+	if err := dbStore.CreateGitConnection(ctx, gitConn); err != nil {
+		t.Fatalf("Failed to create test git connection: %v", err)
+	}
+	gitSource := &store.GitSource{
+		ServiceID:       service.ID,
+		GitConnectionID: gitConn.ID,
+		Provider:        "github",
+		RepoOwner:       "test-owner",
+		RepoName:        "test-repo",
+		Branch:          "main",
+	}
+	if err := dbStore.CreateGitSource(ctx, gitSource); err != nil {
+		t.Fatalf("Failed to create test git source: %v", err)
+	}
+
+	// The reconnect URL is only handed out for the org's own connections
+	req, _ := testutil.MockRequestWithURLParamAndAuth(t, "GET", "/v1/click-deploy/git/connections/"+gitConn.ID.String()+"/reconnect-url",
+		map[string]string{"id": gitConn.ID.String()}, nil, "test-user-123", "other-org")
+	w := testutil.MockResponseRecorder()
+	handler.GetReconnectURL(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for another org, got %d", http.StatusNotFound, w.Code)
+	}
+
+	req, _ = testutil.MockRequestWithURLParamAndAuth(t, "GET", "/v1/click-deploy/git/connections/"+gitConn.ID.String()+"/reconnect-url",
+		map[string]string{"id": gitConn.ID.String()}, nil, "test-user-123", orgID)
+	w = testutil.MockResponseRecorder()
+	handler.GetReconnectURL(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Response: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp["auth_url"] == "" {
+		t.Fatalf("Expected an auth_url, got %s (err: %v)", w.Body.String(), err)
+	}
+
+	// The OAuth callback stores the new token on the same connection
+	reconnected := &store.GitConnection{
+		CasdoorOrgID: orgID,
+		Provider:     "github",
+		AccessToken:  "fresh-token",
+		AccountName:  sql.NullString{String: "test-owner", Valid: true},
+	}
+	if err := handler.saveGitConnection(ctx, reconnected, gitConn.ID.String()); err != nil {
+		t.Fatalf("Failed to save reconnected git connection: %v", err)
+	}
+	if reconnected.ID != gitConn.ID {
+		t.Errorf("Expected the reconnect to reuse connection %s, got %s", gitConn.ID, reconnected.ID)
+	}
+
+	connections, err := dbStore.ListGitConnectionsByOrg(ctx, orgID)
+	if err != nil {
+		t.Fatalf("Failed to list git connections: %v", err)
+	}
+	if len(connections) != 1 || connections[0].AccessToken != "fresh-token" {
+		t.Errorf("Expected one connection with the fresh token, got %+v", connections)
+	}
+
+	source, err := dbStore.GetGitSourceByService(ctx, service.ID)
+	if err != nil || source == nil {
+		t.Fatalf("Expected the service to keep its git source, got %v (err: %v)", source, err)
+	}
+	if source.GitConnectionID != gitConn.ID {
+		t.Errorf("Expected git source to still use connection %s, got %s", gitConn.ID, source.GitConnectionID)
+	}
+}
+
+func TestGitHandler_GetFileContent(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	// A GitLab API serving a Dockerfile and a file over the size limit
+	var requested []string
+	gitlab := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, filePath, _ := strings.Cut(r.URL.EscapedPath(), "/repository/files/")
+		filePath, _ = url.PathUnescape(filePath)
+		requested = append(requested, filePath)
+
+		content := "FROM alpine\n"
+		size := len(content)
+		switch filePath {
+		case "Dockerfile":
+		case "large.txt":
+			size = git.MaxFileContentSize + 1
+		default:
+			http.Error(w, `{"message":"404 File Not Found"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"file_path": filePath,
+			"size":      size,
+			"encoding":  "base64",
+			"content":   base64.StdEncoding.EncodeToString([]byte(content)),
+			"blob_id":   "abc123",
+		})
+	}))
+	defer gitlab.Close()
+
+	dbStore := &store.DB{DB: db}
+	handler := NewGitHandler(dbStore, &config.Config{GitLabBaseURL: gitlab.URL})
+
+	orgID := "test-org-git-002"
+	ctx := testutil.MockAuthContext(context.Background(), "test-user-123", orgID)
+	gitConn := &store.GitConnection{
+		CasdoorOrgID: orgID,
+		Provider:     "gitlab",
+		AccessToken:  "test-token",
+	}
+	if err := dbStore.CreateGitConnection(ctx, gitConn); err != nil {
+		t.Fatalf("Failed to create test git connection: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		path            string
+		expectedStatus  int
+		expectedRequest bool // Whether the file is requested from GitLab
+	}{
+		{name: "text file", path: "Dockerfile", expectedStatus: http.StatusOK, expectedRequest: true},
+		{name: "file over the size limit", path: "large.txt", expectedStatus: http.StatusUnprocessableEntity, expectedRequest: true},
+		{name: "missing file", path: "missing.txt", expectedStatus: http.StatusNotFound, expectedRequest: true},
+		{name: "missing path", path: "", expectedStatus: http.StatusBadRequest},
+		{name: "parent directory", path: "../../users", expectedStatus: http.StatusBadRequest},
+		{name: "parent directory inside the path", path: "app/../../../users", expectedStatus: http.StatusBadRequest},
+		{name: "current directory", path: "./Dockerfile", expectedStatus: http.StatusBadRequest},
+		{name: "encoded parent directory", path: "%2e%2e/users", expectedStatus: http.StatusBadRequest},
+		{name: "backslash", path: "..\\users", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested = nil
+			query := url.Values{"provider": {"gitlab"}, "branch": {"main"}, "path": {tt.path}}
+			req, _ := testutil.MockRequestWithURLParamAndAuth(t, "GET", "/v1/click-deploy/git/repos/test-owner/test-repo/file?"+query.Encode(),
+				map[string]string{"owner": "test-owner", "repo": "test-repo"}, nil, "test-user-123", orgID)
+			w := testutil.MockResponseRecorder()
+
+			handler.GetFileContent(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d. Response: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedRequest != (len(requested) > 0) {
+				t.Errorf("Expected the file to be requested from GitLab: %v, got requests %v", tt.expectedRequest, requested)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var file FileContentResponse
+			if err := json.NewDecoder(w.Body).Decode(&file); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if file.Path != tt.path || file.Content != "FROM alpine\n" {
+				t.Errorf("Expected the content of %s, got %+v", tt.path, file)
+			}
+		})
+	}
+}
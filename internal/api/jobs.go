@@ -0,0 +1,362 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// jobsListSpec limits job lists, which hold the newest jobs up to ?limit=
+var jobsListSpec = ListSpec{DefaultLimit: 50, MaxLimit: 100}
+
+// JobHandler handles background job endpoints
+type JobHandler struct {
+	store  *store.DB
+	config *config.Config
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(store *store.DB, cfg *config.Config) *JobHandler {
+	return &JobHandler{
+		store:  store,
+		config: cfg,
+	}
+}
+
+// RegisterJobRoutes registers job routes
+func RegisterJobRoutes(r chi.Router, db *store.DB, cfg *config.Config) {
+	h := NewJobHandler(db, cfg)
+
+	r.Get("/jobs", h.ListJobs)
+	r.Get("/jobs/{id}", h.GetJob)
+	r.Post("/jobs/{id}/cancel", h.CancelJob)
+
+	// Org admins inspect and retry their org's jobs that ran out of attempts
+	r.With(auth.RequireRole("admin")).Get("/jobs/dead-letter", h.ListDeadLetterJobs)
+	r.With(auth.RequireRole("admin")).Post("/jobs/{id}/requeue", h.RequeueJob)
+}
+
+// JobResponse represents a background job
+type JobResponse struct {
+	ID           string     `json:"id"`
+	Type         string     `json:"type"`
+	Status       string     `json:"status"`
+	Attempts     int        `json:"attempts"`
+	MaxAttempts  int        `json:"max_attempts"`
+	Error        string     `json:"error,omitempty"`
+	ResourceType string     `json:"resource_type"`
+	ResourceID   string     `json:"resource_id"`
+	ProjectID    string     `json:"project_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
+
+// GetJob handles GET /jobs/:id
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, project := h.getOwnedJob(w, r)
+	if job == nil {
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, newJobResponse(job, project))
+}
+
+// ListJobs handles GET /jobs
+// Lists the org's most recent jobs, newest first. Filter with ?type= and ?status=,
+// and cap the number of jobs with ?limit= (default 50, at most 100).
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	params, err := ParseListParams(r, jobsListSpec)
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError(err.Error()))
+		return
+	}
+
+	jobs, err := h.store.ListJobs(r.Context(), orgID, r.URL.Query().Get("type"), r.URL.Query().Get("status"), params.Limit)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	response, err := h.jobResponses(r.Context(), orgID, jobs)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, newListResponse(response))
+}
+
+// jobResponses builds the responses of an org's jobs with their projects. Several
+// jobs often work on the same resource, each one is only resolved once.
+func (h *JobHandler) jobResponses(ctx context.Context, orgID string, jobs []*store.Job) ([]JobResponse, error) {
+	projects := make(map[string]*store.Project)
+	response := make([]JobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		_, resourceID := jobResource(job)
+		project, ok := projects[resourceID]
+		if !ok {
+			var err error
+			project, err = h.jobProject(ctx, job)
+			if err != nil {
+				return nil, err
+			}
+			projects[resourceID] = project
+		}
+		if project == nil || !project.BelongsToOrg(orgID) {
+			continue
+		}
+		response = append(response, newJobResponse(job, project))
+	}
+	return response, nil
+}
+
+// CancelJob handles POST /jobs/:id/cancel
+// Only jobs no worker has picked up yet can be cancelled. The queued deployment of
+// a cancelled build or rollback job is cancelled with it.
+func (h *JobHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	job, project := h.getOwnedJob(w, r)
+	if job == nil {
+		return
+	}
+
+	cancelled, err := h.store.CancelJob(r.Context(), job.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if !cancelled {
+		WriteError(w, domain.NewConflictError("Only pending jobs can be cancelled, this job is "+job.Status))
+		return
+	}
+
+	if deploymentID, err := uuid.Parse(payloadString(job.Payload, "deployment_id")); err == nil {
+		deployment, err := h.store.GetDeployment(r.Context(), deploymentID)
+		if err != nil {
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return
+		}
+		if deployment != nil && deployment.Status == "queued" {
+			if err := h.store.UpdateDeploymentStatus(r.Context(), deploymentID, "cancelled"); err != nil {
+				WriteError(w, domain.ErrDatabase.WithError(err))
+				return
+			}
+			h.store.AddDeploymentLog(r.Context(), deploymentID, "deploy", "info", "Deployment cancelled by user", nil)
+		}
+	}
+
+	job, err = h.store.GetJob(r.Context(), job.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, newJobResponse(job, project))
+}
+
+// ListDeadLetterJobs handles GET /jobs/dead-letter (admin only)
+// Lists the org's jobs that failed for good, newest first, with their last error.
+// Filter with ?type= and cap the number of jobs with ?limit= (default 50, at most 100).
+func (h *JobHandler) ListDeadLetterJobs(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	params, err := ParseListParams(r, jobsListSpec)
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError(err.Error()))
+		return
+	}
+
+	jobs, err := h.store.ListDeadLetterJobs(r.Context(), orgID, r.URL.Query().Get("type"), params.Limit)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	response, err := h.jobResponses(r.Context(), orgID, jobs)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, newListResponse(response))
+}
+
+// RequeueJob handles POST /jobs/:id/requeue (admin only)
+// Puts a job of the org that failed for good back in the queue with a fresh set of
+// attempts.
+func (h *JobHandler) RequeueJob(w http.ResponseWriter, r *http.Request) {
+	job, project := h.getOwnedJob(w, r)
+	if job == nil {
+		return
+	}
+
+	requeued, err := h.store.RequeueJob(r.Context(), job.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if !requeued {
+		WriteError(w, domain.NewConflictError("Only failed jobs can be requeued, this job is "+job.Status))
+		return
+	}
+
+	job, err = h.store.GetJob(r.Context(), job.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, newJobResponse(job, project))
+}
+
+// getOwnedJob loads the job from the {id} URL param and the project it works on,
+// and checks the project belongs to the caller's org. It writes the error response
+// and returns nil when it doesn't.
+func (h *JobHandler) getOwnedJob(w http.ResponseWriter, r *http.Request) (*store.Job, *store.Project) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid job ID"))
+		return nil, nil
+	}
+
+	job, err := h.store.GetJob(r.Context(), id)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil, nil
+	}
+	if job == nil {
+		WriteError(w, domain.NewNotFoundError("Job"))
+		return nil, nil
+	}
+
+	project, err := h.jobProject(r.Context(), job)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil, nil
+	}
+	if !checkOrgAccess(w, project, orgID, "Job") {
+		return nil, nil
+	}
+
+	return job, project
+}
+
+// jobProject returns the project of the resource a job works on, nil when the job
+// names no resource or the resource is gone
+func (h *JobHandler) jobProject(ctx context.Context, job *store.Job) (*store.Project, error) {
+	key, value := jobResource(job)
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, nil
+	}
+
+	var projectID uuid.UUID
+	switch key {
+	case "deployment_id":
+		deployment, err := h.store.GetDeployment(ctx, id)
+		if err != nil || deployment == nil {
+			return nil, err
+		}
+		service, err := h.store.GetService(ctx, deployment.ServiceID)
+		if err != nil || service == nil {
+			return nil, err
+		}
+		projectID = service.ProjectID
+	case "service_id":
+		service, err := h.store.GetService(ctx, id)
+		if err != nil || service == nil {
+			return nil, err
+		}
+		projectID = service.ProjectID
+	case "database_id":
+		database, err := h.store.GetDatabase(ctx, id)
+		if err != nil || database == nil {
+			return nil, err
+		}
+		serviceID, err := uuid.Parse(database.ServiceID.String)
+		if err != nil {
+			return nil, nil
+		}
+		service, err := h.store.GetService(ctx, serviceID)
+		if err != nil || service == nil {
+			return nil, err
+		}
+		projectID = service.ProjectID
+	case "volume_id":
+		volume, err := h.store.GetVolume(ctx, id)
+		if err != nil || volume == nil {
+			return nil, err
+		}
+		projectID = volume.ProjectID
+	case "project_id":
+		projectID = id
+	}
+
+	return h.store.GetProject(ctx, projectID)
+}
+
+// jobResource returns the payload key and ID of the resource a job works on
+func jobResource(job *store.Job) (string, string) {
+	for _, key := range store.JobResourceKeys {
+		if value := payloadString(job.Payload, key); value != "" {
+			return key, value
+		}
+	}
+	return "", ""
+}
+
+func payloadString(payload map[string]interface{}, key string) string {
+	value, _ := payload[key].(string)
+	return value
+}
+
+// newJobResponse builds the response of a job of project
+func newJobResponse(job *store.Job, project *store.Project) JobResponse {
+	key, resourceID := jobResource(job)
+	resp := JobResponse{
+		ID:           job.ID.String(),
+		Type:         job.Type,
+		Status:       job.Status,
+		Attempts:     job.Attempts,
+		MaxAttempts:  job.MaxAttempts,
+		Error:        job.Error.String,
+		ResourceType: strings.TrimSuffix(key, "_id"),
+		ResourceID:   resourceID,
+		CreatedAt:    job.CreatedAt,
+	}
+	if project != nil {
+		resp.ProjectID = project.ID.String()
+	}
+	if job.StartedAt.Valid {
+		resp.StartedAt = &job.StartedAt.Time
+	}
+	if job.FinishedAt.Valid {
+		resp.CompletedAt = &job.FinishedAt.Time
+	}
+	return resp
+}
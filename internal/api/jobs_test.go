@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+// createTestJob creates a job working on the resource named by key and id
+func createTestJob(t *testing.T, dbStore *store.DB, status, key, id string) *store.Job {
+	t.Helper()
+	job := &store.Job{Type: "build", Status: status, MaxAttempts: 3, Payload: map[string]interface{}{key: id}}
+	if err := dbStore.CreateJob(context.Background(), job); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	return job
+}
+
+func TestJobHandler_ListJobs(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	handler := NewJobHandler(dbStore, &config.Config{})
+
+	project := testutil.NewProject(t, db)
+	otherProject := testutil.NewProject(t, db, func(p *testutil.Project) { p.OrgID = "other-org" })
+	service := testutil.NewService(t, db, project.ID)
+	otherService := testutil.NewService(t, db, otherProject.ID)
+
+	job := createTestJob(t, dbStore, "pending", "service_id", service.ID.String())
+	// More of the other org's jobs than the page holds
+	for i := 0; i < 3; i++ {
+		createTestJob(t, dbStore, "pending", "service_id", otherService.ID.String())
+	}
+
+	req, _ := testutil.MockRequestWithURLParamAndAuth(t, http.MethodGet, "/jobs?limit=2", nil, nil, "test-user", project.OrgID)
+	w := testutil.MockResponseRecorder()
+	handler.ListJobs(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ListResponse[JobResponse]
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Items) != 1 || resp.Items[0].ID != job.ID.String() {
+		t.Fatalf("Expected only job %s, got %+v", job.ID, resp.Items)
+	}
+	if resp.Items[0].ProjectID != project.ID.String() {
+		t.Errorf("Expected project %s, got %s", project.ID, resp.Items[0].ProjectID)
+	}
+}
+
+func TestJobHandler_ListDeadLetterJobs(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	handler := NewJobHandler(dbStore, &config.Config{})
+
+	project := testutil.NewProject(t, db)
+	otherProject := testutil.NewProject(t, db, func(p *testutil.Project) { p.OrgID = "other-org" })
+
+	job := createTestJob(t, dbStore, "dead_letter", "project_id", project.ID.String())
+	createTestJob(t, dbStore, "dead_letter", "project_id", otherProject.ID.String())
+
+	req, _ := testutil.MockRequestWithURLParamAndAuth(t, http.MethodGet, "/jobs/dead-letter", nil, nil, "test-user", project.OrgID)
+	w := testutil.MockResponseRecorder()
+	handler.ListDeadLetterJobs(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp ListResponse[JobResponse]
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Items) != 1 || resp.Items[0].ID != job.ID.String() {
+		t.Errorf("Expected only job %s, got %+v", job.ID, resp.Items)
+	}
+}
+
+func TestJobHandler_RequeueJob(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	handler := NewJobHandler(dbStore, &config.Config{})
+
+	project := testutil.NewProject(t, db)
+	otherProject := testutil.NewProject(t, db, func(p *testutil.Project) { p.OrgID = "other-org" })
+
+	job := createTestJob(t, dbStore, "dead_letter", "project_id", project.ID.String())
+	otherJob := createTestJob(t, dbStore, "dead_letter", "project_id", otherProject.ID.String())
+
+	tests := []struct {
+		name           string
+		job            *store.Job
+		expectedStatus int
+		expectedJob    string
+	}{
+		{name: "job of another org", job: otherJob, expectedStatus: http.StatusNotFound, expectedJob: "dead_letter"},
+		{name: "job of the org", job: job, expectedStatus: http.StatusOK, expectedJob: "pending"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := testutil.MockRequestWithURLParamAndAuth(t, http.MethodPost, "/jobs/"+tt.job.ID.String()+"/requeue",
+				map[string]string{"id": tt.job.ID.String()}, nil, "test-user", project.OrgID)
+			w := testutil.MockResponseRecorder()
+			handler.RequeueJob(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			stored, err := dbStore.GetJob(context.Background(), tt.job.ID)
+			if err != nil {
+				t.Fatalf("Failed to get job: %v", err)
+			}
+			if stored.Status != tt.expectedJob {
+				t.Errorf("Expected job %s, got %s", tt.expectedJob, stored.Status)
+			}
+		})
+	}
+}
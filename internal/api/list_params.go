@@ -0,0 +1,86 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// ListSpec is what a list endpoint accepts for ?sort=&order=&limit=&offset=
+type ListSpec struct {
+	// Fields ?sort= accepts, the store's sortable columns of the resource. Lists that
+	// can't be sorted leave it empty.
+	SortFields  []string
+	DefaultSort string
+	DefaultDesc bool
+	// Limits of ?limit=: DefaultLimit when absent, capped at MaxLimit
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// ParseListParams parses and validates the paging and sorting of a list request:
+// ?sort= one of the spec's fields, ?order=asc|desc, ?limit= and where the page starts,
+// ?cursor= (the next_cursor of the previous page) or ?offset=
+func ParseListParams(r *http.Request, spec ListSpec) (store.ListParams, error) {
+	query := r.URL.Query()
+	params := store.ListParams{
+		Sort:  spec.DefaultSort,
+		Desc:  spec.DefaultDesc,
+		Limit: spec.DefaultLimit,
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		allowed := false
+		for _, field := range spec.SortFields {
+			if field == sort {
+				allowed = true
+			}
+		}
+		if !allowed {
+			if len(spec.SortFields) == 0 {
+				return params, fmt.Errorf("this list can't be sorted")
+			}
+			return params, fmt.Errorf("invalid sort %q, use one of %s", sort, strings.Join(spec.SortFields, ", "))
+		}
+		params.Sort = sort
+	}
+
+	switch order := strings.ToLower(query.Get("order")); order {
+	case "":
+	case "asc":
+		params.Desc = false
+	case "desc":
+		params.Desc = true
+	default:
+		return params, fmt.Errorf("invalid order %q, use asc or desc", order)
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return params, fmt.Errorf("invalid limit %q, use a positive number", limitStr)
+		}
+		params.Limit = limit
+	}
+	if spec.MaxLimit > 0 && params.Limit > spec.MaxLimit {
+		params.Limit = spec.MaxLimit
+	}
+
+	// The cursor of a page is the offset it starts at
+	offsetStr := query.Get("cursor")
+	if offsetStr == "" {
+		offsetStr = query.Get("offset")
+	}
+	if offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return params, fmt.Errorf("invalid cursor %q", offsetStr)
+		}
+		params.Offset = offset
+	}
+
+	return params, nil
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+func TestParseListParams(t *testing.T) {
+	spec := ListSpec{
+		SortFields:   []string{"created_at", "status"},
+		DefaultSort:  "created_at",
+		DefaultDesc:  true,
+		DefaultLimit: 50,
+		MaxLimit:     100,
+	}
+
+	tests := []struct {
+		query   string
+		want    store.ListParams
+		wantErr bool
+	}{
+		{"", store.ListParams{Sort: "created_at", Desc: true, Limit: 50}, false},
+		{"?sort=status&order=asc&limit=10&offset=20", store.ListParams{Sort: "status", Limit: 10, Offset: 20}, false},
+		{"?cursor=40&offset=20", store.ListParams{Sort: "created_at", Desc: true, Limit: 50, Offset: 40}, false},
+		{"?limit=1000", store.ListParams{Sort: "created_at", Desc: true, Limit: 100}, false},
+		{"?sort=id%3BDROP%20TABLE%20deployments", store.ListParams{}, true},
+		{"?order=sideways", store.ListParams{}, true},
+		{"?limit=0", store.ListParams{}, true},
+		{"?cursor=-5", store.ListParams{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/items"+tt.query, nil)
+			got, err := ParseListParams(r, spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseListParams(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ParseListParams(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"strconv"
+)
+
+// ListResponse is the body of every list endpoint
+type ListResponse[T any] struct {
+	Items []T `json:"items"`
+	// Items matching the request across all pages. Endpoints that only return the
+	// newest items up to ?limit= count the items returned.
+	Total int `json:"total"`
+	// Passed as ?cursor= to get the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// newListResponse wraps a complete list
+func newListResponse[T any](items []T) ListResponse[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return ListResponse[T]{Items: items, Total: len(items)}
+}
+
+// pageOf returns the page of items starting at offset with at most limit items. The
+// next page's cursor is the offset it starts at.
+func pageOf[T any](items []T, offset, limit int) ListResponse[T] {
+	total := len(items)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	page := newListResponse(items[offset:end])
+	page.Total = total
+	if end < total {
+		page.NextCursor = strconv.Itoa(end)
+	}
+	return page
+}
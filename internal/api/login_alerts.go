@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/email"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// loginAlertTimeout bounds sending a login alert email, which happens after the
+// response
+const loginAlertTimeout = 30 * time.Second
+
+// LoginAlertsRequest is the request body for opting in or out of login alerts
+type LoginAlertsRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// LoginAlertsResponse is whether the user gets login alert emails
+type LoginAlertsResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+// GetLoginAlerts handles GET /auth/login-alerts
+func (h *CustomAuthHandler) GetLoginAlerts(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	enabled, err := h.db.GetUserLoginAlerts(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get login alerts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LoginAlertsResponse{Enabled: enabled})
+}
+
+// UpdateLoginAlerts handles PUT /auth/login-alerts
+// Opts the caller in or out of emails about sign-ins from new IPs or devices and
+// lockouts of their account.
+func (h *CustomAuthHandler) UpdateLoginAlerts(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserID(r.Context())
+	if userID == "" {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req LoginAlertsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		http.Error(w, "enabled is required", http.StatusBadRequest)
+		return
+	}
+
+	err := h.db.SetUserLoginAlerts(r.Context(), userID, *req.Enabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update login alerts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LoginAlertsResponse{Enabled: *req.Enabled})
+}
+
+// checkNewSignIn adds the IP and device of a successful login to the user's known
+// ones. A login from a new one is recorded in the audit log and, unless the user
+// opted out, emailed to them.
+func (h *CustomAuthHandler) checkNewSignIn(r *http.Request, user *store.User) {
+	session := sessionInfo(r, h.config)
+	novelty, err := h.db.RecordSignIn(r.Context(), user.ID, session)
+	if err != nil {
+		log.Printf("Failed to record sign-in of user %s: %v", user.ID, err)
+		return
+	}
+	if !novelty.IsNew() {
+		return
+	}
+
+	if orgs, err := h.db.ListUserOrganizations(r.Context(), user.ID); err == nil && len(orgs) > 0 {
+		entry := &store.AuditLogEntry{
+			OrgID:        orgs[0].ID,
+			ActorID:      store.StringToNullString(user.ID),
+			Action:       store.AuditActionNewSignIn,
+			ResourceType: "user",
+			ResourceID:   user.ID,
+			Metadata: map[string]interface{}{
+				"ip_address":     session.IPAddress,
+				"user_agent":     session.UserAgent,
+				"new_ip":         novelty.NewIP,
+				"new_user_agent": novelty.NewUserAgent,
+			},
+		}
+		if err := h.db.CreateAuditLogEntry(r.Context(), entry); err != nil {
+			log.Printf("Failed to write audit log entry %s for user %s: %v", entry.Action, user.ID, err)
+		}
+	}
+
+	signIn := email.SignInDetails{IPAddress: session.IPAddress, UserAgent: session.UserAgent, Time: time.Now()}
+	h.sendLoginAlert(user, "new sign-in", func(m *email.MailtrapClient) error {
+		return m.SendNewSignInEmail(user.Email, signIn)
+	})
+}
+
+// notifyAccountLocked emails a user whose account was locked out, unless they opted
+// out
+func (h *CustomAuthHandler) notifyAccountLocked(user *store.User, until time.Time, ip string) {
+	h.sendLoginAlert(user, "account locked", func(m *email.MailtrapClient) error {
+		return m.SendAccountLockedEmail(user.Email, until, ip)
+	})
+}
+
+// sendLoginAlert sends a login alert email in the background if email is
+// configured and the user hasn't opted out
+func (h *CustomAuthHandler) sendLoginAlert(user *store.User, kind string, send func(*email.MailtrapClient) error) {
+	if h.mailer == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), loginAlertTimeout)
+		defer cancel()
+
+		enabled, err := h.db.GetUserLoginAlerts(ctx, user.ID)
+		if err != nil {
+			log.Printf("Failed to get login alerts of user %s: %v", user.ID, err)
+			return
+		}
+		if !enabled {
+			return
+		}
+		if err := send(h.mailer); err != nil {
+			log.Printf("Failed to send %s alert to user %s: %v", kind, user.ID, err)
+		}
+	}()
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// loginThrottle is the policy failed logins are throttled by, or nil when throttling
+// is disabled
+func (h *CustomAuthHandler) loginThrottle() *store.LoginThrottle {
+	if h.config == nil || h.config.LoginMaxFailures <= 0 {
+		return nil
+	}
+	return &store.LoginThrottle{
+		MaxFailures: h.config.LoginMaxFailures,
+		Window:      h.config.LoginFailureWindow,
+		BaseLockout: h.config.LoginLockoutBase,
+		MaxLockout:  h.config.LoginLockoutMax,
+	}
+}
+
+// loginKeys are the login attempts keys a login is throttled by: its email and the
+// client's IP
+func (h *CustomAuthHandler) loginKeys(r *http.Request, email string) (emailKey, ipKey string) {
+	return store.LoginEmailKey(strings.ToLower(strings.TrimSpace(email))), store.LoginIPKey(clientIP(r, h.config))
+}
+
+// checkLoginLocked writes a 429 and returns true when the email or the client's IP
+// is locked out. Throttling fails open: a lookup error lets the login through.
+func (h *CustomAuthHandler) checkLoginLocked(w http.ResponseWriter, r *http.Request, email string) bool {
+	if h.loginThrottle() == nil {
+		return false
+	}
+
+	emailKey, ipKey := h.loginKeys(r, email)
+	until, err := h.db.LoginLockedUntil(r.Context(), emailKey, ipKey)
+	if err != nil {
+		log.Printf("Failed to check login lockout: %v", err)
+		return false
+	}
+	if until.IsZero() {
+		return false
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(time.Until(until))))
+	http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+	return true
+}
+
+// recordLoginFailure counts a failed login against the email and the client's IP,
+// recording any lockout it causes in the audit log of the user's organization
+func (h *CustomAuthHandler) recordLoginFailure(r *http.Request, email string, user *store.User) {
+	policy := h.loginThrottle()
+	if policy == nil {
+		return
+	}
+
+	emailKey, ipKey := h.loginKeys(r, email)
+	ip := clientIP(r, h.config)
+	for _, key := range []string{emailKey, ipKey} {
+		until, err := h.db.RecordLoginFailure(r.Context(), key, *policy)
+		if err != nil {
+			log.Printf("Failed to record failed login for %s: %v", key, err)
+			continue
+		}
+		if until.IsZero() {
+			continue
+		}
+		h.auditLoginLockout(r.Context(), key, until, user, ip)
+		if key == emailKey && user != nil {
+			h.notifyAccountLocked(user, until, ip)
+		}
+	}
+}
+
+// resetLoginFailures clears the failed logins of an email after a successful login.
+// The client IP's are kept, one good password mustn't unlock guessing at others.
+func (h *CustomAuthHandler) resetLoginFailures(r *http.Request, email string) {
+	if h.loginThrottle() == nil {
+		return
+	}
+
+	emailKey, _ := h.loginKeys(r, email)
+	if err := h.db.ResetLoginAttempts(r.Context(), emailKey); err != nil {
+		log.Printf("Failed to reset failed logins for %s: %v", emailKey, err)
+	}
+}
+
+// auditLoginLockout records a lockout in the audit log. The audit log is scoped to
+// organizations, so lockouts for emails of no user are only logged.
+func (h *CustomAuthHandler) auditLoginLockout(ctx context.Context, key string, until time.Time, user *store.User, ip string) {
+	log.Printf("Login locked out for %s until %s", key, until.UTC().Format(time.RFC3339))
+	if user == nil {
+		return
+	}
+
+	orgs, err := h.db.ListUserOrganizations(ctx, user.ID)
+	if err != nil || len(orgs) == 0 {
+		return
+	}
+
+	entry := &store.AuditLogEntry{
+		OrgID:        orgs[0].ID,
+		Action:       store.AuditActionLoginLockout,
+		ResourceType: "user",
+		ResourceID:   user.ID,
+		Metadata: map[string]interface{}{
+			"key":          key,
+			"ip_address":   ip,
+			"locked_until": until.UTC().Format(time.RFC3339),
+		},
+	}
+	if err := h.db.CreateAuditLogEntry(ctx, entry); err != nil {
+		log.Printf("Failed to write audit log entry %s for user %s: %v", entry.Action, user.ID, err)
+	}
+}
@@ -2,39 +2,52 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 
+	"github.com/intelifox/click-deploy/internal/auth"
 	"github.com/intelifox/click-deploy/internal/config"
 	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/metrics"
 	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/worker"
 )
 
-// MetricsHandler handles metrics endpoints
+// MetricsHandler handles metrics endpoints. Metrics are read from the cluster
+// of the project's region.
 type MetricsHandler struct {
-	store         *store.DB
-	config        *config.Config
-	metricsClient *k8s.MetricsClient
+	store      *store.DB
+	config     *config.Config
+	k8sClients *k8s.ClientRegistry
+	prometheus *metrics.PrometheusClient
 }
 
 // NewMetricsHandler creates a new metrics handler
-func NewMetricsHandler(store *store.DB, cfg *config.Config, metricsClient *k8s.MetricsClient) *MetricsHandler {
+func NewMetricsHandler(store *store.DB, cfg *config.Config, k8sClients *k8s.ClientRegistry) *MetricsHandler {
 	return &MetricsHandler{
-		store:         store,
-		config:        cfg,
-		metricsClient: metricsClient,
+		store:      store,
+		config:     cfg,
+		k8sClients: k8sClients,
+		prometheus: metrics.NewPrometheusClient(cfg.PrometheusURL),
 	}
 }
 
+// metricsClient returns the metrics client of a region's cluster, nil when there is none
+func (h *MetricsHandler) metricsClient(region string) *k8s.MetricsClient {
+	client, err := h.k8sClients.MetricsClient(region)
+	if err != nil {
+		return nil
+	}
+	return client
+}
+
 // GetServiceMetrics returns live metrics for a service
 func (h *MetricsHandler) GetServiceMetrics(w http.ResponseWriter, r *http.Request) {
-	if h.metricsClient == nil {
-		// Return mock metrics if not using k8s
-		h.returnMockMetrics(w)
-		return
-	}
+	orgID := auth.GetOrgID(r.Context())
 
 	serviceIDStr := chi.URLParam(r, "id")
 	serviceID, err := uuid.Parse(serviceIDStr)
@@ -50,7 +63,23 @@ func (h *MetricsHandler) GetServiceMetrics(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	metrics, err := h.metricsClient.GetServiceMetrics(
+	project, err := h.store.GetProject(r.Context(), service.ProjectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !checkOrgAccess(w, project, orgID, "Service") {
+		return
+	}
+
+	metricsClient := h.metricsClient(worker.ProjectRegion(project))
+	if metricsClient == nil {
+		// Return mock metrics if not using k8s
+		h.returnMockMetrics(w)
+		return
+	}
+
+	metrics, err := metricsClient.GetServiceMetrics(
 		r.Context(),
 		service.ProjectID.String(),
 		serviceID.String(),
@@ -66,20 +95,110 @@ func (h *MetricsHandler) GetServiceMetrics(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	WriteJSONWithETag(w, r, map[string]interface{}{
 		"available": true,
 		"metrics":   metrics,
 	})
 }
 
-// GetProjectMetrics returns metrics for all services in a project
-func (h *MetricsHandler) GetProjectMetrics(w http.ResponseWriter, r *http.Request) {
-	if h.metricsClient == nil {
+// GetDatabaseMetrics returns live metrics for a database
+func (h *MetricsHandler) GetDatabaseMetrics(w http.ResponseWriter, r *http.Request) {
+	database, project, ok := getOwnedDatabase(w, r, h.store)
+	if !ok {
+		return
+	}
+
+	metricsClient := h.metricsClient(worker.ProjectRegion(project))
+	if metricsClient == nil {
+		// Return mock metrics if not using k8s
 		h.returnMockMetrics(w)
 		return
 	}
 
+	databaseMetrics, err := metricsClient.GetDatabaseMetrics(r.Context(), project.ID.String(), database.ID.String(), database.Engine)
+	if err != nil {
+		// If metrics server is not ready, return empty data
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"available": false,
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	WriteJSONWithETag(w, r, map[string]interface{}{
+		"available": true,
+		"metrics":   databaseMetrics,
+	})
+}
+
+// VolumeMetrics is the disk usage of a volume
+type VolumeMetrics struct {
+	VolumeID   string   `json:"volume_id"`
+	CapacityMB int      `json:"capacity_mb"`
+	UsedMB     *float64 `json:"used_mb,omitempty"`
+}
+
+// GetVolumeMetrics returns the disk usage of a volume, read from the kubelet's volume
+// stats in Prometheus. Without them only the volume's capacity is known.
+func (h *MetricsHandler) GetVolumeMetrics(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+
+	volumeID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid volume ID", http.StatusBadRequest)
+		return
+	}
+
+	volume, err := h.store.GetVolume(r.Context(), volumeID)
+	if err != nil || volume == nil {
+		http.Error(w, "Volume not found", http.StatusNotFound)
+		return
+	}
+
+	project, err := h.store.GetProject(r.Context(), volume.ProjectID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !checkOrgAccess(w, project, orgID, "Volume") {
+		return
+	}
+
+	volumeMetrics := VolumeMetrics{VolumeID: volume.ID.String(), CapacityMB: volume.SizeMB}
+	client := projectK8sClient(h.k8sClients, project)
+	if client == nil || h.config.PrometheusURL == "" {
+		WriteJSONWithETag(w, r, map[string]interface{}{
+			"available": false,
+			"metrics":   volumeMetrics,
+		})
+		return
+	}
+
+	query := metrics.ScopeToOrg(fmt.Sprintf(`max by (namespace) (kubelet_volume_stats_used_bytes{namespace="%s",persistentvolumeclaim="%s"})`,
+		client.ProjectNamespace(project.ID.String()), client.PVCName(volume.ID.String())), orgID)
+	samples, err := h.prometheus.QueryOrg(r.Context(), orgID, query, time.Now())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"available": false,
+			"error":     err.Error(),
+		})
+		return
+	}
+	if len(samples) > 0 {
+		usedMB := samples[0].Value / (1024 * 1024)
+		volumeMetrics.UsedMB = &usedMB
+	}
+
+	WriteJSONWithETag(w, r, map[string]interface{}{
+		"available": volumeMetrics.UsedMB != nil,
+		"metrics":   volumeMetrics,
+	})
+}
+
+// GetProjectMetrics returns metrics for all services in a project
+func (h *MetricsHandler) GetProjectMetrics(w http.ResponseWriter, r *http.Request) {
 	projectIDStr := chi.URLParam(r, "id")
 	projectID, err := uuid.Parse(projectIDStr)
 	if err != nil {
@@ -87,7 +206,19 @@ func (h *MetricsHandler) GetProjectMetrics(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	metrics, err := h.metricsClient.GetNamespaceMetrics(r.Context(), projectID.String())
+	project, err := h.store.GetProject(r.Context(), projectID)
+	if err != nil || project == nil {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
+	metricsClient := h.metricsClient(worker.ProjectRegion(project))
+	if metricsClient == nil {
+		h.returnMockMetrics(w)
+		return
+	}
+
+	metrics, err := metricsClient.GetNamespaceMetrics(r.Context(), projectID.String())
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -97,21 +228,28 @@ func (h *MetricsHandler) GetProjectMetrics(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	WriteJSONWithETag(w, r, map[string]interface{}{
 		"available": true,
 		"pods":      metrics,
 	})
 }
 
 // GetClusterMetrics returns cluster-wide metrics (admin only)
+// Pass ?region= for another region's cluster than the default one.
 func (h *MetricsHandler) GetClusterMetrics(w http.ResponseWriter, r *http.Request) {
-	if h.metricsClient == nil {
+	region := r.URL.Query().Get("region")
+	if region != "" && !h.k8sClients.HasRegion(region) {
+		http.Error(w, "Region not configured", http.StatusBadRequest)
+		return
+	}
+
+	metricsClient := h.metricsClient(region)
+	if metricsClient == nil {
 		h.returnMockMetrics(w)
 		return
 	}
 
-	nodes, err := h.metricsClient.GetNodeMetrics(r.Context())
+	nodes, err := metricsClient.GetNodeMetrics(r.Context())
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -121,8 +259,7 @@ func (h *MetricsHandler) GetClusterMetrics(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	WriteJSONWithETag(w, r, map[string]interface{}{
 		"available": true,
 		"nodes":     nodes,
 	})
@@ -130,13 +267,14 @@ func (h *MetricsHandler) GetClusterMetrics(w http.ResponseWriter, r *http.Reques
 
 // CheckMetricsAvailability checks if metrics server is available
 func (h *MetricsHandler) CheckMetricsAvailability(w http.ResponseWriter, r *http.Request) {
-	if h.metricsClient == nil {
+	metricsClient := h.metricsClient(r.URL.Query().Get("region"))
+	if metricsClient == nil {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]bool{"available": false})
 		return
 	}
 
-	available := h.metricsClient.IsMetricsServerAvailable(r.Context())
+	available := metricsClient.IsMetricsServerAvailable(r.Context())
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]bool{"available": available})
 }
@@ -168,10 +306,13 @@ func (h *MetricsHandler) returnMockMetrics(w http.ResponseWriter) {
 }
 
 // RegisterMetricsRoutes registers metrics routes
-func RegisterMetricsRoutes(r chi.Router, db *store.DB, cfg *config.Config, metricsClient *k8s.MetricsClient) {
-	handler := NewMetricsHandler(db, cfg, metricsClient)
+func RegisterMetricsRoutes(r chi.Router, db *store.DB, cfg *config.Config, k8sClients *k8s.ClientRegistry) {
+	handler := NewMetricsHandler(db, cfg, k8sClients)
 
 	r.Get("/services/{id}/metrics", handler.GetServiceMetrics)
+	r.Get("/services/{id}/recommendations", handler.GetServiceRecommendations)
+	r.Get("/databases/{id}/metrics", handler.GetDatabaseMetrics)
+	r.Get("/volumes/{id}/metrics", handler.GetVolumeMetrics)
 	r.Get("/projects/{id}/metrics", handler.GetProjectMetrics)
 	r.Get("/cluster/metrics", handler.GetClusterMetrics)
 	r.Get("/metrics/available", handler.CheckMetricsAvailability)
@@ -18,15 +18,7 @@ func TestMetricsHandler_GetServiceMetrics(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler, err := NewMetricsHandler(dbStore, &config.Config{})
-	if err != nil {
-		// Prometheus may not be configured in tests, create handler with nil client
-		handler = &MetricsHandler{
-			store:  dbStore,
-			config: &config.Config{},
-			client: nil,
-		}
-	}
+	handler := NewMetricsHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-metrics-001"
@@ -104,15 +96,7 @@ func TestMetricsHandler_GetDatabaseMetrics(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler, err := NewMetricsHandler(dbStore, &config.Config{})
-	if err != nil {
-		// Prometheus may not be configured in tests, create handler with nil client
-		handler = &MetricsHandler{
-			store:  dbStore,
-			config: &config.Config{},
-			client: nil,
-		}
-	}
+	handler := NewMetricsHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-metrics-002"
@@ -172,6 +156,12 @@ func TestMetricsHandler_GetDatabaseMetrics(t *testing.T) {
 			orgID:          orgID,
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			name:           "database from different org",
+			databaseID:     database.ID.String(),
+			orgID:          "different-org",
+			expectedStatus: http.StatusNotFound,
+		},
 	}
 
 	for _, tt := range tests {
@@ -197,15 +187,7 @@ func TestMetricsHandler_GetVolumeMetrics(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler, err := NewMetricsHandler(dbStore, &config.Config{})
-	if err != nil {
-		// Prometheus may not be configured in tests, create handler with nil client
-		handler = &MetricsHandler{
-			store:  dbStore,
-			config: &config.Config{},
-			client: nil,
-		}
-	}
+	handler := NewMetricsHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-metrics-003"
@@ -251,6 +233,12 @@ func TestMetricsHandler_GetVolumeMetrics(t *testing.T) {
 			orgID:          orgID,
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			name:           "volume from different org",
+			volumeID:       volume.ID.String(),
+			orgID:          "different-org",
+			expectedStatus: http.StatusNotFound,
+		},
 	}
 
 	for _, tt := range tests {
@@ -269,4 +257,3 @@ func TestMetricsHandler_GetVolumeMetrics(t *testing.T) {
 		})
 	}
 }
-
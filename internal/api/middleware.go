@@ -3,18 +3,24 @@ package api
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/infra"
+	"github.com/intelifox/click-deploy/internal/secrets"
 )
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
-	Error   domain.ErrorCode `json:"error"`
-	Message string            `json:"message"`
-	Details string            `json:"details,omitempty"`
+	Error   domain.ErrorCode    `json:"error"`
+	Message string              `json:"message"`
+	Details string              `json:"details,omitempty"`
+	Fields  []domain.FieldError `json:"fields,omitempty"` // One entry per invalid field of a validation error
 }
 
 // ErrorHandler is a middleware that handles errors consistently
@@ -26,6 +32,20 @@ func ErrorHandler(next http.Handler) http.Handler {
 
 // WriteError writes an error response
 func WriteError(w http.ResponseWriter, err error) {
+	// Infra outages are transient: tell clients when to retry
+	if retryAfter, ok := infra.RetryAfter(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+		writeAppError(w, domain.NewServiceUnavailableError("Infrastructure service is temporarily unavailable, please retry later"))
+		return
+	}
+
+	// Body read past the MaxBodySizeMiddleware limit
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeAppError(w, payloadTooLargeError(maxBytesErr.Limit))
+		return
+	}
+
 	// Check if it's an AppError
 	if appErr, ok := domain.IsAppError(err); ok {
 		writeAppError(w, appErr)
@@ -45,23 +65,34 @@ func WriteError(w http.ResponseWriter, err error) {
 	}
 
 	// Default to internal server error
-	log.Printf("Unhandled error: %v", err)
+	log.Printf("Unhandled error: %s", secrets.RedactConnectionURL(err.Error()))
 	writeAppError(w, domain.ErrInternal.WithError(err))
 }
 
+// retryAfterSeconds rounds a wait up to whole seconds for the Retry-After header (at least 1)
+func retryAfterSeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
 // writeAppError writes an AppError as JSON response
 func writeAppError(w http.ResponseWriter, err *domain.AppError) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(err.StatusCode)
 
+	// Details often quote an underlying error, which may hold a connection URL
 	response := ErrorResponse{
 		Error:   err.Code,
-		Message: err.Message,
+		Message: secrets.RedactConnectionURL(err.Message),
 	}
 
 	if err.Details != "" {
-		response.Details = err.Details
+		response.Details = secrets.RedactConnectionURL(err.Details)
 	}
+	response.Fields = err.Fields
 
 	json.NewEncoder(w).Encode(response)
 }
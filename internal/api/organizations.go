@@ -0,0 +1,356 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// orgInvitationExpiry is how long an invitation can be accepted
+const orgInvitationExpiry = 7 * 24 * time.Hour
+
+// OrgHandler manages the members of organizations
+type OrgHandler struct {
+	store  *store.DB
+	config *config.Config
+}
+
+// NewOrgHandler creates a new organization handler
+func NewOrgHandler(store *store.DB, cfg *config.Config) *OrgHandler {
+	return &OrgHandler{
+		store:  store,
+		config: cfg,
+	}
+}
+
+// RegisterOrgRoutes registers organization membership routes
+func RegisterOrgRoutes(r chi.Router, db *store.DB, cfg *config.Config) {
+	h := NewOrgHandler(db, cfg)
+
+	r.Post("/orgs/invitations/accept", h.AcceptInvitation)
+	r.Post("/orgs/{id}/invitations", h.CreateInvitation)
+	r.Get("/orgs/{id}/members", h.ListMembers)
+	r.Patch("/orgs/{id}/members/{userId}", h.UpdateMemberRole)
+	r.Delete("/orgs/{id}/members/{userId}", h.RemoveMember)
+}
+
+// CreateInvitationRequest represents the request body for inviting someone to an organization
+type CreateInvitationRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"` // Defaults to member
+}
+
+// InvitationResponse is returned once, when an invitation is created. The token
+// is what the invitee accepts; it can't be retrieved later.
+type InvitationResponse struct {
+	ID        string `json:"id"`
+	OrgID     string `json:"org_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// AcceptInvitationRequest represents the request body for accepting an invitation
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// UpdateMemberRoleRequest represents the request body for changing a member's role
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// validOrgRoles are the roles a member can have
+var validOrgRoles = map[string]bool{
+	store.OrgRoleOwner:  true,
+	store.OrgRoleAdmin:  true,
+	store.OrgRoleMember: true,
+}
+
+// CreateInvitation handles POST /orgs/:id/invitations
+// Owners and admins can invite; only owners can invite other owners.
+func (h *OrgHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "id")
+	callerRole, ok := h.requireOrgManager(w, r, orgID)
+	if !ok {
+		return
+	}
+
+	var req CreateInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body: "+err.Error()))
+		return
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Role == "" {
+		req.Role = store.OrgRoleMember
+	}
+
+	validationErrs := &ValidationErrors{}
+	if !strings.Contains(req.Email, "@") {
+		validationErrs.Add("email", "must be a valid email address")
+	}
+	if !validOrgRoles[req.Role] {
+		validationErrs.Add("role", "must be one of owner, admin, member")
+	}
+	if validationErrs.HasErrors() {
+		WriteError(w, validationErrs.ToAppError())
+		return
+	}
+	if req.Role == store.OrgRoleOwner && callerRole != store.OrgRoleOwner {
+		WriteError(w, domain.NewForbiddenError("Only owners can invite owners"))
+		return
+	}
+
+	inv, token, err := h.store.CreateOrgInvitation(r.Context(), orgID, req.Email, req.Role, auth.GetUserID(r.Context()), orgInvitationExpiry)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteCreated(w, InvitationResponse{
+		ID:        inv.ID,
+		OrgID:     inv.OrgID,
+		Email:     inv.Email,
+		Role:      inv.Role,
+		Token:     token,
+		ExpiresAt: inv.ExpiresAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// AcceptInvitation handles POST /orgs/invitations/accept
+// Adds the caller to the invitation's organization. The invitation must be
+// addressed to the caller's email.
+func (h *OrgHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserID(r.Context())
+	if userID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("User ID not found in token"))
+		return
+	}
+
+	var req AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body: "+err.Error()))
+		return
+	}
+	if req.Token == "" {
+		WriteError(w, domain.NewInvalidInputError("token is required"))
+		return
+	}
+
+	inv, err := h.store.GetOrgInvitationByToken(r.Context(), req.Token)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if inv == nil {
+		WriteError(w, domain.NewNotFoundError("Invitation"))
+		return
+	}
+	if inv.AcceptedAt.Valid {
+		WriteError(w, domain.NewConflictError("Invitation was already accepted"))
+		return
+	}
+	if inv.Expired() {
+		WriteError(w, domain.NewInvalidInputError("Invitation has expired"))
+		return
+	}
+
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if !strings.EqualFold(user.Email, inv.Email) {
+		// Don't reveal which email the invitation is for
+		WriteError(w, domain.NewNotFoundError("Invitation"))
+		return
+	}
+
+	member, err := h.store.AcceptOrgInvitation(r.Context(), inv, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrInvitationUsed) {
+			WriteError(w, domain.NewConflictError("Invitation was already accepted"))
+			return
+		}
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, member)
+}
+
+// ListMembers handles GET /orgs/:id/members
+// Any member can see the other members.
+func (h *OrgHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "id")
+	role, err := orgRole(r.Context(), h.store, orgID, auth.GetUserID(r.Context()))
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if role == "" {
+		WriteError(w, domain.NewNotFoundError("Organization"))
+		return
+	}
+
+	members, err := h.store.ListOrgMembers(r.Context(), orgID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if members == nil {
+		members = []*store.OrgMemberWithUser{}
+	}
+
+	WriteJSON(w, http.StatusOK, newListResponse(members))
+}
+
+// UpdateMemberRole handles PATCH /orgs/:id/members/:userId
+// Owners and admins can change roles; only owners can change owners or make
+// someone an owner. The last owner can't be demoted.
+func (h *OrgHandler) UpdateMemberRole(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "id")
+	memberID := chi.URLParam(r, "userId")
+	callerRole, ok := h.requireOrgManager(w, r, orgID)
+	if !ok {
+		return
+	}
+
+	var req UpdateMemberRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body: "+err.Error()))
+		return
+	}
+	if !validOrgRoles[req.Role] {
+		WriteError(w, domain.NewInvalidInputError("role must be one of owner, admin, member"))
+		return
+	}
+
+	currentRole, ok := h.memberRole(w, r, orgID, memberID)
+	if !ok {
+		return
+	}
+	if (currentRole == store.OrgRoleOwner || req.Role == store.OrgRoleOwner) && callerRole != store.OrgRoleOwner {
+		WriteError(w, domain.NewForbiddenError("Only owners can change owners or make someone an owner"))
+		return
+	}
+
+	// The store keeps the last owner from being demoted, even by concurrent requests
+	member, err := h.store.UpdateOrgMemberRole(r.Context(), orgID, memberID, req.Role)
+	if err != nil {
+		writeOrgMemberError(w, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, member)
+}
+
+// RemoveMember handles DELETE /orgs/:id/members/:userId
+// Owners and admins can remove members, and members can leave; only owners can
+// remove owners. The last owner can't be removed.
+func (h *OrgHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "id")
+	memberID := chi.URLParam(r, "userId")
+	userID := auth.GetUserID(r.Context())
+
+	callerRole, err := orgRole(r.Context(), h.store, orgID, userID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if callerRole == "" {
+		WriteError(w, domain.NewNotFoundError("Organization"))
+		return
+	}
+
+	currentRole, ok := h.memberRole(w, r, orgID, memberID)
+	if !ok {
+		return
+	}
+	if memberID != userID {
+		if callerRole != store.OrgRoleOwner && callerRole != store.OrgRoleAdmin {
+			WriteError(w, domain.NewForbiddenError("Only owners and admins can remove members"))
+			return
+		}
+		if currentRole == store.OrgRoleOwner && callerRole != store.OrgRoleOwner {
+			WriteError(w, domain.NewForbiddenError("Only owners can remove owners"))
+			return
+		}
+	}
+
+	if err := h.store.RemoveOrgMember(r.Context(), orgID, memberID); err != nil {
+		writeOrgMemberError(w, err)
+		return
+	}
+
+	WriteNoContent(w)
+}
+
+// requireOrgManager returns the caller's role if they are an owner or admin of the
+// organization, and writes an error response otherwise
+func (h *OrgHandler) requireOrgManager(w http.ResponseWriter, r *http.Request, orgID string) (string, bool) {
+	role, err := orgRole(r.Context(), h.store, orgID, auth.GetUserID(r.Context()))
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return "", false
+	}
+	switch role {
+	case "":
+		WriteError(w, domain.NewNotFoundError("Organization"))
+		return "", false
+	case store.OrgRoleOwner, store.OrgRoleAdmin:
+		return role, true
+	default:
+		WriteError(w, domain.NewForbiddenError("Only owners and admins can manage members"))
+		return "", false
+	}
+}
+
+// memberRole returns the role of the member being changed, and writes an error
+// response if they aren't a member
+func (h *OrgHandler) memberRole(w http.ResponseWriter, r *http.Request, orgID, memberID string) (string, bool) {
+	role, err := orgRole(r.Context(), h.store, orgID, memberID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return "", false
+	}
+	if role == "" {
+		WriteError(w, domain.NewNotFoundError("Member"))
+		return "", false
+	}
+	return role, true
+}
+
+// writeOrgMemberError writes the error response for a failed member change, a
+// conflict if it would have left the organization without an owner
+func writeOrgMemberError(w http.ResponseWriter, err error) {
+	if errors.Is(err, store.ErrLastOrgOwner) {
+		WriteError(w, domain.NewConflictError("An organization must keep at least one owner"))
+		return
+	}
+	WriteError(w, domain.ErrDatabase.WithError(err))
+}
+
+// orgRole returns a user's role in an organization, or "" if they aren't a member
+func orgRole(ctx context.Context, db *store.DB, orgID, userID string) (string, error) {
+	if orgID == "" || userID == "" {
+		return "", nil
+	}
+	isMember, err := db.IsUserInOrg(ctx, orgID, userID)
+	if err != nil || !isMember {
+		return "", err
+	}
+	return db.GetUserRoleInOrg(ctx, orgID, userID)
+}
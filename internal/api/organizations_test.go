@@ -0,0 +1,250 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+// setupOrgMembers creates an organization whose members have the given roles, keyed
+// by user ID
+func setupOrgMembers(t *testing.T, dbStore *store.DB, members map[string]string) string {
+	t.Helper()
+	orgID := testutil.GenerateUUIDString()
+	for userID, role := range members {
+		if _, err := dbStore.AddOrgMember(context.Background(), orgID, userID, role); err != nil {
+			t.Fatalf("Failed to add org member: %v", err)
+		}
+	}
+	return orgID
+}
+
+func TestOrgHandler_UpdateMemberRole(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	handler := NewOrgHandler(dbStore, &config.Config{})
+
+	tests := []struct {
+		name           string
+		members        map[string]string
+		caller, member string
+		role           string
+		expectedStatus int
+		expectedRole   string // The member's role afterwards
+	}{
+		{
+			name:           "admin promotes member to admin",
+			members:        map[string]string{"owner": "owner", "admin": "admin", "member": "member"},
+			caller:         "admin",
+			member:         "member",
+			role:           store.OrgRoleAdmin,
+			expectedStatus: http.StatusOK,
+			expectedRole:   store.OrgRoleAdmin,
+		},
+		{
+			name:           "admin can't make someone an owner",
+			members:        map[string]string{"owner": "owner", "admin": "admin", "member": "member"},
+			caller:         "admin",
+			member:         "member",
+			role:           store.OrgRoleOwner,
+			expectedStatus: http.StatusForbidden,
+			expectedRole:   store.OrgRoleMember,
+		},
+		{
+			name:           "admin can't make themselves an owner",
+			members:        map[string]string{"owner": "owner", "admin": "admin"},
+			caller:         "admin",
+			member:         "admin",
+			role:           store.OrgRoleOwner,
+			expectedStatus: http.StatusForbidden,
+			expectedRole:   store.OrgRoleAdmin,
+		},
+		{
+			name:           "admin can't demote an owner",
+			members:        map[string]string{"owner": "owner", "other": "owner", "admin": "admin"},
+			caller:         "admin",
+			member:         "owner",
+			role:           store.OrgRoleMember,
+			expectedStatus: http.StatusForbidden,
+			expectedRole:   store.OrgRoleOwner,
+		},
+		{
+			name:           "member can't change roles",
+			members:        map[string]string{"owner": "owner", "member": "member"},
+			caller:         "member",
+			member:         "member",
+			role:           store.OrgRoleAdmin,
+			expectedStatus: http.StatusForbidden,
+			expectedRole:   store.OrgRoleMember,
+		},
+		{
+			name:           "owner makes someone an owner",
+			members:        map[string]string{"owner": "owner", "admin": "admin"},
+			caller:         "owner",
+			member:         "admin",
+			role:           store.OrgRoleOwner,
+			expectedStatus: http.StatusOK,
+			expectedRole:   store.OrgRoleOwner,
+		},
+		{
+			name:           "owner steps down with another owner left",
+			members:        map[string]string{"owner": "owner", "other": "owner"},
+			caller:         "owner",
+			member:         "owner",
+			role:           store.OrgRoleAdmin,
+			expectedStatus: http.StatusOK,
+			expectedRole:   store.OrgRoleAdmin,
+		},
+		{
+			name:           "last owner can't step down",
+			members:        map[string]string{"owner": "owner", "admin": "admin"},
+			caller:         "owner",
+			member:         "owner",
+			role:           store.OrgRoleAdmin,
+			expectedStatus: http.StatusConflict,
+			expectedRole:   store.OrgRoleOwner,
+		},
+		{
+			name:           "last owner can stay owner",
+			members:        map[string]string{"owner": "owner"},
+			caller:         "owner",
+			member:         "owner",
+			role:           store.OrgRoleOwner,
+			expectedStatus: http.StatusOK,
+			expectedRole:   store.OrgRoleOwner,
+		},
+		{
+			name:           "unknown member",
+			members:        map[string]string{"owner": "owner"},
+			caller:         "owner",
+			member:         "nobody",
+			role:           store.OrgRoleAdmin,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgID := setupOrgMembers(t, dbStore, tt.members)
+
+			body, _ := json.Marshal(UpdateMemberRoleRequest{Role: tt.role})
+			params := map[string]string{"id": orgID, "userId": tt.member}
+			req, _ := testutil.MockRequestWithURLParamAndAuth(t, "PATCH", "/orgs/"+orgID+"/members/"+tt.member, params, bytes.NewReader(body), tt.caller, orgID)
+			w := testutil.MockResponseRecorder()
+
+			handler.UpdateMemberRole(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedRole == "" {
+				return
+			}
+			role, err := dbStore.GetUserRoleInOrg(context.Background(), orgID, tt.member)
+			if err != nil {
+				t.Fatalf("Failed to get member role: %v", err)
+			}
+			if role != tt.expectedRole {
+				t.Errorf("Expected role %s, got %s", tt.expectedRole, role)
+			}
+		})
+	}
+}
+
+func TestOrgHandler_RemoveMember(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	handler := NewOrgHandler(dbStore, &config.Config{})
+
+	tests := []struct {
+		name           string
+		members        map[string]string
+		caller, member string
+		expectedStatus int
+	}{
+		{
+			name:           "admin removes member",
+			members:        map[string]string{"owner": "owner", "admin": "admin", "member": "member"},
+			caller:         "admin",
+			member:         "member",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "member leaves",
+			members:        map[string]string{"owner": "owner", "member": "member"},
+			caller:         "member",
+			member:         "member",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "member can't remove others",
+			members:        map[string]string{"owner": "owner", "member": "member", "other": "member"},
+			caller:         "member",
+			member:         "other",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "admin can't remove an owner",
+			members:        map[string]string{"owner": "owner", "other": "owner", "admin": "admin"},
+			caller:         "admin",
+			member:         "owner",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "owner removes another owner",
+			members:        map[string]string{"owner": "owner", "other": "owner"},
+			caller:         "owner",
+			member:         "other",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "last owner can't leave",
+			members:        map[string]string{"owner": "owner", "admin": "admin"},
+			caller:         "owner",
+			member:         "owner",
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "non-member",
+			members:        map[string]string{"owner": "owner"},
+			caller:         "stranger",
+			member:         "owner",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgID := setupOrgMembers(t, dbStore, tt.members)
+
+			params := map[string]string{"id": orgID, "userId": tt.member}
+			req, _ := testutil.MockRequestWithURLParamAndAuth(t, "DELETE", "/orgs/"+orgID+"/members/"+tt.member, params, nil, tt.caller, orgID)
+			w := testutil.MockResponseRecorder()
+
+			handler.RemoveMember(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			isMember, err := dbStore.IsUserInOrg(context.Background(), orgID, tt.member)
+			if err != nil {
+				t.Fatalf("Failed to check membership: %v", err)
+			}
+			if removed := tt.expectedStatus == http.StatusNoContent; isMember == removed {
+				t.Errorf("Expected member removed: %v, still a member: %v", removed, isMember)
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/intelifox/click-deploy/internal/config"
 	"github.com/intelifox/click-deploy/internal/email"
 	"github.com/intelifox/click-deploy/internal/store"
@@ -227,6 +229,7 @@ func (h *OTPHandler) CompleteRegistration(w http.ResponseWriter, r *http.Request
 
 	// Generate tokens
 	authHandler := NewCustomAuthHandler(h.db, h.config)
+	sessionID := uuid.NewString()
 	tokenPair, err := authHandler.jwtService.GenerateTokenPair(
 		user.ID,
 		user.Email,
@@ -234,12 +237,20 @@ func (h *OTPHandler) CompleteRegistration(w http.ResponseWriter, r *http.Request
 		org.ID,
 		org.Slug,
 		"owner",
+		sessionID,
 	)
 	if err != nil {
 		http.Error(w, "Failed to generate tokens: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// Store refresh token
+	expiresAt := time.Now().Add(authHandler.jwtService.RefreshExpiry())
+	if _, err := h.db.CreateRefreshToken(r.Context(), sessionID, user.ID, tokenPair.RefreshToken, expiresAt, sessionInfo(r, h.config)); err != nil {
+		http.Error(w, "Failed to store refresh token", http.StatusInternalServerError)
+		return
+	}
+
 	// Clean up OTP codes for this email
 	_ = h.db.DeleteOTPCodes(r.Context(), req.Email, store.OTPPurposeRegistration)
 
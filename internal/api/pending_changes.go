@@ -7,28 +7,34 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 
+	"github.com/intelifox/click-deploy/internal/auth"
 	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/k8s"
 	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/worker"
 )
 
 // PendingChangesHandler handles pending changes endpoints
 type PendingChangesHandler struct {
-	store  *store.DB
-	config *config.Config
+	store       *store.DB
+	config      *config.Config
+	buildWorker *worker.BuildWorker
+	k8sWorker   *worker.K8sDeployWorker
 }
 
 // NewPendingChangesHandler creates a new pending changes handler
-func NewPendingChangesHandler(store *store.DB, cfg *config.Config) *PendingChangesHandler {
-	return &PendingChangesHandler{
-		store:  store,
-		config: cfg,
+func NewPendingChangesHandler(store *store.DB, cfg *config.Config, buildWorker *worker.BuildWorker, k8sClients *k8s.ClientRegistry) *PendingChangesHandler {
+	var k8sWorker *worker.K8sDeployWorker
+	if k8sClients != nil {
+		k8sWorker = worker.NewK8sDeployWorker(store, cfg, k8sClients)
 	}
-}
 
-// PendingChangesResponse is the response for pending changes
-type PendingChangesResponse struct {
-	Count   int                    `json:"count"`
-	Commits []*store.PendingCommit `json:"commits"`
+	return &PendingChangesHandler{
+		store:       store,
+		config:      cfg,
+		buildWorker: buildWorker,
+		k8sWorker:   k8sWorker,
+	}
 }
 
 // ListPendingChanges returns pending changes for a service
@@ -46,17 +52,8 @@ func (h *PendingChangesHandler) ListPendingChanges(w http.ResponseWriter, r *htt
 		return
 	}
 
-	if commits == nil {
-		commits = []*store.PendingCommit{}
-	}
-
-	response := PendingChangesResponse{
-		Count:   len(commits),
-		Commits: commits,
-	}
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(newListResponse(commits))
 }
 
 // AcknowledgeRequest is the request body for acknowledging changes
@@ -143,11 +140,25 @@ func (h *PendingChangesHandler) DeployPendingChanges(w http.ResponseWriter, r *h
 		return
 	}
 
+	project, err := h.store.GetProject(r.Context(), service.ProjectID)
+	if err != nil || project == nil {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	// Deployments beyond the org's concurrent deployment limit wait for a free slot
+	status, err := newDeploymentStatus(r.Context(), h.store, h.config, service, project.CasdoorOrgID)
+	if err != nil {
+		http.Error(w, "Failed to create deployment", http.StatusInternalServerError)
+		return
+	}
+
 	// Create deployment
 	deployment := &store.Deployment{
 		ServiceID:    serviceID,
-		Status:       "queued",
+		Status:       status,
 		TriggeredBy:  "pending_changes",
+		RequestedBy:  store.StringToNullString(auth.GetUserID(r.Context())),
 	}
 	
 	// Set commit info
@@ -164,20 +175,17 @@ func (h *PendingChangesHandler) DeployPendingChanges(w http.ResponseWriter, r *h
 		// Log but don't fail - deployment was created
 	}
 
-	// Create job
-	job := &store.Job{
-		Type:    "build",
-		Payload: map[string]interface{}{"deployment_id": deployment.ID.String()},
-		Status:  "pending",
-	}
-	if err := h.store.CreateJob(r.Context(), job); err != nil {
-		http.Error(w, "Failed to queue deployment", http.StatusInternalServerError)
+	// Run the pipeline, unless it has to be approved or wait for a slot first
+	startDeployment(r.Context(), h.store, h.config, h.buildWorker, h.k8sWorker, deployment)
+
+	response, err := newDeploymentResponse(r.Context(), h.store, deployment)
+	if err != nil {
+		http.Error(w, "Failed to load deployment", http.StatusInternalServerError)
 		return
 	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(deployment)
+	json.NewEncoder(w).Encode(response)
 }
 
 // GetPendingChangesCount returns just the count
@@ -200,8 +208,8 @@ func (h *PendingChangesHandler) GetPendingChangesCount(w http.ResponseWriter, r
 }
 
 // RegisterPendingChangesRoutes registers pending changes routes
-func RegisterPendingChangesRoutes(r chi.Router, db *store.DB, cfg *config.Config) {
-	handler := NewPendingChangesHandler(db, cfg)
+func RegisterPendingChangesRoutes(r chi.Router, db *store.DB, cfg *config.Config, buildWorker *worker.BuildWorker, k8sClients *k8s.ClientRegistry) {
+	handler := NewPendingChangesHandler(db, cfg, buildWorker, k8sClients)
 
 	r.Get("/services/{id}/pending-changes", handler.ListPendingChanges)
 	r.Get("/services/{id}/pending-changes/count", handler.GetPendingChangesCount)
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestPendingChangesHandler_DeployPendingChanges(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	handler := NewPendingChangesHandler(dbStore, &config.Config{}, nil, nil)
+
+	project := testutil.NewProject(t, db)
+	service := testutil.NewService(t, db, project.ID)
+
+	req, _ := testutil.MockRequestWithURLParamAndAuth(t, "POST", "/v1/click-deploy/services/"+service.ID.String()+"/pending-changes/deploy",
+		map[string]string{"id": service.ID.String()}, strings.NewReader(`{"up_to_commit_sha":"abc123"}`), "test-user", project.OrgID)
+	w := testutil.MockResponseRecorder()
+
+	handler.DeployPendingChanges(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Response: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var response DeploymentResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	// The handler has no build or k8s worker, so the deployment fails rather than staying queued
+	if response.Status != "failed" {
+		t.Errorf("Expected failed deployment, got status %q", response.Status)
+	}
+
+	var jobs int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM jobs`).Scan(&jobs); err != nil {
+		t.Fatalf("Failed to count jobs: %v", err)
+	}
+	if jobs != 0 {
+		t.Errorf("Expected no build job, got %d", jobs)
+	}
+}
@@ -0,0 +1,395 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/secrets"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// CreateProjectEnvVarRequest represents a request to create a project-level environment variable.
+// Setting linked_database_id makes it a shared secret that services opt into by reference.
+type CreateProjectEnvVarRequest struct {
+	Key              string    `json:"key"`
+	Value            string    `json:"value,omitempty"` // Optional if linked to database
+	IsSecret         bool      `json:"is_secret,omitempty"`
+	LinkedDatabaseID uuid.UUID `json:"linked_database_id,omitempty"`
+	LinkType         string    `json:"link_type,omitempty"`   // connection_url, host, port, username, password, database
+	Interpolate      bool      `json:"interpolate,omitempty"` // Expand ${VAR} references in the value at deploy time; not for secrets
+}
+
+// UpdateProjectEnvVarRequest represents a request to update a project-level environment variable
+type UpdateProjectEnvVarRequest struct {
+	Value       *string `json:"value,omitempty"`
+	IsSecret    *bool   `json:"is_secret,omitempty"`
+	Interpolate *bool   `json:"interpolate,omitempty"`
+}
+
+// ProjectEnvVarResponse represents a project-level environment variable in API responses
+type ProjectEnvVarResponse struct {
+	ID               string `json:"id"`
+	ProjectID        string `json:"project_id"`
+	Key              string `json:"key"`
+	Value            string `json:"value,omitempty"`
+	IsSecret         bool   `json:"is_secret"`
+	Shared           bool   `json:"shared"` // Database-linked; only reaches services that reference it
+	LinkedDatabaseID string `json:"linked_database_id,omitempty"`
+	LinkType         string `json:"link_type,omitempty"`
+	Interpolate      bool   `json:"interpolate"`
+	CreatedAt        string `json:"created_at"`
+	UpdatedAt        string `json:"updated_at"`
+}
+
+// EffectiveEnvVarResponse is one entry of a service's resolved environment
+type EffectiveEnvVarResponse struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	IsSecret  bool   `json:"is_secret"`
+	Source    string `json:"source"`    // project or service
+	Overrides bool   `json:"overrides"` // service var replaces a project var with the same key
+	// Set for a var linked to another service, whose value is its address once deployed
+	LinkedServiceID string `json:"linked_service_id,omitempty"`
+	LinkType        string `json:"link_type,omitempty"`
+}
+
+func toProjectEnvVarResponse(ev *store.ProjectEnvVar) ProjectEnvVarResponse {
+	resp := ProjectEnvVarResponse{
+		ID:          ev.ID.String(),
+		ProjectID:   ev.ProjectID.String(),
+		Key:         ev.Key,
+		IsSecret:    ev.IsSecret,
+		Shared:      ev.IsShared(),
+		Interpolate: ev.Interpolate,
+		CreatedAt:   ev.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:   ev.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if ev.Value.Valid {
+		resp.Value = ev.Value.String
+		if ev.IsSecret {
+			resp.Value = "***"
+		}
+	}
+	if ev.LinkedDatabaseID.Valid {
+		resp.LinkedDatabaseID = ev.LinkedDatabaseID.String
+	}
+	if ev.LinkType.Valid {
+		resp.LinkType = ev.LinkType.String
+	}
+	return resp
+}
+
+// getOwnedProject loads a project from the {id} URL param and checks it belongs to the caller's org.
+// It writes the error response and returns nil when the project can't be used.
+func (h *EnvVarHandler) getOwnedProject(w http.ResponseWriter, r *http.Request) *store.Project {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return nil
+	}
+
+	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid project ID"))
+		return nil
+	}
+
+	project, err := h.store.GetProject(r.Context(), projectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil
+	}
+	if !checkOrgAccess(w, project, orgID, "Project") {
+		return nil
+	}
+
+	return project
+}
+
+// ListProjectEnvVars handles GET /projects/:id/env
+func (h *EnvVarHandler) ListProjectEnvVars(w http.ResponseWriter, r *http.Request) {
+	project := h.getOwnedProject(w, r)
+	if project == nil {
+		return
+	}
+
+	envVars, err := h.store.ListProjectEnvVars(r.Context(), project.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	response := make([]ProjectEnvVarResponse, 0, len(envVars))
+	for _, ev := range envVars {
+		response = append(response, toProjectEnvVarResponse(ev))
+	}
+
+	WriteJSON(w, http.StatusOK, newListResponse(response))
+}
+
+// CreateProjectEnvVar handles POST /projects/:id/env
+func (h *EnvVarHandler) CreateProjectEnvVar(w http.ResponseWriter, r *http.Request) {
+	project := h.getOwnedProject(w, r)
+	if project == nil {
+		return
+	}
+
+	var req CreateProjectEnvVarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	req.Key = SanitizeName(req.Key)
+	req.LinkType = SanitizeName(req.LinkType)
+
+	validationErrs := ValidateEnvVarKey(req.Key)
+	if req.LinkedDatabaseID != uuid.Nil {
+		if errs := ValidateOneOf(req.LinkType, "link_type", validDatabaseLinkTypes); errs.HasErrors() {
+			validationErrs.Errors = append(validationErrs.Errors, errs.Errors...)
+		}
+	} else if req.Value == "" {
+		validationErrs.Add("value", "value is required if not linking to database")
+	}
+	if req.Interpolate && req.IsSecret {
+		validationErrs.Add("interpolate", "secret values are never interpolated")
+	}
+	if validationErrs.HasErrors() {
+		WriteError(w, validationErrs.ToAppError())
+		return
+	}
+
+	if req.LinkedDatabaseID != uuid.Nil {
+		if !h.databaseBelongsToProject(w, r, req.LinkedDatabaseID, project.ID) {
+			return
+		}
+	}
+
+	existing, err := h.store.GetProjectEnvVarByKey(r.Context(), project.ID, req.Key)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if existing != nil {
+		WriteError(w, domain.NewConflictError("Environment variable already exists for this project"))
+		return
+	}
+
+	envVar := &store.ProjectEnvVar{
+		ProjectID:   project.ID,
+		Key:         req.Key,
+		IsSecret:    req.IsSecret,
+		Interpolate: req.Interpolate,
+	}
+	if req.LinkedDatabaseID != uuid.Nil {
+		envVar.LinkedDatabaseID = sql.NullString{String: req.LinkedDatabaseID.String(), Valid: true}
+		envVar.LinkType = sql.NullString{String: req.LinkType, Valid: true}
+	} else {
+		envVar.Value = sql.NullString{String: req.Value, Valid: true}
+	}
+	if err := h.store.CreateProjectEnvVar(r.Context(), envVar); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteCreated(w, toProjectEnvVarResponse(envVar))
+}
+
+// UpdateProjectEnvVar handles PATCH /projects/:id/env/:key
+func (h *EnvVarHandler) UpdateProjectEnvVar(w http.ResponseWriter, r *http.Request) {
+	project := h.getOwnedProject(w, r)
+	if project == nil {
+		return
+	}
+
+	envVar, err := h.store.GetProjectEnvVarByKey(r.Context(), project.ID, chi.URLParam(r, "key"))
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if envVar == nil {
+		WriteError(w, domain.NewNotFoundError("Environment variable"))
+		return
+	}
+
+	var req UpdateProjectEnvVarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	if req.Value != nil {
+		if envVar.IsShared() {
+			WriteError(w, domain.NewValidationError("value cannot be set on a database-linked variable"))
+			return
+		}
+		if *req.Value == "" {
+			WriteError(w, domain.NewValidationError("value cannot be empty"))
+			return
+		}
+		envVar.Value = sql.NullString{String: *req.Value, Valid: true}
+	}
+	if req.IsSecret != nil {
+		envVar.IsSecret = *req.IsSecret
+	}
+	if req.Interpolate != nil {
+		envVar.Interpolate = *req.Interpolate
+	}
+	if envVar.Interpolate && envVar.IsSecret {
+		WriteError(w, domain.NewValidationError("secret values are never interpolated"))
+		return
+	}
+
+	if err := h.store.UpdateProjectEnvVar(r.Context(), envVar.ID, envVar); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, toProjectEnvVarResponse(envVar))
+}
+
+// DeleteProjectEnvVar handles DELETE /projects/:id/env/:key
+func (h *EnvVarHandler) DeleteProjectEnvVar(w http.ResponseWriter, r *http.Request) {
+	project := h.getOwnedProject(w, r)
+	if project == nil {
+		return
+	}
+
+	envVar, err := h.store.GetProjectEnvVarByKey(r.Context(), project.ID, chi.URLParam(r, "key"))
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if envVar == nil {
+		WriteError(w, domain.NewNotFoundError("Environment variable"))
+		return
+	}
+
+	if err := h.store.DeleteProjectEnvVar(r.Context(), envVar.ID); err != nil {
+		if err == sql.ErrNoRows {
+			WriteError(w, domain.NewNotFoundError("Environment variable"))
+			return
+		}
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteNoContent(w)
+}
+
+// ExportEnvVars handles GET /services/:id/env/export
+// It returns the effective environment the service is deployed with: project
+// vars first, overridden key by key by service vars. Each entry reports where
+// its value came from. Secret values are masked.
+func (h *EnvVarHandler) ExportEnvVars(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	serviceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid service ID"))
+		return
+	}
+
+	if !h.serviceBelongsToOrg(r.Context(), w, serviceID, orgID) {
+		return
+	}
+
+	envVars, err := h.store.ResolveEnvVarsWithSource(r.Context(), serviceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	response := make([]EffectiveEnvVarResponse, 0, len(envVars))
+	for _, ev := range envVars {
+		entry := EffectiveEnvVarResponse{
+			Key:       ev.Key,
+			Value:     ev.Value,
+			IsSecret:  ev.IsSecret,
+			Source:    ev.Source,
+			Overrides: ev.Overrides,
+		}
+		if ev.IsSecret {
+			entry.Value = "***"
+		} else {
+			// A linked database's connection URL is only returned with its credentials
+			entry.Value = secrets.RedactConnectionURL(entry.Value)
+		}
+		if ev.ServiceLink != nil {
+			entry.LinkedServiceID = ev.ServiceLink.ServiceID.String()
+			entry.LinkType = ev.ServiceLink.LinkType
+		}
+		response = append(response, entry)
+	}
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// validDatabaseLinkTypes are the database fields an env var can be linked to
+var validDatabaseLinkTypes = []string{"connection_url", "host", "port", "username", "password", "database"}
+
+// databaseBelongsToProject checks the database exists and is attached to a service in the project,
+// writing the error response if not
+func (h *EnvVarHandler) databaseBelongsToProject(w http.ResponseWriter, r *http.Request, databaseID, projectID uuid.UUID) bool {
+	database, err := h.store.GetDatabase(r.Context(), databaseID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return false
+	}
+	if database == nil || !database.ServiceID.Valid {
+		WriteError(w, domain.NewValidationError("Database not found"))
+		return false
+	}
+
+	dbServiceID, err := uuid.Parse(database.ServiceID.String)
+	if err != nil {
+		WriteError(w, domain.NewValidationError("Database not found"))
+		return false
+	}
+	service, err := h.store.GetService(r.Context(), dbServiceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return false
+	}
+	if service == nil || service.ProjectID != projectID {
+		WriteError(w, domain.NewValidationError("Database does not belong to this project"))
+		return false
+	}
+
+	return true
+}
+
+// serviceBelongsToOrg checks the service exists and belongs to orgID, writing the error response if not
+func (h *EnvVarHandler) serviceBelongsToOrg(ctx context.Context, w http.ResponseWriter, serviceID uuid.UUID, orgID string) bool {
+	service, err := h.store.GetService(ctx, serviceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return false
+	}
+	if service == nil {
+		WriteError(w, domain.NewNotFoundError("Service"))
+		return false
+	}
+
+	project, err := h.store.GetProject(ctx, service.ProjectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return false
+	}
+	if !checkOrgAccess(w, project, orgID, "Service") {
+		return false
+	}
+
+	return true
+}
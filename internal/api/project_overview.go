@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// OverviewService is a service with its live k8s status
+type OverviewService struct {
+	ServiceResponse
+	Live ServiceStatusResponse `json:"live"`
+}
+
+// ProjectOverviewResponse holds everything the project dashboard renders
+type ProjectOverviewResponse struct {
+	Project       ProjectResponse       `json:"project"`
+	Services      []OverviewService     `json:"services"`
+	Databases     []*store.Database     `json:"databases"`
+	Volumes       []*store.Volume       `json:"volumes"`
+	CustomDomains []*store.CustomDomain `json:"custom_domains"`
+}
+
+// GetProjectOverview handles GET /projects/:id/overview
+// Returns services (with live status), databases (credentials masked), volumes and
+// custom domains of a project in one response, checking project ownership once.
+func (h *ServiceHandler) GetProjectOverview(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid project ID"))
+		return
+	}
+
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	project, err := h.Store.GetProject(r.Context(), projectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if !checkOrgAccess(w, project, orgID, "Project") {
+		return
+	}
+
+	services, err := h.Store.ListServicesByProject(r.Context(), projectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	databases, err := h.Store.ListDatabasesByProject(r.Context(), projectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	volumes, err := h.Store.ListVolumesByProject(r.Context(), projectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	customDomains, err := h.Store.ListCustomDomainsByProject(r.Context(), projectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	live := h.liveStatuses(r.Context(), project, services)
+
+	response := ProjectOverviewResponse{
+		Project:       toProjectResponse(project),
+		Services:      make([]OverviewService, 0, len(services)),
+		Databases:     make([]*store.Database, 0, len(databases)),
+		Volumes:       make([]*store.Volume, 0, len(volumes)),
+		CustomDomains: make([]*store.CustomDomain, 0, len(customDomains)),
+	}
+	for _, s := range services {
+		response.Services = append(response.Services, OverviewService{
+			ServiceResponse: h.toServiceResponseWithGitSource(r.Context(), s),
+			Live:            serviceStatus(s, live),
+		})
+	}
+	deployStates := make([]*ServiceResponse, len(response.Services))
+	for i := range response.Services {
+		deployStates[i] = &response.Services[i].ServiceResponse
+	}
+	if err := applyDeployStates(r.Context(), h.Store, deployStates...); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	for _, d := range databases {
+		maskDatabaseCredentials(d)
+		response.Databases = append(response.Databases, d)
+	}
+	response.Volumes = append(response.Volumes, volumes...)
+	response.CustomDomains = append(response.CustomDomains, customDomains...)
+
+	WriteJSONWithETag(w, r, response)
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// TransferProject handles POST /projects/:id/transfer
+// Moves a project, with its services, databases and volumes, to another
+// organization. The caller must be an owner of both organizations. The project
+// keeps its slug, which therefore must be free in the target organization. Git
+// sources move to a git connection of the target organization to the same provider,
+// and are detached when it has none. Projects with secret references are refused.
+func (h *ProjectHandler) TransferProject(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid project ID"))
+		return
+	}
+
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+	userID := auth.GetUserID(r.Context())
+
+	var req TransferProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body: "+err.Error()))
+		return
+	}
+	if req.TargetOrgID == "" {
+		WriteError(w, domain.NewInvalidInputError("target_org_id is required"))
+		return
+	}
+
+	project, err := h.Store.GetProject(r.Context(), id)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if !checkOrgAccess(w, project, orgID, "Project") {
+		return
+	}
+	if req.TargetOrgID == project.CasdoorOrgID {
+		WriteError(w, domain.NewInvalidInputError("Project already belongs to the target organization"))
+		return
+	}
+
+	for _, org := range []string{project.CasdoorOrgID, req.TargetOrgID} {
+		isOwner, err := h.isOrgOwner(r.Context(), org, userID)
+		if err != nil {
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return
+		}
+		if !isOwner {
+			WriteError(w, domain.NewForbiddenError("Transferring a project requires the owner role in both organizations"))
+			return
+		}
+	}
+
+	taken, err := h.takenProjectSlugs(r.Context(), req.TargetOrgID, uuid.Nil)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if taken[project.Slug] {
+		WriteError(w, domain.NewConflictError(fmt.Sprintf("Project slug %q is already in use in the target organization", project.Slug)))
+		return
+	}
+
+	fromOrgID := project.CasdoorOrgID
+	detached, err := h.Store.TransferProject(r.Context(), project.ID, fromOrgID, req.TargetOrgID, project.Slug)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrProjectHasSecretRefs):
+			WriteError(w, domain.NewConflictError("Project env vars reference secrets of its organization, remove them before transferring"))
+		case store.IsUniqueViolation(err):
+			WriteError(w, domain.NewConflictError(fmt.Sprintf("Project slug %q is already in use in the target organization", project.Slug)))
+		case errors.Is(err, sql.ErrNoRows):
+			// Transferred or deleted concurrently
+			WriteError(w, domain.NewNotFoundError("Project"))
+		default:
+			WriteError(w, domain.ErrDatabase.WithError(err))
+		}
+		return
+	}
+	if detached > 0 {
+		log.Printf("Detached %d git sources of project %s: org %s has no git connection to their provider", detached, project.ID, req.TargetOrgID)
+	}
+
+	// The database is the source of truth for ownership; a stale label is only logged
+	if client := projectK8sClient(h.k8sClients, project); client != nil {
+		if err := client.SetNamespaceOrg(r.Context(), project.ID.String(), req.TargetOrgID); err != nil {
+			log.Printf("Failed to relabel namespace of project %s with org %s: %v", project.ID, req.TargetOrgID, err)
+		}
+	}
+
+	for _, org := range []string{fromOrgID, req.TargetOrgID} {
+		entry := &store.AuditLogEntry{
+			OrgID:        org,
+			ActorID:      store.StringToNullString(userID),
+			Action:       store.AuditActionProjectTransfer,
+			ResourceType: "project",
+			ResourceID:   project.ID.String(),
+			Metadata: map[string]interface{}{
+				"from_org_id":          fromOrgID,
+				"to_org_id":            req.TargetOrgID,
+				"detached_git_sources": detached,
+			},
+		}
+		if err := h.Store.CreateAuditLogEntry(r.Context(), entry); err != nil {
+			log.Printf("Failed to write audit log entry %s for project %s: %v", entry.Action, project.ID, err)
+		}
+	}
+
+	transferred, err := h.Store.GetProject(r.Context(), project.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, toProjectResponse(transferred))
+}
+
+// isOrgOwner reports whether a user has the owner role in an organization
+func (h *ProjectHandler) isOrgOwner(ctx context.Context, orgID, userID string) (bool, error) {
+	role, err := orgRole(ctx, h.Store, orgID, userID)
+	return role == store.OrgRoleOwner, err
+}
@@ -1,62 +1,83 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 
 	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/build"
 	"github.com/intelifox/click-deploy/internal/config"
 	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/k8s"
 	"github.com/intelifox/click-deploy/internal/store"
 	"github.com/intelifox/click-deploy/internal/worker"
 )
 
 type ProjectHandler struct {
-	Store  *store.DB
-	config *config.Config
+	Store      *store.DB
+	config     *config.Config
+	k8sClients *k8s.ClientRegistry
 }
 
 // NewProjectHandler creates a new project handler
-func NewProjectHandler(store *store.DB, cfg *config.Config) *ProjectHandler {
+func NewProjectHandler(store *store.DB, cfg *config.Config, k8sClients *k8s.ClientRegistry) *ProjectHandler {
 	return &ProjectHandler{
-		Store:  store,
-		config: cfg,
+		Store:      store,
+		config:     cfg,
+		k8sClients: k8sClients,
 	}
 }
 
+// projectK8sClient returns the client of the cluster of a project's region, or nil
+// when k8s is not configured or the region has no cluster
+func projectK8sClient(clients *k8s.ClientRegistry, project *store.Project) *k8s.Client {
+	client, err := clients.Client(worker.ProjectRegion(project))
+	if err != nil {
+		return nil
+	}
+	return client
+}
+
 // ProjectResponse represents a project in API responses
 type ProjectResponse struct {
-	ID                string  `json:"id"`
-	Name              string  `json:"name"`
-	Slug              string  `json:"slug"`
-	Description       *string `json:"description,omitempty"`
-	CasdoorOrgID      string  `json:"casdoor_org_id"`
-	OpenStackTenantID *string `json:"openstack_tenant_id,omitempty"`
-	OpenStackNetworkID *string `json:"openstack_network_id,omitempty"`
-	DefaultRegion     *string `json:"default_region,omitempty"`
-	AutoDeploy        bool    `json:"auto_deploy"`
-	CreatedBy         *string `json:"created_by,omitempty"`
-	CreatedAt         string  `json:"created_at"`
-	UpdatedAt         string  `json:"updated_at"`
+	ID                  string  `json:"id"`
+	Name                string  `json:"name"`
+	Slug                string  `json:"slug"`
+	Description         *string `json:"description,omitempty"`
+	CasdoorOrgID        string  `json:"casdoor_org_id"`
+	OpenStackTenantID   *string `json:"openstack_tenant_id,omitempty"`
+	OpenStackNetworkID  *string `json:"openstack_network_id,omitempty"`
+	DefaultRegion       *string `json:"default_region,omitempty"`
+	AutoDeploy          bool    `json:"auto_deploy"`
+	ImageRetentionCount *int    `json:"image_retention_count,omitempty"`
+	ImageTagStrategy    string  `json:"image_tag_strategy"`
+	DefaultInstanceSize string  `json:"default_instance_size"`
+	DefaultPort         int     `json:"default_port"`
+	CreatedBy           *string `json:"created_by,omitempty"`
+	CreatedAt           string  `json:"created_at"`
+	UpdatedAt           string  `json:"updated_at"`
 }
 
 // toProjectResponse converts a store.Project to ProjectResponse
 func toProjectResponse(p *store.Project) ProjectResponse {
 	resp := ProjectResponse{
-		ID:           p.ID.String(),
-		Name:         p.Name,
-		Slug:         p.Slug,
-		CasdoorOrgID: p.CasdoorOrgID,
-		AutoDeploy:   p.AutoDeploy,
-		CreatedAt:    p.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:    p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ID:               p.ID.String(),
+		Name:             p.Name,
+		Slug:             p.Slug,
+		CasdoorOrgID:     p.CasdoorOrgID,
+		AutoDeploy:       p.AutoDeploy,
+		ImageTagStrategy: build.ImageTagStrategyCommit,
+		CreatedAt:        p.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:        p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
 
 	if p.Description.Valid {
@@ -68,9 +89,17 @@ func toProjectResponse(p *store.Project) ProjectResponse {
 	if p.OpenStackNetworkID.Valid {
 		resp.OpenStackNetworkID = &p.OpenStackNetworkID.String
 	}
+	if p.ImageRetentionCount.Valid {
+		count := int(p.ImageRetentionCount.Int64)
+		resp.ImageRetentionCount = &count
+	}
+	if p.ImageTagStrategy.Valid {
+		resp.ImageTagStrategy = p.ImageTagStrategy.String
+	}
 	if p.DefaultRegion.Valid {
 		resp.DefaultRegion = &p.DefaultRegion.String
 	}
+	resp.DefaultInstanceSize, resp.DefaultPort = serviceDefaults(p)
 	if p.CreatedBy.Valid {
 		resp.CreatedBy = &p.CreatedBy.String
 	}
@@ -118,7 +147,7 @@ func (h *ProjectHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	WriteJSON(w, http.StatusOK, response)
+	WriteJSON(w, http.StatusOK, newListResponse(response))
 }
 
 func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
@@ -148,8 +177,7 @@ func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify project belongs to organization
-	if !project.BelongsToOrg(orgID) {
-		WriteError(w, domain.NewNotFoundError("Project"))
+	if !checkOrgAccess(w, project, orgID, "Project") {
 		return
 	}
 
@@ -173,40 +201,18 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Sanitize and validate request
-	req.Name = SanitizeString(req.Name)
-	if validationErrs := ValidateCreateProjectRequest(&req); validationErrs.HasErrors() {
+	req.Name = SanitizeName(req.Name)
+	sanitizeOptional(req.Slug, SanitizeName)
+	sanitizeOptional(req.Description, SanitizeText)
+	sanitizeOptional(req.OpenStackTenantID, SanitizeName)
+	sanitizeOptional(req.DefaultRegion, SanitizeName)
+	validationErrs := ValidateCreateProjectRequest(&req)
+	h.validateRegion(req.DefaultRegion, validationErrs)
+	if validationErrs.HasErrors() {
 		WriteError(w, validationErrs.ToAppError())
 		return
 	}
 
-	// Generate slug from name
-	slug := store.GenerateSlug(req.Name)
-
-	// Check if slug already exists for this org
-	existingProjects, err := h.Store.ListProjectsByOrg(r.Context(), orgID)
-	if err != nil {
-		WriteError(w, domain.ErrDatabase.WithError(err))
-		return
-	}
-
-	// Ensure slug is unique
-	baseSlug := slug
-	counter := 1
-	for {
-		unique := true
-		for _, p := range existingProjects {
-			if p.Slug == slug {
-				unique = false
-				break
-			}
-		}
-		if unique {
-			break
-		}
-		slug = baseSlug + "-" + strconv.Itoa(counter)
-		counter++
-	}
-
 	// Determine OpenStack tenant ID
 	tenantID := ""
 	if req.OpenStackTenantID != nil && *req.OpenStackTenantID != "" {
@@ -224,7 +230,6 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 	project := &store.Project{
 		CasdoorOrgID:      orgID,
 		Name:              req.Name,
-		Slug:              slug,
 		OpenStackTenantID: tenantID,
 		AutoDeploy:        true,
 	}
@@ -241,6 +246,18 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 		project.AutoDeploy = *req.AutoDeploy
 	}
 
+	if req.ImageTagStrategy != nil && *req.ImageTagStrategy != "" {
+		project.ImageTagStrategy = sql.NullString{String: *req.ImageTagStrategy, Valid: true}
+	}
+
+	if req.DefaultInstanceSize != nil && *req.DefaultInstanceSize != "" {
+		project.DefaultInstanceSize = sql.NullString{String: *req.DefaultInstanceSize, Valid: true}
+	}
+
+	if req.DefaultPort != nil {
+		project.DefaultPort = sql.NullInt64{Int64: int64(*req.DefaultPort), Valid: true}
+	}
+
 	if userID != "" {
 		project.CreatedBy = sql.NullString{String: userID, Valid: true}
 		// Also set UserID as UUID for custom auth
@@ -254,7 +271,18 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 		project.OrgID = uuid.NullUUID{UUID: parsedOrgID, Valid: true}
 	}
 
-	if err := h.Store.CreateProject(r.Context(), project); err != nil {
+	if req.Slug != nil && *req.Slug != "" {
+		// An explicit slug is used as-is, so a collision is the caller's to resolve
+		project.Slug = *req.Slug
+		if err := h.Store.CreateProject(r.Context(), project); err != nil {
+			if store.IsUniqueViolation(err) {
+				WriteError(w, domain.NewConflictError(fmt.Sprintf("Project slug %q is already in use", project.Slug)))
+				return
+			}
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return
+		}
+	} else if err := h.createProjectWithGeneratedSlug(r.Context(), project, orgID); err != nil {
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
@@ -269,6 +297,53 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 	WriteCreated(w, toProjectResponse(createdProject))
 }
 
+// maxSlugAttempts bounds retries when a generated slug is taken by a concurrent create
+const maxSlugAttempts = 5
+
+// createProjectWithGeneratedSlug creates a project with a slug generated from its name.
+// Collisions get a numeric suffix ("my-app-2"); a slug taken concurrently is skipped and the insert retried.
+func (h *ProjectHandler) createProjectWithGeneratedSlug(ctx context.Context, project *store.Project, orgID string) error {
+	taken, err := h.takenProjectSlugs(ctx, orgID, uuid.Nil)
+	if err != nil {
+		return err
+	}
+
+	base := store.GenerateSlug(project.Name)
+	for attempt := 1; ; attempt++ {
+		project.Slug = nextProjectSlug(base, taken)
+		err = h.Store.CreateProject(ctx, project)
+		if err == nil || !store.IsUniqueViolation(err) || attempt == maxSlugAttempts {
+			return err
+		}
+		taken[project.Slug] = true
+	}
+}
+
+// takenProjectSlugs returns the slugs used by an org's projects, except the project excludeID
+func (h *ProjectHandler) takenProjectSlugs(ctx context.Context, orgID string, excludeID uuid.UUID) (map[string]bool, error) {
+	projects, err := h.Store.ListProjectsByOrg(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	taken := make(map[string]bool, len(projects))
+	for _, p := range projects {
+		if p.ID != excludeID {
+			taken[p.Slug] = true
+		}
+	}
+	return taken, nil
+}
+
+// nextProjectSlug returns base, or base with the lowest numeric suffix from 2 that isn't taken
+func nextProjectSlug(base string, taken map[string]bool) string {
+	slug := base
+	for n := 2; taken[slug]; n++ {
+		slug = base + "-" + strconv.Itoa(n)
+	}
+	return slug
+}
+
 // UpdateProject handles PATCH /projects/:id
 func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
@@ -309,17 +384,27 @@ func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate request
-	if validationErrs := ValidateUpdateProjectRequest(&req); validationErrs.HasErrors() {
+	// Sanitize and validate request
+	sanitizeOptional(req.Name, SanitizeName)
+	sanitizeOptional(req.Description, SanitizeText)
+	sanitizeOptional(req.DefaultRegion, SanitizeName)
+	validationErrs := ValidateUpdateProjectRequest(&req)
+	h.validateRegion(req.DefaultRegion, validationErrs)
+	if validationErrs.HasErrors() {
 		WriteError(w, validationErrs.ToAppError())
 		return
 	}
 
 	// Update fields if provided
-	if req.Name != nil {
+	if req.Name != nil && *req.Name != project.Name {
 		project.Name = *req.Name
 		// Regenerate slug if name changed
-		project.Slug = store.GenerateSlug(*req.Name)
+		taken, err := h.takenProjectSlugs(r.Context(), orgID, project.ID)
+		if err != nil {
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return
+		}
+		project.Slug = nextProjectSlug(store.GenerateSlug(*req.Name), taken)
 	}
 
 	if req.Description != nil {
@@ -334,6 +419,22 @@ func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		project.AutoDeploy = *req.AutoDeploy
 	}
 
+	if req.ImageRetentionCount != nil {
+		project.ImageRetentionCount = sql.NullInt64{Int64: int64(*req.ImageRetentionCount), Valid: *req.ImageRetentionCount > 0}
+	}
+
+	if req.ImageTagStrategy != nil {
+		project.ImageTagStrategy = sql.NullString{String: *req.ImageTagStrategy, Valid: *req.ImageTagStrategy != ""}
+	}
+
+	if req.DefaultInstanceSize != nil {
+		project.DefaultInstanceSize = sql.NullString{String: *req.DefaultInstanceSize, Valid: *req.DefaultInstanceSize != ""}
+	}
+
+	if req.DefaultPort != nil {
+		project.DefaultPort = sql.NullInt64{Int64: int64(*req.DefaultPort), Valid: *req.DefaultPort > 0}
+	}
+
 	// Update project
 	if err := h.Store.UpdateProject(r.Context(), id, project); err != nil {
 		WriteError(w, domain.ErrDatabase.WithError(err))
@@ -394,4 +495,15 @@ func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 	WriteNoContent(w)
 }
 
-
+// validateRegion checks that a requested default region has a cluster to deploy to
+func (h *ProjectHandler) validateRegion(region *string, errs *ValidationErrors) {
+	if region == nil || *region == "" || !h.config.UseK8s {
+		return
+	}
+	for _, configured := range h.config.K8sRegions() {
+		if *region == configured {
+			return
+		}
+	}
+	errs.Add("default_region", "must be one of the configured regions: "+strings.Join(h.config.K8sRegions(), ", "))
+}
@@ -3,10 +3,20 @@ package api
 // CreateProjectRequest represents the request body for creating a project
 type CreateProjectRequest struct {
 	Name              string  `json:"name" validate:"required,min=1,max=255"`
+	Slug              *string `json:"slug,omitempty" validate:"omitempty,max=100"` // Generated from the name when omitted
 	Description       *string `json:"description,omitempty" validate:"omitempty,max=1000"`
 	OpenStackTenantID *string `json:"openstack_tenant_id,omitempty" validate:"omitempty,min=1,max=255"`
 	DefaultRegion     *string `json:"default_region,omitempty" validate:"omitempty,max=100"`
 	AutoDeploy        *bool   `json:"auto_deploy,omitempty"`
+	ImageTagStrategy  *string `json:"image_tag_strategy,omitempty"` // commit (default), git_sha, branch_timestamp or git_tag
+	// Instance size and port of new services that don't set theirs (medium and 8080 by default)
+	DefaultInstanceSize *string `json:"default_instance_size,omitempty"`
+	DefaultPort         *int    `json:"default_port,omitempty" validate:"omitempty,min=1,max=65535"`
+}
+
+// TransferProjectRequest represents the request body for moving a project to another organization
+type TransferProjectRequest struct {
+	TargetOrgID string `json:"target_org_id" validate:"required"`
 }
 
 // UpdateProjectRequest represents the request body for updating a project
@@ -15,5 +25,12 @@ type UpdateProjectRequest struct {
 	Description   *string `json:"description,omitempty" validate:"omitempty,max=1000"`
 	DefaultRegion *string `json:"default_region,omitempty" validate:"omitempty,max=100"`
 	AutoDeploy    *bool   `json:"auto_deploy,omitempty"`
+	// Successful deployment images kept in the registry per service (0 resets to the server default)
+	ImageRetentionCount *int `json:"image_retention_count,omitempty" validate:"omitempty,min=0,max=100"`
+	// How build images are tagged: commit, git_sha, branch_timestamp or git_tag ("" resets to commit)
+	ImageTagStrategy *string `json:"image_tag_strategy,omitempty"`
+	// Instance size and port of new services that don't set theirs ("" and 0 reset to medium and 8080)
+	DefaultInstanceSize *string `json:"default_instance_size,omitempty"`
+	DefaultPort         *int    `json:"default_port,omitempty" validate:"omitempty,min=0,max=65535"`
 }
 
@@ -2,12 +2,14 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/k8s"
 	"github.com/intelifox/click-deploy/internal/store"
 	"github.com/intelifox/click-deploy/internal/testutil"
 )
@@ -22,7 +24,7 @@ func TestProjectHandler_CreateProject(t *testing.T) {
 	mockConfig := &config.Config{
 		UseMockInfra: true,
 	}
-	handler := NewProjectHandler(dbStore, mockConfig)
+	handler := NewProjectHandler(dbStore, mockConfig, nil)
 
 	tests := []struct {
 		name           string
@@ -96,6 +98,47 @@ func TestProjectHandler_CreateProject(t *testing.T) {
 	}
 }
 
+func TestProjectHandler_CreateProject_SlugCollisions(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	handler := NewProjectHandler(&store.DB{DB: db}, &config.Config{UseMockInfra: true}, nil)
+
+	create := func(body CreateProjectRequest) (int, string) {
+		req, _ := testutil.MockRequestJSON(t, "POST", "/v1/click-deploy/projects", body)
+		w := testutil.MockResponseRecorder()
+		handler.CreateProject(w, req)
+
+		var result map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &result)
+		slug, _ := result["slug"].(string)
+		return w.Code, slug
+	}
+
+	// Same name twice: the second project gets a suffix instead of a constraint error
+	for _, want := range []string{"my-app", "my-app-2", "my-app-3"} {
+		code, slug := create(CreateProjectRequest{Name: "My App!"})
+		if code != http.StatusCreated {
+			t.Fatalf("CreateProject() status = %d, want %d", code, http.StatusCreated)
+		}
+		if slug != want {
+			t.Errorf("slug = %q, want %q", slug, want)
+		}
+	}
+
+	// An explicit slug that's taken is a conflict
+	if code, _ := create(CreateProjectRequest{Name: "Other", Slug: stringPtr("my-app")}); code != http.StatusConflict {
+		t.Errorf("CreateProject() with taken slug status = %d, want %d", code, http.StatusConflict)
+	}
+	if code, slug := create(CreateProjectRequest{Name: "Other", Slug: stringPtr("custom")}); code != http.StatusCreated || slug != "custom" {
+		t.Errorf("CreateProject() with free slug = %d %q, want %d %q", code, slug, http.StatusCreated, "custom")
+	}
+	if code, _ := create(CreateProjectRequest{Name: "Other", Slug: stringPtr("Not A Slug")}); code != http.StatusBadRequest {
+		t.Errorf("CreateProject() with invalid slug status = %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
 func TestProjectHandler_GetProject(t *testing.T) {
 	db, cleanup := testutil.SetupTestDB(t)
 	defer cleanup()
@@ -105,7 +148,7 @@ func TestProjectHandler_GetProject(t *testing.T) {
 	mockConfig := &config.Config{
 		UseMockInfra: true,
 	}
-	handler := NewProjectHandler(dbStore, mockConfig)
+	handler := NewProjectHandler(dbStore, mockConfig, nil)
 
 	// Create a test project
 	orgID := "test-org-456"
@@ -173,7 +216,7 @@ func TestProjectHandler_ListProjects(t *testing.T) {
 	mockConfig := &config.Config{
 		UseMockInfra: true,
 	}
-	handler := NewProjectHandler(dbStore, mockConfig)
+	handler := NewProjectHandler(dbStore, mockConfig, nil)
 
 	orgID := "test-org-456"
 	ctx := testutil.MockAuthContext(context.Background(), "test-user-123", orgID)
@@ -201,13 +244,37 @@ func TestProjectHandler_ListProjects(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
 	}
 
-	var projects []interface{}
+	var projects ListResponse[interface{}]
 	if err := json.Unmarshal(w.Body.Bytes(), &projects); err != nil {
 		t.Errorf("Failed to unmarshal response: %v", err)
 	}
 
-	if len(projects) != 3 {
-		t.Errorf("Expected 3 projects, got %d", len(projects))
+	if len(projects.Items) != 3 {
+		t.Errorf("Expected 3 projects, got %d", len(projects.Items))
+	}
+}
+
+func TestProjectK8sClient(t *testing.T) {
+	eu, us := &k8s.Client{}, &k8s.Client{}
+	clients := k8s.NewClientRegistry("eu")
+	clients.Register("eu", eu, nil)
+	clients.Register("us", us, nil)
+
+	inRegion := func(region string) *store.Project {
+		return &store.Project{DefaultRegion: sql.NullString{String: region, Valid: region != ""}}
+	}
+
+	if got := projectK8sClient(clients, inRegion("us")); got != us {
+		t.Errorf("Project in us got the client of another region")
+	}
+	if got := projectK8sClient(clients, inRegion("")); got != eu {
+		t.Errorf("Project without a region didn't get the default region's client")
+	}
+	if got := projectK8sClient(clients, inRegion("ap")); got != nil {
+		t.Errorf("Project in a region without a cluster got a client")
+	}
+	if got := projectK8sClient(nil, inRegion("eu")); got != nil {
+		t.Errorf("Got a client without k8s configured")
 	}
 }
 
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// quotaWarningThreshold is the fraction of a limit at which creates start returning a warning header
+const quotaWarningThreshold = 0.8
+
+// QuotaWarningHeader is set on create responses when the org is close to a plan limit
+const QuotaWarningHeader = "X-Quota-Warning"
+
+// QuotaLimits are the resource limits of a plan. Zero means unlimited.
+type QuotaLimits struct {
+	MaxServices  int `json:"max_services"`
+	MaxMemoryMB  int `json:"max_memory_mb"`
+	MaxStorageMB int `json:"max_storage_mb"`
+}
+
+// planQuotas maps org plans to their limits. Orgs without a (known) plan are unlimited.
+var planQuotas = map[string]QuotaLimits{
+	"free": {MaxServices: 3, MaxMemoryMB: 2048, MaxStorageMB: 5 * 1024},
+	"pro":  {MaxServices: 25, MaxMemoryMB: 32 * 1024, MaxStorageMB: 100 * 1024},
+	"team": {MaxServices: 100, MaxMemoryMB: 128 * 1024, MaxStorageMB: 500 * 1024},
+}
+
+// instanceSizeMemoryMB is the memory counted against the quota for each instance size
+var instanceSizeMemoryMB = map[string]int{
+	"small":  512,
+	"medium": 1024,
+	"large":  2048,
+	"xlarge": 4096,
+}
+
+// QuotaUsage is the amount of quota-limited resources in use (or requested)
+type QuotaUsage struct {
+	Services  int `json:"services"`
+	MemoryMB  int `json:"memory_mb"`
+	StorageMB int `json:"storage_mb"`
+}
+
+// QuotaResponse represents an org's usage against its plan limits
+type QuotaResponse struct {
+	OrgID     string      `json:"org_id"`
+	Plan      string      `json:"plan,omitempty"`
+	Unlimited bool        `json:"unlimited"`
+	Limits    QuotaLimits `json:"limits"`
+	Usage     QuotaUsage  `json:"usage"`
+	Warnings  []string    `json:"warnings,omitempty"`
+}
+
+// GetQuota handles GET /usage/quota
+func (h *UsageHandler) GetQuota(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	plan, limits, err := orgPlanLimits(r.Context(), h.store, orgID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	resources, err := h.store.GetOrgResources(r.Context(), orgID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	usage := quotaUsage(resources)
+
+	_, warnings := checkQuota(limits, usage, QuotaUsage{})
+
+	WriteJSON(w, http.StatusOK, QuotaResponse{
+		OrgID:     orgID,
+		Plan:      plan,
+		Unlimited: limits == QuotaLimits{},
+		Limits:    limits,
+		Usage:     usage,
+		Warnings:  warnings,
+	})
+}
+
+// createWithinQuota runs create in a transaction that first checks adding requested
+// resources keeps the org within its plan, so concurrent creates can't all pass the
+// check. Writes a 402 and returns false when over, or an error response when create
+// fails; sets the warning header when close.
+func createWithinQuota(w http.ResponseWriter, r *http.Request, db *store.DB, orgID string, requested QuotaUsage, create func(tx *store.QuotaTx) error) bool {
+	_, limits, err := orgPlanLimits(r.Context(), db, orgID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return false
+	}
+
+	tx, err := db.BeginQuotaTx(r.Context(), orgID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return false
+	}
+	defer tx.Rollback()
+
+	var warnings []string
+	if limits != (QuotaLimits{}) {
+		resources, err := tx.Resources(r.Context())
+		if err != nil {
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return false
+		}
+
+		var exceeded []string
+		exceeded, warnings = checkQuota(limits, quotaUsage(resources), requested)
+		if len(exceeded) > 0 {
+			WriteError(w, domain.NewQuotaExceededError("Quota exceeded: "+strings.Join(exceeded, "; ")))
+			return false
+		}
+	}
+
+	if err := create(tx); err != nil {
+		if _, ok := domain.IsAppError(err); !ok {
+			err = domain.ErrDatabase.WithError(err)
+		}
+		WriteError(w, err)
+		return false
+	}
+	if err := tx.Commit(); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return false
+	}
+
+	if len(warnings) > 0 {
+		w.Header().Set(QuotaWarningHeader, strings.Join(warnings, "; "))
+	}
+	return true
+}
+
+// checkQuota compares usage plus requested resources with the limits.
+// Returns the limits that would be exceeded and those that would be near their limit.
+func checkQuota(limits QuotaLimits, usage, requested QuotaUsage) (exceeded []string, warnings []string) {
+	check := func(name string, used, req, limit int) {
+		if limit <= 0 {
+			return
+		}
+		total := used + req
+		msg := fmt.Sprintf("%s %d/%d", name, total, limit)
+		switch {
+		case req > 0 && total > limit:
+			exceeded = append(exceeded, msg)
+		case float64(total) >= float64(limit)*quotaWarningThreshold:
+			warnings = append(warnings, msg)
+		}
+	}
+
+	check("services", usage.Services, requested.Services, limits.MaxServices)
+	check("memory_mb", usage.MemoryMB, requested.MemoryMB, limits.MaxMemoryMB)
+	check("storage_mb", usage.StorageMB, requested.StorageMB, limits.MaxStorageMB)
+
+	return exceeded, warnings
+}
+
+// orgPlanLimits returns the plan of an org and its limits.
+// Casdoor orgs (non-UUID IDs) have no organizations row and are unlimited.
+func orgPlanLimits(ctx context.Context, db *store.DB, orgID string) (string, QuotaLimits, error) {
+	if _, err := uuid.Parse(orgID); err != nil {
+		return "", QuotaLimits{}, nil
+	}
+
+	plan, err := db.GetOrgPlan(ctx, orgID)
+	if err != nil {
+		return "", QuotaLimits{}, err
+	}
+
+	return plan, planQuotas[plan], nil
+}
+
+// quotaUsage is the quota usage of an org's resources
+func quotaUsage(resources *store.OrgResources) QuotaUsage {
+	usage := QuotaUsage{Services: resources.Services, StorageMB: resources.StorageMB}
+	for size, count := range resources.AppSizes {
+		usage.MemoryMB += count * instanceSizeMemoryMB[size]
+	}
+	for size, count := range resources.DatabaseSizes {
+		usage.MemoryMB += count * databaseMemoryMB(size)
+	}
+	return usage
+}
+
+// databaseMemoryMB is the memory counted against the quota for a database of a size,
+// the memory limit of its size preset
+func databaseMemoryMB(size string) int {
+	preset, err := k8s.DatabaseSizePreset(size)
+	if err != nil {
+		return 0
+	}
+	return preset.MemoryLimitMB()
+}
+
+// listOrgProjects lists an org's projects, by org_id for custom auth with a fallback to casdoor_org_id
+func listOrgProjects(ctx context.Context, db *store.DB, orgID string) ([]*store.Project, error) {
+	if parsedOrgID, err := uuid.Parse(orgID); err == nil {
+		projects, err := db.ListProjectsByOrgID(ctx, parsedOrgID)
+		if err != nil || len(projects) > 0 {
+			return projects, err
+		}
+	}
+
+	return db.ListProjectsByOrg(ctx, orgID)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+func TestCheckQuota(t *testing.T) {
+	limits := QuotaLimits{MaxServices: 5, MaxMemoryMB: 2048, MaxStorageMB: 1000}
+
+	tests := []struct {
+		name         string
+		limits       QuotaLimits
+		usage        QuotaUsage
+		requested    QuotaUsage
+		wantExceeded int
+		wantWarnings int
+	}{
+		{
+			name:      "well within limits",
+			limits:    limits,
+			usage:     QuotaUsage{Services: 1, MemoryMB: 512, StorageMB: 100},
+			requested: QuotaUsage{Services: 1, MemoryMB: 512},
+		},
+		{
+			name:         "near service limit",
+			limits:       limits,
+			usage:        QuotaUsage{Services: 3},
+			requested:    QuotaUsage{Services: 1},
+			wantWarnings: 1,
+		},
+		{
+			name:         "service limit exceeded",
+			limits:       limits,
+			usage:        QuotaUsage{Services: 5},
+			requested:    QuotaUsage{Services: 1},
+			wantExceeded: 1,
+		},
+		{
+			name:         "storage exceeded and memory near",
+			limits:       limits,
+			usage:        QuotaUsage{MemoryMB: 1024, StorageMB: 900},
+			requested:    QuotaUsage{MemoryMB: 1024, StorageMB: 500},
+			wantExceeded: 1,
+			wantWarnings: 1,
+		},
+		{
+			name:         "already over but not requesting more",
+			limits:       limits,
+			usage:        QuotaUsage{StorageMB: 1200},
+			requested:    QuotaUsage{Services: 1},
+			wantWarnings: 1,
+		},
+		{
+			name:      "unlimited",
+			limits:    QuotaLimits{},
+			usage:     QuotaUsage{Services: 1000, MemoryMB: 1 << 20, StorageMB: 1 << 20},
+			requested: QuotaUsage{Services: 1, MemoryMB: 4096, StorageMB: 4096},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exceeded, warnings := checkQuota(tt.limits, tt.usage, tt.requested)
+			if len(exceeded) != tt.wantExceeded {
+				t.Errorf("exceeded = %v, expected %d entries", exceeded, tt.wantExceeded)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %v, expected %d entries", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestQuotaUsage(t *testing.T) {
+	resources := &store.OrgResources{
+		Services:      4,
+		AppSizes:      map[string]int{"small": 1, "large": 1},
+		DatabaseSizes: map[string]int{"small": 1, "medium": 1},
+		StorageMB:     2048,
+	}
+
+	usage := quotaUsage(resources)
+
+	// Databases count the memory limit of their size preset: 512 MB small, 1 GB medium
+	want := QuotaUsage{Services: 4, MemoryMB: instanceSizeMemoryMB["small"] + instanceSizeMemoryMB["large"] + 512 + 1024, StorageMB: 2048}
+	if usage != want {
+		t.Errorf("quotaUsage = %+v, expected %+v", usage, want)
+	}
+}
@@ -82,6 +82,7 @@ func (h *RealtimeHandler) GetSubscriptionToken(w http.ResponseWriter, r *http.Re
 	// Only allow specific channel prefixes for now.
 	// - deployment:<uuid>
 	// - service:<uuid>
+	// - project:<uuid>
 	if strings.HasPrefix(channel, "deployment:") {
 		idStr := strings.TrimPrefix(channel, "deployment:")
 		deploymentID, err := uuid.Parse(idStr)
@@ -103,8 +104,11 @@ func (h *RealtimeHandler) GetSubscriptionToken(w http.ResponseWriter, r *http.Re
 		}
 
 		project, err := h.store.GetProject(r.Context(), service.ProjectID)
-		if err != nil || project == nil || !project.BelongsToOrg(orgID) {
-			http.Error(w, "Deployment not found", http.StatusNotFound)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !checkOrgAccess(w, project, orgID, "Deployment") {
 			return
 		}
 	} else if strings.HasPrefix(channel, "service:") {
@@ -122,8 +126,26 @@ func (h *RealtimeHandler) GetSubscriptionToken(w http.ResponseWriter, r *http.Re
 		}
 
 		project, err := h.store.GetProject(r.Context(), service.ProjectID)
-		if err != nil || project == nil || !project.BelongsToOrg(orgID) {
-			http.Error(w, "Service not found", http.StatusNotFound)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !checkOrgAccess(w, project, orgID, "Service") {
+			return
+		}
+	} else if strings.HasPrefix(channel, "project:") {
+		projectID, ok := realtime.ParseProjectChannel(channel)
+		if !ok {
+			http.Error(w, "Invalid project ID", http.StatusBadRequest)
+			return
+		}
+
+		project, err := h.store.GetProject(r.Context(), projectID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !checkOrgAccess(w, project, orgID, "Project") {
 			return
 		}
 	} else {
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/metrics"
+)
+
+// Usage window of resource recommendations
+const (
+	defaultRecommendationWindow = 7 * 24 * time.Hour
+	minRecommendationWindow     = time.Hour
+	maxRecommendationWindow     = 30 * 24 * time.Hour
+)
+
+// Autoscaling targets, as a percentage of the requests. Bursty services, whose p99
+// CPU is well above their p95, scale out earlier.
+const (
+	defaultCPUTargetUtilization    = 75
+	burstyCPUTargetUtilization     = 60
+	burstyCPURatio                 = 1.5
+	defaultMemoryTargetUtilization = 80
+)
+
+// ResourceUsage are the observed usage percentiles of a service's pod
+type ResourceUsage struct {
+	CPUP95Cores    float64 `json:"cpu_p95_cores"`
+	CPUP99Cores    float64 `json:"cpu_p99_cores"`
+	MemoryP95Bytes float64 `json:"memory_p95_bytes"`
+	MemoryP99Bytes float64 `json:"memory_p99_bytes"`
+}
+
+// InstanceSizeResources is an instance size with its resources
+type InstanceSizeResources struct {
+	InstanceSize  string `json:"instance_size"`
+	CPURequest    string `json:"cpu_request"`
+	CPULimit      string `json:"cpu_limit"`
+	MemoryRequest string `json:"memory_request"`
+	MemoryLimit   string `json:"memory_limit"`
+}
+
+// AutoscalingRecommendation are the recommended autoscaler target utilizations
+type AutoscalingRecommendation struct {
+	TargetCPUUtilization    int `json:"target_cpu_utilization"`
+	TargetMemoryUtilization int `json:"target_memory_utilization"`
+}
+
+// RecommendationResponse compares a service's current resources with the ones its
+// observed usage calls for. Usage, recommended and autoscaling are only set when
+// there is usage data for the window.
+type RecommendationResponse struct {
+	ServiceID   string                     `json:"service_id"`
+	Window      string                     `json:"window"`
+	Available   bool                       `json:"available"`
+	Current     InstanceSizeResources      `json:"current"`
+	Usage       *ResourceUsage             `json:"usage,omitempty"`
+	Recommended *InstanceSizeResources     `json:"recommended,omitempty"`
+	Autoscaling *AutoscalingRecommendation `json:"autoscaling,omitempty"`
+}
+
+// GetServiceRecommendations handles GET /services/:id/recommendations
+// Recommends an instance size from the service's p95 CPU and p99 memory usage over
+// ?window= (e.g. "24h" or "14d", default 7d, at most 30d), along with autoscaling
+// targets.
+func (h *MetricsHandler) GetServiceRecommendations(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+
+	serviceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid service ID"))
+		return
+	}
+
+	service, err := h.store.GetService(r.Context(), serviceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if service == nil {
+		WriteError(w, domain.NewNotFoundError("Service"))
+		return
+	}
+
+	project, err := h.store.GetProject(r.Context(), service.ProjectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if !checkOrgAccess(w, project, orgID, "Service") {
+		return
+	}
+
+	window := defaultRecommendationWindow
+	if windowStr := r.URL.Query().Get("window"); windowStr != "" {
+		window, err = parseRecommendationWindow(windowStr)
+		if err != nil {
+			WriteError(w, domain.NewValidationError(err.Error()))
+			return
+		}
+	}
+
+	current, err := instanceSizeResources(service.InstanceSize)
+	if err != nil {
+		WriteError(w, domain.ErrInternal.WithError(err))
+		return
+	}
+
+	response := RecommendationResponse{
+		ServiceID: serviceID.String(),
+		Window:    formatRecommendationWindow(window),
+		Current:   current,
+	}
+
+	usage, err := h.queryResourceUsage(r.Context(), orgID, h.config.K8sNamespacePrefix+project.ID.String(), serviceID.String(), window, time.Now())
+	if err != nil {
+		WriteError(w, domain.NewAppError(domain.ErrCodeExternalAPI, "Failed to query usage: "+err.Error(), http.StatusBadGateway))
+		return
+	}
+	if usage == nil {
+		WriteJSON(w, http.StatusOK, response)
+		return
+	}
+
+	// Memory isn't compressible: size it for the higher percentile
+	recommended, err := instanceSizeResources(k8s.RecommendInstanceSize(usage.CPUP95Cores, usage.MemoryP99Bytes))
+	if err != nil {
+		WriteError(w, domain.ErrInternal.WithError(err))
+		return
+	}
+
+	autoscaling := &AutoscalingRecommendation{
+		TargetCPUUtilization:    defaultCPUTargetUtilization,
+		TargetMemoryUtilization: defaultMemoryTargetUtilization,
+	}
+	if usage.CPUP95Cores > 0 && usage.CPUP99Cores/usage.CPUP95Cores > burstyCPURatio {
+		autoscaling.TargetCPUUtilization = burstyCPUTargetUtilization
+	}
+
+	response.Available = true
+	response.Usage = usage
+	response.Recommended = &recommended
+	response.Autoscaling = autoscaling
+
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// queryResourceUsage returns the usage percentiles of a service's pods over the window
+// ending at end, nil when Prometheus has no data for it. Pods are mapped to the service
+// via kube_pod_labels, and only count while their namespace is labeled with the org.
+func (h *MetricsHandler) queryResourceUsage(ctx context.Context, orgID, namespace, serviceID string, window time.Duration, end time.Time) (*ResourceUsage, error) {
+	selector := fmt.Sprintf(`namespace="%s"`, namespace)
+	podLabels := metrics.ScopeToOrg(fmt.Sprintf(`max by (namespace, pod) (kube_pod_labels{%s,%s="%s"})`, selector, serviceIDLabel, serviceID), orgID)
+
+	// Per pod usage, the largest pod of the service at each step
+	cpu := fmt.Sprintf(
+		`max(sum by (namespace, pod) (rate(container_cpu_usage_seconds_total{%s,container!=""}[5m])) * on (namespace, pod) %s)`,
+		selector, podLabels,
+	)
+	memory := fmt.Sprintf(
+		`max(sum by (namespace, pod) (container_memory_working_set_bytes{%s,container!=""}) * on (namespace, pod) %s)`,
+		selector, podLabels,
+	)
+
+	var usage ResourceUsage
+	for _, q := range []struct {
+		expr     string
+		quantile float64
+		dest     *float64
+	}{
+		{cpu, 0.95, &usage.CPUP95Cores},
+		{cpu, 0.99, &usage.CPUP99Cores},
+		{memory, 0.95, &usage.MemoryP95Bytes},
+		{memory, 0.99, &usage.MemoryP99Bytes},
+	} {
+		query := fmt.Sprintf(`quantile_over_time(%g, (%s)[%ds:5m])`, q.quantile, q.expr, int64(window.Seconds()))
+		samples, err := h.prometheus.QueryOrg(ctx, orgID, query, end)
+		if err != nil {
+			return nil, err
+		}
+		if len(samples) == 0 {
+			return nil, nil
+		}
+		*q.dest = samples[0].Value
+	}
+
+	return &usage, nil
+}
+
+// instanceSizeResources returns an instance size along with its resources
+func instanceSizeResources(size string) (InstanceSizeResources, error) {
+	preset, err := k8s.InstanceSizePreset(size)
+	if err != nil {
+		return InstanceSizeResources{}, err
+	}
+	if size == "" {
+		size = "medium"
+	}
+	return InstanceSizeResources{
+		InstanceSize:  size,
+		CPURequest:    preset.CPURequest,
+		CPULimit:      preset.CPULimit,
+		MemoryRequest: preset.MemoryRequest,
+		MemoryLimit:   preset.MemoryLimit,
+	}, nil
+}
+
+// parseRecommendationWindow parses a window given in days ("7d") or as a Go duration ("36h")
+func parseRecommendationWindow(s string) (time.Duration, error) {
+	var window time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("window must be a number of days (e.g. 7d) or a duration (e.g. 24h)")
+		}
+		window = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		window, err = time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("window must be a number of days (e.g. 7d) or a duration (e.g. 24h)")
+		}
+	}
+
+	if window < minRecommendationWindow || window > maxRecommendationWindow {
+		return 0, fmt.Errorf("window must be between 1h and 30d")
+	}
+	return window, nil
+}
+
+// formatRecommendationWindow formats a window the way parseRecommendationWindow reads it
+func formatRecommendationWindow(window time.Duration) string {
+	if window%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", window/(24*time.Hour))
+	}
+	return window.String()
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -11,7 +12,9 @@ import (
 
 	"github.com/intelifox/click-deploy/internal/auth"
 	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/domain"
 	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/tracing"
 )
 
 type RollbackHandler struct {
@@ -72,8 +75,7 @@ func (h *RollbackHandler) RollbackDeployment(w http.ResponseWriter, r *http.Requ
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Service not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
@@ -105,7 +107,52 @@ func (h *RollbackHandler) RollbackDeployment(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Create a new deployment record for the rollback
+	// Rolling back to a rollback would chain rollbacks; roll back to the deployment it
+	// restored instead
+	if targetDeployment.TriggeredBy == store.DeploymentTriggerRollback {
+		message := "Can't roll back to a rollback deployment"
+		if targetDeployment.RollbackTargetID.Valid {
+			message += ", roll back to the deployment it restored (" + targetDeployment.RollbackTargetID.String + ")"
+		}
+		http.Error(w, message, http.StatusBadRequest)
+		return
+	}
+
+	// The rollback would race the deployment in flight for the service's pods
+	deploying, err := h.store.HasActiveDeployment(r.Context(), serviceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if deploying {
+		WriteError(w, domain.NewDeployInProgressError("A deployment of this service is in progress, wait for it to finish or cancel it before rolling back"))
+		return
+	}
+
+	// The newest successful deployment is the live one, the rollback replaces it
+	var rolledBackFromID sql.NullString
+	live, err := h.store.GetSuccessfulDeploymentsByService(r.Context(), serviceID, 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(live) > 0 {
+		current, err := h.store.GetDeployment(r.Context(), live[0].ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if current != nil {
+			if current.ID == targetDeployment.ID || current.RollbackTargetID.String == targetDeployment.ID.String() {
+				http.Error(w, "The service is already running this deployment", http.StatusBadRequest)
+				return
+			}
+			rolledBackFromID = sql.NullString{String: current.ID.String(), Valid: true}
+		}
+	}
+
+	// Create a new deployment record for the rollback. Rollbacks aren't gated by
+	// approval: they redeploy an image that has already been deployed.
 	rollbackDeployment := &store.Deployment{
 		ServiceID:     serviceID,
 		CommitSHA:     targetDeployment.CommitSHA,
@@ -113,8 +160,12 @@ func (h *RollbackHandler) RollbackDeployment(w http.ResponseWriter, r *http.Requ
 		CommitAuthor:  sql.NullString{String: "System", Valid: true},
 		Status:        "queued",
 		ImageTag:      targetDeployment.ImageTag,
-		TriggeredBy:   "rollback",
+		TriggeredBy:   store.DeploymentTriggerRollback,
+		RequestedBy:   store.StringToNullString(auth.GetUserID(r.Context())),
 		StartedAt:     sql.NullTime{Time: time.Now(), Valid: true},
+
+		RolledBackFromID: rolledBackFromID,
+		RollbackTargetID: sql.NullString{String: targetDeployment.ID.String(), Valid: true},
 	}
 
 	if err := h.store.CreateDeployment(r.Context(), rollbackDeployment); err != nil {
@@ -134,6 +185,7 @@ func (h *RollbackHandler) RollbackDeployment(w http.ResponseWriter, r *http.Requ
 		Attempts:   0,
 		MaxAttempts: 3,
 	}
+	tracing.InjectJobPayload(r.Context(), job.Payload)
 
 	if err := h.store.CreateJob(r.Context(), job); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -145,7 +197,8 @@ func (h *RollbackHandler) RollbackDeployment(w http.ResponseWriter, r *http.Requ
 	json.NewEncoder(w).Encode(rollbackDeployment)
 }
 
-// GetRollbackCandidates returns successful deployments that can be rolled back to
+// GetRollbackCandidates returns successful deployments that can be rolled back to.
+// Rollbacks aren't candidates, the deployments they restored are.
 func (h *RollbackHandler) GetRollbackCandidates(w http.ResponseWriter, r *http.Request) {
 	orgID := auth.GetOrgID(r.Context())
 	if orgID == "" {
@@ -176,8 +229,7 @@ func (h *RollbackHandler) GetRollbackCandidates(w http.ResponseWriter, r *http.R
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Service not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
@@ -187,8 +239,38 @@ func (h *RollbackHandler) GetRollbackCandidates(w http.ResponseWriter, r *http.R
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	candidates = slices.DeleteFunc(candidates, func(d *store.Deployment) bool {
+		return d.TriggeredBy == store.DeploymentTriggerRollback
+	})
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(candidates)
+	json.NewEncoder(w).Encode(newListResponse(candidates))
+}
+
+// RollbackLineageEntry is a deployment of a rollback lineage
+type RollbackLineageEntry struct {
+	DeploymentID     string `json:"deployment_id"`
+	Status           string `json:"status"`
+	TriggeredBy      string `json:"triggered_by"`
+	ImageTag         string `json:"image_tag,omitempty"`
+	RolledBackFromID string `json:"rolled_back_from_id,omitempty"`
+	RollbackTargetID string `json:"rollback_target_id,omitempty"` // The deployment whose image a rollback redeployed
+	CreatedAt        string `json:"created_at"`
 }
 
+// toRollbackLineage converts the deployments of a rollback lineage
+func toRollbackLineage(deployments []*store.Deployment) []RollbackLineageEntry {
+	lineage := make([]RollbackLineageEntry, len(deployments))
+	for i, d := range deployments {
+		lineage[i] = RollbackLineageEntry{
+			DeploymentID:     d.ID.String(),
+			Status:           d.Status,
+			TriggeredBy:      d.TriggeredBy,
+			ImageTag:         d.ImageTag.String,
+			RolledBackFromID: d.RolledBackFromID.String,
+			RollbackTargetID: d.RollbackTargetID.String,
+			CreatedAt:        d.CreatedAt.Format(time.RFC3339),
+		}
+	}
+	return lineage
+}
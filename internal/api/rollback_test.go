@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestRollbackHandler_RollbackDeployment(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	handler := NewRollbackHandler(dbStore, &config.Config{})
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	service := testutil.NewService(t, db, project.ID)
+
+	deploy := func(triggeredBy string) *store.Deployment {
+		t.Helper()
+		d := &store.Deployment{
+			ServiceID:   service.ID,
+			Status:      "success",
+			TriggeredBy: triggeredBy,
+			ImageTag:    store.StringToNullString("registry/app:" + triggeredBy),
+		}
+		if err := dbStore.CreateDeployment(ctx, d); err != nil {
+			t.Fatalf("Failed to create deployment: %v", err)
+		}
+		return d
+	}
+	rollback := func(targetID string) *httptest.ResponseRecorder {
+		req, _ := testutil.MockRequestWithURLParamAndAuth(t, "POST", "/v1/click-deploy/services/"+service.ID.String()+"/rollback/"+targetID,
+			map[string]string{"id": service.ID.String(), "deploymentId": targetID}, nil, "test-user", project.OrgID)
+		w := testutil.MockResponseRecorder()
+		handler.RollbackDeployment(w, req)
+		return w
+	}
+
+	target := deploy("manual")
+	live := deploy("webhook")
+	// The newest successful deployment is the live one
+	if _, err := db.ExecContext(ctx, "UPDATE deployments SET created_at = datetime('now', '-1 hour') WHERE id = $1", target.ID.String()); err != nil {
+		t.Fatalf("Failed to age deployment: %v", err)
+	}
+
+	if w := rollback(live.ID.String()); w.Code != http.StatusBadRequest {
+		t.Errorf("Rolling back to the live deployment: expected status 400, got %d", w.Code)
+	}
+
+	if w := rollback(target.ID.String()); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Response: %s", w.Code, w.Body.String())
+	}
+	rollbacks, err := dbStore.ListDeploymentsByService(ctx, service.ID, store.ListParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to list deployments: %v", err)
+	}
+	var created *store.Deployment
+	for _, d := range rollbacks {
+		if d.TriggeredBy == store.DeploymentTriggerRollback {
+			created, _ = dbStore.GetDeployment(ctx, d.ID)
+		}
+	}
+	if created == nil {
+		t.Fatal("Expected a rollback deployment")
+	}
+	if created.RolledBackFromID.String != live.ID.String() || created.RollbackTargetID.String != target.ID.String() {
+		t.Errorf("Expected the rollback from %s to %s, got from %q to %q", live.ID, target.ID, created.RolledBackFromID.String, created.RollbackTargetID.String)
+	}
+
+	// A rollback can't be rolled back to
+	if err := dbStore.UpdateDeploymentStatus(ctx, created.ID, "success"); err != nil {
+		t.Fatalf("Failed to update deployment status: %v", err)
+	}
+	if w := rollback(created.ID.String()); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), target.ID.String()) {
+		t.Errorf("Rolling back to a rollback: expected status 400 pointing at its target, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"unicode"
 )
 
 // SanitizeString sanitizes a string input
@@ -22,6 +23,43 @@ func SanitizeString(s string) string {
 	return s
 }
 
+// SanitizeName sanitizes a single-line name or identifier (project, service,
+// volume names, engines, regions, ...). Control characters, including newlines
+// and tabs, are removed.
+func SanitizeName(s string) string {
+	s = stripControlChars(SanitizeString(s), "")
+	return strings.TrimSpace(s)
+}
+
+// SanitizeText sanitizes free-form text such as descriptions. Newlines and tabs
+// are kept; other control characters are removed.
+func SanitizeText(s string) string {
+	s = strings.ReplaceAll(SanitizeString(s), "\r\n", "\n")
+	s = stripControlChars(s, "\n\t")
+	return strings.TrimSpace(s)
+}
+
+// sanitizeOptional applies sanitize to an optional request field
+func sanitizeOptional(s *string, sanitize func(string) string) {
+	if s != nil {
+		*s = sanitize(*s)
+	}
+}
+
+// stripControlChars removes Unicode control and format characters (e.g. zero-width
+// spaces and bidi overrides), except those listed in keep
+func stripControlChars(s, keep string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(keep, r) {
+			return r
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
 // SanitizeURL sanitizes and validates a URL
 func SanitizeURL(u string) (string, error) {
 	u = strings.TrimSpace(u)
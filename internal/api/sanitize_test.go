@@ -392,3 +392,58 @@ func TestSanitizeCommitSHA(t *testing.T) {
 	}
 }
 
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "plain", input: "my-service", want: "my-service"},
+		{name: "surrounding whitespace", input: " \t my-service \n", want: "my-service"},
+		{name: "embedded newline", input: "my\nservice", want: "myservice"},
+		{name: "carriage return and tab", input: "my\r\tservice", want: "myservice"},
+		{name: "null byte", input: "my\x00service", want: "myservice"},
+		{name: "escape sequence", input: "\x1b[31mred\x1b[0m", want: "[31mred[0m"},
+		{name: "delete char", input: "abc\x7f", want: "abc"},
+		{name: "zero-width space", input: "my\u200bservice", want: "myservice"},
+		{name: "bidi override", input: "\u202egnp.exe", want: "gnp.exe"},
+		{name: "byte order mark", input: "\ufeffapi", want: "api"},
+		{name: "whitespace after stripping", input: "\x00 api \x00", want: "api"},
+		{name: "only control chars", input: "\x01\x02\x03", want: ""},
+		{name: "unicode letters kept", input: "café-東京", want: "café-東京"},
+		{name: "inner spaces kept", input: "My Project", want: "My Project"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeName(tt.input); got != tt.want {
+				t.Errorf("SanitizeName(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "multi-line kept", input: "line one\nline two", want: "line one\nline two"},
+		{name: "CRLF normalized", input: "line one\r\nline two", want: "line one\nline two"},
+		{name: "tab kept", input: "key:\tvalue", want: "key:\tvalue"},
+		{name: "null byte", input: "desc\x00ription", want: "description"},
+		{name: "escape sequence", input: "\x1b[2Jdesc", want: "[2Jdesc"},
+		{name: "zero-width joiner", input: "a\u200db", want: "ab"},
+		{name: "trimmed", input: "\n\n desc \n", want: "desc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeText(tt.input); got != tt.want {
+				t.Errorf("SanitizeText(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
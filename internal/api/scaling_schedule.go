@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/cron"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// maxScheduledReplicas caps the replica count of a scaling schedule entry
+const maxScheduledReplicas = 20
+
+// ScalingScheduleEntryRequest is one entry of a service's scaling schedule
+type ScalingScheduleEntryRequest struct {
+	Cron     string `json:"cron"`               // e.g. "0 8 * * mon-fri"
+	Replicas int    `json:"replicas"`           // Replica count to scale to when cron fires
+	Timezone string `json:"timezone,omitempty"` // IANA time zone cron is evaluated in, defaults to UTC
+}
+
+// ScalingScheduleRequest replaces a service's scaling schedule
+type ScalingScheduleRequest struct {
+	Entries []ScalingScheduleEntryRequest `json:"entries"`
+}
+
+// ScalingScheduleEntryResponse is one entry of a service's scaling schedule
+type ScalingScheduleEntryResponse struct {
+	ID       string `json:"id"`
+	Cron     string `json:"cron"`
+	Replicas int    `json:"replicas"`
+	Timezone string `json:"timezone"`
+	NextRun  string `json:"next_run,omitempty"`
+}
+
+// ScalingScheduleResponse is a service's scaling schedule
+type ScalingScheduleResponse struct {
+	Entries []ScalingScheduleEntryResponse `json:"entries"`
+}
+
+// GetScalingSchedule handles GET /services/:id/scaling-schedule
+func (h *ServiceHandler) GetScalingSchedule(w http.ResponseWriter, r *http.Request) {
+	service, _ := h.getOwnedService(w, r)
+	if service == nil {
+		return
+	}
+
+	entries, err := h.Store.ListScalingSchedule(r.Context(), service.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, newScalingScheduleResponse(entries, time.Now()))
+}
+
+// ReplaceScalingSchedule handles PUT /services/:id/scaling-schedule
+// Replaces the whole schedule; an empty list of entries removes it. Entries
+// scale the service at the times their cron expression fires, e.g. to 5
+// replicas at "0 8 * * mon-fri" and back to 1 at "0 20 * * mon-fri".
+func (h *ServiceHandler) ReplaceScalingSchedule(w http.ResponseWriter, r *http.Request) {
+	service, _ := h.getOwnedService(w, r)
+	if service == nil {
+		return
+	}
+
+	var req ScalingScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body: "+err.Error()))
+		return
+	}
+
+	validationErrs := &ValidationErrors{}
+	entries := make([]*store.ScalingScheduleEntry, 0, len(req.Entries))
+	for i, e := range req.Entries {
+		field := fmt.Sprintf("entries[%d]", i)
+		if e.Timezone == "" {
+			e.Timezone = "UTC"
+		}
+		if _, err := cron.Parse(e.Cron); err != nil {
+			validationErrs.Add(field+".cron", err.Error())
+		}
+		if _, err := time.LoadLocation(e.Timezone); err != nil {
+			validationErrs.Add(field+".timezone", "must be an IANA time zone, e.g. Europe/Paris")
+		}
+		if e.Replicas < 0 || e.Replicas > maxScheduledReplicas {
+			validationErrs.Add(field+".replicas", fmt.Sprintf("must be between 0 and %d", maxScheduledReplicas))
+		}
+		entries = append(entries, &store.ScalingScheduleEntry{
+			CronExpression: e.Cron,
+			Replicas:       e.Replicas,
+			Timezone:       e.Timezone,
+		})
+	}
+	if validationErrs.HasErrors() {
+		WriteError(w, validationErrs.ToAppError())
+		return
+	}
+
+	if err := h.Store.ReplaceScalingSchedule(r.Context(), service.ID, entries); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, newScalingScheduleResponse(entries, time.Now()))
+}
+
+func newScalingScheduleResponse(entries []*store.ScalingScheduleEntry, now time.Time) ScalingScheduleResponse {
+	resp := ScalingScheduleResponse{Entries: make([]ScalingScheduleEntryResponse, 0, len(entries))}
+	for _, e := range entries {
+		entry := ScalingScheduleEntryResponse{
+			ID:       e.ID.String(),
+			Cron:     e.CronExpression,
+			Replicas: e.Replicas,
+			Timezone: e.Timezone,
+		}
+		schedule, err := cron.Parse(e.CronExpression)
+		loc, locErr := time.LoadLocation(e.Timezone)
+		if err == nil && locErr == nil {
+			if next := schedule.Next(now.In(loc)); !next.IsZero() {
+				entry.NextRun = next.Format(time.RFC3339)
+			}
+		}
+		resp.Entries = append(resp.Entries, entry)
+	}
+	return resp
+}
+
+// getOwnedService loads the service from the {id} URL param and its project, and
+// checks the project belongs to the caller's org. It writes the error response and
+// returns nil when it doesn't.
+func (h *ServiceHandler) getOwnedService(w http.ResponseWriter, r *http.Request) (*store.Service, *store.Project) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid service ID"))
+		return nil, nil
+	}
+
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return nil, nil
+	}
+
+	service, err := h.Store.GetService(r.Context(), id)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil, nil
+	}
+	if service == nil {
+		WriteError(w, domain.NewNotFoundError("Service"))
+		return nil, nil
+	}
+
+	project, err := h.Store.GetProject(r.Context(), service.ProjectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return nil, nil
+	}
+	if !checkOrgAccess(w, project, orgID, "Service") {
+		return nil, nil
+	}
+
+	return service, project
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/k8s"
+)
+
+// ListServiceEvents handles GET /services/:id/events
+// Returns the Kubernetes events of the service's pods, oldest first, to explain
+// deploys that don't become ready. Pass ?type=Warning to only get warnings.
+func (h *ServiceHandler) ListServiceEvents(w http.ResponseWriter, r *http.Request) {
+	service, project := h.getOwnedService(w, r)
+	if service == nil {
+		return
+	}
+
+	client := projectK8sClient(h.k8sClients, project)
+	if client == nil {
+		WriteError(w, domain.NewServiceUnavailableError("Kubernetes is not configured"))
+		return
+	}
+
+	events, err := client.GetPodEvents(r.Context(), project.ID.String(), service.ID.String())
+	if err != nil {
+		WriteError(w, domain.NewAppError(domain.ErrCodeExternalAPI, "Failed to get pod events: "+err.Error(), http.StatusBadGateway))
+		return
+	}
+
+	eventType := r.URL.Query().Get("type")
+	response := make([]k8s.PodEvent, 0, len(events))
+	for _, e := range events {
+		if eventType == "" || e.Type == eventType {
+			response = append(response, e)
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, newListResponse(response))
+}
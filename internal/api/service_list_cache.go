@@ -0,0 +1,55 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// serviceListCache keeps the services of each project as ListServices last built them,
+// with the project's cache version at the time. Any change to the project's services,
+// git sources or deployments, deploys by the workers included, moves the version on
+// (see store.ProjectCacheVersion), so the canvas never polls a stale list.
+type serviceListCache struct {
+	mu      sync.Mutex
+	entries map[uuid.UUID]serviceListEntry
+}
+
+type serviceListEntry struct {
+	version  uint64
+	services []ServiceResponse
+}
+
+func newServiceListCache() *serviceListCache {
+	return &serviceListCache{entries: make(map[uuid.UUID]serviceListEntry)}
+}
+
+// get returns the services of a project cached at version
+func (c *serviceListCache) get(projectID uuid.UUID, version uint64) ([]ServiceResponse, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[projectID]
+	if !ok || entry.version != version {
+		return nil, false
+	}
+	return entry.services, true
+}
+
+// put caches the services of a project built at version, unless a newer version was
+// cached meanwhile
+func (c *serviceListCache) put(projectID uuid.UUID, version uint64, services []ServiceResponse) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[projectID]; ok && entry.version > version {
+		return
+	}
+	c.entries[projectID] = serviceListEntry{version: version, services: services}
+}
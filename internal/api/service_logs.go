@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// Service log line limits
+const (
+	defaultServiceLogTail = 500
+	maxServiceLogTail     = 5000
+)
+
+// GetServiceLogs handles GET /services/:id/logs
+// Returns the app logs of the service's newest pod as plain text, one timestamped
+// line each. ?since= takes an RFC 3339 time, or "deploy" for the logs since the
+// current deployment went live. Without since, only the last ?tail= lines are
+// returned (default 500, at most 5000). ?follow=true keeps streaming new lines.
+func (h *ServiceHandler) GetServiceLogs(w http.ResponseWriter, r *http.Request) {
+	service, project := h.getOwnedService(w, r)
+	if service == nil {
+		return
+	}
+
+	client := projectK8sClient(h.k8sClients, project)
+	if client == nil {
+		WriteError(w, domain.NewServiceUnavailableError("Kubernetes is not configured"))
+		return
+	}
+
+	var opts k8s.PodLogOptions
+	validationErrs := &ValidationErrors{}
+
+	switch since := r.URL.Query().Get("since"); since {
+	case "":
+	case "deploy":
+		liveAt, err := deploymentLiveAt(r.Context(), h.Store, service)
+		if err != nil {
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return
+		}
+		if liveAt == nil {
+			WriteError(w, domain.NewNotFoundError("Live deployment"))
+			return
+		}
+		opts.SinceTime = liveAt
+	default:
+		sinceTime, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			validationErrs.Add("since", `must be "deploy" or an RFC 3339 time`)
+		}
+		opts.SinceTime = &sinceTime
+	}
+
+	if tailStr := r.URL.Query().Get("tail"); tailStr != "" {
+		tail, err := strconv.Atoi(tailStr)
+		if err != nil || tail < 1 || tail > maxServiceLogTail {
+			validationErrs.Add("tail", "must be between 1 and "+strconv.Itoa(maxServiceLogTail))
+		}
+		tailLines := int64(tail)
+		opts.TailLines = &tailLines
+	} else if opts.SinceTime == nil {
+		tailLines := int64(defaultServiceLogTail)
+		opts.TailLines = &tailLines
+	}
+
+	opts.Follow = r.URL.Query().Get("follow") == "true"
+
+	if validationErrs.HasErrors() {
+		WriteError(w, validationErrs.ToAppError())
+		return
+	}
+
+	stream, err := client.GetPodLogs(r.Context(), project.ID.String(), service.ID.String(), opts)
+	if err != nil {
+		WriteError(w, domain.NewAppError(domain.ErrCodeExternalAPI, "Failed to get logs: "+err.Error(), http.StatusBadGateway))
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if !opts.Follow {
+		io.Copy(w, stream)
+		return
+	}
+
+	// Send each chunk as it arrives, until the client goes away
+	controller := http.NewResponseController(w)
+	buf := make([]byte, 4096)
+	for {
+		n, err := stream.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return
+			}
+			controller.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// deploymentLiveAt returns when the service's current deployment went live, nil
+// when it has no successful deployment
+func deploymentLiveAt(ctx context.Context, db *store.DB, service *store.Service) (*time.Time, error) {
+	deployments, err := db.GetSuccessfulDeploymentsByService(ctx, service.ID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(deployments) == 0 || !deployments[0].FinishedAt.Valid {
+		return nil, nil
+	}
+	return &deployments[0].FinishedAt.Time, nil
+}
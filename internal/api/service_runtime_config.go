@@ -0,0 +1,86 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/k8s"
+)
+
+// ServiceRuntimeConfigResponse is what a service runs according to the cluster
+type ServiceRuntimeConfigResponse struct {
+	Image           string                   `json:"image"`
+	Command         []string                 `json:"command,omitempty"`
+	Replicas        int32                    `json:"replicas"`
+	ReadyReplicas   int32                    `json:"ready_replicas"`
+	UpdatedReplicas int32                    `json:"updated_replicas"`
+	Resources       RuntimeResourcesResponse `json:"resources"`
+	EnvVars         []RuntimeEnvVarResponse  `json:"env_vars"`
+	// Secrets and config maps the env should come from but that don't exist
+	MissingEnvSources []string `json:"missing_env_sources,omitempty"`
+}
+
+// RuntimeResourcesResponse holds the container's requests and limits as Kubernetes quantities
+type RuntimeResourcesResponse struct {
+	CPURequest    string `json:"cpu_request,omitempty"`
+	CPULimit      string `json:"cpu_limit,omitempty"`
+	MemoryRequest string `json:"memory_request,omitempty"`
+	MemoryLimit   string `json:"memory_limit,omitempty"`
+}
+
+// RuntimeEnvVarResponse is an env var of the running container. Its value is always masked.
+type RuntimeEnvVarResponse struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`         // literal, secret, configmap or field
+	From   string `json:"from,omitempty"` // Secret or config map the value comes from
+}
+
+// GetServiceRuntimeConfig handles GET /services/:id/runtime-config
+// Returns the image, replicas, resources and env var keys the service's live
+// Deployment runs with, read from the cluster rather than the database, to find
+// where the two diverged.
+func (h *ServiceHandler) GetServiceRuntimeConfig(w http.ResponseWriter, r *http.Request) {
+	service, project := h.getOwnedService(w, r)
+	if service == nil {
+		return
+	}
+
+	client := projectK8sClient(h.k8sClients, project)
+	if client == nil {
+		WriteError(w, domain.NewServiceUnavailableError("Kubernetes is not configured"))
+		return
+	}
+
+	config, err := client.GetRuntimeConfig(r.Context(), project.ID.String(), service.ID.String())
+	if errors.Is(err, k8s.ErrDeploymentNotFound) {
+		WriteError(w, domain.NewNotFoundError("Running deployment"))
+		return
+	}
+	if err != nil {
+		WriteError(w, domain.NewAppError(domain.ErrCodeExternalAPI, "Failed to read runtime config: "+err.Error(), http.StatusBadGateway))
+		return
+	}
+
+	envVars := make([]RuntimeEnvVarResponse, len(config.EnvVars))
+	for i, v := range config.EnvVars {
+		envVars[i] = RuntimeEnvVarResponse{Key: v.Key, Value: "***", Source: v.Source, From: v.From}
+	}
+
+	WriteJSON(w, http.StatusOK, ServiceRuntimeConfigResponse{
+		Image:           config.Image,
+		Command:         config.Command,
+		Replicas:        config.Replicas,
+		ReadyReplicas:   config.ReadyReplicas,
+		UpdatedReplicas: config.UpdatedReplicas,
+		Resources: RuntimeResourcesResponse{
+			CPURequest:    config.CPURequest,
+			CPULimit:      config.CPULimit,
+			MemoryRequest: config.MemoryRequest,
+			MemoryLimit:   config.MemoryLimit,
+		},
+		EnvVars:           envVars,
+		MissingEnvSources: config.MissingEnvSources,
+	})
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/intelifox/click-deploy/internal/caddy"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/worker"
+)
+
+// UpdateServiceSubdomainRequest represents the request body for changing a service's
+// generated subdomain
+type UpdateServiceSubdomainRequest struct {
+	// Label under the base domain; a new one is generated when omitted
+	Subdomain *string `json:"subdomain,omitempty"`
+}
+
+// ServiceSubdomainResponse is a service's generated subdomain and the URL it serves at
+type ServiceSubdomainResponse struct {
+	Subdomain         string `json:"subdomain"`
+	GeneratedURL      string `json:"generated_url"`
+	PreviousSubdomain string `json:"previous_subdomain,omitempty"`
+}
+
+// UpdateServiceSubdomain handles POST /services/:id/subdomain
+// Sets the service's generated subdomain to the one requested, or a newly generated
+// one, and moves its ingress host over so the previous subdomain stops routing.
+func (h *ServiceHandler) UpdateServiceSubdomain(w http.ResponseWriter, r *http.Request) {
+	service, project := h.getOwnedService(w, r)
+	if service == nil {
+		return
+	}
+
+	client := projectK8sClient(h.k8sClients, project)
+	if client == nil {
+		WriteError(w, domain.NewServiceUnavailableError("Kubernetes is not configured"))
+		return
+	}
+
+	// The body is optional
+	var req UpdateServiceSubdomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	previous := service.Subdomain
+	previousURL := service.GeneratedURL
+	updatedBy := requestUser(r).String
+
+	var subdomain string
+	if req.Subdomain != nil {
+		subdomain = strings.ToLower(strings.TrimSpace(*req.Subdomain))
+		if err := client.ValidateSubdomain(subdomain); err != nil {
+			WriteError(w, domain.NewValidationError(err.Error()))
+			return
+		}
+		if previous.Valid && previous.String == subdomain {
+			WriteJSON(w, http.StatusOK, ServiceSubdomainResponse{Subdomain: subdomain, GeneratedURL: previousURL.String})
+			return
+		}
+
+		// An explicit subdomain is used as-is, so a collision is the caller's to resolve
+		err := h.Store.SetServiceSubdomain(r.Context(), service.ID, subdomain, client.SubdomainURL(subdomain), updatedBy)
+		if store.IsUniqueViolationOn(err, "services", "subdomain") {
+			WriteError(w, domain.NewConflictError(fmt.Sprintf("Subdomain %q is already in use", subdomain)))
+			return
+		}
+		if err != nil {
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return
+		}
+	} else {
+		var err error
+		subdomain, _, err = h.allocateSubdomain(r.Context(), client, service, updatedBy)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+	}
+	generatedURL := client.SubdomainURL(subdomain)
+
+	// The subdomain is claimed, move the ingress over; on failure give it back so the
+	// database keeps naming the host that still routes
+	host := client.SubdomainHost(subdomain)
+	if err := client.SetIngressDefaultHost(r.Context(), project.ID.String(), service.ID.String(), host); err != nil {
+		if restoreErr := h.Store.SetServiceSubdomain(r.Context(), service.ID, previous.String, previousURL.String, updatedBy); restoreErr != nil {
+			log.Printf("Failed to restore subdomain of service %s: %v", service.ID, restoreErr)
+		}
+		WriteError(w, domain.NewAppError(domain.ErrCodeExternalAPI, "Failed to update ingress: "+err.Error(), http.StatusBadGateway))
+		return
+	}
+
+	// Custom domain routes proxy to the generated URL
+	service.Subdomain = store.StringToNullString(subdomain)
+	service.GeneratedURL = store.StringToNullString(generatedURL)
+	if h.config.CaddyAdminURL != "" {
+		worker.SyncServiceCustomDomainRoutes(r.Context(), h.Store, caddy.NewClient(h.config.CaddyAdminURL), service)
+	}
+
+	WriteJSON(w, http.StatusOK, ServiceSubdomainResponse{
+		Subdomain:         subdomain,
+		GeneratedURL:      generatedURL,
+		PreviousSubdomain: previous.String,
+	})
+}
+
+// allocateSubdomain gives a service a newly generated subdomain of its region's
+// cluster, see store.AllocateSubdomain, and returns it with the service's new
+// generated URL
+func (h *ServiceHandler) allocateSubdomain(ctx context.Context, client *k8s.Client, service *store.Service, updatedBy string) (string, string, error) {
+	subdomain, generatedURL, err := h.Store.AllocateSubdomain(ctx, service.ID, func() (string, string, error) {
+		return client.SubdomainCandidate(service.Name)
+	}, updatedBy)
+	if errors.Is(err, store.ErrSubdomainUnavailable) {
+		return "", "", domain.NewConflictError("Could not allocate a free subdomain, please retry")
+	}
+	if err != nil {
+		return "", "", domain.ErrDatabase.WithError(err)
+	}
+	return subdomain, generatedURL, nil
+}
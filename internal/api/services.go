@@ -5,56 +5,79 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 
 	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/caddy"
 	"github.com/intelifox/click-deploy/internal/config"
 	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/git"
+	"github.com/intelifox/click-deploy/internal/k8s"
 	"github.com/intelifox/click-deploy/internal/store"
 	"github.com/intelifox/click-deploy/internal/worker"
 )
 
 type ServiceHandler struct {
-	Store  *store.DB
-	config *config.Config
+	Store      *store.DB
+	config     *config.Config
+	k8sClients *k8s.ClientRegistry
+	listCache  *serviceListCache
 }
 
-// NewServiceHandler creates a new service handler
-func NewServiceHandler(store *store.DB, cfg *config.Config) *ServiceHandler {
+// NewServiceHandler creates a new service handler.
+// k8sClients is optional; without it live status falls back to the stored status.
+func NewServiceHandler(store *store.DB, cfg *config.Config, k8sClients *k8s.ClientRegistry) *ServiceHandler {
 	return &ServiceHandler{
-		Store:  store,
-		config: cfg,
+		Store:      store,
+		config:     cfg,
+		k8sClients: k8sClients,
+		listCache:  newServiceListCache(),
 	}
 }
 
 // ServiceResponse represents a service in API responses
 type ServiceResponse struct {
-	ID                  string  `json:"id"`
-	ProjectID           string  `json:"project_id"`
-	GitSourceID         *string `json:"git_source_id,omitempty"`
-	Name                string  `json:"name"`
-	Type                string  `json:"type"`
-	Status              string  `json:"status"`
-	InstanceSize        string  `json:"instance_size"`
-	Port                int     `json:"port"`
-	
+	ID           string  `json:"id"`
+	ProjectID    string  `json:"project_id"`
+	GitSourceID  *string `json:"git_source_id,omitempty"`
+	Name         string  `json:"name"`
+	Type         string  `json:"type"`
+	Status       string  `json:"status"`
+	InstanceSize string  `json:"instance_size"`
+	Port         int     `json:"port"`
+
+	// Deployments wait for approval by a second user
+	RequiresApproval bool `json:"requires_approval"`
+	// Routes pass WebSocket upgrades on and don't buffer streamed responses
+	SupportsWebsockets bool `json:"supports_websockets"`
+
 	// Git source info (populated from git_sources table)
 	RepoOwner *string `json:"repo_owner,omitempty"`
 	RepoName  *string `json:"repo_name,omitempty"`
 	Branch    *string `json:"branch,omitempty"`
 	RootDir   *string `json:"root_dir,omitempty"`
-	
+
+	// Deploy trigger: branch pushes or tags matching TagPattern
+	TriggerMode *string `json:"trigger_mode,omitempty"`
+	TagPattern  *string `json:"tag_pattern,omitempty"`
+
 	// Resource limits
 	CPULimit    *string `json:"cpu_limit,omitempty"`
 	MemoryLimit *string `json:"memory_limit,omitempty"`
-	
+
 	// Build config
 	StartCommand *string `json:"start_command,omitempty"`
 	BuildCommand *string `json:"build_command,omitempty"`
-	
+
+	// Health checks
+	HealthCheckPath         *string `json:"health_check_path,omitempty"`
+	HealthCheckInitialDelay *int    `json:"health_check_initial_delay,omitempty"`
+	DisableDefaultProbe     bool    `json:"disable_default_probe"`
+
 	OpenStackInstanceID *string `json:"openstack_instance_id,omitempty"`
 	OpenStackFIPID      *string `json:"openstack_fip_id,omitempty"`
 	OpenStackFIPAddress *string `json:"openstack_fip_address,omitempty"`
@@ -62,31 +85,60 @@ type ServiceResponse struct {
 	Subdomain           *string `json:"subdomain,omitempty"`
 	GeneratedURL        *string `json:"generated_url,omitempty"`
 	CurrentImageTag     *string `json:"current_image_tag,omitempty"`
-	CanvasX             int     `json:"canvas_x"`
-	CanvasY             int     `json:"canvas_y"`
-	CreatedAt           string  `json:"created_at"`
-	UpdatedAt           string  `json:"updated_at"`
+
+	// A deployment of the service isn't over yet: deploy buttons are disabled until it is
+	IsDeploying        bool    `json:"is_deploying"`
+	ActiveDeploymentID *string `json:"active_deployment_id,omitempty"`
+
+	// Live k8s status (only populated by GetService with ?live=true)
+	ReadyReplicas   *int32  `json:"ready_replicas,omitempty"`
+	DesiredReplicas *int32  `json:"desired_replicas,omitempty"`
+	K8sPhase        *string `json:"k8s_phase,omitempty"`
+	Health          *string `json:"health,omitempty"` // healthy, crash_looping, oom_killed or unschedulable
+
+	CanvasX   int    `json:"canvas_x"`
+	CanvasY   int    `json:"canvas_y"`
+	CreatedBy *string `json:"created_by,omitempty"`
+	UpdatedBy *string `json:"updated_by,omitempty"`
+	CreatedAt string  `json:"created_at"`
+	UpdatedAt string  `json:"updated_at"`
 }
 
 // toServiceResponse converts a store.Service to ServiceResponse
 func toServiceResponse(s *store.Service) ServiceResponse {
 	resp := ServiceResponse{
-		ID:           s.ID.String(),
-		ProjectID:    s.ProjectID.String(),
-		Name:         s.Name,
-		Type:         s.Type,
-		Status:       s.Status,
-		InstanceSize: s.InstanceSize,
-		Port:         s.Port,
-		CanvasX:      s.CanvasX,
-		CanvasY:      s.CanvasY,
-		CreatedAt:    s.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:    s.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ID:                 s.ID.String(),
+		ProjectID:          s.ProjectID.String(),
+		Name:               s.Name,
+		Type:               s.Type,
+		Status:             s.Status,
+		InstanceSize:       s.InstanceSize,
+		Port:               s.Port,
+		RequiresApproval:   s.RequiresApproval,
+		SupportsWebsockets: s.SupportsWebsockets,
+		CanvasX:            s.CanvasX,
+		CanvasY:            s.CanvasY,
+		CreatedAt:          s.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:          s.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
 
 	if s.GitSourceID.Valid {
 		resp.GitSourceID = &s.GitSourceID.String
 	}
+	if s.BuildCommand.Valid {
+		resp.BuildCommand = &s.BuildCommand.String
+	}
+	if s.StartCommand.Valid {
+		resp.StartCommand = &s.StartCommand.String
+	}
+	if s.HealthCheckPath.Valid {
+		resp.HealthCheckPath = &s.HealthCheckPath.String
+	}
+	if s.HealthCheckInitialDelay.Valid {
+		delay := int(s.HealthCheckInitialDelay.Int64)
+		resp.HealthCheckInitialDelay = &delay
+	}
+	resp.DisableDefaultProbe = s.DefaultProbeDisabled
 	if s.OpenStackInstanceID.Valid {
 		resp.OpenStackInstanceID = &s.OpenStackInstanceID.String
 	}
@@ -108,6 +160,12 @@ func toServiceResponse(s *store.Service) ServiceResponse {
 	if s.CurrentImageTag.Valid {
 		resp.CurrentImageTag = &s.CurrentImageTag.String
 	}
+	if s.CreatedBy.Valid {
+		resp.CreatedBy = &s.CreatedBy.String
+	}
+	if s.UpdatedBy.Valid {
+		resp.UpdatedBy = &s.UpdatedBy.String
+	}
 
 	return resp
 }
@@ -126,6 +184,10 @@ func (h *ServiceHandler) toServiceResponseWithGitSource(ctx context.Context, s *
 			if gitSource.RootDir.Valid {
 				resp.RootDir = &gitSource.RootDir.String
 			}
+			resp.TriggerMode = &gitSource.TriggerMode
+			if gitSource.TagPattern.Valid {
+				resp.TagPattern = &gitSource.TagPattern.String
+			}
 		}
 	}
 	
@@ -154,8 +216,14 @@ func (h *ServiceHandler) ListServices(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		WriteError(w, domain.NewNotFoundError("Project"))
+	if !checkOrgAccess(w, project, orgID, "Project") {
+		return
+	}
+
+	// Read before the services, so a change made while they're read isn't cached as current
+	version := store.ProjectCacheVersion(projectID)
+	if cached, ok := h.listCache.get(projectID, version); ok {
+		WriteJSONWithETag(w, r, newListResponse(cached))
 		return
 	}
 
@@ -175,8 +243,112 @@ func (h *ServiceHandler) ListServices(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	deployStates := make([]*ServiceResponse, len(response))
+	for i := range response {
+		deployStates[i] = &response[i]
+	}
+	if err := applyDeployStates(r.Context(), h.Store, deployStates...); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	h.listCache.put(projectID, version, response)
+
+	WriteJSONWithETag(w, r, newListResponse(response))
+}
+
+// ServiceStatusResponse represents the live status of a service
+type ServiceStatusResponse struct {
+	ServiceID       string `json:"service_id"`
+	Status          string `json:"status"`
+	K8sStatus       string `json:"k8s_status"`
+	ReadyReplicas   int32  `json:"ready_replicas"`
+	DesiredReplicas int32  `json:"desired_replicas"`
+	Health          string `json:"health,omitempty"` // healthy, crash_looping, oom_killed or unschedulable
+}
+
+// ListServiceStatuses handles GET /projects/:id/services/status
+// Live statuses for every service in the project are fetched with one k8s call.
+// When k8s is not configured or unreachable, k8s_status is "unknown".
+func (h *ServiceHandler) ListServiceStatuses(w http.ResponseWriter, r *http.Request) {
+	projectIDStr := chi.URLParam(r, "id")
+	projectID, err := uuid.Parse(projectIDStr)
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid project ID"))
+		return
+	}
+
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	project, err := h.Store.GetProject(r.Context(), projectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if !checkOrgAccess(w, project, orgID, "Project") {
+		return
+	}
+
+	services, err := h.Store.ListServicesByProject(r.Context(), projectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	live := h.liveStatuses(r.Context(), project, services)
+
+	response := make([]ServiceStatusResponse, 0, len(services))
+	for _, s := range services {
+		response = append(response, serviceStatus(s, live))
+	}
+
+	WriteJSONWithETag(w, r, newListResponse(response))
+}
+
+// liveStatuses fetches the k8s deployment status of every service with one call.
+// Returns nil when k8s is not configured or unreachable.
+func (h *ServiceHandler) liveStatuses(ctx context.Context, project *store.Project, services []*store.Service) map[string]*k8s.DeploymentStatus {
+	client := projectK8sClient(h.k8sClients, project)
+	if client == nil || len(services) == 0 {
+		return nil
+	}
+
+	serviceIDs := make([]string, 0, len(services))
+	for _, s := range services {
+		serviceIDs = append(serviceIDs, s.ID.String())
+	}
+
+	live, err := client.GetDeploymentStatuses(ctx, project.ID.String(), serviceIDs)
+	if err != nil {
+		return nil
+	}
+
+	// Health is best effort: without it the replica counts are still useful
+	if healths, err := client.GetServiceHealths(ctx, project.ID.String()); err == nil {
+		for serviceID, status := range live {
+			status.Health = healths[serviceID]
+		}
+	}
+	return live
+}
 
-	WriteJSON(w, http.StatusOK, response)
+// serviceStatus combines a service's stored status with its live k8s status, if known
+func serviceStatus(s *store.Service, live map[string]*k8s.DeploymentStatus) ServiceStatusResponse {
+	status := ServiceStatusResponse{
+		ServiceID: s.ID.String(),
+		Status:    s.Status,
+		K8sStatus: "unknown",
+	}
+	if ds, ok := live[s.ID.String()]; ok {
+		status.K8sStatus = ds.Phase()
+		status.ReadyReplicas = ds.ReadyReplicas
+		status.DesiredReplicas = ds.DesiredReplicas
+		status.Health = ds.Health
+	}
+	return status
 }
 
 // CreateService handles POST /projects/:id/services
@@ -201,8 +373,7 @@ func (h *ServiceHandler) CreateService(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		WriteError(w, domain.NewNotFoundError("Project"))
+	if !checkOrgAccess(w, project, orgID, "Project") {
 		return
 	}
 
@@ -213,7 +384,7 @@ func (h *ServiceHandler) CreateService(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Sanitize input
-	req.Name = SanitizeString(req.Name)
+	req.Name = SanitizeName(req.Name)
 
 	// Validate request
 	if validationErrs := ValidateCreateServiceRequest(&req); validationErrs.HasErrors() {
@@ -221,16 +392,91 @@ func (h *ServiceHandler) CreateService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create service
+	service := newStoreService(project, &req)
+	service.CreatedBy = requestUser(r)
+	service.UpdatedBy = service.CreatedBy
+
+	// Resolve the git connection and check root_dir before creating anything
+	gitSource, err := h.newStoreGitSource(r.Context(), orgID, req.GitSource)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+
+	requested := QuotaUsage{Services: 1}
+	if service.Type == "app" {
+		requested.MemoryMB = instanceSizeMemoryMB[service.InstanceSize]
+	}
+	created := createWithinQuota(w, r, h.Store, orgID, requested, func(tx *store.QuotaTx) error {
+		// Create service first
+		if err := tx.CreateService(r.Context(), service); err != nil {
+			return err
+		}
+
+		// If git source info provided, create git source after service creation
+		if gitSource != nil {
+			gitSource.ServiceID = service.ID
+			// Note: the service's git_source_id isn't updated, but that's okay
+			// The git_source table has the service_id foreign key, so the relationship is established
+			return tx.CreateGitSource(r.Context(), gitSource)
+		}
+		return nil
+	})
+	if !created {
+		return
+	}
+
+	// Claim the service's generated subdomain now so it shows before the first deploy;
+	// if none is free the deploy tries again
+	if client := projectK8sClient(h.k8sClients, project); client != nil {
+		if _, _, err := h.allocateSubdomain(r.Context(), client, service, service.CreatedBy.String); err != nil {
+			log.Printf("Failed to allocate subdomain for service %s: %v", service.ID, err)
+		}
+	}
+
+	// Fetch created service to return full details
+	createdService, err := h.Store.GetService(r.Context(), service.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteCreated(w, h.toServiceResponseWithGitSource(r.Context(), createdService))
+}
+
+// Instance size and port of new services, unless their project sets other defaults
+const (
+	defaultInstanceSize = "medium"
+	defaultServicePort  = 8080
+)
+
+// serviceDefaults returns the instance size and port of a project's new services that
+// don't set theirs
+func serviceDefaults(project *store.Project) (string, int) {
+	size, port := defaultInstanceSize, defaultServicePort
+	if project.DefaultInstanceSize.Valid {
+		size = project.DefaultInstanceSize.String
+	}
+	if project.DefaultPort.Valid {
+		port = int(project.DefaultPort.Int64)
+	}
+	return size, port
+}
+
+// newStoreService builds the service to create in the project from a validated request
+func newStoreService(project *store.Project, req *CreateServiceRequest) *store.Service {
+	size, port := serviceDefaults(project)
 	service := &store.Service{
-		ProjectID:    projectID,
-		Name:         req.Name,
-		Type:         req.Type,
-		Status:       "pending",
-		InstanceSize: "medium",
-		Port:         8080,
-		CanvasX:      0,
-		CanvasY:      0,
+		ProjectID:          project.ID,
+		Name:               req.Name,
+		Type:               req.Type,
+		Status:             "pending",
+		InstanceSize:       size,
+		Port:               port,
+		CanvasX:            0,
+		CanvasY:            0,
+		RequiresApproval:   req.RequiresApproval,
+		SupportsWebsockets: req.SupportsWebsockets,
 	}
 
 	if req.InstanceSize != "" {
@@ -249,6 +495,24 @@ func (h *ServiceHandler) CreateService(w http.ResponseWriter, r *http.Request) {
 		service.CanvasY = *req.CanvasY
 	}
 
+	if req.BuildCommand != nil {
+		service.BuildCommand = sql.NullString{String: *req.BuildCommand, Valid: *req.BuildCommand != ""}
+	}
+
+	if req.StartCommand != nil {
+		service.StartCommand = sql.NullString{String: *req.StartCommand, Valid: *req.StartCommand != ""}
+	}
+
+	if req.HealthCheckPath != nil {
+		service.HealthCheckPath = sql.NullString{String: *req.HealthCheckPath, Valid: *req.HealthCheckPath != ""}
+	}
+
+	if req.HealthCheckInitialDelay != nil {
+		service.HealthCheckInitialDelay = sql.NullInt64{Int64: int64(*req.HealthCheckInitialDelay), Valid: true}
+	}
+
+	service.DefaultProbeDisabled = req.DisableDefaultProbe
+
 	// Handle git source ID if provided
 	if req.GitSourceID != nil {
 		gitSourceUUID, err := uuid.Parse(*req.GitSourceID)
@@ -257,61 +521,85 @@ func (h *ServiceHandler) CreateService(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Create service first
-	if err := h.Store.CreateService(r.Context(), service); err != nil {
-		WriteError(w, domain.ErrDatabase.WithError(err))
-		return
+	return service
+}
+
+// newStoreGitSource resolves the org's connection to the git provider and checks
+// root_dir, and builds the git source to create for a new service (without its
+// ServiceID). Returns nil when info is nil.
+func (h *ServiceHandler) newStoreGitSource(ctx context.Context, orgID string, info *GitSourceInfo) (*store.GitSource, error) {
+	if info == nil {
+		return nil, nil
 	}
 
-	// If git source info provided, create git source after service creation
-	if req.GitSource != nil {
-		// Get git connection for this org and provider
-		connection, err := h.Store.GetGitConnectionByOrgAndProvider(r.Context(), orgID, req.GitSource.Provider)
-		if err != nil {
-			WriteError(w, domain.ErrDatabase.WithError(err))
-			return
-		}
-		if connection == nil {
-			WriteError(w, domain.NewInvalidInputError(fmt.Sprintf("No %s connection found. Please connect your %s account first.", req.GitSource.Provider, req.GitSource.Provider)))
-			return
-		}
+	// Get git connection for this org and provider
+	connection, err := h.Store.GetGitConnectionByOrgAndProvider(ctx, orgID, info.Provider)
+	if err != nil {
+		return nil, domain.ErrDatabase.WithError(err)
+	}
+	if connection == nil {
+		return nil, domain.NewGitNotConnectedError(fmt.Sprintf("No %s connection found. Please connect your %s account first.", info.Provider, info.Provider))
+	}
 
-		// Create git source
-		gitSource := &store.GitSource{
-			ServiceID:       service.ID,
-			GitConnectionID: connection.ID,
-			Provider:        req.GitSource.Provider,
-			RepoOwner:       SanitizeString(req.GitSource.RepoOwner),
-			RepoName:        SanitizeString(req.GitSource.RepoName),
-			Branch:          SanitizeString(req.GitSource.Branch),
-		}
+	gitSource := &store.GitSource{
+		GitConnectionID: connection.ID,
+		Provider:        info.Provider,
+		RepoOwner:       SanitizeName(info.RepoOwner),
+		RepoName:        SanitizeName(info.RepoName),
+		Branch:          SanitizeName(info.Branch),
+	}
 
-		if req.GitSource.RootDir != nil {
-			gitSource.RootDir = sql.NullString{String: SanitizeString(*req.GitSource.RootDir), Valid: true}
+	if info.RootDir != nil {
+		rootDir, err := h.validateRootDir(ctx, connection, info.RepoOwner, info.RepoName, info.Branch, SanitizeName(*info.RootDir))
+		if err != nil {
+			return nil, err
 		}
-
-		if err := h.Store.CreateGitSource(r.Context(), gitSource); err != nil {
-			WriteError(w, domain.ErrDatabase.WithError(err))
-			return
+		if rootDir != "" {
+			gitSource.RootDir = sql.NullString{String: rootDir, Valid: true}
 		}
+	}
+	if info.TriggerMode != nil {
+		gitSource.TriggerMode = *info.TriggerMode
+	}
+	if info.TagPattern != nil {
+		gitSource.TagPattern = store.StringToNullString(SanitizeName(*info.TagPattern))
+	}
 
-		// Update service with git source ID
-		service.GitSourceID = sql.NullString{String: gitSource.ID.String(), Valid: true}
-		// Note: UpdateService doesn't update git_source_id, but that's okay
-		// The git_source table has the service_id foreign key, so the relationship is established
+	return gitSource, nil
+}
+
+// validateRootDir normalizes a monorepo root_dir and checks it is a directory in the repository.
+// Returns "" for the repository root.
+func (h *ServiceHandler) validateRootDir(ctx context.Context, connection *store.GitConnection, owner, repo, branch, rootDir string) (string, error) {
+	dir, err := git.NormalizeRootDir(rootDir)
+	if err != nil {
+		return "", domain.NewValidationError(err.Error())
+	}
+	if dir == "" {
+		return "", nil
 	}
 
-	// Fetch created service to return full details
-	createdService, err := h.Store.GetService(r.Context(), service.ID)
+	var exists bool
+	switch connection.Provider {
+	case "github":
+		exists, err = git.NewGitHubClient(connection.AccessToken).DirectoryExists(ctx, owner, repo, branch, dir)
+	case "gitlab":
+		exists, err = git.NewGitLabClient(connection.AccessToken, h.config.GitLabBaseURL).DirectoryExists(ctx, owner, repo, branch, dir)
+	default:
+		return "", domain.NewInvalidInputError("Unsupported provider")
+	}
 	if err != nil {
-		WriteError(w, domain.ErrDatabase.WithError(err))
-		return
+		return "", domain.NewAppError(domain.ErrCodeExternalAPI, "Failed to check root_dir: "+err.Error(), http.StatusBadGateway)
+	}
+	if !exists {
+		return "", domain.NewValidationError(fmt.Sprintf("root_dir %q does not exist in %s/%s (branch %s)", dir, owner, repo, branch))
 	}
 
-	WriteCreated(w, h.toServiceResponseWithGitSource(r.Context(), createdService))
+	return dir, nil
 }
 
 // GetService handles GET /services/:id
+// Pass ?live=true to include the live k8s replica counts and phase.
 func (h *ServiceHandler) GetService(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
@@ -345,12 +633,72 @@ func (h *ServiceHandler) GetService(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		WriteError(w, domain.NewNotFoundError("Service"))
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, h.toServiceResponseWithGitSource(r.Context(), service))
+	resp := h.toServiceResponseWithGitSource(r.Context(), service)
+	if err := applyDeployStates(r.Context(), h.Store, &resp); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if r.URL.Query().Get("live") == "true" {
+		h.applyLiveStatus(r.Context(), &resp, project, service)
+	}
+
+	WriteJSONWithETag(w, r, resp)
+}
+
+// applyDeployStates fills IsDeploying and ActiveDeploymentID of service responses,
+// with one query for all of them
+func applyDeployStates(ctx context.Context, db *store.DB, responses ...*ServiceResponse) error {
+	serviceIDs := make([]uuid.UUID, 0, len(responses))
+	for _, resp := range responses {
+		if id, err := uuid.Parse(resp.ID); err == nil {
+			serviceIDs = append(serviceIDs, id)
+		}
+	}
+
+	active, err := db.ActiveDeploymentsByService(ctx, serviceIDs)
+	if err != nil {
+		return err
+	}
+	for _, resp := range responses {
+		id, err := uuid.Parse(resp.ID)
+		if err != nil {
+			continue
+		}
+		if deploymentID, ok := active[id]; ok {
+			activeID := deploymentID.String()
+			resp.IsDeploying = true
+			resp.ActiveDeploymentID = &activeID
+		}
+	}
+	return nil
+}
+
+// applyLiveStatus fills the live status fields from k8s. If k8s is not
+// configured or unreachable the fields are left empty and clients fall back
+// to the stored status.
+func (h *ServiceHandler) applyLiveStatus(ctx context.Context, resp *ServiceResponse, project *store.Project, s *store.Service) {
+	client := projectK8sClient(h.k8sClients, project)
+	if client == nil {
+		return
+	}
+
+	status, err := client.GetDeploymentStatus(ctx, s.ProjectID.String(), s.ID.String())
+	if err != nil {
+		return
+	}
+
+	phase := status.Phase()
+	resp.ReadyReplicas = &status.ReadyReplicas
+	resp.DesiredReplicas = &status.DesiredReplicas
+	resp.K8sPhase = &phase
+
+	if health, err := client.GetServiceHealth(ctx, s.ProjectID.String(), s.ID.String()); err == nil && status.Exists {
+		resp.Health = &health
+	}
 }
 
 // UpdateService handles PATCH /services/:id
@@ -387,8 +735,7 @@ func (h *ServiceHandler) UpdateService(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		WriteError(w, domain.NewNotFoundError("Service"))
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
@@ -398,7 +745,11 @@ func (h *ServiceHandler) UpdateService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate request
+	// Sanitize and validate request
+	sanitizeOptional(req.Name, SanitizeName)
+	sanitizeOptional(req.Branch, SanitizeName)
+	sanitizeOptional(req.RootDir, SanitizeName)
+	sanitizeOptional(req.TagPattern, SanitizeName)
 	if validationErrs := ValidateUpdateServiceRequest(&req); validationErrs.HasErrors() {
 		WriteError(w, validationErrs.ToAppError())
 		return
@@ -424,15 +775,45 @@ func (h *ServiceHandler) UpdateService(w http.ResponseWriter, r *http.Request) {
 	if req.Status != nil {
 		service.Status = *req.Status
 	}
+	if req.RequiresApproval != nil {
+		service.RequiresApproval = *req.RequiresApproval
+	}
+	streamingChanged := req.SupportsWebsockets != nil && *req.SupportsWebsockets != service.SupportsWebsockets
+	if req.SupportsWebsockets != nil {
+		service.SupportsWebsockets = *req.SupportsWebsockets
+	}
+	// An empty command clears it, the detected one is used again
+	if req.BuildCommand != nil {
+		service.BuildCommand = sql.NullString{String: *req.BuildCommand, Valid: *req.BuildCommand != ""}
+	}
+	if req.StartCommand != nil {
+		service.StartCommand = sql.NullString{String: *req.StartCommand, Valid: *req.StartCommand != ""}
+	}
+	// Probe changes reach the pods on the next deploy
+	if req.HealthCheckPath != nil {
+		service.HealthCheckPath = sql.NullString{String: *req.HealthCheckPath, Valid: *req.HealthCheckPath != ""}
+	}
+	if req.HealthCheckInitialDelay != nil {
+		service.HealthCheckInitialDelay = sql.NullInt64{Int64: int64(*req.HealthCheckInitialDelay), Valid: true}
+	}
+	if req.DisableDefaultProbe != nil {
+		service.DefaultProbeDisabled = *req.DisableDefaultProbe
+	}
 
 	// Update service
+	service.UpdatedBy = requestUser(r)
 	if err := h.Store.UpdateService(r.Context(), id, service); err != nil {
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
 
-	// Update git source if branch or root_dir provided
-	if req.Branch != nil || req.RootDir != nil {
+	// Custom domain routes pick the setting up right away, the ingress on the next deploy
+	if streamingChanged && h.config.CaddyAdminURL != "" {
+		worker.SyncServiceCustomDomainRoutes(r.Context(), h.Store, caddy.NewClient(h.config.CaddyAdminURL), service)
+	}
+
+	// Update git source if branch, root_dir or trigger provided
+	if req.Branch != nil || req.RootDir != nil || req.TriggerMode != nil || req.TagPattern != nil {
 		gitSource, err := h.Store.GetGitSourceByService(r.Context(), id)
 		if err != nil {
 			WriteError(w, domain.ErrDatabase.WithError(err))
@@ -444,7 +825,27 @@ func (h *ServiceHandler) UpdateService(w http.ResponseWriter, r *http.Request) {
 				gitSource.Branch = *req.Branch
 			}
 			if req.RootDir != nil {
-				gitSource.RootDir = sql.NullString{String: *req.RootDir, Valid: *req.RootDir != ""}
+				connection, err := h.Store.GetGitConnection(r.Context(), gitSource.GitConnectionID)
+				if err != nil {
+					WriteError(w, domain.ErrDatabase.WithError(err))
+					return
+				}
+				if connection == nil {
+					WriteError(w, domain.NewGitNotConnectedError("Git connection for this service no longer exists"))
+					return
+				}
+				rootDir, err := h.validateRootDir(r.Context(), connection, gitSource.RepoOwner, gitSource.RepoName, gitSource.Branch, *req.RootDir)
+				if err != nil {
+					WriteError(w, err)
+					return
+				}
+				gitSource.RootDir = sql.NullString{String: rootDir, Valid: rootDir != ""}
+			}
+			if req.TriggerMode != nil {
+				gitSource.TriggerMode = *req.TriggerMode
+			}
+			if req.TagPattern != nil {
+				gitSource.TagPattern = store.StringToNullString(*req.TagPattern)
 			}
 			
 			if err := h.Store.UpdateGitSource(r.Context(), gitSource.ID, gitSource); err != nil {
@@ -461,7 +862,13 @@ func (h *ServiceHandler) UpdateService(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, h.toServiceResponseWithGitSource(r.Context(), updatedService))
+	resp := h.toServiceResponseWithGitSource(r.Context(), updatedService)
+	if err := applyDeployStates(r.Context(), h.Store, &resp); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, resp)
 }
 
 // UpdateServicePosition handles PATCH /services/:id/position
@@ -498,8 +905,7 @@ func (h *ServiceHandler) UpdateServicePosition(w http.ResponseWriter, r *http.Re
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		WriteError(w, domain.NewNotFoundError("Service"))
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
@@ -528,7 +934,13 @@ func (h *ServiceHandler) UpdateServicePosition(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, h.toServiceResponseWithGitSource(r.Context(), updatedService))
+	resp := h.toServiceResponseWithGitSource(r.Context(), updatedService)
+	if err := applyDeployStates(r.Context(), h.Store, &resp); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, resp)
 }
 
 // DeleteService handles DELETE /services/:id
@@ -565,8 +977,7 @@ func (h *ServiceHandler) DeleteService(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, domain.ErrDatabase.WithError(err))
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		WriteError(w, domain.NewNotFoundError("Service"))
+	if !checkOrgAccess(w, project, orgID, "Service") {
 		return
 	}
 
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// maxServiceBatch is how many services a single batch request can create
+const maxServiceBatch = 20
+
+// CreateServices handles POST /projects/:id/services/batch
+// Creates the services of a multi-service app in one go. The body is an array of
+// create service requests; all of them are validated first, errors are reported by
+// index (e.g. "[2].name"), and the services are created with their git sources in a
+// single transaction, so either all of them are created or none is. Returns the
+// created services in request order.
+func (h *ServiceHandler) CreateServices(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid project ID"))
+		return
+	}
+
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	project, err := h.Store.GetProject(r.Context(), projectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if !checkOrgAccess(w, project, orgID, "Project") {
+		return
+	}
+
+	var reqs []CreateServiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body: "+err.Error()))
+		return
+	}
+	if len(reqs) == 0 {
+		WriteError(w, domain.NewValidationError("At least one service is required"))
+		return
+	}
+	if len(reqs) > maxServiceBatch {
+		WriteError(w, domain.NewValidationError(fmt.Sprintf("At most %d services can be created at once", maxServiceBatch)))
+		return
+	}
+
+	validationErrs := &ValidationErrors{}
+	for i := range reqs {
+		reqs[i].Name = SanitizeName(reqs[i].Name)
+		for _, e := range ValidateCreateServiceRequest(&reqs[i]).Errors {
+			validationErrs.Add(fmt.Sprintf("[%d].%s", i, e.Field), e.Message)
+		}
+	}
+	if validationErrs.HasErrors() {
+		WriteError(w, validationErrs.ToAppError())
+		return
+	}
+
+	var requested QuotaUsage
+	batch := make([]*store.ServiceWithGitSource, len(reqs))
+	for i := range reqs {
+		service := newStoreService(project, &reqs[i])
+		service.CreatedBy = requestUser(r)
+		service.UpdatedBy = service.CreatedBy
+		requested.Services++
+		if service.Type == "app" {
+			requested.MemoryMB += instanceSizeMemoryMB[service.InstanceSize]
+		}
+		batch[i] = &store.ServiceWithGitSource{Service: service}
+	}
+
+	// Resolve the git connections and check the root_dirs before creating anything
+	for i := range reqs {
+		gitSource, err := h.newStoreGitSource(r.Context(), orgID, reqs[i].GitSource)
+		if appErr, ok := domain.IsAppError(err); ok && appErr.Code == domain.ErrCodeValidation {
+			validationErrs.Add(fmt.Sprintf("[%d].git_source.root_dir", i), appErr.Message)
+			continue
+		}
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		batch[i].GitSource = gitSource
+	}
+	if validationErrs.HasErrors() {
+		WriteError(w, validationErrs.ToAppError())
+		return
+	}
+
+	// All of them are created or none is
+	created := createWithinQuota(w, r, h.Store, orgID, requested, func(tx *store.QuotaTx) error {
+		for _, item := range batch {
+			if err := tx.CreateService(r.Context(), item.Service); err != nil {
+				return err
+			}
+			if item.GitSource != nil {
+				item.GitSource.ServiceID = item.Service.ID
+				if err := tx.CreateGitSource(r.Context(), item.GitSource); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if !created {
+		return
+	}
+
+	response := make([]ServiceResponse, 0, len(batch))
+	for _, item := range batch {
+		created, err := h.Store.GetService(r.Context(), item.Service.ID)
+		if err != nil {
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return
+		}
+		response = append(response, h.toServiceResponseWithGitSource(r.Context(), created))
+	}
+
+	WriteCreated(w, response)
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// ImportServiceRequest names an existing Kubernetes Deployment to import as a service
+type ImportServiceRequest struct {
+	Namespace      string `json:"namespace"`
+	DeploymentName string `json:"deployment_name"`
+	Name           string `json:"name,omitempty"` // Service name, the deployment's by default
+}
+
+// ImportServiceResponse is the imported service, with the variables that weren't imported
+type ImportServiceResponse struct {
+	ServiceResponse
+	// Variables set from secrets, config maps or fields, and envFrom sources, which
+	// have to be added to the service before its first deploy
+	SkippedEnvVars []string `json:"skipped_env_vars,omitempty"`
+}
+
+// ImportService handles POST /projects/:id/services/import
+// Brings an existing Deployment of the cluster under management as a new app service
+// without redeploying it: its image, port, command, resources, readiness probe and
+// literal env vars are copied to the service, and the Deployment gets Zyndra's labels
+// but keeps its pods. The Deployment keeps running where it is until the service's
+// first deploy, which replaces it with the service's own Deployment in the project's
+// namespace.
+//
+// The namespace is the project's, or one labeled zyndra.io/org-id with the caller's
+// org by the cluster's operators, so deployments of other tenants can't be read.
+func (h *ServiceHandler) ImportService(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid project ID"))
+		return
+	}
+
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	project, err := h.Store.GetProject(r.Context(), projectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if !checkOrgAccess(w, project, orgID, "Project") {
+		return
+	}
+
+	var req ImportServiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid request body: "+err.Error()))
+		return
+	}
+	req.Namespace = strings.TrimSpace(req.Namespace)
+	req.DeploymentName = strings.TrimSpace(req.DeploymentName)
+	if req.Namespace == "" || req.DeploymentName == "" {
+		WriteError(w, domain.NewValidationError("namespace and deployment_name are required"))
+		return
+	}
+
+	client := projectK8sClient(h.k8sClients, project)
+	if client == nil {
+		WriteError(w, domain.NewServiceUnavailableError("Kubernetes is not configured"))
+		return
+	}
+
+	imported, err := client.ReadDeploymentForImport(r.Context(), req.Namespace, req.DeploymentName)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+	// A namespace the org can't import from looks like it doesn't exist
+	if req.Namespace != client.ProjectNamespace(projectID.String()) && imported.NamespaceOrgID != orgID {
+		WriteError(w, domain.NewNotFoundError("Deployment"))
+		return
+	}
+	if imported.Managed {
+		WriteError(w, domain.NewConflictError("Deployment is already managed by Zyndra"))
+		return
+	}
+	if imported.Containers != 1 {
+		WriteError(w, domain.NewValidationError("Only deployments with a single container can be imported"))
+		return
+	}
+
+	name := SanitizeName(req.Name)
+	if name == "" {
+		name = imported.Name
+	}
+	service := newImportedService(project, name, imported)
+	service.CreatedBy = requestUser(r)
+	service.UpdatedBy = service.CreatedBy
+
+	requested := QuotaUsage{Services: 1, MemoryMB: instanceSizeMemoryMB[service.InstanceSize]}
+	ok := createWithinQuota(w, r, h.Store, orgID, requested, func(tx *store.QuotaTx) error {
+		return tx.CreateService(r.Context(), service)
+	})
+	if !ok {
+		return
+	}
+	if err := h.importServiceState(r.Context(), service.ID, imported); err != nil {
+		h.discardImportedService(r.Context(), service.ID)
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	// Labeled last: once labeled, the deployment counts as managed
+	if err := client.AdoptDeployment(r.Context(), imported, service.ID.String(), service.Name, projectID.String()); err != nil {
+		h.discardImportedService(r.Context(), service.ID)
+		writeImportError(w, err)
+		return
+	}
+
+	created, err := h.Store.GetService(r.Context(), service.ID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteCreated(w, ImportServiceResponse{
+		ServiceResponse: h.toServiceResponseWithGitSource(r.Context(), created),
+		SkippedEnvVars:  imported.SkippedEnv,
+	})
+}
+
+// newImportedService builds the app service running what an imported deployment runs
+func newImportedService(project *store.Project, name string, imported *k8s.ImportedDeployment) *store.Service {
+	size, port := serviceDefaults(project)
+	service := &store.Service{
+		ProjectID:       project.ID,
+		Name:            name,
+		Type:            "app",
+		Status:          "running",
+		InstanceSize:    k8s.InstanceSizeFitting(imported.CPULimitCores, imported.MemoryLimitBytes),
+		Port:            port,
+		CurrentImageTag: store.StringToNullString(imported.Image),
+		StartCommand:    store.StringToNullString(imported.StartCommand),
+		HealthCheckPath: store.StringToNullString(imported.HealthCheckPath),
+	}
+	// Without limits the container could use anything: the default size is as good a guess as any
+	if imported.CPULimitCores == 0 && imported.MemoryLimitBytes == 0 {
+		service.InstanceSize = size
+	}
+	if imported.Port > 0 {
+		service.Port = int(imported.Port)
+	}
+	if imported.ProbeInitialDelay > 0 {
+		service.HealthCheckInitialDelay = sql.NullInt64{Int64: int64(imported.ProbeInitialDelay), Valid: true}
+	}
+	return service
+}
+
+// importServiceState stores the env vars of an imported service and where its
+// deployment runs
+func (h *ServiceHandler) importServiceState(ctx context.Context, serviceID uuid.UUID, imported *k8s.ImportedDeployment) error {
+	for key, value := range imported.Env {
+		ev := &store.EnvVar{
+			ServiceID: serviceID,
+			Key:       key,
+			Value:     sql.NullString{String: value, Valid: true},
+		}
+		if err := h.Store.CreateEnvVar(ctx, ev); err != nil {
+			return err
+		}
+	}
+	return h.Store.SetServiceImport(ctx, serviceID, imported.Namespace, imported.Name)
+}
+
+// discardImportedService deletes a service whose import failed, with its env vars
+func (h *ServiceHandler) discardImportedService(ctx context.Context, serviceID uuid.UUID) {
+	if err := h.Store.DeleteService(ctx, serviceID); err != nil {
+		log.Printf("Failed to delete service %s after a failed import: %v", serviceID, err)
+	}
+}
+
+// writeImportError writes the error of reading or labeling a deployment to import
+func writeImportError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, k8s.ErrDeploymentNotFound):
+		WriteError(w, domain.NewNotFoundError("Deployment"))
+	case errors.Is(err, k8s.ErrDeploymentChanged):
+		WriteError(w, domain.NewConflictError("Deployment changed while it was imported, try again"))
+	default:
+		WriteError(w, domain.NewAppError(domain.ErrCodeExternalAPI, "Failed to import the deployment: "+err.Error(), http.StatusBadGateway))
+	}
+}
@@ -2,11 +2,13 @@ package api
 
 // GitSourceInfo represents git source information for service creation
 type GitSourceInfo struct {
-	Provider  string  `json:"provider" validate:"required,oneof=github gitlab"`
-	RepoOwner string  `json:"repo_owner" validate:"required,min=1,max=255"`
-	RepoName  string  `json:"repo_name" validate:"required,min=1,max=255"`
-	Branch    string  `json:"branch" validate:"required,min=1,max=255"`
-	RootDir   *string `json:"root_dir,omitempty" validate:"omitempty,max=500"`
+	Provider    string  `json:"provider" validate:"required,oneof=github gitlab"`
+	RepoOwner   string  `json:"repo_owner" validate:"required,min=1,max=255"`
+	RepoName    string  `json:"repo_name" validate:"required,min=1,max=255"`
+	Branch      string  `json:"branch" validate:"required,min=1,max=255"`
+	RootDir     *string `json:"root_dir,omitempty" validate:"omitempty,max=500"`
+	TriggerMode *string `json:"trigger_mode,omitempty" validate:"omitempty,oneof=branch tag"`
+	TagPattern  *string `json:"tag_pattern,omitempty" validate:"omitempty,max=255"`
 }
 
 // CreateServiceRequest represents the request body for creating a service
@@ -19,6 +21,19 @@ type CreateServiceRequest struct {
 	GitSource    *GitSourceInfo `json:"git_source,omitempty"`
 	CanvasX      *int            `json:"canvas_x,omitempty"`
 	CanvasY      *int            `json:"canvas_y,omitempty"`
+
+	RequiresApproval bool `json:"requires_approval,omitempty"` // Hold deployments until a second user approves them
+	// Route WebSocket upgrades and streamed responses: no buffering, long timeouts
+	SupportsWebsockets bool `json:"supports_websockets,omitempty"`
+
+	// Build config, overriding the commands Railpack detects
+	StartCommand *string `json:"start_command,omitempty" validate:"omitempty,max=1000"`
+	BuildCommand *string `json:"build_command,omitempty" validate:"omitempty,max=1000"`
+
+	// Health checks: HTTP probes on the path, otherwise a TCP readiness probe on the port
+	HealthCheckPath         *string `json:"health_check_path,omitempty" validate:"omitempty,max=255"`
+	HealthCheckInitialDelay *int    `json:"health_check_initial_delay,omitempty" validate:"omitempty,min=0,max=600"`
+	DisableDefaultProbe     bool    `json:"disable_default_probe,omitempty"`
 }
 
 // UpdateServiceRequest represents the request body for updating a service
@@ -28,10 +43,15 @@ type UpdateServiceRequest struct {
 	InstanceSize *string `json:"instance_size,omitempty" validate:"omitempty,oneof=small medium large xlarge"`
 	Port         *int    `json:"port,omitempty" validate:"omitempty,min=1,max=65535"`
 	Status       *string `json:"status,omitempty" validate:"omitempty,oneof=pending provisioning building deploying live failed stopped"`
+
+	RequiresApproval   *bool `json:"requires_approval,omitempty"`
+	SupportsWebsockets *bool `json:"supports_websockets,omitempty"`
 	
 	// Git source updates
-	Branch  *string `json:"branch,omitempty" validate:"omitempty,min=1,max=255"`
-	RootDir *string `json:"root_dir,omitempty" validate:"omitempty,max=500"`
+	Branch      *string `json:"branch,omitempty" validate:"omitempty,min=1,max=255"`
+	RootDir     *string `json:"root_dir,omitempty" validate:"omitempty,max=500"`
+	TriggerMode *string `json:"trigger_mode,omitempty" validate:"omitempty,oneof=branch tag"`
+	TagPattern  *string `json:"tag_pattern,omitempty" validate:"omitempty,max=255"`
 	
 	// Resource limits
 	CPULimit    *string `json:"cpu_limit,omitempty" validate:"omitempty"`
@@ -40,6 +60,11 @@ type UpdateServiceRequest struct {
 	// Build config
 	StartCommand *string `json:"start_command,omitempty" validate:"omitempty,max=1000"`
 	BuildCommand *string `json:"build_command,omitempty" validate:"omitempty,max=1000"`
+
+	// Health checks, an empty path falls back to the TCP readiness probe
+	HealthCheckPath         *string `json:"health_check_path,omitempty" validate:"omitempty,max=255"`
+	HealthCheckInitialDelay *int    `json:"health_check_initial_delay,omitempty" validate:"omitempty,min=0,max=600"`
+	DisableDefaultProbe     *bool   `json:"disable_default_probe,omitempty"`
 }
 
 // UpdateServicePositionRequest represents the request body for updating canvas position
@@ -5,6 +5,8 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -20,7 +22,7 @@ func TestServiceHandler_CreateService(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewServiceHandler(dbStore, &config.Config{})
+	handler := NewServiceHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project first
 	orgID := "test-org-789"
@@ -98,17 +100,87 @@ func TestServiceHandler_CreateService(t *testing.T) {
 			if w.Code != tt.expectedStatus {
 				t.Errorf("Expected status %d, got %d. Response: %s", tt.expectedStatus, w.Code, w.Body.String())
 			}
+			if w.Code == http.StatusCreated {
+				var created ServiceResponse
+				if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if created.CreatedBy == nil || *created.CreatedBy != "test-user-123" {
+					t.Errorf("Expected created_by test-user-123, got %v", created.CreatedBy)
+				}
+			}
 		})
 	}
 }
 
+func TestServiceHandler_CreateServices(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	handler := NewServiceHandler(dbStore, &config.Config{}, nil)
+
+	orgID := "test-org-batch"
+	project := testutil.NewProject(t, db, func(p *testutil.Project) { p.OrgID = orgID })
+
+	createBatch := func(reqs []CreateServiceRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqs)
+		req, _ := testutil.MockRequestWithURLParamAndAuth(t, "POST", "/v1/click-deploy/projects/"+project.ID.String()+"/services/batch",
+			map[string]string{"id": project.ID.String()}, bytes.NewReader(body), "test-user-123", orgID)
+		w := testutil.MockResponseRecorder()
+		handler.CreateServices(w, req)
+		return w
+	}
+	countServices := func() int {
+		services, err := dbStore.ListServicesByProject(context.Background(), project.ID)
+		if err != nil {
+			t.Fatalf("Failed to list services: %v", err)
+		}
+		return len(services)
+	}
+
+	// One invalid service fails the whole batch
+	w := createBatch([]CreateServiceRequest{
+		{Name: "web", Type: "app"},
+		{Type: "app"},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d. Response: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "[1].name") {
+		t.Errorf("Expected the error to name [1].name, got %s", w.Body.String())
+	}
+	if n := countServices(); n != 0 {
+		t.Errorf("Expected no service to be created, got %d", n)
+	}
+
+	w = createBatch([]CreateServiceRequest{
+		{Name: "web", Type: "app"},
+		{Name: "worker", Type: "app", InstanceSize: "small"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Response: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created []ServiceResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(created) != 2 || created[0].Name != "web" || created[1].Name != "worker" {
+		t.Fatalf("Expected services web and worker in order, got %+v", created)
+	}
+	if n := countServices(); n != 2 {
+		t.Errorf("Expected 2 services, got %d", n)
+	}
+}
+
 func TestServiceHandler_ListServices(t *testing.T) {
 	db, cleanup := testutil.SetupTestDB(t)
 	defer cleanup()
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewServiceHandler(dbStore, &config.Config{})
+	handler := NewServiceHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-101"
@@ -160,13 +232,13 @@ func TestServiceHandler_ListServices(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
 	}
 
-	var services []*store.Service
+	var services ListResponse[*store.Service]
 	if err := json.NewDecoder(w.Body).Decode(&services); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if len(services) != 2 {
-		t.Errorf("Expected 2 services, got %d", len(services))
+	if len(services.Items) != 2 {
+		t.Errorf("Expected 2 services, got %d", len(services.Items))
 	}
 }
 
@@ -176,7 +248,7 @@ func TestServiceHandler_GetService(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewServiceHandler(dbStore, &config.Config{})
+	handler := NewServiceHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-202"
@@ -254,7 +326,7 @@ func TestServiceHandler_UpdateService(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewServiceHandler(dbStore, &config.Config{})
+	handler := NewServiceHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-303"
@@ -320,7 +392,7 @@ func TestServiceHandler_DeleteService(t *testing.T) {
 	testutil.RunMigrations(t, db)
 
 	dbStore := &store.DB{DB: db}
-	handler := NewServiceHandler(dbStore, &config.Config{})
+	handler := NewServiceHandler(dbStore, &config.Config{}, nil)
 
 	// Create a test project
 	orgID := "test-org-404"
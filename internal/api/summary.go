@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+)
+
+// SummaryResponse represents the resource counts of an org for its dashboard
+type SummaryResponse struct {
+	OrgID                 string         `json:"org_id"`
+	Projects              int            `json:"projects"`
+	Services              int            `json:"services"`
+	ServicesByStatus      map[string]int `json:"services_by_status"`
+	Databases             int            `json:"databases"`
+	DatabasesByEngine     map[string]int `json:"databases_by_engine"`
+	Volumes               int            `json:"volumes"`
+	VolumeSizeGB          float64        `json:"volume_size_gb"`
+	CustomDomains         int            `json:"custom_domains"`
+	CustomDomainsByStatus map[string]int `json:"custom_domains_by_status"`
+}
+
+// GetSummary handles GET /summary
+// Returns the counts of the org's projects, services by status, databases by engine,
+// volumes and their total size, and custom domains by status.
+func (h *UsageHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	summary, err := h.store.GetOrgSummary(r.Context(), orgID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SummaryResponse{
+		OrgID:                 orgID,
+		Projects:              summary.Projects,
+		Services:              sumCounts(summary.ServicesByStatus),
+		ServicesByStatus:      summary.ServicesByStatus,
+		Databases:             sumCounts(summary.DatabasesByEngine),
+		DatabasesByEngine:     summary.DatabasesByEngine,
+		Volumes:               summary.Volumes,
+		VolumeSizeGB:          float64(summary.VolumeSizeMB) / 1024,
+		CustomDomains:         sumCounts(summary.CustomDomainsByStatus),
+		CustomDomainsByStatus: summary.CustomDomainsByStatus,
+	})
+}
+
+func sumCounts(counts map[string]int) int {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return total
+}
@@ -0,0 +1,295 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/metrics"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// maxUsagePeriod is the longest period a single usage query may cover
+const maxUsagePeriod = 366 * 24 * time.Hour
+
+// serviceIDLabel is the kube-state-metrics label carrying the zyndra.io/service-id pod label
+const serviceIDLabel = "label_zyndra_io_service_id"
+
+// UsageHandler handles org-wide usage aggregation
+type UsageHandler struct {
+	store      *store.DB
+	config     *config.Config
+	prometheus *metrics.PrometheusClient
+}
+
+// NewUsageHandler creates a new usage handler
+func NewUsageHandler(store *store.DB, cfg *config.Config) *UsageHandler {
+	return &UsageHandler{
+		store:      store,
+		config:     cfg,
+		prometheus: metrics.NewPrometheusClient(cfg.PrometheusURL),
+	}
+}
+
+// RegisterUsageRoutes registers usage routes
+func RegisterUsageRoutes(r chi.Router, db *store.DB, cfg *config.Config) {
+	h := NewUsageHandler(db, cfg)
+
+	r.Get("/usage", h.GetUsage)
+	r.Get("/usage/quota", h.GetQuota)
+	r.Get("/summary", h.GetSummary)
+}
+
+// UsageTotals holds resource-hours consumed over a period
+type UsageTotals struct {
+	CPUCoreHours   float64 `json:"cpu_core_hours"`
+	MemoryGBHours  float64 `json:"memory_gb_hours"`
+	StorageGBHours float64 `json:"storage_gb_hours"`
+}
+
+func (t *UsageTotals) add(o UsageTotals) {
+	t.CPUCoreHours += o.CPUCoreHours
+	t.MemoryGBHours += o.MemoryGBHours
+	t.StorageGBHours += o.StorageGBHours
+}
+
+// ServiceUsage represents usage for a single service
+type ServiceUsage struct {
+	ServiceID   string `json:"service_id"`
+	ServiceName string `json:"service_name"`
+	UsageTotals
+}
+
+// ProjectUsage represents usage for a project and its services
+type ProjectUsage struct {
+	ProjectID   string `json:"project_id"`
+	ProjectName string `json:"project_name"`
+	UsageTotals
+	Services []ServiceUsage `json:"services"`
+}
+
+// UsageResponse represents org usage over a period
+type UsageResponse struct {
+	OrgID string `json:"org_id"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+	UsageTotals
+	Projects []ProjectUsage `json:"projects"`
+	// Compute usage comes from Prometheus; storage is always available
+	ComputeAvailable bool   `json:"compute_available"`
+	ComputeError     string `json:"compute_error,omitempty"`
+}
+
+// GetUsage handles GET /usage?start=&end=
+func (h *UsageHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	start, end, err := parseUsagePeriod(r, time.Now().UTC())
+	if err != nil {
+		WriteError(w, domain.NewValidationError(err.Error()))
+		return
+	}
+
+	projects, err := listOrgProjects(r.Context(), h.store, orgID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	resp := UsageResponse{
+		OrgID:            orgID,
+		Start:            start.Format(time.RFC3339),
+		End:              end.Format(time.RFC3339),
+		Projects:         make([]ProjectUsage, 0, len(projects)),
+		ComputeAvailable: true,
+	}
+
+	compute, err := h.queryComputeUsage(r.Context(), orgID, projects, start, end)
+	if err != nil {
+		log.Printf("Failed to query compute usage for org %s: %v", orgID, err)
+		resp.ComputeAvailable = false
+		resp.ComputeError = err.Error()
+	}
+
+	for _, project := range projects {
+		pu, err := h.projectUsage(r.Context(), project, compute, start, end)
+		if err != nil {
+			WriteError(w, domain.ErrDatabase.WithError(err))
+			return
+		}
+		resp.UsageTotals.add(pu.UsageTotals)
+		resp.Projects = append(resp.Projects, *pu)
+	}
+
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// projectUsage builds the per-service breakdown for a project.
+// Storage not attached to a service only counts towards the project total.
+func (h *UsageHandler) projectUsage(ctx context.Context, project *store.Project, compute map[string]UsageTotals, start, end time.Time) (*ProjectUsage, error) {
+	services, err := h.store.ListServicesByProject(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	volumes, err := h.store.ListVolumesByProject(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	databases, err := h.store.ListDatabasesByProject(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	storageByService := make(map[string]float64)
+	var unattachedStorage float64
+	for _, v := range volumes {
+		// Database volumes are counted through the database's provisioned size
+		if v.VolumeType == "database_auto" {
+			continue
+		}
+		hours := storageGBHours(v.SizeMB, v.CreatedAt, start, end)
+		if v.AttachedToServiceID.Valid {
+			storageByService[v.AttachedToServiceID.String] += hours
+		} else {
+			unattachedStorage += hours
+		}
+	}
+	for _, d := range databases {
+		hours := storageGBHours(d.VolumeSizeMB, d.CreatedAt, start, end)
+		if d.ServiceID.Valid {
+			storageByService[d.ServiceID.String] += hours
+		} else {
+			unattachedStorage += hours
+		}
+	}
+
+	pu := &ProjectUsage{
+		ProjectID:   project.ID.String(),
+		ProjectName: project.Name,
+		Services:    make([]ServiceUsage, 0, len(services)),
+	}
+	pu.StorageGBHours = unattachedStorage
+
+	for _, s := range services {
+		su := ServiceUsage{
+			ServiceID:   s.ID.String(),
+			ServiceName: s.Name,
+			UsageTotals: compute[s.ID.String()],
+		}
+		su.StorageGBHours = storageByService[s.ID.String()]
+		pu.UsageTotals.add(su.UsageTotals)
+		pu.Services = append(pu.Services, su)
+	}
+
+	return pu, nil
+}
+
+// queryComputeUsage returns CPU and memory usage per service ID, summed across
+// the org's project namespaces. Pods are mapped to services via kube_pod_labels,
+// and only pods of namespaces labeled with the org are counted.
+func (h *UsageHandler) queryComputeUsage(ctx context.Context, orgID string, projects []*store.Project, start, end time.Time) (map[string]UsageTotals, error) {
+	usage := make(map[string]UsageTotals)
+	if len(projects) == 0 {
+		return usage, nil
+	}
+
+	namespaces := make([]string, len(projects))
+	for i, p := range projects {
+		namespaces[i] = h.config.K8sNamespacePrefix + p.ID.String()
+	}
+	selector := fmt.Sprintf(`namespace=~"%s"`, strings.Join(namespaces, "|"))
+	window := fmt.Sprintf("%ds", int64(end.Sub(start).Seconds()))
+	podLabels := metrics.ScopeToOrg(fmt.Sprintf(`max by (namespace, pod, %s) (max_over_time(kube_pod_labels{%s}[%s]))`, serviceIDLabel, selector, window), orgID)
+
+	// CPU: total core-seconds consumed over the window
+	cpuQuery := fmt.Sprintf(
+		`sum by (%s) (increase(container_cpu_usage_seconds_total{%s,container!=""}[%s]) * on (namespace, pod) group_left(%s) %s)`,
+		serviceIDLabel, selector, window, serviceIDLabel, podLabels,
+	)
+	cpu, err := h.prometheus.QueryOrg(ctx, orgID, cpuQuery, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range cpu {
+		id := s.Labels[serviceIDLabel]
+		u := usage[id]
+		u.CPUCoreHours = s.Value / 3600
+		usage[id] = u
+	}
+
+	// Memory: working set sampled every minute, so each sample is 1/60 of a byte-hour
+	memQuery := fmt.Sprintf(
+		`sum by (%s) (sum_over_time(container_memory_working_set_bytes{%s,container!=""}[%s:1m]) * on (namespace, pod) group_left(%s) %s)`,
+		serviceIDLabel, selector, window, serviceIDLabel, podLabels,
+	)
+	mem, err := h.prometheus.QueryOrg(ctx, orgID, memQuery, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range mem {
+		id := s.Labels[serviceIDLabel]
+		u := usage[id]
+		u.MemoryGBHours = s.Value / 60 / (1 << 30)
+		usage[id] = u
+	}
+
+	return usage, nil
+}
+
+// parseUsagePeriod parses the start/end query params (RFC3339).
+// Defaults to the start of the current month until now.
+func parseUsagePeriod(r *http.Request, now time.Time) (time.Time, time.Time, error) {
+	end := now
+	if v := r.URL.Query().Get("end"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("end must be an RFC3339 timestamp")
+		}
+		end = t.UTC()
+	}
+	if end.After(now) {
+		end = now
+	}
+
+	start := time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if v := r.URL.Query().Get("start"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("start must be an RFC3339 timestamp")
+		}
+		start = t.UTC()
+	}
+
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start must be before end")
+	}
+	if end.Sub(start) > maxUsagePeriod {
+		return time.Time{}, time.Time{}, fmt.Errorf("period must not exceed 366 days")
+	}
+
+	return start, end, nil
+}
+
+// storageGBHours returns GB-hours for storage provisioned at createdAt, within [start, end]
+func storageGBHours(sizeMB int, createdAt, start, end time.Time) float64 {
+	from := start
+	if createdAt.After(from) {
+		from = createdAt
+	}
+	if !from.Before(end) {
+		return 0
+	}
+	return float64(sizeMB) / 1024 * end.Sub(from).Hours()
+}
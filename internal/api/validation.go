@@ -2,9 +2,16 @@ package api
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
+	"unicode"
 
+	"github.com/intelifox/click-deploy/internal/build"
 	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/git"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/store"
 )
 
 // ValidationError represents a validation error with field details
@@ -36,9 +43,14 @@ func (ve *ValidationErrors) HasErrors() bool {
 	return len(ve.Errors) > 0
 }
 
-// ToAppError converts ValidationErrors to AppError
+// ToAppError converts ValidationErrors to AppError. The message lists every error,
+// and the error's fields hold them one by one for clients to show next to the field.
 func (ve *ValidationErrors) ToAppError() *domain.AppError {
-	return domain.NewValidationError(ve.Error())
+	fields := make([]domain.FieldError, len(ve.Errors))
+	for i, err := range ve.Errors {
+		fields[i] = domain.FieldError{Field: err.Field, Message: err.Message}
+	}
+	return domain.NewValidationError(ve.Error()).WithFields(fields)
 }
 
 // ValidateString validates and sanitizes a string field
@@ -129,6 +141,9 @@ func ValidateUUID(value, fieldName string, required bool) *ValidationErrors {
 	return errors
 }
 
+// projectSlugPattern matches slugs such as "my-project-2"
+var projectSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
 // ValidateCreateProjectRequest validates CreateProjectRequest
 func ValidateCreateProjectRequest(req *CreateProjectRequest) *ValidationErrors {
 	errors := &ValidationErrors{}
@@ -138,6 +153,13 @@ func ValidateCreateProjectRequest(req *CreateProjectRequest) *ValidationErrors {
 		errors.Errors = append(errors.Errors, nameErrs.Errors...)
 	}
 
+	// Validate slug (optional)
+	if req.Slug != nil && *req.Slug != "" {
+		if len(*req.Slug) > 100 || !projectSlugPattern.MatchString(*req.Slug) {
+			errors.Add("slug", "must be lowercase letters, digits and single hyphens (at most 100 characters)")
+		}
+	}
+
 	// Validate description (optional)
 	if req.Description != nil {
 		if descErrs := ValidateString(*req.Description, "description", false, 0, 1000); descErrs.HasErrors() {
@@ -159,6 +181,9 @@ func ValidateCreateProjectRequest(req *CreateProjectRequest) *ValidationErrors {
 		}
 	}
 
+	validateImageTagStrategy(req.ImageTagStrategy, errors)
+	validateServiceDefaults(req.DefaultInstanceSize, req.DefaultPort, 1, errors)
+
 	return errors
 }
 
@@ -187,9 +212,37 @@ func ValidateUpdateProjectRequest(req *UpdateProjectRequest) *ValidationErrors {
 		}
 	}
 
+	// Validate image retention count (optional)
+	if countErrs := ValidateInt(req.ImageRetentionCount, "image_retention_count", false, 0, 100); countErrs.HasErrors() {
+		errors.Errors = append(errors.Errors, countErrs.Errors...)
+	}
+
+	validateImageTagStrategy(req.ImageTagStrategy, errors)
+	validateServiceDefaults(req.DefaultInstanceSize, req.DefaultPort, 0, errors)
+
 	return errors
 }
 
+// validateServiceDefaults checks a project's optional default instance size and port.
+// minPort is 0 where 0 resets the port to the global default.
+func validateServiceDefaults(size *string, port *int, minPort int, errors *ValidationErrors) {
+	if size != nil && *size != "" {
+		if sizeErrs := ValidateOneOf(*size, "default_instance_size", []string{"small", "medium", "large", "xlarge"}); sizeErrs.HasErrors() {
+			errors.Errors = append(errors.Errors, sizeErrs.Errors...)
+		}
+	}
+	if portErrs := ValidateInt(port, "default_port", false, minPort, 65535); portErrs.HasErrors() {
+		errors.Errors = append(errors.Errors, portErrs.Errors...)
+	}
+}
+
+// validateImageTagStrategy checks an optional image tag strategy against the known ones
+func validateImageTagStrategy(strategy *string, errors *ValidationErrors) {
+	if strategy != nil && *strategy != "" && !build.IsImageTagStrategy(*strategy) {
+		errors.Add("image_tag_strategy", "must be one of: "+strings.Join(build.ImageTagStrategies, ", "))
+	}
+}
+
 // ValidateCreateServiceRequest validates CreateServiceRequest
 func ValidateCreateServiceRequest(req *CreateServiceRequest) *ValidationErrors {
 	errors := &ValidationErrors{}
@@ -256,8 +309,13 @@ func ValidateCreateServiceRequest(req *CreateServiceRequest) *ValidationErrors {
 				Message: "Branch is required",
 			})
 		}
+		errors.Errors = append(errors.Errors, validateTrigger(req.GitSource.TriggerMode, req.GitSource.TagPattern, "git_source.").Errors...)
 	}
 
+	validateCommand(req.BuildCommand, "build_command", errors)
+	validateCommand(req.StartCommand, "start_command", errors)
+	validateHealthCheck(req.HealthCheckPath, req.HealthCheckInitialDelay, errors)
+
 	return errors
 }
 
@@ -301,6 +359,84 @@ func ValidateUpdateServiceRequest(req *UpdateServiceRequest) *ValidationErrors {
 		}
 	}
 
+	// Validate deploy trigger (optional)
+	errors.Errors = append(errors.Errors, validateTrigger(req.TriggerMode, req.TagPattern, "").Errors...)
+
+	// Validate build config (optional, empty clears the command)
+	validateCommand(req.BuildCommand, "build_command", errors)
+	validateCommand(req.StartCommand, "start_command", errors)
+	validateHealthCheck(req.HealthCheckPath, req.HealthCheckInitialDelay, errors)
+
+	return errors
+}
+
+// maxProbeInitialDelay bounds the seconds a service may take to start listening
+const maxProbeInitialDelay = 600
+
+// validateHealthCheck checks an optional health check path, probed over HTTP, and
+// probe initial delay. An empty path is allowed: it selects the TCP probe.
+func validateHealthCheck(path *string, initialDelay *int, errors *ValidationErrors) {
+	if path != nil && *path != "" {
+		switch {
+		case len(*path) > 255:
+			errors.Add("health_check_path", "must be at most 255 characters")
+		case !strings.HasPrefix(*path, "/"):
+			errors.Add("health_check_path", "must start with /")
+		case strings.IndexFunc(*path, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0:
+			errors.Add("health_check_path", "must not contain whitespace or control characters")
+		}
+	}
+
+	// ValidateInt doesn't check a minimum of 0
+	if initialDelay != nil && (*initialDelay < 0 || *initialDelay > maxProbeInitialDelay) {
+		errors.Add("health_check_initial_delay", fmt.Sprintf("must be between 0 and %d seconds", maxProbeInitialDelay))
+	}
+}
+
+// maxCommandLength is the maximum length of a custom build or start command
+const maxCommandLength = 1000
+
+// validateCommand checks an optional build or start command. Commands are written
+// into generated Dockerfiles one line each, so control characters such as null
+// bytes and newlines are rejected; tabs are allowed.
+func validateCommand(command *string, fieldName string, errors *ValidationErrors) {
+	if command == nil {
+		return
+	}
+	if len(*command) > maxCommandLength {
+		errors.Add(fieldName, fmt.Sprintf("must be at most %d characters", maxCommandLength))
+		return
+	}
+	for _, r := range *command {
+		if r == 0 {
+			errors.Add(fieldName, "must not contain null bytes")
+			return
+		}
+		if unicode.IsControl(r) && r != '\t' {
+			errors.Add(fieldName, "must be a single line without control characters")
+			return
+		}
+	}
+}
+
+// validateTrigger validates a git source trigger mode and tag pattern
+func validateTrigger(triggerMode, tagPattern *string, fieldPrefix string) *ValidationErrors {
+	errors := &ValidationErrors{}
+
+	if triggerMode != nil {
+		if modeErrs := ValidateOneOf(*triggerMode, fieldPrefix+"trigger_mode", []string{"branch", "tag"}); modeErrs.HasErrors() {
+			errors.Errors = append(errors.Errors, modeErrs.Errors...)
+		}
+	}
+
+	if tagPattern != nil {
+		if len(*tagPattern) > 255 {
+			errors.Add(fieldPrefix+"tag_pattern", "must be at most 255 characters")
+		} else if err := git.ValidateTagPattern(*tagPattern); err != nil {
+			errors.Add(fieldPrefix+"tag_pattern", "must be a valid glob pattern such as v*")
+		}
+	}
+
 	return errors
 }
 
@@ -321,3 +457,177 @@ func ValidateUpdateServicePositionRequest(req *UpdateServicePositionRequest) *Va
 	return errors
 }
 
+
+// Database volume size bounds in MB
+const (
+	minDatabaseVolumeSizeMB = 100
+	maxDatabaseVolumeSizeMB = 100 * 1024
+)
+
+// databaseVersionPattern matches engine versions such as "16", "8.0" or "7.2.4"
+var databaseVersionPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+){0,2}$`)
+
+// storageClassPattern matches Kubernetes object names (DNS subdomains)
+var storageClassPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// ValidateCreateDatabaseRequest validates CreateDatabaseRequest
+func ValidateCreateDatabaseRequest(req *CreateDatabaseRequest) *ValidationErrors {
+	errors := &ValidationErrors{}
+
+	// Validate engine
+	validEngine := false
+	if req.Engine == "" {
+		errors.Add("engine", "is required")
+	} else if engineErrs := ValidateOneOf(req.Engine, "engine", []string{"postgresql", "mysql", "redis"}); engineErrs.HasErrors() {
+		errors.Errors = append(errors.Errors, engineErrs.Errors...)
+	} else {
+		validEngine = true
+	}
+
+	// Validate version (optional, the engine's default is used when empty)
+	if req.Version != "" && (len(req.Version) > 20 || !databaseVersionPattern.MatchString(req.Version)) {
+		errors.Add("version", "must be a numeric version such as 16 or 8.0")
+	}
+
+	// Validate size (optional)
+	if sizeErrs := ValidateOneOf(req.Size, "size", []string{"small", "medium", "large"}); sizeErrs.HasErrors() {
+		errors.Errors = append(errors.Errors, sizeErrs.Errors...)
+	}
+
+	// Validate volume size (optional, 0 uses the default)
+	if req.VolumeSizeMB != 0 {
+		if volumeErrs := ValidateInt(&req.VolumeSizeMB, "volume_size_mb", false, minDatabaseVolumeSizeMB, maxDatabaseVolumeSizeMB); volumeErrs.HasErrors() {
+			errors.Errors = append(errors.Errors, volumeErrs.Errors...)
+		}
+	}
+
+	// Validate storage class (optional, the cluster default is used when empty)
+	if req.StorageClass != "" && (len(req.StorageClass) > 253 || !storageClassPattern.MatchString(req.StorageClass)) {
+		errors.Add("storage_class", "must be a valid Kubernetes storage class name")
+	}
+
+	// Validate database name and username (optional, the engine's defaults are used when
+	// empty) against the engine's naming rules
+	if req.DatabaseName != "" && validEngine {
+		if err := k8s.ValidateDatabaseName(req.Engine, req.DatabaseName); err != nil {
+			errors.Add("database_name", err.Error())
+		}
+	}
+	if req.Username != "" && validEngine {
+		if err := k8s.ValidateDatabaseUsername(req.Engine, req.Username); err != nil {
+			errors.Add("username", err.Error())
+		}
+	}
+
+	return errors
+}
+
+// ValidateDatabaseParameters checks config overrides against the engine's allowlist
+func ValidateDatabaseParameters(engine string, params map[string]string) *ValidationErrors {
+	errors := &ValidationErrors{}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := k8s.ValidateDatabaseParameter(engine, name, params[name]); err != nil {
+			errors.Add("parameters", err.Error())
+		}
+	}
+
+	return errors
+}
+
+// envVarKeyPattern matches the keys env vars can have, which ${KEY} references in
+// interpolated values can name
+var envVarKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateEnvVarKey validates an env var key. Keys are stored as given, so one that
+// isn't a valid name is rejected rather than rewritten.
+func ValidateEnvVarKey(key string) *ValidationErrors {
+	errors := ValidateString(key, "key", true, 1, 255)
+	if key != "" && !envVarKeyPattern.MatchString(key) {
+		errors.Add("key", "must start with a letter or underscore and contain only letters, digits and underscores")
+	}
+	return errors
+}
+
+// ValidateCreateEnvSchemaRequest validates CreateEnvSchemaRequest
+func ValidateCreateEnvSchemaRequest(req *CreateEnvSchemaRequest) *ValidationErrors {
+	errors := ValidateString(req.Key, "key", true, 1, 255)
+
+	if typeErrs := ValidateOneOf(req.Type, "type", store.EnvSchemaTypes); typeErrs.HasErrors() {
+		errors.Errors = append(errors.Errors, typeErrs.Errors...)
+	}
+
+	if patternErrs := validateEnvSchemaPattern(req.Pattern); patternErrs.HasErrors() {
+		errors.Errors = append(errors.Errors, patternErrs.Errors...)
+	}
+
+	return errors
+}
+
+// maxBuildArgValueLength bounds build arg and secret values, e.g. a registry token
+// or a small key file
+const maxBuildArgValueLength = 32 * 1024
+
+// ValidateCreateBuildArgRequest validates CreateBuildArgRequest
+func ValidateCreateBuildArgRequest(req *CreateBuildArgRequest) *ValidationErrors {
+	errors := ValidateString(req.Key, "key", true, 1, 255)
+
+	if valueErrs := ValidateString(req.Value, "value", true, 1, maxBuildArgValueLength); valueErrs.HasErrors() {
+		errors.Errors = append(errors.Errors, valueErrs.Errors...)
+	}
+
+	return errors
+}
+
+// ValidateUpdateBuildArgRequest validates UpdateBuildArgRequest
+func ValidateUpdateBuildArgRequest(req *UpdateBuildArgRequest) *ValidationErrors {
+	errors := &ValidationErrors{}
+
+	if req.Value != nil {
+		if valueErrs := ValidateString(*req.Value, "value", true, 1, maxBuildArgValueLength); valueErrs.HasErrors() {
+			errors.Errors = append(errors.Errors, valueErrs.Errors...)
+		}
+	}
+
+	return errors
+}
+
+// ValidateUpdateEnvSchemaRequest validates UpdateEnvSchemaRequest
+func ValidateUpdateEnvSchemaRequest(req *UpdateEnvSchemaRequest) *ValidationErrors {
+	errors := &ValidationErrors{}
+
+	if req.Type != nil {
+		if *req.Type == "" {
+			errors.Add("type", "cannot be empty")
+		} else if typeErrs := ValidateOneOf(*req.Type, "type", store.EnvSchemaTypes); typeErrs.HasErrors() {
+			errors.Errors = append(errors.Errors, typeErrs.Errors...)
+		}
+	}
+
+	if req.Pattern != nil {
+		if patternErrs := validateEnvSchemaPattern(*req.Pattern); patternErrs.HasErrors() {
+			errors.Errors = append(errors.Errors, patternErrs.Errors...)
+		}
+	}
+
+	return errors
+}
+
+// validateEnvSchemaPattern checks an optional env schema regex compiles
+func validateEnvSchemaPattern(pattern string) *ValidationErrors {
+	errors := &ValidationErrors{}
+
+	if len(pattern) > 500 {
+		errors.Add("pattern", "must be at most 500 characters")
+	} else if _, err := regexp.Compile(pattern); err != nil {
+		errors.Add("pattern", "must be a valid regular expression")
+	}
+
+	return errors
+}
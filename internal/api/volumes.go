@@ -65,8 +65,7 @@ func (h *VolumeHandler) CreateVolume(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Project not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Project") {
 		return
 	}
 
@@ -76,6 +75,8 @@ func (h *VolumeHandler) CreateVolume(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	req.Name = SanitizeName(req.Name)
+	req.MountPath = SanitizeName(req.MountPath)
 
 	// Validation
 	if req.Name == "" {
@@ -94,14 +95,18 @@ func (h *VolumeHandler) CreateVolume(w http.ResponseWriter, r *http.Request) {
 		SizeMB:     req.SizeMB,
 		Status:     "pending",
 		VolumeType: "user",
+		CreatedBy:  requestUser(r),
 	}
+	volume.UpdatedBy = volume.CreatedBy
 
 	if req.MountPath != "" {
 		volume.MountPath = sql.NullString{String: req.MountPath, Valid: true}
 	}
 
-	if err := h.store.CreateVolume(r.Context(), volume); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	created := createWithinQuota(w, r, h.store, orgID, QuotaUsage{StorageMB: req.SizeMB}, func(tx *store.QuotaTx) error {
+		return tx.CreateVolume(r.Context(), volume)
+	})
+	if !created {
 		return
 	}
 
@@ -133,8 +138,7 @@ func (h *VolumeHandler) ListVolumes(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Project not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Project") {
 		return
 	}
 
@@ -145,7 +149,7 @@ func (h *VolumeHandler) ListVolumes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(volumes)
+	json.NewEncoder(w).Encode(newListResponse(volumes))
 }
 
 // GetVolume retrieves a volume by ID
@@ -179,8 +183,7 @@ func (h *VolumeHandler) GetVolume(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Volume not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Volume") {
 		return
 	}
 
@@ -225,8 +228,7 @@ func (h *VolumeHandler) AttachVolume(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Volume not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Volume") {
 		return
 	}
 
@@ -250,7 +252,7 @@ func (h *VolumeHandler) AttachVolume(w http.ResponseWriter, r *http.Request) {
 
 	// TODO: Queue attach_volume job
 
-	if err := h.store.AttachVolumeToService(r.Context(), volumeID, req.ServiceID, req.MountPath); err != nil {
+	if err := h.store.AttachVolumeToService(r.Context(), volumeID, req.ServiceID, req.MountPath, auth.GetUserID(r.Context())); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -289,14 +291,13 @@ func (h *VolumeHandler) DetachVolume(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Volume not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Volume") {
 		return
 	}
 
 	// TODO: Queue detach_volume job
 
-	if err := h.store.DetachVolumeFromService(r.Context(), volumeID); err != nil {
+	if err := h.store.DetachVolumeFromService(r.Context(), volumeID, auth.GetUserID(r.Context())); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -335,8 +336,7 @@ func (h *VolumeHandler) DeleteVolume(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if project == nil || !project.BelongsToOrg(orgID) {
-		http.Error(w, "Volume not found", http.StatusNotFound)
+	if !checkOrgAccess(w, project, orgID, "Volume") {
 		return
 	}
 
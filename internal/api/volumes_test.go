@@ -153,13 +153,13 @@ func TestVolumeHandler_ListVolumes(t *testing.T) {
 		t.Errorf("Expected status %d, got %d. Response: %s", http.StatusOK, w.Code, w.Body.String())
 	}
 
-	var volumes []*store.Volume
+	var volumes ListResponse[*store.Volume]
 	if err := json.NewDecoder(w.Body).Decode(&volumes); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if len(volumes) != 2 {
-		t.Errorf("Expected 2 volumes, got %d", len(volumes))
+	if len(volumes.Items) != 2 {
+		t.Errorf("Expected 2 volumes, got %d", len(volumes.Items))
 	}
 }
 
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/auth"
+	"github.com/intelifox/click-deploy/internal/domain"
+	"github.com/intelifox/click-deploy/internal/git"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// RotateWebhookSecretResponse is returned after a git source's webhook secret was rotated.
+// The new secret itself is never returned: it only goes to the git provider.
+type RotateWebhookSecretResponse struct {
+	GitSourceID              string `json:"git_source_id"`
+	RotatedAt                string `json:"rotated_at"`
+	PreviousSecretValidUntil string `json:"previous_secret_valid_until"`
+}
+
+// RotateWebhookSecret handles POST /services/:id/git-source/rotate-webhook-secret
+// The new secret is stored first while the old one stays valid for a grace window,
+// then pushed to the provider's webhook, so deliveries keep verifying throughout.
+func (h *GitHandler) RotateWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgID(r.Context())
+	if orgID == "" {
+		WriteError(w, domain.ErrUnauthorized.WithDetails("Organization ID not found in token"))
+		return
+	}
+
+	serviceID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteError(w, domain.NewInvalidInputError("Invalid service ID"))
+		return
+	}
+
+	service, err := h.store.GetService(r.Context(), serviceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if service == nil {
+		WriteError(w, domain.NewNotFoundError("Service"))
+		return
+	}
+	project, err := h.store.GetProject(r.Context(), service.ProjectID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if !checkOrgAccess(w, project, orgID, "Service") {
+		return
+	}
+
+	gitSource, err := h.store.GetGitSourceByService(r.Context(), serviceID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if gitSource == nil {
+		WriteError(w, domain.NewNotFoundError("Git source"))
+		return
+	}
+	hookID, err := strconv.ParseInt(gitSource.WebhookID.String, 10, 64)
+	if !gitSource.WebhookID.Valid || err != nil {
+		WriteError(w, domain.NewConflictError("No webhook is registered for this git source"))
+		return
+	}
+
+	connection, err := h.store.GetGitConnection(r.Context(), gitSource.GitConnectionID)
+	if err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+	if connection == nil {
+		WriteError(w, domain.NewGitNotConnectedError("The git connection of this git source no longer exists"))
+		return
+	}
+
+	secret, err := git.GenerateWebhookSecret()
+	if err != nil {
+		WriteError(w, domain.ErrInternal.WithError(err))
+		return
+	}
+
+	rotatedAt := time.Now()
+	graceUntil := rotatedAt.Add(h.config.WebhookSecretRotationGrace)
+	if err := h.store.RotateWebhookSecret(r.Context(), gitSource.ID, secret, h.config.WebhookSecret, graceUntil); err != nil {
+		WriteError(w, domain.ErrDatabase.WithError(err))
+		return
+	}
+
+	if err := h.updateProviderWebhook(r.Context(), gitSource, connection, hookID, secret); err != nil {
+		// The provider still signs with the old secret, so put it back
+		if revertErr := h.store.RevertWebhookSecretRotation(r.Context(), gitSource.ID, gitSource.WebhookSecret); revertErr != nil {
+			log.Printf("Failed to revert webhook secret rotation of git source %s: %v", gitSource.ID, revertErr)
+		}
+		WriteError(w, domain.NewAppError(domain.ErrCodeExternalAPI, "Failed to update webhook: "+err.Error(), http.StatusBadGateway))
+		return
+	}
+
+	entry := &store.AuditLogEntry{
+		OrgID:        orgID,
+		ActorID:      store.StringToNullString(auth.GetUserID(r.Context())),
+		Action:       store.AuditActionWebhookSecretRotate,
+		ResourceType: "git_source",
+		ResourceID:   gitSource.ID.String(),
+		Metadata: map[string]interface{}{
+			"service_id":                  serviceID.String(),
+			"webhook_id":                  gitSource.WebhookID.String,
+			"previous_secret_valid_until": graceUntil.UTC().Format(time.RFC3339),
+		},
+	}
+	if err := h.store.CreateAuditLogEntry(r.Context(), entry); err != nil {
+		log.Printf("Failed to write audit log entry %s for git source %s: %v", entry.Action, gitSource.ID, err)
+	}
+
+	WriteJSON(w, http.StatusOK, RotateWebhookSecretResponse{
+		GitSourceID:              gitSource.ID.String(),
+		RotatedAt:                rotatedAt.UTC().Format(time.RFC3339),
+		PreviousSecretValidUntil: graceUntil.UTC().Format(time.RFC3339),
+	})
+}
+
+// updateProviderWebhook points the git source's provider webhook at this server with secret
+func (h *GitHandler) updateProviderWebhook(ctx context.Context, gs *store.GitSource, connection *store.GitConnection, hookID int64, secret string) error {
+	config := &git.WebhookConfig{
+		URL:    fmt.Sprintf("%s/webhooks/%s", strings.TrimSuffix(h.config.BaseURL, "/"), gs.Provider),
+		Secret: secret,
+	}
+
+	switch gs.Provider {
+	case "github":
+		return git.NewGitHubClient(connection.AccessToken).UpdateWebhook(ctx, gs.RepoOwner, gs.RepoName, hookID, config)
+	case "gitlab":
+		return git.NewGitLabClient(connection.AccessToken, h.config.GitLabBaseURL).UpdateWebhook(ctx, gs.RepoOwner, gs.RepoName, hookID, config)
+	default:
+		return fmt.Errorf("unsupported provider: %s", gs.Provider)
+	}
+}
@@ -2,39 +2,67 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"github.com/intelifox/click-deploy/internal/config"
 	"github.com/intelifox/click-deploy/internal/git"
+	"github.com/intelifox/click-deploy/internal/k8s"
 	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/worker"
 )
 
+// zeroSHA is the "after" SHA of a push that deleted a branch or tag
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// webhookDedupeWindow is how long a webhook deploy of a commit still in progress makes
+// further webhooks for the same commit and service duplicates
+const webhookDedupeWindow = 10 * time.Minute
+
 type WebhookHandler struct {
-	store  *store.DB
-	config *config.Config
+	store       *store.DB
+	config      *config.Config
+	buildWorker *worker.BuildWorker
+	k8sWorker   *worker.K8sDeployWorker
+
+	// deployMu serializes checking for a duplicate and creating the deployment, so
+	// concurrent retries of a webhook can't both deploy
+	deployMu sync.Mutex
 }
 
-func NewWebhookHandler(store *store.DB, cfg *config.Config) *WebhookHandler {
+func NewWebhookHandler(store *store.DB, cfg *config.Config, buildWorker *worker.BuildWorker, k8sClients *k8s.ClientRegistry) *WebhookHandler {
+	var k8sWorker *worker.K8sDeployWorker
+	if k8sClients != nil {
+		k8sWorker = worker.NewK8sDeployWorker(store, cfg, k8sClients)
+	}
+
 	return &WebhookHandler{
-		store:  store,
-		config: cfg,
+		store:       store,
+		config:      cfg,
+		buildWorker: buildWorker,
+		k8sWorker:   k8sWorker,
 	}
 }
 
 // RegisterWebhookRoutes registers webhook routes
-func RegisterWebhookRoutes(r chi.Router, db *store.DB, cfg *config.Config) {
-	h := NewWebhookHandler(db, cfg)
+func RegisterWebhookRoutes(r chi.Router, db *store.DB, cfg *config.Config, buildWorker *worker.BuildWorker, k8sClients *k8s.ClientRegistry) {
+	h := NewWebhookHandler(db, cfg, buildWorker, k8sClients)
 
 	// Webhook endpoints (public, but validated via signature)
-	r.Post("/webhooks/github", h.HandleGitHubWebhook)
-	r.Post("/webhooks/gitlab", h.HandleGitLabWebhook)
+	r.Group(func(r chi.Router) {
+		r.Use(MaxBodySizeMiddleware(cfg.MaxWebhookBodyBytes))
+		r.Post("/webhooks/github", h.HandleGitHubWebhook)
+		r.Post("/webhooks/gitlab", h.HandleGitLabWebhook)
+	})
 }
 
 // HandleGitHubWebhook handles GitHub webhook events
@@ -54,8 +82,21 @@ func (h *WebhookHandler) HandleGitHubWebhook(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Validate signature
-	if !git.ValidateGitHubWebhookSignature(h.config.WebhookSecret, payload, signature) {
+	// Validate signature against the secrets of the repository's git sources
+	var delivery struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}
+	json.Unmarshal(payload, &delivery)
+	sources, ok, err := h.authenticateWebhook(r.Context(), "github", delivery.Repository.FullName, func(secret string) bool {
+		return git.ValidateGitHubWebhookSignature(secret, payload, signature)
+	})
+	if err != nil {
+		http.Error(w, "Failed to verify signature", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
 		http.Error(w, "Invalid signature", http.StatusUnauthorized)
 		return
 	}
@@ -89,8 +130,21 @@ func (h *WebhookHandler) HandleGitHubWebhook(w http.ResponseWriter, r *http.Requ
 			return
 		}
 
+		// Branch and tag deletions are also delivered as pushes
+		if pushEvent.Deleted || pushEvent.After == zeroSHA {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("OK"))
+			return
+		}
+
+		// For annotated tags "after" is the tag object, so prefer the head commit
+		commitSHA := pushEvent.HeadCommit.ID
+		if commitSHA == "" {
+			commitSHA = pushEvent.After
+		}
+
 		// Find services matching this repository and trigger deployments
-		if err := h.triggerDeploymentsForPush(r.Context(), pushEvent.Repository.FullName, pushEvent.Ref, pushEvent.After, pushEvent.HeadCommit.Message, pushEvent.HeadCommit.Author.Name); err != nil {
+		if err := h.triggerDeploymentsForPush(r.Context(), sources, pushEvent.Repository.FullName, pushEvent.Ref, commitSHA, pushEvent.HeadCommit.Message, pushEvent.HeadCommit.Author.Name); err != nil {
 			log.Printf("Error triggering deployments: %v", err)
 			// Don't fail the webhook, just log
 		}
@@ -120,8 +174,21 @@ func (h *WebhookHandler) HandleGitLabWebhook(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Validate token
-	if !git.ValidateGitLabWebhookSignature(h.config.WebhookSecret, token) {
+	// Validate token against the secrets of the project's git sources
+	var delivery struct {
+		Project struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+		} `json:"project"`
+	}
+	json.Unmarshal(payload, &delivery)
+	sources, ok, err := h.authenticateWebhook(r.Context(), "gitlab", delivery.Project.PathWithNamespace, func(secret string) bool {
+		return git.ValidateGitLabWebhookSignature(secret, token)
+	})
+	if err != nil {
+		http.Error(w, "Failed to verify token", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
 		http.Error(w, "Invalid token", http.StatusUnauthorized)
 		return
 	}
@@ -151,7 +218,36 @@ func (h *WebhookHandler) HandleGitLabWebhook(w http.ResponseWriter, r *http.Requ
 		// Find services matching this repository and trigger deployments
 		if len(pushEvent.Commits) > 0 {
 			lastCommit := pushEvent.Commits[len(pushEvent.Commits)-1]
-			if err := h.triggerDeploymentsForPush(r.Context(), pushEvent.Project.PathWithNamespace, pushEvent.Ref, pushEvent.After, lastCommit.Message, lastCommit.Author.Name); err != nil {
+			if err := h.triggerDeploymentsForPush(r.Context(), sources, pushEvent.Project.PathWithNamespace, pushEvent.Ref, pushEvent.After, lastCommit.Message, lastCommit.Author.Name); err != nil {
+				log.Printf("Error triggering deployments: %v", err)
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+		return
+	}
+
+	// Handle tag push event
+	if event == "Tag Push Hook" {
+		var pushEvent GitLabPushEvent
+		if err := json.Unmarshal(payload, &pushEvent); err != nil {
+			http.Error(w, "Failed to parse payload", http.StatusBadRequest)
+			return
+		}
+
+		// Tag deletions have no checkout SHA
+		if pushEvent.CheckoutSHA != "" && pushEvent.After != zeroSHA {
+			commitMessage := pushEvent.Message
+			var commitAuthor string
+			if len(pushEvent.Commits) > 0 {
+				lastCommit := pushEvent.Commits[len(pushEvent.Commits)-1]
+				if commitMessage == "" {
+					commitMessage = lastCommit.Message
+				}
+				commitAuthor = lastCommit.Author.Name
+			}
+			if err := h.triggerDeploymentsForPush(r.Context(), sources, pushEvent.Project.PathWithNamespace, pushEvent.Ref, pushEvent.CheckoutSHA, commitMessage, commitAuthor); err != nil {
 				log.Printf("Error triggering deployments: %v", err)
 			}
 		}
@@ -168,6 +264,7 @@ func (h *WebhookHandler) HandleGitLabWebhook(w http.ResponseWriter, r *http.Requ
 type GitHubPushEvent struct {
 	Ref        string `json:"ref"`
 	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
 	Repository struct {
 		FullName string `json:"full_name"`
 		Owner    struct {
@@ -184,11 +281,13 @@ type GitHubPushEvent struct {
 	} `json:"head_commit"`
 }
 
-// GitLabPushEvent represents a GitLab push webhook event
+// GitLabPushEvent represents a GitLab push or tag push webhook event
 type GitLabPushEvent struct {
-	Ref    string `json:"ref"`
-	After  string `json:"after"`
-	Project struct {
+	Ref         string `json:"ref"`
+	After       string `json:"after"`
+	CheckoutSHA string `json:"checkout_sha"`
+	Message     string `json:"message"` // annotated tag message
+	Project     struct {
 		PathWithNamespace string `json:"path_with_namespace"`
 	} `json:"project"`
 	Commits []struct {
@@ -200,31 +299,153 @@ type GitLabPushEvent struct {
 	} `json:"commits"`
 }
 
-// triggerDeploymentsForPush triggers deployments for services matching the repository
-func (h *WebhookHandler) triggerDeploymentsForPush(ctx context.Context, repoFullName, ref, commitSHA, commitMessage, commitAuthor string) error {
+// authenticateWebhook verifies a delivery for a repository and returns the repository's
+// git sources it is authenticated for. Sources with their own webhook secret verify
+// against it (or, during a rotation's grace window, the secret it replaced); the others
+// against the global webhook secret. ok is false when the delivery matches no secret.
+func (h *WebhookHandler) authenticateWebhook(ctx context.Context, provider, repoFullName string, verify func(secret string) bool) (sources []*store.GitSource, ok bool, err error) {
+	globalOK := verify(h.config.WebhookSecret)
+
+	repoSources, err := h.listRepoSources(ctx, provider, repoFullName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	for _, gs := range repoSources {
+		secrets := gs.AcceptedWebhookSecrets(now)
+		if len(secrets) == 0 {
+			if globalOK {
+				sources = append(sources, gs)
+			}
+			continue
+		}
+		for _, secret := range secrets {
+			if verify(secret) {
+				sources = append(sources, gs)
+				break
+			}
+		}
+	}
+
+	return sources, globalOK || len(sources) > 0, nil
+}
+
+// listRepoSources lists the git sources of all services built from a repository ("owner/repo")
+func (h *WebhookHandler) listRepoSources(ctx context.Context, provider, repoFullName string) ([]*store.GitSource, error) {
 	// Extract owner and repo name
 	parts := strings.Split(repoFullName, "/")
 	if len(parts) < 2 {
-		return fmt.Errorf("invalid repository name: %s", repoFullName)
+		return nil, nil
 	}
 	owner := parts[0]
 	repoName := strings.Join(parts[1:], "/")
 
-	// Extract branch from ref (refs/heads/main -> main)
-	branch := strings.TrimPrefix(ref, "refs/heads/")
-
-	// TODO: Find all git sources matching this repository and branch
-	// This requires a query like:
-	// SELECT services.* FROM services 
-	// JOIN git_sources ON services.id = git_sources.service_id 
-	// WHERE git_sources.repo_owner = $1 AND git_sources.repo_name = $2 AND git_sources.branch = $3
-	
-	// For now, log the webhook event
-	log.Printf("Webhook push event: repo=%s/%s, branch=%s, commit=%s", owner, repoName, branch, commitSHA)
-	
-	// TODO: Implement service lookup and deployment creation
-	// When implemented, create deployment and queue build job for each matching service
+	sources, err := h.store.ListGitSourcesByRepo(ctx, provider, owner, repoName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list git sources: %w", err)
+	}
+	return sources, nil
+}
+
+// triggerDeploymentsForPush triggers deployments for the authenticated git sources of the pushed repository.
+// Branch-mode sources deploy pushes to their branch; tag-mode sources deploy tags matching their pattern.
+func (h *WebhookHandler) triggerDeploymentsForPush(ctx context.Context, sources []*store.GitSource, repoFullName, ref, commitSHA, commitMessage, commitAuthor string) error {
+	// refs/heads/main -> (branch, main), refs/tags/v1.0.0 -> (tag, v1.0.0)
+	refKind, refName := git.ParseRef(ref)
+	if refKind == "" {
+		return nil
+	}
+
+	log.Printf("Webhook push event: repo=%s, %s=%s, commit=%s", repoFullName, refKind, refName, commitSHA)
+
+	for _, gs := range sources {
+		if !gitSourceMatchesRef(gs, refKind, refName) {
+			continue
+		}
+		if err := h.deployPush(ctx, gs, refKind, refName, commitSHA, commitMessage, commitAuthor); err != nil {
+			log.Printf("Failed to trigger deployment for service %s: %v", gs.ServiceID, err)
+		}
+	}
 
 	return nil
 }
 
+// gitSourceMatchesRef reports whether a push to a ref should deploy a git source
+func gitSourceMatchesRef(gs *store.GitSource, refKind, refName string) bool {
+	if gs.TriggerMode == git.RefKindTag {
+		return refKind == git.RefKindTag && git.MatchTagPattern(gs.TagPattern.String, refName)
+	}
+	return refKind == git.RefKindBranch && refName == gs.Branch
+}
+
+// deployPush creates and queues a deployment for a matching push, or records a
+// pending commit when auto-deploy is disabled for the project
+func (h *WebhookHandler) deployPush(ctx context.Context, gs *store.GitSource, refKind, refName, commitSHA, commitMessage, commitAuthor string) error {
+	service, err := h.store.GetService(ctx, gs.ServiceID)
+	if err != nil {
+		return err
+	}
+	if service == nil {
+		return nil
+	}
+	project, err := h.store.GetProject(ctx, service.ProjectID)
+	if err != nil {
+		return err
+	}
+	if project == nil {
+		return nil
+	}
+
+	if !project.AutoDeploy {
+		return h.store.CreatePendingCommit(ctx, &store.PendingCommit{
+			ServiceID:     service.ID,
+			CommitSHA:     commitSHA,
+			CommitMessage: commitMessage,
+			CommitAuthor:  commitAuthor,
+			Branch:        refName,
+			PushedAt:      time.Now(),
+		})
+	}
+
+	// Providers retry webhooks they think failed: one push deploys a commit once
+	h.deployMu.Lock()
+	defer h.deployMu.Unlock()
+	if commitSHA != "" {
+		existing, err := h.store.GetRecentDeploymentForCommit(ctx, service.ID, commitSHA, webhookDedupeWindow)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			log.Printf("Skipping duplicate webhook deploy of %s to service %s: deployment %s is %s", commitSHA, service.ID, existing.ID, existing.Status)
+			return nil
+		}
+	}
+
+	// Deployments beyond the org's concurrent deployment limit wait for a free slot
+	status, err := newDeploymentStatus(ctx, h.store, h.config, service, project.CasdoorOrgID)
+	if err != nil {
+		return err
+	}
+
+	deployment := &store.Deployment{
+		ServiceID:     service.ID,
+		CommitSHA:     store.StringToNullString(commitSHA),
+		CommitMessage: store.StringToNullString(commitMessage),
+		CommitAuthor:  store.StringToNullString(commitAuthor),
+		Status:        status,
+		TriggeredBy:   "webhook",
+	}
+	if refKind == git.RefKindTag {
+		deployment.GitTag = sql.NullString{String: refName, Valid: true}
+	}
+
+	if err := h.store.CreateDeployment(ctx, deployment); err != nil {
+		return err
+	}
+
+	// Queue build job asynchronously, unless it has to be approved or wait for a slot first
+	startDeployment(ctx, h.store, h.config, h.buildWorker, h.k8sWorker, deployment)
+
+	return nil
+}
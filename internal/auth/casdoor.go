@@ -23,6 +23,8 @@ const (
 	OrgIDKey  ContextKey = "org_id"
 	RolesKey  ContextKey = "roles"
 	NameKey   ContextKey = "name"
+	// SessionIDKey holds the access token's ID, the session of Zyndra tokens
+	SessionIDKey ContextKey = "session_id"
 )
 
 // Validator validates JWT tokens from Casdoor
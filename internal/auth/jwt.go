@@ -56,8 +56,9 @@ type TokenPair struct {
 	TokenType    string    `json:"token_type"`
 }
 
-// GenerateTokenPair generates access and refresh tokens for a user
-func (s *JWTService) GenerateTokenPair(userID, email, name, orgID, orgSlug, role string) (*TokenPair, error) {
+// GenerateTokenPair generates access and refresh tokens for a user. The access
+// token's ID (jti) is the session the refresh token is stored as.
+func (s *JWTService) GenerateTokenPair(userID, email, name, orgID, orgSlug, role, sessionID string) (*TokenPair, error) {
 	now := time.Now()
 	accessExpiry := now.Add(s.config.AccessExpiry)
 	
@@ -75,6 +76,7 @@ func (s *JWTService) GenerateTokenPair(userID, email, name, orgID, orgSlug, role
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    s.config.Issuer,
 			Subject:   userID,
+			ID:        sessionID,
 		},
 	}
 
@@ -39,6 +39,7 @@ func Middleware(validator ValidatorInterface) func(http.Handler) http.Handler {
 			ctx = context.WithValue(ctx, OrgIDKey, claims.Owner)
 			ctx = context.WithValue(ctx, RolesKey, claims.Roles)
 			ctx = context.WithValue(ctx, NameKey, claims.Name)
+			ctx = context.WithValue(ctx, SessionIDKey, claims.ID)
 
 			// Continue with authenticated request
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -80,6 +81,7 @@ func OptionalMiddleware(validator ValidatorInterface) func(http.Handler) http.Ha
 			ctx = context.WithValue(ctx, OrgIDKey, claims.Owner)
 			ctx = context.WithValue(ctx, RolesKey, claims.Roles)
 			ctx = context.WithValue(ctx, NameKey, claims.Name)
+			ctx = context.WithValue(ctx, SessionIDKey, claims.ID)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -118,6 +120,14 @@ func GetUserName(ctx context.Context) string {
 	return ""
 }
 
+// GetSessionID extracts the session of the access token from context
+func GetSessionID(ctx context.Context) string {
+	if sessionID, ok := ctx.Value(SessionIDKey).(string); ok {
+		return sessionID
+	}
+	return ""
+}
+
 // RequireRole creates a middleware that requires a specific role
 func RequireRole(role string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
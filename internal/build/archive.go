@@ -0,0 +1,138 @@
+package build
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrArchiveTooLarge is returned when an archive extracts to more than allowed
+var ErrArchiveTooLarge = errors.New("archive is too large")
+
+// ExtractArchive extracts a .tar.gz source archive into destDir and returns the
+// build context: destDir, or the archive's only top-level directory when it has one,
+// as `tar czf app.tar.gz app/` makes. Entries escaping destDir, symlinks pointing up
+// the tree, hard links and special files are rejected, and extraction stops once
+// maxSize bytes were written (0 for no limit).
+func ExtractArchive(archivePath, destDir string, maxSize int64) (string, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return "", fmt.Errorf("not a gzip archive: %w", err)
+	}
+	defer gz.Close()
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", err
+	}
+
+	var written int64
+	topLevel := map[string]bool{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid archive: %w", err)
+		}
+
+		name, err := archiveEntryPath(hdr.Name)
+		if err != nil {
+			return "", err
+		}
+		if name == "" {
+			continue
+		}
+		topLevel[strings.SplitN(name, "/", 2)[0]] = true
+		target := filepath.Join(destDir, filepath.FromSlash(name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", err
+			}
+		case tar.TypeReg:
+			remaining := int64(-1)
+			if maxSize > 0 {
+				remaining = maxSize - written
+			}
+			n, err := writeArchiveFile(target, tr, hdr.FileInfo().Mode().Perm(), remaining)
+			written += n
+			if err != nil {
+				return "", err
+			}
+		case tar.TypeSymlink:
+			// Links may only point down the tree, so none can lead out of destDir
+			if filepath.IsAbs(hdr.Linkname) || strings.Contains("/"+filepath.ToSlash(hdr.Linkname)+"/", "/../") {
+				return "", fmt.Errorf("archive entry %q links outside the archive", hdr.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return "", err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return "", err
+			}
+		case tar.TypeXGlobalHeader:
+			// PAX metadata, not a file
+		default:
+			return "", fmt.Errorf("archive entry %q: hard links and special files are not supported", hdr.Name)
+		}
+	}
+
+	if len(topLevel) == 1 {
+		for dir := range topLevel {
+			root := filepath.Join(destDir, dir)
+			if info, err := os.Stat(root); err == nil && info.IsDir() {
+				return root, nil
+			}
+		}
+	}
+	return destDir, nil
+}
+
+// archiveEntryPath cleans an archive entry's name into a relative slash path, "" for
+// the archive root, rejecting names that escape it
+func archiveEntryPath(name string) (string, error) {
+	cleaned := filepath.ToSlash(filepath.Clean("/" + name))
+	if strings.HasPrefix(name, "/") || strings.Contains("/"+name+"/", "/../") {
+		return "", fmt.Errorf("archive entry %q escapes the archive", name)
+	}
+	return strings.TrimPrefix(cleaned, "/"), nil
+}
+
+// writeArchiveFile writes one file of an archive, at most limit bytes (-1 for no
+// limit), and returns how many were written
+func writeArchiveFile(target string, r io.Reader, perm os.FileMode, limit int64) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return 0, err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm|0600)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	if limit < 0 {
+		return io.Copy(out, r)
+	}
+	n, err := io.Copy(out, io.LimitReader(r, limit+1))
+	if err != nil {
+		return n, err
+	}
+	if n > limit {
+		return n, ErrArchiveTooLarge
+	}
+	return n, nil
+}
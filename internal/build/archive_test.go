@@ -0,0 +1,119 @@
+package build
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type archiveEntry struct {
+	name     string
+	body     string
+	typeflag byte
+	linkname string
+}
+
+func writeTestArchive(t *testing.T, entries []archiveEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "src.tar.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: e.typeflag, Linkname: e.linkname}
+		if e.typeflag == 0 {
+			hdr.Typeflag = tar.TypeReg
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractArchive(t *testing.T) {
+	t.Run("flat archive", func(t *testing.T) {
+		archive := writeTestArchive(t, []archiveEntry{
+			{name: "Dockerfile", body: "FROM scratch"},
+			{name: "src/main.go", body: "package main"},
+		})
+		dest := t.TempDir()
+
+		contextPath, err := ExtractArchive(archive, dest, 0)
+		if err != nil {
+			t.Fatalf("ExtractArchive error: %v", err)
+		}
+		if contextPath != dest {
+			t.Errorf("context = %s, want %s", contextPath, dest)
+		}
+		if b, _ := os.ReadFile(filepath.Join(dest, "src", "main.go")); string(b) != "package main" {
+			t.Errorf("src/main.go = %q", b)
+		}
+	})
+
+	t.Run("single top-level directory", func(t *testing.T) {
+		archive := writeTestArchive(t, []archiveEntry{
+			{name: "app/", typeflag: tar.TypeDir},
+			{name: "app/Dockerfile", body: "FROM scratch"},
+			{name: "app/current", typeflag: tar.TypeSymlink, linkname: "Dockerfile"},
+		})
+		dest := t.TempDir()
+
+		contextPath, err := ExtractArchive(archive, dest, 0)
+		if err != nil {
+			t.Fatalf("ExtractArchive error: %v", err)
+		}
+		if want := filepath.Join(dest, "app"); contextPath != want {
+			t.Errorf("context = %s, want %s", contextPath, want)
+		}
+	})
+
+	rejected := map[string][]archiveEntry{
+		"path traversal":   {{name: "../evil", body: "x"}},
+		"absolute path":    {{name: "/etc/evil", body: "x"}},
+		"symlink up":       {{name: "link", typeflag: tar.TypeSymlink, linkname: "../../etc"}},
+		"absolute symlink": {{name: "link", typeflag: tar.TypeSymlink, linkname: "/etc"}},
+		"hard link":        {{name: "a", body: "x"}, {name: "b", typeflag: tar.TypeLink, linkname: "a"}},
+	}
+	for name, entries := range rejected {
+		t.Run(name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest")
+			if _, err := ExtractArchive(writeTestArchive(t, entries), dest, 0); err == nil {
+				t.Error("ExtractArchive succeeded, want an error")
+			}
+		})
+	}
+
+	t.Run("size limit", func(t *testing.T) {
+		archive := writeTestArchive(t, []archiveEntry{
+			{name: "a", body: strings.Repeat("x", 60)},
+			{name: "b", body: strings.Repeat("x", 60)},
+		})
+		if _, err := ExtractArchive(archive, t.TempDir(), 100); !errors.Is(err, ErrArchiveTooLarge) {
+			t.Errorf("ExtractArchive error = %v, want ErrArchiveTooLarge", err)
+		}
+	})
+}
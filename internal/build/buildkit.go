@@ -36,7 +36,8 @@ type BuildOptions struct {
 	ContextPath    string            // Path to build context
 	DockerfilePath string            // Path to Dockerfile (default: "Dockerfile")
 	ImageTag       string            // Full image tag (registry/image:tag)
-	BuildArgs      map[string]string // Build arguments
+	BuildArgs      map[string]string // Build arguments, passed as --build-arg
+	Secrets        map[string]string // Build secrets by ID, passed as --secret mounts, never logged
 	RegistryAuth   map[string]AuthConfig // Registry authentication
 	ProgressWriter io.Writer         // Progress output writer
 }
@@ -68,6 +69,13 @@ func (b *BuildKitClient) BuildImage(ctx context.Context, opts BuildOptions) erro
 			fmt.Fprintf(opts.ProgressWriter, "[mock] Starting build for %s\n", opts.ImageTag)
 			fmt.Fprintf(opts.ProgressWriter, "[mock] Using Dockerfile: %s\n", dockerfilePath)
 			fmt.Fprintf(opts.ProgressWriter, "[mock] Context path: %s\n", opts.ContextPath)
+			for key := range opts.BuildArgs {
+				fmt.Fprintf(opts.ProgressWriter, "[mock] Build arg: %s\n", key)
+			}
+			if len(opts.Secrets) > 0 {
+				// Only the number of secrets, neither their IDs nor values
+				fmt.Fprintf(opts.ProgressWriter, "[mock] Mounting %d build secrets\n", len(opts.Secrets))
+			}
 			
 			// Simulate build steps
 			steps := []string{
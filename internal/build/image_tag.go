@@ -0,0 +1,79 @@
+package build
+
+import (
+	"time"
+)
+
+// Image tag strategies, set per project
+const (
+	// ImageTagStrategyCommit tags images with the commit SHA, or <git tag>-<short SHA>
+	// for tag deployments. It is the default.
+	ImageTagStrategyCommit = "commit"
+	// ImageTagStrategyGitSHA tags images git-<short SHA>
+	ImageTagStrategyGitSHA = "git_sha"
+	// ImageTagStrategyBranchTimestamp tags images <branch>-<UTC build time>, e.g. main-20240102150405
+	ImageTagStrategyBranchTimestamp = "branch_timestamp"
+	// ImageTagStrategyGitTag tags images with the git tag as is (e.g. v1.2.0) for tag
+	// deployments, and git-<short SHA> otherwise
+	ImageTagStrategyGitTag = "git_tag"
+)
+
+// ImageTagStrategies lists the valid image tag strategies
+var ImageTagStrategies = []string{
+	ImageTagStrategyCommit,
+	ImageTagStrategyGitSHA,
+	ImageTagStrategyBranchTimestamp,
+	ImageTagStrategyGitTag,
+}
+
+// IsImageTagStrategy reports whether strategy is a valid image tag strategy
+func IsImageTagStrategy(strategy string) bool {
+	for _, s := range ImageTagStrategies {
+		if s == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageTagSource is what an image tag is derived from
+type ImageTagSource struct {
+	CommitSHA string
+	Branch    string
+	GitTag    string // Set for tag deployments
+	BuiltAt   time.Time
+}
+
+// StrategyImageTag returns the image tag (the part after the colon) of a build under
+// strategy. Unknown or empty strategies use ImageTagStrategyCommit.
+func StrategyImageTag(strategy string, src ImageTagSource) string {
+	switch strategy {
+	case ImageTagStrategyGitSHA:
+		return "git-" + shortSHA(src.CommitSHA)
+	case ImageTagStrategyBranchTimestamp:
+		return sanitizeImageTag(src.Branch, "-"+src.BuiltAt.UTC().Format("20060102150405"))
+	case ImageTagStrategyGitTag:
+		if src.GitTag != "" {
+			return sanitizeImageTag(src.GitTag, "")
+		}
+		return "git-" + shortSHA(src.CommitSHA)
+	default:
+		if src.GitTag != "" {
+			return GitTagImageTag(src.GitTag, src.CommitSHA)
+		}
+		return src.CommitSHA
+	}
+}
+
+// UniqueImageTag makes tag unique by appending the UTC build time, for tags that a
+// previous build of the service already used
+func UniqueImageTag(tag string, builtAt time.Time) string {
+	return sanitizeImageTag(tag, "-"+builtAt.UTC().Format("20060102150405"))
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
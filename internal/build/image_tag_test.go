@@ -0,0 +1,85 @@
+package build
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStrategyImageTag(t *testing.T) {
+	sha := "3f2a9c1d0e4b5a6978877665544332211ffeedd0"
+	builtAt := time.Date(2024, 1, 2, 15, 4, 5, 0, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		strategy string
+		src      ImageTagSource
+		want     string
+	}{
+		{ImageTagStrategyCommit, ImageTagSource{CommitSHA: sha}, sha},
+		{"", ImageTagSource{CommitSHA: sha}, sha},
+		{"unknown", ImageTagSource{CommitSHA: sha}, sha},
+		{ImageTagStrategyCommit, ImageTagSource{CommitSHA: sha, GitTag: "v1.2.0"}, "v1.2.0-3f2a9c1"},
+		{ImageTagStrategyGitSHA, ImageTagSource{CommitSHA: sha}, "git-3f2a9c1"},
+		{ImageTagStrategyGitSHA, ImageTagSource{CommitSHA: "abc"}, "git-abc"},
+		{ImageTagStrategyBranchTimestamp, ImageTagSource{Branch: "feature/login", BuiltAt: builtAt}, "feature-login-20240102140405"},
+		{ImageTagStrategyGitTag, ImageTagSource{CommitSHA: sha, GitTag: "v1.0.0"}, "v1.0.0"},
+		{ImageTagStrategyGitTag, ImageTagSource{CommitSHA: sha, GitTag: "release/2024+1"}, "release-2024-1"},
+		{ImageTagStrategyGitTag, ImageTagSource{CommitSHA: sha}, "git-3f2a9c1"},
+	}
+	for _, tt := range tests {
+		if got := StrategyImageTag(tt.strategy, tt.src); got != tt.want {
+			t.Errorf("StrategyImageTag(%q, %+v) = %q, want %q", tt.strategy, tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueImageTag(t *testing.T) {
+	builtAt := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	if got := UniqueImageTag("v1.0.0", builtAt); got != "v1.0.0-20240102150405" {
+		t.Errorf("UniqueImageTag = %q", got)
+	}
+
+	// Kept within the 128 characters of an image tag, the build time intact
+	got := UniqueImageTag(strings.Repeat("a", 200), builtAt)
+	if len(got) != 128 || !strings.HasSuffix(got, "-20240102150405") {
+		t.Errorf("UniqueImageTag of a long tag = %q (%d characters)", got, len(got))
+	}
+}
+
+func TestSanitizeImageTag(t *testing.T) {
+	tests := map[string]string{
+		"v1.0.0":         "v1.0.0",
+		".hidden":        "hidden",
+		"-dash":          "dash",
+		"feat/x y@z":     "feat-x-y-z",
+		"under_score.ok": "under_score.ok",
+	}
+	for tag, want := range tests {
+		if got := sanitizeImageTag(tag, ""); got != want {
+			t.Errorf("sanitizeImageTag(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestIsImageTagStrategy(t *testing.T) {
+	for _, strategy := range ImageTagStrategies {
+		if !IsImageTagStrategy(strategy) {
+			t.Errorf("IsImageTagStrategy(%q) = false", strategy)
+		}
+	}
+	if IsImageTagStrategy("semver") {
+		t.Error("IsImageTagStrategy(semver) = true")
+	}
+}
+
+func TestServiceImageTag(t *testing.T) {
+	// Same-named services of other projects get their own repository
+	a := ServiceImageTag("https://registry.example.com", "web", "11111111-1111-1111-1111-111111111111", "v1.0.0")
+	b := ServiceImageTag("https://registry.example.com", "web", "22222222-2222-2222-2222-222222222222", "v1.0.0")
+	if a != "registry.example.com/web/11111111-1111-1111-1111-111111111111:v1.0.0" {
+		t.Errorf("ServiceImageTag = %q", a)
+	}
+	if a == b {
+		t.Errorf("Services of the same name share image %q", a)
+	}
+}
@@ -2,10 +2,12 @@ package build
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -30,6 +32,7 @@ type RailpackBuildOptions struct {
 	StartCommand   string            // Optional: override start command
 	InstallCommand string            // Optional: override install command
 	BuildArgs      map[string]string // Build arguments
+	Secrets        map[string]string // Build secrets by ID, mounted into RUN steps only
 	EnvVars        map[string]string // Environment variables for build
 }
 
@@ -96,6 +99,7 @@ func (r *RailpackClient) Build(ctx context.Context, opts RailpackBuildOptions) e
 		DockerfilePath: "Dockerfile.railpack",
 		ImageTag:       opts.ImageTag,
 		BuildArgs:      opts.BuildArgs,
+		Secrets:        opts.Secrets,
 	}
 
 	return buildkit.BuildImage(ctx, buildOpts)
@@ -104,26 +108,31 @@ func (r *RailpackClient) Build(ctx context.Context, opts RailpackBuildOptions) e
 // generateDockerfile generates a Dockerfile based on runtime
 func (r *RailpackClient) generateDockerfile(runtime string, opts RailpackBuildOptions) (string, error) {
 	var dockerfile strings.Builder
+	if len(opts.Secrets) > 0 {
+		// Secret mounts exposed as env vars need a recent Dockerfile frontend
+		dockerfile.WriteString("# syntax=docker/dockerfile:1.10\n")
+	}
 
 	switch runtime {
 	case "nodejs":
 		dockerfile.WriteString("FROM node:20-alpine AS builder\n")
 		dockerfile.WriteString("WORKDIR /app\n")
+		dockerfile.WriteString(argInstructions(opts.BuildArgs))
 		dockerfile.WriteString("COPY package*.json ./\n")
 		
 		installCmd := opts.InstallCommand
 		if installCmd == "" {
 			installCmd = "npm ci"
 		}
-		dockerfile.WriteString(fmt.Sprintf("RUN %s\n", installCmd))
+		dockerfile.WriteString(runInstruction(installCmd, opts))
 		
 		dockerfile.WriteString("COPY . .\n")
 		
 		buildCmd := opts.BuildCommand
 		if buildCmd == "" {
-			dockerfile.WriteString("RUN npm run build || true\n")
+			dockerfile.WriteString(runInstruction("npm run build || true", opts))
 		} else {
-			dockerfile.WriteString(fmt.Sprintf("RUN %s\n", buildCmd))
+			dockerfile.WriteString(runInstruction(buildCmd, opts))
 		}
 		
 		dockerfile.WriteString("FROM node:20-alpine\n")
@@ -135,20 +144,21 @@ func (r *RailpackClient) generateDockerfile(runtime string, opts RailpackBuildOp
 		if startCmd == "" {
 			startCmd = "npm start"
 		}
-		dockerfile.WriteString(fmt.Sprintf("CMD [\"sh\", \"-c\", \"%s\"]\n", startCmd))
+		dockerfile.WriteString(cmdInstruction("sh", "-c", startCmd))
 
 	case "go":
 		dockerfile.WriteString("FROM golang:1.22-alpine AS builder\n")
 		dockerfile.WriteString("WORKDIR /app\n")
+		dockerfile.WriteString(argInstructions(opts.BuildArgs))
 		dockerfile.WriteString("COPY go.mod go.sum ./\n")
-		dockerfile.WriteString("RUN go mod download\n")
+		dockerfile.WriteString(runInstruction("go mod download", opts))
 		dockerfile.WriteString("COPY . .\n")
 		
 		buildCmd := opts.BuildCommand
 		if buildCmd == "" {
-			dockerfile.WriteString("RUN go build -o /app/server ./...\n")
+			dockerfile.WriteString(runInstruction("go build -o /app/server ./...", opts))
 		} else {
-			dockerfile.WriteString(fmt.Sprintf("RUN %s\n", buildCmd))
+			dockerfile.WriteString(runInstruction(buildCmd, opts))
 		}
 		
 		dockerfile.WriteString("FROM alpine:latest\n")
@@ -156,28 +166,29 @@ func (r *RailpackClient) generateDockerfile(runtime string, opts RailpackBuildOp
 		dockerfile.WriteString("WORKDIR /app\n")
 		dockerfile.WriteString("COPY --from=builder /app/server .\n")
 		
-		startCmd := opts.StartCommand
-		if startCmd == "" {
-			startCmd = "./server"
+		if opts.StartCommand == "" {
+			dockerfile.WriteString(cmdInstruction("./server"))
+		} else {
+			dockerfile.WriteString(cmdInstruction("sh", "-c", opts.StartCommand))
 		}
-		dockerfile.WriteString(fmt.Sprintf("CMD [\"%s\"]\n", startCmd))
 
 	case "python":
 		dockerfile.WriteString("FROM python:3.11-slim AS builder\n")
 		dockerfile.WriteString("WORKDIR /app\n")
+		dockerfile.WriteString(argInstructions(opts.BuildArgs))
 		dockerfile.WriteString("COPY requirements.txt ./\n")
 		
 		installCmd := opts.InstallCommand
 		if installCmd == "" {
 			installCmd = "pip install --no-cache-dir -r requirements.txt"
 		}
-		dockerfile.WriteString(fmt.Sprintf("RUN %s\n", installCmd))
+		dockerfile.WriteString(runInstruction(installCmd, opts))
 		
 		dockerfile.WriteString("COPY . .\n")
 		
 		buildCmd := opts.BuildCommand
 		if buildCmd != "" {
-			dockerfile.WriteString(fmt.Sprintf("RUN %s\n", buildCmd))
+			dockerfile.WriteString(runInstruction(buildCmd, opts))
 		}
 		
 		dockerfile.WriteString("FROM python:3.11-slim\n")
@@ -189,37 +200,39 @@ func (r *RailpackClient) generateDockerfile(runtime string, opts RailpackBuildOp
 		if startCmd == "" {
 			startCmd = "python app.py"
 		}
-		dockerfile.WriteString(fmt.Sprintf("CMD [\"sh\", \"-c\", \"%s\"]\n", startCmd))
+		dockerfile.WriteString(cmdInstruction("sh", "-c", startCmd))
 
 	case "php":
 		dockerfile.WriteString("FROM php:8.2-fpm-alpine\n")
 		dockerfile.WriteString("WORKDIR /var/www/html\n")
+		dockerfile.WriteString(argInstructions(opts.BuildArgs))
 		dockerfile.WriteString("COPY composer.json composer.lock ./\n")
 		
 		installCmd := opts.InstallCommand
 		if installCmd == "" {
 			installCmd = "composer install --no-dev --optimize-autoloader"
 		}
-		dockerfile.WriteString(fmt.Sprintf("RUN %s\n", installCmd))
+		dockerfile.WriteString(runInstruction(installCmd, opts))
 		
 		dockerfile.WriteString("COPY . .\n")
 		
-		startCmd := opts.StartCommand
-		if startCmd == "" {
-			startCmd = "php-fpm"
+		if opts.StartCommand == "" {
+			dockerfile.WriteString(cmdInstruction("php-fpm"))
+		} else {
+			dockerfile.WriteString(cmdInstruction("sh", "-c", opts.StartCommand))
 		}
-		dockerfile.WriteString(fmt.Sprintf("CMD [\"%s\"]\n", startCmd))
 
 	case "ruby":
 		dockerfile.WriteString("FROM ruby:3.2-alpine\n")
 		dockerfile.WriteString("WORKDIR /app\n")
+		dockerfile.WriteString(argInstructions(opts.BuildArgs))
 		dockerfile.WriteString("COPY Gemfile Gemfile.lock ./\n")
 		
 		installCmd := opts.InstallCommand
 		if installCmd == "" {
 			installCmd = "bundle install"
 		}
-		dockerfile.WriteString(fmt.Sprintf("RUN %s\n", installCmd))
+		dockerfile.WriteString(runInstruction(installCmd, opts))
 		
 		dockerfile.WriteString("COPY . .\n")
 		
@@ -227,7 +240,7 @@ func (r *RailpackClient) generateDockerfile(runtime string, opts RailpackBuildOp
 		if startCmd == "" {
 			startCmd = "bundle exec rails server"
 		}
-		dockerfile.WriteString(fmt.Sprintf("CMD [\"sh\", \"-c\", \"%s\"]\n", startCmd))
+		dockerfile.WriteString(cmdInstruction("sh", "-c", startCmd))
 
 	case "static":
 		dockerfile.WriteString("FROM caddy:2-alpine\n")
@@ -243,6 +256,50 @@ func (r *RailpackClient) generateDockerfile(runtime string, opts RailpackBuildOp
 	return dockerfile.String(), nil
 }
 
+// argInstructions declares the build args in the first stage, so RUN steps see
+// them. ARG values aren't part of the image's env, and the final stage of a
+// multi-stage build doesn't declare them at all.
+func argInstructions(buildArgs map[string]string) string {
+	keys := make([]string, 0, len(buildArgs))
+	for key := range buildArgs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var args strings.Builder
+	for _, key := range keys {
+		args.WriteString(fmt.Sprintf("ARG %s\n", key))
+	}
+	return args.String()
+}
+
+// runInstruction returns the RUN instruction of an install or build step. Each
+// build secret is mounted for the step and exposed as an env var of the same name,
+// it's never written to an image layer.
+func runInstruction(command string, opts RailpackBuildOptions) string {
+	keys := make([]string, 0, len(opts.Secrets))
+	for key := range opts.Secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var run strings.Builder
+	run.WriteString("RUN ")
+	for _, key := range keys {
+		run.WriteString(fmt.Sprintf("--mount=type=secret,id=%s,env=%s ", key, key))
+	}
+	run.WriteString(command)
+	run.WriteString("\n")
+	return run.String()
+}
+
+// cmdInstruction returns the exec form CMD instruction running args. The arguments
+// are JSON encoded, so quotes and backslashes in a custom start command stay intact.
+func cmdInstruction(args ...string) string {
+	encoded, _ := json.Marshal(args)
+	return fmt.Sprintf("CMD %s\n", encoded)
+}
+
 // BuildWithRailpackCLI builds using Railpack CLI if available
 // This is a fallback if we want to use the actual Railpack binary
 func (r *RailpackClient) BuildWithRailpackCLI(ctx context.Context, opts RailpackBuildOptions) error {
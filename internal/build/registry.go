@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // RegistryClient handles container registry operations
@@ -69,23 +70,13 @@ func (r *RegistryClient) VerifyImage(ctx context.Context, imageTag string) error
 
 // GetImageManifest retrieves the manifest for an image
 func (r *RegistryClient) GetImageManifest(ctx context.Context, imageTag string) (map[string]interface{}, error) {
-	// Parse image tag
-	parts := strings.Split(imageTag, "/")
-	if len(parts) < 2 {
-		return nil, fmt.Errorf("invalid image tag format: %s", imageTag)
-	}
-
-	project := parts[0]
-	imageParts := strings.Split(parts[len(parts)-1], ":")
-	repository := imageParts[0]
-	tag := "latest"
-	if len(imageParts) > 1 {
-		tag = imageParts[1]
+	project, repository, tag, err := ParseImageReference(imageTag)
+	if err != nil {
+		return nil, err
 	}
 
 	// Harbor API: GET /api/v2.0/projects/{project_name}/repositories/{repository_name}/artifacts/{reference}
-	apiURL := fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts/%s",
-		r.baseURL, url.PathEscape(project), url.PathEscape(repository), url.PathEscape(tag))
+	apiURL := r.artifactsURL(project, repository) + "/" + url.PathEscape(tag)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
@@ -116,23 +107,18 @@ func (r *RegistryClient) GetImageManifest(ctx context.Context, imageTag string)
 
 // DeleteImage deletes an image from the registry
 func (r *RegistryClient) DeleteImage(ctx context.Context, imageTag string) error {
-	// Parse image tag
-	parts := strings.Split(imageTag, "/")
-	if len(parts) < 2 {
-		return fmt.Errorf("invalid image tag format: %s", imageTag)
+	project, repository, tag, err := ParseImageReference(imageTag)
+	if err != nil {
+		return err
 	}
 
-	project := parts[0]
-	imageParts := strings.Split(parts[len(parts)-1], ":")
-	repository := imageParts[0]
-	tag := "latest"
-	if len(imageParts) > 1 {
-		tag = imageParts[1]
-	}
+	return r.DeleteArtifact(ctx, project, repository, tag)
+}
 
+// DeleteArtifact deletes an artifact (and all of its tags) by tag or digest
+func (r *RegistryClient) DeleteArtifact(ctx context.Context, project, repository, reference string) error {
 	// Harbor API: DELETE /api/v2.0/projects/{project_name}/repositories/{repository_name}/artifacts/{reference}
-	apiURL := fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts/%s",
-		r.baseURL, url.PathEscape(project), url.PathEscape(repository), url.PathEscape(tag))
+	apiURL := r.artifactsURL(project, repository) + "/" + url.PathEscape(reference)
 
 	req, err := http.NewRequestWithContext(ctx, "DELETE", apiURL, nil)
 	if err != nil {
@@ -155,6 +141,104 @@ func (r *RegistryClient) DeleteImage(ctx context.Context, imageTag string) error
 	return nil
 }
 
+// Artifact is an image stored in a registry repository
+type Artifact struct {
+	Digest   string
+	Tags     []string
+	PushedAt time.Time
+}
+
+// ListArtifacts lists all artifacts of a repository with their tags.
+// Returns an empty list if the repository does not exist.
+func (r *RegistryClient) ListArtifacts(ctx context.Context, project, repository string) ([]Artifact, error) {
+	const pageSize = 100
+
+	var artifacts []Artifact
+	for page := 1; ; page++ {
+		// Harbor API: GET /api/v2.0/projects/{project_name}/repositories/{repository_name}/artifacts
+		apiURL := fmt.Sprintf("%s?with_tag=true&page=%d&page_size=%d", r.artifactsURL(project, repository), page, pageSize)
+
+		req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(r.username, r.password)
+
+		resp, err := r.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artifacts: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return artifacts, nil
+		}
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to list artifacts: %d - %s", resp.StatusCode, string(body))
+		}
+
+		var result []struct {
+			Digest   string    `json:"digest"`
+			PushTime time.Time `json:"push_time"`
+			Tags     []struct {
+				Name string `json:"name"`
+			} `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode artifacts: %w", err)
+		}
+
+		for _, a := range result {
+			artifact := Artifact{Digest: a.Digest, PushedAt: a.PushTime}
+			for _, t := range a.Tags {
+				artifact.Tags = append(artifact.Tags, t.Name)
+			}
+			artifacts = append(artifacts, artifact)
+		}
+
+		if len(result) < pageSize {
+			return artifacts, nil
+		}
+	}
+}
+
+// artifactsURL returns the Harbor artifacts endpoint of a repository.
+// Repository names containing slashes must be double-encoded.
+func (r *RegistryClient) artifactsURL(project, repository string) string {
+	return fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts",
+		r.baseURL, url.PathEscape(project), url.PathEscape(url.PathEscape(repository)))
+}
+
+// ParseImageReference splits an image reference such as registry.example.com/project/app:tag
+// into its registry project, repository and tag. The registry host is optional and the tag
+// defaults to latest.
+func ParseImageReference(imageTag string) (project, repository, tag string, err error) {
+	parts := strings.Split(imageTag, "/")
+	// The first component is a registry host if it looks like one
+	if len(parts) > 1 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		parts = parts[1:]
+	}
+	if len(parts) < 2 {
+		return "", "", "", fmt.Errorf("invalid image tag format: %s", imageTag)
+	}
+
+	project = parts[0]
+	repository = strings.Join(parts[1:], "/")
+	tag = "latest"
+	if i := strings.LastIndex(repository, ":"); i >= 0 {
+		repository, tag = repository[:i], repository[i+1:]
+	}
+	if project == "" || repository == "" || tag == "" {
+		return "", "", "", fmt.Errorf("invalid image tag format: %s", imageTag)
+	}
+
+	return project, repository, tag, nil
+}
+
 // GetAuthHeader returns the Authorization header value for registry requests
 func (r *RegistryClient) GetAuthHeader() string {
 	auth := fmt.Sprintf("%s:%s", r.username, r.password)
@@ -175,3 +259,41 @@ func BuildImageTag(registryURL, project, imageName, tag string) string {
 	return fmt.Sprintf("%s/%s/%s:%s", registryHost, project, imageName, tag)
 }
 
+
+// ServiceImageTag returns the image reference of a build of a service. Each service
+// has its own repository, named after its ID: services of the same name in other
+// projects and orgs never share its tags or images.
+func ServiceImageTag(registryURL, serviceName, serviceID, tag string) string {
+	return BuildImageTag(registryURL, serviceName, serviceID, tag)
+}
+
+// GitTagImageTag builds an image tag for a git tag deployment, e.g. v1.2.0-3f2a9c1.
+// Characters not allowed in image tags are replaced and the result is kept within 128 characters.
+func GitTagImageTag(gitTag, commitSHA string) string {
+	suffix := ""
+	if len(commitSHA) >= 7 {
+		suffix = "-" + commitSHA[:7]
+	}
+	return sanitizeImageTag(gitTag, suffix)
+}
+
+// sanitizeImageTag replaces characters not allowed in image tags and trims tag so
+// that tag+suffix stays within 128 characters
+func sanitizeImageTag(tag, suffix string) string {
+	tag = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '-'
+		}
+	}, tag)
+	// Image tags may not start with '.' or '-'
+	tag = strings.TrimLeft(tag, ".-")
+
+	if len(tag)+len(suffix) > 128 {
+		tag = tag[:128-len(suffix)]
+	}
+
+	return tag + suffix
+}
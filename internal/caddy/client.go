@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"time"
 )
 
@@ -13,6 +14,7 @@ import (
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	validator  *DomainValidator
 }
 
 // NewClient creates a new Caddy client
@@ -22,6 +24,7 @@ func NewClient(baseURL string) *Client {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		validator: NewDomainValidator(DefaultValidationTTL),
 	}
 }
 
@@ -35,17 +38,38 @@ type Route struct {
 // MatchRule represents a route match rule
 type MatchRule struct {
 	Host []string `json:"host"`
+	Path []string `json:"path,omitempty"`
 }
 
 // Handle represents a route handler
 type Handle struct {
-	Handler     string                 `json:"handler"`
-	Upstreams   []Upstream             `json:"upstreams,omitempty"`
-	Transport   *Transport             `json:"transport,omitempty"`
-	Routes      []Route                `json:"routes,omitempty"`
-	Headers     map[string]interface{} `json:"headers,omitempty"`
+	Handler        string                 `json:"handler"`
+	Upstreams      []Upstream             `json:"upstreams,omitempty"`
+	Transport      *Transport             `json:"transport,omitempty"`
+	Routes         []Route                `json:"routes,omitempty"`
+	Headers        map[string]interface{} `json:"headers,omitempty"`
+	HandleResponse []ResponseHandler      `json:"handle_response,omitempty"`
+	FlushInterval  int64                  `json:"flush_interval,omitempty"` // -1 flushes every write, i.e. no response buffering
+	// static_response fields
+	StatusCode string `json:"status_code,omitempty"`
+	Body       string `json:"body,omitempty"`
 }
 
+// ResponseHandler handles upstream responses matching a status code in reverse_proxy
+type ResponseHandler struct {
+	Match  *ResponseMatch `json:"match,omitempty"`
+	Routes []Route        `json:"routes,omitempty"`
+}
+
+// ResponseMatch matches upstream responses by status code
+type ResponseMatch struct {
+	StatusCode []int `json:"status_code,omitempty"`
+}
+
+// errorPageStatusCodes are the statuses served with the custom error page,
+// i.e. when the upstream is down or has no ready pods
+var errorPageStatusCodes = []int{http.StatusBadGateway, http.StatusServiceUnavailable}
+
 // Upstream represents an upstream server
 type Upstream struct {
 	Dial string `json:"dial"`
@@ -55,17 +79,95 @@ type Upstream struct {
 type Transport struct {
 	Protocol     string `json:"protocol"`
 	TLSSkipVerify bool  `json:"tls_skip_verify,omitempty"`
+	ReadTimeout  string `json:"read_timeout,omitempty"`
+	WriteTimeout string `json:"write_timeout,omitempty"`
+}
+
+// streamingTimeout is how long a streaming route waits on a quiet upstream
+// connection, long enough for idle WebSockets and server-sent events
+const streamingTimeout = "1h"
+
+// PathRoute sends the requests to a domain whose path starts with PathPrefix to another upstream
+type PathRoute struct {
+	PathPrefix string // e.g. /api, without trailing slash
+	TargetHost string
+	TargetPort int
+	Streaming  bool // Upstream serves WebSockets or streamed responses
 }
 
-// AddRoute adds a route to Caddy for a custom domain
-func (c *Client) AddRoute(ctx context.Context, domain string, targetHost string, targetPort int, enableSSL bool) error {
-	// Construct route configuration
+// AddRoute adds the routes of a custom domain to Caddy, replacing any it already has.
+// Requests matching one of paths go to its upstream, the longest prefix first; all
+// other requests go to targetHost.
+// With streaming, the upstream's WebSocket upgrades are passed on, responses aren't
+// buffered and quiet connections are kept open for streamingTimeout.
+// If errorPageHTML is set, it is served instead of Caddy's default 502/503 response.
+func (c *Client) AddRoute(ctx context.Context, domain string, targetHost string, targetPort int, enableSSL bool, streaming bool, errorPageHTML string, paths ...PathRoute) error {
+	paths = append([]PathRoute(nil), paths...)
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(paths[i].PathPrefix) > len(paths[j].PathPrefix)
+	})
+
+	routes := make([]Route, 0, len(paths)+1)
+	for _, p := range paths {
+		match := MatchRule{
+			Host: []string{domain},
+			// Both, so /api matches /api and /api/users but not /apis
+			Path: []string{p.PathPrefix, p.PathPrefix + "/*"},
+		}
+		routes = append(routes, proxyRoute(match, p.TargetHost, p.TargetPort, p.Streaming, errorPageHTML))
+	}
+	routes = append(routes, proxyRoute(MatchRule{Host: []string{domain}}, targetHost, targetPort, streaming, errorPageHTML))
+
+	// If SSL is enabled, add SSL handler (Caddy will auto-provision certificates)
+	if enableSSL {
+		// Caddy handles SSL automatically via automatic HTTPS
+		// The route will be served over HTTPS by default
+	}
+
+	// POST to Caddy Admin API to add route
+	// URL is constructed in setRoutes
+	
+	// Get existing routes first
+	existingRoutes, err := c.getRoutes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get existing routes: %w", err)
+	}
+
+	// Replace the domain's routes
+	allRoutes := append(filterRoutesByHost(existingRoutes, domain), routes...)
+
+	// Update routes
+	if err := c.setRoutes(ctx, allRoutes); err != nil {
+		return err
+	}
+
+	// Upstream could not be reached at all; Caddy raises the error itself,
+	// so it has to be handled by the server's error routes
+	errorRoutes, err := c.getErrorRoutes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get existing error routes: %w", err)
+	}
+	filteredErrorRoutes := filterRoutesByHost(errorRoutes, domain)
+	if errorPageHTML == "" {
+		// Drop a custom error page the domain had before
+		if len(filteredErrorRoutes) == len(errorRoutes) {
+			return nil
+		}
+		return c.setErrorRoutes(ctx, filteredErrorRoutes)
+	}
+	errorRoutes = append(filteredErrorRoutes, Route{
+		Match:    []MatchRule{{Host: []string{domain}}},
+		Handle:   []Handle{errorPageHandle(errorPageHTML, "{http.error.status_code}")},
+		Terminal: true,
+	})
+
+	return c.setErrorRoutes(ctx, errorRoutes)
+}
+
+// proxyRoute builds a terminal reverse_proxy route to targetHost:targetPort
+func proxyRoute(match MatchRule, targetHost string, targetPort int, streaming bool, errorPageHTML string) Route {
 	route := Route{
-		Match: []MatchRule{
-			{
-				Host: []string{domain},
-			},
-		},
+		Match: []MatchRule{match},
 		Handle: []Handle{
 			{
 				Handler: "reverse_proxy",
@@ -82,26 +184,45 @@ func (c *Client) AddRoute(ctx context.Context, domain string, targetHost string,
 		Terminal: true,
 	}
 
-	// If SSL is enabled, add SSL handler (Caddy will auto-provision certificates)
-	if enableSSL {
-		// Caddy handles SSL automatically via automatic HTTPS
-		// The route will be served over HTTPS by default
+	if streaming {
+		proxy := &route.Handle[0]
+		proxy.FlushInterval = -1
+		proxy.Transport.ReadTimeout = streamingTimeout
+		proxy.Transport.WriteTimeout = streamingTimeout
+		// Hop-by-hop headers are dropped by default, pass the upgrade on explicitly
+		proxy.Headers = map[string]interface{}{
+			"request": map[string]interface{}{
+				"set": map[string][]string{
+					"Connection": {"{http.request.header.Connection}"},
+					"Upgrade":    {"{http.request.header.Upgrade}"},
+				},
+			},
+		}
 	}
 
-	// POST to Caddy Admin API to add route
-	// URL is constructed in setRoutes
-	
-	// Get existing routes first
-	existingRoutes, err := c.getRoutes(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get existing routes: %w", err)
+	if errorPageHTML != "" {
+		// Upstream answered with 502/503 (e.g. ingress with no ready endpoints)
+		route.Handle[0].HandleResponse = []ResponseHandler{
+			{
+				Match:  &ResponseMatch{StatusCode: errorPageStatusCodes},
+				Routes: []Route{{Handle: []Handle{errorPageHandle(errorPageHTML, "{http.reverse_proxy.status_code}")}}},
+			},
+		}
 	}
 
-	// Add new route
-	allRoutes := append(existingRoutes, route)
+	return route
+}
 
-	// Update routes
-	return c.setRoutes(ctx, allRoutes)
+// errorPageHandle builds a static_response handler serving the custom error page
+func errorPageHandle(html string, statusCode string) Handle {
+	return Handle{
+		Handler:    "static_response",
+		StatusCode: statusCode,
+		Body:       html,
+		Headers: map[string]interface{}{
+			"Content-Type": []string{"text/html; charset=utf-8"},
+		},
+	}
 }
 
 // RemoveRoute removes a route from Caddy
@@ -112,7 +233,26 @@ func (c *Client) RemoveRoute(ctx context.Context, domain string) error {
 		return fmt.Errorf("failed to get existing routes: %w", err)
 	}
 
-	// Filter out route matching the domain
+	// Update routes
+	if err := c.setRoutes(ctx, filterRoutesByHost(routes, domain)); err != nil {
+		return err
+	}
+
+	// Drop the domain's custom error page, if any
+	errorRoutes, err := c.getErrorRoutes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get existing error routes: %w", err)
+	}
+	filteredErrorRoutes := filterRoutesByHost(errorRoutes, domain)
+	if len(filteredErrorRoutes) == len(errorRoutes) {
+		return nil
+	}
+
+	return c.setErrorRoutes(ctx, filteredErrorRoutes)
+}
+
+// filterRoutesByHost returns routes without the ones matching the domain
+func filterRoutesByHost(routes []Route, domain string) []Route {
 	filteredRoutes := make([]Route, 0)
 	for _, route := range routes {
 		shouldKeep := true
@@ -131,20 +271,18 @@ func (c *Client) RemoveRoute(ctx context.Context, domain string) error {
 			filteredRoutes = append(filteredRoutes, route)
 		}
 	}
-
-	// Update routes
-	return c.setRoutes(ctx, filteredRoutes)
+	return filteredRoutes
 }
 
 // UpdateRoute updates an existing route
-func (c *Client) UpdateRoute(ctx context.Context, domain string, targetHost string, targetPort int) error {
+func (c *Client) UpdateRoute(ctx context.Context, domain string, targetHost string, targetPort int, streaming bool, errorPageHTML string, paths ...PathRoute) error {
 	// Remove old route
 	if err := c.RemoveRoute(ctx, domain); err != nil {
 		return fmt.Errorf("failed to remove old route: %w", err)
 	}
 
 	// Add new route
-	return c.AddRoute(ctx, domain, targetHost, targetPort, true)
+	return c.AddRoute(ctx, domain, targetHost, targetPort, true, streaming, errorPageHTML, paths...)
 }
 
 // getRoutes gets all routes from Caddy
@@ -206,11 +344,67 @@ func (c *Client) setRoutes(ctx context.Context, routes []Route) error {
 	return nil
 }
 
+// getErrorRoutes gets the server's error routes from Caddy
+func (c *Client) getErrorRoutes(ctx context.Context) ([]Route, error) {
+	url := fmt.Sprintf("%s/config/apps/http/servers/srv0/errors/routes", c.baseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// No error routes configured yet
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest {
+		return []Route{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("caddy API returned status %d", resp.StatusCode)
+	}
+
+	var routes []Route
+	if err := json.NewDecoder(resp.Body).Decode(&routes); err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
+
+// setErrorRoutes replaces the server's error routes in Caddy
+func (c *Client) setErrorRoutes(ctx context.Context, routes []Route) error {
+	url := fmt.Sprintf("%s/config/apps/http/servers/srv0/errors", c.baseURL)
+
+	body, err := json.Marshal(map[string][]Route{"routes": routes})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("caddy API returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
 // ValidateDomain validates if a CNAME record exists for a domain
 func (c *Client) ValidateDomain(ctx context.Context, domain string, expectedTarget string) (bool, error) {
-	// This would typically use DNS lookup to check CNAME
-	// For now, we'll return true (assume valid if user adds it)
-	// In production, implement actual DNS lookup
-	return true, nil
+	return c.validator.Validate(ctx, domain, expectedTarget)
 }
 
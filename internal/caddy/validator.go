@@ -0,0 +1,143 @@
+package caddy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultValidationTTL is how long a domain validation result is reused
+const DefaultValidationTTL = time.Minute
+
+// validationLookupTimeout bounds a shared DNS lookup, which no single caller can cancel
+const validationLookupTimeout = 10 * time.Second
+
+// DomainValidator checks that a custom domain points at its expected target.
+// Results are cached for a short TTL and concurrent lookups for the same
+// domain share a single DNS query.
+type DomainValidator struct {
+	ttl      time.Duration
+	resolver *net.Resolver
+
+	mu    sync.Mutex
+	cache map[string]validationResult
+	group singleflight.Group
+}
+
+type validationResult struct {
+	valid     bool
+	expiresAt time.Time
+}
+
+// NewDomainValidator creates a validator that caches results for ttl
+func NewDomainValidator(ttl time.Duration) *DomainValidator {
+	if ttl <= 0 {
+		ttl = DefaultValidationTTL
+	}
+	return &DomainValidator{
+		ttl:      ttl,
+		resolver: net.DefaultResolver,
+		cache:    make(map[string]validationResult),
+	}
+}
+
+// Validate reports whether domain resolves to expectedTarget, either through a
+// CNAME to the target hostname or, when the target is an IP, an A/AAAA record.
+func (v *DomainValidator) Validate(ctx context.Context, domain, expectedTarget string) (bool, error) {
+	key := normalizeHost(domain) + "|" + normalizeHost(expectedTarget)
+
+	v.mu.Lock()
+	if cached, ok := v.cache[key]; ok && time.Now().Before(cached.expiresAt) {
+		v.mu.Unlock()
+		return cached.valid, nil
+	}
+	v.mu.Unlock()
+
+	results := v.group.DoChan(key, func() (interface{}, error) {
+		// Callers waiting on the same domain share this lookup, so it runs detached
+		// from the first one's context: its cancellation mustn't fail the others
+		lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), validationLookupTimeout)
+		defer cancel()
+
+		valid, err := v.lookup(lookupCtx, domain, expectedTarget)
+		if err != nil {
+			return false, err
+		}
+
+		v.mu.Lock()
+		v.cache[key] = validationResult{valid: valid, expiresAt: time.Now().Add(v.ttl)}
+		v.mu.Unlock()
+
+		return valid, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return false, result.Err
+		}
+		return result.Val.(bool), nil
+	}
+}
+
+// Forget drops any cached result for domain so the next call does a fresh lookup
+func (v *DomainValidator) Forget(domain string) {
+	prefix := normalizeHost(domain) + "|"
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for key := range v.cache {
+		if strings.HasPrefix(key, prefix) {
+			delete(v.cache, key)
+		}
+	}
+}
+
+func (v *DomainValidator) lookup(ctx context.Context, domain, expectedTarget string) (bool, error) {
+	target := normalizeHost(expectedTarget)
+	if target == "" {
+		return false, nil
+	}
+
+	if ip := net.ParseIP(target); ip != nil {
+		addrs, err := v.resolver.LookupHost(ctx, domain)
+		if err != nil {
+			return false, ignoreNotFound(err)
+		}
+		for _, addr := range addrs {
+			if net.ParseIP(addr).Equal(ip) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	cname, err := v.resolver.LookupCNAME(ctx, domain)
+	if err != nil {
+		return false, ignoreNotFound(err)
+	}
+	return normalizeHost(cname) == target, nil
+}
+
+// ignoreNotFound turns "no such host" into a plain negative result; any other
+// resolver failure is returned so callers can tell DNS outages from bad records
+func ignoreNotFound(err error) error {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil
+	}
+	return err
+}
+
+func normalizeHost(host string) string {
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
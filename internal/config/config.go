@@ -1,6 +1,8 @@
 package config
 
 import (
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -11,6 +13,14 @@ type Config struct {
 	// Server
 	Port string `envconfig:"PORT" default:"8080"`
 
+	ReadHeaderTimeout time.Duration `envconfig:"READ_HEADER_TIMEOUT" default:"10s"` // Time allowed to read request headers
+	ReadTimeout       time.Duration `envconfig:"READ_TIMEOUT" default:"60s"`        // Time allowed to read a whole request, body included
+	IdleTimeout       time.Duration `envconfig:"IDLE_TIMEOUT" default:"120s"`       // Keep-alive connections are closed after this long idle
+
+	MaxRequestBodyBytes     int64 `envconfig:"MAX_REQUEST_BODY_BYTES" default:"1048576"`       // Default request body limit (1MB)
+	MaxBulkRequestBodyBytes int64 `envconfig:"MAX_BULK_REQUEST_BODY_BYTES" default:"10485760"` // Routes replacing a whole set at once (10MB)
+	MaxWebhookBodyBytes     int64 `envconfig:"MAX_WEBHOOK_BODY_BYTES" default:"26214400"`      // Git webhook payloads can be large (GitHub caps them at 25MB)
+
 	// Database
 	DatabaseURL string `envconfig:"DATABASE_URL" required:"true"`
 
@@ -24,11 +34,18 @@ type Config struct {
 	InfraServiceAPIKey string `envconfig:"INFRA_SERVICE_API_KEY"`
 	UseMockInfra       bool   `envconfig:"USE_MOCK_INFRA" default:"true"` // Use mock OpenStack client
 
+	OrphanReconcileInterval time.Duration `envconfig:"ORPHAN_RECONCILE_INTERVAL" default:"6h"`   // How often cloud resources are checked for a missing owning record (0 disables)
+	OrphanDeleteGracePeriod time.Duration `envconfig:"ORPHAN_DELETE_GRACE_PERIOD" default:"72h"` // How long a resource stays orphaned before it's deleted
+	OrphanReconcileDryRun   bool          `envconfig:"ORPHAN_RECONCILE_DRY_RUN" default:"true"`  // Only flag orphaned resources, never delete them
+
 	// Registry
 	RegistryURL      string `envconfig:"REGISTRY_URL" required:"true"`
 	RegistryUsername string `envconfig:"REGISTRY_USERNAME" required:"true"`
 	RegistryPassword string `envconfig:"REGISTRY_PASSWORD" required:"true"`
 
+	ImageRetentionCount    int           `envconfig:"IMAGE_RETENTION_COUNT" default:"10"`     // Successful deployment images kept per service unless the project overrides it
+	ImageRetentionInterval time.Duration `envconfig:"IMAGE_RETENTION_INTERVAL" default:"24h"` // How often old images are pruned from the registry (0 disables)
+
 	// GitHub OAuth (legacy)
 	GitHubClientID     string `envconfig:"GITHUB_CLIENT_ID"`
 	GitHubClientSecret string `envconfig:"GITHUB_CLIENT_SECRET"`
@@ -49,12 +66,23 @@ type Config struct {
 	GitLabBaseURL      string `envconfig:"GITLAB_BASE_URL"` // Optional, for self-hosted GitLab
 
 	// Webhook
-	WebhookSecret string `envconfig:"WEBHOOK_SECRET" required:"true"`
-	BaseURL       string `envconfig:"BASE_URL" default:"http://localhost:8080"`
+	WebhookSecret              string        `envconfig:"WEBHOOK_SECRET" required:"true"`
+	WebhookSecretRotationGrace time.Duration `envconfig:"WEBHOOK_SECRET_ROTATION_GRACE" default:"1h"` // How long a rotated-out per-source secret is still accepted
+	BaseURL                    string        `envconfig:"BASE_URL" default:"http://localhost:8080"`
 
 	// BuildKit
 	BuildKitAddress string `envconfig:"BUILDKIT_ADDRESS" default:"unix:///run/buildkit/buildkitd.sock"`
-	BuildDir        string `envconfig:"BUILD_DIR" default:"/tmp/click-deploy-builds"`
+	BuildDir        string `envconfig:"BUILD_DIR" default:"/tmp/click-deploy-builds"` // Also holds uploaded source archives until built; share it between replicas for held deployments
+
+	// Uploaded source archives (POST /services/{id}/deploy-archive)
+	MaxDeployArchiveBytes          int64 `envconfig:"MAX_DEPLOY_ARCHIVE_BYTES" default:"268435456"`            // Largest archive upload (256MB)
+	MaxDeployArchiveExtractedBytes int64 `envconfig:"MAX_DEPLOY_ARCHIVE_EXTRACTED_BYTES" default:"1073741824"` // Most an archive may extract to (1GB)
+
+	// Deploy concurrency (deployments beyond the limit wait for a free slot)
+	MaxConcurrentDeploys     int            `envconfig:"MAX_CONCURRENT_DEPLOYS" default:"5"`                          // In-flight deployments per org (0 = unlimited)
+	PlanMaxConcurrentDeploys map[string]int `envconfig:"PLAN_MAX_CONCURRENT_DEPLOYS" default:"free:2,pro:10,team:25"` // Per-plan overrides, plan:limit pairs
+	MaxDeployWait            time.Duration  `envconfig:"MAX_DEPLOY_WAIT" default:"15m"`                               // Longest ?wait=true deploy requests are held open
+	StaleDeploymentTimeout   time.Duration  `envconfig:"STALE_DEPLOYMENT_TIMEOUT" default:"2h"`                       // In-flight deployments older than this are failed, freeing their slot (0 disables)
 
 	// DNS (for database internal hostnames)
 	DNSZoneID string `envconfig:"DNS_ZONE_ID"` // OpenStack Designate zone ID
@@ -66,6 +94,11 @@ type Config struct {
 	PrometheusURL        string `envconfig:"PROMETHEUS_URL" default:"http://localhost:9090"`
 	PrometheusTargetsDir string `envconfig:"PROMETHEUS_TARGETS_DIR" default:"/tmp/prometheus-targets"`
 
+	// Tracing (OpenTelemetry)
+	OTLPEndpoint       string  `envconfig:"OTLP_ENDPOINT"`                         // OTLP/HTTP collector URL, e.g. http://otel-collector:4318 (empty disables tracing)
+	TracingServiceName string  `envconfig:"TRACING_SERVICE_NAME" default:"zyndra-api"`
+	TracingSampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"` // Fraction of new traces recorded (0-1)
+
 	// Performance
 	DBMaxOpenConns    int `envconfig:"DB_MAX_OPEN_CONNS" default:"25"`
 	DBMaxIdleConns    int `envconfig:"DB_MAX_IDLE_CONNS" default:"5"`
@@ -80,6 +113,7 @@ type Config struct {
 	CentrifugoAPIURL           string `envconfig:"CENTRIFUGO_API_URL"`             // e.g. http://centrifugo:8000/api
 	CentrifugoAPIKey           string `envconfig:"CENTRIFUGO_API_KEY"`             // HTTP API key
 	CentrifugoTokenHMACSecret  string `envconfig:"CENTRIFUGO_TOKEN_HMAC_SECRET"`   // JWT HMAC secret
+	LiveStatusPublishInterval  time.Duration `envconfig:"LIVE_STATUS_PUBLISH_INTERVAL" default:"10s"` // How often live service status is pushed to subscribed projects (0 disables)
 
 	// CORS
 	CORSOrigins string `envconfig:"CORS_ORIGINS" default:"*"` // Comma-separated list of allowed origins
@@ -93,6 +127,27 @@ type Config struct {
 	JWTAccessExpiry  time.Duration `envconfig:"JWT_ACCESS_EXPIRY" default:"15m"`
 	JWTRefreshExpiry time.Duration `envconfig:"JWT_REFRESH_EXPIRY" default:"168h"` // 7 days
 
+	// Login throttling, per email and per client IP
+	LoginMaxFailures   int           `envconfig:"LOGIN_MAX_FAILURES" default:"5"`     // Failed logins within the window that lock out (0 disables)
+	LoginFailureWindow time.Duration `envconfig:"LOGIN_FAILURE_WINDOW" default:"15m"` // Window failed logins are counted in
+	LoginLockoutBase   time.Duration `envconfig:"LOGIN_LOCKOUT_BASE" default:"1m"`    // First lockout, doubled for each one in a row
+	LoginLockoutMax    time.Duration `envconfig:"LOGIN_LOCKOUT_MAX" default:"1h"`     // Longest lockout
+
+	// Proxies in front of the API, as comma-separated IPs or CIDRs. Client IPs are only
+	// read from X-Forwarded-For and X-Real-IP on requests one of them forwarded.
+	TrustedProxies string `envconfig:"TRUSTED_PROXIES"`
+
+	// Encryption at rest of secret values, such as build secrets
+	EncryptionKey string `envconfig:"ENCRYPTION_KEY" default:"change-me-in-production-32-chars"`
+
+	// External secret manager, read at deploy time for env vars that reference it
+	VaultAddr      string        `envconfig:"VAULT_ADDR"`                    // Vault server, e.g. https://vault.example.com:8200 (empty disables Vault references)
+	VaultToken     string        `envconfig:"VAULT_TOKEN"`                   // Token the deploy worker reads secrets with
+	SecretCacheTTL time.Duration `envconfig:"SECRET_CACHE_TTL" default:"1m"` // How long resolved secret values are reused (0 disables caching)
+	// Path each org's references must be below, {org_id} is replaced with the org's ID.
+	// Secrets are read with one token, so this is what keeps orgs out of each other's.
+	VaultOrgPathPrefix string `envconfig:"VAULT_ORG_PATH_PREFIX" default:"secret/data/zyndra/{org_id}"`
+
 	// Kubernetes (k3s)
 	UseK8s            bool   `envconfig:"USE_K8S" default:"false"` // Use k8s instead of OpenStack
 	K8sKubeconfigPath string `envconfig:"K8S_KUBECONFIG_PATH"`     // Path to kubeconfig (empty = auto-detect)
@@ -101,6 +156,19 @@ type Config struct {
 	K8sBaseDomain     string `envconfig:"K8S_BASE_DOMAIN" default:"up.zyndra.app"` // Base domain for generated URLs
 	K8sIngressClass   string `envconfig:"K8S_INGRESS_CLASS" default:"traefik"`
 	K8sCertIssuer     string `envconfig:"K8S_CERT_ISSUER" default:"letsencrypt-prod"`
+	DefaultStorageClass string `envconfig:"DEFAULT_STORAGE_CLASS" default:"longhorn"` // Storage class for database and volume PVCs
+	K8sRegistryMirror   string `envconfig:"K8S_REGISTRY_MIRROR"`                      // Registry Docker Hub images are pulled through (empty = pull directly)
+	K8sDefaultRegion     string            `envconfig:"K8S_DEFAULT_REGION" default:"default"` // Region of the cluster above, used by projects without a region
+	K8sRegionKubeconfigs map[string]string `envconfig:"K8S_REGION_KUBECONFIGS"`               // Clusters of other regions, region:kubeconfig-path pairs
+	ServiceStatusReconcileSchedule string `envconfig:"SERVICE_STATUS_RECONCILE_SCHEDULE" default:"*/5 * * * *"` // Cron expression of when stored service statuses are checked against k8s (empty disables)
+
+	// Domains
+	ReservedDomains       string `envconfig:"RESERVED_DOMAINS" default:"zyndra.app,zyndra.armonika.cloud"`                        // Comma-separated platform domains (and their subdomains) users can't claim
+	ReservedSubdomains    string `envconfig:"RESERVED_SUBDOMAINS" default:"www,api,admin,app,dashboard,ingress,mail,status,docs"` // Comma-separated labels never handed out as generated subdomains
+	BlockedSubdomainWords string `envconfig:"BLOCKED_SUBDOMAIN_WORDS" default:"fuck,shit,cunt,bitch,whore,slut,porn,nazi,rape"`   // Comma-separated offensive words no generated subdomain may contain
+
+	DomainRevalidationInterval time.Duration `envconfig:"DOMAIN_REVALIDATION_INTERVAL" default:"10m"` // How often custom domain DNS is re-checked (0 disables)
+	DomainRevalidationFailures int           `envconfig:"DOMAIN_REVALIDATION_FAILURES" default:"3"`   // Consecutive failed re-checks before a verified or active domain goes back to pending
 
 	// Mailtrap (Email)
 	MailtrapAPIToken   string `envconfig:"MAILTRAP_API_TOKEN"`
@@ -120,3 +188,62 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// ReservedDomainList returns the reserved platform domains, always including
+// the base domain used for generated URLs
+func (c *Config) ReservedDomainList() []string {
+	domains := splitList(c.ReservedDomains)
+	if c.K8sBaseDomain != "" {
+		domains = append(domains, strings.ToLower(c.K8sBaseDomain))
+	}
+	return domains
+}
+
+// ReservedSubdomainList returns the labels that must not be used as generated subdomains
+func (c *Config) ReservedSubdomainList() []string {
+	return splitList(c.ReservedSubdomains)
+}
+
+// BlockedSubdomainWordList returns the words generated subdomains must not contain
+func (c *Config) BlockedSubdomainWordList() []string {
+	return splitList(c.BlockedSubdomainWords)
+}
+
+// TrustedProxyList returns the IPs and CIDRs of the proxies in front of the API
+func (c *Config) TrustedProxyList() []string {
+	return splitList(c.TrustedProxies)
+}
+
+// K8sRegions returns the regions with a configured cluster, default region first
+func (c *Config) K8sRegions() []string {
+	regions := []string{c.K8sDefaultRegion}
+	others := make([]string, 0, len(c.K8sRegionKubeconfigs))
+	for region := range c.K8sRegionKubeconfigs {
+		if region != c.K8sDefaultRegion {
+			others = append(others, region)
+		}
+	}
+	sort.Strings(others)
+	return append(regions, others...)
+}
+
+// MaxConcurrentDeploysForPlan returns how many deployments an org on plan may have
+// in flight at once. 0 means unlimited.
+func (c *Config) MaxConcurrentDeploysForPlan(plan string) int {
+	if limit, ok := c.PlanMaxConcurrentDeploys[plan]; ok {
+		return limit
+	}
+	return c.MaxConcurrentDeploys
+}
+
+// splitList splits a comma-separated env value into trimmed, lowercased, non-empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
@@ -0,0 +1,62 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * * funday",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) error = nil, want error", expr)
+		}
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+
+	// Friday 2024-03-15 19:30 in Paris
+	from := time.Date(2024, 3, 15, 19, 30, 0, 0, paris)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 20 * * 1-5", time.Date(2024, 3, 15, 20, 0, 0, 0, paris)},
+		{"0 8 * * mon-fri", time.Date(2024, 3, 18, 8, 0, 0, 0, paris)},
+		{"*/15 * * * *", time.Date(2024, 3, 15, 19, 45, 0, 0, paris)},
+		{"0 0 1 * *", time.Date(2024, 4, 1, 0, 0, 0, 0, paris)},
+		{"30 9 * * 7", time.Date(2024, 3, 17, 9, 30, 0, 0, paris)},
+		// Either day field matching is enough when both are restricted
+		{"0 12 20 * 6", time.Date(2024, 3, 16, 12, 0, 0, 0, paris)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.expr, err)
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next() = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	never, _ := Parse("0 0 31 2 *")
+	if got := never.Next(from); !got.IsZero() {
+		t.Errorf("Next() of a schedule that never fires = %v, want zero time", got)
+	}
+}
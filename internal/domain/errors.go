@@ -6,7 +6,8 @@ import (
 	"net/http"
 )
 
-// ErrorCode represents different types of errors
+// ErrorCode represents different types of errors. Clients switch on the code, so
+// codes never change once released; ERROR_HANDLING_COMPLETE.md lists them all.
 type ErrorCode string
 
 const (
@@ -27,19 +28,42 @@ const (
 	ErrCodeConflict      ErrorCode = "CONFLICT"
 	ErrCodeAlreadyExists ErrorCode = "ALREADY_EXISTS"
 
+	// Quota errors
+	ErrCodeQuotaExceeded ErrorCode = "QUOTA_EXCEEDED"
+
+	// Domain state errors: the request is valid but the resource isn't in a state
+	// to serve it yet, and the client can tell the user what to do about it
+	ErrCodeGitNotConnected      ErrorCode = "GIT_NOT_CONNECTED"
+	ErrCodeResourceProvisioning ErrorCode = "RESOURCE_PROVISIONING"
+	ErrCodeDeployInProgress     ErrorCode = "DEPLOY_IN_PROGRESS"
+
+	// Request errors
+	ErrCodePayloadTooLarge ErrorCode = "PAYLOAD_TOO_LARGE"
+
 	// Internal errors
 	ErrCodeInternal     ErrorCode = "INTERNAL_ERROR"
 	ErrCodeDatabase     ErrorCode = "DATABASE_ERROR"
 	ErrCodeExternalAPI  ErrorCode = "EXTERNAL_API_ERROR"
+
+	// Transient errors
+	ErrCodeServiceUnavailable ErrorCode = "SERVICE_UNAVAILABLE"
+	ErrCodeGitRateLimited     ErrorCode = "GIT_RATE_LIMITED"
 )
 
 // AppError represents an application error
 type AppError struct {
-	Code       ErrorCode `json:"code"`
-	Message    string    `json:"message"`
-	Details    string    `json:"details,omitempty"`
-	StatusCode int       `json:"-"`
-	Err        error     `json:"-"`
+	Code       ErrorCode    `json:"code"`
+	Message    string       `json:"message"`
+	Details    string       `json:"details,omitempty"`
+	Fields     []FieldError `json:"fields,omitempty"` // Per-field causes of a validation error
+	StatusCode int          `json:"-"`
+	Err        error        `json:"-"`
+}
+
+// FieldError is what is wrong with one field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // Error implements the error interface
@@ -70,6 +94,12 @@ func (e *AppError) WithDetails(details string) *AppError {
 	return e
 }
 
+// WithFields adds the per-field causes of the error
+func (e *AppError) WithFields(fields []FieldError) *AppError {
+	e.Fields = fields
+	return e
+}
+
 // WithError wraps an underlying error
 func (e *AppError) WithError(err error) *AppError {
 	e.Err = err
@@ -99,11 +129,54 @@ func NewNotFoundError(resource string) *AppError {
 	return NewAppError(ErrCodeNotFound, fmt.Sprintf("%s not found", resource), http.StatusNotFound)
 }
 
+// NewForbiddenError creates an error for authenticated callers who aren't allowed to act
+func NewForbiddenError(message string) *AppError {
+	return NewAppError(ErrCodeForbidden, message, http.StatusForbidden)
+}
+
 // NewConflictError creates a conflict error
 func NewConflictError(message string) *AppError {
 	return NewAppError(ErrCodeConflict, message, http.StatusConflict)
 }
 
+// NewQuotaExceededError creates a quota exceeded error
+func NewQuotaExceededError(message string) *AppError {
+	return NewAppError(ErrCodeQuotaExceeded, message, http.StatusPaymentRequired)
+}
+
+// NewGitNotConnectedError creates an error for git operations of an org that has no
+// (more) connection to the git provider
+func NewGitNotConnectedError(message string) *AppError {
+	return NewAppError(ErrCodeGitNotConnected, message, http.StatusConflict)
+}
+
+// NewResourceProvisioningError creates an error for resources that aren't provisioned yet
+func NewResourceProvisioningError(message string) *AppError {
+	return NewAppError(ErrCodeResourceProvisioning, message, http.StatusConflict)
+}
+
+// NewDeployInProgressError creates an error for operations that can't run while a
+// service is being deployed
+func NewDeployInProgressError(message string) *AppError {
+	return NewAppError(ErrCodeDeployInProgress, message, http.StatusConflict)
+}
+
+// NewPayloadTooLargeError creates an error for request bodies over the size limit
+func NewPayloadTooLargeError(message string) *AppError {
+	return NewAppError(ErrCodePayloadTooLarge, message, http.StatusRequestEntityTooLarge)
+}
+
+// NewServiceUnavailableError creates a service unavailable error for transient outages
+func NewServiceUnavailableError(message string) *AppError {
+	return NewAppError(ErrCodeServiceUnavailable, message, http.StatusServiceUnavailable)
+}
+
+// NewGitRateLimitedError creates an error for git operations refused by the provider's
+// rate limit, when there's no cached result to fall back on
+func NewGitRateLimitedError(message string) *AppError {
+	return NewAppError(ErrCodeGitRateLimited, message, http.StatusTooManyRequests)
+}
+
 // NewInvalidInputError creates an invalid input error
 func NewInvalidInputError(message string) *AppError {
 	return NewAppError(ErrCodeInvalidInput, message, http.StatusBadRequest)
@@ -0,0 +1,93 @@
+package email
+
+import (
+	"fmt"
+	"html"
+	"time"
+)
+
+// SignInDetails describes a sign-in for security alert emails
+type SignInDetails struct {
+	IPAddress string
+	UserAgent string
+	Time      time.Time
+}
+
+// SendNewSignInEmail warns a user of a sign-in from an IP or device they haven't
+// signed in from before
+func (c *MailtrapClient) SendNewSignInEmail(to string, signIn SignInDetails) error {
+	subject := "New sign-in to your Zyndra account"
+	details := fmt.Sprintf("Time: %s\nIP address: %s\nDevice: %s",
+		signIn.Time.UTC().Format(time.RFC1123), valueOrUnknown(signIn.IPAddress), valueOrUnknown(signIn.UserAgent))
+
+	text := fmt.Sprintf(`New sign-in to your Zyndra account
+
+Your account was just signed in to from an IP address or device it hasn't been used from before.
+
+%s
+
+If this was you, you can ignore this email. If not, change your password and sign out your other sessions from your account settings.
+`, details)
+
+	return c.SendEmail(to, subject, text, securityAlertHTML("New sign-in to your account",
+		"Your account was just signed in to from an IP address or device it hasn't been used from before.",
+		details,
+		"If this was you, you can ignore this email. If not, change your password and sign out your other sessions from your account settings."),
+		"security-new-sign-in")
+}
+
+// SendAccountLockedEmail warns a user that sign-ins to their account are locked
+// after too many failed attempts
+func (c *MailtrapClient) SendAccountLockedEmail(to string, lockedUntil time.Time, ipAddress string) error {
+	subject := "Sign-ins to your Zyndra account are temporarily locked"
+	details := fmt.Sprintf("Locked until: %s\nLast attempt from: %s",
+		lockedUntil.UTC().Format(time.RFC1123), valueOrUnknown(ipAddress))
+
+	text := fmt.Sprintf(`Sign-ins to your Zyndra account are temporarily locked
+
+There were too many failed sign-in attempts to your account, so sign-ins are locked for a while.
+
+%s
+
+If these attempts weren't yours, someone may be guessing your password. Consider changing it once the lock ends.
+`, details)
+
+	return c.SendEmail(to, subject, text, securityAlertHTML("Sign-ins temporarily locked",
+		"There were too many failed sign-in attempts to your account, so sign-ins are locked for a while.",
+		details,
+		"If these attempts weren't yours, someone may be guessing your password. Consider changing it once the lock ends."),
+		"security-account-locked")
+}
+
+// securityAlertHTML renders a security alert email
+func securityAlertHTML(title, intro, details, advice string) string {
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+</head>
+<body style="margin: 0; padding: 0; font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; background-color: #f8f8f8;">
+  <div style="max-width: 480px; margin: 40px auto; background: white; border-radius: 16px; overflow: hidden; box-shadow: 0 4px 6px rgba(0, 0, 0, 0.05);">
+    <div style="background: linear-gradient(135deg, #4F46E5 0%%, #06B6D4 100%%); padding: 32px; text-align: center;">
+      <h1 style="color: white; margin: 0; font-size: 20px; font-weight: 600;">Zyndra</h1>
+    </div>
+    <div style="padding: 32px;">
+      <h2 style="color: #1f2937; margin: 0 0 8px; font-size: 20px; font-weight: 600;">%s</h2>
+      <p style="color: #6b7280; margin: 0 0 24px; font-size: 14px; line-height: 1.5;">%s</p>
+      <pre style="background: #f3f4f6; border-radius: 12px; padding: 16px; margin: 0 0 24px; font-size: 13px; color: #1f2937; white-space: pre-wrap;">%s</pre>
+      <p style="color: #9ca3af; font-size: 12px; margin: 0;">%s</p>
+    </div>
+  </div>
+</body>
+</html>
+`, html.EscapeString(title), html.EscapeString(intro), html.EscapeString(details), html.EscapeString(advice))
+}
+
+func valueOrUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
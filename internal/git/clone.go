@@ -2,9 +2,13 @@ package git
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/go-git/go-git/v5"
@@ -16,6 +20,7 @@ import (
 type CloneOptions struct {
 	URL      string
 	Branch   string
+	Tag      string // Optional: clone a tag instead of a branch
 	Commit   string // Optional: checkout specific commit
 	Token    string // OAuth token for private repos
 	Provider string // github, gitlab
@@ -29,7 +34,59 @@ type CloneResult struct {
 	ClonedAt   time.Time
 }
 
-// CloneRepository clones a git repository to a temporary directory
+// Default hosts of the providers, used when no base URL is configured
+const (
+	defaultGitHubURL = "https://github.com"
+	defaultGitLabURL = "https://gitlab.com"
+)
+
+// CloneURL returns the HTTPS clone URL of a repository, with token as its credentials
+// when set. baseURL is the provider's web URL for self-managed instances (GitLab's
+// API URL works too), empty for github.com and gitlab.com. The URL holds the token:
+// never log it, log the repository's owner and name instead.
+func CloneURL(provider, baseURL, owner, repo, token string) (string, error) {
+	var username string
+	switch provider {
+	case "github":
+		username = "x-access-token"
+		if baseURL == "" {
+			baseURL = defaultGitHubURL
+		}
+	case "gitlab":
+		username = "oauth2"
+		if baseURL == "" {
+			baseURL = defaultGitLabURL
+		}
+	default:
+		return "", fmt.Errorf("unsupported git provider: %s", provider)
+	}
+
+	u, err := url.Parse(strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/api/v4"))
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid %s base URL: %q", provider, baseURL)
+	}
+	// GitLab subgroups make the owner a path of its own: group/subgroup
+	u.Path = path.Join("/", u.Path, owner, repo+".git")
+	u.RawQuery = ""
+	u.Fragment = ""
+	u.User = nil
+	if token != "" {
+		u.User = url.UserPassword(username, token)
+	}
+	return u.String(), nil
+}
+
+// redactToken removes a token from an error, whose message may quote the clone URL
+func redactToken(err error, token string) error {
+	if err == nil || token == "" || !strings.Contains(err.Error(), token) {
+		return err
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), token, "***"))
+}
+
+// CloneRepository clones a git repository to a temporary directory. Credentials in
+// opts.URL are sent as basic auth: the clone's remote, which may end up in the build
+// context, never holds them.
 func CloneRepository(ctx context.Context, opts CloneOptions, baseDir string) (*CloneResult, error) {
 	// Create a unique directory for this clone
 	dirName := fmt.Sprintf("repo-%d", time.Now().UnixNano())
@@ -41,7 +98,18 @@ func CloneRepository(ctx context.Context, opts CloneOptions, baseDir string) (*C
 
 	// Build auth if token provided
 	var auth *http.BasicAuth
-	if opts.Token != "" {
+	if u, err := url.Parse(opts.URL); err == nil && u.User != nil {
+		password, _ := u.User.Password()
+		auth = &http.BasicAuth{
+			Username: u.User.Username(),
+			Password: password,
+		}
+		if opts.Token == "" {
+			opts.Token = password
+		}
+		u.User = nil
+		opts.URL = u.String()
+	} else if opts.Token != "" {
 		// For GitHub, use token as username (password can be empty)
 		// For GitLab, use oauth2 token
 		username := "oauth2"
@@ -61,8 +129,11 @@ func CloneRepository(ctx context.Context, opts CloneOptions, baseDir string) (*C
 		Auth:     auth,
 	}
 
-	// Set branch if specified
-	if opts.Branch != "" {
+	// Set tag or branch if specified
+	if opts.Tag != "" {
+		cloneOpts.ReferenceName = plumbing.NewTagReferenceName(opts.Tag)
+		cloneOpts.SingleBranch = true
+	} else if opts.Branch != "" {
 		cloneOpts.ReferenceName = plumbing.NewBranchReferenceName(opts.Branch)
 		cloneOpts.SingleBranch = true
 	}
@@ -70,7 +141,7 @@ func CloneRepository(ctx context.Context, opts CloneOptions, baseDir string) (*C
 	// Clone the repository
 	repo, err := git.PlainCloneContext(ctx, clonePath, false, cloneOpts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to clone repository: %w", err)
+		return nil, fmt.Errorf("failed to clone repository: %w", redactToken(err, opts.Token))
 	}
 
 	// Get the HEAD reference
@@ -113,3 +184,40 @@ func CleanupRepository(path string) error {
 	return os.RemoveAll(path)
 }
 
+
+// NormalizeRootDir cleans a monorepo root directory into a repo-relative path.
+// Returns "" for the repository root and an error if it escapes the repository.
+func NormalizeRootDir(rootDir string) (string, error) {
+	rootDir = strings.TrimSpace(rootDir)
+	for _, part := range strings.Split(rootDir, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("root directory must not contain '..'")
+		}
+	}
+	return strings.Trim(path.Clean("/"+rootDir), "/"), nil
+}
+
+// ResolveBuildContext returns the build context directory for rootDir inside a cloned repository
+func ResolveBuildContext(repoPath, rootDir string) (string, error) {
+	dir, err := NormalizeRootDir(rootDir)
+	if err != nil {
+		return "", err
+	}
+	if dir == "" {
+		return repoPath, nil
+	}
+
+	contextPath := filepath.Join(repoPath, filepath.FromSlash(dir))
+	info, err := os.Stat(contextPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("root directory %q does not exist in repository", dir)
+		}
+		return "", fmt.Errorf("failed to stat root directory: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("root directory %q is not a directory", dir)
+	}
+
+	return contextPath, nil
+}
@@ -0,0 +1,36 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"unicode/utf8"
+)
+
+// MaxFileContentSize is the largest file GetFileContent will return
+const MaxFileContentSize = 1024 * 1024
+
+var (
+	// ErrFileTooLarge is returned when a file exceeds MaxFileContentSize
+	ErrFileTooLarge = errors.New("file is too large")
+	// ErrBinaryFile is returned for files that are not valid UTF-8 text
+	ErrBinaryFile = errors.New("file is binary")
+	// ErrNotAFile is returned when the path points to a directory or does not exist
+	ErrNotAFile = errors.New("path is not a file")
+)
+
+// FileContent is the decoded content of a text file in a repository
+type FileContent struct {
+	Path    string
+	Ref     string
+	Size    int64
+	SHA     string
+	Content string
+}
+
+// checkTextContent rejects content that looks binary (NUL bytes or invalid UTF-8)
+func checkTextContent(content []byte) error {
+	if bytes.IndexByte(content, 0) != -1 || !utf8.Valid(content) {
+		return ErrBinaryFile
+	}
+	return nil
+}
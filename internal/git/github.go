@@ -2,7 +2,10 @@ package git
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/google/go-github/v60/github"
 	"golang.org/x/oauth2"
@@ -27,6 +30,36 @@ func NewGitHubClient(token string) *GitHubClient {
 	}
 }
 
+// CheckToken calls GET /user to check the token still works.
+// A 401 is reported as an invalid token rather than an error.
+func (c *GitHubClient) CheckToken(ctx context.Context) (*TokenCheck, error) {
+	user, resp, err := c.client.Users.Get(ctx, "")
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusUnauthorized {
+			return &TokenCheck{Valid: false}, nil
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	check := &TokenCheck{
+		Valid:              true,
+		AccountName:        user.GetLogin(),
+		RateLimitRemaining: resp.Rate.Remaining,
+	}
+	// OAuth tokens list their scopes; GitHub App tokens don't send the header (Scopes stays nil)
+	if _, ok := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]; ok {
+		check.Scopes = []string{}
+		for _, scope := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				check.Scopes = append(check.Scopes, scope)
+			}
+		}
+	}
+
+	return check, nil
+}
+
 // GetUserRepositories lists repositories accessible to the authenticated user
 func (c *GitHubClient) GetUserRepositories(ctx context.Context) ([]*Repository, error) {
 	opt := &github.RepositoryListOptions{
@@ -40,7 +73,7 @@ func (c *GitHubClient) GetUserRepositories(ctx context.Context) ([]*Repository,
 	for {
 		repos, resp, err := c.client.Repositories.List(ctx, "", opt)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list repositories: %w", err)
+			return nil, fmt.Errorf("failed to list repositories: %w", asRateLimitError(err))
 		}
 
 		for _, repo := range repos {
@@ -70,7 +103,7 @@ func (c *GitHubClient) GetUserRepositories(ctx context.Context) ([]*Repository,
 func (c *GitHubClient) GetBranches(ctx context.Context, owner, repo string) ([]*Branch, error) {
 	branches, _, err := c.client.Repositories.ListBranches(ctx, owner, repo, &github.BranchListOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to list branches: %w", err)
+		return nil, fmt.Errorf("failed to list branches: %w", asRateLimitError(err))
 	}
 
 	var result []*Branch
@@ -85,8 +118,10 @@ func (c *GitHubClient) GetBranches(ctx context.Context, owner, repo string) ([]*
 	return result, nil
 }
 
-// GetRepositoryTree gets the directory tree for a repository
-func (c *GitHubClient) GetRepositoryTree(ctx context.Context, owner, repo, branch, path string) ([]*TreeEntry, error) {
+// GetRepositoryTree lists the entries of a directory of a repository, recursively or
+// not, within the limits of opts. GitHub returns at most 100,000 entries (7 MB) of a
+// recursive tree; a tree it truncated is reported as such.
+func (c *GitHubClient) GetRepositoryTree(ctx context.Context, owner, repo, branch string, opts TreeOptions) (*Tree, error) {
 	ref := branch
 	if ref == "" {
 		// Get default branch
@@ -97,30 +132,115 @@ func (c *GitHubClient) GetRepositoryTree(ctx context.Context, owner, repo, branc
 		ref = repository.GetDefaultBranch()
 	}
 
-	tree, _, err := c.client.Git.GetTree(ctx, owner, repo, ref, true)
+	collector := newTreeCollector(opts)
+	sha, err := c.subtreeSHA(ctx, owner, repo, ref, collector.opts.Path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get tree: %w", err)
+		return nil, err
 	}
 
-	var result []*TreeEntry
-	for _, entry := range tree.Entries {
-		// Filter by path if provided
-		if path != "" && path != "/" {
-			if entry.GetPath() != path && !startsWith(entry.GetPath(), path+"/") {
-				continue
-			}
-		}
+	tree, _, err := c.client.Git.GetTree(ctx, owner, repo, sha, opts.Recursive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", asRateLimitError(err))
+	}
+	if tree.GetTruncated() {
+		collector.truncate(TreeTruncatedProvider)
+	}
 
-		result = append(result, &TreeEntry{
-			Path: entry.GetPath(),
+	for _, entry := range tree.Entries {
+		if !collector.add(&TreeEntry{
+			Path: entry.GetPath(), // Relative to the listed tree
 			Type: entry.GetType(), // blob, tree
 			Size: int64(entry.GetSize()),
 			SHA:  entry.GetSHA(),
 			URL:  entry.GetURL(),
-		})
+		}) {
+			break
+		}
 	}
 
-	return result, nil
+	return collector.tree, nil
+}
+
+// subtreeSHA returns the SHA of the tree at path in ref, walking down from the root
+// one directory at a time so only the directories on the way are listed
+func (c *GitHubClient) subtreeSHA(ctx context.Context, owner, repo, ref, path string) (string, error) {
+	sha := ref
+	if path == "" {
+		return sha, nil
+	}
+
+	for _, name := range strings.Split(path, "/") {
+		tree, resp, err := c.client.Git.GetTree(ctx, owner, repo, sha, false)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return "", ErrNotADirectory
+			}
+			return "", fmt.Errorf("failed to get tree: %w", asRateLimitError(err))
+		}
+
+		found := false
+		for _, entry := range tree.Entries {
+			if entry.GetPath() == name && entry.GetType() == "tree" {
+				sha = entry.GetSHA()
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", ErrNotADirectory
+		}
+	}
+
+	return sha, nil
+}
+
+// GetFileContent gets the decoded content of a text file in a repository
+func (c *GitHubClient) GetFileContent(ctx context.Context, owner, repo, branch, path string) (*FileContent, error) {
+	opts := &github.RepositoryContentGetOptions{Ref: branch}
+	file, _, resp, err := c.client.Repositories.GetContents(ctx, owner, repo, path, opts)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotAFile
+		}
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	if file == nil || file.GetType() != "file" {
+		return nil, ErrNotAFile
+	}
+	if file.GetSize() > MaxFileContentSize {
+		return nil, ErrFileTooLarge
+	}
+
+	// Content is base64 encoded
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode file: %w", err)
+	}
+	if err := checkTextContent([]byte(content)); err != nil {
+		return nil, err
+	}
+
+	return &FileContent{
+		Path:    file.GetPath(),
+		Ref:     branch,
+		Size:    int64(file.GetSize()),
+		SHA:     file.GetSHA(),
+		Content: content,
+	}, nil
+}
+
+// DirectoryExists checks that dir is a directory in the repository at branch
+func (c *GitHubClient) DirectoryExists(ctx context.Context, owner, repo, branch, dir string) (bool, error) {
+	opts := &github.RepositoryContentGetOptions{Ref: branch}
+	_, dirContent, resp, err := c.client.Repositories.GetContents(ctx, owner, repo, dir, opts)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get directory: %w", err)
+	}
+	// A nil listing means dir is a file
+	return dirContent != nil, nil
 }
 
 // CreateWebhook creates a webhook for a repository
@@ -152,6 +272,22 @@ func (c *GitHubClient) CreateWebhook(ctx context.Context, owner, repo string, co
 	}, nil
 }
 
+// UpdateWebhook replaces the URL and secret of an existing webhook
+func (c *GitHubClient) UpdateWebhook(ctx context.Context, owner, repo string, hookID int64, config *WebhookConfig) error {
+	hook := &github.Hook{
+		Config: &github.HookConfig{
+			URL:         github.String(config.URL),
+			ContentType: github.String("json"),
+			Secret:      github.String(config.Secret),
+		},
+	}
+
+	if _, _, err := c.client.Repositories.EditHook(ctx, owner, repo, hookID, hook); err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
 // DeleteWebhook deletes a webhook
 func (c *GitHubClient) DeleteWebhook(ctx context.Context, owner, repo string, hookID int64) error {
 	_, err := c.client.Repositories.DeleteHook(ctx, owner, repo, hookID)
@@ -185,6 +321,13 @@ type TreeEntry struct {
 	URL  string
 }
 
+type TokenCheck struct {
+	Valid              bool
+	AccountName        string
+	Scopes             []string // nil if the provider doesn't report scopes
+	RateLimitRemaining int
+}
+
 type WebhookConfig struct {
 	URL    string
 	Secret string
@@ -197,8 +340,3 @@ type Webhook struct {
 	Active bool
 }
 
-// Helper functions
-func startsWith(s, prefix string) bool {
-	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
-}
-
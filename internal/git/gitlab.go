@@ -2,7 +2,12 @@ package git
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/xanzy/go-gitlab"
 )
@@ -22,6 +27,30 @@ func NewGitLabClient(token, baseURL string) *GitLabClient {
 	}
 }
 
+// CheckToken calls GET /user to check the token still works.
+// A 401 is reported as an invalid token rather than an error.
+func (c *GitLabClient) CheckToken(ctx context.Context) (*TokenCheck, error) {
+	user, resp, err := c.client.Users.CurrentUser(gitlab.WithContext(ctx))
+	if err != nil {
+		var errResp *gitlab.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusUnauthorized {
+			return &TokenCheck{Valid: false}, nil
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// GitLab doesn't return the scopes of OAuth tokens on /user
+	check := &TokenCheck{
+		Valid:       true,
+		AccountName: user.Username,
+	}
+	if remaining := resp.Header.Get("RateLimit-Remaining"); remaining != "" {
+		check.RateLimitRemaining, _ = strconv.Atoi(remaining)
+	}
+
+	return check, nil
+}
+
 // GetUserRepositories lists repositories accessible to the authenticated user
 func (c *GitLabClient) GetUserRepositories(ctx context.Context) ([]*Repository, error) {
 	opt := &gitlab.ListProjectsOptions{
@@ -37,7 +66,7 @@ func (c *GitLabClient) GetUserRepositories(ctx context.Context) ([]*Repository,
 	for {
 		projects, resp, err := c.client.Projects.ListProjects(opt, gitlab.WithContext(ctx))
 		if err != nil {
-			return nil, fmt.Errorf("failed to list projects: %w", err)
+			return nil, fmt.Errorf("failed to list projects: %w", asRateLimitError(err))
 		}
 
 		for _, project := range projects {
@@ -70,7 +99,7 @@ func (c *GitLabClient) GetBranches(ctx context.Context, owner, repo string) ([]*
 	projectID := fmt.Sprintf("%s/%s", owner, repo)
 	branches, _, err := c.client.Branches.ListBranches(projectID, &gitlab.ListBranchesOptions{}, gitlab.WithContext(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("failed to list branches: %w", err)
+		return nil, fmt.Errorf("failed to list branches: %w", asRateLimitError(err))
 	}
 
 	var result []*Branch
@@ -85,8 +114,10 @@ func (c *GitLabClient) GetBranches(ctx context.Context, owner, repo string) ([]*
 	return result, nil
 }
 
-// GetRepositoryTree gets the directory tree for a repository
-func (c *GitLabClient) GetRepositoryTree(ctx context.Context, owner, repo, branch, path string) ([]*TreeEntry, error) {
+// GetRepositoryTree lists the entries of a directory of a repository, recursively or
+// not, within the limits of opts. Pages are fetched until MaxEntries is reached, so
+// the rest of a large tree isn't listed.
+func (c *GitLabClient) GetRepositoryTree(ctx context.Context, owner, repo, branch string, opts TreeOptions) (*Tree, error) {
 	projectID := fmt.Sprintf("%s/%s", owner, repo)
 	ref := branch
 	if ref == "" {
@@ -98,28 +129,111 @@ func (c *GitLabClient) GetRepositoryTree(ctx context.Context, owner, repo, branc
 		ref = project.DefaultBranch
 	}
 
+	collector := newTreeCollector(opts)
 	opt := &gitlab.ListTreeOptions{
-		Ref:  gitlab.String(ref),
-		Path: gitlab.String(path),
+		Ref:       gitlab.String(ref),
+		Path:      gitlab.String(collector.opts.Path),
+		Recursive: gitlab.Bool(opts.Recursive),
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+			Page:    1,
+		},
 	}
 
-	tree, _, err := c.client.Repositories.ListTree(projectID, opt, gitlab.WithContext(ctx))
+	for {
+		tree, resp, err := c.client.Repositories.ListTree(projectID, opt, gitlab.WithContext(ctx))
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, ErrNotADirectory
+			}
+			return nil, fmt.Errorf("failed to get tree: %w", asRateLimitError(err))
+		}
+
+		for _, entry := range tree {
+			if !collector.add(&TreeEntry{
+				// GitLab paths start at the repository root
+				Path: strings.TrimPrefix(entry.Path, collector.opts.Path+"/"),
+				Type: entry.Type, // blob, tree
+				Size: 0,          // GitLab doesn't provide size in tree listing
+				SHA:  entry.ID,
+				URL:  "",
+			}) {
+				return collector.tree, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return collector.tree, nil
+}
+
+// GetFileContent gets the decoded content of a text file in a repository
+func (c *GitLabClient) GetFileContent(ctx context.Context, owner, repo, branch, path string) (*FileContent, error) {
+	projectID := fmt.Sprintf("%s/%s", owner, repo)
+	ref := branch
+	if ref == "" {
+		// GitLab requires a ref, use the default branch
+		project, _, err := c.client.Projects.GetProject(projectID, nil, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get project: %w", err)
+		}
+		ref = project.DefaultBranch
+	}
+
+	file, resp, err := c.client.RepositoryFiles.GetFile(projectID, path, &gitlab.GetFileOptions{Ref: gitlab.String(ref)}, gitlab.WithContext(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get tree: %w", err)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, ErrNotAFile
+		}
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	if file.Size > MaxFileContentSize {
+		return nil, ErrFileTooLarge
 	}
 
-	var result []*TreeEntry
-	for _, entry := range tree {
-		result = append(result, &TreeEntry{
-			Path: entry.Path,
-			Type: entry.Type, // blob, tree
-			Size: 0,          // GitLab doesn't provide size in tree listing
-			SHA:  entry.ID,
-			URL:  "",
-		})
+	content := []byte(file.Content)
+	if file.Encoding == "base64" {
+		content, err = base64.StdEncoding.DecodeString(file.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode file: %w", err)
+		}
+	}
+	if err := checkTextContent(content); err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	return &FileContent{
+		Path:    file.FilePath,
+		Ref:     ref,
+		Size:    int64(file.Size),
+		SHA:     file.BlobID,
+		Content: string(content),
+	}, nil
+}
+
+// DirectoryExists checks that dir is a directory in the repository at branch
+func (c *GitLabClient) DirectoryExists(ctx context.Context, owner, repo, branch, dir string) (bool, error) {
+	projectID := fmt.Sprintf("%s/%s", owner, repo)
+	opt := &gitlab.ListTreeOptions{
+		Path: gitlab.String(dir),
+	}
+	if branch != "" {
+		opt.Ref = gitlab.String(branch)
+	}
+
+	tree, resp, err := c.client.Repositories.ListTree(projectID, opt, gitlab.WithContext(ctx))
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get tree: %w", err)
+	}
+	// Listing a file path returns no entries
+	return len(tree) > 0, nil
 }
 
 // CreateWebhook creates a webhook for a repository
@@ -150,6 +264,22 @@ func (c *GitLabClient) CreateWebhook(ctx context.Context, owner, repo string, co
 	}, nil
 }
 
+// UpdateWebhook replaces the URL and secret token of an existing webhook
+func (c *GitLabClient) UpdateWebhook(ctx context.Context, owner, repo string, hookID int64, config *WebhookConfig) error {
+	projectID := fmt.Sprintf("%s/%s", owner, repo)
+	hook := &gitlab.EditProjectHookOptions{
+		URL:                   gitlab.String(config.URL),
+		PushEvents:            gitlab.Bool(true),
+		Token:                 gitlab.String(config.Secret),
+		EnableSSLVerification: gitlab.Bool(true),
+	}
+
+	if _, _, err := c.client.Projects.EditProjectHook(projectID, int(hookID), hook, gitlab.WithContext(ctx)); err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
 // DeleteWebhook deletes a webhook
 func (c *GitLabClient) DeleteWebhook(ctx context.Context, owner, repo string, hookID int64) error {
 	projectID := fmt.Sprintf("%s/%s", owner, repo)
@@ -0,0 +1,195 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// listingStaleFor is how long a listing is kept past its TTL, to be served when the
+// provider's rate limit is used up
+const listingStaleFor = time.Hour
+
+// ListingCache stores cached listings. MemoryCache keeps them in the process; a shared
+// store (e.g. Redis) can take its place when several API instances run.
+type ListingCache interface {
+	// Get returns the entry stored under key, false when there is none
+	Get(ctx context.Context, key string) (*CacheEntry, bool)
+	// Set stores an entry under key for at least retention
+	Set(ctx context.Context, key string, entry *CacheEntry, retention time.Duration)
+}
+
+// CacheEntry is a cached value, JSON-encoded so any store can hold it
+type CacheEntry struct {
+	Data     []byte
+	StoredAt time.Time
+}
+
+// MemoryCache is an in-process ListingCache
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	entry     *CacheEntry
+	expiresAt time.Time
+}
+
+// NewMemoryCache creates an empty in-process cache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryCacheEntry)}
+}
+
+// Get returns the entry stored under key, false when there is none or it expired
+func (c *MemoryCache) Get(ctx context.Context, key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.entry, true
+}
+
+// Set stores an entry under key for retention, dropping the entries that expired
+func (c *MemoryCache) Set(ctx context.Context, key string, entry *CacheEntry, retention time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = memoryCacheEntry{entry: entry, expiresAt: now.Add(retention)}
+}
+
+// CacheStatus tells where a listing came from
+type CacheStatus string
+
+const (
+	CacheHit   CacheStatus = "HIT"   // Cached and still fresh
+	CacheMiss  CacheStatus = "MISS"  // Fetched from the provider
+	CacheStale CacheStatus = "STALE" // Cached past its TTL, the provider is rate limiting
+)
+
+// CachedLister serves repository, branch and tree listings of a git connection from a cache,
+// calling the provider once the cached listing is older than the TTL. While the
+// connection's rate limit is used up the provider isn't called at all: the last
+// listing is served instead, however old.
+type CachedLister struct {
+	cache ListingCache
+	ttl   time.Duration
+}
+
+// NewCachedLister creates a lister caching listings in cache for ttl
+func NewCachedLister(cache ListingCache, ttl time.Duration) *CachedLister {
+	return &CachedLister{cache: cache, ttl: ttl}
+}
+
+// Repositories returns the repositories of a connection, from the cache unless refresh
+// is set or the cached listing is too old, in which case fetch lists them
+func (l *CachedLister) Repositories(ctx context.Context, provider, connectionID string, refresh bool, fetch func(context.Context) ([]*Repository, error)) ([]*Repository, CacheStatus, error) {
+	connKey := provider + ":" + connectionID
+	return cachedListing(ctx, l, connKey, "repos:"+connKey, refresh, fetch)
+}
+
+// Branches returns the branches of a repository of a connection, from the cache unless
+// refresh is set or the cached listing is too old, in which case fetch lists them
+func (l *CachedLister) Branches(ctx context.Context, provider, connectionID, owner, repo string, refresh bool, fetch func(context.Context) ([]*Branch, error)) ([]*Branch, CacheStatus, error) {
+	connKey := provider + ":" + connectionID
+	return cachedListing(ctx, l, connKey, "branches:"+connKey+":"+owner+"/"+repo, refresh, fetch)
+}
+
+// Tree returns a tree listing of a repository of a connection, from the cache unless
+// refresh is set or the cached listing is too old, in which case fetch lists it. The
+// cache key covers the branch and opts, so pages of a listing are served from one fetch.
+func (l *CachedLister) Tree(ctx context.Context, provider, connectionID, owner, repo, branch string, opts TreeOptions, refresh bool, fetch func(context.Context) (*Tree, error)) (*Tree, CacheStatus, error) {
+	connKey := provider + ":" + connectionID
+	key := fmt.Sprintf("tree:%s:%s/%s@%s:%s:%t:%d:%d", connKey, owner, repo, branch, opts.Path, opts.Recursive, opts.MaxDepth, opts.MaxEntries)
+	return cachedListing(ctx, l, connKey, key, refresh, fetch)
+}
+
+func cachedListing[T any](ctx context.Context, l *CachedLister, connKey, key string, refresh bool, fetch func(context.Context) (T, error)) (T, CacheStatus, error) {
+	var zero T
+
+	entry, cached := l.cache.Get(ctx, key)
+	if cached && !refresh && time.Since(entry.StoredAt) < l.ttl {
+		var value T
+		if err := json.Unmarshal(entry.Data, &value); err == nil {
+			return value, CacheHit, nil
+		}
+	}
+
+	// Don't call the provider again before its rate limit resets
+	if reset, limited := l.rateLimitedUntil(ctx, connKey); limited {
+		return staleListing[T](entry, cached, &RateLimitError{
+			Reset: reset,
+			Err:   errors.New("waiting for the rate limit to reset"),
+		})
+	}
+
+	value, err := fetch(ctx)
+	if err != nil {
+		var rateErr *RateLimitError
+		if errors.As(err, &rateErr) {
+			l.setRateLimited(ctx, connKey, rateErr.Reset)
+			return staleListing[T](entry, cached, err)
+		}
+		return zero, "", err
+	}
+
+	if data, err := json.Marshal(value); err == nil {
+		l.cache.Set(ctx, key, &CacheEntry{Data: data, StoredAt: time.Now()}, l.ttl+listingStaleFor)
+	}
+	return value, CacheMiss, nil
+}
+
+// staleListing returns the cached listing when there is one, err otherwise
+func staleListing[T any](entry *CacheEntry, cached bool, err error) (T, CacheStatus, error) {
+	var value T
+	if !cached {
+		return value, "", err
+	}
+	if jsonErr := json.Unmarshal(entry.Data, &value); jsonErr != nil {
+		return value, "", err
+	}
+	return value, CacheStale, nil
+}
+
+// rateLimitedUntil returns when the rate limit of a connection resets, false when
+// the connection isn't known to be rate limited
+func (l *CachedLister) rateLimitedUntil(ctx context.Context, connKey string) (time.Time, bool) {
+	entry, ok := l.cache.Get(ctx, "ratelimit:"+connKey)
+	if !ok {
+		return time.Time{}, false
+	}
+	var reset time.Time
+	if err := json.Unmarshal(entry.Data, &reset); err != nil || !time.Now().Before(reset) {
+		return time.Time{}, false
+	}
+	return reset, true
+}
+
+// setRateLimited records that a connection's rate limit is used up until reset
+func (l *CachedLister) setRateLimited(ctx context.Context, connKey string, reset time.Time) {
+	wait := time.Until(reset)
+	if wait <= 0 {
+		return
+	}
+	data, err := json.Marshal(reset)
+	if err != nil {
+		return
+	}
+	l.cache.Set(ctx, "ratelimit:"+connKey, &CacheEntry{Data: data, StoredAt: time.Now()}, wait)
+}
@@ -28,15 +28,26 @@ type OAuthConfig struct {
 
 // OAuthState stores OAuth state for CSRF protection
 type OAuthState struct {
-	Provider   string
-	OrgID      string
-	UserID     string
-	ExpiresAt  time.Time
-	StateToken string
+	Provider     string
+	OrgID        string
+	UserID       string
+	ConnectionID string // Set when reconnecting an existing git connection
+	ExpiresAt    time.Time
+	StateToken   string
 }
 
 // GenerateOAuthState generates a secure OAuth state token with orgID and userID encoded
 func GenerateOAuthState(provider, orgID, userID string) (*OAuthState, error) {
+	return generateOAuthState(provider, orgID, userID, "")
+}
+
+// GenerateReconnectOAuthState generates an OAuth state token that also carries the
+// git connection being reconnected, so the callback updates that connection
+func GenerateReconnectOAuthState(provider, orgID, userID, connectionID string) (*OAuthState, error) {
+	return generateOAuthState(provider, orgID, userID, connectionID)
+}
+
+func generateOAuthState(provider, orgID, userID, connectionID string) (*OAuthState, error) {
 	// Generate random token for CSRF protection
 	bytes := make([]byte, 16)
 	if _, err := rand.Read(bytes); err != nil {
@@ -51,6 +62,9 @@ func GenerateOAuthState(provider, orgID, userID string) (*OAuthState, error) {
 		"token":   randomToken,
 		"expires": fmt.Sprintf("%d", time.Now().Add(10*time.Minute).Unix()),
 	}
+	if connectionID != "" {
+		stateData["connectionID"] = connectionID
+	}
 	stateJSON, err := json.Marshal(stateData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal state: %w", err)
@@ -58,42 +72,56 @@ func GenerateOAuthState(provider, orgID, userID string) (*OAuthState, error) {
 	stateToken := base64.URLEncoding.EncodeToString(stateJSON)
 
 	return &OAuthState{
-		Provider:   provider,
-		OrgID:      orgID,
-		UserID:     userID,
-		ExpiresAt:  time.Now().Add(10 * time.Minute),
-		StateToken: stateToken,
+		Provider:     provider,
+		OrgID:        orgID,
+		UserID:       userID,
+		ConnectionID: connectionID,
+		ExpiresAt:    time.Now().Add(10 * time.Minute),
+		StateToken:   stateToken,
 	}, nil
 }
 
 // ParseOAuthState decodes the state token to extract orgID and userID
 func ParseOAuthState(stateToken string) (orgID, userID string, err error) {
+	state, err := DecodeOAuthState(stateToken)
+	if err != nil {
+		return "", "", err
+	}
+	return state.OrgID, state.UserID, nil
+}
+
+// DecodeOAuthState decodes and validates the state token. The provider isn't
+// encoded in the token and is left empty.
+func DecodeOAuthState(stateToken string) (*OAuthState, error) {
 	// Decode base64
 	stateJSON, err := base64.URLEncoding.DecodeString(stateToken)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to decode state: %w", err)
+		return nil, fmt.Errorf("failed to decode state: %w", err)
 	}
 
 	// Parse JSON
 	var stateData map[string]string
 	if err := json.Unmarshal(stateJSON, &stateData); err != nil {
-		return "", "", fmt.Errorf("failed to parse state: %w", err)
+		return nil, fmt.Errorf("failed to parse state: %w", err)
 	}
 
-	orgID = stateData["orgID"]
-	userID = stateData["userID"]
-	
 	// Validate expiration
+	var expTime int64
 	if expStr, ok := stateData["expires"]; ok {
-		var expTime int64
 		if _, err := fmt.Sscanf(expStr, "%d", &expTime); err == nil {
 			if time.Now().Unix() > expTime {
-				return "", "", fmt.Errorf("state token expired")
+				return nil, fmt.Errorf("state token expired")
 			}
 		}
 	}
 
-	return orgID, userID, nil
+	return &OAuthState{
+		OrgID:        stateData["orgID"],
+		UserID:       stateData["userID"],
+		ConnectionID: stateData["connectionID"],
+		ExpiresAt:    time.Unix(expTime, 0),
+		StateToken:   stateToken,
+	}, nil
 }
 
 // GetGitHubOAuthURL generates the GitHub OAuth authorization URL
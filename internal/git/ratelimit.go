@@ -0,0 +1,72 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/xanzy/go-gitlab"
+)
+
+// defaultRateLimitWait is how long to back off when the provider doesn't say when
+// its rate limit resets
+const defaultRateLimitWait = time.Minute
+
+// RateLimitError is returned when the git provider refused a call because the
+// connection's rate limit is used up
+type RateLimitError struct {
+	Reset time.Time // When the provider accepts calls again
+	Err   error
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("git provider rate limit exceeded until %s: %v", e.Reset.Format(time.RFC3339), e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// asRateLimitError returns err as a RateLimitError when it is a GitHub or GitLab
+// rate limit response, and err unchanged otherwise. The reset time comes from the
+// provider's rate-limit headers.
+func asRateLimitError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var ghRateErr *github.RateLimitError
+	if errors.As(err, &ghRateErr) {
+		return &RateLimitError{Reset: ghRateErr.Rate.Reset.Time, Err: err}
+	}
+
+	// Secondary rate limits (403 with Retry-After)
+	var ghAbuseErr *github.AbuseRateLimitError
+	if errors.As(err, &ghAbuseErr) {
+		wait := defaultRateLimitWait
+		if ghAbuseErr.RetryAfter != nil {
+			wait = *ghAbuseErr.RetryAfter
+		}
+		return &RateLimitError{Reset: time.Now().Add(wait), Err: err}
+	}
+
+	// GitLab answers 429, and sends RateLimit-Reset as a unix time
+	var glErr *gitlab.ErrorResponse
+	if errors.As(err, &glErr) && glErr.Response != nil {
+		resp := glErr.Response
+		limited := resp.StatusCode == http.StatusTooManyRequests ||
+			resp.StatusCode == http.StatusForbidden && resp.Header.Get("RateLimit-Remaining") == "0"
+		if limited {
+			reset := time.Now().Add(defaultRateLimitWait)
+			if unix, parseErr := strconv.ParseInt(resp.Header.Get("RateLimit-Reset"), 10, 64); parseErr == nil {
+				reset = time.Unix(unix, 0)
+			}
+			return &RateLimitError{Reset: reset, Err: err}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,82 @@
+package git
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNotADirectory is returned when a tree listing's path is a file or does not exist
+var ErrNotADirectory = errors.New("path is not a directory")
+
+// Reasons a Tree is truncated
+const (
+	TreeTruncatedProvider   = "provider_limit" // The provider cut the listing short
+	TreeTruncatedMaxEntries = "max_entries"    // TreeOptions.MaxEntries was reached
+)
+
+// TreeOptions selects the entries of a repository tree listing
+type TreeOptions struct {
+	Path      string // Directory to list, the repository root when empty
+	Recursive bool   // List the content of subdirectories too
+	// Levels of subdirectories listed when recursive, 0 for all. 1 lists the
+	// directory and its subdirectories' entries.
+	MaxDepth int
+	// Entries listed at most, 0 for no limit. The listing is truncated past it.
+	MaxEntries int
+}
+
+// Tree is a listing of a repository tree. A truncated tree misses entries and says why.
+type Tree struct {
+	Entries         []*TreeEntry
+	Truncated       bool
+	TruncatedReason string
+}
+
+// treeCollector builds a Tree within the limits of TreeOptions
+type treeCollector struct {
+	opts TreeOptions
+	tree *Tree
+}
+
+func newTreeCollector(opts TreeOptions) *treeCollector {
+	opts.Path = strings.Trim(opts.Path, "/")
+	return &treeCollector{opts: opts, tree: &Tree{Entries: []*TreeEntry{}}}
+}
+
+// fullPath returns the path from the repository root of an entry at path relative to
+// the listed directory
+func (c *treeCollector) fullPath(path string) string {
+	if c.opts.Path == "" {
+		return path
+	}
+	return c.opts.Path + "/" + path
+}
+
+// add adds an entry whose Path is relative to the listed directory, unless it's deeper
+// than MaxDepth. Returns false once the tree is full: the entry wasn't added and the
+// tree is truncated.
+func (c *treeCollector) add(entry *TreeEntry) bool {
+	depth := strings.Count(entry.Path, "/")
+	if !c.opts.Recursive && depth > 0 {
+		return true
+	}
+	if c.opts.Recursive && c.opts.MaxDepth > 0 && depth > c.opts.MaxDepth {
+		return true
+	}
+	if c.opts.MaxEntries > 0 && len(c.tree.Entries) >= c.opts.MaxEntries {
+		c.truncate(TreeTruncatedMaxEntries)
+		return false
+	}
+
+	entry.Path = c.fullPath(entry.Path)
+	c.tree.Entries = append(c.tree.Entries, entry)
+	return true
+}
+
+// truncate marks the tree as missing entries, keeping the first reason
+func (c *treeCollector) truncate(reason string) {
+	if !c.tree.Truncated {
+		c.tree.Truncated = true
+		c.tree.TruncatedReason = reason
+	}
+}
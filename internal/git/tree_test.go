@@ -0,0 +1,68 @@
+package git
+
+import (
+	"testing"
+)
+
+func collectTree(opts TreeOptions, paths ...string) *Tree {
+	c := newTreeCollector(opts)
+	for _, path := range paths {
+		if !c.add(&TreeEntry{Path: path, Type: "blob"}) {
+			break
+		}
+	}
+	return c.tree
+}
+
+func treePaths(tree *Tree) []string {
+	paths := make([]string, len(tree.Entries))
+	for i, entry := range tree.Entries {
+		paths[i] = entry.Path
+	}
+	return paths
+}
+
+func TestTreeCollector(t *testing.T) {
+	entries := []string{"Dockerfile", "src", "src/main.go", "src/pkg", "src/pkg/util.go"}
+
+	tests := []struct {
+		name          string
+		opts          TreeOptions
+		want          []string
+		wantTruncated string
+	}{
+		{"recursive", TreeOptions{Recursive: true}, entries, ""},
+		{"not recursive", TreeOptions{}, []string{"Dockerfile", "src"}, ""},
+		{"max depth", TreeOptions{Recursive: true, MaxDepth: 1}, []string{"Dockerfile", "src", "src/main.go", "src/pkg"}, ""},
+		{"max entries", TreeOptions{Recursive: true, MaxEntries: 3}, []string{"Dockerfile", "src", "src/main.go"}, TreeTruncatedMaxEntries},
+		{"exactly max entries", TreeOptions{Recursive: true, MaxEntries: 5}, entries, ""},
+		{"path", TreeOptions{Path: "/app/", Recursive: true, MaxDepth: 0, MaxEntries: 2}, []string{"app/Dockerfile", "app/src"}, TreeTruncatedMaxEntries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := collectTree(tt.opts, entries...)
+			got := treePaths(tree)
+			if len(got) != len(tt.want) {
+				t.Fatalf("entries = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("entries = %v, want %v", got, tt.want)
+				}
+			}
+			if tree.Truncated != (tt.wantTruncated != "") || tree.TruncatedReason != tt.wantTruncated {
+				t.Errorf("truncated = %v (%q), want %q", tree.Truncated, tree.TruncatedReason, tt.wantTruncated)
+			}
+		})
+	}
+
+	t.Run("provider truncation is kept", func(t *testing.T) {
+		c := newTreeCollector(TreeOptions{Recursive: true, MaxEntries: 1})
+		c.truncate(TreeTruncatedProvider)
+		c.add(&TreeEntry{Path: "a"})
+		c.add(&TreeEntry{Path: "b"})
+		if c.tree.TruncatedReason != TreeTruncatedProvider {
+			t.Errorf("reason = %q, want %q", c.tree.TruncatedReason, TreeTruncatedProvider)
+		}
+	})
+}
@@ -2,12 +2,51 @@ package git
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"path"
 	"strings"
 )
 
+// Ref kinds returned by ParseRef
+const (
+	RefKindBranch = "branch"
+	RefKindTag    = "tag"
+)
+
+// ParseRef splits a pushed ref (refs/heads/main, refs/tags/v1.0.0) into its kind and short name.
+// Returns an empty kind for other refs.
+func ParseRef(ref string) (kind, name string) {
+	switch {
+	case strings.HasPrefix(ref, "refs/heads/"):
+		return RefKindBranch, strings.TrimPrefix(ref, "refs/heads/")
+	case strings.HasPrefix(ref, "refs/tags/"):
+		return RefKindTag, strings.TrimPrefix(ref, "refs/tags/")
+	default:
+		return "", ref
+	}
+}
+
+// ValidateTagPattern checks that a tag pattern is a valid glob
+func ValidateTagPattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid tag pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// MatchTagPattern reports whether a tag matches a glob pattern such as v*.
+// An empty pattern matches every tag.
+func MatchTagPattern(pattern, tag string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, err := path.Match(pattern, tag)
+	return err == nil && matched
+}
+
 // ValidateGitHubWebhookSignature validates a GitHub webhook signature
 func ValidateGitHubWebhookSignature(secret string, payload []byte, signature string) bool {
 	// GitHub sends signature as: sha256=<hash>
@@ -24,6 +63,15 @@ func ValidateGitHubWebhookSignature(secret string, payload []byte, signature str
 	return hmac.Equal([]byte(expectedHash), []byte(actualHash))
 }
 
+// GenerateWebhookSecret returns a new random secret for signing webhook deliveries
+func GenerateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
 // ValidateGitLabWebhookSignature validates a GitLab webhook signature
 // GitLab uses X-Gitlab-Token header for simple token validation
 func ValidateGitLabWebhookSignature(secret, token string) bool {
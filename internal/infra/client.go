@@ -2,8 +2,30 @@ package infra
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 )
 
+// ErrNotFound is matched (errors.Is) by errors about a resource that doesn't exist,
+// e.g. because it was already deleted
+var ErrNotFound = errors.New("resource not found")
+
+// NotFoundError is returned for operations on a resource that doesn't exist
+type NotFoundError struct {
+	Resource string // e.g. instance, floating IP, volume
+	ID       string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s not found: %s", e.Resource, e.ID)
+}
+
+// Is makes errors.Is(err, ErrNotFound) match
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
 // Client is the interface for OpenStack operations
 // This allows us to swap between mock and real implementations
 type Client interface {
@@ -12,11 +34,16 @@ type Client interface {
 	GetInstance(ctx context.Context, instanceID string) (*Instance, error)
 	DeleteInstance(ctx context.Context, instanceID string) error
 	WaitForInstanceStatus(ctx context.Context, instanceID string, status string) error
+	ListInstances(ctx context.Context) ([]*Instance, error)
 
 	// Network operations
 	AllocateFloatingIP(ctx context.Context, req AllocateFloatingIPRequest) (*FloatingIP, error)
 	AttachFloatingIP(ctx context.Context, fipID string, instanceID string) error
+	ListFloatingIPs(ctx context.Context) ([]*FloatingIP, error)
+	ReleaseFloatingIP(ctx context.Context, fipID string) error
 	CreateSecurityGroup(ctx context.Context, req CreateSecurityGroupRequest) (*SecurityGroup, error)
+	ListSecurityGroups(ctx context.Context) ([]*SecurityGroup, error)
+	DeleteSecurityGroup(ctx context.Context, sgID string) error
 	CreateDNSRecord(ctx context.Context, req CreateDNSRecordRequest) (*DNSRecord, error)
 
 	// Container operations
@@ -31,6 +58,7 @@ type Client interface {
 	AttachVolume(ctx context.Context, volumeID string, instanceID string, device string) error
 	DetachVolume(ctx context.Context, volumeID string) error
 	DeleteVolume(ctx context.Context, volumeID string) error
+	ListVolumes(ctx context.Context) ([]*Volume, error)
 }
 
 // Config holds configuration for the OpenStack client
@@ -49,6 +77,30 @@ func NewClient(cfg Config) Client {
 	return NewHTTPClient(cfg)
 }
 
+// Cloud resources created by Zyndra carry the ManagedByKey metadata tag, or have a
+// name starting with ResourceNamePrefix. Anything else in a tenant isn't ours.
+const (
+	ManagedByKey       = "managed_by"
+	ManagedByValue     = "zyndra"
+	ResourceNamePrefix = "zyndra-"
+)
+
+// ManagedMetadata returns metadata with the ownership tag added
+func ManagedMetadata(metadata map[string]string) map[string]string {
+	managed := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		managed[k] = v
+	}
+	managed[ManagedByKey] = ManagedByValue
+	return managed
+}
+
+// IsManaged reports whether a cloud resource was created by Zyndra, going by its
+// ownership tag or name prefix
+func IsManaged(name string, metadata map[string]string) bool {
+	return metadata[ManagedByKey] == ManagedByValue || strings.HasPrefix(name, ResourceNamePrefix)
+}
+
 // Request/Response types
 
 type CreateInstanceRequest struct {
@@ -68,10 +120,12 @@ type Instance struct {
 	IPAddress   string
 	FloatingIP  string
 	CreatedAt   string
+	Metadata    map[string]string
 }
 
 type AllocateFloatingIPRequest struct {
 	NetworkID string
+	Metadata  map[string]string
 }
 
 type FloatingIP struct {
@@ -79,12 +133,14 @@ type FloatingIP struct {
 	IPAddress string
 	NetworkID string
 	Status    string
+	Metadata  map[string]string
 }
 
 type CreateSecurityGroupRequest struct {
 	Name        string
 	Description string
 	Rules       []SecurityGroupRule
+	Metadata    map[string]string
 }
 
 type SecurityGroupRule struct {
@@ -100,6 +156,7 @@ type SecurityGroup struct {
 	Name        string
 	Description string
 	Rules       []SecurityGroupRule
+	Metadata    map[string]string
 }
 
 type CreateDNSRecordRequest struct {
@@ -151,6 +208,7 @@ type CreateVolumeRequest struct {
 	Name     string
 	SizeGB   int
 	VolumeType string
+	Metadata map[string]string
 }
 
 type Volume struct {
@@ -160,5 +218,6 @@ type Volume struct {
 	Status     string // available, in-use, error
 	AttachedTo string // instance ID if attached
 	VolumeType string
+	Metadata   map[string]string
 }
 
@@ -0,0 +1,21 @@
+package infra
+
+import "testing"
+
+func TestIsManaged(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		want     bool
+	}{
+		{name: "db-1234", metadata: ManagedMetadata(map[string]string{"database_id": "1234"}), want: true},
+		{name: "zyndra-web", want: true},
+		{name: "default", want: false},
+		{name: "web", metadata: map[string]string{ManagedByKey: "terraform"}, want: false},
+	}
+	for _, tt := range tests {
+		if got := IsManaged(tt.name, tt.metadata); got != tt.want {
+			t.Errorf("IsManaged(%q, %v) = %v, want %v", tt.name, tt.metadata, got, tt.want)
+		}
+	}
+}
@@ -46,6 +46,11 @@ func (h *HTTPClient) WaitForInstanceStatus(ctx context.Context, instanceID strin
 	return fmt.Errorf("HTTP client not yet implemented - use mock client for now")
 }
 
+func (h *HTTPClient) ListInstances(ctx context.Context) ([]*Instance, error) {
+	// TODO: Implement HTTP call to GET /api/instances
+	return nil, fmt.Errorf("HTTP client not yet implemented - use mock client for now")
+}
+
 // Network operations (stubs)
 
 func (h *HTTPClient) AllocateFloatingIP(ctx context.Context, req AllocateFloatingIPRequest) (*FloatingIP, error) {
@@ -58,11 +63,31 @@ func (h *HTTPClient) AttachFloatingIP(ctx context.Context, fipID string, instanc
 	return fmt.Errorf("HTTP client not yet implemented - use mock client for now")
 }
 
+func (h *HTTPClient) ListFloatingIPs(ctx context.Context) ([]*FloatingIP, error) {
+	// TODO: Implement HTTP call to GET /api/floating-ips
+	return nil, fmt.Errorf("HTTP client not yet implemented - use mock client for now")
+}
+
+func (h *HTTPClient) ReleaseFloatingIP(ctx context.Context, fipID string) error {
+	// TODO: Implement HTTP call to DELETE /api/floating-ips/:id
+	return fmt.Errorf("HTTP client not yet implemented - use mock client for now")
+}
+
 func (h *HTTPClient) CreateSecurityGroup(ctx context.Context, req CreateSecurityGroupRequest) (*SecurityGroup, error) {
 	// TODO: Implement HTTP call to POST /api/security-groups
 	return nil, fmt.Errorf("HTTP client not yet implemented - use mock client for now")
 }
 
+func (h *HTTPClient) ListSecurityGroups(ctx context.Context) ([]*SecurityGroup, error) {
+	// TODO: Implement HTTP call to GET /api/security-groups
+	return nil, fmt.Errorf("HTTP client not yet implemented - use mock client for now")
+}
+
+func (h *HTTPClient) DeleteSecurityGroup(ctx context.Context, sgID string) error {
+	// TODO: Implement HTTP call to DELETE /api/security-groups/:id
+	return fmt.Errorf("HTTP client not yet implemented - use mock client for now")
+}
+
 func (h *HTTPClient) CreateDNSRecord(ctx context.Context, req CreateDNSRecordRequest) (*DNSRecord, error) {
 	// TODO: Implement HTTP call to POST /api/dns/records
 	return nil, fmt.Errorf("HTTP client not yet implemented - use mock client for now")
@@ -117,3 +142,7 @@ func (h *HTTPClient) DeleteVolume(ctx context.Context, volumeID string) error {
 	return fmt.Errorf("HTTP client not yet implemented - use mock client for now")
 }
 
+func (h *HTTPClient) ListVolumes(ctx context.Context) ([]*Volume, error) {
+	// TODO: Implement HTTP call to GET /api/volumes
+	return nil, fmt.Errorf("HTTP client not yet implemented - use mock client for now")
+}
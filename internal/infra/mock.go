@@ -47,6 +47,7 @@ func (m *MockClient) CreateInstance(ctx context.Context, req CreateInstanceReque
 		Status:    "building",
 		IPAddress: generateMockIP(),
 		CreatedAt: time.Now().Format(time.RFC3339),
+		Metadata:  req.Metadata,
 	}
 
 	m.instances[instance.ID] = instance
@@ -70,7 +71,7 @@ func (m *MockClient) GetInstance(ctx context.Context, instanceID string) (*Insta
 
 	instance, ok := m.instances[instanceID]
 	if !ok {
-		return nil, fmt.Errorf("instance not found: %s", instanceID)
+		return nil, &NotFoundError{Resource: "instance", ID: instanceID}
 	}
 
 	return instance, nil
@@ -81,7 +82,7 @@ func (m *MockClient) DeleteInstance(ctx context.Context, instanceID string) erro
 	defer m.mu.Unlock()
 
 	if _, ok := m.instances[instanceID]; !ok {
-		return fmt.Errorf("instance not found: %s", instanceID)
+		return &NotFoundError{Resource: "instance", ID: instanceID}
 	}
 
 	delete(m.instances, instanceID)
@@ -105,6 +106,17 @@ func (m *MockClient) WaitForInstanceStatus(ctx context.Context, instanceID strin
 	return fmt.Errorf("timeout waiting for instance %s to reach status %s", instanceID, targetStatus)
 }
 
+func (m *MockClient) ListInstances(ctx context.Context) ([]*Instance, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	instances := make([]*Instance, 0, len(m.instances))
+	for _, instance := range m.instances {
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
 // Network operations
 
 func (m *MockClient) AllocateFloatingIP(ctx context.Context, req AllocateFloatingIPRequest) (*FloatingIP, error) {
@@ -116,6 +128,7 @@ func (m *MockClient) AllocateFloatingIP(ctx context.Context, req AllocateFloatin
 		IPAddress: generateMockFloatingIP(),
 		NetworkID: req.NetworkID,
 		Status:    "active",
+		Metadata:  req.Metadata,
 	}
 
 	m.floatingIPs[fip.ID] = fip
@@ -128,18 +141,41 @@ func (m *MockClient) AttachFloatingIP(ctx context.Context, fipID string, instanc
 
 	fip, ok := m.floatingIPs[fipID]
 	if !ok {
-		return fmt.Errorf("floating IP not found: %s", fipID)
+		return &NotFoundError{Resource: "floating IP", ID: fipID}
 	}
 
 	instance, ok := m.instances[instanceID]
 	if !ok {
-		return fmt.Errorf("instance not found: %s", instanceID)
+		return &NotFoundError{Resource: "instance", ID: instanceID}
 	}
 
 	instance.FloatingIP = fip.IPAddress
 	return nil
 }
 
+func (m *MockClient) ListFloatingIPs(ctx context.Context) ([]*FloatingIP, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	fips := make([]*FloatingIP, 0, len(m.floatingIPs))
+	for _, fip := range m.floatingIPs {
+		fips = append(fips, fip)
+	}
+	return fips, nil
+}
+
+func (m *MockClient) ReleaseFloatingIP(ctx context.Context, fipID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.floatingIPs[fipID]; !ok {
+		return &NotFoundError{Resource: "floating IP", ID: fipID}
+	}
+
+	delete(m.floatingIPs, fipID)
+	return nil
+}
+
 func (m *MockClient) CreateSecurityGroup(ctx context.Context, req CreateSecurityGroupRequest) (*SecurityGroup, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -149,12 +185,36 @@ func (m *MockClient) CreateSecurityGroup(ctx context.Context, req CreateSecurity
 		Name:        req.Name,
 		Description: req.Description,
 		Rules:       req.Rules,
+		Metadata:    req.Metadata,
 	}
 
 	m.securityGroups[sg.ID] = sg
 	return sg, nil
 }
 
+func (m *MockClient) ListSecurityGroups(ctx context.Context) ([]*SecurityGroup, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	groups := make([]*SecurityGroup, 0, len(m.securityGroups))
+	for _, sg := range m.securityGroups {
+		groups = append(groups, sg)
+	}
+	return groups, nil
+}
+
+func (m *MockClient) DeleteSecurityGroup(ctx context.Context, sgID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.securityGroups[sgID]; !ok {
+		return &NotFoundError{Resource: "security group", ID: sgID}
+	}
+
+	delete(m.securityGroups, sgID)
+	return nil
+}
+
 func (m *MockClient) CreateDNSRecord(ctx context.Context, req CreateDNSRecordRequest) (*DNSRecord, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -215,7 +275,7 @@ func (m *MockClient) GetContainerStatus(ctx context.Context, containerID string)
 
 	container, ok := m.containers[containerID]
 	if !ok {
-		return nil, fmt.Errorf("container not found: %s", containerID)
+		return nil, &NotFoundError{Resource: "container", ID: containerID}
 	}
 
 	return container, nil
@@ -227,7 +287,7 @@ func (m *MockClient) StopContainer(ctx context.Context, containerID string) erro
 
 	container, ok := m.containers[containerID]
 	if !ok {
-		return fmt.Errorf("container not found: %s", containerID)
+		return &NotFoundError{Resource: "container", ID: containerID}
 	}
 
 	container.Status = "stopped"
@@ -239,7 +299,7 @@ func (m *MockClient) DeleteContainer(ctx context.Context, containerID string) er
 	defer m.mu.Unlock()
 
 	if _, ok := m.containers[containerID]; !ok {
-		return fmt.Errorf("container not found: %s", containerID)
+		return &NotFoundError{Resource: "container", ID: containerID}
 	}
 
 	delete(m.containers, containerID)
@@ -275,6 +335,7 @@ func (m *MockClient) CreateVolume(ctx context.Context, req CreateVolumeRequest)
 		SizeGB:     req.SizeGB,
 		Status:     "available",
 		VolumeType: req.VolumeType,
+		Metadata:   req.Metadata,
 	}
 
 	m.volumes[volume.ID] = volume
@@ -287,11 +348,11 @@ func (m *MockClient) AttachVolume(ctx context.Context, volumeID string, instance
 
 	volume, ok := m.volumes[volumeID]
 	if !ok {
-		return fmt.Errorf("volume not found: %s", volumeID)
+		return &NotFoundError{Resource: "volume", ID: volumeID}
 	}
 
 	if _, ok := m.instances[instanceID]; !ok {
-		return fmt.Errorf("instance not found: %s", instanceID)
+		return &NotFoundError{Resource: "instance", ID: instanceID}
 	}
 
 	volume.Status = "in-use"
@@ -305,7 +366,7 @@ func (m *MockClient) DetachVolume(ctx context.Context, volumeID string) error {
 
 	volume, ok := m.volumes[volumeID]
 	if !ok {
-		return fmt.Errorf("volume not found: %s", volumeID)
+		return &NotFoundError{Resource: "volume", ID: volumeID}
 	}
 
 	volume.Status = "available"
@@ -318,13 +379,24 @@ func (m *MockClient) DeleteVolume(ctx context.Context, volumeID string) error {
 	defer m.mu.Unlock()
 
 	if _, ok := m.volumes[volumeID]; !ok {
-		return fmt.Errorf("volume not found: %s", volumeID)
+		return &NotFoundError{Resource: "volume", ID: volumeID}
 	}
 
 	delete(m.volumes, volumeID)
 	return nil
 }
 
+func (m *MockClient) ListVolumes(ctx context.Context) ([]*Volume, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	volumes := make([]*Volume, 0, len(m.volumes))
+	for _, volume := range m.volumes {
+		volumes = append(volumes, volume)
+	}
+	return volumes, nil
+}
+
 // Helper functions
 
 func generateMockIP() string {
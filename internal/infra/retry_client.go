@@ -2,57 +2,221 @@ package infra
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sync"
+	"time"
 
+	"github.com/intelifox/click-deploy/internal/metrics"
 	"github.com/intelifox/click-deploy/internal/retry"
 )
 
-// RetryClient wraps an infra Client with retry and circuit breaker logic
+// ErrCircuitOpen is matched (errors.Is) by errors returned while the infra circuit breaker
+// is open. Calls fail fast without reaching the infra service; the outage is transient.
+var ErrCircuitOpen = errors.New("infra service unavailable: circuit breaker is open")
+
+// CircuitOpenError is returned by RetryClient when a call is rejected by an open circuit breaker
+type CircuitOpenError struct {
+	Breaker    string        // Name of the circuit breaker that rejected the call
+	RetryAfter time.Duration // Time until the circuit breaker lets a trial call through
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.Breaker == "" {
+		return ErrCircuitOpen.Error()
+	}
+	return fmt.Sprintf("%s (%s)", ErrCircuitOpen.Error(), e.Breaker)
+}
+
+// Is makes errors.Is(err, ErrCircuitOpen) match
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// RetryAfter reports how long to wait before retrying when err was caused by an open circuit breaker
+func RetryAfter(err error) (time.Duration, bool) {
+	var circuitErr *CircuitOpenError
+	if errors.As(err, &circuitErr) {
+		return circuitErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// Operation groups. Each group has its own circuit breaker so that an outage of one
+// infra endpoint (e.g. DNS) doesn't block unrelated operations.
+const (
+	OpInstance      = "instance"
+	OpFloatingIP    = "floating_ip"
+	OpSecurityGroup = "security_group"
+	OpDNS           = "dns"
+	OpContainer     = "container"
+	OpVolume        = "volume"
+)
+
+// RetryClient wraps an infra Client with retry and per-operation circuit breaker logic
 type RetryClient struct {
-	client         Client
-	retryConfig    retry.RetryConfig
-	circuitBreaker *retry.CircuitBreaker
+	client       Client
+	retryConfig  retry.RetryConfig
+	retryConfigs map[string]retry.RetryConfig // per operation group overrides
+
+	breakerConfig  retry.Config
+	breakerConfigs map[string]retry.Config // per operation group overrides
+	breakers       map[string]*retry.CircuitBreaker
+	mu             sync.Mutex
 }
 
 // NewRetryClient creates a new retry-enabled infra client
 func NewRetryClient(client Client) *RetryClient {
 	return &RetryClient{
-		client:      client,
-		retryConfig: retry.DefaultRetryConfig(),
-		circuitBreaker: retry.NewCircuitBreaker(retry.DefaultConfig()),
+		client:         client,
+		retryConfig:    retry.DefaultRetryConfig(),
+		retryConfigs:   make(map[string]retry.RetryConfig),
+		breakerConfig:  retry.DefaultConfig(),
+		breakerConfigs: make(map[string]retry.Config),
+		breakers:       make(map[string]*retry.CircuitBreaker),
 	}
 }
 
-// WithRetryConfig sets a custom retry configuration
+// recordStateChange logs a circuit breaker transition and exports it as metrics
+func recordStateChange(name string, from, to retry.CircuitBreakerState) {
+	log.Printf("Circuit breaker %s: %s -> %s", name, from, to)
+	metrics.RecordCircuitBreakerTransition(name, from.String(), to.String(), int(to))
+}
+
+// WithRetryConfig sets the retry configuration of all operation groups without an override
 func (c *RetryClient) WithRetryConfig(cfg retry.RetryConfig) *RetryClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.retryConfig = cfg
 	return c
 }
 
-// WithCircuitBreakerConfig sets a custom circuit breaker configuration
+// WithOperationRetryConfig sets the retry configuration of one operation group
+func (c *RetryClient) WithOperationRetryConfig(op string, cfg retry.RetryConfig) *RetryClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retryConfigs[op] = cfg
+	return c
+}
+
+// WithCircuitBreakerConfig sets the circuit breaker configuration of all operation groups
+// without an override. Their existing breakers are replaced.
 func (c *RetryClient) WithCircuitBreakerConfig(cfg retry.Config) *RetryClient {
-	c.circuitBreaker = retry.NewCircuitBreaker(cfg)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.breakerConfig = cfg
+	for op := range c.breakers {
+		if _, ok := c.breakerConfigs[op]; !ok {
+			delete(c.breakers, op)
+		}
+	}
 	return c
 }
 
+// WithOperationCircuitBreakerConfig sets the circuit breaker configuration of one
+// operation group. Its existing breaker is replaced.
+func (c *RetryClient) WithOperationCircuitBreakerConfig(op string, cfg retry.Config) *RetryClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.breakerConfigs[op] = cfg
+	delete(c.breakers, op)
+	return c
+}
+
+// CircuitBreakerStats returns the stats of every breaker created so far, by operation group
+func (c *RetryClient) CircuitBreakerStats() map[string]retry.Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := make(map[string]retry.Stats, len(c.breakers))
+	for op, cb := range c.breakers {
+		stats[op] = cb.GetStats()
+	}
+	return stats
+}
+
+// breaker returns the circuit breaker of an operation group, creating it on first use
+func (c *RetryClient) breaker(op string) *retry.CircuitBreaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cb, ok := c.breakers[op]; ok {
+		return cb
+	}
+
+	cfg, ok := c.breakerConfigs[op]
+	if !ok {
+		cfg = c.breakerConfig
+	}
+	if cfg.Name == "" {
+		cfg.Name = "infra_" + op
+	}
+	if cfg.OnStateChange == nil {
+		cfg.OnStateChange = recordStateChange
+	}
+
+	cb := retry.NewCircuitBreaker(cfg)
+	c.breakers[op] = cb
+	return cb
+}
+
+// retryConfigFor returns the retry configuration of an operation group
+func (c *RetryClient) retryConfigFor(op string) retry.RetryConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cfg, ok := c.retryConfigs[op]; ok {
+		return cfg
+	}
+	return c.retryConfig
+}
+
+// callError converts an error returned through a circuit breaker. Rejections by the
+// breaker (open, or half-open with its probes in flight) become a *CircuitOpenError;
+// other errors come from the wrapped call.
+func (c *RetryClient) callError(cb *retry.CircuitBreaker, err error) error {
+	if errors.Is(err, retry.ErrCircuitOpen) || errors.Is(err, retry.ErrCircuitHalfOpen) {
+		return &CircuitOpenError{Breaker: cb.Name(), RetryAfter: cb.RetryAfter()}
+	}
+	return fmt.Errorf("circuit breaker error: %w", err)
+}
+
+// retryableUnlessNotFound marks err as retryable, unless it's about a resource that
+// doesn't exist: retrying won't make it appear
+func retryableUnlessNotFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return retry.NewRetryableError(err)
+}
+
+// breakerResult is the result of a call as the circuit breaker sees it. A missing
+// resource is an answer of the infra service, not a failure of it.
+func breakerResult(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
 // CreateInstance wraps CreateInstance with retry and circuit breaker
 func (c *RetryClient) CreateInstance(ctx context.Context, req CreateInstanceRequest) (*Instance, error) {
 	var result *Instance
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpInstance)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpInstance), func() error {
 			result, err = c.client.CreateInstance(ctx, req)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to create instance: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to create instance: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return nil, fmt.Errorf("circuit breaker error: %w", callErr)
+		return nil, c.callError(cb, callErr)
 	}
 
 	return result, err
@@ -63,19 +227,20 @@ func (c *RetryClient) GetInstance(ctx context.Context, instanceID string) (*Inst
 	var result *Instance
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpInstance)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpInstance), func() error {
 			result, err = c.client.GetInstance(ctx, instanceID)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to get instance: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to get instance: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return nil, fmt.Errorf("circuit breaker error: %w", callErr)
+		return nil, c.callError(cb, callErr)
 	}
 
 	return result, err
@@ -85,19 +250,20 @@ func (c *RetryClient) GetInstance(ctx context.Context, instanceID string) (*Inst
 func (c *RetryClient) DeleteInstance(ctx context.Context, instanceID string) error {
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpInstance)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpInstance), func() error {
 			err = c.client.DeleteInstance(ctx, instanceID)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to delete instance: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to delete instance: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return fmt.Errorf("circuit breaker error: %w", callErr)
+		return c.callError(cb, callErr)
 	}
 
 	return err
@@ -107,42 +273,68 @@ func (c *RetryClient) DeleteInstance(ctx context.Context, instanceID string) err
 func (c *RetryClient) WaitForInstanceStatus(ctx context.Context, instanceID string, status string) error {
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpInstance)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpInstance), func() error {
 			err = c.client.WaitForInstanceStatus(ctx, instanceID, status)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to wait for instance status: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to wait for instance status: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return fmt.Errorf("circuit breaker error: %w", callErr)
+		return c.callError(cb, callErr)
 	}
 
 	return err
 }
 
+// ListInstances wraps ListInstances with retry
+func (c *RetryClient) ListInstances(ctx context.Context) ([]*Instance, error) {
+	var result []*Instance
+	var err error
+
+	cb := c.breaker(OpInstance)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpInstance), func() error {
+			result, err = c.client.ListInstances(ctx)
+			if err != nil {
+				return retryableUnlessNotFound(fmt.Errorf("failed to list instances: %w", err))
+			}
+			return nil
+		})
+		return breakerResult(err)
+	})
+
+	if callErr != nil {
+		return nil, c.callError(cb, callErr)
+	}
+
+	return result, err
+}
+
 // AllocateFloatingIP wraps AllocateFloatingIP with retry
 func (c *RetryClient) AllocateFloatingIP(ctx context.Context, req AllocateFloatingIPRequest) (*FloatingIP, error) {
 	var result *FloatingIP
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpFloatingIP)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpFloatingIP), func() error {
 			result, err = c.client.AllocateFloatingIP(ctx, req)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to allocate floating IP: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to allocate floating IP: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return nil, fmt.Errorf("circuit breaker error: %w", callErr)
+		return nil, c.callError(cb, callErr)
 	}
 
 	return result, err
@@ -152,19 +344,67 @@ func (c *RetryClient) AllocateFloatingIP(ctx context.Context, req AllocateFloati
 func (c *RetryClient) AttachFloatingIP(ctx context.Context, fipID string, instanceID string) error {
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpFloatingIP)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpFloatingIP), func() error {
 			err = c.client.AttachFloatingIP(ctx, fipID, instanceID)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to attach floating IP: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to attach floating IP: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
+	})
+
+	if callErr != nil {
+		return c.callError(cb, callErr)
+	}
+
+	return err
+}
+
+// ListFloatingIPs wraps ListFloatingIPs with retry
+func (c *RetryClient) ListFloatingIPs(ctx context.Context) ([]*FloatingIP, error) {
+	var result []*FloatingIP
+	var err error
+
+	cb := c.breaker(OpFloatingIP)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpFloatingIP), func() error {
+			result, err = c.client.ListFloatingIPs(ctx)
+			if err != nil {
+				return retryableUnlessNotFound(fmt.Errorf("failed to list floating IPs: %w", err))
+			}
+			return nil
+		})
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return fmt.Errorf("circuit breaker error: %w", callErr)
+		return nil, c.callError(cb, callErr)
+	}
+
+	return result, err
+}
+
+// ReleaseFloatingIP wraps ReleaseFloatingIP with retry
+func (c *RetryClient) ReleaseFloatingIP(ctx context.Context, fipID string) error {
+	var err error
+
+	cb := c.breaker(OpFloatingIP)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpFloatingIP), func() error {
+			err = c.client.ReleaseFloatingIP(ctx, fipID)
+			if err != nil {
+				return retryableUnlessNotFound(fmt.Errorf("failed to release floating IP: %w", err))
+			}
+			return nil
+		})
+		return breakerResult(err)
+	})
+
+	if callErr != nil {
+		return c.callError(cb, callErr)
 	}
 
 	return err
@@ -175,42 +415,91 @@ func (c *RetryClient) CreateSecurityGroup(ctx context.Context, req CreateSecurit
 	var result *SecurityGroup
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpSecurityGroup)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpSecurityGroup), func() error {
 			result, err = c.client.CreateSecurityGroup(ctx, req)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to create security group: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to create security group: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
+	})
+
+	if callErr != nil {
+		return nil, c.callError(cb, callErr)
+	}
+
+	return result, err
+}
+
+// ListSecurityGroups wraps ListSecurityGroups with retry
+func (c *RetryClient) ListSecurityGroups(ctx context.Context) ([]*SecurityGroup, error) {
+	var result []*SecurityGroup
+	var err error
+
+	cb := c.breaker(OpSecurityGroup)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpSecurityGroup), func() error {
+			result, err = c.client.ListSecurityGroups(ctx)
+			if err != nil {
+				return retryableUnlessNotFound(fmt.Errorf("failed to list security groups: %w", err))
+			}
+			return nil
+		})
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return nil, fmt.Errorf("circuit breaker error: %w", callErr)
+		return nil, c.callError(cb, callErr)
 	}
 
 	return result, err
 }
 
+// DeleteSecurityGroup wraps DeleteSecurityGroup with retry
+func (c *RetryClient) DeleteSecurityGroup(ctx context.Context, sgID string) error {
+	var err error
+
+	cb := c.breaker(OpSecurityGroup)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpSecurityGroup), func() error {
+			err = c.client.DeleteSecurityGroup(ctx, sgID)
+			if err != nil {
+				return retryableUnlessNotFound(fmt.Errorf("failed to delete security group: %w", err))
+			}
+			return nil
+		})
+		return breakerResult(err)
+	})
+
+	if callErr != nil {
+		return c.callError(cb, callErr)
+	}
+
+	return err
+}
+
 // CreateDNSRecord wraps CreateDNSRecord with retry
 func (c *RetryClient) CreateDNSRecord(ctx context.Context, req CreateDNSRecordRequest) (*DNSRecord, error) {
 	var result *DNSRecord
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpDNS)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpDNS), func() error {
 			result, err = c.client.CreateDNSRecord(ctx, req)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to create DNS record: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to create DNS record: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return nil, fmt.Errorf("circuit breaker error: %w", callErr)
+		return nil, c.callError(cb, callErr)
 	}
 
 	return result, err
@@ -221,19 +510,20 @@ func (c *RetryClient) CreateContainer(ctx context.Context, req CreateContainerRe
 	var result *Container
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpContainer)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpContainer), func() error {
 			result, err = c.client.CreateContainer(ctx, req)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to create container: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to create container: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return nil, fmt.Errorf("circuit breaker error: %w", callErr)
+		return nil, c.callError(cb, callErr)
 	}
 
 	return result, err
@@ -244,19 +534,20 @@ func (c *RetryClient) GetContainerStatus(ctx context.Context, containerID string
 	var result *Container
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpContainer)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpContainer), func() error {
 			result, err = c.client.GetContainerStatus(ctx, containerID)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to get container status: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to get container status: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return nil, fmt.Errorf("circuit breaker error: %w", callErr)
+		return nil, c.callError(cb, callErr)
 	}
 
 	return result, err
@@ -267,19 +558,20 @@ func (c *RetryClient) GetContainerStatus(ctx context.Context, containerID string
 func (c *RetryClient) StopContainer(ctx context.Context, containerID string) error {
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpContainer)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpContainer), func() error {
 			err = c.client.StopContainer(ctx, containerID)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to stop container: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to stop container: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return fmt.Errorf("circuit breaker error: %w", callErr)
+		return c.callError(cb, callErr)
 	}
 
 	return err
@@ -289,19 +581,20 @@ func (c *RetryClient) StopContainer(ctx context.Context, containerID string) err
 func (c *RetryClient) DeleteContainer(ctx context.Context, containerID string) error {
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpContainer)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpContainer), func() error {
 			err = c.client.DeleteContainer(ctx, containerID)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to delete container: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to delete container: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return fmt.Errorf("circuit breaker error: %w", callErr)
+		return c.callError(cb, callErr)
 	}
 
 	return err
@@ -311,19 +604,20 @@ func (c *RetryClient) DeleteContainer(ctx context.Context, containerID string) e
 func (c *RetryClient) WaitForContainerStatus(ctx context.Context, containerID string, status string) error {
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpContainer)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpContainer), func() error {
 			err = c.client.WaitForContainerStatus(ctx, containerID, status)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to wait for container status: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to wait for container status: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return fmt.Errorf("circuit breaker error: %w", callErr)
+		return c.callError(cb, callErr)
 	}
 
 	return err
@@ -334,19 +628,20 @@ func (c *RetryClient) CreateVolume(ctx context.Context, req CreateVolumeRequest)
 	var result *Volume
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpVolume)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpVolume), func() error {
 			result, err = c.client.CreateVolume(ctx, req)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to create volume: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to create volume: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return nil, fmt.Errorf("circuit breaker error: %w", callErr)
+		return nil, c.callError(cb, callErr)
 	}
 
 	return result, err
@@ -356,19 +651,20 @@ func (c *RetryClient) CreateVolume(ctx context.Context, req CreateVolumeRequest)
 func (c *RetryClient) AttachVolume(ctx context.Context, volumeID string, instanceID string, device string) error {
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpVolume)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpVolume), func() error {
 			err = c.client.AttachVolume(ctx, volumeID, instanceID, device)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to attach volume: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to attach volume: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return fmt.Errorf("circuit breaker error: %w", callErr)
+		return c.callError(cb, callErr)
 	}
 
 	return err
@@ -378,19 +674,20 @@ func (c *RetryClient) AttachVolume(ctx context.Context, volumeID string, instanc
 func (c *RetryClient) DetachVolume(ctx context.Context, volumeID string) error {
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpVolume)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpVolume), func() error {
 			err = c.client.DetachVolume(ctx, volumeID)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to detach volume: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to detach volume: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return fmt.Errorf("circuit breaker error: %w", callErr)
+		return c.callError(cb, callErr)
 	}
 
 	return err
@@ -400,21 +697,45 @@ func (c *RetryClient) DetachVolume(ctx context.Context, volumeID string) error {
 func (c *RetryClient) DeleteVolume(ctx context.Context, volumeID string) error {
 	var err error
 
-	callErr := c.circuitBreaker.Call(ctx, func() error {
-		err = retry.Do(ctx, c.retryConfig, func() error {
+	cb := c.breaker(OpVolume)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpVolume), func() error {
 			err = c.client.DeleteVolume(ctx, volumeID)
 			if err != nil {
-				return retry.NewRetryableError(fmt.Errorf("failed to delete volume: %w", err))
+				return retryableUnlessNotFound(fmt.Errorf("failed to delete volume: %w", err))
 			}
 			return nil
 		})
-		return err
+		return breakerResult(err)
 	})
 
 	if callErr != nil {
-		return fmt.Errorf("circuit breaker error: %w", callErr)
+		return c.callError(cb, callErr)
 	}
 
 	return err
 }
 
+// ListVolumes wraps ListVolumes with retry
+func (c *RetryClient) ListVolumes(ctx context.Context) ([]*Volume, error) {
+	var result []*Volume
+	var err error
+
+	cb := c.breaker(OpVolume)
+	callErr := cb.Call(ctx, func() error {
+		err = retry.Do(ctx, c.retryConfigFor(OpVolume), func() error {
+			result, err = c.client.ListVolumes(ctx)
+			if err != nil {
+				return retryableUnlessNotFound(fmt.Errorf("failed to list volumes: %w", err))
+			}
+			return nil
+		})
+		return breakerResult(err)
+	})
+
+	if callErr != nil {
+		return nil, c.callError(cb, callErr)
+	}
+
+	return result, err
+}
@@ -0,0 +1,120 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/retry"
+)
+
+// dnsDownClient is a mock client whose DNS endpoint always fails
+type dnsDownClient struct {
+	*MockClient
+}
+
+func (c *dnsDownClient) CreateDNSRecord(ctx context.Context, req CreateDNSRecordRequest) (*DNSRecord, error) {
+	return nil, errors.New("dns unavailable")
+}
+
+func newTestRetryClient() *RetryClient {
+	breakerCfg := retry.DefaultConfig()
+	breakerCfg.FailureThreshold = 2
+	breakerCfg.Timeout = time.Minute
+
+	return NewRetryClient(&dnsDownClient{MockClient: NewMockClient(Config{UseMock: true})}).
+		WithRetryConfig(retry.RetryConfig{MaxAttempts: 1}).
+		WithCircuitBreakerConfig(breakerCfg)
+}
+
+func tripDNSBreaker(t *testing.T, c *RetryClient) {
+	t.Helper()
+	ctx := context.Background()
+	req := CreateDNSRecordRequest{Name: "app.example.com", Type: "A", Records: []string{"10.0.0.1"}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.CreateDNSRecord(ctx, req); err == nil {
+			t.Fatal("CreateDNSRecord() error = nil, want failure")
+		}
+	}
+
+	_, err := c.CreateDNSRecord(ctx, req)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("CreateDNSRecord() after failures error = %v, want ErrCircuitOpen", err)
+	}
+	if retryAfter, ok := RetryAfter(err); !ok || retryAfter <= 0 {
+		t.Errorf("RetryAfter() = %v, %v, want positive duration", retryAfter, ok)
+	}
+}
+
+func TestRetryClient_BreakerIsolatedPerOperation(t *testing.T) {
+	c := newTestRetryClient()
+	ctx := context.Background()
+
+	tripDNSBreaker(t, c)
+
+	// Instance and volume operations have their own breakers and keep working
+	if _, err := c.CreateInstance(ctx, CreateInstanceRequest{Name: "web"}); err != nil {
+		t.Errorf("CreateInstance() error = %v, want nil", err)
+	}
+	if _, err := c.CreateVolume(ctx, CreateVolumeRequest{Name: "data", SizeGB: 1}); err != nil {
+		t.Errorf("CreateVolume() error = %v, want nil", err)
+	}
+
+	stats := c.CircuitBreakerStats()
+	if stats[OpDNS].State != retry.StateOpen {
+		t.Errorf("dns breaker state = %v, want %v", stats[OpDNS].State, retry.StateOpen)
+	}
+	for _, op := range []string{OpInstance, OpVolume} {
+		if stats[op].State != retry.StateClosed {
+			t.Errorf("%s breaker state = %v, want %v", op, stats[op].State, retry.StateClosed)
+		}
+	}
+}
+
+func TestRetryClient_OperationCircuitBreakerConfig(t *testing.T) {
+	dnsCfg := retry.DefaultConfig()
+	dnsCfg.Name = "custom_dns"
+	dnsCfg.FailureThreshold = 1
+
+	c := newTestRetryClient().WithOperationCircuitBreakerConfig(OpDNS, dnsCfg)
+	ctx := context.Background()
+	req := CreateDNSRecordRequest{Name: "app.example.com", Type: "A"}
+
+	// A single failure opens the DNS breaker with the override
+	c.CreateDNSRecord(ctx, req)
+
+	var circuitErr *CircuitOpenError
+	_, err := c.CreateDNSRecord(ctx, req)
+	if !errors.As(err, &circuitErr) {
+		t.Fatalf("CreateDNSRecord() error = %v, want *CircuitOpenError", err)
+	}
+	if circuitErr.Breaker != "custom_dns" {
+		t.Errorf("CircuitOpenError.Breaker = %q, want %q", circuitErr.Breaker, "custom_dns")
+	}
+
+	// The default config still applies to other operations
+	if _, err := c.CreateInstance(ctx, CreateInstanceRequest{Name: "web"}); err != nil {
+		t.Errorf("CreateInstance() error = %v, want nil", err)
+	}
+	if name := c.CircuitBreakerStats()[OpInstance].Name; name != "infra_instance" {
+		t.Errorf("instance breaker name = %q, want %q", name, "infra_instance")
+	}
+}
+
+func TestRetryClient_NotFoundDoesNotTripBreaker(t *testing.T) {
+	c := newTestRetryClient()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		err := c.DeleteInstance(ctx, "already-deleted")
+		if !errors.Is(err, ErrNotFound) {
+			t.Fatalf("DeleteInstance() error = %v, want ErrNotFound", err)
+		}
+	}
+
+	if state := c.CircuitBreakerStats()[OpInstance].State; state != retry.StateClosed {
+		t.Errorf("instance breaker state = %v, want %v", state, retry.StateClosed)
+	}
+}
@@ -2,6 +2,7 @@ package k8s
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +12,7 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -18,13 +20,17 @@ import (
 
 // Config holds the k8s client configuration
 type Config struct {
-	KubeconfigPath    string // Path to kubeconfig file (for local dev)
-	InCluster         bool   // Use in-cluster config
-	NamespacePrefix   string // Prefix for project namespaces (e.g., "zyndra-")
-	DefaultRegistry   string // Default container registry
-	BaseDomain        string // Base domain for generated URLs (e.g., "up.zyndra.app")
-	IngressClass      string // Ingress class (e.g., "traefik")
-	CertIssuer        string // cert-manager ClusterIssuer name
+	KubeconfigPath     string   // Path to kubeconfig file (for local dev)
+	InCluster          bool     // Use in-cluster config
+	NamespacePrefix    string   // Prefix for project namespaces (e.g., "zyndra-")
+	DefaultRegistry    string   // Default container registry
+	BaseDomain         string   // Base domain for generated URLs (e.g., "up.zyndra.app")
+	IngressClass       string   // Ingress class (e.g., "traefik")
+	CertIssuer         string   // cert-manager ClusterIssuer name
+	ReservedSubdomains []string // Labels never handed out as generated subdomains
+	BlockedWords       []string // Offensive words no generated subdomain may contain
+	StorageClass       string   // Default storage class for PVCs (e.g., "longhorn")
+	RegistryMirror     string   // Registry Docker Hub images are pulled through (e.g., "mirror.internal:5000"), empty pulls them directly
 }
 
 // Client wraps the Kubernetes clientset
@@ -83,6 +89,9 @@ func NewClient(cfg Config) (*Client, error) {
 	if cfg.CertIssuer == "" {
 		cfg.CertIssuer = "letsencrypt-prod"
 	}
+	if cfg.StorageClass == "" {
+		cfg.StorageClass = "longhorn"
+	}
 
 	return &Client{
 		clientset: clientset,
@@ -105,8 +114,9 @@ func (c *Client) ProjectNamespace(projectID string) string {
 	return c.config.NamespacePrefix + projectID
 }
 
-// CreateNamespace creates a namespace for a project
-func (c *Client) CreateNamespace(ctx context.Context, projectID, projectName string) error {
+// CreateNamespace creates a namespace for a project, labeled with the organization
+// that owns it so the project's metrics can be scoped to the org
+func (c *Client) CreateNamespace(ctx context.Context, projectID, projectName, orgID string) error {
 	ns := &corev1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: c.ProjectNamespace(projectID),
@@ -114,12 +124,17 @@ func (c *Client) CreateNamespace(ctx context.Context, projectID, projectName str
 				"app.kubernetes.io/managed-by": "zyndra",
 				"zyndra.io/project-id":          projectID,
 				"zyndra.io/project-name":        projectName,
+				"zyndra.io/org-id":              orgID,
 			},
 		},
 	}
 
 	_, err := c.clientset.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
-	if err != nil && !errors.IsAlreadyExists(err) {
+	if errors.IsAlreadyExists(err) {
+		// Namespaces created before they carried the org label get it on the next deploy
+		return c.SetNamespaceOrg(ctx, projectID, orgID)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to create namespace: %w", err)
 	}
 
@@ -144,6 +159,25 @@ func (c *Client) GetNamespace(ctx context.Context, projectID string) (*corev1.Na
 	return ns, nil
 }
 
+// SetNamespaceOrg labels a project's namespace with the organization that owns the
+// project. Projects that were never deployed have no namespace and are skipped.
+func (c *Client) SetNamespaceOrg(ctx context.Context, projectID, orgID string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]string{"zyndra.io/org-id": orgID},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.clientset.CoreV1().Namespaces().Patch(ctx, c.ProjectNamespace(projectID), types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to label namespace: %w", err)
+	}
+	return nil
+}
+
 // NamespaceExists checks if a project's namespace exists
 func (c *Client) NamespaceExists(ctx context.Context, projectID string) (bool, error) {
 	_, err := c.GetNamespace(ctx, projectID)
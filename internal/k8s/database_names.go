@@ -0,0 +1,110 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// databaseIdentity holds an engine's naming rules and defaults for the database and
+// the user created with it
+type databaseIdentity struct {
+	defaultName     string
+	defaultUsername string
+	namePattern     *regexp.Regexp
+	nameMaxLen      int
+	usernamePattern *regexp.Regexp
+	usernameMaxLen  int
+	hint            string   // Describes the allowed characters
+	reservedNames   []string // Names the engine keeps for itself
+	reservedUsers   []string
+	reservedPrefix  string // Usernames may not start with it
+}
+
+var databaseIdentities = map[string]databaseIdentity{
+	// Unquoted identifiers, which Postgres folds to lower case anyway
+	"postgresql": {
+		defaultName:     "app",
+		defaultUsername: "admin",
+		namePattern:     regexp.MustCompile(`^[a-z_][a-z0-9_]*$`),
+		nameMaxLen:      63,
+		usernamePattern: regexp.MustCompile(`^[a-z_][a-z0-9_]*$`),
+		usernameMaxLen:  63,
+		hint:            "lowercase letters, digits and underscores, not starting with a digit",
+		reservedNames:   []string{"template0", "template1"},
+		reservedPrefix:  "pg_",
+	},
+	"mysql": {
+		defaultName:     "app",
+		defaultUsername: "admin",
+		namePattern:     regexp.MustCompile(`^[A-Za-z0-9_]+$`),
+		nameMaxLen:      64,
+		usernamePattern: regexp.MustCompile(`^[A-Za-z0-9_]+$`),
+		usernameMaxLen:  32,
+		hint:            "letters, digits and underscores",
+		reservedNames:   []string{"mysql", "information_schema", "performance_schema", "sys"},
+		reservedUsers:   []string{"root"}, // The image's MYSQL_USER can't be root
+	},
+	"mongodb": {
+		defaultName:     "app",
+		defaultUsername: "admin",
+		namePattern:     regexp.MustCompile(`^[A-Za-z0-9_-]+$`),
+		nameMaxLen:      63,
+		usernamePattern: regexp.MustCompile(`^[A-Za-z0-9_.-]+$`),
+		usernameMaxLen:  64,
+		hint:            "letters, digits, underscores and dashes",
+		reservedNames:   []string{"admin", "local", "config"},
+	},
+	// Redis has numbered databases and the built-in default user, neither can be named
+	"redis": {
+		defaultUsername: "default",
+	},
+}
+
+// DefaultDatabaseName returns the name of the database created for engine when none is
+// given, empty for engines without named databases
+func DefaultDatabaseName(engine string) string {
+	return databaseIdentities[engine].defaultName
+}
+
+// DefaultDatabaseUsername returns the user created for engine when none is given
+func DefaultDatabaseUsername(engine string) string {
+	return databaseIdentities[engine].defaultUsername
+}
+
+// ValidateDatabaseName checks name against engine's rules for database names
+func ValidateDatabaseName(engine, name string) error {
+	identity := databaseIdentities[engine]
+	if identity.namePattern == nil {
+		return fmt.Errorf("%s databases can't be named", engine)
+	}
+	if len(name) > identity.nameMaxLen || !identity.namePattern.MatchString(name) {
+		return fmt.Errorf("must be at most %d characters: %s", identity.nameMaxLen, identity.hint)
+	}
+	for _, reserved := range identity.reservedNames {
+		if strings.EqualFold(name, reserved) {
+			return fmt.Errorf("%s is reserved by %s", name, engine)
+		}
+	}
+	return nil
+}
+
+// ValidateDatabaseUsername checks username against engine's rules for user names
+func ValidateDatabaseUsername(engine, username string) error {
+	identity := databaseIdentities[engine]
+	if identity.usernamePattern == nil {
+		return fmt.Errorf("%s databases don't support custom usernames", engine)
+	}
+	if len(username) > identity.usernameMaxLen || !identity.usernamePattern.MatchString(username) {
+		return fmt.Errorf("must be at most %d characters: %s", identity.usernameMaxLen, identity.hint)
+	}
+	for _, reserved := range identity.reservedUsers {
+		if strings.EqualFold(username, reserved) {
+			return fmt.Errorf("%s is reserved by %s", username, engine)
+		}
+	}
+	if identity.reservedPrefix != "" && strings.HasPrefix(username, identity.reservedPrefix) {
+		return fmt.Errorf("must not start with %s", identity.reservedPrefix)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// databaseParameter describes a database setting users may override
+type databaseParameter struct {
+	kind    string         // int, float, enum or pattern
+	min     int64          // int only
+	max     int64          // int only
+	values  []string       // enum only
+	pattern *regexp.Regexp // pattern only
+	hint    string         // pattern only, describes the expected format
+}
+
+// Memory sizes as each engine parses them
+var (
+	postgresSize = databaseParameter{kind: "pattern", pattern: regexp.MustCompile(`^[0-9]{1,9}(kB|MB|GB|TB)?$`), hint: "a size such as 128MB"}
+	mysqlSize    = databaseParameter{kind: "pattern", pattern: regexp.MustCompile(`^[0-9]{1,12}[KMG]?$`), hint: "a size such as 128M"}
+	redisSize    = databaseParameter{kind: "pattern", pattern: regexp.MustCompile(`^[0-9]{1,12}([kKmMgG][bB]?)?$`), hint: "a size such as 256mb"}
+)
+
+// databaseParameters is the allowlist of overridable settings per engine. Only settings
+// that are safe to change on a managed instance are listed (no paths, auth or replication).
+var databaseParameters = map[string]map[string]databaseParameter{
+	"postgresql": {
+		"max_connections":                     {kind: "int", min: 10, max: 5000},
+		"shared_buffers":                      postgresSize,
+		"work_mem":                            postgresSize,
+		"maintenance_work_mem":                postgresSize,
+		"effective_cache_size":                postgresSize,
+		"max_wal_size":                        postgresSize,
+		"random_page_cost":                    {kind: "float"},
+		"statement_timeout":                   {kind: "int", min: 0, max: 86400000},
+		"idle_in_transaction_session_timeout": {kind: "int", min: 0, max: 86400000},
+		"log_min_duration_statement":          {kind: "int", min: -1, max: 86400000},
+	},
+	"mysql": {
+		"sql_mode":                {kind: "pattern", pattern: regexp.MustCompile(`^([A-Z_]+(,[A-Z_]+)*)?$`), hint: "a comma-separated list of SQL modes"},
+		"max_connections":         {kind: "int", min: 10, max: 5000},
+		"innodb_buffer_pool_size": mysqlSize,
+		"max_allowed_packet":      mysqlSize,
+		"wait_timeout":            {kind: "int", min: 1, max: 31536000},
+		"long_query_time":         {kind: "float"},
+		"slow_query_log":          {kind: "enum", values: []string{"0", "1", "ON", "OFF"}},
+	},
+	"redis": {
+		"maxmemory":        redisSize,
+		"maxmemory-policy": {kind: "enum", values: []string{"noeviction", "allkeys-lru", "allkeys-lfu", "allkeys-random", "volatile-lru", "volatile-lfu", "volatile-random", "volatile-ttl"}},
+		"timeout":          {kind: "int", min: 0, max: 31536000},
+	},
+}
+
+// AllowedDatabaseParameters returns the names of the settings that can be overridden for engine
+func AllowedDatabaseParameters(engine string) []string {
+	names := make([]string, 0, len(databaseParameters[engine]))
+	for name := range databaseParameters[engine] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateDatabaseParameter checks that a setting may be overridden for engine and that value suits it
+func ValidateDatabaseParameter(engine, name, value string) error {
+	params, ok := databaseParameters[engine]
+	if !ok {
+		return fmt.Errorf("%s databases don't support parameter overrides", engine)
+	}
+	param, ok := params[name]
+	if !ok {
+		return fmt.Errorf("%s is not an allowed %s parameter", name, engine)
+	}
+
+	switch param.kind {
+	case "int":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be an integer", name)
+		}
+		if n < param.min || n > param.max {
+			return fmt.Errorf("%s must be between %d and %d", name, param.min, param.max)
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(value, 64); err != nil || f < 0 {
+			return fmt.Errorf("%s must be a non-negative number", name)
+		}
+	case "enum":
+		for _, allowed := range param.values {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s must be one of: %s", name, strings.Join(param.values, ", "))
+	case "pattern":
+		if !param.pattern.MatchString(value) {
+			return fmt.Errorf("%s must be %s", name, param.hint)
+		}
+	}
+
+	return nil
+}
+
+// databaseArgs renders parameter overrides as container args for engine. The official
+// images pass args starting with "-" on to the server binary.
+func databaseArgs(engine string, params map[string]string) []string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var args []string
+	for _, name := range names {
+		switch engine {
+		case "postgresql":
+			args = append(args, "-c", fmt.Sprintf("%s=%s", name, params[name]))
+		case "mysql":
+			args = append(args, fmt.Sprintf("--%s=%s", strings.ReplaceAll(name, "_", "-"), params[name]))
+		case "redis":
+			args = append(args, "--"+name, params[name])
+		}
+	}
+	return args
+}
+
+// ApplyDatabaseParameters updates the database's StatefulSet with new parameter overrides.
+// Changing the pod template makes the StatefulSet controller restart the pod with them.
+func (c *Client) ApplyDatabaseParameters(ctx context.Context, projectID, databaseID, engine string, params map[string]string) error {
+	namespace := c.ProjectNamespace(projectID)
+	ssName := c.dbStatefulSetName(databaseID)
+
+	ss, err := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, ssName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get database StatefulSet: %w", err)
+	}
+
+	containers := ss.Spec.Template.Spec.Containers
+	for i := range containers {
+		if containers[i].Name == engine {
+			containers[i].Args = databaseArgs(engine, params)
+		}
+	}
+
+	// Restart even when the args are unchanged, e.g. to retry a failed apply
+	if ss.Spec.Template.Annotations == nil {
+		ss.Spec.Template.Annotations = make(map[string]string)
+	}
+	ss.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"] = metav1.Now().Format("2006-01-02T15:04:05Z")
+
+	_, err = c.clientset.AppsV1().StatefulSets(namespace).Update(ctx, ss, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update database StatefulSet: %w", err)
+	}
+
+	return nil
+}
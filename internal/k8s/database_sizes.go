@@ -0,0 +1,46 @@
+package k8s
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// DatabaseResources are the CPU and memory requests and limits of a database container
+type DatabaseResources struct {
+	CPURequest    string
+	CPULimit      string
+	MemoryRequest string
+	MemoryLimit   string
+}
+
+// databaseSizePresets maps a database's size to its resources. The memory limit is
+// what the database counts against its org's quota.
+//
+//	size    cpu request/limit  memory request/limit
+//	small   100m / 500m        256Mi / 512Mi
+//	medium  250m / 1           512Mi / 1Gi
+//	large   500m / 2           1Gi / 2Gi
+var databaseSizePresets = map[string]DatabaseResources{
+	"small":  {CPURequest: "100m", CPULimit: "500m", MemoryRequest: "256Mi", MemoryLimit: "512Mi"},
+	"medium": {CPURequest: "250m", CPULimit: "1", MemoryRequest: "512Mi", MemoryLimit: "1Gi"},
+	"large":  {CPURequest: "500m", CPULimit: "2", MemoryRequest: "1Gi", MemoryLimit: "2Gi"},
+}
+
+// DatabaseSizePreset returns the resources for a database size. An empty size is small.
+func DatabaseSizePreset(size string) (DatabaseResources, error) {
+	if size == "" {
+		size = "small"
+	}
+	preset, ok := databaseSizePresets[size]
+	if !ok {
+		return DatabaseResources{}, fmt.Errorf("unknown database size: %s", size)
+	}
+	return preset, nil
+}
+
+// MemoryLimitMB returns the memory limit in MB
+func (r DatabaseResources) MemoryLimitMB() int {
+	limit := resource.MustParse(r.MemoryLimit)
+	return int(limit.Value() / (1024 * 1024))
+}
@@ -0,0 +1,59 @@
+package k8s
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestDatabaseSizePreset(t *testing.T) {
+	tests := []struct {
+		size string
+		want DatabaseResources
+	}{
+		{"", DatabaseResources{CPURequest: "100m", CPULimit: "500m", MemoryRequest: "256Mi", MemoryLimit: "512Mi"}},
+		{"small", DatabaseResources{CPURequest: "100m", CPULimit: "500m", MemoryRequest: "256Mi", MemoryLimit: "512Mi"}},
+		{"medium", DatabaseResources{CPURequest: "250m", CPULimit: "1", MemoryRequest: "512Mi", MemoryLimit: "1Gi"}},
+		{"large", DatabaseResources{CPURequest: "500m", CPULimit: "2", MemoryRequest: "1Gi", MemoryLimit: "2Gi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			got, err := DatabaseSizePreset(tt.size)
+			if err != nil {
+				t.Fatalf("DatabaseSizePreset(%q) returned error: %v", tt.size, err)
+			}
+			if got != tt.want {
+				t.Errorf("DatabaseSizePreset(%q) = %+v, want %+v", tt.size, got, tt.want)
+			}
+
+			// The values end up in resource.MustParse, so they must be valid quantities
+			for _, q := range []string{got.CPURequest, got.CPULimit, got.MemoryRequest, got.MemoryLimit} {
+				if _, err := resource.ParseQuantity(q); err != nil {
+					t.Errorf("Invalid quantity %q: %v", q, err)
+				}
+			}
+		})
+	}
+
+	if _, err := DatabaseSizePreset("huge"); err == nil {
+		t.Error("Expected an error for an unknown size")
+	}
+}
+
+func TestDatabaseSizePreset_LargerSizesGetMoreResources(t *testing.T) {
+	sizes := []string{"small", "medium", "large"}
+	for i := 1; i < len(sizes); i++ {
+		smaller, _ := DatabaseSizePreset(sizes[i-1])
+		larger, _ := DatabaseSizePreset(sizes[i])
+
+		largerCPU, smallerCPU := resource.MustParse(larger.CPULimit), resource.MustParse(smaller.CPULimit)
+		if largerCPU.Cmp(smallerCPU) <= 0 {
+			t.Errorf("%s CPU limit %s is not above %s's %s", sizes[i], larger.CPULimit, sizes[i-1], smaller.CPULimit)
+		}
+		largerMemory, smallerMemory := resource.MustParse(larger.MemoryLimit), resource.MustParse(smaller.MemoryLimit)
+		if largerMemory.Cmp(smallerMemory) <= 0 {
+			t.Errorf("%s memory limit %s is not above %s's %s", sizes[i], larger.MemoryLimit, sizes[i-1], smaller.MemoryLimit)
+		}
+	}
+}
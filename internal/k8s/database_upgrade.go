@@ -0,0 +1,439 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// How a database moves to a new version
+const (
+	// DatabaseUpgradeInPlace restarts the database on the new image with its data,
+	// which the engine upgrades when it starts
+	DatabaseUpgradeInPlace = "in_place"
+	// DatabaseUpgradeDumpRestore starts the new version on a fresh volume and restores
+	// a dump of the old one into it, for engines whose data files don't carry over
+	DatabaseUpgradeDumpRestore = "dump_restore"
+)
+
+// databaseJobPollInterval is how often upgrade steps check on their jobs and rollouts
+const databaseJobPollInterval = 5 * time.Second
+
+// ErrDatabaseNotUpgraded wraps the error of an upgrade that left the database running
+// its previous version on its previous data
+var ErrDatabaseNotUpgraded = errors.New("database was not upgraded")
+
+// databaseUpgradePolicy lists the versions of an engine and how to move between them
+type databaseUpgradePolicy struct {
+	versions   []string // Oldest first
+	strategy   string
+	singleStep bool // Only to the next version, the engine can't skip one
+}
+
+var databaseUpgradePolicies = map[string]databaseUpgradePolicy{
+	// The data directory format changes with every major version
+	"postgresql": {versions: []string{"13", "14", "15", "16", "17"}, strategy: DatabaseUpgradeDumpRestore},
+	// 8.4 upgrades an 8.0 data directory when it starts
+	"mysql": {versions: []string{"8.0", "8.4"}, strategy: DatabaseUpgradeInPlace, singleStep: true},
+	// Newer servers load older RDB files
+	"redis": {versions: []string{"6", "7"}, strategy: DatabaseUpgradeInPlace},
+	// A major version only starts on data at the previous one's feature compatibility version
+	"mongodb": {versions: []string{"5", "6", "7"}, strategy: DatabaseUpgradeInPlace, singleStep: true},
+}
+
+// defaultDatabaseVersions are the versions databases created without one run
+var defaultDatabaseVersions = map[string]string{
+	"postgresql": "16",
+	"mysql":      "8.0",
+	"redis":      "7",
+	"mongodb":    "7",
+}
+
+// DefaultDatabaseVersion returns the version a database of engine runs when created
+// without one
+func DefaultDatabaseVersion(engine string) string {
+	return defaultDatabaseVersions[engine]
+}
+
+// DatabaseUpgradeStrategy checks a database of engine can be upgraded from one version
+// ("" for the default) to another and returns how. Downgrades, unknown versions and
+// skipping versions the engine can't skip are rejected.
+func DatabaseUpgradeStrategy(engine, from, to string) (string, error) {
+	if err := ValidateEngine(engine); err != nil {
+		return "", err
+	}
+	if from == "" {
+		from = DefaultDatabaseVersion(engine)
+	}
+
+	policy := databaseUpgradePolicies[engine]
+	fromIndex := slices.Index(policy.versions, from)
+	toIndex := slices.Index(policy.versions, to)
+	switch {
+	case toIndex < 0:
+		return "", fmt.Errorf("%s %s is not supported, use one of: %s", engine, to, strings.Join(policy.versions, ", "))
+	case fromIndex < 0:
+		return "", fmt.Errorf("%s %s can't be upgraded", engine, from)
+	case toIndex == fromIndex:
+		return "", fmt.Errorf("database already runs %s %s", engine, to)
+	case toIndex < fromIndex:
+		return "", fmt.Errorf("downgrading %s from %s to %s is not supported", engine, from, to)
+	case policy.singleStep && toIndex > fromIndex+1:
+		return "", fmt.Errorf("%s is upgraded one version at a time, upgrade to %s first", engine, policy.versions[fromIndex+1])
+	}
+	return policy.strategy, nil
+}
+
+// DatabaseUpgrade describes an upgrade done by UpgradeDatabase
+type DatabaseUpgrade struct {
+	Strategy  string
+	BackupPVC string // Holds the dump taken before the upgrade
+}
+
+// UpgradeDatabase moves a running database from spec.Version to targetVersion. It
+// first dumps the database to a backup volume, then either restarts it on the new
+// image or starts the new version on a fresh volume and restores the dump into it. A
+// dump and restore stops writes to the old version before the dump, so none are lost,
+// and checks the restored tables hold the rows dumped before switching over. One that
+// fails goes back to the old version and its untouched volume, writable again.
+func (c *Client) UpgradeDatabase(ctx context.Context, spec DatabaseSpec, targetVersion string) (*DatabaseUpgrade, error) {
+	strategy, err := DatabaseUpgradeStrategy(spec.Engine, spec.Version, targetVersion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseNotUpgraded, err)
+	}
+
+	namespace := c.ProjectNamespace(spec.ProjectID)
+	ssName := c.dbStatefulSetName(spec.DatabaseID)
+	ss, err := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, ssName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to get database StatefulSet: %v", ErrDatabaseNotUpgraded, err)
+	}
+
+	suffix := time.Now().UTC().Format("20060102150405")
+	upgrade := &DatabaseUpgrade{Strategy: strategy, BackupPVC: c.dbBackupPVCName(spec.DatabaseID, suffix)}
+	if strategy == DatabaseUpgradeDumpRestore {
+		if err := c.setPostgresReadOnly(ctx, namespace, spec, upgrade.BackupPVC+"-readonly", true); err != nil {
+			err = fmt.Errorf("failed to stop writes: %v", err)
+			return nil, c.resumeDatabaseWrites(ctx, namespace, spec, upgrade.BackupPVC, err)
+		}
+	}
+	if err := c.backupDatabase(ctx, namespace, spec, upgrade.BackupPVC); err != nil {
+		err = fmt.Errorf("backup failed: %v", err)
+		if strategy == DatabaseUpgradeDumpRestore {
+			return nil, c.resumeDatabaseWrites(ctx, namespace, spec, upgrade.BackupPVC, err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseNotUpgraded, err)
+	}
+
+	target := spec
+	target.Version = targetVersion
+	if strategy == DatabaseUpgradeInPlace {
+		// The engine may already have converted the data, so there is no going back
+		// but restoring the backup
+		if err := c.setDatabaseImage(ctx, namespace, target, ""); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseNotUpgraded, err)
+		}
+		if err := c.waitForStatefulSetRollout(ctx, namespace, ssName); err != nil {
+			return nil, err
+		}
+		if spec.Engine == "mongodb" {
+			if err := c.setMongoFeatureCompatibility(ctx, namespace, target); err != nil {
+				return nil, err
+			}
+		}
+		return upgrade, nil
+	}
+
+	previous := ss.Spec.Template.DeepCopy()
+	dataPVC := c.dbPVCName(spec.DatabaseID) + "-" + suffix
+	if err := c.createDatabaseVolume(ctx, namespace, spec, dataPVC, nil); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseNotUpgraded, err)
+	}
+
+	err = c.setDatabaseImage(ctx, namespace, target, dataPVC)
+	if err == nil {
+		err = c.waitForStatefulSetRollout(ctx, namespace, ssName)
+	}
+	if err == nil {
+		err = c.restoreDatabase(ctx, namespace, target, upgrade.BackupPVC)
+	}
+	if err != nil {
+		if rollbackErr := c.restoreStatefulSetTemplate(ctx, namespace, ssName, previous); rollbackErr != nil {
+			return nil, fmt.Errorf("%v; rolling back to %s %s failed: %v", err, spec.Engine, spec.Version, rollbackErr)
+		}
+		_ = c.clientset.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, dataPVC, metav1.DeleteOptions{})
+		return nil, c.resumeDatabaseWrites(ctx, namespace, spec, upgrade.BackupPVC, err)
+	}
+	return upgrade, nil
+}
+
+// resumeDatabaseWrites makes the old version of a database whose dump and restore
+// failed with err writable again. The error returned wraps ErrDatabaseNotUpgraded
+// unless the database stays read-only.
+func (c *Client) resumeDatabaseWrites(ctx context.Context, namespace string, spec DatabaseSpec, backupPVC string, err error) error {
+	if writeErr := c.setPostgresReadOnly(ctx, namespace, spec, backupPVC+"-writable", false); writeErr != nil {
+		return fmt.Errorf("%v; %s %s is back but still read-only: %v", err, spec.Engine, spec.Version, writeErr)
+	}
+	return fmt.Errorf("%w: %v", ErrDatabaseNotUpgraded, err)
+}
+
+// setPostgresReadOnly makes new transactions on a Postgres database read-only, or
+// writable again. Making it read-only also disconnects clients, so transactions
+// already started can't write either.
+func (c *Client) setPostgresReadOnly(ctx context.Context, namespace string, spec DatabaseSpec, name string, readOnly bool) error {
+	// ALTER SYSTEM can't run in a transaction, so each statement gets its own -c
+	script := postgresEnv + `psql -v ON_ERROR_STOP=1 -c "ALTER SYSTEM RESET default_transaction_read_only" -c "SELECT pg_reload_conf()"`
+	if readOnly {
+		script = postgresEnv + `psql -v ON_ERROR_STOP=1 -c "ALTER SYSTEM SET default_transaction_read_only = on" -c "SELECT pg_reload_conf()" ` +
+			`-c "SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE pid <> pg_backend_pid() AND backend_type = 'client backend'"`
+	}
+	return c.runDatabaseJob(ctx, namespace, spec, name, "", script)
+}
+
+// postgresEnv points the Postgres client tools at the database of a job
+const postgresEnv = `export PGHOST="$DB_HOST" PGUSER="$POSTGRES_USER" PGPASSWORD="$POSTGRES_PASSWORD" PGDATABASE=postgres; `
+
+// postgresTableRows is a script writing the number of rows of every table of every
+// database to a file, so a restore can be checked against the database it was
+// dumped from
+func postgresTableRows(file string) string {
+	databases := `SELECT datname FROM pg_database WHERE datallowconn AND NOT datistemplate ORDER BY 1`
+	rows := `SELECT table_schema, table_name, ` +
+		`(xpath('/row/c/text()', query_to_xml(format('SELECT count(*) AS c FROM %I.%I', table_schema, table_name), false, true, '')))[1] ` +
+		`FROM information_schema.tables WHERE table_type = 'BASE TABLE' AND table_schema NOT IN ('pg_catalog', 'information_schema') ORDER BY 1, 2`
+	return fmt.Sprintf(`dbs=$(psql -At -c "%s") || exit 1; `+
+		`echo "$dbs" | while read -r db; do echo "database $db"; psql -v ON_ERROR_STOP=1 -At -d "$db" -c "%s" || exit 1; done > %s`,
+		databases, rows, file)
+}
+
+// backupDatabase dumps a database with its engine's dump tool into a new backup volume
+func (c *Client) backupDatabase(ctx context.Context, namespace string, spec DatabaseSpec, backupPVC string) error {
+	var script string
+	switch spec.Engine {
+	case "postgresql":
+		// Writes are stopped by then, so the row counts match the dump
+		script = postgresEnv + `pg_dumpall --clean --if-exists -f /backup/dump.sql && ` + postgresTableRows("/backup/tables.txt")
+	case "mysql":
+		script = `mysqldump -h "$DB_HOST" -uroot -p"$MYSQL_ROOT_PASSWORD" --all-databases --single-transaction --routines --events --result-file=/backup/dump.sql`
+	case "redis":
+		script = `redis-cli -h "$DB_HOST" --rdb /backup/dump.rdb`
+	case "mongodb":
+		script = `mongodump --host "$DB_HOST" --username "$MONGO_INITDB_ROOT_USERNAME" --password "$MONGO_INITDB_ROOT_PASSWORD" --authenticationDatabase admin --gzip --archive=/backup/dump.archive`
+	default:
+		return ValidateEngine(spec.Engine)
+	}
+
+	labels := map[string]string{"zyndra.io/database-backup": "true"}
+	if err := c.createDatabaseVolume(ctx, namespace, spec, backupPVC, labels); err != nil {
+		return err
+	}
+	return c.runDatabaseJob(ctx, namespace, spec, backupPVC, backupPVC, script)
+}
+
+// restoreDatabase loads the dump taken by backupDatabase into a fresh database and
+// checks every table has as many rows as when it was dumped. Only engines upgraded by
+// dump and restore need it.
+func (c *Client) restoreDatabase(ctx context.Context, namespace string, spec DatabaseSpec, backupPVC string) error {
+	if spec.Engine != "postgresql" {
+		return fmt.Errorf("%s databases aren't restored from dumps", spec.Engine)
+	}
+	// The dump drops and recreates every role, but the one restoring it can't be
+	// dropped or created again, so those two statements are left out. Any other error
+	// fails the restore.
+	script := postgresEnv +
+		`grep -v -x -F -e "DROP ROLE IF EXISTS $POSTGRES_USER;" -e "CREATE ROLE $POSTGRES_USER;" ` +
+		`-e "DROP ROLE IF EXISTS \"$POSTGRES_USER\";" -e "CREATE ROLE \"$POSTGRES_USER\";" /backup/dump.sql | ` +
+		`psql -v ON_ERROR_STOP=1 -q -f - && ` +
+		postgresTableRows("/backup/tables-restored.txt") + ` && ` +
+		`diff /backup/tables.txt /backup/tables-restored.txt`
+	return c.runDatabaseJob(ctx, namespace, spec, backupPVC+"-restore", backupPVC, script)
+}
+
+// setMongoFeatureCompatibility raises a MongoDB database's feature compatibility
+// version to the version it runs, so it can be upgraded again later
+func (c *Client) setMongoFeatureCompatibility(ctx context.Context, namespace string, spec DatabaseSpec) error {
+	// Required from 7.0 on, rejected before
+	confirm := ""
+	if major, _ := strconv.Atoi(spec.Version); major >= 7 {
+		confirm = ", confirm: true"
+	}
+	script := fmt.Sprintf(`mongosh --host "$DB_HOST" --username "$MONGO_INITDB_ROOT_USERNAME" --password "$MONGO_INITDB_ROOT_PASSWORD" --authenticationDatabase admin --eval 'db.adminCommand({setFeatureCompatibilityVersion: "%s.0"%s})'`, spec.Version, confirm)
+	name := fmt.Sprintf("%s-fcv-%s", c.dbStatefulSetName(spec.DatabaseID), time.Now().UTC().Format("20060102150405"))
+	return c.runDatabaseJob(ctx, namespace, spec, name, "", script)
+}
+
+// runDatabaseJob runs script in a Job on the image of spec's version, with the
+// database's credentials in its env, its host in DB_HOST and backupPVC, if any,
+// mounted at /backup. It waits for the job to finish.
+func (c *Client) runDatabaseJob(ctx context.Context, namespace string, spec DatabaseSpec, name, backupPVC, script string) error {
+	image, _, err := c.getDatabaseImage(spec.Engine, spec.Version)
+	if err != nil {
+		return err
+	}
+
+	container := corev1.Container{
+		Name:    "job",
+		Image:   image,
+		Command: []string{"sh", "-c", script},
+		Env:     []corev1.EnvVar{{Name: "DB_HOST", Value: c.dbServiceName(spec.DatabaseID)}},
+		EnvFrom: []corev1.EnvFromSource{
+			{
+				SecretRef: &corev1.SecretEnvSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: c.dbSecretName(spec.DatabaseID)},
+				},
+			},
+		},
+	}
+	var volumes []corev1.Volume
+	if backupPVC != "" {
+		container.VolumeMounts = []corev1.VolumeMount{{Name: "backup", MountPath: "/backup"}}
+		volumes = []corev1.Volume{
+			{
+				Name: "backup",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: backupPVC},
+				},
+			},
+		}
+	}
+
+	backoffLimit := int32(0)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "zyndra",
+				"zyndra.io/database-id":        spec.DatabaseID,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				// Not labelled with the database ID, the database's Service would
+				// route to the pod
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app.kubernetes.io/managed-by": "zyndra"},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers:    []corev1.Container{container},
+					Volumes:       volumes,
+				},
+			},
+		},
+	}
+
+	jobs := c.clientset.BatchV1().Jobs(namespace)
+	if _, err := jobs.Create(ctx, job, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create job %s: %w", name, err)
+	}
+
+	ticker := time.NewTicker(databaseJobPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("job %s: %w", name, ctx.Err())
+		case <-ticker.C:
+			job, err := jobs.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get job %s: %w", name, err)
+			}
+			if job.Status.Succeeded > 0 {
+				return nil
+			}
+			if job.Status.Failed > 0 {
+				return fmt.Errorf("job %s failed, see its pod's logs", name)
+			}
+		}
+	}
+}
+
+// setDatabaseImage switches the database's container to the image of spec's version
+// and, when dataPVC is set, its data volume to that PVC
+func (c *Client) setDatabaseImage(ctx context.Context, namespace string, spec DatabaseSpec, dataPVC string) error {
+	image, _, err := c.getDatabaseImage(spec.Engine, spec.Version)
+	if err != nil {
+		return err
+	}
+
+	statefulSets := c.clientset.AppsV1().StatefulSets(namespace)
+	ss, err := statefulSets.Get(ctx, c.dbStatefulSetName(spec.DatabaseID), metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get database StatefulSet: %w", err)
+	}
+
+	podSpec := &ss.Spec.Template.Spec
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name == spec.Engine {
+			podSpec.Containers[i].Image = image
+		}
+	}
+	if dataPVC != "" {
+		for i := range podSpec.Volumes {
+			if podSpec.Volumes[i].Name == "data" && podSpec.Volumes[i].PersistentVolumeClaim != nil {
+				podSpec.Volumes[i].PersistentVolumeClaim.ClaimName = dataPVC
+			}
+		}
+	}
+
+	if _, err := statefulSets.Update(ctx, ss, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update database StatefulSet: %w", err)
+	}
+	return nil
+}
+
+// restoreStatefulSetTemplate puts back a StatefulSet's previous pod template and waits
+// for it to roll out
+func (c *Client) restoreStatefulSetTemplate(ctx context.Context, namespace, name string, template *corev1.PodTemplateSpec) error {
+	statefulSets := c.clientset.AppsV1().StatefulSets(namespace)
+	ss, err := statefulSets.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get database StatefulSet: %w", err)
+	}
+	ss.Spec.Template = *template
+	if _, err := statefulSets.Update(ctx, ss, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update database StatefulSet: %w", err)
+	}
+	return c.waitForStatefulSetRollout(ctx, namespace, name)
+}
+
+// waitForStatefulSetRollout waits until all replicas of a StatefulSet run its current
+// template and are ready
+func (c *Client) waitForStatefulSetRollout(ctx context.Context, namespace, name string) error {
+	ticker := time.NewTicker(databaseJobPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for database to restart: %w", ctx.Err())
+		case <-ticker.C:
+			ss, err := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get database StatefulSet: %w", err)
+			}
+			replicas := int32(1)
+			if ss.Spec.Replicas != nil {
+				replicas = *ss.Spec.Replicas
+			}
+			status := ss.Status
+			if status.ObservedGeneration >= ss.Generation && status.UpdateRevision == status.CurrentRevision &&
+				status.UpdatedReplicas == replicas && status.ReadyReplicas == replicas {
+				return nil
+			}
+		}
+	}
+}
+
+func (c *Client) dbBackupPVCName(databaseID, suffix string) string {
+	return fmt.Sprintf("db-backup-%s-%s", databaseID[:8], suffix)
+}
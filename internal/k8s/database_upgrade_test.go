@@ -0,0 +1,36 @@
+package k8s
+
+import "testing"
+
+func TestDatabaseUpgradeStrategy(t *testing.T) {
+	tests := []struct {
+		engine, from, to string
+		want             string // "" when the upgrade is rejected
+	}{
+		{"postgresql", "14", "16", DatabaseUpgradeDumpRestore},
+		{"postgresql", "", "17", DatabaseUpgradeDumpRestore}, // Created on the default, 16
+		{"mysql", "8.0", "8.4", DatabaseUpgradeInPlace},
+		{"redis", "6", "7", DatabaseUpgradeInPlace},
+		{"mongodb", "6", "7", DatabaseUpgradeInPlace},
+
+		{"postgresql", "16", "14", ""}, // Downgrade
+		{"postgresql", "16", "16", ""},
+		{"postgresql", "16", "18", ""}, // Unsupported target
+		{"postgresql", "9.6", "16", ""},
+		{"mongodb", "5", "7", ""}, // Skips 6
+		{"postgres", "14", "16", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := DatabaseUpgradeStrategy(tt.engine, tt.from, tt.to)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("DatabaseUpgradeStrategy(%s, %q, %q) = %s, want an error", tt.engine, tt.from, tt.to, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("DatabaseUpgradeStrategy(%s, %q, %q) = %s, %v, want %s", tt.engine, tt.from, tt.to, got, err, tt.want)
+		}
+	}
+}
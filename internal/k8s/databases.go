@@ -4,11 +4,12 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -17,7 +18,8 @@ import (
 // DatabaseSpec defines the specification for a managed database
 type DatabaseSpec struct {
 	DatabaseID   string
-	DatabaseName string
+	DatabaseName string // Name of the database created in it, see DefaultDatabaseName
+	Username     string // User the app connects as, see DefaultDatabaseUsername
 	ProjectID    string
 	Engine       string // postgresql, mysql, redis, mongodb
 	Version      string // e.g., "16", "8.0", "7"
@@ -26,6 +28,22 @@ type DatabaseSpec struct {
 	CPULimit     string // e.g., "500m"
 	MemoryRequest string // e.g., "256Mi"
 	MemoryLimit  string // e.g., "1Gi"
+	Parameters   map[string]string // Allowlisted config overrides, see ValidateDatabaseParameter
+	StorageClass string // Optional: overrides the client's default storage class
+}
+
+// ErrUnsupportedEngine is returned for a database engine the client can't run
+var ErrUnsupportedEngine = errors.New("unsupported engine")
+
+// ValidateEngine checks the client can run databases of engine. Engines are matched
+// exactly, so a typo such as "postgres" fails rather than running something else.
+func ValidateEngine(engine string) error {
+	switch engine {
+	case "postgresql", "mysql", "redis", "mongodb":
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedEngine, engine)
+	}
 }
 
 // DatabaseCredentials holds the auto-generated credentials
@@ -40,7 +58,19 @@ type DatabaseCredentials struct {
 
 // CreateDatabase creates a managed database using StatefulSet
 func (c *Client) CreateDatabase(ctx context.Context, spec DatabaseSpec) (*DatabaseCredentials, error) {
+	// Before anything is created for it
+	port, err := c.getDefaultPort(spec.Engine)
+	if err != nil {
+		return nil, err
+	}
+
 	namespace := c.ProjectNamespace(spec.ProjectID)
+	if spec.DatabaseName == "" {
+		spec.DatabaseName = DefaultDatabaseName(spec.Engine)
+	}
+	if spec.Username == "" {
+		spec.Username = DefaultDatabaseUsername(spec.Engine)
+	}
 	
 	// Generate credentials
 	password, err := generateRandomPassword(32)
@@ -49,10 +79,10 @@ func (c *Client) CreateDatabase(ctx context.Context, spec DatabaseSpec) (*Databa
 	}
 	
 	creds := &DatabaseCredentials{
-		Username: "admin",
+		Username: spec.Username,
 		Password: password,
 		Database: spec.DatabaseName,
-		Port:     c.getDefaultPort(spec.Engine),
+		Port:     port,
 	}
 
 	// Create the secret for credentials
@@ -124,7 +154,7 @@ func (c *Client) createDatabaseSecret(ctx context.Context, namespace string, spe
 	}
 
 	_, err := c.clientset.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
-	if err != nil && !errors.IsAlreadyExists(err) {
+	if err != nil && !apierrors.IsAlreadyExists(err) {
 		return fmt.Errorf("failed to create database secret: %w", err)
 	}
 
@@ -132,19 +162,32 @@ func (c *Client) createDatabaseSecret(ctx context.Context, namespace string, spe
 }
 
 func (c *Client) createDatabasePVC(ctx context.Context, namespace string, spec DatabaseSpec) error {
-	pvcName := c.dbPVCName(spec.DatabaseID)
-	storageClass := "longhorn"
-	
+	return c.createDatabaseVolume(ctx, namespace, spec, c.dbPVCName(spec.DatabaseID), nil)
+}
+
+// createDatabaseVolume creates a PVC of the database's size and storage class, with
+// labels added to the database's own
+func (c *Client) createDatabaseVolume(ctx context.Context, namespace string, spec DatabaseSpec, pvcName string, labels map[string]string) error {
+	storageClass := c.storageClass(spec.StorageClass)
+	if err := c.EnsureStorageClass(ctx, storageClass); err != nil {
+		return err
+	}
+
 	sizeStr := fmt.Sprintf("%dMi", spec.SizeMB)
 
+	pvcLabels := map[string]string{
+		"app.kubernetes.io/managed-by": "zyndra",
+		"zyndra.io/database-id":        spec.DatabaseID,
+	}
+	for k, v := range labels {
+		pvcLabels[k] = v
+	}
+
 	pvc := &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      pvcName,
 			Namespace: namespace,
-			Labels: map[string]string{
-				"app.kubernetes.io/managed-by": "zyndra",
-				"zyndra.io/database-id":         spec.DatabaseID,
-			},
+			Labels:    pvcLabels,
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
 			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
@@ -158,7 +201,7 @@ func (c *Client) createDatabasePVC(ctx context.Context, namespace string, spec D
 	}
 
 	_, err := c.clientset.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, pvc, metav1.CreateOptions{})
-	if err != nil && !errors.IsAlreadyExists(err) {
+	if err != nil && !apierrors.IsAlreadyExists(err) {
 		return fmt.Errorf("failed to create database PVC: %w", err)
 	}
 
@@ -170,7 +213,18 @@ func (c *Client) createDatabaseStatefulSet(ctx context.Context, namespace string
 	secretName := c.dbSecretName(spec.DatabaseID)
 	pvcName := c.dbPVCName(spec.DatabaseID)
 
-	image, dataPath := c.getDatabaseImage(spec.Engine, spec.Version)
+	image, dataPath, err := c.getDatabaseImage(spec.Engine, spec.Version)
+	if err != nil {
+		return err
+	}
+	port, err := c.getDefaultPort(spec.Engine)
+	if err != nil {
+		return err
+	}
+	probe, err := c.getDatabaseProbe(spec)
+	if err != nil {
+		return err
+	}
 
 	// Build container
 	container := corev1.Container{
@@ -179,7 +233,7 @@ func (c *Client) createDatabaseStatefulSet(ctx context.Context, namespace string
 		Ports: []corev1.ContainerPort{
 			{
 				Name:          spec.Engine,
-				ContainerPort: c.getDefaultPort(spec.Engine),
+				ContainerPort: port,
 			},
 		},
 		EnvFrom: []corev1.EnvFromSource{
@@ -197,12 +251,13 @@ func (c *Client) createDatabaseStatefulSet(ctx context.Context, namespace string
 				MountPath: dataPath,
 			},
 		},
+		Args:      databaseArgs(spec.Engine, spec.Parameters),
 		Resources: c.buildDatabaseResources(spec),
 	}
 
 	// Add liveness probe
-	container.LivenessProbe = c.getDatabaseProbe(spec.Engine)
-	container.ReadinessProbe = c.getDatabaseProbe(spec.Engine)
+	container.LivenessProbe = probe
+	container.ReadinessProbe = probe.DeepCopy()
 
 	replicas := int32(1)
 
@@ -248,8 +303,8 @@ func (c *Client) createDatabaseStatefulSet(ctx context.Context, namespace string
 		},
 	}
 
-	_, err := c.clientset.AppsV1().StatefulSets(namespace).Create(ctx, ss, metav1.CreateOptions{})
-	if err != nil && !errors.IsAlreadyExists(err) {
+	_, err = c.clientset.AppsV1().StatefulSets(namespace).Create(ctx, ss, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
 		return fmt.Errorf("failed to create database StatefulSet: %w", err)
 	}
 
@@ -258,6 +313,10 @@ func (c *Client) createDatabaseStatefulSet(ctx context.Context, namespace string
 
 func (c *Client) createDatabaseService(ctx context.Context, namespace string, spec DatabaseSpec) error {
 	svcName := c.dbServiceName(spec.DatabaseID)
+	port, err := c.getDefaultPort(spec.Engine)
+	if err != nil {
+		return err
+	}
 
 	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
@@ -276,15 +335,15 @@ func (c *Client) createDatabaseService(ctx context.Context, namespace string, sp
 			Ports: []corev1.ServicePort{
 				{
 					Name:       spec.Engine,
-					Port:       c.getDefaultPort(spec.Engine),
-					TargetPort: intstr.FromInt32(c.getDefaultPort(spec.Engine)),
+					Port:       port,
+					TargetPort: intstr.FromInt32(port),
 				},
 			},
 		},
 	}
 
-	_, err := c.clientset.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
-	if err != nil && !errors.IsAlreadyExists(err) {
+	_, err = c.clientset.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
 		return fmt.Errorf("failed to create database service: %w", err)
 	}
 
@@ -297,25 +356,35 @@ func (c *Client) DeleteDatabase(ctx context.Context, projectID, databaseID strin
 
 	// Delete StatefulSet
 	ssName := c.dbStatefulSetName(databaseID)
-	if err := c.clientset.AppsV1().StatefulSets(namespace).Delete(ctx, ssName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+	if err := c.clientset.AppsV1().StatefulSets(namespace).Delete(ctx, ssName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
 		return fmt.Errorf("failed to delete StatefulSet: %w", err)
 	}
 
 	// Delete Service
 	svcName := c.dbServiceName(databaseID)
-	if err := c.clientset.CoreV1().Services(namespace).Delete(ctx, svcName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+	if err := c.clientset.CoreV1().Services(namespace).Delete(ctx, svcName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
 		return fmt.Errorf("failed to delete Service: %w", err)
 	}
 
 	// Delete PVC
 	pvcName := c.dbPVCName(databaseID)
-	if err := c.clientset.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, pvcName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+	if err := c.clientset.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, pvcName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
 		return fmt.Errorf("failed to delete PVC: %w", err)
 	}
 
+	// Delete what upgrades left: their jobs, backups and the volumes of replaced versions
+	selector := metav1.ListOptions{LabelSelector: "zyndra.io/database-id=" + databaseID}
+	background := metav1.DeletePropagationBackground
+	if err := c.clientset.BatchV1().Jobs(namespace).DeleteCollection(ctx, metav1.DeleteOptions{PropagationPolicy: &background}, selector); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete database jobs: %w", err)
+	}
+	if err := c.clientset.CoreV1().PersistentVolumeClaims(namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, selector); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete database PVCs: %w", err)
+	}
+
 	// Delete Secret
 	secretName := c.dbSecretName(databaseID)
-	if err := c.clientset.CoreV1().Secrets(namespace).Delete(ctx, secretName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+	if err := c.clientset.CoreV1().Secrets(namespace).Delete(ctx, secretName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
 		return fmt.Errorf("failed to delete Secret: %w", err)
 	}
 
@@ -324,6 +393,11 @@ func (c *Client) DeleteDatabase(ctx context.Context, projectID, databaseID strin
 
 // GetDatabaseCredentials retrieves the credentials for a database
 func (c *Client) GetDatabaseCredentials(ctx context.Context, projectID, databaseID, engine string) (*DatabaseCredentials, error) {
+	port, err := c.getDefaultPort(engine)
+	if err != nil {
+		return nil, err
+	}
+
 	namespace := c.ProjectNamespace(projectID)
 	secretName := c.dbSecretName(databaseID)
 
@@ -337,7 +411,7 @@ func (c *Client) GetDatabaseCredentials(ctx context.Context, projectID, database
 		Password: string(secret.Data["password"]),
 		Database: string(secret.Data["database"]),
 		Host:     fmt.Sprintf("db-%s.%s.svc.cluster.local", databaseID[:8], namespace),
-		Port:     c.getDefaultPort(engine),
+		Port:     port,
 	}
 	creds.ConnectionURL = c.buildConnectionURL(engine, creds)
 
@@ -351,7 +425,7 @@ func (c *Client) GetDatabaseStatus(ctx context.Context, projectID, databaseID st
 
 	ss, err := c.clientset.AppsV1().StatefulSets(namespace).Get(ctx, ssName, metav1.GetOptions{})
 	if err != nil {
-		if errors.IsNotFound(err) {
+		if apierrors.IsNotFound(err) {
 			return &DatabaseStatus{Exists: false}, nil
 		}
 		return nil, err
@@ -391,64 +465,54 @@ func (c *Client) dbServiceName(databaseID string) string {
 	return "db-" + databaseID[:8]
 }
 
-func (c *Client) getDefaultPort(engine string) int32 {
+func (c *Client) getDefaultPort(engine string) (int32, error) {
 	switch engine {
 	case "postgresql":
-		return 5432
+		return 5432, nil
 	case "mysql":
-		return 3306
+		return 3306, nil
 	case "redis":
-		return 6379
+		return 6379, nil
 	case "mongodb":
-		return 27017
+		return 27017, nil
 	default:
-		return 5432
+		return 0, ValidateEngine(engine)
 	}
 }
 
-func (c *Client) getDatabaseImage(engine, version string) (image string, dataPath string) {
+// getDatabaseImage returns the image of an engine's version, through the registry
+// mirror if any, and where the image keeps its data
+func (c *Client) getDatabaseImage(engine, version string) (image string, dataPath string, err error) {
+	if version == "" {
+		version = DefaultDatabaseVersion(engine)
+	}
 	switch engine {
 	case "postgresql":
-		v := version
-		if v == "" {
-			v = "16"
-		}
-		return fmt.Sprintf("postgres:%s-alpine", v), "/var/lib/postgresql/data"
+		image, dataPath = fmt.Sprintf("postgres:%s-alpine", version), "/var/lib/postgresql/data"
 	case "mysql":
-		v := version
-		if v == "" {
-			v = "8.0"
-		}
-		return fmt.Sprintf("mysql:%s", v), "/var/lib/mysql"
+		image, dataPath = fmt.Sprintf("mysql:%s", version), "/var/lib/mysql"
 	case "redis":
-		v := version
-		if v == "" {
-			v = "7"
-		}
-		return fmt.Sprintf("redis:%s-alpine", v), "/data"
+		image, dataPath = fmt.Sprintf("redis:%s-alpine", version), "/data"
 	case "mongodb":
-		v := version
-		if v == "" {
-			v = "7"
-		}
-		return fmt.Sprintf("mongo:%s", v), "/data/db"
+		image, dataPath = fmt.Sprintf("mongo:%s", version), "/data/db"
 	default:
-		return "postgres:16-alpine", "/var/lib/postgresql/data"
+		return "", "", ValidateEngine(engine)
 	}
+	return c.mirrorImage(image), dataPath, nil
 }
 
-func (c *Client) getDatabaseProbe(engine string) *corev1.Probe {
-	switch engine {
+func (c *Client) getDatabaseProbe(spec DatabaseSpec) (*corev1.Probe, error) {
+	switch spec.Engine {
 	case "postgresql":
 		return &corev1.Probe{
 			ProbeHandler: corev1.ProbeHandler{
 				Exec: &corev1.ExecAction{
-					Command: []string{"pg_isready", "-U", "admin"},
+					Command: []string{"pg_isready", "-U", spec.Username, "-d", spec.DatabaseName},
 				},
 			},
 			InitialDelaySeconds: 30,
 			PeriodSeconds:       10,
-		}
+		}, nil
 	case "mysql":
 		return &corev1.Probe{
 			ProbeHandler: corev1.ProbeHandler{
@@ -458,7 +522,7 @@ func (c *Client) getDatabaseProbe(engine string) *corev1.Probe {
 			},
 			InitialDelaySeconds: 30,
 			PeriodSeconds:       10,
-		}
+		}, nil
 	case "redis":
 		return &corev1.Probe{
 			ProbeHandler: corev1.ProbeHandler{
@@ -468,7 +532,7 @@ func (c *Client) getDatabaseProbe(engine string) *corev1.Probe {
 			},
 			InitialDelaySeconds: 10,
 			PeriodSeconds:       5,
-		}
+		}, nil
 	case "mongodb":
 		return &corev1.Probe{
 			ProbeHandler: corev1.ProbeHandler{
@@ -478,9 +542,9 @@ func (c *Client) getDatabaseProbe(engine string) *corev1.Probe {
 			},
 			InitialDelaySeconds: 30,
 			PeriodSeconds:       10,
-		}
+		}, nil
 	default:
-		return nil
+		return nil, ValidateEngine(spec.Engine)
 	}
 }
 
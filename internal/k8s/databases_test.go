@@ -0,0 +1,43 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateEngine(t *testing.T) {
+	for _, engine := range []string{"postgresql", "mysql", "redis", "mongodb"} {
+		if err := ValidateEngine(engine); err != nil {
+			t.Errorf("ValidateEngine(%q) = %v, want nil", engine, err)
+		}
+	}
+
+	for _, engine := range []string{"", "postgres", "PostgreSQL", "mariadb"} {
+		err := ValidateEngine(engine)
+		if !errors.Is(err, ErrUnsupportedEngine) {
+			t.Errorf("ValidateEngine(%q) = %v, want ErrUnsupportedEngine", engine, err)
+		}
+	}
+}
+
+func TestUnsupportedEngineFailsFast(t *testing.T) {
+	c := &Client{}
+	spec := DatabaseSpec{DatabaseID: "0123456789abcdef", ProjectID: "project", Engine: "postgres"}
+
+	if _, err := c.getDefaultPort(spec.Engine); !errors.Is(err, ErrUnsupportedEngine) {
+		t.Errorf("getDefaultPort error = %v, want ErrUnsupportedEngine", err)
+	}
+	if _, _, err := c.getDatabaseImage(spec.Engine, ""); !errors.Is(err, ErrUnsupportedEngine) {
+		t.Errorf("getDatabaseImage error = %v, want ErrUnsupportedEngine", err)
+	}
+	if _, err := c.getDatabaseProbe(spec); !errors.Is(err, ErrUnsupportedEngine) {
+		t.Errorf("getDatabaseProbe error = %v, want ErrUnsupportedEngine", err)
+	}
+
+	// Fails before touching the cluster, which this client has none of
+	_, err := c.CreateDatabase(context.Background(), spec)
+	if err == nil || err.Error() != "unsupported engine: postgres" {
+		t.Errorf("CreateDatabase error = %v, want unsupported engine: postgres", err)
+	}
+}
@@ -23,6 +23,10 @@ type DeploymentSpec struct {
 	Image       string
 	Port        int32
 	Replicas    int32
+	Command     []string // Overrides the image's entrypoint and command when set
+	// Empty pulls pinned tags and digests if not present, :latest and untagged
+	// images always
+	ImagePullPolicy corev1.PullPolicy
 	
 	// Resources
 	CPURequest    string // e.g., "100m"
@@ -36,11 +40,23 @@ type DeploymentSpec struct {
 	// Volume mounts
 	VolumeMounts []VolumeMount
 	
-	// Health checks
-	HealthCheckPath string
-	HealthCheckPort int32
+	// Health checks: HTTP probes on HealthCheckPath when set, otherwise a TCP-connect
+	// readiness probe on the port unless DefaultProbeDisabled
+	HealthCheckPath      string
+	HealthCheckPort      int32
+	ProbeInitialDelay    int32 // Seconds before the first readiness probe, 0 uses the default
+	DefaultProbeDisabled bool
 }
 
+// Probe defaults
+const (
+	// DefaultProbeInitialDelay is how long a container gets to start listening
+	// before its first readiness probe
+	DefaultProbeInitialDelay int32 = 5
+	// livenessProbeMinDelay keeps a slow but healthy start from being restarted
+	livenessProbeMinDelay int32 = 30
+)
+
 // VolumeMount defines a volume to mount in the container
 type VolumeMount struct {
 	Name      string
@@ -53,74 +69,7 @@ func (c *Client) CreateDeployment(ctx context.Context, spec DeploymentSpec) (*ap
 	namespace := c.ProjectNamespace(spec.ProjectID)
 	deploymentName := c.deploymentName(spec.ServiceID)
 
-	// Build container spec
-	container := corev1.Container{
-		Name:  spec.ServiceName,
-		Image: spec.Image,
-		Ports: []corev1.ContainerPort{
-			{
-				Name:          "http",
-				ContainerPort: spec.Port,
-				Protocol:      corev1.ProtocolTCP,
-			},
-		},
-		Resources: c.buildResourceRequirements(spec),
-	}
-
-	// Add environment variables from secret
-	if spec.EnvSecretName != "" {
-		container.EnvFrom = []corev1.EnvFromSource{
-			{
-				SecretRef: &corev1.SecretEnvSource{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: spec.EnvSecretName,
-					},
-				},
-			},
-		}
-	}
-
-	// Add volume mounts
-	if len(spec.VolumeMounts) > 0 {
-		for _, vm := range spec.VolumeMounts {
-			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
-				Name:      vm.Name,
-				MountPath: vm.MountPath,
-			})
-		}
-	}
-
-	// Add health checks if path specified
-	if spec.HealthCheckPath != "" {
-		port := spec.HealthCheckPort
-		if port == 0 {
-			port = spec.Port
-		}
-		container.LivenessProbe = &corev1.Probe{
-			ProbeHandler: corev1.ProbeHandler{
-				HTTPGet: &corev1.HTTPGetAction{
-					Path: spec.HealthCheckPath,
-					Port: intstr.FromInt32(port),
-				},
-			},
-			InitialDelaySeconds: 30,
-			PeriodSeconds:       10,
-			TimeoutSeconds:      5,
-			FailureThreshold:    3,
-		}
-		container.ReadinessProbe = &corev1.Probe{
-			ProbeHandler: corev1.ProbeHandler{
-				HTTPGet: &corev1.HTTPGetAction{
-					Path: spec.HealthCheckPath,
-					Port: intstr.FromInt32(port),
-				},
-			},
-			InitialDelaySeconds: 5,
-			PeriodSeconds:       5,
-			TimeoutSeconds:      3,
-			FailureThreshold:    3,
-		}
-	}
+	container := c.buildContainer(spec)
 
 	// Build pod spec
 	podSpec := corev1.PodSpec{
@@ -184,6 +133,115 @@ func (c *Client) CreateDeployment(ctx context.Context, spec DeploymentSpec) (*ap
 	return result, nil
 }
 
+// buildContainer returns the container of a service deployment
+func (c *Client) buildContainer(spec DeploymentSpec) corev1.Container {
+	container := corev1.Container{
+		Name:            spec.ServiceName,
+		Image:           c.mirrorImage(spec.Image),
+		ImagePullPolicy: containerPullPolicy(spec),
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "http",
+				ContainerPort: spec.Port,
+				Protocol:      corev1.ProtocolTCP,
+			},
+		},
+		Resources: c.buildResourceRequirements(spec),
+		Command:   spec.Command,
+	}
+
+	// Add environment variables from secret
+	if spec.EnvSecretName != "" {
+		container.EnvFrom = []corev1.EnvFromSource{
+			{
+				SecretRef: &corev1.SecretEnvSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: spec.EnvSecretName,
+					},
+				},
+			},
+		}
+	}
+
+	// Add volume mounts
+	if len(spec.VolumeMounts) > 0 {
+		for _, vm := range spec.VolumeMounts {
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      vm.Name,
+				MountPath: vm.MountPath,
+			})
+		}
+	}
+
+	container.LivenessProbe, container.ReadinessProbe = buildProbes(spec)
+	return container
+}
+
+// containerPullPolicy returns the pull policy of the spec's container, the one it sets
+// or the default for its image
+func containerPullPolicy(spec DeploymentSpec) corev1.PullPolicy {
+	if spec.ImagePullPolicy != "" {
+		return spec.ImagePullPolicy
+	}
+	return imagePullPolicy(spec.Image)
+}
+
+// buildProbes returns the liveness and readiness probes of a service container.
+// With a health check path both probe it over HTTP. Without one the container
+// only gets a TCP-connect readiness probe on its port, so it receives traffic once
+// it listens rather than as soon as it starts; nothing restarts it.
+func buildProbes(spec DeploymentSpec) (*corev1.Probe, *corev1.Probe) {
+	port := spec.HealthCheckPort
+	if port == 0 {
+		port = spec.Port
+	}
+	initialDelay := spec.ProbeInitialDelay
+	if initialDelay == 0 {
+		initialDelay = DefaultProbeInitialDelay
+	}
+
+	if spec.HealthCheckPath == "" {
+		if spec.DefaultProbeDisabled || port == 0 {
+			return nil, nil
+		}
+		return nil, &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				TCPSocket: &corev1.TCPSocketAction{
+					Port: intstr.FromInt32(port),
+				},
+			},
+			InitialDelaySeconds: initialDelay,
+			PeriodSeconds:       5,
+			TimeoutSeconds:      3,
+			FailureThreshold:    3,
+		}
+	}
+
+	httpGet := func() corev1.ProbeHandler {
+		return corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: spec.HealthCheckPath,
+				Port: intstr.FromInt32(port),
+			},
+		}
+	}
+	liveness := &corev1.Probe{
+		ProbeHandler:        httpGet(),
+		InitialDelaySeconds: max(livenessProbeMinDelay, initialDelay),
+		PeriodSeconds:       10,
+		TimeoutSeconds:      5,
+		FailureThreshold:    3,
+	}
+	readiness := &corev1.Probe{
+		ProbeHandler:        httpGet(),
+		InitialDelaySeconds: initialDelay,
+		PeriodSeconds:       5,
+		TimeoutSeconds:      3,
+		FailureThreshold:    3,
+	}
+	return liveness, readiness
+}
+
 // UpdateDeployment updates an existing deployment
 func (c *Client) UpdateDeployment(ctx context.Context, spec DeploymentSpec) (*appsv1.Deployment, error) {
 	namespace := c.ProjectNamespace(spec.ProjectID)
@@ -196,7 +254,14 @@ func (c *Client) UpdateDeployment(ctx context.Context, spec DeploymentSpec) (*ap
 	}
 
 	// Update image
-	existing.Spec.Template.Spec.Containers[0].Image = spec.Image
+	existing.Spec.Template.Spec.Containers[0].Image = c.mirrorImage(spec.Image)
+	existing.Spec.Template.Spec.Containers[0].ImagePullPolicy = containerPullPolicy(spec)
+
+	// Update command, clearing a start command the service no longer has
+	existing.Spec.Template.Spec.Containers[0].Command = spec.Command
+
+	// Update probes, the health check config may have changed
+	existing.Spec.Template.Spec.Containers[0].LivenessProbe, existing.Spec.Template.Spec.Containers[0].ReadinessProbe = buildProbes(spec)
 
 	// Update resources if specified
 	if spec.CPURequest != "" || spec.MemoryRequest != "" {
@@ -247,22 +312,86 @@ func (c *Client) GetDeploymentStatus(ctx context.Context, projectID, serviceID s
 		return nil, err
 	}
 
+	return deploymentStatusFrom(deployment), nil
+}
+
+// GetDeploymentStatuses returns the status of several services in a project
+// using a single list call. The result is keyed by service ID; services with
+// no Deployment get a status with Exists set to false.
+func (c *Client) GetDeploymentStatuses(ctx context.Context, projectID string, serviceIDs []string) (map[string]*DeploymentStatus, error) {
+	namespace := c.ProjectNamespace(projectID)
+
+	deployments, err := c.clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/managed-by=zyndra,zyndra.io/project-id=" + projectID,
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+
+	found := make(map[string]*DeploymentStatus)
+	if deployments != nil {
+		for i := range deployments.Items {
+			serviceID := deployments.Items[i].Labels["zyndra.io/service-id"]
+			if serviceID != "" {
+				found[serviceID] = deploymentStatusFrom(&deployments.Items[i])
+			}
+		}
+	}
+
+	statuses := make(map[string]*DeploymentStatus, len(serviceIDs))
+	for _, serviceID := range serviceIDs {
+		if status, ok := found[serviceID]; ok {
+			statuses[serviceID] = status
+		} else {
+			statuses[serviceID] = &DeploymentStatus{Exists: false}
+		}
+	}
+
+	return statuses, nil
+}
+
+func deploymentStatusFrom(deployment *appsv1.Deployment) *DeploymentStatus {
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+
 	return &DeploymentStatus{
 		Exists:          true,
 		Replicas:        deployment.Status.Replicas,
+		DesiredReplicas: desired,
 		ReadyReplicas:   deployment.Status.ReadyReplicas,
 		UpdatedReplicas: deployment.Status.UpdatedReplicas,
 		Available:       deployment.Status.ReadyReplicas > 0,
-	}, nil
+	}
 }
 
 // DeploymentStatus represents the status of a deployment
 type DeploymentStatus struct {
 	Exists          bool
 	Replicas        int32
+	DesiredReplicas int32
 	ReadyReplicas   int32
 	UpdatedReplicas int32
 	Available       bool
+	Health          string // Set from the pods by callers that need it, see GetServiceHealth
+}
+
+// Phase summarizes the status as a single word for API responses:
+// not_deployed, scaled_down, running, degraded or unavailable
+func (s *DeploymentStatus) Phase() string {
+	switch {
+	case !s.Exists:
+		return "not_deployed"
+	case s.DesiredReplicas == 0:
+		return "scaled_down"
+	case s.ReadyReplicas >= s.DesiredReplicas:
+		return "running"
+	case s.ReadyReplicas > 0:
+		return "degraded"
+	default:
+		return "unavailable"
+	}
 }
 
 // ScaleDeployment scales a deployment to the specified number of replicas
@@ -0,0 +1,67 @@
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestBuildContainerImagePullPolicy(t *testing.T) {
+	c := &Client{}
+
+	tests := []struct {
+		image  string
+		policy corev1.PullPolicy
+		want   corev1.PullPolicy
+	}{
+		{image: "registry.zyndra.app/org/api:a1b2c3d", want: corev1.PullIfNotPresent},
+		{image: "nginx:1.27", want: corev1.PullIfNotPresent},
+		{image: "localhost:5000/api:v2", want: corev1.PullIfNotPresent},
+		{image: "nginx@sha256:0123456789abcdef", want: corev1.PullIfNotPresent},
+		{image: "nginx:latest", want: corev1.PullAlways},
+		{image: "nginx", want: corev1.PullAlways},
+		// A registry port isn't a tag
+		{image: "localhost:5000/api", want: corev1.PullAlways},
+		{image: "registry.zyndra.app/org/api:a1b2c3d", policy: corev1.PullAlways, want: corev1.PullAlways},
+		{image: "nginx:latest", policy: corev1.PullNever, want: corev1.PullNever},
+	}
+	for _, tt := range tests {
+		container := c.buildContainer(DeploymentSpec{ServiceName: "api", Image: tt.image, ImagePullPolicy: tt.policy})
+		if container.ImagePullPolicy != tt.want {
+			t.Errorf("pull policy of %q with %q = %q, want %q", tt.image, tt.policy, container.ImagePullPolicy, tt.want)
+		}
+	}
+}
+
+func TestMirrorImage(t *testing.T) {
+	c := &Client{config: Config{RegistryMirror: "mirror.internal:5000/"}}
+
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "postgres:16-alpine", want: "mirror.internal:5000/library/postgres:16-alpine"},
+		{image: "bitnami/redis:7", want: "mirror.internal:5000/bitnami/redis:7"},
+		{image: "docker.io/library/nginx:1.27", want: "mirror.internal:5000/library/nginx:1.27"},
+		{image: "registry.zyndra.app/org/api:a1b2c3d", want: "registry.zyndra.app/org/api:a1b2c3d"},
+		{image: "ghcr.io/org/tool:v1", want: "ghcr.io/org/tool:v1"},
+	}
+	for _, tt := range tests {
+		if got := c.mirrorImage(tt.image); got != tt.want {
+			t.Errorf("mirrorImage(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+
+	container := c.buildContainer(DeploymentSpec{ServiceName: "api", Image: "nginx:1.27"})
+	if container.Image != "mirror.internal:5000/library/nginx:1.27" {
+		t.Errorf("container image = %q, want it pulled through the mirror", container.Image)
+	}
+	if container.ImagePullPolicy != corev1.PullIfNotPresent {
+		t.Errorf("container pull policy = %q, want IfNotPresent", container.ImagePullPolicy)
+	}
+
+	unmirrored := &Client{}
+	if got := unmirrored.mirrorImage("postgres:16-alpine"); got != "postgres:16-alpine" {
+		t.Errorf("mirrorImage without a mirror = %q, want the image unchanged", got)
+	}
+}
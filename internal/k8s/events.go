@@ -0,0 +1,131 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodEvent is a Kubernetes event (or container state) about one of a service's pods
+type PodEvent struct {
+	Type     string    `json:"type"`   // Normal or Warning
+	Reason   string    `json:"reason"` // e.g., FailedScheduling, BackOff, OOMKilled
+	Message  string    `json:"message"`
+	PodName  string    `json:"pod_name"`
+	Count    int32     `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Key identifies the event across polls, so callers can tell which events are new
+func (e PodEvent) Key() string {
+	return fmt.Sprintf("%s/%s/%s/%d", e.PodName, e.Reason, e.Message, e.LastSeen.Unix())
+}
+
+// containerWaitingReasons are waiting states that mean a container can't start
+// rather than that it is still starting
+var containerWaitingReasons = map[string]bool{
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// GetPodEvents returns the events of a service's pods, oldest first. Pod
+// events are complemented by container states that Kubernetes doesn't report
+// as events, such as containers terminated for running out of memory.
+func (c *Client) GetPodEvents(ctx context.Context, projectID, serviceID string) ([]PodEvent, error) {
+	namespace := c.ProjectNamespace(projectID)
+
+	// Pod names start with the deployment's, which also covers pods that are already gone
+	podPrefix := c.deploymentName(serviceID) + "-"
+
+	events, err := c.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "involvedObject.kind=Pod",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %w", err)
+	}
+
+	var result []PodEvent
+	for _, e := range events.Items {
+		if !strings.HasPrefix(e.InvolvedObject.Name, podPrefix) {
+			continue
+		}
+		result = append(result, PodEvent{
+			Type:     e.Type,
+			Reason:   e.Reason,
+			Message:  e.Message,
+			PodName:  e.InvolvedObject.Name,
+			Count:    e.Count,
+			LastSeen: eventTime(e),
+		})
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "zyndra.io/service-id=" + serviceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		result = append(result, containerStateEvents(pod)...)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].LastSeen.Before(result[j].LastSeen)
+	})
+
+	return result, nil
+}
+
+// containerStateEvents reports containers that can't start or were OOM killed as warnings
+func containerStateEvents(pod corev1.Pod) []PodEvent {
+	var events []PodEvent
+	for _, cs := range pod.Status.ContainerStatuses {
+		if waiting := cs.State.Waiting; waiting != nil && containerWaitingReasons[waiting.Reason] {
+			message := fmt.Sprintf("Container %s is in %s", cs.Name, waiting.Reason)
+			if waiting.Message != "" {
+				message += ": " + waiting.Message
+			}
+			events = append(events, PodEvent{
+				Type:     corev1.EventTypeWarning,
+				Reason:   waiting.Reason,
+				Message:  message,
+				PodName:  pod.Name,
+				Count:    1,
+				LastSeen: pod.CreationTimestamp.Time,
+			})
+		}
+		if terminated := cs.LastTerminationState.Terminated; terminated != nil && terminated.Reason == "OOMKilled" {
+			events = append(events, PodEvent{
+				Type:     corev1.EventTypeWarning,
+				Reason:   terminated.Reason,
+				Message:  fmt.Sprintf("Container %s ran out of memory and was killed (exit code %d)", cs.Name, terminated.ExitCode),
+				PodName:  pod.Name,
+				Count:    cs.RestartCount,
+				LastSeen: terminated.FinishedAt.Time,
+			})
+		}
+	}
+	return events
+}
+
+// eventTime returns when an event last happened, whichever timestamp the reporter set
+func eventTime(e corev1.Event) time.Time {
+	switch {
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	default:
+		return e.CreationTimestamp.Time
+	}
+}
@@ -0,0 +1,107 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Service health, derived from the state of a service's pods
+const (
+	HealthHealthy       = "healthy"
+	HealthCrashLooping  = "crash_looping"
+	HealthOOMKilled     = "oom_killed"
+	HealthUnschedulable = "unschedulable"
+)
+
+const (
+	// crashLoopRestarts is how many restarts of a container that isn't ready count as a crash loop,
+	// even before Kubernetes backs off
+	crashLoopRestarts = 3
+	// recentOOMKill is how long after an OOM kill a service is still reported as oom_killed
+	recentOOMKill = 10 * time.Minute
+)
+
+// GetServiceHealth returns the health of a service from its pods
+func (c *Client) GetServiceHealth(ctx context.Context, projectID, serviceID string) (string, error) {
+	pods, err := c.clientset.CoreV1().Pods(c.ProjectNamespace(projectID)).List(ctx, metav1.ListOptions{
+		LabelSelector: "zyndra.io/service-id=" + serviceID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	return serviceHealth(pods.Items, time.Now()), nil
+}
+
+// GetServiceHealths returns the health of every service in a project with a
+// single list call, keyed by service ID. Services without pods are left out.
+func (c *Client) GetServiceHealths(ctx context.Context, projectID string) (map[string]string, error) {
+	pods, err := c.clientset.CoreV1().Pods(c.ProjectNamespace(projectID)).List(ctx, metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/managed-by=zyndra,zyndra.io/project-id=" + projectID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	byService := make(map[string][]corev1.Pod)
+	for _, pod := range pods.Items {
+		if serviceID := pod.Labels["zyndra.io/service-id"]; serviceID != "" {
+			byService[serviceID] = append(byService[serviceID], pod)
+		}
+	}
+
+	now := time.Now()
+	healths := make(map[string]string, len(byService))
+	for serviceID, servicePods := range byService {
+		healths[serviceID] = serviceHealth(servicePods, now)
+	}
+	return healths, nil
+}
+
+// serviceHealth reports the worst problem among pods. A pod that can't be
+// scheduled outranks an OOM kill, which outranks other crash loops (an OOM
+// killed container usually crash loops too).
+func serviceHealth(pods []corev1.Pod, now time.Time) string {
+	health := HealthHealthy
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse && cond.Reason == corev1.PodReasonUnschedulable {
+				return HealthUnschedulable
+			}
+		}
+
+		for _, cs := range pod.Status.ContainerStatuses {
+			if oomKilled(cs, now) {
+				health = HealthOOMKilled
+			} else if health == HealthHealthy && crashLooping(cs) {
+				health = HealthCrashLooping
+			}
+		}
+	}
+	return health
+}
+
+// oomKilled reports whether the container is, or recently was, killed for running out of memory
+func oomKilled(cs corev1.ContainerStatus, now time.Time) bool {
+	if terminated := cs.State.Terminated; terminated != nil && terminated.Reason == "OOMKilled" {
+		return true
+	}
+	terminated := cs.LastTerminationState.Terminated
+	return terminated != nil && terminated.Reason == "OOMKilled" && now.Sub(terminated.FinishedAt.Time) < recentOOMKill
+}
+
+// crashLooping reports whether the container keeps exiting
+func crashLooping(cs corev1.ContainerStatus) bool {
+	if waiting := cs.State.Waiting; waiting != nil && waiting.Reason == "CrashLoopBackOff" {
+		return true
+	}
+	return !cs.Ready && cs.RestartCount >= crashLoopRestarts
+}
@@ -0,0 +1,60 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServiceHealth(t *testing.T) {
+	now := time.Now()
+
+	running := corev1.ContainerStatus{Name: "app", Ready: true, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}
+	crashLoop := corev1.ContainerStatus{Name: "app", RestartCount: 1, State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}}
+	restarting := corev1.ContainerStatus{Name: "app", RestartCount: crashLoopRestarts}
+	oomKilledAt := func(at time.Time) corev1.ContainerStatus {
+		return corev1.ContainerStatus{
+			Name:  "app",
+			Ready: true,
+			State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				Reason:     "OOMKilled",
+				ExitCode:   137,
+				FinishedAt: metav1.NewTime(at),
+			}},
+		}
+	}
+	pod := func(statuses ...corev1.ContainerStatus) corev1.Pod {
+		return corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: statuses}}
+	}
+	unschedulable := corev1.Pod{Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{
+		Type:   corev1.PodScheduled,
+		Status: corev1.ConditionFalse,
+		Reason: corev1.PodReasonUnschedulable,
+	}}}}
+
+	tests := []struct {
+		name string
+		pods []corev1.Pod
+		want string
+	}{
+		{"no pods", nil, HealthHealthy},
+		{"running", []corev1.Pod{pod(running)}, HealthHealthy},
+		{"crash loop back-off", []corev1.Pod{pod(running), pod(crashLoop)}, HealthCrashLooping},
+		{"restarting without back-off", []corev1.Pod{pod(restarting)}, HealthCrashLooping},
+		{"recent OOM kill", []corev1.Pod{pod(oomKilledAt(now.Add(-time.Minute)))}, HealthOOMKilled},
+		{"old OOM kill", []corev1.Pod{pod(oomKilledAt(now.Add(-time.Hour)))}, HealthHealthy},
+		{"OOM kill outranks crash loop", []corev1.Pod{pod(crashLoop), pod(oomKilledAt(now))}, HealthOOMKilled},
+		{"unschedulable outranks everything", []corev1.Pod{pod(oomKilledAt(now)), unschedulable}, HealthUnschedulable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serviceHealth(tt.pods, now); got != tt.want {
+				t.Errorf("serviceHealth() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package k8s
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// dockerHubRegistry is the registry of image references without a registry host
+const dockerHubRegistry = "docker.io"
+
+// splitImageRegistry returns the registry host of an image reference and the rest of
+// it. The first path component is a registry host when it has a dot or a port or is
+// localhost, otherwise the image is on Docker Hub.
+func splitImageRegistry(image string) (registry, remainder string) {
+	host, rest, found := strings.Cut(image, "/")
+	if found && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		return host, rest
+	}
+	return dockerHubRegistry, image
+}
+
+// imagePullPolicy returns the pull policy of a container running image when its
+// spec doesn't set one. Images pinned to a tag or digest are pulled if not present,
+// :latest and untagged images on every start, like Kubernetes does on its own.
+func imagePullPolicy(image string) corev1.PullPolicy {
+	if strings.Contains(image, "@") {
+		return corev1.PullIfNotPresent
+	}
+	_, remainder := splitImageRegistry(image)
+	lastComponent := remainder[strings.LastIndex(remainder, "/")+1:]
+	_, tag, tagged := strings.Cut(lastComponent, ":")
+	if !tagged || tag == "latest" {
+		return corev1.PullAlways
+	}
+	return corev1.PullIfNotPresent
+}
+
+// mirrorImage rewrites a Docker Hub image reference to pull it through the configured
+// registry mirror, e.g. "postgres:16" to "mirror.internal/library/postgres:16".
+// Like a Docker daemon's registry-mirrors, images of other registries, including ours,
+// are left as they are, as is every image without a mirror.
+func (c *Client) mirrorImage(image string) string {
+	mirror := strings.TrimSuffix(c.config.RegistryMirror, "/")
+	if mirror == "" {
+		return image
+	}
+	registry, remainder := splitImageRegistry(image)
+	if registry != dockerHubRegistry && registry != "index.docker.io" {
+		return image
+	}
+	if !strings.Contains(remainder, "/") {
+		remainder = "library/" + remainder
+	}
+	return mirror + "/" + remainder
+}
@@ -0,0 +1,185 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Errors returned when importing a deployment
+var (
+	ErrDeploymentNotFound = errors.New("deployment not found")
+	ErrDeploymentChanged  = errors.New("deployment changed while it was imported")
+)
+
+// ImportedDeployment is what an existing Deployment runs, read to bring it under
+// management as a service
+type ImportedDeployment struct {
+	Namespace       string
+	Name            string
+	ResourceVersion string
+	NamespaceOrgID  string // zyndra.io/org-id label of the namespace, empty when unlabeled
+	Managed         bool   // Already managed by Zyndra
+
+	Image        string
+	Port         int32  // First container port, 0 when none is declared
+	StartCommand string // Command and args as a shell command, empty when the image's are used
+
+	// Limits of the container, 0 when unset
+	CPULimitCores    float64
+	MemoryLimitBytes float64
+
+	Env        map[string]string // Variables with literal values
+	SkippedEnv []string          // Variables set from secrets, config maps or fields, and envFrom sources
+
+	HealthCheckPath   string // Path of the HTTP readiness probe, empty without one
+	ProbeInitialDelay int32
+	Containers        int
+}
+
+// ReadDeploymentForImport reads a Deployment to import. Returns ErrDeploymentNotFound
+// when it doesn't exist.
+func (c *Client) ReadDeploymentForImport(ctx context.Context, namespace, name string) (*ImportedDeployment, error) {
+	deployment, err := c.clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrDeploymentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+
+	ns, err := c.clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespace: %w", err)
+	}
+
+	imported := importedDeploymentFrom(deployment)
+	imported.NamespaceOrgID = ns.Labels["zyndra.io/org-id"]
+	imported.Managed = deployment.Labels["app.kubernetes.io/managed-by"] == "zyndra" || deployment.Labels["zyndra.io/service-id"] != ""
+	return imported, nil
+}
+
+// importedDeploymentFrom maps the first container of a deployment
+func importedDeploymentFrom(deployment *appsv1.Deployment) *ImportedDeployment {
+	imported := &ImportedDeployment{
+		Namespace:       deployment.Namespace,
+		Name:            deployment.Name,
+		ResourceVersion: deployment.ResourceVersion,
+		Env:             map[string]string{},
+		Containers:      len(deployment.Spec.Template.Spec.Containers),
+	}
+	if imported.Containers == 0 {
+		return imported
+	}
+
+	container := deployment.Spec.Template.Spec.Containers[0]
+	imported.Image = container.Image
+	if len(container.Ports) > 0 {
+		imported.Port = container.Ports[0].ContainerPort
+	}
+	imported.StartCommand = startCommandFrom(container.Command, container.Args)
+
+	if cpu, ok := container.Resources.Limits[corev1.ResourceCPU]; ok {
+		imported.CPULimitCores = cpu.AsApproximateFloat64()
+	}
+	if memory, ok := container.Resources.Limits[corev1.ResourceMemory]; ok {
+		imported.MemoryLimitBytes = memory.AsApproximateFloat64()
+	}
+
+	for _, env := range container.Env {
+		if env.ValueFrom != nil {
+			imported.SkippedEnv = append(imported.SkippedEnv, env.Name)
+			continue
+		}
+		imported.Env[env.Name] = env.Value
+	}
+	for _, source := range container.EnvFrom {
+		switch {
+		case source.SecretRef != nil:
+			imported.SkippedEnv = append(imported.SkippedEnv, "secret/"+source.SecretRef.Name)
+		case source.ConfigMapRef != nil:
+			imported.SkippedEnv = append(imported.SkippedEnv, "configmap/"+source.ConfigMapRef.Name)
+		}
+	}
+	sort.Strings(imported.SkippedEnv)
+
+	if probe := container.ReadinessProbe; probe != nil {
+		if probe.HTTPGet != nil {
+			imported.HealthCheckPath = probe.HTTPGet.Path
+		}
+		imported.ProbeInitialDelay = probe.InitialDelaySeconds
+	}
+
+	return imported
+}
+
+// startCommandFrom turns a container's command and args into the shell command Zyndra
+// runs with sh -c, the inverse of how DeploymentSpec.Command is set from a start command.
+// Args without a command replace the image's entrypoint too, which isn't known here.
+func startCommandFrom(command, args []string) string {
+	if len(command) == 3 && len(args) == 0 && (command[0] == "sh" || command[0] == "/bin/sh") && command[1] == "-c" {
+		return command[2]
+	}
+
+	words := append(append([]string{}, command...), args...)
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = shellQuote(word)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes a word for sh unless it only holds characters sh leaves alone
+func shellQuote(word string) string {
+	if word != "" && strings.Trim(word, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,@%+") == "" {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'"'"'`) + "'"
+}
+
+// AdoptDeployment labels an imported deployment as managed by Zyndra for a service.
+// Only the Deployment's own labels change, not its pod template or selector, so its
+// pods keep running untouched. Returns ErrDeploymentChanged when the deployment
+// changed since it was read.
+func (c *Client) AdoptDeployment(ctx context.Context, imported *ImportedDeployment, serviceID, serviceName, projectID string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": imported.ResourceVersion,
+			"labels":          c.buildLabels(serviceID, serviceName, projectID),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.clientset.AppsV1().Deployments(imported.Namespace).Patch(ctx, imported.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if apierrors.IsConflict(err) {
+		return ErrDeploymentChanged
+	}
+	if apierrors.IsNotFound(err) {
+		return ErrDeploymentNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to label deployment: %w", err)
+	}
+	return nil
+}
+
+// DeleteImportedDeployment deletes the deployment a service was imported from, once
+// the service runs as a Zyndra deployment
+func (c *Client) DeleteImportedDeployment(ctx context.Context, namespace, name string) error {
+	err := c.clientset.AppsV1().Deployments(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete imported deployment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestImportedDeploymentFrom(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "legacy", ResourceVersion: "42"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Image:   "registry.example.com/api:1.4",
+			Ports:   []corev1.ContainerPort{{ContainerPort: 3000}},
+			Command: []string{"node"},
+			Args:    []string{"server.js", "--name", "it's"},
+			Env: []corev1.EnvVar{
+				{Name: "LOG_LEVEL", Value: "debug"},
+				{Name: "DB_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{Key: "password"}}},
+			},
+			EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "api-secrets"}}}},
+			Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1500m"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			}},
+			ReadinessProbe: &corev1.Probe{
+				ProbeHandler:        corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/healthz"}},
+				InitialDelaySeconds: 10,
+			},
+		}}}}},
+	}
+
+	imported := importedDeploymentFrom(deployment)
+
+	if imported.Image != "registry.example.com/api:1.4" || imported.Port != 3000 {
+		t.Errorf("image/port = %s/%d", imported.Image, imported.Port)
+	}
+	if want := `node server.js --name 'it'"'"'s'`; imported.StartCommand != want {
+		t.Errorf("StartCommand = %s, want %s", imported.StartCommand, want)
+	}
+	if !reflect.DeepEqual(imported.Env, map[string]string{"LOG_LEVEL": "debug"}) {
+		t.Errorf("Env = %v", imported.Env)
+	}
+	if want := []string{"DB_PASSWORD", "secret/api-secrets"}; !reflect.DeepEqual(imported.SkippedEnv, want) {
+		t.Errorf("SkippedEnv = %v, want %v", imported.SkippedEnv, want)
+	}
+	if imported.HealthCheckPath != "/healthz" || imported.ProbeInitialDelay != 10 {
+		t.Errorf("probe = %s after %ds", imported.HealthCheckPath, imported.ProbeInitialDelay)
+	}
+	// 1.5 cores don't fit medium's limit of 1
+	if size := InstanceSizeFitting(imported.CPULimitCores, imported.MemoryLimitBytes); size != "large" {
+		t.Errorf("InstanceSizeFitting = %s, want large", size)
+	}
+}
+
+func TestStartCommandFrom(t *testing.T) {
+	tests := []struct {
+		command, args []string
+		want          string
+	}{
+		{nil, nil, ""},
+		{[]string{"sh", "-c", "npm start && echo done"}, nil, "npm start && echo done"},
+		{[]string{"/app/server"}, []string{"--port=8080"}, "/app/server --port=8080"},
+		{nil, []string{"worker", "two words"}, "worker 'two words'"},
+	}
+
+	for _, tt := range tests {
+		if got := startCommandFrom(tt.command, tt.args); got != tt.want {
+			t.Errorf("startCommandFrom(%q, %q) = %q, want %q", tt.command, tt.args, got, tt.want)
+		}
+	}
+}
@@ -18,6 +18,28 @@ type IngressSpec struct {
 	Environment   string // e.g., "prod", "staging"
 	Port          int32
 	CustomDomains []string // Custom domains to add
+	Streaming     bool     // Service uses WebSockets or streamed responses
+	Subdomain     string   // Generated subdomain label; derived from the service name when empty
+}
+
+// streamingAnnotations configure the ingress controller for WebSockets and streamed
+// responses: no response buffering and long read/send timeouts. Traefik proxies
+// upgrades and streams unbuffered out of the box; these cover nginx ingresses.
+var streamingAnnotations = map[string]string{
+	"nginx.ingress.kubernetes.io/proxy-buffering":    "off",
+	"nginx.ingress.kubernetes.io/proxy-read-timeout": "3600",
+	"nginx.ingress.kubernetes.io/proxy-send-timeout": "3600",
+}
+
+// setStreamingAnnotations adds or removes the streamingAnnotations
+func setStreamingAnnotations(annotations map[string]string, streaming bool) {
+	for key, value := range streamingAnnotations {
+		if streaming {
+			annotations[key] = value
+		} else {
+			delete(annotations, key)
+		}
+	}
 }
 
 // CreateIngress creates a Kubernetes Ingress for a service
@@ -27,7 +49,7 @@ func (c *Client) CreateIngress(ctx context.Context, spec IngressSpec) (*networki
 	serviceName := c.serviceName(spec.ServiceID)
 
 	// Generate default hostname
-	defaultHost := c.generateDefaultHost(spec.ServiceName, spec.Environment)
+	defaultHost := c.defaultHost(spec)
 
 	// Build hosts list (default + custom)
 	hosts := []string{defaultHost}
@@ -86,6 +108,7 @@ func (c *Client) CreateIngress(ctx context.Context, spec IngressSpec) (*networki
 			TLS:              tls,
 		},
 	}
+	setStreamingAnnotations(ingress.Annotations, spec.Streaming)
 
 	result, err := c.clientset.NetworkingV1().Ingresses(namespace).Create(ctx, ingress, metav1.CreateOptions{})
 	if err != nil {
@@ -107,7 +130,7 @@ func (c *Client) UpdateIngress(ctx context.Context, spec IngressSpec) (*networki
 	}
 
 	// Generate default hostname
-	defaultHost := c.generateDefaultHost(spec.ServiceName, spec.Environment)
+	defaultHost := c.defaultHost(spec)
 
 	// Build hosts list
 	hosts := []string{defaultHost}
@@ -141,6 +164,11 @@ func (c *Client) UpdateIngress(ctx context.Context, spec IngressSpec) (*networki
 		})
 	}
 
+	if existing.Annotations == nil {
+		existing.Annotations = make(map[string]string)
+	}
+	setStreamingAnnotations(existing.Annotations, spec.Streaming)
+
 	// Update TLS
 	existing.Spec.Rules = rules
 	existing.Spec.TLS = []networkingv1.IngressTLS{
@@ -289,16 +317,58 @@ func (c *Client) ingressName(serviceID string) string {
 	return "ing-" + serviceID[:8]
 }
 
+// defaultHost returns the generated host of an ingress, the service's own subdomain
+// when it has one
+func (c *Client) defaultHost(spec IngressSpec) string {
+	if spec.Subdomain != "" {
+		return c.SubdomainHost(spec.Subdomain)
+	}
+	return c.generateDefaultHost(spec.ServiceName, spec.Environment)
+}
+
 func (c *Client) generateDefaultHost(serviceName, environment string) string {
 	// Format: servicename-environment.up.zyndra.app
-	name := strings.ToLower(serviceName)
-	name = strings.ReplaceAll(name, " ", "-")
-	name = strings.ReplaceAll(name, "_", "-")
+	name := sanitizeSubdomain(serviceName)
 	
 	if environment == "" {
 		environment = "prod"
 	}
-	
-	return fmt.Sprintf("%s-%s.%s", name, environment, c.config.BaseDomain)
+
+	// Never hand out a reserved platform label, not even as the name part; prefix
+	// it instead. Names with a blocked word aren't used at all.
+	if c.hasBlockedWord(name) {
+		name = "svc"
+	} else if c.isReservedSubdomain(name) {
+		name = "svc-" + name
+	}
+	label := fmt.Sprintf("%s-%s", name, environment)
+	if c.isReservedSubdomain(label) {
+		label = "svc-" + label
+	}
+
+	return fmt.Sprintf("%s.%s", label, c.config.BaseDomain)
+}
+
+// isReservedSubdomain reports whether label is on the reserved subdomain blocklist
+func (c *Client) isReservedSubdomain(label string) bool {
+	for _, reserved := range c.config.ReservedSubdomains {
+		if strings.EqualFold(label, reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasBlockedWord reports whether one of the dash-separated words of label is on the
+// offensive word blocklist
+func (c *Client) hasBlockedWord(label string) bool {
+	for _, word := range strings.Split(label, "-") {
+		for _, blocked := range c.config.BlockedWords {
+			if strings.EqualFold(word, blocked) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
@@ -0,0 +1,78 @@
+package k8s
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// InstanceResources are the CPU and memory requests and limits of an app container
+type InstanceResources struct {
+	CPURequest    string
+	CPULimit      string
+	MemoryRequest string
+	MemoryLimit   string
+}
+
+// instanceSizes lists the instance sizes from smallest to largest
+var instanceSizes = []string{"small", "medium", "large", "xlarge"}
+
+// instanceSizePresets maps a service's instance size to its resources. Memory limits
+// match the memory counted against the org's quota for the same size.
+//
+//	size    cpu request/limit  memory request/limit
+//	small   100m / 500m        256Mi / 512Mi
+//	medium  250m / 1           512Mi / 1Gi
+//	large   500m / 2           1Gi / 2Gi
+//	xlarge  1 / 4              2Gi / 4Gi
+var instanceSizePresets = map[string]InstanceResources{
+	"small":  {CPURequest: "100m", CPULimit: "500m", MemoryRequest: "256Mi", MemoryLimit: "512Mi"},
+	"medium": {CPURequest: "250m", CPULimit: "1", MemoryRequest: "512Mi", MemoryLimit: "1Gi"},
+	"large":  {CPURequest: "500m", CPULimit: "2", MemoryRequest: "1Gi", MemoryLimit: "2Gi"},
+	"xlarge": {CPURequest: "1", CPULimit: "4", MemoryRequest: "2Gi", MemoryLimit: "4Gi"},
+}
+
+// InstanceSizePreset returns the resources for an instance size. An empty size is medium,
+// the default of new services.
+func InstanceSizePreset(size string) (InstanceResources, error) {
+	if size == "" {
+		size = "medium"
+	}
+	preset, ok := instanceSizePresets[size]
+	if !ok {
+		return InstanceResources{}, fmt.Errorf("unknown instance size: %s", size)
+	}
+	return preset, nil
+}
+
+// instanceSizeHeadroom is the share of a size's limits the observed usage may take up,
+// leaving room for spikes beyond the measured percentiles
+const instanceSizeHeadroom = 0.8
+
+// RecommendInstanceSize returns the smallest instance size whose limits fit cpuCores
+// and memoryBytes with headroom to spare. Usage beyond every size gets the largest.
+func RecommendInstanceSize(cpuCores, memoryBytes float64) string {
+	return smallestInstanceSize(cpuCores, memoryBytes, instanceSizeHeadroom)
+}
+
+// InstanceSizeFitting returns the smallest instance size whose limits are at least
+// cpuCores and memoryBytes, e.g. the limits of a container that wasn't deployed by
+// Zyndra. Limits beyond every size get the largest.
+func InstanceSizeFitting(cpuCores, memoryBytes float64) string {
+	return smallestInstanceSize(cpuCores, memoryBytes, 1)
+}
+
+// smallestInstanceSize returns the smallest instance size for which cpuCores and
+// memoryBytes take up at most share of its limits, the largest when none does
+func smallestInstanceSize(cpuCores, memoryBytes, share float64) string {
+	for _, size := range instanceSizes {
+		preset := instanceSizePresets[size]
+		cpuLimit := resource.MustParse(preset.CPULimit)
+		memoryLimit := resource.MustParse(preset.MemoryLimit)
+		if cpuCores <= cpuLimit.AsApproximateFloat64()*share &&
+			memoryBytes <= memoryLimit.AsApproximateFloat64()*share {
+			return size
+		}
+	}
+	return instanceSizes[len(instanceSizes)-1]
+}
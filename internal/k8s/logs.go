@@ -0,0 +1,73 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PodLogOptions selects which logs of a service's pod GetPodLogs returns
+type PodLogOptions struct {
+	SinceTime *time.Time // Only lines logged at or after this time
+	TailLines *int64     // Only the last lines, nil for all of them
+	Follow    bool       // Keep streaming new lines until the context is done
+}
+
+// GetPodLogs returns the log stream of a service's newest running pod, or of its
+// newest pod when none is running. Lines are prefixed with their timestamp. The
+// caller closes the stream.
+func (c *Client) GetPodLogs(ctx context.Context, projectID, serviceID string, opts PodLogOptions) (io.ReadCloser, error) {
+	namespace := c.ProjectNamespace(projectID)
+
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "zyndra.io/service-id=" + serviceID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	pod := newestPod(pods.Items)
+	if pod == nil {
+		return nil, fmt.Errorf("no pods found for service %s", serviceID)
+	}
+
+	logOptions := &corev1.PodLogOptions{
+		TailLines:  opts.TailLines,
+		Follow:     opts.Follow,
+		Timestamps: true,
+	}
+	if opts.SinceTime != nil {
+		since := metav1.NewTime(*opts.SinceTime)
+		logOptions.SinceTime = &since
+	}
+
+	stream, err := c.clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, logOptions).Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs of pod %s: %w", pod.Name, err)
+	}
+	return stream, nil
+}
+
+// newestPod returns the most recently created running pod, falling back to the
+// most recently created pod of any phase
+func newestPod(pods []corev1.Pod) *corev1.Pod {
+	var newest, newestRunning *corev1.Pod
+	for i := range pods {
+		pod := &pods[i]
+		if newest == nil || pod.CreationTimestamp.After(newest.CreationTimestamp.Time) {
+			newest = pod
+		}
+		if pod.Status.Phase == corev1.PodRunning &&
+			(newestRunning == nil || pod.CreationTimestamp.After(newestRunning.CreationTimestamp.Time)) {
+			newestRunning = pod
+		}
+	}
+	if newestRunning != nil {
+		return newestRunning
+	}
+	return newest
+}
@@ -108,6 +108,43 @@ func (m *MetricsClient) GetServiceMetrics(ctx context.Context, projectID, servic
 	return result, nil
 }
 
+// DatabaseMetrics represents aggregated metrics for a database
+type DatabaseMetrics struct {
+	DatabaseID  string       `json:"database_id"`
+	Engine      string       `json:"engine"`
+	TotalCPU    float64      `json:"total_cpu_cores"`
+	TotalMemory float64      `json:"total_memory_mb"`
+	PodCount    int          `json:"pod_count"`
+	Pods        []PodMetrics `json:"pods"`
+}
+
+// GetDatabaseMetrics gets aggregated metrics for all pods of a database
+func (m *MetricsClient) GetDatabaseMetrics(ctx context.Context, projectID, databaseID, engine string) (*DatabaseMetrics, error) {
+	namespace := m.config.NamespacePrefix + projectID
+
+	podMetricsList, err := m.client.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("zyndra.io/database-id=%s", databaseID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod metrics: %w", err)
+	}
+
+	result := &DatabaseMetrics{
+		DatabaseID: databaseID,
+		Engine:     engine,
+		Pods:       make([]PodMetrics, 0, len(podMetricsList.Items)),
+	}
+	for _, pm := range podMetricsList.Items {
+		podMetric := m.convertPodMetrics(&pm)
+		result.Pods = append(result.Pods, *podMetric)
+		result.TotalCPU += podMetric.CPUCores
+		result.TotalMemory += podMetric.MemoryMB
+	}
+	result.PodCount = len(result.Pods)
+
+	return result, nil
+}
+
 // GetNamespaceMetrics gets metrics for all pods in a project namespace
 func (m *MetricsClient) GetNamespaceMetrics(ctx context.Context, projectID string) ([]PodMetrics, error) {
 	namespace := m.config.NamespacePrefix + projectID
@@ -16,7 +16,7 @@ type PVCSpec struct {
 	VolumeName   string
 	ProjectID    string
 	SizeMB       int64  // Size in megabytes
-	StorageClass string // e.g., "longhorn" for Longhorn volumes
+	StorageClass string // Empty uses the client's default storage class
 	AccessMode   string // ReadWriteOnce, ReadWriteMany, ReadOnlyMany
 }
 
@@ -26,9 +26,9 @@ func (c *Client) CreatePVC(ctx context.Context, spec PVCSpec) (*corev1.Persisten
 	pvcName := c.pvcName(spec.VolumeID)
 
 	// Set defaults
-	storageClass := spec.StorageClass
-	if storageClass == "" {
-		storageClass = "longhorn" // Default to Longhorn
+	storageClass := c.storageClass(spec.StorageClass)
+	if err := c.EnsureStorageClass(ctx, storageClass); err != nil {
+		return nil, err
 	}
 
 	accessMode := corev1.ReadWriteOnce
@@ -157,6 +157,26 @@ func (c *Client) PVCName(volumeID string) string {
 	return c.pvcName(volumeID)
 }
 
+// EnsureStorageClass returns an error if the cluster has no storage class named name
+func (c *Client) EnsureStorageClass(ctx context.Context, name string) error {
+	_, err := c.clientset.StorageV1().StorageClasses().Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("storage class %q does not exist in the cluster", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get storage class %s: %w", name, err)
+	}
+	return nil
+}
+
+// storageClass returns override, or the default storage class when it's empty
+func (c *Client) storageClass(override string) string {
+	if override != "" {
+		return override
+	}
+	return c.config.StorageClass
+}
+
 func (c *Client) pvcName(volumeID string) string {
 	return "vol-" + volumeID[:8]
 }
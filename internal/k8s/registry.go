@@ -0,0 +1,92 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrRegionNotConfigured is returned for a region without a cluster
+var ErrRegionNotConfigured = errors.New("region not configured")
+
+// ClientRegistry holds the clients of the cluster of each region. Projects
+// without a region run on the default region's cluster.
+type ClientRegistry struct {
+	defaultRegion string
+	clients       map[string]*Client
+	metrics       map[string]*MetricsClient
+}
+
+// NewClientRegistry creates an empty registry
+func NewClientRegistry(defaultRegion string) *ClientRegistry {
+	return &ClientRegistry{
+		defaultRegion: defaultRegion,
+		clients:       make(map[string]*Client),
+		metrics:       make(map[string]*MetricsClient),
+	}
+}
+
+// Register adds the clients of a region's cluster. metrics may be nil when the
+// cluster's metrics server can't be reached.
+func (r *ClientRegistry) Register(region string, client *Client, metrics *MetricsClient) {
+	r.clients[region] = client
+	if metrics != nil {
+		r.metrics[region] = metrics
+	}
+}
+
+// DefaultRegion returns the region used by projects without one
+func (r *ClientRegistry) DefaultRegion() string {
+	return r.defaultRegion
+}
+
+// Regions returns the regions with a cluster, sorted
+func (r *ClientRegistry) Regions() []string {
+	if r == nil {
+		return nil
+	}
+	regions := make([]string, 0, len(r.clients))
+	for region := range r.clients {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// HasRegion reports whether region (or the default region, if empty) has a cluster
+func (r *ClientRegistry) HasRegion(region string) bool {
+	_, err := r.Client(region)
+	return err == nil
+}
+
+// Client returns the client of a region's cluster, or of the default region's if
+// region is empty
+func (r *ClientRegistry) Client(region string) (*Client, error) {
+	if r == nil {
+		return nil, ErrRegionNotConfigured
+	}
+	if region == "" {
+		region = r.defaultRegion
+	}
+	client, ok := r.clients[region]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRegionNotConfigured, region)
+	}
+	return client, nil
+}
+
+// MetricsClient returns the metrics client of a region's cluster, or of the
+// default region's if region is empty
+func (r *ClientRegistry) MetricsClient(region string) (*MetricsClient, error) {
+	if r == nil {
+		return nil, ErrRegionNotConfigured
+	}
+	if region == "" {
+		region = r.defaultRegion
+	}
+	metrics, ok := r.metrics[region]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRegionNotConfigured, region)
+	}
+	return metrics, nil
+}
@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Where a runtime env var gets its value
+const (
+	EnvSourceLiteral   = "literal"
+	EnvSourceSecret    = "secret"
+	EnvSourceConfigMap = "configmap"
+	EnvSourceField     = "field"
+)
+
+// RuntimeConfig is what a service's Deployment runs according to the cluster, which
+// may differ from the service's config in the database. Env var values are never read.
+type RuntimeConfig struct {
+	Image           string
+	Command         []string
+	Replicas        int32 // Desired replicas
+	ReadyReplicas   int32
+	UpdatedReplicas int32
+
+	// Requests and limits of the container as quantities, e.g. 500m or 1Gi; empty when unset
+	CPURequest    string
+	CPULimit      string
+	MemoryRequest string
+	MemoryLimit   string
+
+	EnvVars []RuntimeEnvVar // Sorted by key
+	// Secrets and config maps the container takes its env from that don't exist,
+	// as secret/<name> or configmap/<name>
+	MissingEnvSources []string
+}
+
+// RuntimeEnvVar is an env var of a running container
+type RuntimeEnvVar struct {
+	Key    string
+	Source string // One of the EnvSource constants
+	From   string // Name of the secret or config map the value comes from
+}
+
+// GetRuntimeConfig reads the live Deployment of a service and the keys of the
+// secrets and config maps its env comes from. Returns ErrDeploymentNotFound when
+// the service has no Deployment.
+func (c *Client) GetRuntimeConfig(ctx context.Context, projectID, serviceID string) (*RuntimeConfig, error) {
+	namespace := c.ProjectNamespace(projectID)
+
+	deployment, err := c.GetDeployment(ctx, projectID, serviceID)
+	if apierrors.IsNotFound(err) {
+		return nil, ErrDeploymentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment: %w", err)
+	}
+
+	config := runtimeConfigFrom(deployment)
+	if len(deployment.Spec.Template.Spec.Containers) == 0 {
+		return config, nil
+	}
+
+	// Vars the container sets itself win over the ones of its env sources, and a
+	// later source wins over an earlier one
+	explicit := make(map[string]bool, len(config.EnvVars))
+	for _, v := range config.EnvVars {
+		explicit[v.Key] = true
+	}
+	fromSources := make(map[string]RuntimeEnvVar)
+
+	for _, source := range deployment.Spec.Template.Spec.Containers[0].EnvFrom {
+		var keys []string
+		var envSource, name string
+		switch {
+		case source.SecretRef != nil:
+			envSource, name = EnvSourceSecret, source.SecretRef.Name
+			secret, err := c.clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err == nil {
+				for key := range secret.Data {
+					keys = append(keys, key)
+				}
+			} else if !apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to get secret %s: %w", name, err)
+			}
+		case source.ConfigMapRef != nil:
+			envSource, name = EnvSourceConfigMap, source.ConfigMapRef.Name
+			configMap, err := c.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err == nil {
+				for key := range configMap.Data {
+					keys = append(keys, key)
+				}
+			} else if !apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to get config map %s: %w", name, err)
+			}
+		default:
+			continue
+		}
+
+		if keys == nil {
+			config.MissingEnvSources = append(config.MissingEnvSources, envSource+"/"+name)
+			continue
+		}
+		for _, key := range keys {
+			fromSources[source.Prefix+key] = RuntimeEnvVar{Key: source.Prefix + key, Source: envSource, From: name}
+		}
+	}
+
+	for key, v := range fromSources {
+		if !explicit[key] {
+			config.EnvVars = append(config.EnvVars, v)
+		}
+	}
+	sortRuntimeEnv(config.EnvVars)
+	return config, nil
+}
+
+// runtimeConfigFrom maps the first container of a deployment, with the env vars it
+// sets itself
+func runtimeConfigFrom(deployment *appsv1.Deployment) *RuntimeConfig {
+	status := deploymentStatusFrom(deployment)
+	config := &RuntimeConfig{
+		Replicas:        status.DesiredReplicas,
+		ReadyReplicas:   status.ReadyReplicas,
+		UpdatedReplicas: status.UpdatedReplicas,
+	}
+	if len(deployment.Spec.Template.Spec.Containers) == 0 {
+		return config
+	}
+
+	container := deployment.Spec.Template.Spec.Containers[0]
+	config.Image = container.Image
+	config.Command = append(append([]string{}, container.Command...), container.Args...)
+
+	quantity := func(list corev1.ResourceList, name corev1.ResourceName) string {
+		if q, ok := list[name]; ok {
+			return q.String()
+		}
+		return ""
+	}
+	config.CPURequest = quantity(container.Resources.Requests, corev1.ResourceCPU)
+	config.CPULimit = quantity(container.Resources.Limits, corev1.ResourceCPU)
+	config.MemoryRequest = quantity(container.Resources.Requests, corev1.ResourceMemory)
+	config.MemoryLimit = quantity(container.Resources.Limits, corev1.ResourceMemory)
+
+	for _, env := range container.Env {
+		v := RuntimeEnvVar{Key: env.Name, Source: EnvSourceLiteral}
+		switch from := env.ValueFrom; {
+		case from == nil:
+		case from.SecretKeyRef != nil:
+			v.Source, v.From = EnvSourceSecret, from.SecretKeyRef.Name
+		case from.ConfigMapKeyRef != nil:
+			v.Source, v.From = EnvSourceConfigMap, from.ConfigMapKeyRef.Name
+		default:
+			v.Source = EnvSourceField
+		}
+		config.EnvVars = append(config.EnvVars, v)
+	}
+	sortRuntimeEnv(config.EnvVars)
+
+	return config
+}
+
+func sortRuntimeEnv(envVars []RuntimeEnvVar) {
+	sort.Slice(envVars, func(i, j int) bool { return envVars[i].Key < envVars[j].Key })
+}
@@ -0,0 +1,58 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRuntimeConfigFrom(t *testing.T) {
+	replicas := int32(2)
+	deployment := &appsv1.Deployment{
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Image:   "registry.example.com/api:abc123",
+				Command: []string{"sh", "-c"},
+				Args:    []string{"npm start"},
+				Env: []corev1.EnvVar{
+					{Name: "LOG_LEVEL", Value: "debug"},
+					{Name: "DB_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "db"},
+						Key:                  "password",
+					}}},
+					{Name: "POD_IP", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"}}},
+				},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+					Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+				},
+			}}}},
+		},
+		Status: appsv1.DeploymentStatus{ReadyReplicas: 1, UpdatedReplicas: 2},
+	}
+
+	config := runtimeConfigFrom(deployment)
+
+	if config.Image != "registry.example.com/api:abc123" || config.Replicas != 2 || config.ReadyReplicas != 1 {
+		t.Errorf("image/replicas/ready = %s/%d/%d", config.Image, config.Replicas, config.ReadyReplicas)
+	}
+	if !reflect.DeepEqual(config.Command, []string{"sh", "-c", "npm start"}) {
+		t.Errorf("command = %v", config.Command)
+	}
+	if config.CPURequest != "250m" || config.CPULimit != "" || config.MemoryLimit != "512Mi" {
+		t.Errorf("resources = %+v", config)
+	}
+
+	want := []RuntimeEnvVar{
+		{Key: "DB_PASSWORD", Source: EnvSourceSecret, From: "db"},
+		{Key: "LOG_LEVEL", Source: EnvSourceLiteral},
+		{Key: "POD_IP", Source: EnvSourceField},
+	}
+	if !reflect.DeepEqual(config.EnvVars, want) {
+		t.Errorf("env = %+v, want %+v", config.EnvVars, want)
+	}
+}
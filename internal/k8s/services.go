@@ -106,6 +106,11 @@ func (c *Client) GetService(ctx context.Context, projectID, serviceID string) (*
 	return c.clientset.CoreV1().Services(namespace).Get(ctx, serviceName, metav1.GetOptions{})
 }
 
+// ServiceHost returns the in-cluster DNS name of a service's Kubernetes Service
+func (c *Client) ServiceHost(projectID, serviceID string) string {
+	return fmt.Sprintf("%s.%s.svc.cluster.local", c.serviceName(serviceID), c.ProjectNamespace(projectID))
+}
+
 func (c *Client) serviceName(serviceID string) string {
 	return "svc-" + serviceID[:8]
 }
@@ -0,0 +1,127 @@
+package k8s
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// A generated subdomain is a single DNS label under the base domain
+var (
+	subdomainPattern      = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+	subdomainInvalidChars = regexp.MustCompile(`[^a-z0-9-]`)
+)
+
+const (
+	subdomainMaxLen = 63
+	// Length of the random suffix of a regenerated subdomain
+	subdomainSuffixLen = 6
+)
+
+// ValidateSubdomain checks that label can be a service's generated subdomain: a
+// lowercase DNS label that isn't reserved and has no blocked word
+func (c *Client) ValidateSubdomain(label string) error {
+	if label == "" || len(label) > subdomainMaxLen {
+		return fmt.Errorf("subdomain must be 1 to %d characters", subdomainMaxLen)
+	}
+	if !subdomainPattern.MatchString(label) {
+		return fmt.Errorf("subdomain may only contain lowercase letters, digits and dashes, and must start and end with a letter or digit")
+	}
+	if c.isReservedSubdomain(label) {
+		return fmt.Errorf("subdomain %q is reserved", label)
+	}
+	if c.hasBlockedWord(label) {
+		return fmt.Errorf("subdomain %q is not allowed", label)
+	}
+	return nil
+}
+
+// GenerateSubdomain returns a new subdomain for a service, its name with a random
+// suffix, so regenerating one never hands back the label it replaces
+func (c *Client) GenerateSubdomain(serviceName string) (string, error) {
+	suffix := make([]byte, subdomainSuffixLen/2)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+
+	name := subdomainInvalidChars.ReplaceAllString(sanitizeSubdomain(serviceName), "")
+	if max := subdomainMaxLen - subdomainSuffixLen - 1; len(name) > max {
+		name = name[:max]
+	}
+	name = strings.Trim(name, "-")
+	if name == "" || c.hasBlockedWord(name) {
+		name = "svc"
+	}
+	return name + "-" + hex.EncodeToString(suffix), nil
+}
+
+// SubdomainCandidate generates a subdomain for a service, see GenerateSubdomain, and
+// returns it with the URL the service would serve at under it. It fits
+// store.SubdomainGenerator.
+func (c *Client) SubdomainCandidate(serviceName string) (string, string, error) {
+	label, err := c.GenerateSubdomain(serviceName)
+	if err != nil {
+		return "", "", err
+	}
+	if err := c.ValidateSubdomain(label); err != nil {
+		return "", "", err
+	}
+	return label, c.SubdomainURL(label), nil
+}
+
+// SubdomainHost returns the host of a generated subdomain
+func (c *Client) SubdomainHost(label string) string {
+	return label + "." + c.config.BaseDomain
+}
+
+// SubdomainURL returns the URL a generated subdomain serves at
+func (c *Client) SubdomainURL(label string) string {
+	return "https://" + c.SubdomainHost(label)
+}
+
+// SetIngressDefaultHost replaces the generated host of a service's ingress, its first
+// rule, so the previous host stops routing as soon as the ingress controller syncs.
+// Custom domain rules are kept. A service without an ingress yet is left alone; its
+// first deploy creates the ingress with the new host.
+func (c *Client) SetIngressDefaultHost(ctx context.Context, projectID, serviceID, host string) error {
+	existing, err := c.GetIngress(ctx, projectID, serviceID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get ingress: %w", err)
+	}
+	if len(existing.Spec.Rules) == 0 {
+		return nil
+	}
+
+	oldHost := existing.Spec.Rules[0].Host
+	existing.Spec.Rules[0].Host = host
+	for i := range existing.Spec.TLS {
+		for j, h := range existing.Spec.TLS[i].Hosts {
+			if h == oldHost {
+				existing.Spec.TLS[i].Hosts[j] = host
+			}
+		}
+	}
+
+	namespace := c.ProjectNamespace(projectID)
+	if _, err := c.clientset.NetworkingV1().Ingresses(namespace).Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ingress: %w", err)
+	}
+	return nil
+}
+
+// sanitizeSubdomain lowercases a service name and turns spaces and underscores into
+// dashes, as generated hosts do
+func sanitizeSubdomain(name string) string {
+	name = strings.ToLower(name)
+	name = strings.ReplaceAll(name, " ", "-")
+	return strings.ReplaceAll(name, "_", "-")
+}
@@ -0,0 +1,94 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSubdomain(t *testing.T) {
+	c := &Client{config: Config{ReservedSubdomains: []string{"api", "admin"}, BlockedWords: []string{"porn"}}}
+
+	tests := []struct {
+		label   string
+		wantErr bool
+	}{
+		{"my-app", false},
+		{"app2", false},
+		{"", true},
+		{"My-App", true},
+		{"-app", true},
+		{"app-", true},
+		{"my.app", true},
+		{strings.Repeat("a", 64), true},
+		{"api", true},
+		{"ADMIN", true},
+		{"porn-a1b2c3", true},
+		{"pornography", false},
+	}
+	for _, tt := range tests {
+		if err := c.ValidateSubdomain(tt.label); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateSubdomain(%q) error = %v, wantErr %v", tt.label, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGenerateSubdomain(t *testing.T) {
+	c := &Client{}
+
+	for _, name := range []string{"My Web_App", "!!!", strings.Repeat("long-name", 10)} {
+		label, err := c.GenerateSubdomain(name)
+		if err != nil {
+			t.Fatalf("GenerateSubdomain(%q) error: %v", name, err)
+		}
+		if err := c.ValidateSubdomain(label); err != nil {
+			t.Errorf("GenerateSubdomain(%q) = %q, not a valid subdomain: %v", name, label, err)
+		}
+	}
+
+	label, _ := c.GenerateSubdomain("My Web_App")
+	if !strings.HasPrefix(label, "my-web-app-") {
+		t.Errorf("GenerateSubdomain(%q) = %q, want the service name as prefix", "My Web_App", label)
+	}
+	if other, _ := c.GenerateSubdomain("My Web_App"); other == label {
+		t.Errorf("GenerateSubdomain returned %q twice", label)
+	}
+}
+
+func TestGenerateSubdomain_BlockedWord(t *testing.T) {
+	c := &Client{config: Config{BlockedWords: []string{"porn"}}}
+
+	label, err := c.GenerateSubdomain("Porn Hub")
+	if err != nil {
+		t.Fatalf("GenerateSubdomain error: %v", err)
+	}
+	if !strings.HasPrefix(label, "svc-") {
+		t.Errorf("GenerateSubdomain(%q) = %q, want the name replaced", "Porn Hub", label)
+	}
+}
+
+func TestGenerateDefaultHost(t *testing.T) {
+	c := &Client{config: Config{
+		BaseDomain:         "up.zyndra.app",
+		ReservedSubdomains: []string{"api", "admin-prod"},
+		BlockedWords:       []string{"porn"},
+	}}
+
+	tests := []struct {
+		serviceName string
+		environment string
+		want        string
+	}{
+		{serviceName: "Web App", want: "web-app-prod.up.zyndra.app"},
+		{serviceName: "web", environment: "staging", want: "web-staging.up.zyndra.app"},
+		// A reserved service name, which the environment would otherwise hide
+		{serviceName: "API", want: "svc-api-prod.up.zyndra.app"},
+		{serviceName: "admin", want: "svc-admin-prod.up.zyndra.app"},
+		{serviceName: "porn-site", want: "svc-prod.up.zyndra.app"},
+		{serviceName: "Porn", environment: "dev", want: "svc-dev.up.zyndra.app"},
+	}
+	for _, tt := range tests {
+		if got := c.generateDefaultHost(tt.serviceName, tt.environment); got != tt.want {
+			t.Errorf("generateDefaultHost(%q, %q) = %q, want %q", tt.serviceName, tt.environment, got, tt.want)
+		}
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+// Service, database and volume series carry the org_id of the owning organization
+// (OrgLabel), so queries on behalf of an org can be constrained to its series
 var (
 	// Service metrics
 	ServiceCPUUsage = promauto.NewGaugeVec(
@@ -14,7 +16,7 @@ var (
 			Name: "click_deploy_service_cpu_usage_percent",
 			Help: "CPU usage percentage for a service",
 		},
-		[]string{"service_id", "service_name"},
+		[]string{"org_id", "service_id", "service_name"},
 	)
 
 	ServiceMemoryUsage = promauto.NewGaugeVec(
@@ -22,7 +24,7 @@ var (
 			Name: "click_deploy_service_memory_usage_bytes",
 			Help: "Memory usage in bytes for a service",
 		},
-		[]string{"service_id", "service_name"},
+		[]string{"org_id", "service_id", "service_name"},
 	)
 
 	ServiceNetworkTrafficIn = promauto.NewCounterVec(
@@ -30,7 +32,7 @@ var (
 			Name: "click_deploy_service_network_traffic_in_bytes_total",
 			Help: "Total incoming network traffic in bytes for a service",
 		},
-		[]string{"service_id", "service_name"},
+		[]string{"org_id", "service_id", "service_name"},
 	)
 
 	ServiceNetworkTrafficOut = promauto.NewCounterVec(
@@ -38,7 +40,7 @@ var (
 			Name: "click_deploy_service_network_traffic_out_bytes_total",
 			Help: "Total outgoing network traffic in bytes for a service",
 		},
-		[]string{"service_id", "service_name"},
+		[]string{"org_id", "service_id", "service_name"},
 	)
 
 	ServiceRequestCount = promauto.NewCounterVec(
@@ -46,7 +48,7 @@ var (
 			Name: "click_deploy_service_requests_total",
 			Help: "Total number of requests to a service",
 		},
-		[]string{"service_id", "service_name", "status_code"},
+		[]string{"org_id", "service_id", "service_name", "status_code"},
 	)
 
 	ServiceRequestDuration = promauto.NewHistogramVec(
@@ -55,7 +57,7 @@ var (
 			Help:    "Request duration in seconds for a service",
 			Buckets: prometheus.DefBuckets,
 		},
-		[]string{"service_id", "service_name"},
+		[]string{"org_id", "service_id", "service_name"},
 	)
 
 	ServiceErrorRate = promauto.NewGaugeVec(
@@ -63,7 +65,7 @@ var (
 			Name: "click_deploy_service_error_rate",
 			Help: "Error rate (errors per second) for a service",
 		},
-		[]string{"service_id", "service_name"},
+		[]string{"org_id", "service_id", "service_name"},
 	)
 
 	// Database metrics
@@ -72,7 +74,7 @@ var (
 			Name: "click_deploy_database_cpu_usage_percent",
 			Help: "CPU usage percentage for a database",
 		},
-		[]string{"database_id", "database_name", "engine"},
+		[]string{"org_id", "database_id", "database_name", "engine"},
 	)
 
 	DatabaseMemoryUsage = promauto.NewGaugeVec(
@@ -80,7 +82,7 @@ var (
 			Name: "click_deploy_database_memory_usage_bytes",
 			Help: "Memory usage in bytes for a database",
 		},
-		[]string{"database_id", "database_name", "engine"},
+		[]string{"org_id", "database_id", "database_name", "engine"},
 	)
 
 	DatabaseNetworkTrafficIn = promauto.NewCounterVec(
@@ -88,7 +90,7 @@ var (
 			Name: "click_deploy_database_network_traffic_in_bytes_total",
 			Help: "Total incoming network traffic in bytes for a database",
 		},
-		[]string{"database_id", "database_name", "engine"},
+		[]string{"org_id", "database_id", "database_name", "engine"},
 	)
 
 	DatabaseNetworkTrafficOut = promauto.NewCounterVec(
@@ -96,7 +98,7 @@ var (
 			Name: "click_deploy_database_network_traffic_out_bytes_total",
 			Help: "Total outgoing network traffic in bytes for a database",
 		},
-		[]string{"database_id", "database_name", "engine"},
+		[]string{"org_id", "database_id", "database_name", "engine"},
 	)
 
 	DatabaseConnections = promauto.NewGaugeVec(
@@ -104,7 +106,7 @@ var (
 			Name: "click_deploy_database_connections",
 			Help: "Number of active connections to a database",
 		},
-		[]string{"database_id", "database_name", "engine"},
+		[]string{"org_id", "database_id", "database_name", "engine"},
 	)
 
 	// Volume metrics
@@ -113,7 +115,7 @@ var (
 			Name: "click_deploy_volume_usage_bytes",
 			Help: "Volume usage in bytes",
 		},
-		[]string{"volume_id", "volume_name"},
+		[]string{"org_id", "volume_id", "volume_name"},
 	)
 
 	VolumeIORead = promauto.NewCounterVec(
@@ -121,7 +123,7 @@ var (
 			Name: "click_deploy_volume_io_read_bytes_total",
 			Help: "Total bytes read from volume",
 		},
-		[]string{"volume_id", "volume_name"},
+		[]string{"org_id", "volume_id", "volume_name"},
 	)
 
 	VolumeIOWrite = promauto.NewCounterVec(
@@ -129,42 +131,66 @@ var (
 			Name: "click_deploy_volume_io_write_bytes_total",
 			Help: "Total bytes written to volume",
 		},
-		[]string{"volume_id", "volume_name"},
+		[]string{"org_id", "volume_id", "volume_name"},
+	)
+
+	// Circuit breaker metrics
+	CircuitBreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "click_deploy_circuit_breaker_state",
+			Help: "Current circuit breaker state (0 = closed, 1 = open, 2 = half-open)",
+		},
+		[]string{"breaker"},
+	)
+
+	CircuitBreakerTransitions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "click_deploy_circuit_breaker_transitions_total",
+			Help: "Total circuit breaker state transitions",
+		},
+		[]string{"breaker", "from", "to"},
 	)
 )
 
 // RecordServiceMetrics records metrics for a service
-func RecordServiceMetrics(serviceID, serviceName string, cpuPercent float64, memoryBytes int64, networkIn, networkOut int64, requestCount int, responseTime time.Duration, errorCount int) {
-	ServiceCPUUsage.WithLabelValues(serviceID, serviceName).Set(cpuPercent)
-	ServiceMemoryUsage.WithLabelValues(serviceID, serviceName).Set(float64(memoryBytes))
-	ServiceNetworkTrafficIn.WithLabelValues(serviceID, serviceName).Add(float64(networkIn))
-	ServiceNetworkTrafficOut.WithLabelValues(serviceID, serviceName).Add(float64(networkOut))
-	ServiceRequestDuration.WithLabelValues(serviceID, serviceName).Observe(responseTime.Seconds())
+func RecordServiceMetrics(orgID, serviceID, serviceName string, cpuPercent float64, memoryBytes int64, networkIn, networkOut int64, requestCount int, responseTime time.Duration, errorCount int) {
+	ServiceCPUUsage.WithLabelValues(orgID, serviceID, serviceName).Set(cpuPercent)
+	ServiceMemoryUsage.WithLabelValues(orgID, serviceID, serviceName).Set(float64(memoryBytes))
+	ServiceNetworkTrafficIn.WithLabelValues(orgID, serviceID, serviceName).Add(float64(networkIn))
+	ServiceNetworkTrafficOut.WithLabelValues(orgID, serviceID, serviceName).Add(float64(networkOut))
+	ServiceRequestDuration.WithLabelValues(orgID, serviceID, serviceName).Observe(responseTime.Seconds())
 	
 	// Calculate error rate (errors per second over last minute)
 	errorRate := float64(errorCount) / 60.0
-	ServiceErrorRate.WithLabelValues(serviceID, serviceName).Set(errorRate)
+	ServiceErrorRate.WithLabelValues(orgID, serviceID, serviceName).Set(errorRate)
 }
 
 // RecordServiceRequest records a single request metric
-func RecordServiceRequest(serviceID, serviceName string, statusCode int, duration time.Duration) {
-	ServiceRequestCount.WithLabelValues(serviceID, serviceName, string(rune(statusCode))).Inc()
-	ServiceRequestDuration.WithLabelValues(serviceID, serviceName).Observe(duration.Seconds())
+func RecordServiceRequest(orgID, serviceID, serviceName string, statusCode int, duration time.Duration) {
+	ServiceRequestCount.WithLabelValues(orgID, serviceID, serviceName, string(rune(statusCode))).Inc()
+	ServiceRequestDuration.WithLabelValues(orgID, serviceID, serviceName).Observe(duration.Seconds())
 }
 
 // RecordDatabaseMetrics records metrics for a database
-func RecordDatabaseMetrics(databaseID, databaseName, engine string, cpuPercent float64, memoryBytes int64, networkIn, networkOut int64, connections int) {
-	DatabaseCPUUsage.WithLabelValues(databaseID, databaseName, engine).Set(cpuPercent)
-	DatabaseMemoryUsage.WithLabelValues(databaseID, databaseName, engine).Set(float64(memoryBytes))
-	DatabaseNetworkTrafficIn.WithLabelValues(databaseID, databaseName, engine).Add(float64(networkIn))
-	DatabaseNetworkTrafficOut.WithLabelValues(databaseID, databaseName, engine).Add(float64(networkOut))
-	DatabaseConnections.WithLabelValues(databaseID, databaseName, engine).Set(float64(connections))
+func RecordDatabaseMetrics(orgID, databaseID, databaseName, engine string, cpuPercent float64, memoryBytes int64, networkIn, networkOut int64, connections int) {
+	DatabaseCPUUsage.WithLabelValues(orgID, databaseID, databaseName, engine).Set(cpuPercent)
+	DatabaseMemoryUsage.WithLabelValues(orgID, databaseID, databaseName, engine).Set(float64(memoryBytes))
+	DatabaseNetworkTrafficIn.WithLabelValues(orgID, databaseID, databaseName, engine).Add(float64(networkIn))
+	DatabaseNetworkTrafficOut.WithLabelValues(orgID, databaseID, databaseName, engine).Add(float64(networkOut))
+	DatabaseConnections.WithLabelValues(orgID, databaseID, databaseName, engine).Set(float64(connections))
 }
 
 // RecordVolumeMetrics records metrics for a volume
-func RecordVolumeMetrics(volumeID, volumeName string, usageBytes int64, readBytes, writeBytes int64) {
-	VolumeUsage.WithLabelValues(volumeID, volumeName).Set(float64(usageBytes))
-	VolumeIORead.WithLabelValues(volumeID, volumeName).Add(float64(readBytes))
-	VolumeIOWrite.WithLabelValues(volumeID, volumeName).Add(float64(writeBytes))
+func RecordVolumeMetrics(orgID, volumeID, volumeName string, usageBytes int64, readBytes, writeBytes int64) {
+	VolumeUsage.WithLabelValues(orgID, volumeID, volumeName).Set(float64(usageBytes))
+	VolumeIORead.WithLabelValues(orgID, volumeID, volumeName).Add(float64(readBytes))
+	VolumeIOWrite.WithLabelValues(orgID, volumeID, volumeName).Add(float64(writeBytes))
 }
 
+
+// RecordCircuitBreakerTransition records a circuit breaker state change.
+// state is the numeric value of the new state.
+func RecordCircuitBreakerTransition(breaker, from, to string, state int) {
+	CircuitBreakerState.WithLabelValues(breaker).Set(float64(state))
+	CircuitBreakerTransitions.WithLabelValues(breaker, from, to).Inc()
+}
@@ -26,7 +26,7 @@ func NewTargetManager(targetsDir string) *TargetManager {
 }
 
 // RegisterInstance registers an instance as a Prometheus scrape target
-func (tm *TargetManager) RegisterInstance(instanceIP, instanceID, serviceID, projectID, serviceName string) error {
+func (tm *TargetManager) RegisterInstance(instanceIP, instanceID, orgID, serviceID, projectID, serviceName string) error {
 	// Ensure targets directory exists
 	if err := os.MkdirAll(tm.targetsDir, 0755); err != nil {
 		return fmt.Errorf("failed to create targets directory: %w", err)
@@ -42,6 +42,7 @@ func (tm *TargetManager) RegisterInstance(instanceIP, instanceID, serviceID, pro
 		},
 		Labels: map[string]string{
 			"instance_id": instanceID,
+			OrgLabel:       orgID,
 			"service_id":   serviceID,
 			"project_id":   projectID,
 			"service_name": serviceName,
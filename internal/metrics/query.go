@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PrometheusClient runs PromQL queries against the Prometheus HTTP API
+type PrometheusClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Sample is a single series of an instant query result
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// NewPrometheusClient creates a new Prometheus query client
+func NewPrometheusClient(baseURL string) *PrometheusClient {
+	return &PrometheusClient{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Query runs an instant query evaluated at ts
+func (c *PrometheusClient) Query(ctx context.Context, query string, ts time.Time) ([]Sample, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("time", strconv.FormatInt(ts.Unix(), 10))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/v1/query?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prometheus: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prometheus response: %w", err)
+	}
+
+	// Prometheus also reports query errors (400/422) in the JSON body
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusUnprocessableEntity {
+		return nil, fmt.Errorf("prometheus returned status %d", resp.StatusCode)
+	}
+
+	return parsePrometheusResult(body)
+}
+
+// parsePrometheusResult parses an instant query response with a vector result
+func parsePrometheusResult(body []byte) ([]Sample, error) {
+	var result struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Value  [2]interface{}    `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse prometheus response: %w", err)
+	}
+
+	if result.Status != "success" {
+		return nil, fmt.Errorf("prometheus query failed: %s", result.Error)
+	}
+	if result.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("unexpected prometheus result type: %s", result.Data.ResultType)
+	}
+
+	samples := make([]Sample, 0, len(result.Data.Result))
+	for _, r := range result.Data.Result {
+		// Values are encoded as [<unix time>, "<value>"]
+		raw, ok := r.Value[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected prometheus sample value: %v", r.Value[1])
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prometheus sample value %q: %w", raw, err)
+		}
+		samples = append(samples, Sample{Labels: r.Metric, Value: value})
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OrgLabel is the label carrying the owning organization on the series Zyndra exports
+const OrgLabel = "org_id"
+
+// NamespaceOrgLabel is the kube-state-metrics label carrying the zyndra.io/org-id label
+// of project namespaces. kube-state-metrics only exports it when run with
+// --metric-labels-allowlist=namespaces=[zyndra.io/org-id].
+const NamespaceOrgLabel = "label_zyndra_io_org_id"
+
+// ErrQueryNotScoped is returned for an org's query that isn't constrained by the org
+var ErrQueryNotScoped = errors.New("query is not constrained by the organization")
+
+// OrgMatcher returns the matcher selecting an org's series on label
+func OrgMatcher(label, orgID string) string {
+	return label + "=" + strconv.Quote(orgID)
+}
+
+// ScopeToOrg restricts a vector with a namespace label to the namespaces of the org's
+// projects, so a query matching other orgs' namespaces still returns only the org's series
+func ScopeToOrg(vector, orgID string) string {
+	return fmt.Sprintf(`(%s) * on (namespace) group_left() max by (namespace) (kube_namespace_labels{%s})`,
+		vector, OrgMatcher(NamespaceOrgLabel, orgID))
+}
+
+// QueryOrg runs an instant query on behalf of an org. The query itself must constrain
+// by the org, on the org_id label of Zyndra's own series or with ScopeToOrg, on top of
+// whatever ownership checks the caller did: a query that doesn't is refused instead of
+// being run across all tenants.
+func (c *PrometheusClient) QueryOrg(ctx context.Context, orgID, query string, ts time.Time) ([]Sample, error) {
+	if orgID == "" {
+		return nil, ErrQueryNotScoped
+	}
+	if !hasMatcher(query, OrgMatcher(OrgLabel, orgID)) && !hasMatcher(query, OrgMatcher(NamespaceOrgLabel, orgID)) {
+		return nil, ErrQueryNotScoped
+	}
+	return c.Query(ctx, query, ts)
+}
+
+// hasMatcher reports whether query contains matcher on its own, not as the tail of a
+// longer label name
+func hasMatcher(query, matcher string) bool {
+	for i := 0; ; {
+		j := strings.Index(query[i:], matcher)
+		if j < 0 {
+			return false
+		}
+		start := i + j
+		if start == 0 || !isLabelNameChar(query[start-1]) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isLabelNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
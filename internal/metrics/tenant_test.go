@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQueryOrg(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"1"]}]}}`))
+	}))
+	defer server.Close()
+	client := NewPrometheusClient(server.URL)
+
+	tests := []struct {
+		name   string
+		orgID  string
+		query  string
+		scoped bool
+	}{
+		{"exported series", "org-a", `sum(click_deploy_service_memory_usage_bytes{org_id="org-a"})`, true},
+		{"scoped to namespaces", "org-a", ScopeToOrg(`kube_pod_labels{namespace="ns"}`, "org-a"), true},
+		{"unscoped", "org-a", `sum(container_memory_working_set_bytes)`, false},
+		{"other org", "org-a", `sum(click_deploy_service_memory_usage_bytes{org_id="org-b"})`, false},
+		{"org as a prefix", "org-a", `sum(click_deploy_service_memory_usage_bytes{org_id="org-ab"})`, false},
+		{"longer label name", "org-a", `sum(click_deploy_service_memory_usage_bytes{parent_org_id="org-a"})`, false},
+		{"no org", "", `sum(click_deploy_service_memory_usage_bytes{org_id=""})`, false},
+		{"quote in org", `a"} or vector(1) #`, `sum(x{org_id="a"} or vector(1) #"})`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.QueryOrg(context.Background(), tt.orgID, tt.query, time.Now())
+			if tt.scoped && err != nil {
+				t.Errorf("QueryOrg(%q) returned error: %v", tt.query, err)
+			}
+			if !tt.scoped && !errors.Is(err, ErrQueryNotScoped) {
+				t.Errorf("QueryOrg(%q) error = %v, want %v", tt.query, err, ErrQueryNotScoped)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package realtime
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types of the events published, in their type field
+const (
+	EventDeploymentLog    = "log"
+	EventDeploymentStatus = "status"
+	EventServiceStatuses  = "service_statuses"
+)
+
+const (
+	projectChannelPrefix = "project:"
+	// ProjectChannelPattern matches the channels of all projects
+	ProjectChannelPattern = projectChannelPrefix + "*"
+)
+
+// DeploymentChannel is the channel a deployment's log lines and status changes go to
+func DeploymentChannel(deploymentID uuid.UUID) string {
+	return "deployment:" + deploymentID.String()
+}
+
+// ServiceChannel is the channel the status changes of a service's deployments go to
+func ServiceChannel(serviceID uuid.UUID) string {
+	return "service:" + serviceID.String()
+}
+
+// ProjectChannel is the channel the live status of a project's services goes to
+func ProjectChannel(projectID uuid.UUID) string {
+	return projectChannelPrefix + projectID.String()
+}
+
+// ParseProjectChannel returns the project of a project channel
+func ParseProjectChannel(channel string) (uuid.UUID, bool) {
+	if !strings.HasPrefix(channel, projectChannelPrefix) {
+		return uuid.Nil, false
+	}
+	projectID, err := uuid.Parse(strings.TrimPrefix(channel, projectChannelPrefix))
+	return projectID, err == nil
+}
+
+// DeploymentLogEvent is a log line of a deployment
+type DeploymentLogEvent struct {
+	Type      string                 `json:"type"`
+	Timestamp time.Time              `json:"timestamp"`
+	Phase     string                 `json:"phase"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// DeploymentStatusEvent is a deployment moving to a new status
+type DeploymentStatusEvent struct {
+	Type         string    `json:"type"`
+	Timestamp    time.Time `json:"timestamp"`
+	DeploymentID uuid.UUID `json:"deployment_id"`
+	ServiceID    uuid.UUID `json:"service_id"`
+	Status       string    `json:"status"`
+}
+
+// PublishDeploymentLog publishes a deployment's log line to its channel
+func PublishDeploymentLog(ctx context.Context, p Publisher, deploymentID uuid.UUID, phase, level, message string, metadata map[string]interface{}) error {
+	return p.Publish(ctx, DeploymentChannel(deploymentID), DeploymentLogEvent{
+		Type:      EventDeploymentLog,
+		Timestamp: time.Now().UTC(),
+		Phase:     phase,
+		Level:     level,
+		Message:   message,
+		Metadata:  metadata,
+	})
+}
+
+// PublishDeploymentStatus publishes a deployment's new status to its channel and
+// its service's, so a service page sees deploys start and finish
+func PublishDeploymentStatus(ctx context.Context, p Publisher, serviceID, deploymentID uuid.UUID, status string) error {
+	event := DeploymentStatusEvent{
+		Type:         EventDeploymentStatus,
+		Timestamp:    time.Now().UTC(),
+		DeploymentID: deploymentID,
+		ServiceID:    serviceID,
+		Status:       status,
+	}
+	if err := p.Publish(ctx, DeploymentChannel(deploymentID), event); err != nil {
+		return err
+	}
+	return p.Publish(ctx, ServiceChannel(serviceID), event)
+}
+
+// ServiceStatusesEvent is a snapshot of the live status of a project's services
+type ServiceStatusesEvent struct {
+	Type      string              `json:"type"`
+	Timestamp time.Time           `json:"timestamp"`
+	ProjectID uuid.UUID           `json:"project_id"`
+	Services  []ServiceLiveStatus `json:"services"`
+}
+
+// ServiceLiveStatus is what the cluster reports about a service. Metrics are left
+// out when the metrics server can't be reached.
+type ServiceLiveStatus struct {
+	ServiceID       uuid.UUID `json:"service_id"`
+	Status          string    `json:"status"`
+	K8sStatus       string    `json:"k8s_status"`
+	ReadyReplicas   int32     `json:"ready_replicas"`
+	DesiredReplicas int32     `json:"desired_replicas"`
+	Health          string    `json:"health,omitempty"`
+	CPUCores        *float64  `json:"cpu_cores,omitempty"`
+	MemoryMB        *float64  `json:"memory_mb,omitempty"`
+}
+
+// PublishServiceStatuses publishes the live status of a project's services to its channel
+func PublishServiceStatuses(ctx context.Context, p Publisher, projectID uuid.UUID, services []ServiceLiveStatus) error {
+	return p.Publish(ctx, ProjectChannel(projectID), ServiceStatusesEvent{
+		Type:      EventServiceStatuses,
+		Timestamp: time.Now().UTC(),
+		ProjectID: projectID,
+		Services:  services,
+	})
+}
@@ -0,0 +1,31 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type recordingPublisher struct {
+	channels []string
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, channel string, data any) error {
+	p.channels = append(p.channels, channel)
+	return nil
+}
+
+func TestPublishDeploymentStatus(t *testing.T) {
+	serviceID, deploymentID := uuid.New(), uuid.New()
+	p := &recordingPublisher{}
+
+	if err := PublishDeploymentStatus(context.Background(), p, serviceID, deploymentID, "success"); err != nil {
+		t.Fatalf("PublishDeploymentStatus error: %v", err)
+	}
+
+	want := []string{"deployment:" + deploymentID.String(), "service:" + serviceID.String()}
+	if len(p.channels) != len(want) || p.channels[0] != want[0] || p.channels[1] != want[1] {
+		t.Errorf("published to %v, want %v", p.channels, want)
+	}
+}
@@ -27,12 +27,24 @@ func NewCentrifugoPublisher(apiURL, apiKey string) *CentrifugoPublisher {
 	}
 }
 
-type centrifugoPublishRequest struct {
+type centrifugoRequest struct {
 	Method string `json:"method"`
-	Params struct {
-		Channel string `json:"channel"`
-		Data    any    `json:"data"`
-	} `json:"params"`
+	Params any    `json:"params"`
+}
+
+type centrifugoPublishParams struct {
+	Channel string `json:"channel"`
+	Data    any    `json:"data"`
+}
+
+type centrifugoChannelsParams struct {
+	Pattern string `json:"pattern,omitempty"`
+}
+
+type centrifugoChannelsResult struct {
+	Channels map[string]struct {
+		NumClients int `json:"num_clients"`
+	} `json:"channels"`
 }
 
 type centrifugoError struct {
@@ -41,7 +53,8 @@ type centrifugoError struct {
 }
 
 type centrifugoResponse struct {
-	Error *centrifugoError `json:"error,omitempty"`
+	Error  *centrifugoError `json:"error,omitempty"`
+	Result json.RawMessage  `json:"result,omitempty"`
 }
 
 func (p *CentrifugoPublisher) Enabled() bool {
@@ -56,13 +69,36 @@ func (p *CentrifugoPublisher) Publish(ctx context.Context, channel string, data
 		return fmt.Errorf("missing channel")
 	}
 
-	reqBody := centrifugoPublishRequest{Method: "publish"}
-	reqBody.Params.Channel = channel
-	reqBody.Params.Data = data
+	return p.call(ctx, "publish", centrifugoPublishParams{Channel: channel, Data: data}, nil)
+}
+
+// ActiveChannels returns the channels matching pattern (e.g. "project:*") that have
+// at least one subscriber. Nothing is returned when Centrifugo isn't configured.
+func (p *CentrifugoPublisher) ActiveChannels(ctx context.Context, pattern string) ([]string, error) {
+	if !p.Enabled() {
+		return nil, nil
+	}
+
+	var result centrifugoChannelsResult
+	if err := p.call(ctx, "channels", centrifugoChannelsParams{Pattern: pattern}, &result); err != nil {
+		return nil, err
+	}
 
-	b, err := json.Marshal(reqBody)
+	channels := make([]string, 0, len(result.Channels))
+	for channel, info := range result.Channels {
+		if info.NumClients > 0 {
+			channels = append(channels, channel)
+		}
+	}
+	return channels, nil
+}
+
+// call sends a server API command to Centrifugo and decodes its result into result,
+// when not nil
+func (p *CentrifugoPublisher) call(ctx context.Context, method string, params any, result any) error {
+	b, err := json.Marshal(centrifugoRequest{Method: method, Params: params})
 	if err != nil {
-		return fmt.Errorf("marshal publish request: %w", err)
+		return fmt.Errorf("marshal %s request: %w", method, err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.apiURL, bytes.NewReader(b))
@@ -74,21 +110,24 @@ func (p *CentrifugoPublisher) Publish(ctx context.Context, channel string, data
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("centrifugo publish request failed: %w", err)
+		return fmt.Errorf("centrifugo %s request failed: %w", method, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("centrifugo publish failed: status=%d", resp.StatusCode)
+		return fmt.Errorf("centrifugo %s failed: status=%d", method, resp.StatusCode)
 	}
 
 	var out centrifugoResponse
 	_ = json.NewDecoder(resp.Body).Decode(&out)
 	if out.Error != nil {
-		return fmt.Errorf("centrifugo publish error: %s", out.Error.Message)
+		return fmt.Errorf("centrifugo %s error: %s", method, out.Error.Message)
+	}
+	if result != nil && len(out.Result) > 0 {
+		if err := json.Unmarshal(out.Result, result); err != nil {
+			return fmt.Errorf("decode %s result: %w", method, err)
+		}
 	}
 
 	return nil
 }
-
-
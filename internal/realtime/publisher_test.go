@@ -0,0 +1,31 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCentrifugoPublisher_ActiveChannels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string                   `json:"method"`
+			Params centrifugoChannelsParams `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "channels" || req.Params.Pattern != ProjectChannelPattern {
+			t.Errorf("unexpected request %+v (%v)", req, err)
+		}
+		w.Write([]byte(`{"result":{"channels":{"project:a":{"num_clients":2},"project:b":{"num_clients":0}}}}`))
+	}))
+	defer server.Close()
+
+	channels, err := NewCentrifugoPublisher(server.URL, "key").ActiveChannels(context.Background(), ProjectChannelPattern)
+	if err != nil {
+		t.Fatalf("ActiveChannels error: %v", err)
+	}
+	if len(channels) != 1 || channels[0] != "project:a" {
+		t.Errorf("ActiveChannels = %v, want [project:a]", channels)
+	}
+}
@@ -23,22 +23,42 @@ const (
 	StateHalfOpen
 )
 
+// String returns the state name, as used in logs and metric labels
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
 // Config configures circuit breaker behavior
 type Config struct {
+	Name               string        // Identifies the breaker in state change callbacks
 	FailureThreshold   int           // Number of failures before opening circuit
 	SuccessThreshold   int           // Number of successes in half-open to close circuit
-	Timeout            time.Duration // Time to wait before attempting half-open
+	Timeout            time.Duration // How long the circuit stays open before attempting half-open
 	ResetTimeout       time.Duration // Time to wait before resetting failure count
+	HalfOpenMaxProbes  int           // Maximum concurrent trial calls while half-open (0 = unlimited)
 	MaxConcurrentCalls int           // Maximum concurrent calls (optional, 0 = unlimited)
+
+	// OnStateChange is called after every state transition, outside the breaker's lock
+	OnStateChange func(name string, from, to CircuitBreakerState)
 }
 
 // DefaultConfig returns a default circuit breaker configuration
 func DefaultConfig() Config {
 	return Config{
-		FailureThreshold: 5,
-		SuccessThreshold: 2,
-		Timeout:          30 * time.Second,
-		ResetTimeout:     60 * time.Second,
+		FailureThreshold:   5,
+		SuccessThreshold:   2,
+		Timeout:            30 * time.Second,
+		ResetTimeout:       60 * time.Second,
+		HalfOpenMaxProbes:  1,
 		MaxConcurrentCalls: 0, // Unlimited
 	}
 }
@@ -49,11 +69,18 @@ type CircuitBreaker struct {
 	state       CircuitBreakerState
 	failures    int
 	successes   int
+	probes      int // trial calls in flight while half-open
+	transitions int
 	lastFailure time.Time
 	lastReset   time.Time
 	mu          sync.RWMutex
 }
 
+// stateChange is a transition to report once the lock is released
+type stateChange struct {
+	from, to CircuitBreakerState
+}
+
 // NewCircuitBreaker creates a new circuit breaker
 func NewCircuitBreaker(config Config) *CircuitBreaker {
 	return &CircuitBreaker{
@@ -63,6 +90,11 @@ func NewCircuitBreaker(config Config) *CircuitBreaker {
 	}
 }
 
+// Name returns the configured name of the circuit breaker
+func (cb *CircuitBreaker) Name() string {
+	return cb.config.Name
+}
+
 // State returns the current state of the circuit breaker
 func (cb *CircuitBreaker) State() CircuitBreakerState {
 	cb.mu.RLock()
@@ -73,21 +105,23 @@ func (cb *CircuitBreaker) State() CircuitBreakerState {
 // Call executes a function through the circuit breaker
 func (cb *CircuitBreaker) Call(ctx context.Context, fn func() error) error {
 	// Check if we can make the call
-	if err := cb.beforeCall(); err != nil {
+	probe, change, err := cb.beforeCall()
+	cb.notify(change)
+	if err != nil {
 		return err
 	}
 
 	// Execute the function
-	err := fn()
+	err = fn()
 
 	// Update circuit breaker state based on result
-	cb.afterCall(err)
+	cb.notify(cb.afterCall(probe, err))
 
 	return err
 }
 
-// beforeCall checks if a call can be made
-func (cb *CircuitBreaker) beforeCall() error {
+// beforeCall checks if a call can be made and whether it is a half-open trial call
+func (cb *CircuitBreaker) beforeCall() (bool, *stateChange, error) {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
@@ -99,34 +133,47 @@ func (cb *CircuitBreaker) beforeCall() error {
 		cb.lastReset = now
 	}
 
+	var change *stateChange
+
 	switch cb.state {
 	case StateClosed:
 		// Allow call
-		return nil
+		return false, nil, nil
 
 	case StateOpen:
 		// Check if timeout has passed, transition to half-open
-		if now.Sub(cb.lastFailure) > cb.config.Timeout {
-			cb.state = StateHalfOpen
-			cb.successes = 0
-			return nil
+		if now.Sub(cb.lastFailure) <= cb.config.Timeout {
+			return false, nil, ErrCircuitOpen
 		}
-		return ErrCircuitOpen
+		change = cb.setState(StateHalfOpen)
+		cb.successes = 0
+		cb.probes = 0
+		fallthrough
 
 	case StateHalfOpen:
-		// Allow call (testing if service is back)
-		return nil
+		// Allow a limited number of trial calls (testing if service is back)
+		if cb.config.HalfOpenMaxProbes > 0 && cb.probes >= cb.config.HalfOpenMaxProbes {
+			return false, change, ErrCircuitHalfOpen
+		}
+		cb.probes++
+		return true, change, nil
 
 	default:
-		return ErrCircuitOpen
+		return false, nil, ErrCircuitOpen
 	}
 }
 
 // afterCall updates circuit breaker state based on call result
-func (cb *CircuitBreaker) afterCall(err error) {
+func (cb *CircuitBreaker) afterCall(probe bool, err error) *stateChange {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
+	if probe && cb.probes > 0 {
+		cb.probes--
+	}
+
+	var change *stateChange
+
 	if err != nil {
 		// Call failed
 		cb.failures++
@@ -136,14 +183,12 @@ func (cb *CircuitBreaker) afterCall(err error) {
 		case StateClosed:
 			// Check if we should open the circuit
 			if cb.failures >= cb.config.FailureThreshold {
-				cb.state = StateOpen
-				cb.lastFailure = time.Now()
+				change = cb.setState(StateOpen)
 			}
 
 		case StateHalfOpen:
 			// Failure in half-open state, go back to open
-			cb.state = StateOpen
-			cb.lastFailure = time.Now()
+			change = cb.setState(StateOpen)
 			cb.successes = 0
 		}
 	} else {
@@ -160,29 +205,70 @@ func (cb *CircuitBreaker) afterCall(err error) {
 			cb.successes++
 			if cb.successes >= cb.config.SuccessThreshold {
 				// Enough successes, close the circuit
-				cb.state = StateClosed
+				change = cb.setState(StateClosed)
 				cb.successes = 0
 				cb.lastReset = time.Now()
 			}
 		}
 	}
+
+	return change
+}
+
+// setState changes the state and returns the transition. Must be called with the lock held.
+func (cb *CircuitBreaker) setState(to CircuitBreakerState) *stateChange {
+	from := cb.state
+	if from == to {
+		return nil
+	}
+	cb.state = to
+	cb.transitions++
+	return &stateChange{from: from, to: to}
+}
+
+// notify reports a state transition to the OnStateChange callback
+func (cb *CircuitBreaker) notify(change *stateChange) {
+	if change != nil && cb.config.OnStateChange != nil {
+		cb.config.OnStateChange(cb.config.Name, change.from, change.to)
+	}
+}
+
+// RetryAfter returns how long until an open circuit lets a trial call through.
+// Returns 0 if the circuit is not open.
+func (cb *CircuitBreaker) RetryAfter() time.Duration {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	if cb.state != StateOpen {
+		return 0
+	}
+	remaining := cb.config.Timeout - time.Since(cb.lastFailure)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
 }
 
 // Reset manually resets the circuit breaker to closed state
 func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
-	cb.state = StateClosed
+	change := cb.setState(StateClosed)
 	cb.failures = 0
 	cb.successes = 0
+	cb.probes = 0
 	cb.lastReset = time.Now()
+	cb.mu.Unlock()
+
+	cb.notify(change)
 }
 
 // Stats returns circuit breaker statistics
 type Stats struct {
-	State     CircuitBreakerState
-	Failures  int
-	Successes int
+	Name        string
+	State       CircuitBreakerState
+	Failures    int
+	Successes   int
+	Transitions int // Number of state changes since creation
 	LastFailure time.Time
 }
 
@@ -191,10 +277,11 @@ func (cb *CircuitBreaker) GetStats() Stats {
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
 	return Stats{
+		Name:        cb.config.Name,
 		State:       cb.state,
 		Failures:    cb.failures,
 		Successes:   cb.successes,
+		Transitions: cb.transitions,
 		LastFailure: cb.lastFailure,
 	}
 }
-
@@ -0,0 +1,42 @@
+package secrets
+
+import "testing"
+
+func TestCipher_RoundTrip(t *testing.T) {
+	c, err := NewCipher("test-encryption-key")
+	if err != nil {
+		t.Fatalf("NewCipher failed: %v", err)
+	}
+
+	encrypted, err := c.Encrypt("npm_secret_token")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if encrypted == "npm_secret_token" {
+		t.Fatal("Expected the value to be encrypted")
+	}
+
+	// A fresh nonce per value, the same plaintext encrypts differently
+	again, _ := c.Encrypt("npm_secret_token")
+	if again == encrypted {
+		t.Error("Expected different ciphertexts for the same value")
+	}
+
+	decrypted, err := c.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if decrypted != "npm_secret_token" {
+		t.Errorf("Expected npm_secret_token, got %q", decrypted)
+	}
+
+	// Another key can't read the value
+	other, _ := NewCipher("another-key")
+	if _, err := other.Decrypt(encrypted); err == nil {
+		t.Error("Expected decrypting with another key to fail")
+	}
+
+	if _, err := NewCipher(""); err == nil {
+		t.Error("Expected an empty key to be rejected")
+	}
+}
@@ -0,0 +1,167 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret manager providers an env var can reference
+const (
+	ProviderVault = "vault"
+)
+
+// SecretRef points at a secret kept in an external secret manager. Only the
+// reference is stored; the value is read at deploy time.
+type SecretRef struct {
+	Provider string `json:"provider"`
+	Path     string `json:"path"`
+	Key      string `json:"key"`
+}
+
+// ParseSecretRef parses a reference stored as JSON
+func ParseSecretRef(raw string) (SecretRef, error) {
+	var ref SecretRef
+	if err := json.Unmarshal([]byte(raw), &ref); err != nil {
+		return ref, fmt.Errorf("invalid secret reference: %w", err)
+	}
+	return ref, ref.Validate()
+}
+
+// Validate checks the reference is complete, names a supported provider and has a
+// plain path: no "." or ".." segments, query, fragment or escapes that could make
+// it read another path than it shows
+func (r SecretRef) Validate() error {
+	switch {
+	case r.Provider != ProviderVault:
+		return fmt.Errorf("unsupported secret provider %q, use %s", r.Provider, ProviderVault)
+	case r.Path == "":
+		return errors.New("secret path is required")
+	case r.Key == "":
+		return errors.New("secret key is required")
+	case strings.ContainsAny(r.Path, "?#%\\"):
+		return errors.New("secret path must not contain ?, #, % or \\")
+	}
+	for _, segment := range strings.Split(strings.Trim(r.Path, "/"), "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid secret path %q", r.Path)
+		}
+	}
+	return nil
+}
+
+// OrgIDPlaceholder is replaced with the org's ID in the org path prefix template
+const OrgIDPlaceholder = "{org_id}"
+
+// OrgPathPrefix returns the path an org's secret references must be below, from a
+// template such as "secret/data/zyndra/{org_id}". Returns an empty prefix, which
+// refuses every reference, when the template doesn't scope by org or the org ID
+// isn't a single path segment.
+func OrgPathPrefix(template, orgID string) string {
+	if !strings.Contains(template, OrgIDPlaceholder) || orgID == "" || orgID == "." || orgID == ".." ||
+		strings.ContainsAny(orgID, "/?#%\\") {
+		return ""
+	}
+	return strings.Trim(strings.ReplaceAll(template, OrgIDPlaceholder, orgID), "/")
+}
+
+// ValidateScope checks the reference is valid and points below prefix, the path
+// prefix of the org it belongs to
+func (r SecretRef) ValidateScope(prefix string) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	if prefix == "" {
+		return errors.New("secret references are not available for this organization")
+	}
+	if !strings.HasPrefix(strings.Trim(r.Path, "/"), prefix+"/") {
+		return fmt.Errorf("secret path must be below %s/", prefix)
+	}
+	return nil
+}
+
+// String returns the reference as it's shown in logs and errors, never the value
+func (r SecretRef) String() string {
+	return fmt.Sprintf("%s:%s#%s", r.Provider, r.Path, r.Key)
+}
+
+// Resolver reads the value a secret reference points at
+type Resolver interface {
+	Resolve(ctx context.Context, ref SecretRef) (string, error)
+}
+
+// Providers resolves each reference with the resolver of its provider
+type Providers map[string]Resolver
+
+// Resolve implements Resolver
+func (p Providers) Resolve(ctx context.Context, ref SecretRef) (string, error) {
+	resolver, ok := p[ref.Provider]
+	if !ok {
+		return "", fmt.Errorf("secret provider %s is not configured", ref.Provider)
+	}
+	return resolver.Resolve(ctx, ref)
+}
+
+// CachingResolver keeps resolved values in memory for a short while, so deploying
+// several services that share a secret reads it once
+type CachingResolver struct {
+	next Resolver
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[SecretRef]cachedSecret
+}
+
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+// NewCachingResolver caches the values next resolves for ttl
+func NewCachingResolver(next Resolver, ttl time.Duration) *CachingResolver {
+	return &CachingResolver{
+		next:    next,
+		ttl:     ttl,
+		entries: make(map[SecretRef]cachedSecret),
+	}
+}
+
+// Resolve implements Resolver. Failures aren't cached.
+func (c *CachingResolver) Resolve(ctx context.Context, ref SecretRef) (string, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[ref]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	value, err := c.next.Resolve(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.entries[ref] = cachedSecret{value: value, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+
+	return value, nil
+}
+
+// NewResolver returns the resolver of the configured providers, caching values for
+// cacheTTL. Vault is configured when vaultAddr is set.
+func NewResolver(vaultAddr, vaultToken string, cacheTTL time.Duration) Resolver {
+	providers := Providers{}
+	if vaultAddr != "" {
+		providers[ProviderVault] = NewVaultResolver(vaultAddr, vaultToken)
+	}
+	if cacheTTL <= 0 {
+		return providers
+	}
+	return NewCachingResolver(providers, cacheTTL)
+}
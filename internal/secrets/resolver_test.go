@@ -0,0 +1,128 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVaultResolver_CachesValues(t *testing.T) {
+	reads := 0
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/myapp":
+			reads++
+			w.Write([]byte(`{"data": {"data": {"API_KEY": "s3cret", "PORT": 5432}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/legacy":
+			w.Write([]byte(`{"data": {"password": "hunter2"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	resolver := NewResolver(vault.URL, "vault-token", time.Minute)
+	ctx := context.Background()
+
+	tests := []struct {
+		ref     SecretRef
+		want    string
+		wantErr bool
+	}{
+		{SecretRef{Provider: ProviderVault, Path: "secret/data/myapp", Key: "API_KEY"}, "s3cret", false},
+		{SecretRef{Provider: ProviderVault, Path: "secret/data/myapp", Key: "API_KEY"}, "s3cret", false}, // cached
+		{SecretRef{Provider: ProviderVault, Path: "secret/data/myapp", Key: "PORT"}, "5432", false},
+		{SecretRef{Provider: ProviderVault, Path: "kv/legacy", Key: "password"}, "hunter2", false},
+		{SecretRef{Provider: ProviderVault, Path: "secret/data/myapp", Key: "MISSING"}, "", true},
+		{SecretRef{Provider: ProviderVault, Path: "secret/data/other", Key: "API_KEY"}, "", true},
+		{SecretRef{Provider: "aws", Path: "prod/db", Key: "password"}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := resolver.Resolve(ctx, tt.ref)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("Resolve(%s) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%s) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+
+	// API_KEY once, then PORT and MISSING; the second API_KEY came from the cache
+	if reads != 3 {
+		t.Errorf("Expected 3 reads of secret/data/myapp, got %d", reads)
+	}
+}
+
+func TestParseSecretRef(t *testing.T) {
+	ref, err := ParseSecretRef(`{"provider": "vault", "path": "secret/data/myapp", "key": "API_KEY"}`)
+	if err != nil {
+		t.Fatalf("ParseSecretRef failed: %v", err)
+	}
+	if ref.String() != "vault:secret/data/myapp#API_KEY" {
+		t.Errorf("Unexpected reference %s", ref)
+	}
+
+	for _, raw := range []string{`{"provider": "vault", "key": "API_KEY"}`, `{"provider": "1password", "path": "p", "key": "k"}`, `not json`} {
+		if _, err := ParseSecretRef(raw); err == nil {
+			t.Errorf("Expected ParseSecretRef(%s) to fail", raw)
+		}
+	}
+}
+
+func TestSecretRef_ValidateScope(t *testing.T) {
+	prefix := OrgPathPrefix("secret/data/zyndra/{org_id}/", "org-a")
+	if prefix != "secret/data/zyndra/org-a" {
+		t.Fatalf("OrgPathPrefix = %q", prefix)
+	}
+
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{"secret/data/zyndra/org-a/myapp", false},
+		{"/secret/data/zyndra/org-a/team/myapp", false},
+		{"secret/data/zyndra/org-b/myapp", true}, // Another org's
+		{"secret/data/zyndra/org-a", true},       // The prefix itself
+		{"secret/data/zyndra/org-ab/myapp", true},
+		{"secret/data/platform/db", true},
+		{"secret/data/zyndra/org-a/../org-b/myapp", true},
+		{"secret/data/zyndra/org-a/%2e%2e/org-b/myapp", true},
+		{"secret/data/zyndra/org-a/myapp?version=1", true},
+		{"secret/data/zyndra/org-a/myapp#frag", true},
+		{"secret/data/zyndra/org-a//myapp", true},
+	}
+	for _, tt := range tests {
+		ref := SecretRef{Provider: ProviderVault, Path: tt.path, Key: "API_KEY"}
+		if err := ref.ValidateScope(prefix); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateScope(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+		}
+	}
+
+	// Without an org in the template or a usable org ID, no reference is allowed
+	for _, prefix := range []string{OrgPathPrefix("secret/data/zyndra", "org-a"), OrgPathPrefix("secret/data/{org_id}", "../org-b"), OrgPathPrefix("secret/data/{org_id}", "")} {
+		ref := SecretRef{Provider: ProviderVault, Path: "secret/data/zyndra/org-a/myapp", Key: "API_KEY"}
+		if err := ref.ValidateScope(prefix); err == nil {
+			t.Errorf("ValidateScope with prefix %q should fail", prefix)
+		}
+	}
+}
+
+func TestVaultResolver_RefusesUnsafePaths(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"API_KEY": "s3cret"}}`))
+	}))
+	defer vault.Close()
+
+	resolver := NewVaultResolver(vault.URL, "vault-token")
+	for _, path := range []string{"secret/org-a/../platform", "secret/org-a?x=1", "secret/org-a#x"} {
+		if _, err := resolver.Resolve(context.Background(), SecretRef{Provider: ProviderVault, Path: path, Key: "API_KEY"}); err == nil {
+			t.Errorf("Resolve(%q) should fail", path)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultResolver reads secrets from HashiCorp Vault over its HTTP API, authenticating
+// with a token. Both versions of the KV secrets engine are supported: the path is the
+// API path of the secret below /v1, e.g. secret/data/myapp for KV v2.
+type VaultResolver struct {
+	addr   string
+	token  string
+	client *http.Client
+}
+
+// NewVaultResolver creates a resolver for the Vault server at addr
+func NewVaultResolver(addr, token string) *VaultResolver {
+	return &VaultResolver{
+		addr:   strings.TrimRight(addr, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Resolve implements Resolver
+func (v *VaultResolver) Resolve(ctx context.Context, ref SecretRef) (string, error) {
+	if err := ref.Validate(); err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", ref, err)
+	}
+	url := v.addr + "/v1/" + strings.TrimLeft(ref.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", ref, err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("failed to read secret %s: vault returned %d: %s", ref, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", fmt.Errorf("failed to decode secret %s: %w", ref, err)
+	}
+
+	data := secret.Data
+	// KV v2 nests the secret's fields under data.data, next to data.metadata
+	if nested, ok := data["data"]; ok {
+		if _, versioned := data["metadata"]; versioned {
+			data = nil
+			if err := json.Unmarshal(nested, &data); err != nil {
+				return "", fmt.Errorf("failed to decode secret %s: %w", ref, err)
+			}
+		}
+	}
+
+	raw, ok := data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", ref)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		// Numbers and booleans are injected as they're written
+		return string(raw), nil
+	}
+	return value, nil
+}
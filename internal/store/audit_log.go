@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit log actions
+const (
+	AuditActionDeploymentApprove = "deployment.approve"
+	AuditActionDeploymentReject  = "deployment.reject"
+
+	AuditActionWebhookSecretRotate = "git_source.rotate_webhook_secret"
+
+	AuditActionProjectTransfer = "project.transfer"
+
+	AuditActionLoginLockout = "auth.login_lockout"
+	AuditActionNewSignIn    = "auth.new_sign_in"
+)
+
+// AuditLogEntry records who performed a sensitive action in an organization
+type AuditLogEntry struct {
+	ID           uuid.UUID
+	OrgID        string
+	ActorID      sql.NullString // User who performed the action; NULL for system actions
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Metadata     map[string]interface{}
+	CreatedAt    time.Time
+}
+
+// CreateAuditLogEntry appends an entry to the audit log
+func (db *DB) CreateAuditLogEntry(ctx context.Context, e *AuditLogEntry) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+
+	var metadataJSON sql.NullString
+	if e.Metadata != nil {
+		jsonBytes, err := json.Marshal(e.Metadata)
+		if err != nil {
+			return err
+		}
+		metadataJSON = sql.NullString{String: string(jsonBytes), Valid: true}
+	}
+
+	query := `
+		INSERT INTO audit_log (id, org_id, actor_id, action, resource_type, resource_id, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := db.ExecContext(ctx, query,
+		e.ID.String(), e.OrgID, e.ActorID, e.Action, e.ResourceType, e.ResourceID, metadataJSON,
+	)
+	if err != nil {
+		return err
+	}
+
+	return db.QueryRowContext(ctx, "SELECT created_at FROM audit_log WHERE id = $1", e.ID.String()).
+		Scan(&e.CreatedAt)
+}
+
+// ListAuditLogByResource lists the audit log entries of a resource, oldest first
+func (db *DB) ListAuditLogByResource(ctx context.Context, resourceType, resourceID string) ([]*AuditLogEntry, error) {
+	query := `
+		SELECT id, org_id, actor_id, action, resource_type, resource_id, metadata, created_at
+		FROM audit_log
+		WHERE resource_type = $1 AND resource_id = $2
+		ORDER BY created_at ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, resourceType, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*AuditLogEntry
+	for rows.Next() {
+		var e AuditLogEntry
+		var metadataJSON sql.NullString
+		err := rows.Scan(
+			&e.ID,
+			&e.OrgID,
+			&e.ActorID,
+			&e.Action,
+			&e.ResourceType,
+			&e.ResourceID,
+			&metadataJSON,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if metadataJSON.Valid {
+			json.Unmarshal([]byte(metadataJSON.String), &e.Metadata)
+		}
+		entries = append(entries, &e)
+	}
+
+	return entries, rows.Err()
+}
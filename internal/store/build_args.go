@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BuildArg is a build-time variable of a service, separate from its runtime env vars.
+// Plain args are passed to the build as --build-arg; secrets are mounted into the
+// RUN steps only, so they don't end up in image layers.
+type BuildArg struct {
+	ID        uuid.UUID
+	ServiceID uuid.UUID
+	Key       string
+	Value     string // Encrypted when IsSecret
+	IsSecret  bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// CreateBuildArg creates a new build arg
+func (db *DB) CreateBuildArg(ctx context.Context, a *BuildArg) error {
+	// Generate UUID if not set (for SQLite compatibility)
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+
+	// Check if we're using SQLite (for compatibility)
+	var versionStr string
+	isSQLite := db.QueryRow("SELECT sqlite_version()").Scan(&versionStr) == nil
+
+	if isSQLite {
+		// SQLite: Insert with explicit UUID (no RETURNING support in older versions)
+		isSecret := 0
+		if a.IsSecret {
+			isSecret = 1
+		}
+		query := `
+			INSERT INTO build_args (id, service_id, key, value, is_secret)
+			VALUES ($1, $2, $3, $4, $5)
+		`
+		_, err := db.ExecContext(ctx, query, a.ID.String(), a.ServiceID.String(), a.Key, a.Value, isSecret)
+		if err != nil {
+			return err
+		}
+		// Get timestamps
+		return db.QueryRowContext(ctx, "SELECT created_at, updated_at FROM build_args WHERE id = $1", a.ID.String()).
+			Scan(&a.CreatedAt, &a.UpdatedAt)
+	}
+
+	// PostgreSQL: Use RETURNING clause
+	query := `
+		INSERT INTO build_args (service_id, key, value, is_secret)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`
+
+	return db.QueryRowContext(ctx, query,
+		a.ServiceID,
+		a.Key,
+		a.Value,
+		a.IsSecret,
+	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+}
+
+// GetBuildArgByKey retrieves a service's build arg by key
+func (db *DB) GetBuildArgByKey(ctx context.Context, serviceID uuid.UUID, key string) (*BuildArg, error) {
+	query := `
+		SELECT id, service_id, key, value, is_secret, created_at, updated_at
+		FROM build_args
+		WHERE service_id = $1 AND key = $2
+	`
+
+	var a BuildArg
+	err := db.QueryRowContext(ctx, query, serviceID, key).Scan(
+		&a.ID,
+		&a.ServiceID,
+		&a.Key,
+		&a.Value,
+		&a.IsSecret,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// ListBuildArgsByService lists a service's build args and secrets
+func (db *DB) ListBuildArgsByService(ctx context.Context, serviceID uuid.UUID) ([]*BuildArg, error) {
+	query := `
+		SELECT id, service_id, key, value, is_secret, created_at, updated_at
+		FROM build_args
+		WHERE service_id = $1
+		ORDER BY key ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var args []*BuildArg
+	for rows.Next() {
+		var a BuildArg
+		err := rows.Scan(
+			&a.ID,
+			&a.ServiceID,
+			&a.Key,
+			&a.Value,
+			&a.IsSecret,
+			&a.CreatedAt,
+			&a.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, &a)
+	}
+
+	return args, rows.Err()
+}
+
+// UpdateBuildArg updates the value of a build arg and whether it's a secret
+func (db *DB) UpdateBuildArg(ctx context.Context, id uuid.UUID, a *BuildArg) error {
+	query := `
+		UPDATE build_args
+		SET value = $1, is_secret = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+	`
+
+	result, err := db.ExecContext(ctx, query, a.Value, a.IsSecret, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteBuildArg deletes a build arg
+func (db *DB) DeleteBuildArg(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM build_args WHERE id = $1`
+
+	result, err := db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// Versions of the in-process caches of project reads, such as service listings. Every
+// change to a project's services, git sources or deployments made through the store,
+// by a handler or a worker alike, moves the project's version on; a cache entry
+// recorded under an older version is stale.
+var (
+	cacheVersionCounter atomic.Uint64
+	// cacheVersionAll is the version of every project, moved on when the project of a
+	// change can't be told
+	cacheVersionAll      atomic.Uint64
+	projectCacheVersions sync.Map // uuid.UUID -> uint64
+)
+
+// ProjectCacheVersion returns the current version of a project's cached reads. Read it
+// before reading from the database, so a change made meanwhile marks the result stale.
+func ProjectCacheVersion(projectID uuid.UUID) uint64 {
+	version := cacheVersionAll.Load()
+	if v, ok := projectCacheVersions.Load(projectID); ok && v.(uint64) > version {
+		version = v.(uint64)
+	}
+	return version
+}
+
+// InvalidateProjectCache moves a project's cache version on
+func InvalidateProjectCache(projectID uuid.UUID) {
+	projectCacheVersions.Store(projectID, cacheVersionCounter.Add(1))
+}
+
+// invalidateAllProjectCaches moves the cache version of every project on
+func invalidateAllProjectCaches() {
+	cacheVersionAll.Store(cacheVersionCounter.Add(1))
+}
+
+// Look up the project of a row to invalidate, by the row's ID
+const (
+	serviceProjectQuery    = `SELECT project_id FROM services WHERE id = $1`
+	deploymentProjectQuery = `
+		SELECT s.project_id
+		FROM deployments d
+		JOIN services s ON s.id = d.service_id
+		WHERE d.id = $1
+	`
+	gitSourceProjectQuery = `
+		SELECT s.project_id
+		FROM git_sources g
+		JOIN services s ON s.id = g.service_id
+		WHERE g.id = $1
+	`
+)
+
+// cacheInvalidator looks up the project of a row with one of the project queries and
+// returns the function moving its cache version on, to defer before changing the row:
+//
+//	defer db.cacheInvalidator(ctx, serviceProjectQuery, id)()
+//
+// The project is looked up first so a deleted row still invalidates it. When it can't
+// be, every project is invalidated rather than risking a stale read.
+func (db *DB) cacheInvalidator(ctx context.Context, projectQuery string, id uuid.UUID) func() {
+	var projectID uuid.UUID
+	if err := db.QueryRowContext(ctx, projectQuery, id).Scan(&projectID); err != nil {
+		return invalidateAllProjectCaches
+	}
+	return func() { InvalidateProjectCache(projectID) }
+}
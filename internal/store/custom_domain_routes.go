@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CustomDomainRoute sends the requests to a custom domain whose path starts with
+// PathPrefix to another service than the domain's own
+type CustomDomainRoute struct {
+	ID         uuid.UUID
+	DomainID   uuid.UUID
+	PathPrefix string // e.g. /api, without trailing slash
+	ServiceID  uuid.UUID
+	CreatedAt  time.Time
+}
+
+// ListCustomDomainRoutes lists the path routes of a custom domain, by path prefix
+func (db *DB) ListCustomDomainRoutes(ctx context.Context, domainID uuid.UUID) ([]*CustomDomainRoute, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, domain_id, path_prefix, service_id, created_at
+		FROM custom_domain_routes
+		WHERE domain_id = $1
+		ORDER BY path_prefix
+	`, domainID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routes []*CustomDomainRoute
+	for rows.Next() {
+		var route CustomDomainRoute
+		if err := rows.Scan(&route.ID, &route.DomainID, &route.PathPrefix, &route.ServiceID, &route.CreatedAt); err != nil {
+			return nil, err
+		}
+		routes = append(routes, &route)
+	}
+	return routes, rows.Err()
+}
+
+// ReplaceCustomDomainRoutes replaces the path routes of a custom domain with routes
+func (db *DB) ReplaceCustomDomainRoutes(ctx context.Context, domainID uuid.UUID, routes []*CustomDomainRoute) error {
+	if _, err := db.ExecContext(ctx, "DELETE FROM custom_domain_routes WHERE domain_id = $1", domainID); err != nil {
+		return err
+	}
+
+	for _, route := range routes {
+		if route.ID == uuid.Nil {
+			route.ID = uuid.New()
+		}
+		route.DomainID = domainID
+
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO custom_domain_routes (id, domain_id, path_prefix, service_id)
+			VALUES ($1, $2, $3, $4)
+		`, route.ID.String(), domainID.String(), route.PathPrefix, route.ServiceID.String())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -144,6 +144,57 @@ func (db *DB) GetCustomDomain(ctx context.Context, id uuid.UUID) (*CustomDomain,
 	return &d, nil
 }
 
+// GetCustomDomainByDomain retrieves a custom domain by its hostname, across all services
+func (db *DB) GetCustomDomainByDomain(ctx context.Context, domainName string) (*CustomDomain, error) {
+	var d CustomDomain
+	query := `
+		SELECT id, service_id, domain, status, cname, cname_target,
+		       ssl_enabled, ssl_cert_status, ssl_cert_expiry,
+		       validation_token, created_at, updated_at, verified_at
+		FROM custom_domains
+		WHERE LOWER(domain) = LOWER($1)
+	`
+
+	var cname sql.NullString
+	var cnameTarget sql.NullString
+	var sslCertStatus sql.NullString
+	var sslCertExpiry sql.NullTime
+	var validationToken sql.NullString
+	var verifiedAt sql.NullTime
+
+	err := db.QueryRowContext(ctx, query, domainName).Scan(
+		&d.ID,
+		&d.ServiceID,
+		&d.Domain,
+		&d.Status,
+		&cname,
+		&cnameTarget,
+		&d.SSLEnabled,
+		&sslCertStatus,
+		&sslCertExpiry,
+		&validationToken,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+		&verifiedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d.CNAME = cname
+	d.CNAMETarget = cnameTarget
+	d.SSLCertStatus = sslCertStatus
+	d.SSLCertExpiry = sslCertExpiry
+	d.ValidationToken = validationToken
+	d.VerifiedAt = verifiedAt
+
+	return &d, nil
+}
+
 // ListCustomDomainsByService lists custom domains for a service
 func (db *DB) ListCustomDomainsByService(ctx context.Context, serviceID uuid.UUID) ([]*CustomDomain, error) {
 	query := `
@@ -161,6 +212,110 @@ func (db *DB) ListCustomDomainsByService(ctx context.Context, serviceID uuid.UUI
 	}
 	defer rows.Close()
 
+	return scanCustomDomains(rows)
+}
+
+// ListCustomDomainsByProject lists custom domains of all services in a project
+func (db *DB) ListCustomDomainsByProject(ctx context.Context, projectID uuid.UUID) ([]*CustomDomain, error) {
+	query := `
+		SELECT cd.id, cd.service_id, cd.domain, cd.status, cd.cname, cd.cname_target,
+		       cd.ssl_enabled, cd.ssl_cert_status, cd.ssl_cert_expiry,
+		       cd.validation_token, cd.created_at, cd.updated_at, cd.verified_at
+		FROM custom_domains cd
+		JOIN services s ON s.id = cd.service_id
+		WHERE s.project_id = $1
+		ORDER BY cd.created_at DESC
+	`
+
+	rows, err := db.QueryContext(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCustomDomains(rows)
+}
+
+// ListCustomDomainsByStatus lists custom domains across all services with the given status
+func (db *DB) ListCustomDomainsByStatus(ctx context.Context, status string) ([]*CustomDomain, error) {
+	query := `
+		SELECT id, service_id, domain, status, cname, cname_target,
+		       ssl_enabled, ssl_cert_status, ssl_cert_expiry,
+		       validation_token, created_at, updated_at, verified_at
+		FROM custom_domains
+		WHERE status = $1
+		ORDER BY updated_at ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCustomDomains(rows)
+}
+
+// ListCustomDomainsAwaitingReady lists custom domains whose route waits for their
+// service to have a ready pod
+func (db *DB) ListCustomDomainsAwaitingReady(ctx context.Context) ([]*CustomDomain, error) {
+	query := `
+		SELECT id, service_id, domain, status, cname, cname_target,
+		       ssl_enabled, ssl_cert_status, ssl_cert_expiry,
+		       validation_token, created_at, updated_at, verified_at
+		FROM custom_domains
+		WHERE awaiting_service_ready = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, true)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCustomDomains(rows)
+}
+
+// SetCustomDomainAwaitingReady flags whether a custom domain's route waits for its service to be ready
+func (db *DB) SetCustomDomainAwaitingReady(ctx context.Context, id uuid.UUID, awaiting bool) error {
+	_, err := db.ExecContext(ctx, `UPDATE custom_domains SET awaiting_service_ready = $1 WHERE id = $2`, awaiting, id)
+	return err
+}
+
+// IsCustomDomainAwaitingReady reports whether a custom domain's route waits for its service to be ready
+func (db *DB) IsCustomDomainAwaitingReady(ctx context.Context, id uuid.UUID) (bool, error) {
+	var awaiting bool
+	err := db.QueryRowContext(ctx, `SELECT awaiting_service_ready FROM custom_domains WHERE id = $1`, id).Scan(&awaiting)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return awaiting, err
+}
+
+// GetCustomDomainDNSFailures returns the consecutive failed DNS re-validations of a
+// custom domain and, once they moved it back to pending, the status it had before
+func (db *DB) GetCustomDomainDNSFailures(ctx context.Context, id uuid.UUID) (int, sql.NullString, error) {
+	var failures int
+	var statusBefore sql.NullString
+	err := db.QueryRowContext(ctx, `SELECT dns_check_failures, status_before_dns_failure FROM custom_domains WHERE id = $1`, id).
+		Scan(&failures, &statusBefore)
+	if err == sql.ErrNoRows {
+		return 0, sql.NullString{}, nil
+	}
+	return failures, statusBefore, err
+}
+
+// SetCustomDomainDNSFailures records the consecutive failed DNS re-validations of a
+// custom domain and the status it had before they moved it back to pending
+func (db *DB) SetCustomDomainDNSFailures(ctx context.Context, id uuid.UUID, failures int, statusBefore sql.NullString) error {
+	_, err := db.ExecContext(ctx, `UPDATE custom_domains SET dns_check_failures = $1, status_before_dns_failure = $2 WHERE id = $3`,
+		failures, statusBefore, id)
+	return err
+}
+
+// scanCustomDomains scans custom domain rows selected with the standard column list
+func scanCustomDomains(rows *sql.Rows) ([]*CustomDomain, error) {
 	var domains []*CustomDomain
 	for rows.Next() {
 		var d CustomDomain
@@ -213,7 +368,7 @@ func (db *DB) UpdateCustomDomain(ctx context.Context, id uuid.UUID, updates *Cus
 		    ssl_cert_status = $4,
 		    ssl_cert_expiry = $5,
 		    verified_at = $6,
-		    updated_at = now()
+		    updated_at = CURRENT_TIMESTAMP
 		WHERE id = $7
 		RETURNING updated_at
 	`
@@ -3,6 +3,8 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -28,23 +30,29 @@ type Database struct {
 	OpenStackInstanceID sql.NullString
 	OpenStackPortID     sql.NullString
 	SecurityGroupID     sql.NullString
+	StorageClass        sql.NullString // Overrides the cluster's default storage class
 	Status              string // pending, provisioning, active, error
+	CreatedBy           sql.NullString // User who created the database
+	UpdatedBy           sql.NullString // User who last changed its config
 	CreatedAt           time.Time
 }
 
 // CreateDatabase creates a new database
 func (db *DB) CreateDatabase(ctx context.Context, d *Database) error {
+	// Check if we're using SQLite (for compatibility)
+	var versionStr string
+	isSQLite := db.QueryRow("SELECT sqlite_version()").Scan(&versionStr) == nil
+
+	return insertDatabase(ctx, db, isSQLite, d)
+}
+
+// insertDatabase inserts a database with q, the database or a transaction
+func insertDatabase(ctx context.Context, q execer, isSQLite bool, d *Database) error {
 	// Generate UUID if not set (for SQLite compatibility)
 	if d.ID == uuid.Nil {
 		d.ID = uuid.New()
 	}
 
-	// Check if we're using SQLite (for compatibility)
-	var isSQLite bool
-	var versionStr string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&versionStr)
-	isSQLite = err == nil
-
 	var serviceID interface{}
 	if d.ServiceID.Valid {
 		serviceID = d.ServiceID.String
@@ -65,18 +73,20 @@ func (db *DB) CreateDatabase(ctx context.Context, d *Database) error {
 		query := `
 			INSERT INTO databases (
 				id, service_id, engine, version, size,
-				volume_id, volume_size_mb, status
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				volume_id, volume_size_mb, storage_class, status,
+				database_name, username, created_by, updated_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		`
-		_, err = db.ExecContext(ctx, query,
+		_, err := q.ExecContext(ctx, query,
 			d.ID.String(), serviceID, d.Engine, version, d.Size,
-			volumeID, d.VolumeSizeMB, d.Status,
+			volumeID, d.VolumeSizeMB, d.StorageClass, d.Status,
+			d.DatabaseName, d.Username, d.CreatedBy, d.UpdatedBy,
 		)
 		if err != nil {
 			return err
 		}
 		// Get timestamp
-		err = db.QueryRowContext(ctx, "SELECT created_at FROM databases WHERE id = $1", d.ID.String()).
+		err = q.QueryRowContext(ctx, "SELECT created_at FROM databases WHERE id = $1", d.ID.String()).
 			Scan(&d.CreatedAt)
 		return err
 	}
@@ -85,19 +95,25 @@ func (db *DB) CreateDatabase(ctx context.Context, d *Database) error {
 	query := `
 		INSERT INTO databases (
 			service_id, engine, version, size,
-			volume_id, volume_size_mb, status
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			volume_id, volume_size_mb, storage_class, status,
+			database_name, username, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at
 	`
 
-	err = db.QueryRowContext(ctx, query,
+	err := q.QueryRowContext(ctx, query,
 		serviceID,
 		d.Engine,
 		version,
 		d.Size,
 		volumeID,
 		d.VolumeSizeMB,
+		d.StorageClass,
 		d.Status,
+		d.DatabaseName,
+		d.Username,
+		d.CreatedBy,
+		d.UpdatedBy,
 	).Scan(&d.ID, &d.CreatedAt)
 
 	return err
@@ -110,7 +126,7 @@ func (db *DB) GetDatabase(ctx context.Context, id uuid.UUID) (*Database, error)
 		       volume_id, volume_size_mb, internal_hostname, internal_ip, port,
 		       username, password, database_name, connection_url,
 		       openstack_instance_id, openstack_port_id, security_group_id,
-		       status, created_at
+		       storage_class, status, created_by, updated_by, created_at
 		FROM databases
 		WHERE id = $1
 	`
@@ -148,7 +164,10 @@ func (db *DB) GetDatabase(ctx context.Context, id uuid.UUID) (*Database, error)
 		&openstackInstanceID,
 		&openstackPortID,
 		&securityGroupID,
+		&d.StorageClass,
 		&d.Status,
+		&d.CreatedBy,
+		&d.UpdatedBy,
 		&d.CreatedAt,
 	)
 
@@ -184,7 +203,7 @@ func (db *DB) ListDatabasesByService(ctx context.Context, serviceID uuid.UUID) (
 		       volume_id, volume_size_mb, internal_hostname, internal_ip, port,
 		       username, password, database_name, connection_url,
 		       openstack_instance_id, openstack_port_id, security_group_id,
-		       status, created_at
+		       storage_class, status, created_by, updated_by, created_at
 		FROM databases
 		WHERE service_id = $1
 		ORDER BY created_at DESC
@@ -231,7 +250,10 @@ func (db *DB) ListDatabasesByService(ctx context.Context, serviceID uuid.UUID) (
 			&openstackInstanceID,
 			&openstackPortID,
 			&securityGroupID,
+			&d.StorageClass,
 			&d.Status,
+			&d.CreatedBy,
+			&d.UpdatedBy,
 			&d.CreatedAt,
 		)
 		if err != nil {
@@ -265,7 +287,7 @@ func (db *DB) ListDatabasesByProject(ctx context.Context, projectID uuid.UUID) (
 		       d.volume_id, d.volume_size_mb, d.internal_hostname, d.internal_ip, d.port,
 		       d.username, d.password, d.database_name, d.connection_url,
 		       d.openstack_instance_id, d.openstack_port_id, d.security_group_id,
-		       d.status, d.created_at
+		       d.storage_class, d.status, d.created_by, d.updated_by, d.created_at
 		FROM databases d
 		JOIN services s ON d.service_id = s.id
 		WHERE s.project_id = $1
@@ -313,7 +335,10 @@ func (db *DB) ListDatabasesByProject(ctx context.Context, projectID uuid.UUID) (
 			&openstackInstanceID,
 			&openstackPortID,
 			&securityGroupID,
+			&d.StorageClass,
 			&d.Status,
+			&d.CreatedBy,
+			&d.UpdatedBy,
 			&d.CreatedAt,
 		)
 		if err != nil {
@@ -507,6 +532,42 @@ func (db *DB) UpdateDatabaseStatus(ctx context.Context, id uuid.UUID, status str
 	return err
 }
 
+// ErrDatabaseNotActive is returned when upgrading a database that isn't running or is
+// already being upgraded
+var ErrDatabaseNotActive = errors.New("database is not active")
+
+// StartDatabaseUpgrade marks an active database upgrading, so only one upgrade of it
+// runs at a time
+func (db *DB) StartDatabaseUpgrade(ctx context.Context, id uuid.UUID) error {
+	result, err := db.ExecContext(ctx, `UPDATE databases SET status = 'upgrading' WHERE id = $1 AND status = 'active'`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrDatabaseNotActive
+	}
+
+	return nil
+}
+
+// FinishDatabaseUpgrade marks an upgrading database active again, running version
+// ("" when the upgrade didn't happen)
+func (db *DB) FinishDatabaseUpgrade(ctx context.Context, id uuid.UUID, version, updatedBy string) error {
+	if version == "" {
+		_, err := db.ExecContext(ctx, `UPDATE databases SET status = 'active' WHERE id = $1 AND status = 'upgrading'`, id)
+		return err
+	}
+
+	_, err := db.ExecContext(ctx, `UPDATE databases SET status = 'active', version = $1, updated_by = $2 WHERE id = $3 AND status = 'upgrading'`,
+		version, StringToNullString(updatedBy), id)
+	return err
+}
+
 // UpdateDatabaseFields updates multiple fields of a database using a map
 func (db *DB) UpdateDatabaseFields(ctx context.Context, id uuid.UUID, fields map[string]interface{}) error {
 	if len(fields) == 0 {
@@ -534,3 +595,51 @@ func (db *DB) UpdateDatabaseFields(ctx context.Context, id uuid.UUID, fields map
 	return err
 }
 
+
+// GetDatabaseParameters returns a database's config overrides (empty if it has none).
+// Returns nil if the database does not exist.
+func (db *DB) GetDatabaseParameters(ctx context.Context, id uuid.UUID) (map[string]string, error) {
+	var parametersJSON sql.NullString
+	err := db.QueryRowContext(ctx, `SELECT parameters FROM databases WHERE id = $1`, id).Scan(&parametersJSON)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{}
+	if parametersJSON.Valid && parametersJSON.String != "" {
+		if err := json.Unmarshal([]byte(parametersJSON.String), &params); err != nil {
+			return nil, fmt.Errorf("failed to decode database parameters: %w", err)
+		}
+	}
+	return params, nil
+}
+
+// UpdateDatabaseParameters replaces a database's config overrides
+func (db *DB) UpdateDatabaseParameters(ctx context.Context, id uuid.UUID, params map[string]string, updatedBy string) error {
+	if params == nil {
+		params = map[string]string{}
+	}
+	parametersJSON, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.ExecContext(ctx, `UPDATE databases SET parameters = $1, updated_by = $2 WHERE id = $3`,
+		string(parametersJSON), StringToNullString(updatedBy), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 
 	"github.com/google/uuid"
@@ -17,12 +18,6 @@ func TestDB_CreateDatabase(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Check if we're using SQLite
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
 	database := &Database{
 		Engine:       "postgresql",
 		Version:      sql.NullString{String: "14", Valid: true},
@@ -31,7 +26,8 @@ func TestDB_CreateDatabase(t *testing.T) {
 		Status:       "pending",
 	}
 
-	if isSQLite {
+	var err error
+	if testutil.IsSQLite(db) {
 		database.ID = uuid.New()
 		query := `INSERT INTO databases (id, engine, version, size, volume_size_mb, status) 
 			VALUES ($1, $2, $3, $4, $5, $6)`
@@ -67,33 +63,7 @@ func TestDB_GetDatabase(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Setup
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	databaseID := uuid.New()
-
-	if isSQLite {
-		_, err = db.ExecContext(ctx, `INSERT INTO databases (id, engine, size, volume_size_mb, status) 
-			VALUES ($1, $2, $3, $4, $5)`,
-			databaseID.String(), "postgresql", "medium", 10240, "pending")
-		if err != nil {
-			t.Fatalf("Failed to create test database: %v", err)
-		}
-	} else {
-		database := &Database{
-			ID:           databaseID,
-			Engine:       "postgresql",
-			Size:         "medium",
-			VolumeSizeMB: 10240,
-			Status:       "pending",
-		}
-		if err := dbStore.CreateDatabase(ctx, database); err != nil {
-			t.Fatalf("Failed to create test database: %v", err)
-		}
-	}
+	databaseID := testutil.NewDatabase(t, db, uuid.Nil).ID
 
 	// Retrieve the database
 	retrieved, err := dbStore.GetDatabase(ctx, databaseID)
@@ -132,33 +102,7 @@ func TestDB_UpdateDatabase(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Setup
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	databaseID := uuid.New()
-
-	if isSQLite {
-		_, err = db.ExecContext(ctx, `INSERT INTO databases (id, engine, size, volume_size_mb, status) 
-			VALUES ($1, $2, $3, $4, $5)`,
-			databaseID.String(), "postgresql", "medium", 10240, "pending")
-		if err != nil {
-			t.Fatalf("Failed to create test database: %v", err)
-		}
-	} else {
-		database := &Database{
-			ID:           databaseID,
-			Engine:       "postgresql",
-			Size:         "medium",
-			VolumeSizeMB: 10240,
-			Status:       "pending",
-		}
-		if err := dbStore.CreateDatabase(ctx, database); err != nil {
-			t.Fatalf("Failed to create test database: %v", err)
-		}
-	}
+	databaseID := testutil.NewDatabase(t, db, uuid.Nil).ID
 
 	// Update the database
 	updates := &Database{
@@ -173,7 +117,8 @@ func TestDB_UpdateDatabase(t *testing.T) {
 		OpenStackInstanceID: sql.NullString{String: "instance-123", Valid: true},
 	}
 
-	if isSQLite {
+	var err error
+	if testutil.IsSQLite(db) {
 		query := `UPDATE databases SET status = $1, internal_hostname = $2, internal_ip = $3, 
 			port = $4, username = $5, password = $6, database_name = $7, connection_url = $8, 
 			openstack_instance_id = $9 WHERE id = $10`
@@ -216,36 +161,10 @@ func TestDB_DeleteDatabase(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Setup
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	databaseID := uuid.New()
-
-	if isSQLite {
-		_, err = db.ExecContext(ctx, `INSERT INTO databases (id, engine, size, volume_size_mb, status) 
-			VALUES ($1, $2, $3, $4, $5)`,
-			databaseID.String(), "postgresql", "medium", 10240, "pending")
-		if err != nil {
-			t.Fatalf("Failed to create test database: %v", err)
-		}
-	} else {
-		database := &Database{
-			ID:           databaseID,
-			Engine:       "postgresql",
-			Size:         "medium",
-			VolumeSizeMB: 10240,
-			Status:       "pending",
-		}
-		if err := dbStore.CreateDatabase(ctx, database); err != nil {
-			t.Fatalf("Failed to create test database: %v", err)
-		}
-	}
+	databaseID := testutil.NewDatabase(t, db, uuid.Nil).ID
 
 	// Delete the database
-	err = dbStore.DeleteDatabase(ctx, databaseID)
+	err := dbStore.DeleteDatabase(ctx, databaseID)
 	if err != nil {
 		t.Fatalf("Failed to delete database: %v", err)
 	}
@@ -261,3 +180,37 @@ func TestDB_DeleteDatabase(t *testing.T) {
 	}
 }
 
+
+func TestDB_DatabaseUpgrade(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	pending := testutil.NewDatabase(t, db, uuid.Nil).ID
+	if err := dbStore.StartDatabaseUpgrade(ctx, pending); !errors.Is(err, ErrDatabaseNotActive) {
+		t.Errorf("StartDatabaseUpgrade of a pending database = %v, want ErrDatabaseNotActive", err)
+	}
+
+	databaseID := testutil.NewDatabase(t, db, uuid.Nil, func(d *testutil.Database) { d.Status = "active" }).ID
+	if err := dbStore.StartDatabaseUpgrade(ctx, databaseID); err != nil {
+		t.Fatalf("StartDatabaseUpgrade failed: %v", err)
+	}
+	// Only one upgrade at a time
+	if err := dbStore.StartDatabaseUpgrade(ctx, databaseID); !errors.Is(err, ErrDatabaseNotActive) {
+		t.Errorf("second StartDatabaseUpgrade = %v, want ErrDatabaseNotActive", err)
+	}
+
+	if err := dbStore.FinishDatabaseUpgrade(ctx, databaseID, "17", ""); err != nil {
+		t.Fatalf("FinishDatabaseUpgrade failed: %v", err)
+	}
+	database, err := dbStore.GetDatabase(ctx, databaseID)
+	if err != nil {
+		t.Fatalf("GetDatabase failed: %v", err)
+	}
+	if database.Status != "active" || database.Version.String != "17" {
+		t.Errorf("status = %s, version = %s, want active and 17", database.Status, database.Version.String)
+	}
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -12,6 +13,13 @@ type DB struct {
 	*sql.DB
 }
 
+// execer runs statements on the database or in a transaction, so inserts can be
+// shared by single-row methods and batches that must all succeed or fail together
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 // PoolConfig holds database connection pool configuration
 type PoolConfig struct {
 	MaxOpenConns    int // Maximum number of open connections
@@ -0,0 +1,272 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeploymentStatusWaiting is the status of a deployment held back because its org
+// already has the maximum number of deployments in flight
+const DeploymentStatusWaiting = "waiting"
+
+// WaitingDeployment is a deployment waiting for one of its org's deploy slots
+type WaitingDeployment struct {
+	ID    uuid.UUID
+	OrgID string
+}
+
+// CountActiveDeploymentsByOrg counts the org's deployments that are in flight
+// (queued, building, pushing or deploying)
+func (db *DB) CountActiveDeploymentsByOrg(ctx context.Context, orgID string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM deployments d
+		JOIN services s ON s.id = d.service_id
+		JOIN projects p ON p.id = s.project_id
+		WHERE p.casdoor_org_id = $1 AND d.status IN ('queued', 'building', 'pushing', 'deploying')
+	`
+
+	var count int
+	err := db.QueryRowContext(ctx, query, orgID).Scan(&count)
+	return count, err
+}
+
+// HasActiveDeployment reports whether the service has a deployment in flight
+// (queued, building, pushing or deploying)
+func (db *DB) HasActiveDeployment(ctx context.Context, serviceID uuid.UUID) (bool, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM deployments
+		WHERE service_id = $1 AND status IN ('queued', 'building', 'pushing', 'deploying')
+	`
+
+	var count int
+	err := db.QueryRowContext(ctx, query, serviceID).Scan(&count)
+	return count > 0, err
+}
+
+// CountWaitingDeploymentsByOrg counts the org's deployments waiting for a deploy slot
+func (db *DB) CountWaitingDeploymentsByOrg(ctx context.Context, orgID string) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM deployments d
+		JOIN services s ON s.id = d.service_id
+		JOIN projects p ON p.id = s.project_id
+		WHERE p.casdoor_org_id = $1 AND d.status = 'waiting'
+	`
+
+	var count int
+	err := db.QueryRowContext(ctx, query, orgID).Scan(&count)
+	return count, err
+}
+
+// ListWaitingDeployments lists deployments waiting for a deploy slot, oldest first
+func (db *DB) ListWaitingDeployments(ctx context.Context) ([]*WaitingDeployment, error) {
+	query := `
+		SELECT d.id, p.casdoor_org_id
+		FROM deployments d
+		JOIN services s ON s.id = d.service_id
+		JOIN projects p ON p.id = s.project_id
+		WHERE d.status = 'waiting'
+		ORDER BY d.created_at ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deployments []*WaitingDeployment
+	for rows.Next() {
+		var d WaitingDeployment
+		if err := rows.Scan(&d.ID, &d.OrgID); err != nil {
+			return nil, err
+		}
+		deployments = append(deployments, &d)
+	}
+
+	return deployments, rows.Err()
+}
+
+// GetDeploymentQueuePosition returns the 1-based position of a waiting deployment
+// in its org's queue, or 0 if the deployment isn't waiting
+func (db *DB) GetDeploymentQueuePosition(ctx context.Context, id uuid.UUID) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM deployments target
+		JOIN services ts ON ts.id = target.service_id
+		JOIN projects tp ON tp.id = ts.project_id
+		JOIN deployments d ON d.status = 'waiting' AND d.created_at <= target.created_at
+		JOIN services s ON s.id = d.service_id
+		JOIN projects p ON p.id = s.project_id AND p.casdoor_org_id = tp.casdoor_org_id
+		WHERE target.id = $1 AND target.status = 'waiting'
+	`
+
+	var position int
+	err := db.QueryRowContext(ctx, query, id).Scan(&position)
+	return position, err
+}
+
+// StartWaitingDeployment moves a waiting deployment to queued. It returns false if the
+// deployment was no longer waiting (e.g. it was cancelled in the meantime).
+func (db *DB) StartWaitingDeployment(ctx context.Context, id uuid.UUID) (bool, error) {
+	defer db.cacheInvalidator(ctx, deploymentProjectQuery, id)()
+
+	query := `UPDATE deployments SET status = 'queued' WHERE id = $1 AND status = 'waiting'`
+
+	result, err := db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// GetDeploymentOrgID returns the org of the project a deployment's service is in, or
+// "" if the deployment doesn't exist
+func (db *DB) GetDeploymentOrgID(ctx context.Context, id uuid.UUID) (string, error) {
+	query := `
+		SELECT p.casdoor_org_id
+		FROM deployments d
+		JOIN services s ON s.id = d.service_id
+		JOIN projects p ON p.id = s.project_id
+		WHERE d.id = $1
+	`
+
+	var orgID string
+	err := db.QueryRowContext(ctx, query, id).Scan(&orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return orgID, err
+}
+
+// AdmitWaitingDeployment moves a waiting deployment of the org to queued when the
+// org has fewer than limit deployments in flight and none waiting ahead of it. It
+// returns false if the deployment stays waiting or was no longer waiting.
+//
+// The check and the update are one statement run under the org's lock, a
+// transaction-scoped advisory lock on Postgres (SQLite serializes writers), so
+// concurrent admissions for an org can't overshoot its limit.
+func (db *DB) AdmitWaitingDeployment(ctx context.Context, id uuid.UUID, orgID string, limit int) (bool, error) {
+	defer db.cacheInvalidator(ctx, deploymentProjectQuery, id)()
+
+	var version string
+	isSQLite := db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&version) == nil
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if !isSQLite {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('deploy_queue:' || $1))", orgID); err != nil {
+			return false, err
+		}
+	}
+
+	query := `
+		UPDATE deployments
+		SET status = 'queued'
+		WHERE id = $1 AND status = 'waiting'
+		  AND (
+			SELECT COUNT(*)
+			FROM deployments a
+			JOIN services s ON s.id = a.service_id
+			JOIN projects p ON p.id = s.project_id
+			WHERE p.casdoor_org_id = $2 AND a.status IN ('queued', 'building', 'pushing', 'deploying')
+		  ) < $3
+		  AND NOT EXISTS (
+			SELECT 1
+			FROM deployments w
+			JOIN services s ON s.id = w.service_id
+			JOIN projects p ON p.id = s.project_id
+			WHERE p.casdoor_org_id = $2 AND w.status = 'waiting'
+			  AND w.created_at < (SELECT t.created_at FROM deployments t WHERE t.id = $1)
+		  )
+	`
+	result, err := tx.ExecContext(ctx, query, id, orgID, limit)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// FailStaleDeployments fails the deployments in flight (queued, building, pushing or
+// deploying) since before cutoff, whose pipeline died with the server that ran it,
+// freeing their org's deploy slots. It returns the IDs of the deployments failed.
+func (db *DB) FailStaleDeployments(ctx context.Context, cutoff time.Time, message string) ([]uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM deployments
+		WHERE status IN ('queued', 'building', 'pushing', 'deploying')
+		  AND COALESCE(started_at, created_at) < $1
+	`
+	rows, err := db.QueryContext(ctx, query, cutoff.UTC())
+	if err != nil {
+		return nil, err
+	}
+	var stale []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stale = append(stale, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var failed []uuid.UUID
+	for _, id := range stale {
+		ok, err := db.failInFlightDeployment(ctx, id, message)
+		if err != nil {
+			return failed, err
+		}
+		if ok {
+			failed = append(failed, id)
+		}
+	}
+	return failed, nil
+}
+
+// failInFlightDeployment fails a deployment unless it finished meanwhile
+func (db *DB) failInFlightDeployment(ctx context.Context, id uuid.UUID, message string) (bool, error) {
+	defer db.cacheInvalidator(ctx, deploymentProjectQuery, id)()
+
+	query := `
+		UPDATE deployments
+		SET status = 'failed', error_message = $1, finished_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status IN ('queued', 'building', 'pushing', 'deploying')
+	`
+	result, err := db.ExecContext(ctx, query, message, id)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
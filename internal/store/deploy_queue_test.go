@@ -0,0 +1,234 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestDB_DeployQueue(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	newService := func(orgID, slug string) *Service {
+		project := &Project{
+			CasdoorOrgID:      orgID,
+			Name:              slug,
+			Slug:              slug,
+			OpenStackTenantID: "test-tenant",
+		}
+		if err := dbStore.CreateProject(ctx, project); err != nil {
+			t.Fatalf("Failed to create test project: %v", err)
+		}
+		service := &Service{
+			ProjectID:    project.ID,
+			Name:         "Test Service",
+			Type:         "app",
+			Status:       "pending",
+			InstanceSize: "medium",
+			Port:         8080,
+		}
+		if err := dbStore.CreateService(ctx, service); err != nil {
+			t.Fatalf("Failed to create test service: %v", err)
+		}
+		return service
+	}
+	service := newService("org-a", "project-a")
+	otherService := newService("org-b", "project-b")
+
+	base := time.Now().Add(-time.Hour)
+	newDeployment := func(service *Service, status string, age int) *Deployment {
+		d := &Deployment{ServiceID: service.ID, Status: status, TriggeredBy: "manual"}
+		if err := dbStore.CreateDeployment(ctx, d); err != nil {
+			t.Fatalf("Failed to create deployment: %v", err)
+		}
+		// Spread creation times so queue order doesn't depend on timestamp resolution
+		if _, err := db.ExecContext(ctx, "UPDATE deployments SET created_at = $1 WHERE id = $2", base.Add(time.Duration(age)*time.Minute), d.ID); err != nil {
+			t.Fatalf("Failed to set created_at: %v", err)
+		}
+		return d
+	}
+
+	newDeployment(service, "building", 0)
+	newDeployment(service, "success", 1)
+	first := newDeployment(service, DeploymentStatusWaiting, 2)
+	second := newDeployment(service, DeploymentStatusWaiting, 3)
+	newDeployment(otherService, "deploying", 4)
+	otherWaiting := newDeployment(otherService, DeploymentStatusWaiting, 5)
+
+	active, err := dbStore.CountActiveDeploymentsByOrg(ctx, "org-a")
+	if err != nil {
+		t.Fatalf("Failed to count active deployments: %v", err)
+	}
+	if active != 1 {
+		t.Errorf("Expected 1 active deployment, got %d", active)
+	}
+
+	for _, tt := range []struct {
+		deployment *Deployment
+		want       int
+	}{
+		{first, 1},
+		{second, 2},
+		{otherWaiting, 1},
+	} {
+		position, err := dbStore.GetDeploymentQueuePosition(ctx, tt.deployment.ID)
+		if err != nil {
+			t.Fatalf("Failed to get queue position: %v", err)
+		}
+		if position != tt.want {
+			t.Errorf("Queue position of %s = %d, want %d", tt.deployment.ID, position, tt.want)
+		}
+	}
+
+	waiting, err := dbStore.ListWaitingDeployments(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list waiting deployments: %v", err)
+	}
+	if len(waiting) != 3 || waiting[0].ID != first.ID || waiting[0].OrgID != "org-a" || waiting[2].OrgID != "org-b" {
+		t.Fatalf("Unexpected waiting deployments: %+v", waiting)
+	}
+
+	started, err := dbStore.StartWaitingDeployment(ctx, first.ID)
+	if err != nil || !started {
+		t.Fatalf("Expected first deployment to start, got %v, %v", started, err)
+	}
+	if started, _ := dbStore.StartWaitingDeployment(ctx, first.ID); started {
+		t.Error("Expected a deployment to start only once")
+	}
+
+	position, err := dbStore.GetDeploymentQueuePosition(ctx, second.ID)
+	if err != nil {
+		t.Fatalf("Failed to get queue position: %v", err)
+	}
+	if position != 1 {
+		t.Errorf("Expected second deployment to move up to position 1, got %d", position)
+	}
+	if position, _ := dbStore.GetDeploymentQueuePosition(ctx, first.ID); position != 0 {
+		t.Errorf("Expected no queue position for a started deployment, got %d", position)
+	}
+	if active, _ := dbStore.CountActiveDeploymentsByOrg(ctx, "org-a"); active != 2 {
+		t.Errorf("Expected 2 active deployments after starting one, got %d", active)
+	}
+}
+
+func TestDB_AdmitWaitingDeployment(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	service := testutil.NewService(t, db, project.ID)
+
+	base := time.Now().Add(-time.Hour)
+	newDeployment := func(status string, age int) *Deployment {
+		d := &Deployment{ServiceID: service.ID, Status: status, TriggeredBy: "manual"}
+		if err := dbStore.CreateDeployment(ctx, d); err != nil {
+			t.Fatalf("Failed to create deployment: %v", err)
+		}
+		if _, err := db.ExecContext(ctx, "UPDATE deployments SET created_at = $1 WHERE id = $2", base.Add(time.Duration(age)*time.Minute), d.ID); err != nil {
+			t.Fatalf("Failed to set created_at: %v", err)
+		}
+		return d
+	}
+
+	newDeployment("building", 0)
+	first := newDeployment(DeploymentStatusWaiting, 1)
+	second := newDeployment(DeploymentStatusWaiting, 2)
+
+	admit := func(d *Deployment, limit int) bool {
+		admitted, err := dbStore.AdmitWaitingDeployment(ctx, d.ID, project.OrgID, limit)
+		if err != nil {
+			t.Fatalf("AdmitWaitingDeployment failed: %v", err)
+		}
+		return admitted
+	}
+
+	if admit(first, 1) {
+		t.Error("Expected no admission while the org's only slot is taken")
+	}
+	if admit(second, 3) {
+		t.Error("Expected no admission ahead of an older waiting deployment")
+	}
+	if !admit(first, 2) {
+		t.Fatal("Expected the oldest waiting deployment admitted into a free slot")
+	}
+	if admit(first, 3) {
+		t.Error("Expected a deployment admitted only once")
+	}
+	// The last slot was just taken
+	if admit(second, 2) {
+		t.Error("Expected no admission beyond the limit")
+	}
+	if !admit(second, 3) {
+		t.Error("Expected the next waiting deployment admitted into a free slot")
+	}
+
+	orgID, err := dbStore.GetDeploymentOrgID(ctx, second.ID)
+	if err != nil || orgID != project.OrgID {
+		t.Errorf("GetDeploymentOrgID = %q, %v, want %q", orgID, err, project.OrgID)
+	}
+}
+
+func TestDB_FailStaleDeployments(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	service := testutil.NewService(t, db, project.ID)
+
+	newDeployment := func(status string, age time.Duration) *Deployment {
+		d := &Deployment{ServiceID: service.ID, Status: status, TriggeredBy: "manual"}
+		if err := dbStore.CreateDeployment(ctx, d); err != nil {
+			t.Fatalf("Failed to create deployment: %v", err)
+		}
+		if _, err := db.ExecContext(ctx, "UPDATE deployments SET created_at = $1 WHERE id = $2", time.Now().Add(-age).UTC(), d.ID); err != nil {
+			t.Fatalf("Failed to set created_at: %v", err)
+		}
+		return d
+	}
+
+	stale := newDeployment("building", 3*time.Hour)
+	running := newDeployment("deploying", time.Minute)
+	finished := newDeployment("success", 3*time.Hour)
+	waiting := newDeployment(DeploymentStatusWaiting, 3*time.Hour)
+
+	failed, err := dbStore.FailStaleDeployments(ctx, time.Now().Add(-2*time.Hour), "pipeline stopped")
+	if err != nil {
+		t.Fatalf("FailStaleDeployments failed: %v", err)
+	}
+	if len(failed) != 1 || failed[0] != stale.ID {
+		t.Fatalf("Failed deployments = %v, want only %s", failed, stale.ID)
+	}
+
+	for _, tt := range []struct {
+		deployment *Deployment
+		want       string
+	}{
+		{stale, "failed"},
+		{running, "deploying"},
+		{finished, "success"},
+		{waiting, DeploymentStatusWaiting},
+	} {
+		d, err := dbStore.GetDeployment(ctx, tt.deployment.ID)
+		if err != nil {
+			t.Fatalf("Failed to get deployment: %v", err)
+		}
+		if d.Status != tt.want {
+			t.Errorf("Deployment status = %s, want %s", d.Status, tt.want)
+		}
+	}
+}
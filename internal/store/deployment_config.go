@@ -0,0 +1,82 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DeploymentConfig is the effective config of a service when a deployment rolled it out
+type DeploymentConfig struct {
+	Image           string   `json:"image"`
+	EnvVarKeys      []string `json:"env_var_keys"` // Sorted; values are left out, they may be secrets
+	InstanceSize    string   `json:"instance_size"`
+	Replicas        int      `json:"replicas"`
+	Port            int      `json:"port"`
+	HealthCheckPath string   `json:"health_check_path,omitempty"`
+}
+
+// ConfigChange is a setting that differs between two deployment configs
+type ConfigChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// DeploymentConfigDiff lists how one deployment config differs from another
+type DeploymentConfigDiff struct {
+	Changes        []ConfigChange `json:"changes"`
+	EnvVarsAdded   []string       `json:"env_vars_added"`
+	EnvVarsRemoved []string       `json:"env_vars_removed"`
+}
+
+// DiffDeploymentConfigs returns how config to differs from config from. A nil
+// config, e.g. of a deployment from before snapshots were taken, diffs as empty.
+func DiffDeploymentConfigs(from, to *DeploymentConfig) DeploymentConfigDiff {
+	if from == nil {
+		from = &DeploymentConfig{}
+	}
+	if to == nil {
+		to = &DeploymentConfig{}
+	}
+
+	diff := DeploymentConfigDiff{
+		Changes:        []ConfigChange{},
+		EnvVarsAdded:   missingKeys(to.EnvVarKeys, from.EnvVarKeys),
+		EnvVarsRemoved: missingKeys(from.EnvVarKeys, to.EnvVarKeys),
+	}
+
+	fields := []struct {
+		name     string
+		from, to string
+	}{
+		{"image", from.Image, to.Image},
+		{"instance_size", from.InstanceSize, to.InstanceSize},
+		{"replicas", fmt.Sprint(from.Replicas), fmt.Sprint(to.Replicas)},
+		{"port", fmt.Sprint(from.Port), fmt.Sprint(to.Port)},
+		{"health_check_path", from.HealthCheckPath, to.HealthCheckPath},
+	}
+	for _, f := range fields {
+		if f.from != f.to {
+			diff.Changes = append(diff.Changes, ConfigChange{Field: f.name, From: f.from, To: f.to})
+		}
+	}
+
+	return diff
+}
+
+// missingKeys returns the keys of a that aren't in b, sorted
+func missingKeys(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, k := range b {
+		inB[k] = true
+	}
+
+	missing := []string{}
+	for _, k := range a {
+		if !inB[k] {
+			missing = append(missing, k)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffDeploymentConfigs(t *testing.T) {
+	from := &DeploymentConfig{
+		Image:        "registry/app:v1",
+		EnvVarKeys:   []string{"API_KEY", "DATABASE_URL"},
+		InstanceSize: "small",
+		Replicas:     1,
+		Port:         8080,
+	}
+	to := &DeploymentConfig{
+		Image:        "registry/app:v2",
+		EnvVarKeys:   []string{"DATABASE_URL", "REDIS_URL"},
+		InstanceSize: "small",
+		Replicas:     3,
+		Port:         8080,
+	}
+
+	diff := DiffDeploymentConfigs(from, to)
+
+	wantChanges := []ConfigChange{
+		{Field: "image", From: "registry/app:v1", To: "registry/app:v2"},
+		{Field: "replicas", From: "1", To: "3"},
+	}
+	if !reflect.DeepEqual(diff.Changes, wantChanges) {
+		t.Errorf("Changes = %+v, want %+v", diff.Changes, wantChanges)
+	}
+	if !reflect.DeepEqual(diff.EnvVarsAdded, []string{"REDIS_URL"}) {
+		t.Errorf("EnvVarsAdded = %v, want [REDIS_URL]", diff.EnvVarsAdded)
+	}
+	if !reflect.DeepEqual(diff.EnvVarsRemoved, []string{"API_KEY"}) {
+		t.Errorf("EnvVarsRemoved = %v, want [API_KEY]", diff.EnvVarsRemoved)
+	}
+
+	if same := DiffDeploymentConfigs(to, to); len(same.Changes) != 0 || len(same.EnvVarsAdded) != 0 || len(same.EnvVarsRemoved) != 0 {
+		t.Errorf("DiffDeploymentConfigs(to, to) = %+v, want no differences", same)
+	}
+}
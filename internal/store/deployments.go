@@ -17,19 +17,38 @@ type Deployment struct {
 	CommitSHA     sql.NullString
 	CommitMessage sql.NullString
 	CommitAuthor  sql.NullString
-	Status        string // queued, building, pushing, deploying, success, failed, cancelled
+	Status        string // awaiting_approval, waiting, queued, building, pushing, deploying, success, failed, cancelled
 	ImageTag      sql.NullString
+	GitTag        sql.NullString // set for deployments triggered by a tag push
 	BuildDuration sql.NullInt64 // seconds
 	DeployDuration sql.NullInt64 // seconds
 	ErrorMessage  sql.NullString
-	TriggeredBy   string // webhook, manual, rollback
+	TriggeredBy   string // webhook, manual, rollback, archive
+	RequestedBy   sql.NullString // ID of the user who triggered it, if any
+	ApprovedBy    sql.NullString // ID of the user who approved it, for services requiring approval
+	ApprovedAt    sql.NullTime
 	StartedAt     sql.NullTime
 	FinishedAt    sql.NullTime
 	CreatedAt     time.Time
+	ConfigSnapshot *DeploymentConfig // Config the deployment rolled out; only loaded by GetDeployment
+	// Set for rollbacks: the deployment that was live when rolling back and the one whose
+	// image is redeployed. Only loaded by GetDeployment.
+	RolledBackFromID sql.NullString
+	RollbackTargetID sql.NullString
 }
 
+// DeploymentTriggerRollback is the TriggeredBy of deployments redeploying the image of
+// an earlier deployment
+const DeploymentTriggerRollback = "rollback"
+
+// DeploymentTriggerArchive is the TriggeredBy of deployments of an uploaded source
+// archive, which are built from the archive instead of the service's git source
+const DeploymentTriggerArchive = "archive"
+
 // CreateDeployment creates a new deployment record
 func (db *DB) CreateDeployment(ctx context.Context, d *Deployment) error {
+	defer db.cacheInvalidator(ctx, serviceProjectQuery, d.ServiceID)()
+
 	// Generate UUID if not set (for SQLite compatibility)
 	if d.ID == uuid.Nil {
 		d.ID = uuid.New()
@@ -71,12 +90,14 @@ func (db *DB) CreateDeployment(ctx context.Context, d *Deployment) error {
 		query := `
 			INSERT INTO deployments (
 				id, service_id, commit_sha, commit_message, commit_author,
-				status, image_tag, triggered_by, started_at
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+				status, image_tag, git_tag, triggered_by, requested_by, started_at,
+				rolled_back_from_id, rollback_target_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		`
 		_, err = db.ExecContext(ctx, query,
 			d.ID.String(), d.ServiceID.String(), commitSHA, commitMessage, commitAuthor,
-			d.Status, imageTag, d.TriggeredBy, startedAt,
+			d.Status, imageTag, d.GitTag, d.TriggeredBy, d.RequestedBy, startedAt,
+			d.RolledBackFromID, d.RollbackTargetID,
 		)
 		if err != nil {
 			return err
@@ -91,8 +112,9 @@ func (db *DB) CreateDeployment(ctx context.Context, d *Deployment) error {
 	query := `
 		INSERT INTO deployments (
 			service_id, commit_sha, commit_message, commit_author,
-			status, image_tag, triggered_by, started_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			status, image_tag, git_tag, triggered_by, requested_by, started_at,
+			rolled_back_from_id, rollback_target_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at
 	`
 
@@ -103,8 +125,12 @@ func (db *DB) CreateDeployment(ctx context.Context, d *Deployment) error {
 		commitAuthor,
 		d.Status,
 		imageTag,
+		d.GitTag,
 		d.TriggeredBy,
+		d.RequestedBy,
 		startedAt,
+		d.RolledBackFromID,
+		d.RollbackTargetID,
 	).Scan(&d.ID, &d.CreatedAt)
 
 	return err
@@ -115,8 +141,10 @@ func (db *DB) GetDeployment(ctx context.Context, id uuid.UUID) (*Deployment, err
 	var d Deployment
 	query := `
 		SELECT id, service_id, commit_sha, commit_message, commit_author,
-		       status, image_tag, build_duration, deploy_duration,
-		       error_message, triggered_by, started_at, finished_at, created_at
+		       status, image_tag, git_tag, build_duration, deploy_duration,
+		       error_message, triggered_by, requested_by, approved_by, approved_at,
+		       started_at, finished_at, created_at, config_snapshot,
+		       rolled_back_from_id, rollback_target_id
 		FROM deployments
 		WHERE id = $1
 	`
@@ -130,6 +158,7 @@ func (db *DB) GetDeployment(ctx context.Context, id uuid.UUID) (*Deployment, err
 	var errorMessage sql.NullString
 	var startedAt sql.NullTime
 	var finishedAt sql.NullTime
+	var configSnapshot sql.NullString
 
 	err := db.QueryRowContext(ctx, query, id).Scan(
 		&d.ID,
@@ -139,13 +168,20 @@ func (db *DB) GetDeployment(ctx context.Context, id uuid.UUID) (*Deployment, err
 		&commitAuthor,
 		&d.Status,
 		&imageTag,
+		&d.GitTag,
 		&buildDuration,
 		&deployDuration,
 		&errorMessage,
 		&d.TriggeredBy,
+		&d.RequestedBy,
+		&d.ApprovedBy,
+		&d.ApprovedAt,
 		&startedAt,
 		&finishedAt,
 		&d.CreatedAt,
+		&configSnapshot,
+		&d.RolledBackFromID,
+		&d.RollbackTargetID,
 	)
 
 	if err == sql.ErrNoRows {
@@ -155,6 +191,13 @@ func (db *DB) GetDeployment(ctx context.Context, id uuid.UUID) (*Deployment, err
 		return nil, err
 	}
 
+	if configSnapshot.Valid {
+		d.ConfigSnapshot = &DeploymentConfig{}
+		if err := json.Unmarshal([]byte(configSnapshot.String), d.ConfigSnapshot); err != nil {
+			return nil, fmt.Errorf("failed to decode config snapshot: %w", err)
+		}
+	}
+
 	d.CommitSHA = commitSHA
 	d.CommitMessage = commitMessage
 	d.CommitAuthor = commitAuthor
@@ -168,19 +211,95 @@ func (db *DB) GetDeployment(ctx context.Context, id uuid.UUID) (*Deployment, err
 	return &d, nil
 }
 
-// ListDeploymentsByService lists deployments for a service, ordered by created_at DESC
-func (db *DB) ListDeploymentsByService(ctx context.Context, serviceID uuid.UUID, limit, offset int) ([]*Deployment, error) {
+// GetRecentDeploymentForCommit returns the newest deployment of a commit to a service
+// that is still in progress and was created less than window ago, or nil when there's none
+func (db *DB) GetRecentDeploymentForCommit(ctx context.Context, serviceID uuid.UUID, commitSHA string, window time.Duration) (*Deployment, error) {
 	query := `
-		SELECT id, service_id, commit_sha, commit_message, commit_author,
-		       status, image_tag, build_duration, deploy_duration,
-		       error_message, triggered_by, started_at, finished_at, created_at
+		SELECT id, created_at
 		FROM deployments
-		WHERE service_id = $1
+		WHERE service_id = $1 AND commit_sha = $2
+		  AND status NOT IN ('success', 'failed', 'cancelled')
 		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3
+		LIMIT 1
 	`
 
-	rows, err := db.QueryContext(ctx, query, serviceID, limit, offset)
+	var id uuid.UUID
+	var createdAt time.Time
+	err := db.QueryRowContext(ctx, query, serviceID, commitSHA).Scan(&id, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Compared here rather than in SQL, where SQLite and PostgreSQL timestamps differ
+	if time.Since(createdAt) > window {
+		return nil, nil
+	}
+
+	return db.GetDeployment(ctx, id)
+}
+
+// ActiveDeploymentsByService returns the newest deployment that isn't over yet (any
+// status but success, failed and cancelled) of each of the services that have one,
+// by service ID, in a single query
+func (db *DB) ActiveDeploymentsByService(ctx context.Context, serviceIDs []uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
+	active := make(map[uuid.UUID]uuid.UUID)
+	if len(serviceIDs) == 0 {
+		return active, nil
+	}
+
+	args := make([]interface{}, len(serviceIDs))
+	placeholders := make([]string, len(serviceIDs))
+	for i, id := range serviceIDs {
+		args[i] = id
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	query := `
+		SELECT service_id, id
+		FROM deployments
+		WHERE service_id IN (` + strings.Join(placeholders, ", ") + `)
+		  AND status NOT IN ('success', 'failed', 'cancelled')
+		ORDER BY created_at ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var serviceID, deploymentID uuid.UUID
+		if err := rows.Scan(&serviceID, &deploymentID); err != nil {
+			return nil, err
+		}
+		// Oldest first: the newest deployment of a service is scanned last
+		active[serviceID] = deploymentID
+	}
+	return active, rows.Err()
+}
+
+// CountDeploymentsByService counts a service's deployments
+func (db *DB) CountDeploymentsByService(ctx context.Context, serviceID uuid.UUID) (int, error) {
+	var count int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM deployments WHERE service_id = $1`, serviceID).Scan(&count)
+	return count, err
+}
+
+// ListDeploymentsByService lists a page of deployments for a service, sorted by one of
+// DeploymentSortFields
+func (db *DB) ListDeploymentsByService(ctx context.Context, serviceID uuid.UUID, params ListParams) ([]*Deployment, error) {
+	query := `
+		SELECT id, service_id, commit_sha, commit_message, commit_author,
+		       status, image_tag, git_tag, build_duration, deploy_duration,
+		       error_message, triggered_by, requested_by, approved_by, approved_at,
+		       started_at, finished_at, created_at
+		FROM deployments
+		WHERE service_id = $1
+	` + params.orderBy(DeploymentSortFields, 2)
+
+	rows, err := db.QueryContext(ctx, query, serviceID, params.Limit, params.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -207,10 +326,14 @@ func (db *DB) ListDeploymentsByService(ctx context.Context, serviceID uuid.UUID,
 			&commitAuthor,
 			&d.Status,
 			&imageTag,
+			&d.GitTag,
 			&buildDuration,
 			&deployDuration,
 			&errorMessage,
 			&d.TriggeredBy,
+			&d.RequestedBy,
+			&d.ApprovedBy,
+			&d.ApprovedAt,
 			&startedAt,
 			&finishedAt,
 			&d.CreatedAt,
@@ -235,12 +358,15 @@ func (db *DB) ListDeploymentsByService(ctx context.Context, serviceID uuid.UUID,
 	return deployments, rows.Err()
 }
 
-// GetSuccessfulDeploymentsByService gets successful deployments for a service (for rollback)
+// GetSuccessfulDeploymentsByService gets successful deployments for a service (for rollback),
+// newest first. Deployments still in progress, rollbacks included, are left out: the
+// first one is the deployment that is live.
 func (db *DB) GetSuccessfulDeploymentsByService(ctx context.Context, serviceID uuid.UUID, limit int) ([]*Deployment, error) {
 	query := `
 		SELECT id, service_id, commit_sha, commit_message, commit_author,
-		       status, image_tag, build_duration, deploy_duration,
-		       error_message, triggered_by, started_at, finished_at, created_at
+		       status, image_tag, git_tag, build_duration, deploy_duration,
+		       error_message, triggered_by, requested_by, approved_by, approved_at,
+		       started_at, finished_at, created_at
 		FROM deployments
 		WHERE service_id = $1 AND status = 'success' AND image_tag IS NOT NULL
 		ORDER BY created_at DESC
@@ -274,10 +400,14 @@ func (db *DB) GetSuccessfulDeploymentsByService(ctx context.Context, serviceID u
 			&commitAuthor,
 			&d.Status,
 			&imageTag,
+			&d.GitTag,
 			&buildDuration,
 			&deployDuration,
 			&errorMessage,
 			&d.TriggeredBy,
+			&d.RequestedBy,
+			&d.ApprovedBy,
+			&d.ApprovedAt,
 			&startedAt,
 			&finishedAt,
 			&d.CreatedAt,
@@ -302,15 +432,117 @@ func (db *DB) GetSuccessfulDeploymentsByService(ctx context.Context, serviceID u
 	return deployments, rows.Err()
 }
 
+// maxRollbackLineage bounds how far GetRollbackLineage follows rollbacks
+const maxRollbackLineage = 50
+
+// GetRollbackLineage returns the chain of rollbacks that led to a deployment, starting
+// with it: while a deployment is a rollback, the next one is the deployment it rolled
+// back from. The chain ends with the first deployment that isn't a rollback, or one
+// that was deleted.
+func (db *DB) GetRollbackLineage(ctx context.Context, id uuid.UUID) ([]*Deployment, error) {
+	var lineage []*Deployment
+	seen := make(map[uuid.UUID]bool)
+	for len(lineage) < maxRollbackLineage && !seen[id] {
+		seen[id] = true
+		d, err := db.GetDeployment(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if d == nil {
+			break
+		}
+		lineage = append(lineage, d)
+
+		if d.TriggeredBy != DeploymentTriggerRollback || !d.RolledBackFromID.Valid {
+			break
+		}
+		if id, err = uuid.Parse(d.RolledBackFromID.String); err != nil {
+			break
+		}
+	}
+	return lineage, nil
+}
+
+// SetDeploymentConfigSnapshot records the config a deployment rolls out
+func (db *DB) SetDeploymentConfigSnapshot(ctx context.Context, id uuid.UUID, cfg *DeploymentConfig) error {
+	snapshot, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `UPDATE deployments SET config_snapshot = $1 WHERE id = $2`, string(snapshot), id)
+	return err
+}
+
+// IsImageTagUsed reports whether another deployment of the service already built an
+// image with imageTag
+func (db *DB) IsImageTagUsed(ctx context.Context, serviceID, excludeID uuid.UUID, imageTag string) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM deployments WHERE service_id = $1 AND id != $2 AND image_tag = $3)`,
+		serviceID, excludeID, imageTag,
+	).Scan(&exists)
+	return exists, err
+}
+
 // UpdateDeploymentStatus updates the status of a deployment
 func (db *DB) UpdateDeploymentStatus(ctx context.Context, id uuid.UUID, status string) error {
+	defer db.cacheInvalidator(ctx, deploymentProjectQuery, id)()
+
 	query := `UPDATE deployments SET status = $1 WHERE id = $2`
 	_, err := db.ExecContext(ctx, query, status, id)
 	return err
 }
 
+// ApproveDeployment moves a deployment awaiting approval to status (queued, or waiting
+// when the org has no free deploy slot) and records the approver. It returns false if
+// the deployment was no longer awaiting approval, so two concurrent approvals can't
+// both start the pipeline.
+func (db *DB) ApproveDeployment(ctx context.Context, id uuid.UUID, approverID, status string) (bool, error) {
+	defer db.cacheInvalidator(ctx, deploymentProjectQuery, id)()
+
+	query := `
+		UPDATE deployments
+		SET status = $1, approved_by = $2, approved_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND status = 'awaiting_approval'
+	`
+	result, err := db.ExecContext(ctx, query, status, approverID, id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// RejectDeployment cancels a deployment awaiting approval. It returns false if the
+// deployment was no longer awaiting approval.
+func (db *DB) RejectDeployment(ctx context.Context, id uuid.UUID, reason string) (bool, error) {
+	defer db.cacheInvalidator(ctx, deploymentProjectQuery, id)()
+
+	query := `
+		UPDATE deployments
+		SET status = 'cancelled', error_message = $1, finished_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND status = 'awaiting_approval'
+	`
+	result, err := db.ExecContext(ctx, query, reason, id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
 // UpdateDeploymentProgress updates deployment progress fields
 func (db *DB) UpdateDeploymentProgress(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
+	defer db.cacheInvalidator(ctx, deploymentProjectQuery, id)()
+
 	if len(updates) == 0 {
 		return nil
 	}
@@ -392,17 +624,19 @@ func (db *DB) AddDeploymentLog(ctx context.Context, deploymentID uuid.UUID, phas
 	return err
 }
 
-// GetDeploymentLogs retrieves logs for a deployment
-func (db *DB) GetDeploymentLogs(ctx context.Context, deploymentID uuid.UUID, limit int) ([]*DeploymentLog, error) {
+// GetDeploymentLogs retrieves logs for a deployment, oldest first. Only the logs
+// with an ID above afterID are returned, so a client polling the logs can fetch
+// just the lines added since the last one it saw; 0 returns them from the start.
+func (db *DB) GetDeploymentLogs(ctx context.Context, deploymentID uuid.UUID, afterID int64, limit int) ([]*DeploymentLog, error) {
 	query := `
 		SELECT id, deployment_id, timestamp, phase, level, message, metadata
 		FROM deployment_logs
-		WHERE deployment_id = $1
-		ORDER BY timestamp ASC
-		LIMIT $2
+		WHERE deployment_id = $1 AND id > $2
+		ORDER BY id ASC
+		LIMIT $3
 	`
 
-	rows, err := db.QueryContext(ctx, query, deploymentID, limit)
+	rows, err := db.QueryContext(ctx, query, deploymentID, afterID, limit)
 	if err != nil {
 		return nil, err
 	}
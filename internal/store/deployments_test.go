@@ -0,0 +1,251 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestDB_GetRecentDeploymentForCommit(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := &Project{
+		CasdoorOrgID:      "org-a",
+		Name:              "project-a",
+		Slug:              "project-a",
+		OpenStackTenantID: "test-tenant",
+	}
+	if err := dbStore.CreateProject(ctx, project); err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+	service := &Service{
+		ProjectID:    project.ID,
+		Name:         "Test Service",
+		Type:         "app",
+		Status:       "pending",
+		InstanceSize: "medium",
+		Port:         8080,
+	}
+	if err := dbStore.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+
+	newDeployment := func(commitSHA, status string) *Deployment {
+		d := &Deployment{
+			ServiceID:   service.ID,
+			CommitSHA:   StringToNullString(commitSHA),
+			Status:      status,
+			TriggeredBy: "webhook",
+		}
+		if err := dbStore.CreateDeployment(ctx, d); err != nil {
+			t.Fatalf("Failed to create deployment: %v", err)
+		}
+		return d
+	}
+
+	building := newDeployment("aaa", "building")
+	newDeployment("bbb", "success")
+	old := newDeployment("ccc", "queued")
+	if _, err := db.ExecContext(ctx, "UPDATE deployments SET created_at = $1 WHERE id = $2", time.Now().Add(-time.Hour).UTC(), old.ID); err != nil {
+		t.Fatalf("Failed to set created_at: %v", err)
+	}
+
+	tests := []struct {
+		commitSHA string
+		want      *Deployment
+	}{
+		{"aaa", building},
+		{"bbb", nil}, // finished
+		{"ccc", nil}, // older than the window
+		{"ddd", nil}, // never deployed
+	}
+	for _, tt := range tests {
+		got, err := dbStore.GetRecentDeploymentForCommit(ctx, service.ID, tt.commitSHA, 10*time.Minute)
+		if err != nil {
+			t.Fatalf("GetRecentDeploymentForCommit(%s) error: %v", tt.commitSHA, err)
+		}
+		switch {
+		case tt.want == nil && got != nil:
+			t.Errorf("GetRecentDeploymentForCommit(%s) = %s, want none", tt.commitSHA, got.ID)
+		case tt.want != nil && (got == nil || got.ID != tt.want.ID):
+			t.Errorf("GetRecentDeploymentForCommit(%s) = %v, want %s", tt.commitSHA, got, tt.want.ID)
+		}
+	}
+}
+
+func TestDB_ActiveDeploymentsByService(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := &Project{
+		CasdoorOrgID:      "org-a",
+		Name:              "project-a",
+		Slug:              "project-a",
+		OpenStackTenantID: "test-tenant",
+	}
+	if err := dbStore.CreateProject(ctx, project); err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+	newService := func(name string) *Service {
+		service := &Service{
+			ProjectID:    project.ID,
+			Name:         name,
+			Type:         "app",
+			Status:       "pending",
+			InstanceSize: "medium",
+			Port:         8080,
+		}
+		if err := dbStore.CreateService(ctx, service); err != nil {
+			t.Fatalf("Failed to create test service: %v", err)
+		}
+		return service
+	}
+	deploying := newService("deploying")
+	idle := newService("idle")
+
+	newDeployment := func(service *Service, status string) *Deployment {
+		d := &Deployment{ServiceID: service.ID, Status: status, TriggeredBy: "manual"}
+		if err := dbStore.CreateDeployment(ctx, d); err != nil {
+			t.Fatalf("Failed to create deployment: %v", err)
+		}
+		return d
+	}
+	newDeployment(deploying, "success")
+	building := newDeployment(deploying, "building")
+	newDeployment(idle, "failed")
+
+	active, err := dbStore.ActiveDeploymentsByService(ctx, []uuid.UUID{deploying.ID, idle.ID})
+	if err != nil {
+		t.Fatalf("ActiveDeploymentsByService error: %v", err)
+	}
+	if len(active) != 1 || active[deploying.ID] != building.ID {
+		t.Errorf("ActiveDeploymentsByService = %v, want only %s -> %s", active, deploying.ID, building.ID)
+	}
+}
+
+func TestDB_DeploymentStatusInvalidatesProjectCache(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := &Project{
+		CasdoorOrgID:      "org-a",
+		Name:              "project-a",
+		Slug:              "project-a",
+		OpenStackTenantID: "test-tenant",
+	}
+	if err := dbStore.CreateProject(ctx, project); err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+	service := &Service{
+		ProjectID:    project.ID,
+		Name:         "Test Service",
+		Type:         "app",
+		Status:       "pending",
+		InstanceSize: "medium",
+		Port:         8080,
+	}
+	if err := dbStore.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+	deployment := &Deployment{ServiceID: service.ID, Status: "queued", TriggeredBy: "manual"}
+	if err := dbStore.CreateDeployment(ctx, deployment); err != nil {
+		t.Fatalf("Failed to create deployment: %v", err)
+	}
+
+	before := ProjectCacheVersion(project.ID)
+	if err := dbStore.UpdateDeploymentStatus(ctx, deployment.ID, "building"); err != nil {
+		t.Fatalf("UpdateDeploymentStatus error: %v", err)
+	}
+	if after := ProjectCacheVersion(project.ID); after <= before {
+		t.Errorf("ProjectCacheVersion after a status update = %d, want more than %d", after, before)
+	}
+}
+
+func TestDB_GetRollbackLineage(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	service := testutil.NewService(t, db, project.ID)
+
+	deploy := func(status string, from, target *Deployment) *Deployment {
+		t.Helper()
+		d := &Deployment{ServiceID: service.ID, Status: status, TriggeredBy: "manual", ImageTag: StringToNullString("registry/app:" + status)}
+		if target != nil {
+			d.TriggeredBy = DeploymentTriggerRollback
+			d.ImageTag = target.ImageTag
+			d.RolledBackFromID = StringToNullString(from.ID.String())
+			d.RollbackTargetID = StringToNullString(target.ID.String())
+		}
+		if err := dbStore.CreateDeployment(ctx, d); err != nil {
+			t.Fatalf("Failed to create deployment: %v", err)
+		}
+		return d
+	}
+
+	first := deploy("success", nil, nil)
+	second := deploy("success", nil, nil)
+	rollback := deploy("success", second, first)        // Back to the first deployment
+	inProgress := deploy("deploying", rollback, second) // Then back to the second
+
+	lineage, err := dbStore.GetRollbackLineage(ctx, inProgress.ID)
+	if err != nil {
+		t.Fatalf("Failed to get rollback lineage: %v", err)
+	}
+	want := []uuid.UUID{inProgress.ID, rollback.ID, second.ID}
+	if len(lineage) != len(want) {
+		t.Fatalf("Expected a lineage of %d deployments, got %d", len(want), len(lineage))
+	}
+	for i, d := range lineage {
+		if d.ID != want[i] {
+			t.Errorf("lineage[%d] = %s, want %s", i, d.ID, want[i])
+		}
+	}
+	if lineage[1].RollbackTargetID.String != first.ID.String() {
+		t.Errorf("Expected the rollback to target the first deployment, got %q", lineage[1].RollbackTargetID.String)
+	}
+
+	// A deployment that isn't a rollback is its own lineage
+	lineage, err = dbStore.GetRollbackLineage(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("Failed to get rollback lineage: %v", err)
+	}
+	if len(lineage) != 1 || lineage[0].ID != first.ID {
+		t.Errorf("Expected only the deployment itself, got %d deployments", len(lineage))
+	}
+
+	// The rollback in progress isn't a successful deployment yet
+	successful, err := dbStore.GetSuccessfulDeploymentsByService(ctx, service.ID, 10)
+	if err != nil {
+		t.Fatalf("Failed to get successful deployments: %v", err)
+	}
+	if len(successful) != 3 {
+		t.Errorf("Expected 3 successful deployments, got %d", len(successful))
+	}
+	for _, d := range successful {
+		if d.ID == inProgress.ID {
+			t.Error("Expected the rollback in progress to be left out")
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// envVarNamePattern matches the names ${...} references may use
+var envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// InterpolateEnvVars expands ${VAR} references in the values of envVars against the
+// values of the others, as resolved into values, and returns the expanded values by
+// key. References are expanded recursively; a reference cycle or one to a var that
+// isn't set fails. $$ is a literal $, and a $ followed by anything else than { or $
+// is kept as is. Only vars that opted into interpolation are expanded; the others,
+// secrets and linked vars included, are used as is but can be referenced.
+func InterpolateEnvVars(envVars []*ResolvedEnvVar, values map[string]string) (map[string]string, error) {
+	expanded, _, err := interpolateEnvVars(envVars, values, nil)
+	return expanded, err
+}
+
+// interpolateEnvVars is InterpolateEnvVars with the values of the unknown vars not
+// resolved yet. It also returns the vars whose expanded value is unknown: those and
+// the ones referencing them, directly or not.
+func interpolateEnvVars(envVars []*ResolvedEnvVar, values map[string]string, unknown map[string]bool) (map[string]string, map[string]bool, error) {
+	literal := make(map[string]bool, len(envVars))
+	for _, ev := range envVars {
+		literal[ev.Key] = ev.Literal
+	}
+
+	in := &envInterpolation{
+		values:   values,
+		literal:  literal,
+		expanded: make(map[string]string, len(values)),
+		unknown:  make(map[string]bool, len(unknown)),
+		visiting: make(map[string]bool),
+	}
+	for key := range unknown {
+		in.expanded[key] = ""
+		in.unknown[key] = true
+	}
+	for key := range values {
+		if _, err := in.expand(key); err != nil {
+			return nil, nil, err
+		}
+	}
+	return in.expanded, in.unknown, nil
+}
+
+// envInterpolation is the state of one InterpolateEnvVars
+type envInterpolation struct {
+	values   map[string]string
+	literal  map[string]bool
+	expanded map[string]string
+	unknown  map[string]bool // Vars whose value is unknown, or references one that is
+	visiting map[string]bool
+	path     []string // Vars being expanded, outermost first, to report cycles
+}
+
+// expand returns the expanded value of key, expanding the vars it references first
+func (in *envInterpolation) expand(key string) (string, error) {
+	if value, ok := in.expanded[key]; ok {
+		return value, nil
+	}
+	if in.literal[key] {
+		in.expanded[key] = in.values[key]
+		return in.values[key], nil
+	}
+	if in.visiting[key] {
+		return "", fmt.Errorf("env var reference cycle: %s -> %s", strings.Join(in.path[slices.Index(in.path, key):], " -> "), key)
+	}
+
+	in.visiting[key] = true
+	in.path = append(in.path, key)
+	defer func() {
+		delete(in.visiting, key)
+		in.path = in.path[:len(in.path)-1]
+	}()
+
+	value := in.values[key]
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i == len(value)-1 {
+			b.WriteByte(value[i])
+			continue
+		}
+
+		switch value[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(value[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("env var %s: unterminated ${ reference", key)
+			}
+			name := value[i+2 : i+2+end]
+			if !envVarNamePattern.MatchString(name) {
+				return "", fmt.Errorf("env var %s: invalid reference ${%s}", key, name)
+			}
+			if _, ok := in.values[name]; !ok && !in.unknown[name] {
+				return "", fmt.Errorf("env var %s: references undefined variable ${%s}", key, name)
+			}
+			ref, err := in.expand(name)
+			if err != nil {
+				return "", err
+			}
+			if in.unknown[name] {
+				in.unknown[key] = true
+			}
+			b.WriteString(ref)
+			i += end + 2
+		default:
+			b.WriteByte('$')
+		}
+	}
+
+	in.expanded[key] = b.String()
+	return in.expanded[key], nil
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInterpolateEnvVars(t *testing.T) {
+	envVars := []*ResolvedEnvVar{
+		{Key: "DB_HOST", Value: "db.internal", Literal: true},
+		{Key: "DB_PORT", Value: "5432", Literal: true},
+		{Key: "DB_PASSWORD", Value: "p${ss", Literal: true},
+		{Key: "DATABASE_URL", Value: "postgres://${DB_HOST}:${DB_PORT}/app"},
+		{Key: "READ_URL", Value: "${DATABASE_URL}?replica=1"},
+		{Key: "PRICE", Value: "$$5 or $5"},
+		{Key: "PASSWORD_COPY", Value: "${DB_PASSWORD}"},
+	}
+	values := make(map[string]string)
+	for _, ev := range envVars {
+		values[ev.Key] = ev.Value
+	}
+
+	got, err := InterpolateEnvVars(envVars, values)
+	if err != nil {
+		t.Fatalf("InterpolateEnvVars error: %v", err)
+	}
+	want := map[string]string{
+		"DATABASE_URL":  "postgres://db.internal:5432/app",
+		"READ_URL":      "postgres://db.internal:5432/app?replica=1",
+		"PRICE":         "$5 or $5",
+		"DB_PASSWORD":   "p${ss",
+		"PASSWORD_COPY": "p${ss",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %q, want %q", key, got[key], value)
+		}
+	}
+}
+
+func TestInterpolateEnvVars_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]string
+		wantErr string
+	}{
+		{"undefined", map[string]string{"A": "${MISSING}"}, "undefined variable ${MISSING}"},
+		{"cycle", map[string]string{"A": "${B}", "B": "${C}", "C": "${A}"}, "cycle"},
+		{"self reference", map[string]string{"A": "x${A}"}, "cycle: A -> A"},
+		{"unterminated", map[string]string{"A": "${B"}, "unterminated"},
+		{"invalid name", map[string]string{"A": "${B-C}"}, "invalid reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := InterpolateEnvVars(nil, tt.values)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("InterpolateEnvVars error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Env schema value types
+const (
+	EnvTypeString = "string"
+	EnvTypeInt    = "int"
+	EnvTypeBool   = "bool"
+	EnvTypeURL    = "url"
+)
+
+// EnvSchemaTypes lists the value types an env schema entry can declare
+var EnvSchemaTypes = []string{EnvTypeString, EnvTypeInt, EnvTypeBool, EnvTypeURL}
+
+// EnvSchemaEntry declares what a service expects of one environment variable
+type EnvSchemaEntry struct {
+	ID        uuid.UUID
+	ServiceID uuid.UUID
+	Key       string
+	Required  bool
+	Type      string         // string, int, bool, url
+	Pattern   sql.NullString // Optional regex the value must match
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// EnvSchemaViolation is a resolved env var that doesn't satisfy the schema
+type EnvSchemaViolation struct {
+	Key     string
+	Message string
+}
+
+// CreateEnvSchemaEntry creates a new env schema entry
+func (db *DB) CreateEnvSchemaEntry(ctx context.Context, e *EnvSchemaEntry) error {
+	// Generate UUID if not set (for SQLite compatibility)
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+
+	// Check if we're using SQLite (for compatibility)
+	var isSQLite bool
+	var versionStr string
+	err := db.QueryRow("SELECT sqlite_version()").Scan(&versionStr)
+	isSQLite = err == nil
+
+	var pattern interface{}
+	if e.Pattern.Valid {
+		pattern = e.Pattern.String
+	}
+
+	if isSQLite {
+		// SQLite: Insert with explicit UUID (no RETURNING support in older versions)
+		required := 0
+		if e.Required {
+			required = 1
+		}
+		query := `
+			INSERT INTO env_schema (id, service_id, key, required, type, pattern)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`
+		_, err = db.ExecContext(ctx, query,
+			e.ID.String(), e.ServiceID.String(), e.Key, required, e.Type, pattern,
+		)
+		if err != nil {
+			return err
+		}
+		// Get timestamps
+		err = db.QueryRowContext(ctx, "SELECT created_at, updated_at FROM env_schema WHERE id = $1", e.ID.String()).
+			Scan(&e.CreatedAt, &e.UpdatedAt)
+		return err
+	}
+
+	// PostgreSQL: Use RETURNING clause
+	query := `
+		INSERT INTO env_schema (service_id, key, required, type, pattern)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+
+	return db.QueryRowContext(ctx, query,
+		e.ServiceID,
+		e.Key,
+		e.Required,
+		e.Type,
+		pattern,
+	).Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt)
+}
+
+// GetEnvSchemaEntryByKey retrieves a service's env schema entry by key
+func (db *DB) GetEnvSchemaEntryByKey(ctx context.Context, serviceID uuid.UUID, key string) (*EnvSchemaEntry, error) {
+	query := `
+		SELECT id, service_id, key, required, type, pattern, created_at, updated_at
+		FROM env_schema
+		WHERE service_id = $1 AND key = $2
+	`
+
+	var e EnvSchemaEntry
+	err := db.QueryRowContext(ctx, query, serviceID, key).Scan(
+		&e.ID,
+		&e.ServiceID,
+		&e.Key,
+		&e.Required,
+		&e.Type,
+		&e.Pattern,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// ListEnvSchemaByService lists a service's env schema entries
+func (db *DB) ListEnvSchemaByService(ctx context.Context, serviceID uuid.UUID) ([]*EnvSchemaEntry, error) {
+	query := `
+		SELECT id, service_id, key, required, type, pattern, created_at, updated_at
+		FROM env_schema
+		WHERE service_id = $1
+		ORDER BY key ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*EnvSchemaEntry
+	for rows.Next() {
+		var e EnvSchemaEntry
+		err := rows.Scan(
+			&e.ID,
+			&e.ServiceID,
+			&e.Key,
+			&e.Required,
+			&e.Type,
+			&e.Pattern,
+			&e.CreatedAt,
+			&e.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+
+	return entries, rows.Err()
+}
+
+// UpdateEnvSchemaEntry updates an env schema entry
+func (db *DB) UpdateEnvSchemaEntry(ctx context.Context, id uuid.UUID, e *EnvSchemaEntry) error {
+	query := `
+		UPDATE env_schema
+		SET required = $1, type = $2, pattern = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+	`
+
+	var pattern interface{}
+	if e.Pattern.Valid {
+		pattern = e.Pattern.String
+	}
+
+	result, err := db.ExecContext(ctx, query, e.Required, e.Type, pattern, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteEnvSchemaEntry deletes an env schema entry
+func (db *DB) DeleteEnvSchemaEntry(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM env_schema WHERE id = $1`
+
+	result, err := db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ValidateServiceEnv checks a service's resolved environment against its env schema
+func (db *DB) ValidateServiceEnv(ctx context.Context, serviceID uuid.UUID) ([]EnvSchemaViolation, error) {
+	schema, err := db.ListEnvSchemaByService(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(schema) == 0 {
+		return nil, nil
+	}
+
+	envVars, err := db.ResolveEnvVarsWithSource(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(envVars))
+	external := make(map[string]bool)
+	for _, ev := range envVars {
+		if ev.SecretRef != "" || ev.ServiceLink != nil {
+			external[ev.Key] = true
+			continue
+		}
+		values[ev.Key] = ev.Value
+	}
+
+	// Values are checked as the app sees them, with their ${VAR} references expanded
+	env, unknown, err := interpolateEnvVars(envVars, values, external)
+	if err != nil {
+		return []EnvSchemaViolation{{Message: err.Error()}}, nil
+	}
+
+	// Vars read from a secret manager or linked to a service count as set; their values,
+	// and those of vars referencing them, can't be checked before they're resolved at
+	// deploy time
+	checked := make([]*EnvSchemaEntry, 0, len(schema))
+	for _, entry := range schema {
+		if !unknown[entry.Key] {
+			checked = append(checked, entry)
+		}
+	}
+
+	return CheckEnvSchema(checked, env), nil
+}
+
+// CheckEnvSchema validates an env set against schema entries, in schema order.
+// An empty value counts as missing; type and pattern checks only apply to set values.
+func CheckEnvSchema(schema []*EnvSchemaEntry, env map[string]string) []EnvSchemaViolation {
+	var violations []EnvSchemaViolation
+	for _, entry := range schema {
+		value := env[entry.Key]
+		if value == "" {
+			if entry.Required {
+				violations = append(violations, EnvSchemaViolation{
+					Key:     entry.Key,
+					Message: fmt.Sprintf("missing required env var %s", entry.Key),
+				})
+			}
+			continue
+		}
+
+		if msg := checkEnvValue(entry, value); msg != "" {
+			violations = append(violations, EnvSchemaViolation{
+				Key:     entry.Key,
+				Message: fmt.Sprintf("env var %s %s", entry.Key, msg),
+			})
+		}
+	}
+
+	return violations
+}
+
+// checkEnvValue returns why value doesn't satisfy entry, or "" if it does
+func checkEnvValue(entry *EnvSchemaEntry, value string) string {
+	switch entry.Type {
+	case EnvTypeInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "must be an integer"
+		}
+	case EnvTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "must be a boolean"
+		}
+	case EnvTypeURL:
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be a valid URL"
+		}
+	}
+
+	if entry.Pattern.Valid && entry.Pattern.String != "" {
+		re, err := regexp.Compile(entry.Pattern.String)
+		if err != nil {
+			return "has an invalid pattern in the env schema"
+		}
+		if !re.MatchString(value) {
+			return fmt.Sprintf("must match pattern %s", entry.Pattern.String)
+		}
+	}
+
+	return ""
+}
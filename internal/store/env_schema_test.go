@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestCheckEnvSchema(t *testing.T) {
+	schema := []*EnvSchemaEntry{
+		{Key: "DATABASE_URL", Required: true, Type: EnvTypeURL},
+		{Key: "DEBUG", Type: EnvTypeBool},
+		{Key: "PORT", Required: true, Type: EnvTypeInt},
+		{Key: "REGION", Type: EnvTypeString, Pattern: sql.NullString{String: `^[a-z]+-[0-9]$`, Valid: true}},
+	}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "valid",
+			env:  map[string]string{"DATABASE_URL": "postgres://db:5432/app", "PORT": "8080", "REGION": "eu-1"},
+		},
+		{
+			name: "missing required",
+			env:  map[string]string{"DATABASE_URL": "postgres://db:5432/app", "PORT": ""},
+			want: []string{"missing required env var PORT"},
+		},
+		{
+			name: "wrong types and pattern",
+			env:  map[string]string{"DATABASE_URL": "not a url", "DEBUG": "maybe", "PORT": "80a", "REGION": "EU"},
+			want: []string{
+				"env var DATABASE_URL must be a valid URL",
+				"env var DEBUG must be a boolean",
+				"env var PORT must be an integer",
+				"env var REGION must match pattern ^[a-z]+-[0-9]$",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := CheckEnvSchema(schema, tt.env)
+			if len(violations) != len(tt.want) {
+				t.Fatalf("got %d violations %+v, want %d", len(violations), violations, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if violations[i].Message != want {
+					t.Errorf("violation %d = %q, want %q", i, violations[i].Message, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDB_ValidateServiceEnv(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := &Project{
+		CasdoorOrgID:      "test-org",
+		Name:              "Test Project",
+		Slug:              "test-project",
+		OpenStackTenantID: "test-tenant",
+	}
+	if err := dbStore.CreateProject(ctx, project); err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+
+	service := &Service{
+		ProjectID:    project.ID,
+		Name:         "Test Service",
+		Type:         "app",
+		Status:       "pending",
+		InstanceSize: "medium",
+		Port:         8080,
+	}
+	if err := dbStore.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+
+	for _, e := range []*EnvSchemaEntry{
+		{ServiceID: service.ID, Key: "API_KEY", Required: true, Type: EnvTypeString},
+		{ServiceID: service.ID, Key: "WORKERS", Type: EnvTypeInt},
+	} {
+		if err := dbStore.CreateEnvSchemaEntry(ctx, e); err != nil {
+			t.Fatalf("Failed to create env schema entry: %v", err)
+		}
+	}
+
+	// Project-level values count towards the resolved env
+	if err := dbStore.CreateProjectEnvVar(ctx, &ProjectEnvVar{
+		ProjectID: project.ID, Key: "WORKERS", Value: sql.NullString{String: "four", Valid: true},
+	}); err != nil {
+		t.Fatalf("Failed to create project env var: %v", err)
+	}
+
+	violations, err := dbStore.ValidateServiceEnv(ctx, service.ID)
+	if err != nil {
+		t.Fatalf("Failed to validate env: %v", err)
+	}
+	if len(violations) != 2 || violations[0].Key != "API_KEY" || violations[1].Key != "WORKERS" {
+		t.Fatalf("Expected violations for API_KEY and WORKERS, got %+v", violations)
+	}
+
+	if err := dbStore.CreateEnvVar(ctx, &EnvVar{
+		ServiceID: service.ID, Key: "API_KEY", Value: sql.NullString{String: "secret", Valid: true}, IsSecret: true,
+	}); err != nil {
+		t.Fatalf("Failed to create env var: %v", err)
+	}
+	if err := dbStore.CreateEnvVar(ctx, &EnvVar{
+		ServiceID: service.ID, Key: "WORKERS", Value: sql.NullString{String: "4", Valid: true},
+	}); err != nil {
+		t.Fatalf("Failed to create env var: %v", err)
+	}
+
+	violations, err = dbStore.ValidateServiceEnv(ctx, service.ID)
+	if err != nil {
+		t.Fatalf("Failed to validate env: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations, got %+v", violations)
+	}
+}
+
+func TestDB_ValidateServiceEnv_Interpolation(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	service := testutil.NewService(t, db, project.ID)
+
+	for _, e := range []*EnvSchemaEntry{
+		{ServiceID: service.ID, Key: "WORKERS", Type: EnvTypeInt},
+		{ServiceID: service.ID, Key: "API_URL", Type: EnvTypeURL},
+		{ServiceID: service.ID, Key: "TOKEN_URL", Type: EnvTypeURL},
+		{ServiceID: service.ID, Key: "RETRIES", Type: EnvTypeInt},
+	} {
+		if err := dbStore.CreateEnvSchemaEntry(ctx, e); err != nil {
+			t.Fatalf("Failed to create env schema entry: %v", err)
+		}
+	}
+
+	for _, ev := range []*EnvVar{
+		{Key: "BASE_WORKERS", Value: sql.NullString{String: "4", Valid: true}},
+		{Key: "WORKERS", Value: sql.NullString{String: "${BASE_WORKERS}", Valid: true}, Interpolate: true},
+		{Key: "API_HOST", Value: sql.NullString{String: "api.example.com", Valid: true}},
+		{Key: "API_URL", Value: sql.NullString{String: "https://${API_HOST}/v1", Valid: true}, Interpolate: true},
+		// Only known at deploy time, so neither it nor vars referencing it can be checked
+		{Key: "TOKEN_HOST", IsSecret: true, SecretRef: sql.NullString{String: `{"path":"secret/data/app","key":"host"}`, Valid: true}},
+		{Key: "TOKEN_URL", Value: sql.NullString{String: "${TOKEN_HOST}", Valid: true}, Interpolate: true},
+		{Key: "NAME", Value: sql.NullString{String: "three", Valid: true}},
+		{Key: "RETRIES", Value: sql.NullString{String: "${NAME}", Valid: true}, Interpolate: true},
+	} {
+		ev.ServiceID = service.ID
+		if err := dbStore.CreateEnvVar(ctx, ev); err != nil {
+			t.Fatalf("Failed to create env var %s: %v", ev.Key, err)
+		}
+	}
+
+	violations, err := dbStore.ValidateServiceEnv(ctx, service.ID)
+	if err != nil {
+		t.Fatalf("Failed to validate env: %v", err)
+	}
+	if len(violations) != 1 || violations[0].Key != "RETRIES" {
+		t.Errorf("Expected only RETRIES to violate the schema once expanded, got %+v", violations)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,23 +17,36 @@ type EnvVar struct {
 	Value           sql.NullString // NULL if linked to database
 	IsSecret        bool
 	LinkedDatabaseID sql.NullString
-	LinkType        sql.NullString // connection_url, host, port, username, password, database
+	LinkType        sql.NullString // connection_url, host, port, username, password, database; service_url, service_host
+	ProjectEnvVarID sql.NullString // Set when the value comes from a shared project variable
+	SecretRef       sql.NullString // JSON reference to an external secret, resolved at deploy time
+	LinkedServiceID sql.NullString // Another service of the project, its address is resolved at deploy time
+	Interpolate     bool           // ${VAR} references in Value are expanded at deploy time; never for secrets
 	CreatedAt       time.Time
 }
 
+// Link types of env vars linked to another service
+const (
+	EnvLinkServiceURL  = "service_url"  // http://<host>:<port>
+	EnvLinkServiceHost = "service_host" // In-cluster DNS name
+)
+
 // CreateEnvVar creates a new environment variable
 func (db *DB) CreateEnvVar(ctx context.Context, ev *EnvVar) error {
+	// Check if we're using SQLite (for compatibility)
+	var versionStr string
+	isSQLite := db.QueryRow("SELECT sqlite_version()").Scan(&versionStr) == nil
+
+	return insertEnvVar(ctx, db, isSQLite, ev)
+}
+
+// insertEnvVar inserts an environment variable with q, the database or a transaction
+func insertEnvVar(ctx context.Context, q execer, isSQLite bool, ev *EnvVar) error {
 	// Generate UUID if not set (for SQLite compatibility)
 	if ev.ID == uuid.Nil {
 		ev.ID = uuid.New()
 	}
 
-	// Check if we're using SQLite (for compatibility)
-	var isSQLite bool
-	var versionStr string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&versionStr)
-	isSQLite = err == nil
-
 	var value interface{}
 	if ev.Value.Valid {
 		value = ev.Value.String
@@ -48,52 +62,73 @@ func (db *DB) CreateEnvVar(ctx context.Context, ev *EnvVar) error {
 		linkType = ev.LinkType.String
 	}
 
+	var projectEnvVarID interface{}
+	if ev.ProjectEnvVarID.Valid {
+		projectEnvVarID = ev.ProjectEnvVarID.String
+	}
+
+	var secretRef interface{}
+	if ev.SecretRef.Valid {
+		secretRef = ev.SecretRef.String
+	}
+
+	var linkedServiceID interface{}
+	if ev.LinkedServiceID.Valid {
+		linkedServiceID = ev.LinkedServiceID.String
+	}
+
 	if isSQLite {
 		// SQLite: Insert with explicit UUID (no RETURNING support in older versions)
 		isSecret := 0
 		if ev.IsSecret {
 			isSecret = 1
 		}
+		interpolate := 0
+		if ev.Interpolate {
+			interpolate = 1
+		}
 		query := `
-			INSERT INTO env_vars (id, service_id, key, value, is_secret, linked_database_id, link_type)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			INSERT INTO env_vars (id, service_id, key, value, is_secret, linked_database_id, link_type, project_env_var_id, secret_ref, linked_service_id, interpolate)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		`
-		_, err = db.ExecContext(ctx, query,
-			ev.ID.String(), ev.ServiceID.String(), ev.Key, value, isSecret, linkedDatabaseID, linkType,
+		_, err := q.ExecContext(ctx, query,
+			ev.ID.String(), ev.ServiceID.String(), ev.Key, value, isSecret, linkedDatabaseID, linkType, projectEnvVarID, secretRef, linkedServiceID, interpolate,
 		)
 		if err != nil {
 			return err
 		}
 		// Get timestamp
-		err = db.QueryRowContext(ctx, "SELECT created_at FROM env_vars WHERE id = $1", ev.ID.String()).
+		err = q.QueryRowContext(ctx, "SELECT created_at FROM env_vars WHERE id = $1", ev.ID.String()).
 			Scan(&ev.CreatedAt)
 		return err
 	}
 
 	// PostgreSQL: Use RETURNING clause
 	query := `
-		INSERT INTO env_vars (service_id, key, value, is_secret, linked_database_id, link_type)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO env_vars (service_id, key, value, is_secret, linked_database_id, link_type, project_env_var_id, secret_ref, linked_service_id, interpolate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, created_at
 	`
 
-	err = db.QueryRowContext(ctx, query,
+	return q.QueryRowContext(ctx, query,
 		ev.ServiceID,
 		ev.Key,
 		value,
 		ev.IsSecret,
 		linkedDatabaseID,
 		linkType,
+		projectEnvVarID,
+		secretRef,
+		linkedServiceID,
+		ev.Interpolate,
 	).Scan(&ev.ID, &ev.CreatedAt)
-
-	return err
 }
 
 // GetEnvVar retrieves an environment variable by ID
 func (db *DB) GetEnvVar(ctx context.Context, id uuid.UUID) (*EnvVar, error) {
 	query := `
 		SELECT id, service_id, key, value, is_secret,
-		       linked_database_id, link_type, created_at
+		       linked_database_id, link_type, project_env_var_id, secret_ref, linked_service_id, interpolate, created_at
 		FROM env_vars
 		WHERE id = $1
 	`
@@ -111,6 +146,10 @@ func (db *DB) GetEnvVar(ctx context.Context, id uuid.UUID) (*EnvVar, error) {
 		&ev.IsSecret,
 		&linkedDatabaseID,
 		&linkType,
+		&ev.ProjectEnvVarID,
+		&ev.SecretRef,
+		&ev.LinkedServiceID,
+		&ev.Interpolate,
 		&ev.CreatedAt,
 	)
 
@@ -133,7 +172,7 @@ func (db *DB) GetEnvVar(ctx context.Context, id uuid.UUID) (*EnvVar, error) {
 func (db *DB) ListEnvVarsByService(ctx context.Context, serviceID uuid.UUID) ([]*EnvVar, error) {
 	query := `
 		SELECT id, service_id, key, value, is_secret,
-		       linked_database_id, link_type, created_at
+		       linked_database_id, link_type, project_env_var_id, secret_ref, linked_service_id, interpolate, created_at
 		FROM env_vars
 		WHERE service_id = $1
 		ORDER BY key ASC
@@ -160,6 +199,10 @@ func (db *DB) ListEnvVarsByService(ctx context.Context, serviceID uuid.UUID) ([]
 			&ev.IsSecret,
 			&linkedDatabaseID,
 			&linkType,
+			&ev.ProjectEnvVarID,
+			&ev.SecretRef,
+			&ev.LinkedServiceID,
+			&ev.Interpolate,
 			&ev.CreatedAt,
 		)
 		if err != nil {
@@ -180,8 +223,8 @@ func (db *DB) ListEnvVarsByService(ctx context.Context, serviceID uuid.UUID) ([]
 func (db *DB) UpdateEnvVar(ctx context.Context, id uuid.UUID, ev *EnvVar) error {
 	query := `
 		UPDATE env_vars
-		SET value = $1, is_secret = $2, linked_database_id = $3, link_type = $4
-		WHERE id = $5
+		SET value = $1, is_secret = $2, linked_database_id = $3, link_type = $4, project_env_var_id = $5, secret_ref = $6, linked_service_id = $7, interpolate = $8
+		WHERE id = $9
 	`
 
 	var value interface{}
@@ -199,11 +242,30 @@ func (db *DB) UpdateEnvVar(ctx context.Context, id uuid.UUID, ev *EnvVar) error
 		linkType = ev.LinkType.String
 	}
 
+	var projectEnvVarID interface{}
+	if ev.ProjectEnvVarID.Valid {
+		projectEnvVarID = ev.ProjectEnvVarID.String
+	}
+
+	var secretRef interface{}
+	if ev.SecretRef.Valid {
+		secretRef = ev.SecretRef.String
+	}
+
+	var linkedServiceID interface{}
+	if ev.LinkedServiceID.Valid {
+		linkedServiceID = ev.LinkedServiceID.String
+	}
+
 	_, err := db.ExecContext(ctx, query,
 		value,
 		ev.IsSecret,
 		linkedDatabaseID,
 		linkType,
+		projectEnvVarID,
+		secretRef,
+		linkedServiceID,
+		ev.Interpolate,
 		id,
 	)
 
@@ -231,61 +293,320 @@ func (db *DB) DeleteEnvVar(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// CopyEnvVars copies envVars, read from another service, to the service targetID in
+// one transaction: either all of them are copied or none is. A var whose key the
+// target already has replaces it when overwrite is set and is skipped otherwise.
+// It returns the keys copied and the keys skipped.
+func (db *DB) CopyEnvVars(ctx context.Context, targetID uuid.UUID, envVars []*EnvVar, overwrite bool) (copied, skipped []string, err error) {
+	var versionStr string
+	isSQLite := db.QueryRow("SELECT sqlite_version()").Scan(&versionStr) == nil
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	for _, src := range envVars {
+		ev := &EnvVar{
+			ServiceID:        targetID,
+			Key:              src.Key,
+			Value:            src.Value,
+			IsSecret:         src.IsSecret,
+			LinkedDatabaseID: src.LinkedDatabaseID,
+			LinkType:         src.LinkType,
+			ProjectEnvVarID:  src.ProjectEnvVarID,
+			SecretRef:        src.SecretRef,
+			LinkedServiceID:  src.LinkedServiceID,
+			Interpolate:      src.Interpolate,
+		}
+
+		var existingID string
+		err := tx.QueryRowContext(ctx, "SELECT id FROM env_vars WHERE service_id = $1 AND key = $2", targetID.String(), ev.Key).
+			Scan(&existingID)
+		switch {
+		case err == sql.ErrNoRows:
+			if err := insertEnvVar(ctx, tx, isSQLite, ev); err != nil {
+				return nil, nil, fmt.Errorf("failed to copy env var %s: %w", ev.Key, err)
+			}
+		case err != nil:
+			return nil, nil, err
+		case !overwrite:
+			skipped = append(skipped, ev.Key)
+			continue
+		default:
+			query := `
+				UPDATE env_vars
+				SET value = $1, is_secret = $2, linked_database_id = $3, link_type = $4, project_env_var_id = $5, secret_ref = $6, linked_service_id = $7, interpolate = $8
+				WHERE id = $9
+			`
+			_, err := tx.ExecContext(ctx, query,
+				ev.Value,
+				ev.IsSecret,
+				ev.LinkedDatabaseID,
+				ev.LinkType,
+				ev.ProjectEnvVarID,
+				ev.SecretRef,
+				ev.LinkedServiceID,
+				ev.Interpolate,
+				existingID,
+			)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to copy env var %s: %w", ev.Key, err)
+			}
+		}
+		copied = append(copied, ev.Key)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return copied, skipped, nil
+}
+
+// Env var sources, in increasing order of precedence
+const (
+	EnvVarSourceProject = "project"
+	EnvVarSourceService = "service"
+)
+
+// ResolvedEnvVar is an environment variable as it will be injected into a deployment
+type ResolvedEnvVar struct {
+	Key      string
+	Value    string
+	IsSecret bool
+	Source   string // EnvVarSourceProject or EnvVarSourceService
+	// Overrides is true when a service-level var replaced a project-level var of the same key
+	Overrides bool
+	// SecretRef is the JSON reference of a var read from an external secret manager. Its
+	// Value is empty until the deploy worker resolves it.
+	SecretRef string
+	// Literal is true for values that are used as is rather than interpolated: all but
+	// the plain, non-secret values of vars that opted into interpolation
+	Literal bool
+	// ServiceLink is set for a var linked to another service. Its Value is empty until
+	// the deploy worker resolves the service's address.
+	ServiceLink *ServiceLink
+}
+
+// ServiceLink is the service an env var is linked to
+type ServiceLink struct {
+	ServiceID uuid.UUID
+	ProjectID uuid.UUID
+	Port      int
+	LinkType  string // EnvLinkServiceURL or EnvLinkServiceHost
+}
+
+// Value returns the value of the link for the service reachable at host
+func (l *ServiceLink) Value(host string) string {
+	if l.LinkType == EnvLinkServiceURL {
+		return fmt.Sprintf("http://%s:%d", host, l.Port)
+	}
+	return host
+}
+
 // ResolveEnvVars resolves environment variables for a service
-// This includes resolving linked database values
+// This includes resolving linked database values and project-level defaults.
+// Vars referencing an external secret or linked to another service are left out,
+// their values are only known at deploy time.
 func (db *DB) ResolveEnvVars(ctx context.Context, serviceID uuid.UUID) (map[string]string, error) {
+	envVars, err := db.ResolveEnvVarsWithSource(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make(map[string]string, len(envVars))
+	for _, ev := range envVars {
+		if ev.SecretRef != "" || ev.ServiceLink != nil {
+			continue
+		}
+		resolved[ev.Key] = ev.Value
+	}
+
+	return resolved, nil
+}
+
+// ResolveEnvVarsWithSource resolves the effective environment of a service,
+// sorted by key. Project-level vars are applied first and service-level vars
+// (including database-linked ones) override them key by key.
+func (db *DB) ResolveEnvVarsWithSource(ctx context.Context, serviceID uuid.UUID) ([]*ResolvedEnvVar, error) {
+	service, err := db.GetService(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if service == nil {
+		return nil, fmt.Errorf("service not found: %s", serviceID)
+	}
+
+	resolved := make(map[string]*ResolvedEnvVar)
+
+	projectVars, err := db.ListProjectEnvVars(ctx, service.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, ev := range projectVars {
+		if ev.IsShared() || !ev.Value.Valid {
+			continue // Shared secrets only reach services that reference them
+		}
+		resolved[ev.Key] = &ResolvedEnvVar{
+			Key:      ev.Key,
+			Value:    ev.Value.String,
+			IsSecret: ev.IsSecret,
+			Source:   EnvVarSourceProject,
+			Literal:  !ev.Interpolate || ev.IsSecret,
+		}
+	}
+
+	serviceVars, err := db.resolveServiceEnvVars(ctx, serviceID, service.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, ev := range serviceVars {
+		if existing, ok := resolved[ev.Key]; ok && existing.Source == EnvVarSourceProject {
+			ev.Overrides = true
+		}
+		resolved[ev.Key] = ev
+	}
+
+	keys := make([]string, 0, len(resolved))
+	for key := range resolved {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]*ResolvedEnvVar, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, resolved[key])
+	}
+
+	return result, nil
+}
+
+// resolveServiceEnvVars resolves a service's own env vars, including linked database values
+// and the services linked to
+func (db *DB) resolveServiceEnvVars(ctx context.Context, serviceID uuid.UUID, projectID uuid.UUID) ([]*ResolvedEnvVar, error) {
 	envVars, err := db.ListEnvVarsByService(ctx, serviceID)
 	if err != nil {
 		return nil, err
 	}
 
-	resolved := make(map[string]string)
+	var resolved []*ResolvedEnvVar
+	add := func(ev *EnvVar, value string, literal bool) {
+		resolved = append(resolved, &ResolvedEnvVar{
+			Key:      ev.Key,
+			Value:    value,
+			IsSecret: ev.IsSecret,
+			Source:   EnvVarSourceService,
+			Literal:  literal,
+		})
+	}
+
 	for _, ev := range envVars {
-		if ev.LinkedDatabaseID.Valid {
-			// Resolve from linked database
-			databaseID, err := uuid.Parse(ev.LinkedDatabaseID.String)
+		switch {
+		case ev.ProjectEnvVarID.Valid:
+			// Resolve through the referenced shared project variable
+			projectEnvVarID, err := uuid.Parse(ev.ProjectEnvVarID.String)
 			if err != nil {
-				continue // Skip invalid database ID
+				continue // Skip invalid reference
 			}
 
-			database, err := db.GetDatabase(ctx, databaseID)
-			if err != nil || database == nil {
-				continue // Skip if database not found
+			shared, err := db.GetProjectEnvVar(ctx, projectEnvVarID)
+			if err != nil || shared == nil {
+				continue // Skip if the shared variable was removed
 			}
 
-			// Resolve based on link type
-			switch ev.LinkType.String {
-			case "connection_url":
-				if database.ConnectionURL.Valid {
-					resolved[ev.Key] = database.ConnectionURL.String
-				}
-			case "host":
-				if database.InternalHostname.Valid {
-					resolved[ev.Key] = database.InternalHostname.String
-				}
-			case "port":
-				if database.Port.Valid {
-					resolved[ev.Key] = fmt.Sprintf("%d", database.Port.Int64)
-				}
-			case "username":
-				if database.Username.Valid {
-					resolved[ev.Key] = database.Username.String
-				}
-			case "password":
-				if database.Password.Valid {
-					resolved[ev.Key] = database.Password.String // TODO: Decrypt
-				}
-			case "database":
-				if database.DatabaseName.Valid {
-					resolved[ev.Key] = database.DatabaseName.String
+			ev.IsSecret = ev.IsSecret || shared.IsSecret
+			if shared.LinkedDatabaseID.Valid {
+				if value, ok := db.resolveDatabaseLink(ctx, shared.LinkedDatabaseID.String, shared.LinkType.String); ok {
+					add(ev, value, true)
 				}
+			} else if shared.Value.Valid {
+				add(ev, shared.Value.String, !shared.Interpolate || ev.IsSecret)
+			}
+		case ev.SecretRef.Valid:
+			// Resolved at deploy time, the value never reaches the database
+			resolved = append(resolved, &ResolvedEnvVar{
+				Key:       ev.Key,
+				IsSecret:  true,
+				Source:    EnvVarSourceService,
+				SecretRef: ev.SecretRef.String,
+				Literal:   true,
+			})
+		case ev.LinkedServiceID.Valid:
+			if link, ok := db.resolveServiceLink(ctx, ev.LinkedServiceID.String, ev.LinkType.String, projectID); ok {
+				resolved = append(resolved, &ResolvedEnvVar{
+					Key:         ev.Key,
+					IsSecret:    ev.IsSecret,
+					Source:      EnvVarSourceService,
+					Literal:     true,
+					ServiceLink: link,
+				})
 			}
-		} else if ev.Value.Valid {
-			// Direct value
-			resolved[ev.Key] = ev.Value.String
+		case ev.LinkedDatabaseID.Valid:
+			if value, ok := db.resolveDatabaseLink(ctx, ev.LinkedDatabaseID.String, ev.LinkType.String); ok {
+				add(ev, value, true)
+			}
+		case ev.Value.Valid:
+			// Direct value, interpolated only when the var opted in and isn't a secret
+			add(ev, ev.Value.String, !ev.Interpolate || ev.IsSecret)
 		}
 	}
 
 	return resolved, nil
 }
 
+// resolveServiceLink returns the link to a service of the project
+func (db *DB) resolveServiceLink(ctx context.Context, serviceIDStr, linkType string, projectID uuid.UUID) (*ServiceLink, bool) {
+	serviceID, err := uuid.Parse(serviceIDStr)
+	if err != nil {
+		return nil, false // Skip invalid service ID
+	}
+
+	service, err := db.GetService(ctx, serviceID)
+	if err != nil || service == nil || service.ProjectID != projectID {
+		return nil, false // Skip if the service was removed
+	}
+	if linkType != EnvLinkServiceURL && linkType != EnvLinkServiceHost {
+		return nil, false
+	}
+
+	return &ServiceLink{
+		ServiceID: service.ID,
+		ProjectID: service.ProjectID,
+		Port:      service.Port,
+		LinkType:  linkType,
+	}, true
+}
+
+// resolveDatabaseLink returns the value of a database field for a link type
+func (db *DB) resolveDatabaseLink(ctx context.Context, databaseIDStr, linkType string) (string, bool) {
+	databaseID, err := uuid.Parse(databaseIDStr)
+	if err != nil {
+		return "", false // Skip invalid database ID
+	}
+
+	database, err := db.GetDatabase(ctx, databaseID)
+	if err != nil || database == nil {
+		return "", false // Skip if database not found
+	}
+
+	// Resolve based on link type
+	switch linkType {
+	case "connection_url":
+		return database.ConnectionURL.String, database.ConnectionURL.Valid
+	case "host":
+		return database.InternalHostname.String, database.InternalHostname.Valid
+	case "port":
+		if database.Port.Valid {
+			return fmt.Sprintf("%d", database.Port.Int64), true
+		}
+	case "username":
+		return database.Username.String, database.Username.Valid
+	case "password":
+		return database.Password.String, database.Password.Valid // TODO: Decrypt
+	case "database":
+		return database.DatabaseName.String, database.DatabaseName.Valid
+	}
+
+	return "", false
+}
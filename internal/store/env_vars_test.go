@@ -0,0 +1,278 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestDB_ResolveEnvVarsWithSource(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := &Project{
+		CasdoorOrgID:      "test-org",
+		Name:              "Test Project",
+		Slug:              "test-project",
+		OpenStackTenantID: "test-tenant",
+	}
+	if err := dbStore.CreateProject(ctx, project); err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+
+	service := &Service{
+		ProjectID:    project.ID,
+		Name:         "Test Service",
+		Type:         "app",
+		Status:       "pending",
+		InstanceSize: "medium",
+		Port:         8080,
+	}
+	if err := dbStore.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create test service: %v", err)
+	}
+
+	for _, ev := range []*ProjectEnvVar{
+		{ProjectID: project.ID, Key: "LOG_LEVEL", Value: sql.NullString{String: "info", Valid: true}},
+		{ProjectID: project.ID, Key: "SENTRY_DSN", Value: sql.NullString{String: "https://sentry.example", Valid: true}, IsSecret: true},
+	} {
+		if err := dbStore.CreateProjectEnvVar(ctx, ev); err != nil {
+			t.Fatalf("Failed to create project env var: %v", err)
+		}
+	}
+
+	for _, ev := range []*EnvVar{
+		{ServiceID: service.ID, Key: "LOG_LEVEL", Value: sql.NullString{String: "debug", Valid: true}},
+		{ServiceID: service.ID, Key: "PORT", Value: sql.NullString{String: "3000", Valid: true}},
+	} {
+		if err := dbStore.CreateEnvVar(ctx, ev); err != nil {
+			t.Fatalf("Failed to create service env var: %v", err)
+		}
+	}
+
+	resolved, err := dbStore.ResolveEnvVarsWithSource(ctx, service.ID)
+	if err != nil {
+		t.Fatalf("Failed to resolve env vars: %v", err)
+	}
+
+	expected := []ResolvedEnvVar{
+		{Key: "LOG_LEVEL", Value: "debug", Source: EnvVarSourceService, Overrides: true, Literal: true},
+		{Key: "PORT", Value: "3000", Source: EnvVarSourceService, Literal: true},
+		{Key: "SENTRY_DSN", Value: "https://sentry.example", IsSecret: true, Source: EnvVarSourceProject, Literal: true},
+	}
+	if len(resolved) != len(expected) {
+		t.Fatalf("Expected %d env vars, got %d", len(expected), len(resolved))
+	}
+	for i, want := range expected {
+		if *resolved[i] != want {
+			t.Errorf("Env var %d: expected %+v, got %+v", i, want, *resolved[i])
+		}
+	}
+
+	envMap, err := dbStore.ResolveEnvVars(ctx, service.ID)
+	if err != nil {
+		t.Fatalf("Failed to resolve env map: %v", err)
+	}
+	if envMap["LOG_LEVEL"] != "debug" {
+		t.Errorf("Expected service value to override project value, got %q", envMap["LOG_LEVEL"])
+	}
+}
+
+func TestDB_CopyEnvVars(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := &Project{
+		CasdoorOrgID:      "test-org",
+		Name:              "Test Project",
+		Slug:              "test-project",
+		OpenStackTenantID: "test-tenant",
+	}
+	if err := dbStore.CreateProject(ctx, project); err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+
+	var services []*Service
+	for _, name := range []string{"prod", "staging"} {
+		service := &Service{ProjectID: project.ID, Name: name, Type: "app", Status: "pending", InstanceSize: "medium", Port: 8080}
+		if err := dbStore.CreateService(ctx, service); err != nil {
+			t.Fatalf("Failed to create test service: %v", err)
+		}
+		services = append(services, service)
+	}
+	source, target := services[0], services[1]
+
+	for _, ev := range []*EnvVar{
+		{ServiceID: source.ID, Key: "API_KEY", Value: sql.NullString{String: "prod-key", Valid: true}, IsSecret: true},
+		{ServiceID: source.ID, Key: "LOG_LEVEL", Value: sql.NullString{String: "info", Valid: true}},
+		{ServiceID: target.ID, Key: "LOG_LEVEL", Value: sql.NullString{String: "debug", Valid: true}},
+	} {
+		if err := dbStore.CreateEnvVar(ctx, ev); err != nil {
+			t.Fatalf("Failed to create env var: %v", err)
+		}
+	}
+
+	sourceVars, err := dbStore.ListEnvVarsByService(ctx, source.ID)
+	if err != nil {
+		t.Fatalf("ListEnvVarsByService failed: %v", err)
+	}
+
+	targetValues := func() map[string]string {
+		t.Helper()
+		envVars, err := dbStore.ListEnvVarsByService(ctx, target.ID)
+		if err != nil {
+			t.Fatalf("ListEnvVarsByService failed: %v", err)
+		}
+		values := make(map[string]string)
+		for _, ev := range envVars {
+			values[ev.Key] = ev.Value.String
+		}
+		return values
+	}
+
+	copied, skipped, err := dbStore.CopyEnvVars(ctx, target.ID, sourceVars, false)
+	if err != nil {
+		t.Fatalf("CopyEnvVars failed: %v", err)
+	}
+	if len(copied) != 1 || copied[0] != "API_KEY" || len(skipped) != 1 || skipped[0] != "LOG_LEVEL" {
+		t.Errorf("copied = %v, skipped = %v, want [API_KEY] and [LOG_LEVEL]", copied, skipped)
+	}
+	if values := targetValues(); values["API_KEY"] != "prod-key" || values["LOG_LEVEL"] != "debug" {
+		t.Errorf("target env = %v, want API_KEY copied and LOG_LEVEL kept", values)
+	}
+
+	copied, skipped, err = dbStore.CopyEnvVars(ctx, target.ID, sourceVars, true)
+	if err != nil {
+		t.Fatalf("CopyEnvVars failed: %v", err)
+	}
+	if len(copied) != 2 || len(skipped) != 0 {
+		t.Errorf("copied = %v, skipped = %v, want both copied", copied, skipped)
+	}
+	if values := targetValues(); values["LOG_LEVEL"] != "info" || len(values) != 2 {
+		t.Errorf("target env = %v, want LOG_LEVEL overwritten", values)
+	}
+}
+
+func TestDB_ResolveServiceLinks(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	web := testutil.NewService(t, db, project.ID)
+	api := testutil.NewService(t, db, project.ID, func(s *testutil.Service) { s.Port = 3000 })
+	other := testutil.NewService(t, db, testutil.NewProject(t, db).ID)
+
+	links := map[string]string{
+		"API_URL":   api.ID.String(),
+		"API_HOST":  api.ID.String(),
+		"OTHER_URL": other.ID.String(), // Another project's services aren't resolved
+	}
+	linkTypes := map[string]string{"API_URL": EnvLinkServiceURL, "API_HOST": EnvLinkServiceHost, "OTHER_URL": EnvLinkServiceURL}
+	for key, serviceID := range links {
+		ev := &EnvVar{
+			ServiceID:       web.ID,
+			Key:             key,
+			LinkedServiceID: sql.NullString{String: serviceID, Valid: true},
+			LinkType:        sql.NullString{String: linkTypes[key], Valid: true},
+		}
+		if err := dbStore.CreateEnvVar(ctx, ev); err != nil {
+			t.Fatalf("Failed to create env var %s: %v", key, err)
+		}
+	}
+
+	envVars, err := dbStore.ResolveEnvVarsWithSource(ctx, web.ID)
+	if err != nil {
+		t.Fatalf("Failed to resolve env vars: %v", err)
+	}
+	if len(envVars) != 2 {
+		t.Fatalf("Expected the 2 links to the project's service, got %d vars", len(envVars))
+	}
+
+	host := "svc-api.ns.svc.cluster.local"
+	want := map[string]string{"API_HOST": host, "API_URL": "http://" + host + ":3000"}
+	for _, ev := range envVars {
+		if ev.ServiceLink == nil || ev.ServiceLink.ServiceID != api.ID {
+			t.Fatalf("%s: expected a link to the api service, got %+v", ev.Key, ev.ServiceLink)
+		}
+		if !ev.Literal {
+			t.Errorf("%s: service links should not be interpolated", ev.Key)
+		}
+		if got := ev.ServiceLink.Value(host); got != want[ev.Key] {
+			t.Errorf("%s = %q, want %q", ev.Key, got, want[ev.Key])
+		}
+	}
+
+	// Links are only known at deploy time
+	envMap, err := dbStore.ResolveEnvVars(ctx, web.ID)
+	if err != nil {
+		t.Fatalf("Failed to resolve env vars: %v", err)
+	}
+	if len(envMap) != 0 {
+		t.Errorf("Expected no values before deploy, got %v", envMap)
+	}
+}
+
+func TestDB_ResolveEnvVarsWithSource_InterpolationOptIn(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	service := testutil.NewService(t, db, project.ID)
+
+	for _, ev := range []*EnvVar{
+		{Key: "HOST", Value: sql.NullString{String: "db.internal", Valid: true}},
+		{Key: "URL", Value: sql.NullString{String: "postgres://${HOST}/app", Valid: true}, Interpolate: true},
+		// Existing values don't opt in, whatever they contain
+		{Key: "PASSWORD", Value: sql.NullString{String: "pa$$w${rd}", Valid: true}},
+		// Secrets are never interpolated
+		{Key: "TOKEN", Value: sql.NullString{String: "t$$k${en}", Valid: true}, IsSecret: true, Interpolate: true},
+	} {
+		ev.ServiceID = service.ID
+		if err := dbStore.CreateEnvVar(ctx, ev); err != nil {
+			t.Fatalf("Failed to create env var %s: %v", ev.Key, err)
+		}
+	}
+
+	envVars, err := dbStore.ResolveEnvVarsWithSource(ctx, service.ID)
+	if err != nil {
+		t.Fatalf("Failed to resolve env vars: %v", err)
+	}
+	values, err := dbStore.ResolveEnvVars(ctx, service.ID)
+	if err != nil {
+		t.Fatalf("Failed to resolve env map: %v", err)
+	}
+	expanded, err := InterpolateEnvVars(envVars, values)
+	if err != nil {
+		t.Fatalf("InterpolateEnvVars failed: %v", err)
+	}
+
+	want := map[string]string{
+		"HOST":     "db.internal",
+		"URL":      "postgres://db.internal/app",
+		"PASSWORD": "pa$$w${rd}",
+		"TOKEN":    "t$$k${en}",
+	}
+	for key, value := range want {
+		if expanded[key] != value {
+			t.Errorf("%s = %q, want %q", key, expanded[key], value)
+		}
+	}
+}
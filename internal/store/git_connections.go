@@ -182,7 +182,8 @@ func (db *DB) ListGitConnectionsByOrg(ctx context.Context, orgID string) ([]*Git
 	return connections, rows.Err()
 }
 
-// GetGitConnectionByOrgAndProvider gets a git connection by org and provider
+// GetGitConnectionByOrgAndProvider gets a git connection by org and provider. If the
+// org somehow has several, the oldest one is returned so reconnects keep reusing it.
 func (db *DB) GetGitConnectionByOrgAndProvider(ctx context.Context, orgID, provider string) (*GitConnection, error) {
 	var gc GitConnection
 	query := `
@@ -191,6 +192,7 @@ func (db *DB) GetGitConnectionByOrgAndProvider(ctx context.Context, orgID, provi
 		       created_at, updated_at
 		FROM git_connections
 		WHERE casdoor_org_id = $1 AND provider = $2
+		ORDER BY created_at, id
 		LIMIT 1
 	`
 
@@ -240,7 +242,7 @@ func (db *DB) UpdateGitConnection(ctx context.Context, id uuid.UUID, gc *GitConn
 		    token_expires_at = $3,
 		    account_name = $4,
 		    account_id = $5,
-		    updated_at = now()
+		    updated_at = CURRENT_TIMESTAMP
 		WHERE id = $6
 		RETURNING updated_at
 	`
@@ -9,49 +9,77 @@ import (
 )
 
 type GitSource struct {
-	ID              uuid.UUID
-	ServiceID       uuid.UUID
-	GitConnectionID uuid.UUID
-	Provider        string // github, gitlab
-	RepoOwner       string
-	RepoName        string
-	Branch          string
-	RootDir         sql.NullString
-	WebhookID       sql.NullString
-	WebhookSecret   sql.NullString
-	CreatedAt       time.Time
+	ID                             uuid.UUID
+	ServiceID                      uuid.UUID
+	GitConnectionID                uuid.UUID
+	Provider                       string // github, gitlab
+	RepoOwner                      string
+	RepoName                       string
+	Branch                         string
+	RootDir                        sql.NullString
+	TriggerMode                    string         // branch, tag
+	TagPattern                     sql.NullString // glob matched against tag names in tag mode, e.g. v*
+	WebhookID                      sql.NullString
+	WebhookSecret                  sql.NullString
+	PreviousWebhookSecret          sql.NullString // replaced by the last rotation, accepted until it expires
+	PreviousWebhookSecretExpiresAt sql.NullTime
+	CreatedAt                      time.Time
+}
+
+// AcceptedWebhookSecrets returns the secrets webhook deliveries for this source may be
+// signed with at now: its current secret, and the previous one during a rotation's grace window
+func (gs *GitSource) AcceptedWebhookSecrets(now time.Time) []string {
+	var secrets []string
+	if gs.WebhookSecret.Valid && gs.WebhookSecret.String != "" {
+		secrets = append(secrets, gs.WebhookSecret.String)
+	}
+	if gs.PreviousWebhookSecret.Valid && gs.PreviousWebhookSecret.String != "" &&
+		gs.PreviousWebhookSecretExpiresAt.Valid && now.Before(gs.PreviousWebhookSecretExpiresAt.Time) {
+		secrets = append(secrets, gs.PreviousWebhookSecret.String)
+	}
+	return secrets
 }
 
 // CreateGitSource creates a new git source
 func (db *DB) CreateGitSource(ctx context.Context, gs *GitSource) error {
+	// Check if we're using SQLite (for compatibility)
+	var versionStr string
+	isSQLite := db.QueryRow("SELECT sqlite_version()").Scan(&versionStr) == nil
+
+	defer db.cacheInvalidator(ctx, serviceProjectQuery, gs.ServiceID)()
+	return insertGitSource(ctx, db, isSQLite, gs)
+}
+
+// insertGitSource inserts a git source with q, the database or a transaction
+func insertGitSource(ctx context.Context, q execer, isSQLite bool, gs *GitSource) error {
 	// Generate UUID if not set (for SQLite compatibility)
 	if gs.ID == uuid.Nil {
 		gs.ID = uuid.New()
 	}
 
-	// Check if we're using SQLite (for compatibility)
-	var isSQLite bool
-	var versionStr string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&versionStr)
-	isSQLite = err == nil
+	if gs.TriggerMode == "" {
+		gs.TriggerMode = "branch"
+	}
 
 	if isSQLite {
 		// SQLite: Insert with explicit UUID (no RETURNING support in older versions)
 		query := `
 			INSERT INTO git_sources (
 				id, service_id, git_connection_id, provider, repo_owner,
-				repo_name, branch, root_dir, webhook_id, webhook_secret
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				repo_name, branch, root_dir, trigger_mode, tag_pattern,
+				webhook_id, webhook_secret
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		`
-		_, err = db.ExecContext(ctx, query,
+		_, err := q.ExecContext(ctx, query,
 			gs.ID.String(), gs.ServiceID.String(), gs.GitConnectionID.String(), gs.Provider,
-			gs.RepoOwner, gs.RepoName, gs.Branch, gs.RootDir, gs.WebhookID, gs.WebhookSecret,
+			gs.RepoOwner, gs.RepoName, gs.Branch, gs.RootDir, gs.TriggerMode, gs.TagPattern,
+			gs.WebhookID, gs.WebhookSecret,
 		)
 		if err != nil {
 			return err
 		}
 		// Get timestamp
-		err = db.QueryRowContext(ctx, "SELECT created_at FROM git_sources WHERE id = $1", gs.ID.String()).
+		err = q.QueryRowContext(ctx, "SELECT created_at FROM git_sources WHERE id = $1", gs.ID.String()).
 			Scan(&gs.CreatedAt)
 		return err
 	}
@@ -60,12 +88,13 @@ func (db *DB) CreateGitSource(ctx context.Context, gs *GitSource) error {
 	query := `
 		INSERT INTO git_sources (
 			service_id, git_connection_id, provider, repo_owner,
-			repo_name, branch, root_dir, webhook_id, webhook_secret
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			repo_name, branch, root_dir, trigger_mode, tag_pattern,
+			webhook_id, webhook_secret
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING id, created_at
 	`
 
-	err = db.QueryRowContext(ctx, query,
+	return q.QueryRowContext(ctx, query,
 		gs.ServiceID,
 		gs.GitConnectionID,
 		gs.Provider,
@@ -73,11 +102,11 @@ func (db *DB) CreateGitSource(ctx context.Context, gs *GitSource) error {
 		gs.RepoName,
 		gs.Branch,
 		gs.RootDir,
+		gs.TriggerMode,
+		gs.TagPattern,
 		gs.WebhookID,
 		gs.WebhookSecret,
 	).Scan(&gs.ID, &gs.CreatedAt)
-
-	return err
 }
 
 // GetGitSource retrieves a git source by ID
@@ -85,13 +114,15 @@ func (db *DB) GetGitSource(ctx context.Context, id uuid.UUID) (*GitSource, error
 	var gs GitSource
 	query := `
 		SELECT id, service_id, git_connection_id, provider, repo_owner,
-		       repo_name, branch, root_dir, webhook_id, webhook_secret,
-		       created_at
+		       repo_name, branch, root_dir, trigger_mode, tag_pattern,
+		       webhook_id, webhook_secret, previous_webhook_secret,
+		       previous_webhook_secret_expires_at, created_at
 		FROM git_sources
 		WHERE id = $1
 	`
 
 	var rootDir sql.NullString
+	var tagPattern sql.NullString
 	var webhookID sql.NullString
 	var webhookSecret sql.NullString
 
@@ -104,8 +135,12 @@ func (db *DB) GetGitSource(ctx context.Context, id uuid.UUID) (*GitSource, error
 		&gs.RepoName,
 		&gs.Branch,
 		&rootDir,
+		&gs.TriggerMode,
+		&tagPattern,
 		&webhookID,
 		&webhookSecret,
+		&gs.PreviousWebhookSecret,
+		&gs.PreviousWebhookSecretExpiresAt,
 		&gs.CreatedAt,
 	)
 
@@ -118,6 +153,7 @@ func (db *DB) GetGitSource(ctx context.Context, id uuid.UUID) (*GitSource, error
 	}
 
 	gs.RootDir = rootDir
+	gs.TagPattern = tagPattern
 	gs.WebhookID = webhookID
 	gs.WebhookSecret = webhookSecret
 
@@ -129,14 +165,16 @@ func (db *DB) GetGitSourceByService(ctx context.Context, serviceID uuid.UUID) (*
 	var gs GitSource
 	query := `
 		SELECT id, service_id, git_connection_id, provider, repo_owner,
-		       repo_name, branch, root_dir, webhook_id, webhook_secret,
-		       created_at
+		       repo_name, branch, root_dir, trigger_mode, tag_pattern,
+		       webhook_id, webhook_secret, previous_webhook_secret,
+		       previous_webhook_secret_expires_at, created_at
 		FROM git_sources
 		WHERE service_id = $1
 		LIMIT 1
 	`
 
 	var rootDir sql.NullString
+	var tagPattern sql.NullString
 	var webhookID sql.NullString
 	var webhookSecret sql.NullString
 
@@ -149,8 +187,12 @@ func (db *DB) GetGitSourceByService(ctx context.Context, serviceID uuid.UUID) (*
 		&gs.RepoName,
 		&gs.Branch,
 		&rootDir,
+		&gs.TriggerMode,
+		&tagPattern,
 		&webhookID,
 		&webhookSecret,
+		&gs.PreviousWebhookSecret,
+		&gs.PreviousWebhookSecretExpiresAt,
 		&gs.CreatedAt,
 	)
 
@@ -163,6 +205,7 @@ func (db *DB) GetGitSourceByService(ctx context.Context, serviceID uuid.UUID) (*
 	}
 
 	gs.RootDir = rootDir
+	gs.TagPattern = tagPattern
 	gs.WebhookID = webhookID
 	gs.WebhookSecret = webhookSecret
 
@@ -171,15 +214,24 @@ func (db *DB) GetGitSourceByService(ctx context.Context, serviceID uuid.UUID) (*
 
 // UpdateGitSource updates a git source
 func (db *DB) UpdateGitSource(ctx context.Context, id uuid.UUID, gs *GitSource) error {
+	defer db.cacheInvalidator(ctx, gitSourceProjectQuery, id)()
+
 	query := `
 		UPDATE git_sources
-		SET branch = $1, root_dir = $2, webhook_id = $3, webhook_secret = $4
-		WHERE id = $5
+		SET branch = $1, root_dir = $2, trigger_mode = $3, tag_pattern = $4,
+		    webhook_id = $5, webhook_secret = $6
+		WHERE id = $7
 	`
 
+	if gs.TriggerMode == "" {
+		gs.TriggerMode = "branch"
+	}
+
 	_, err := db.ExecContext(ctx, query,
 		gs.Branch,
 		gs.RootDir,
+		gs.TriggerMode,
+		gs.TagPattern,
 		gs.WebhookID,
 		gs.WebhookSecret,
 		id,
@@ -188,8 +240,57 @@ func (db *DB) UpdateGitSource(ctx context.Context, id uuid.UUID, gs *GitSource)
 	return err
 }
 
+// ListGitSourcesByRepo lists the git sources of all services built from a repository
+func (db *DB) ListGitSourcesByRepo(ctx context.Context, provider, repoOwner, repoName string) ([]*GitSource, error) {
+	query := `
+		SELECT id, service_id, git_connection_id, provider, repo_owner,
+		       repo_name, branch, root_dir, trigger_mode, tag_pattern,
+		       webhook_id, webhook_secret, previous_webhook_secret,
+		       previous_webhook_secret_expires_at, created_at
+		FROM git_sources
+		WHERE provider = $1 AND LOWER(repo_owner) = LOWER($2) AND LOWER(repo_name) = LOWER($3)
+		ORDER BY created_at ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, provider, repoOwner, repoName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sources []*GitSource
+	for rows.Next() {
+		var gs GitSource
+		err := rows.Scan(
+			&gs.ID,
+			&gs.ServiceID,
+			&gs.GitConnectionID,
+			&gs.Provider,
+			&gs.RepoOwner,
+			&gs.RepoName,
+			&gs.Branch,
+			&gs.RootDir,
+			&gs.TriggerMode,
+			&gs.TagPattern,
+			&gs.WebhookID,
+			&gs.WebhookSecret,
+			&gs.PreviousWebhookSecret,
+			&gs.PreviousWebhookSecretExpiresAt,
+			&gs.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, &gs)
+	}
+
+	return sources, rows.Err()
+}
+
 // DeleteGitSource deletes a git source
 func (db *DB) DeleteGitSource(ctx context.Context, id uuid.UUID) error {
+	defer db.cacheInvalidator(ctx, gitSourceProjectQuery, id)()
+
 	query := `DELETE FROM git_sources WHERE id = $1`
 
 	result, err := db.ExecContext(ctx, query, id)
@@ -209,3 +310,45 @@ func (db *DB) DeleteGitSource(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// RotateWebhookSecret makes secret the git source's webhook secret. The replaced secret
+// stays valid until graceUntil so deliveries signed before the provider picks up the
+// new secret still verify. A source without a secret of its own was verified with the
+// global one, globalSecret, which stays valid instead.
+func (db *DB) RotateWebhookSecret(ctx context.Context, id uuid.UUID, secret, globalSecret string, graceUntil time.Time) error {
+	query := `
+		UPDATE git_sources
+		SET previous_webhook_secret = COALESCE(NULLIF(webhook_secret, ''), NULLIF($1, '')),
+		    previous_webhook_secret_expires_at = $2, webhook_secret = $3
+		WHERE id = $4
+	`
+
+	result, err := db.ExecContext(ctx, query, globalSecret, graceUntil, secret, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// RevertWebhookSecretRotation puts back secret, the git source's webhook secret before
+// RotateWebhookSecret, for when the provider's webhook couldn't be updated with the new
+// one. A NULL secret puts the source back on the global webhook secret.
+func (db *DB) RevertWebhookSecretRotation(ctx context.Context, id uuid.UUID, secret sql.NullString) error {
+	query := `
+		UPDATE git_sources
+		SET webhook_secret = $1, previous_webhook_secret = NULL,
+		    previous_webhook_secret_expires_at = NULL
+		WHERE id = $2
+	`
+
+	_, err := db.ExecContext(ctx, query, secret, id)
+	return err
+}
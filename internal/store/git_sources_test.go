@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestDB_RotateWebhookSecret(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	gc := &GitConnection{CasdoorOrgID: project.OrgID, Provider: "github", AccessToken: "token"}
+	if err := dbStore.CreateGitConnection(ctx, gc); err != nil {
+		t.Fatalf("Failed to create git connection: %v", err)
+	}
+	newGitSource := func() *GitSource {
+		service := testutil.NewService(t, db, project.ID)
+		gs := &GitSource{ServiceID: service.ID, GitConnectionID: gc.ID, Provider: "github", RepoOwner: "acme", RepoName: "api", Branch: "main"}
+		if err := dbStore.CreateGitSource(ctx, gs); err != nil {
+			t.Fatalf("Failed to create git source: %v", err)
+		}
+		return gs
+	}
+	accepts := func(gs *GitSource, secret string) bool {
+		stored, err := dbStore.GetGitSource(ctx, gs.ID)
+		if err != nil {
+			t.Fatalf("Failed to get git source: %v", err)
+		}
+		for _, s := range stored.AcceptedWebhookSecrets(time.Now()) {
+			if s == secret {
+				return true
+			}
+		}
+		return false
+	}
+	graceUntil := time.Now().Add(time.Hour)
+
+	// The first rotation of a source verified with the global secret keeps that one valid
+	gs := newGitSource()
+	if err := dbStore.RotateWebhookSecret(ctx, gs.ID, "first", "global", graceUntil); err != nil {
+		t.Fatalf("RotateWebhookSecret failed: %v", err)
+	}
+	if !accepts(gs, "first") || !accepts(gs, "global") {
+		t.Error("Expected the new secret and the global one it replaced to be accepted")
+	}
+
+	// Later rotations keep the source's own previous secret valid
+	if err := dbStore.RotateWebhookSecret(ctx, gs.ID, "second", "global", graceUntil); err != nil {
+		t.Fatalf("RotateWebhookSecret failed: %v", err)
+	}
+	if !accepts(gs, "second") || !accepts(gs, "first") || accepts(gs, "global") {
+		t.Error("Expected only the new secret and the one it replaced to be accepted")
+	}
+
+	// Without a global secret there is nothing to keep
+	unset := newGitSource()
+	if err := dbStore.RotateWebhookSecret(ctx, unset.ID, "first", "", graceUntil); err != nil {
+		t.Fatalf("RotateWebhookSecret failed: %v", err)
+	}
+	stored, err := dbStore.GetGitSource(ctx, unset.ID)
+	if err != nil {
+		t.Fatalf("Failed to get git source: %v", err)
+	}
+	if stored.PreviousWebhookSecret.Valid {
+		t.Errorf("Expected no previous secret, got %q", stored.PreviousWebhookSecret.String)
+	}
+
+	// Reverting the first rotation puts the source back on the global secret
+	if err := dbStore.RevertWebhookSecretRotation(ctx, unset.ID, sql.NullString{}); err != nil {
+		t.Fatalf("RevertWebhookSecretRotation failed: %v", err)
+	}
+	stored, err = dbStore.GetGitSource(ctx, unset.ID)
+	if err != nil {
+		t.Fatalf("Failed to get git source: %v", err)
+	}
+	if stored.WebhookSecret.Valid || len(stored.AcceptedWebhookSecrets(time.Now())) != 0 {
+		t.Errorf("Expected the source back on the global secret, got %q", stored.WebhookSecret.String)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -81,9 +84,9 @@ func (db *DB) CreateJob(ctx context.Context, job *Job) error {
 func (db *DB) GetNextJob(ctx context.Context) (*Job, error) {
 	query := `
 		SELECT id, type, payload, status, attempts, max_attempts, error,
-		       created_at, updated_at, started_at, finished_at
+		       created_at, updated_at, started_at, completed_at
 		FROM jobs
-		WHERE status = 'queued'
+		WHERE status = 'queued' AND (run_at IS NULL OR run_at <= now())
 		ORDER BY created_at ASC
 		FOR UPDATE SKIP LOCKED
 		LIMIT 1
@@ -129,6 +132,114 @@ func (db *DB) GetNextJob(ctx context.Context) (*Job, error) {
 	return &job, nil
 }
 
+// JobLockDuration is how long a claimed job stays locked to its worker. A job whose
+// worker died is claimable again once the lock expires.
+const JobLockDuration = 10 * time.Minute
+
+// ClaimNextJob atomically claims the oldest pending job of one of the given types
+// (any type when types is empty) for workerID: the job is set running and locked
+// for JobLockDuration. Jobs locked by another worker are skipped until their lock
+// expires. Returns nil when there is no job to claim.
+func (db *DB) ClaimNextJob(ctx context.Context, workerID string, types []string) (*Job, error) {
+	now := time.Now().UTC()
+	args := []interface{}{now}
+	where := `status = 'pending' AND (run_at IS NULL OR run_at <= $1)
+		  AND (locked_until IS NULL OR locked_until < $1)`
+	if len(types) > 0 {
+		placeholders := make([]string, len(types))
+		for i, jobType := range types {
+			args = append(args, jobType)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		where += " AND type IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	var versionStr string
+	if db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&versionStr) == nil {
+		return db.claimNextJobSQLite(ctx, workerID, now, where, args)
+	}
+
+	// PostgreSQL: concurrent claims skip the row another transaction is claiming
+	// instead of waiting for it
+	query := fmt.Sprintf(`
+		UPDATE jobs
+		SET status = 'running', locked_by = $%d, locked_until = $%d,
+		    started_at = $1, updated_at = $1
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE %s
+			ORDER BY created_at ASC
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING id, type, payload, status, attempts, max_attempts, created_at, updated_at, started_at
+	`, len(args)+1, len(args)+2, where)
+	args = append(args, workerID, now.Add(JobLockDuration))
+
+	return scanClaimedJob(db.QueryRowContext(ctx, query, args...))
+}
+
+// claimNextJobSQLite claims a job in a transaction. SQLite serializes writers, and
+// the update only applies while the job is still claimable, so two workers can't
+// both claim it.
+func (db *DB) claimNextJobSQLite(ctx context.Context, workerID string, now time.Time, where string, args []interface{}) (*Job, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var jobID string
+	err = tx.QueryRowContext(ctx, "SELECT id FROM jobs WHERE "+where+" ORDER BY created_at ASC LIMIT 1", args...).Scan(&jobID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = 'running', locked_by = $1, locked_until = $2, started_at = $3, updated_at = $3
+		WHERE id = $4 AND status = 'pending' AND (locked_until IS NULL OR locked_until < $3)
+	`, workerID, now.Add(JobLockDuration), now, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, nil
+	}
+
+	job, err := scanClaimedJob(tx.QueryRowContext(ctx, `
+		SELECT id, type, payload, status, attempts, max_attempts, created_at, updated_at, started_at
+		FROM jobs WHERE id = $1
+	`, jobID))
+	if err != nil || job == nil {
+		return nil, err
+	}
+	return job, tx.Commit()
+}
+
+func scanClaimedJob(row *sql.Row) (*Job, error) {
+	var job Job
+	var payloadJSON []byte
+	err := row.Scan(&job.ID, &job.Type, &payloadJSON, &job.Status, &job.Attempts, &job.MaxAttempts,
+		&job.CreatedAt, &job.UpdatedAt, &job.StartedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(payloadJSON, &job.Payload); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
 // UpdateJobStatus updates job status
 func (db *DB) UpdateJobStatus(ctx context.Context, jobID uuid.UUID, status string) error {
 	query := `UPDATE jobs SET status = $1, updated_at = now() WHERE id = $2`
@@ -147,25 +258,58 @@ func (db *DB) StartJob(ctx context.Context, jobID uuid.UUID) error {
 	return err
 }
 
-// CompleteJob marks a job as completed
+// CompleteJob marks a job as completed and releases its claim
 func (db *DB) CompleteJob(ctx context.Context, jobID uuid.UUID) error {
+	now := time.Now().UTC()
 	query := `
 		UPDATE jobs 
-		SET status = 'completed', finished_at = now(), updated_at = now()
-		WHERE id = $1
+		SET status = 'completed', completed_at = $1, updated_at = $1,
+		    locked_by = NULL, locked_until = NULL
+		WHERE id = $2
 	`
-	_, err := db.ExecContext(ctx, query, jobID)
+	_, err := db.ExecContext(ctx, query, now, jobID)
 	return err
 }
 
-// FailJob marks a job as failed
+// FailJob marks a job as failed and releases its claim
 func (db *DB) FailJob(ctx context.Context, jobID uuid.UUID, errorMsg string) error {
+	now := time.Now().UTC()
 	query := `
 		UPDATE jobs 
-		SET status = 'failed', error = $1, finished_at = now(), updated_at = now()
-		WHERE id = $2
+		SET status = 'failed', error = $1, completed_at = $2, updated_at = $2,
+		    locked_by = NULL, locked_until = NULL
+		WHERE id = $3
+	`
+	_, err := db.ExecContext(ctx, query, errorMsg, now, jobID)
+	return err
+}
+
+// RetryJob records a failed attempt of a claimed job and puts it back as pending,
+// to be claimed again no earlier than runAt, and releases its claim
+func (db *DB) RetryJob(ctx context.Context, jobID uuid.UUID, errorMsg string, runAt time.Time) error {
+	now := time.Now().UTC()
+	query := `
+		UPDATE jobs 
+		SET status = 'pending', attempts = attempts + 1, error = $1, run_at = $2, updated_at = $3,
+		    locked_by = NULL, locked_until = NULL
+		WHERE id = $4
+	`
+	_, err := db.ExecContext(ctx, query, errorMsg, runAt.UTC(), now, jobID)
+	return err
+}
+
+// DeadLetterJob marks a job that ran out of attempts as dead_letter, distinct from
+// a failed attempt, and releases its claim. Dead-letter jobs stay until an operator
+// requeues them.
+func (db *DB) DeadLetterJob(ctx context.Context, jobID uuid.UUID, errorMsg string) error {
+	now := time.Now().UTC()
+	query := `
+		UPDATE jobs 
+		SET status = 'dead_letter', error = $1, completed_at = $2, updated_at = $2,
+		    locked_by = NULL, locked_until = NULL
+		WHERE id = $3
 	`
-	_, err := db.ExecContext(ctx, query, errorMsg, jobID)
+	_, err := db.ExecContext(ctx, query, errorMsg, now, jobID)
 	return err
 }
 
@@ -180,11 +324,22 @@ func (db *DB) IncrementJobAttempts(ctx context.Context, jobID uuid.UUID) error {
 	return err
 }
 
+// RescheduleJob requeues a job to run no earlier than runAt, without counting an attempt
+func (db *DB) RescheduleJob(ctx context.Context, jobID uuid.UUID, runAt time.Time) error {
+	query := `
+		UPDATE jobs 
+		SET status = 'queued', run_at = $1, updated_at = now()
+		WHERE id = $2
+	`
+	_, err := db.ExecContext(ctx, query, runAt, jobID)
+	return err
+}
+
 // GetJob retrieves a job by ID
 func (db *DB) GetJob(ctx context.Context, jobID uuid.UUID) (*Job, error) {
 	query := `
 		SELECT id, type, payload, status, attempts, max_attempts, error,
-		       created_at, updated_at, started_at, finished_at
+		       created_at, updated_at, started_at, completed_at
 		FROM jobs
 		WHERE id = $1
 	`
@@ -229,3 +384,132 @@ func (db *DB) GetJob(ctx context.Context, jobID uuid.UUID) (*Job, error) {
 	return &job, nil
 }
 
+
+// JobResourceKeys are the payload keys naming the resource a job works on, in the
+// order they're checked to find which project, and so which org, the job belongs to
+var JobResourceKeys = []string{"deployment_id", "service_id", "database_id", "volume_id", "project_id"}
+
+// jobResourceProjects selects the IDs of an org's resources of each kind, as text
+// like the payload holds them. The org is $1.
+var jobResourceProjects = map[string]string{
+	"deployment_id": `SELECT CAST(d.id AS TEXT) FROM deployments d JOIN services s ON s.id = d.service_id JOIN projects p ON p.id = s.project_id WHERE ` + projectOrgCondition,
+	"service_id":    `SELECT CAST(s.id AS TEXT) FROM services s JOIN projects p ON p.id = s.project_id WHERE ` + projectOrgCondition,
+	"database_id":   `SELECT CAST(d.id AS TEXT) FROM databases d JOIN services s ON s.id = d.service_id JOIN projects p ON p.id = s.project_id WHERE ` + projectOrgCondition,
+	"volume_id":     `SELECT CAST(v.id AS TEXT) FROM volumes v JOIN projects p ON p.id = v.project_id WHERE ` + projectOrgCondition,
+	"project_id":    `SELECT CAST(p.id AS TEXT) FROM projects p WHERE ` + projectOrgCondition,
+}
+
+// projectOrgCondition matches the projects p of the org $1, by Casdoor org or org ID
+const projectOrgCondition = `(p.casdoor_org_id = $1 OR CAST(p.org_id AS TEXT) = $1)`
+
+// jobOrgCondition returns the condition matching the jobs j of the org $1: the first
+// resource their payload names belongs to one of the org's projects
+func jobOrgCondition(isSQLite bool) string {
+	field := func(key string) string {
+		if isSQLite {
+			return "COALESCE(json_extract(j.payload, '$." + key + "'), '')"
+		}
+		return "COALESCE(j.payload->>'" + key + "', '')"
+	}
+
+	alternatives := make([]string, 0, len(JobResourceKeys))
+	for i, key := range JobResourceKeys {
+		conditions := make([]string, 0, i+1)
+		// Keys checked before this one are absent
+		for _, earlier := range JobResourceKeys[:i] {
+			conditions = append(conditions, field(earlier)+" = ''")
+		}
+		conditions = append(conditions, field(key)+" IN ("+jobResourceProjects[key]+")")
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+// ListJobs lists the most recent jobs of an org, newest first: those working on a
+// resource of one of its projects. Empty jobType or status match any type or status.
+func (db *DB) ListJobs(ctx context.Context, orgID, jobType, status string, limit int) ([]*Job, error) {
+	var version string
+	isSQLite := db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&version) == nil
+
+	query := `
+		SELECT j.id, j.type, j.payload, j.status, j.attempts, j.max_attempts, j.error,
+		       j.created_at, j.updated_at, j.started_at, j.completed_at
+		FROM jobs j
+		WHERE ` + jobOrgCondition(isSQLite) + ` AND ($2 = '' OR j.type = $2) AND ($3 = '' OR j.status = $3)
+		ORDER BY j.created_at DESC
+		LIMIT $4
+	`
+	rows, err := db.QueryContext(ctx, query, orgID, jobType, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*Job
+	for rows.Next() {
+		var job Job
+		var payloadJSON []byte
+		if err := rows.Scan(&job.ID, &job.Type, &payloadJSON, &job.Status, &job.Attempts, &job.MaxAttempts,
+			&job.Error, &job.CreatedAt, &job.UpdatedAt, &job.StartedAt, &job.FinishedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(payloadJSON, &job.Payload); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, rows.Err()
+}
+
+// CancelJob cancels a job that hasn't started yet. Returns false when the job
+// isn't waiting anymore, e.g. a worker claimed it in the meantime.
+func (db *DB) CancelJob(ctx context.Context, jobID uuid.UUID) (bool, error) {
+	now := time.Now().UTC()
+	result, err := db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = 'cancelled', completed_at = $1, updated_at = $1
+		WHERE id = $2 AND status IN ('pending', 'queued')
+	`, now, jobID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ListDeadLetterJobs lists the jobs of an org that failed for good, newest first. Jobs
+// failed before dead_letter existed are included. An empty jobType matches any type.
+func (db *DB) ListDeadLetterJobs(ctx context.Context, orgID, jobType string, limit int) ([]*Job, error) {
+	jobs, err := db.ListJobs(ctx, orgID, jobType, "dead_letter", limit)
+	if err != nil {
+		return nil, err
+	}
+	failed, err := db.ListJobs(ctx, orgID, jobType, "failed", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs = append(jobs, failed...)
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+// RequeueJob puts a failed or dead-letter job back in the queue as pending, with
+// its attempts reset. Returns false when the job isn't failed.
+func (db *DB) RequeueJob(ctx context.Context, jobID uuid.UUID) (bool, error) {
+	now := time.Now().UTC()
+	result, err := db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = 'pending', attempts = 0, error = NULL, run_at = $1, updated_at = $1,
+		    started_at = NULL, completed_at = NULL, locked_by = NULL, locked_until = NULL
+		WHERE id = $2 AND status IN ('failed', 'dead_letter')
+	`, now, jobID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
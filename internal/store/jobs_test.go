@@ -0,0 +1,204 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestDB_ClaimNextJob(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	base := time.Now().UTC().Add(-time.Hour)
+	newJob := func(jobType string, age int) *Job {
+		job := &Job{Type: jobType, Status: "pending", MaxAttempts: 3, Payload: map[string]interface{}{"n": age}}
+		if err := dbStore.CreateJob(ctx, job); err != nil {
+			t.Fatalf("Failed to create job: %v", err)
+		}
+		// created_at has a one second resolution on SQLite, order the jobs explicitly
+		if _, err := db.Exec("UPDATE jobs SET created_at = $1 WHERE id = $2", base.Add(time.Duration(age)*time.Minute), job.ID.String()); err != nil {
+			t.Fatalf("Failed to set job created_at: %v", err)
+		}
+		return job
+	}
+	oldBuild := newJob("build", 0)
+	rollback := newJob("rollback", 1)
+	newBuild := newJob("build", 2)
+
+	t.Run("claims the oldest pending job of the types", func(t *testing.T) {
+		job, err := dbStore.ClaimNextJob(ctx, "worker-1", []string{"build"})
+		if err != nil {
+			t.Fatalf("ClaimNextJob failed: %v", err)
+		}
+		if job == nil || job.ID != oldBuild.ID {
+			t.Fatalf("Expected to claim job %s, got %+v", oldBuild.ID, job)
+		}
+		if job.Status != "running" || !job.StartedAt.Valid {
+			t.Errorf("Expected a running job with started_at, got status %s", job.Status)
+		}
+		if job.Payload["n"] != float64(0) {
+			t.Errorf("Expected the job payload, got %v", job.Payload)
+		}
+
+		var lockedBy string
+		if err := db.QueryRow("SELECT locked_by FROM jobs WHERE id = $1", job.ID.String()).Scan(&lockedBy); err != nil {
+			t.Fatalf("Failed to read lock: %v", err)
+		}
+		if lockedBy != "worker-1" {
+			t.Errorf("Expected job locked by worker-1, got %s", lockedBy)
+		}
+	})
+
+	t.Run("skips claimed jobs", func(t *testing.T) {
+		job, err := dbStore.ClaimNextJob(ctx, "worker-2", []string{"build"})
+		if err != nil {
+			t.Fatalf("ClaimNextJob failed: %v", err)
+		}
+		if job == nil || job.ID != newBuild.ID {
+			t.Fatalf("Expected to claim job %s, got %+v", newBuild.ID, job)
+		}
+
+		job, err = dbStore.ClaimNextJob(ctx, "worker-3", []string{"build"})
+		if err != nil {
+			t.Fatalf("ClaimNextJob failed: %v", err)
+		}
+		if job != nil {
+			t.Errorf("Expected no build job left, got %s", job.ID)
+		}
+	})
+
+	t.Run("reclaims jobs whose lock expired", func(t *testing.T) {
+		// A pending job still locked by a worker is skipped until the lock expires
+		if _, err := db.Exec("UPDATE jobs SET locked_by = 'dead-worker', locked_until = $1 WHERE id = $2",
+			time.Now().UTC().Add(time.Minute), rollback.ID.String()); err != nil {
+			t.Fatalf("Failed to lock job: %v", err)
+		}
+		job, err := dbStore.ClaimNextJob(ctx, "worker-1", nil)
+		if err != nil {
+			t.Fatalf("ClaimNextJob failed: %v", err)
+		}
+		if job != nil {
+			t.Fatalf("Expected the locked job to be skipped, got %s", job.ID)
+		}
+
+		if _, err := db.Exec("UPDATE jobs SET locked_until = $1 WHERE id = $2",
+			time.Now().UTC().Add(-time.Minute), rollback.ID.String()); err != nil {
+			t.Fatalf("Failed to expire lock: %v", err)
+		}
+		job, err = dbStore.ClaimNextJob(ctx, "worker-1", nil)
+		if err != nil {
+			t.Fatalf("ClaimNextJob failed: %v", err)
+		}
+		if job == nil || job.ID != rollback.ID {
+			t.Fatalf("Expected to claim job %s, got %+v", rollback.ID, job)
+		}
+	})
+
+	t.Run("complete and fail release the claim", func(t *testing.T) {
+		if err := dbStore.CompleteJob(ctx, oldBuild.ID); err != nil {
+			t.Fatalf("CompleteJob failed: %v", err)
+		}
+		if err := dbStore.FailJob(ctx, newBuild.ID, "build failed"); err != nil {
+			t.Fatalf("FailJob failed: %v", err)
+		}
+
+		for id, want := range map[string]string{oldBuild.ID.String(): "completed", newBuild.ID.String(): "failed"} {
+			var status string
+			var lockedBy *string
+			var completed bool
+			if err := db.QueryRow("SELECT status, locked_by, completed_at IS NOT NULL FROM jobs WHERE id = $1", id).
+				Scan(&status, &lockedBy, &completed); err != nil {
+				t.Fatalf("Failed to read job: %v", err)
+			}
+			if status != want || lockedBy != nil || !completed {
+				t.Errorf("Expected job %s %s, unlocked, with completed_at, got status %s, locked_by %v", id, want, status, lockedBy)
+			}
+		}
+	})
+}
+
+func TestDB_ListJobs_ByOrg(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	otherProject := testutil.NewProject(t, db, func(p *testutil.Project) { p.OrgID = "other-org" })
+	service := testutil.NewService(t, db, project.ID)
+	otherService := testutil.NewService(t, db, otherProject.ID)
+	deployment := testutil.NewDeployment(t, db, service.ID)
+	volume := testutil.NewVolume(t, db, project.ID)
+	database := testutil.NewDatabase(t, db, service.ID)
+
+	base := time.Now().UTC().Add(-time.Hour)
+	n := 0
+	newJob := func(status string, payload map[string]interface{}) *Job {
+		job := &Job{Type: "build", Status: status, MaxAttempts: 3, Payload: payload}
+		if err := dbStore.CreateJob(ctx, job); err != nil {
+			t.Fatalf("Failed to create job: %v", err)
+		}
+		n++
+		if _, err := db.Exec("UPDATE jobs SET created_at = $1 WHERE id = $2", base.Add(time.Duration(n)*time.Minute), job.ID.String()); err != nil {
+			t.Fatalf("Failed to set job created_at: %v", err)
+		}
+		return job
+	}
+
+	byDeployment := newJob("pending", map[string]interface{}{"deployment_id": deployment.ID.String(), "service_id": otherService.ID.String()})
+	byService := newJob("completed", map[string]interface{}{"service_id": service.ID.String()})
+	byDatabase := newJob("failed", map[string]interface{}{"database_id": database.ID.String()})
+	byVolume := newJob("pending", map[string]interface{}{"volume_id": volume.ID.String()})
+	byProject := newJob("dead_letter", map[string]interface{}{"project_id": project.ID.String()})
+	// Jobs of the other org, and jobs naming no resource of any org
+	newJob("pending", map[string]interface{}{"service_id": otherService.ID.String(), "project_id": project.ID.String()})
+	newJob("dead_letter", map[string]interface{}{"project_id": otherProject.ID.String()})
+	newJob("pending", map[string]interface{}{"n": 1})
+	// More of the other org's jobs than the limit, newer than the org's
+	for i := 0; i < 5; i++ {
+		newJob("pending", map[string]interface{}{"service_id": otherService.ID.String()})
+	}
+
+	ids := func(jobs []*Job) []string {
+		var ids []string
+		for _, j := range jobs {
+			ids = append(ids, j.ID.String())
+		}
+		return ids
+	}
+
+	jobs, err := dbStore.ListJobs(ctx, "test-org", "", "", 5)
+	if err != nil {
+		t.Fatalf("ListJobs failed: %v", err)
+	}
+	want := ids([]*Job{byProject, byVolume, byDatabase, byService, byDeployment})
+	if got := ids(jobs); len(got) != len(want) || strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("ListJobs = %v, want %v", got, want)
+	}
+
+	jobs, err = dbStore.ListJobs(ctx, "test-org", "", "pending", 5)
+	if err != nil {
+		t.Fatalf("ListJobs failed: %v", err)
+	}
+	if got := ids(jobs); strings.Join(got, ",") != strings.Join(ids([]*Job{byVolume, byDeployment}), ",") {
+		t.Errorf("ListJobs of pending jobs = %v", got)
+	}
+
+	jobs, err = dbStore.ListDeadLetterJobs(ctx, "test-org", "", 10)
+	if err != nil {
+		t.Fatalf("ListDeadLetterJobs failed: %v", err)
+	}
+	if got := ids(jobs); strings.Join(got, ",") != strings.Join(ids([]*Job{byProject, byDatabase}), ",") {
+		t.Errorf("ListDeadLetterJobs = %v", got)
+	}
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Kinds of a user's known devices
+const (
+	KnownDeviceIP        = "ip"
+	KnownDeviceUserAgent = "user_agent"
+)
+
+// SignInNovelty is what was new about a sign-in
+type SignInNovelty struct {
+	FirstSignIn  bool // The user had no known devices; nothing to compare against
+	NewIP        bool
+	NewUserAgent bool
+}
+
+// IsNew is whether the sign-in came from an IP or device the user hasn't signed in
+// from before. A user's first tracked sign-in isn't.
+func (n SignInNovelty) IsNew() bool {
+	return !n.FirstSignIn && (n.NewIP || n.NewUserAgent)
+}
+
+// RecordSignIn adds the IP and device of a sign-in to the user's known ones, and
+// returns which of them were new
+func (db *DB) RecordSignIn(ctx context.Context, userID string, session SessionInfo) (SignInNovelty, error) {
+	var novelty SignInNovelty
+
+	var known int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_known_devices WHERE user_id = $1", userID).Scan(&known)
+	if err != nil {
+		return novelty, err
+	}
+	novelty.FirstSignIn = known == 0
+
+	now := time.Now().UTC()
+	for _, d := range []struct {
+		kind  string
+		value string
+		isNew *bool
+	}{
+		{KnownDeviceIP, session.IPAddress, &novelty.NewIP},
+		{KnownDeviceUserAgent, session.UserAgent, &novelty.NewUserAgent},
+	} {
+		if d.value == "" {
+			continue
+		}
+
+		result, err := db.ExecContext(ctx, `
+			UPDATE user_known_devices SET last_seen_at = $1
+			WHERE user_id = $2 AND kind = $3 AND value = $4
+		`, now, userID, d.kind, d.value)
+		if err != nil {
+			return novelty, err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			continue
+		}
+
+		*d.isNew = true
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO user_known_devices (user_id, kind, value, first_seen_at, last_seen_at)
+			VALUES ($1, $2, $3, $4, $4)
+			ON CONFLICT (user_id, kind, value) DO UPDATE SET last_seen_at = excluded.last_seen_at
+		`, userID, d.kind, d.value, now)
+		if err != nil {
+			return novelty, err
+		}
+	}
+
+	return novelty, nil
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestDB_RecordSignIn(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+	userID := uuid.NewString()
+
+	signIn := func(ip, userAgent string) SignInNovelty {
+		novelty, err := dbStore.RecordSignIn(ctx, userID, SessionInfo{IPAddress: ip, UserAgent: userAgent})
+		if err != nil {
+			t.Fatalf("RecordSignIn error: %v", err)
+		}
+		return novelty
+	}
+
+	if n := signIn("203.0.113.7", "Firefox"); !n.FirstSignIn || n.IsNew() {
+		t.Errorf("first sign-in = %+v, want first and not new", n)
+	}
+	if n := signIn("203.0.113.7", "Firefox"); n.IsNew() {
+		t.Errorf("sign-in from a known device = %+v, want not new", n)
+	}
+	if n := signIn("198.51.100.2", "Firefox"); !n.IsNew() || !n.NewIP || n.NewUserAgent {
+		t.Errorf("sign-in from a new IP = %+v, want a new IP", n)
+	}
+	if n := signIn("203.0.113.7", "Safari"); !n.IsNew() || n.NewIP || !n.NewUserAgent {
+		t.Errorf("sign-in from a new device = %+v, want a new user agent", n)
+	}
+
+	// Devices are known per user
+	other, err := dbStore.RecordSignIn(ctx, uuid.NewString(), SessionInfo{IPAddress: "203.0.113.7", UserAgent: "Firefox"})
+	if err != nil {
+		t.Fatalf("RecordSignIn error: %v", err)
+	}
+	if !other.FirstSignIn {
+		t.Errorf("another user's sign-in = %+v, want their first", other)
+	}
+}
@@ -0,0 +1,38 @@
+package store
+
+import (
+	"fmt"
+)
+
+// ListParams pages and sorts a list query. The API parses them from the request and
+// validates Sort against the resource's sortable columns; the store checks it again
+// before it goes into the query.
+type ListParams struct {
+	Sort   string // Column to sort by
+	Desc   bool
+	Limit  int
+	Offset int
+}
+
+// Sortable columns of the resources whose lists can be sorted
+var (
+	DeploymentSortFields = []string{"created_at", "finished_at", "status"}
+)
+
+// orderBy returns the ORDER BY and paging clauses of the params, with the limit and
+// offset as the placeholders $next and $next+1. A sort column that isn't one of
+// columns falls back to the first of them. Rows with equal sort values keep the
+// order of their IDs, so pages don't overlap.
+func (p ListParams) orderBy(columns []string, next int) string {
+	column := columns[0]
+	for _, c := range columns {
+		if c == p.Sort {
+			column = c
+		}
+	}
+	direction := "ASC"
+	if p.Desc {
+		direction = "DESC"
+	}
+	return fmt.Sprintf("ORDER BY %s %s, id %s LIMIT $%d OFFSET $%d", column, direction, direction, next, next+1)
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// LoginThrottle is the policy failed logins are throttled by: MaxFailures failures
+// within Window lock the key out for BaseLockout, doubled for each lockout in a row
+// up to MaxLockout.
+type LoginThrottle struct {
+	MaxFailures int
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+// LoginAttempts is the failed login state of an email or client IP
+type LoginAttempts struct {
+	Key             string
+	Failures        int
+	WindowStartedAt time.Time
+	Lockouts        int
+	LockedUntil     sql.NullTime
+}
+
+// LoginEmailKey is the login attempts key of an email address
+func LoginEmailKey(email string) string {
+	return "email:" + email
+}
+
+// LoginIPKey is the login attempts key of a client IP
+func LoginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// GetLoginAttempts returns the failed login state of a key, or nil if it has none
+func (db *DB) GetLoginAttempts(ctx context.Context, key string) (*LoginAttempts, error) {
+	var a LoginAttempts
+	err := db.QueryRowContext(ctx, `
+		SELECT key, failures, window_started_at, lockouts, locked_until
+		FROM login_attempts
+		WHERE key = $1
+	`, key).Scan(&a.Key, &a.Failures, &a.WindowStartedAt, &a.Lockouts, &a.LockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// LoginLockedUntil returns when the last of the keys' lockouts ends, or the zero time
+// if none of them is locked out
+func (db *DB) LoginLockedUntil(ctx context.Context, keys ...string) (time.Time, error) {
+	var until time.Time
+	now := time.Now()
+	for _, key := range keys {
+		a, err := db.GetLoginAttempts(ctx, key)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if a != nil && a.LockedUntil.Valid && a.LockedUntil.Time.After(now) && a.LockedUntil.Time.After(until) {
+			until = a.LockedUntil.Time
+		}
+	}
+	return until, nil
+}
+
+// RecordLoginFailure counts a failed login against a key. When it reaches the
+// policy's failures within the window the key is locked out, and the end of the
+// lockout is returned; otherwise the zero time is. The failure is counted in one
+// upsert, so concurrent failures of a key each count.
+func (db *DB) RecordLoginFailure(ctx context.Context, key string, policy LoginThrottle) (time.Time, error) {
+	now := time.Now().UTC()
+	windowStart := now.Add(-policy.Window)
+
+	// A window that ended starts over; lockouts only escalate while the failures
+	// keep coming
+	var failures, lockouts int
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO login_attempts (key, failures, window_started_at, lockouts, locked_until, updated_at)
+		VALUES ($1, 1, $2, 0, NULL, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.window_started_at <= $3 THEN 1
+				ELSE login_attempts.failures + 1 END,
+			lockouts = CASE WHEN login_attempts.window_started_at <= $3
+				AND (login_attempts.locked_until IS NULL OR login_attempts.locked_until <= $3) THEN 0
+				ELSE login_attempts.lockouts END,
+			window_started_at = CASE WHEN login_attempts.window_started_at <= $3 THEN $2
+				ELSE login_attempts.window_started_at END,
+			updated_at = $2
+		RETURNING failures, lockouts
+	`, key, now, windowStart).Scan(&failures, &lockouts)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if failures < policy.MaxFailures {
+		return time.Time{}, nil
+	}
+
+	// Of concurrent failures reaching the limit, the first one locks out
+	lockedUntil := now.Add(lockoutDuration(policy, lockouts))
+	result, err := db.ExecContext(ctx, `
+		UPDATE login_attempts
+		SET failures = 0, window_started_at = $1, lockouts = lockouts + 1, locked_until = $2, updated_at = $1
+		WHERE key = $3 AND lockouts = $4 AND failures >= $5
+	`, now, lockedUntil, key, lockouts, policy.MaxFailures)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return time.Time{}, err
+	}
+	return lockedUntil, nil
+}
+
+// lockoutDuration is how long the lockout following the given number of previous
+// ones lasts
+func lockoutDuration(policy LoginThrottle, previous int) time.Duration {
+	d := policy.BaseLockout
+	for i := 0; i < previous && d < policy.MaxLockout; i++ {
+		d *= 2
+	}
+	if policy.MaxLockout > 0 && d > policy.MaxLockout {
+		d = policy.MaxLockout
+	}
+	return d
+}
+
+// ResetLoginAttempts clears the failed logins of a key
+func (db *DB) ResetLoginAttempts(ctx context.Context, key string) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM login_attempts WHERE key = $1", key)
+	return err
+}
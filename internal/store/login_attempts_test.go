@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestDB_LoginAttempts(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+	policy := LoginThrottle{MaxFailures: 3, Window: time.Hour, BaseLockout: time.Minute, MaxLockout: 3 * time.Minute}
+	key := LoginEmailKey("alice@example.com")
+
+	fail := func() time.Time {
+		until, err := dbStore.RecordLoginFailure(ctx, key, policy)
+		if err != nil {
+			t.Fatalf("RecordLoginFailure error: %v", err)
+		}
+		return until
+	}
+
+	// Failures below the limit don't lock out
+	for i := 0; i < 2; i++ {
+		if until := fail(); !until.IsZero() {
+			t.Fatalf("failure %d locked out until %s", i+1, until)
+		}
+	}
+	if until, _ := dbStore.LoginLockedUntil(ctx, key); !until.IsZero() {
+		t.Fatalf("LoginLockedUntil = %s before the limit", until)
+	}
+
+	// Each lockout in a row doubles, up to the maximum
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute} {
+		if i > 0 {
+			fail()
+			fail()
+		}
+		until := fail()
+		if got := time.Until(until); got < want-5*time.Second || got > want {
+			t.Errorf("lockout %d = %s, want %s", i+1, got, want)
+		}
+	}
+
+	locked, err := dbStore.LoginLockedUntil(ctx, LoginIPKey("203.0.113.7"), key)
+	if err != nil {
+		t.Fatalf("LoginLockedUntil error: %v", err)
+	}
+	if locked.IsZero() {
+		t.Error("LoginLockedUntil = zero, want the email's lockout")
+	}
+
+	if err := dbStore.ResetLoginAttempts(ctx, key); err != nil {
+		t.Fatalf("ResetLoginAttempts error: %v", err)
+	}
+	if a, _ := dbStore.GetLoginAttempts(ctx, key); a != nil {
+		t.Errorf("GetLoginAttempts after reset = %+v, want nil", a)
+	}
+}
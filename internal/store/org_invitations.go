@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvitationUsed is returned when accepting an invitation that was already accepted
+var ErrInvitationUsed = errors.New("invitation was already accepted")
+
+// OrgInvitation is an invitation for an email address to join an organization
+type OrgInvitation struct {
+	ID         string         `json:"id"`
+	OrgID      string         `json:"org_id"`
+	Email      string         `json:"email"`
+	Role       string         `json:"role"`
+	InvitedBy  sql.NullString `json:"-"`
+	ExpiresAt  time.Time      `json:"expires_at"`
+	AcceptedAt sql.NullTime   `json:"-"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// Expired reports whether the invitation can no longer be accepted
+func (i *OrgInvitation) Expired() bool {
+	return time.Now().After(i.ExpiresAt)
+}
+
+// generateInvitationToken generates a random invitation token
+func generateInvitationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateOrgInvitation invites an email address to join an organization with a role.
+// It returns the invitation and its token; only the token's hash is stored.
+func (db *DB) CreateOrgInvitation(ctx context.Context, orgID, email, role, invitedBy string, expiresIn time.Duration) (*OrgInvitation, string, error) {
+	token, err := generateInvitationToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+
+	query := `
+		INSERT INTO org_invitations (org_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, org_id, email, role, invited_by, expires_at, accepted_at, created_at
+	`
+
+	inv, err := scanOrgInvitation(db.QueryRowContext(ctx, query,
+		orgID, strings.ToLower(email), role, hashToken(token), StringToNullString(invitedBy), time.Now().Add(expiresIn),
+	))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return inv, token, nil
+}
+
+// GetOrgInvitationByToken retrieves an invitation by its token (hashes and looks up).
+// Returns nil if no invitation has the token.
+func (db *DB) GetOrgInvitationByToken(ctx context.Context, token string) (*OrgInvitation, error) {
+	query := `
+		SELECT id, org_id, email, role, invited_by, expires_at, accepted_at, created_at
+		FROM org_invitations
+		WHERE token_hash = $1
+	`
+
+	inv, err := scanOrgInvitation(db.QueryRowContext(ctx, query, hashToken(token)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	return inv, nil
+}
+
+// AcceptOrgInvitation marks an invitation accepted and adds the user to its
+// organization. A user who already is a member keeps their current role.
+// Returns ErrInvitationUsed if the invitation was already accepted.
+func (db *DB) AcceptOrgInvitation(ctx context.Context, inv *OrgInvitation, userID string) (*OrgMember, error) {
+	// The invitation is only used up if the member is added
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Claim the invitation first, so that it can't be accepted twice
+	result, err := tx.ExecContext(ctx,
+		"UPDATE org_invitations SET accepted_at = CURRENT_TIMESTAMP WHERE id = $1 AND accepted_at IS NULL",
+		inv.ID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, ErrInvitationUsed
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO org_members (org_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (org_id, user_id) DO NOTHING
+	`, inv.OrgID, userID, inv.Role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add org member: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return db.GetOrgMember(ctx, inv.OrgID, userID)
+}
+
+func scanOrgInvitation(row *sql.Row) (*OrgInvitation, error) {
+	var inv OrgInvitation
+	err := row.Scan(
+		&inv.ID,
+		&inv.OrgID,
+		&inv.Email,
+		&inv.Role,
+		&inv.InvitedBy,
+		&inv.ExpiresAt,
+		&inv.AcceptedAt,
+		&inv.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
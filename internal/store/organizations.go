@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -19,6 +20,13 @@ type Organization struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Organization member roles
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
 // OrgMember represents a member of an organization
 type OrgMember struct {
 	OrgID    string    `json:"org_id"`
@@ -202,6 +210,23 @@ func (db *DB) DeleteOrganization(ctx context.Context, id string) error {
 	return nil
 }
 
+// GetOrgPlan returns the plan of an organization.
+// Returns an empty string if the organization has no plan or does not exist.
+func (db *DB) GetOrgPlan(ctx context.Context, orgID string) (string, error) {
+	var plan sql.NullString
+	query := `SELECT plan FROM organizations WHERE id = $1`
+
+	err := db.QueryRowContext(ctx, query, orgID).Scan(&plan)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get organization plan: %w", err)
+	}
+
+	return plan.String, nil
+}
+
 // AddOrgMember adds a user to an organization
 func (db *DB) AddOrgMember(ctx context.Context, orgID, userID, role string) (*OrgMember, error) {
 	query := `
@@ -286,10 +311,28 @@ func (db *DB) ListOrgMembers(ctx context.Context, orgID string) ([]*OrgMemberWit
 	return members, nil
 }
 
-// RemoveOrgMember removes a user from an organization
+// ErrLastOrgOwner is returned when a change would leave an organization without an
+// owner
+var ErrLastOrgOwner = errors.New("an organization must keep at least one owner")
+
+// keepsOrgOwner is the condition for a member to stop being an owner: they aren't
+// one, or the organization has another
+const keepsOrgOwner = `(role <> 'owner' OR EXISTS (
+	SELECT 1 FROM org_members o
+	WHERE o.org_id = org_members.org_id AND o.user_id <> org_members.user_id AND o.role = 'owner'
+))`
+
+// RemoveOrgMember removes a user from an organization. Returns ErrLastOrgOwner if
+// they are its last owner.
 func (db *DB) RemoveOrgMember(ctx context.Context, orgID, userID string) error {
-	query := `DELETE FROM org_members WHERE org_id = $1 AND user_id = $2`
-	result, err := db.ExecContext(ctx, query, orgID, userID)
+	tx, err := db.lockOrgOwners(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM org_members WHERE org_id = $1 AND user_id = $2 AND ` + keepsOrgOwner
+	result, err := tx.ExecContext(ctx, query, orgID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to remove org member: %w", err)
 	}
@@ -300,12 +343,84 @@ func (db *DB) RemoveOrgMember(ctx context.Context, orgID, userID string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("member not found")
+		return unchangedOrgMemberError(ctx, tx, orgID, userID)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to remove org member: %w", err)
+	}
 	return nil
 }
 
+// UpdateOrgMemberRole changes a member's role in an organization. Returns
+// ErrLastOrgOwner if they are its last owner and the role isn't owner.
+func (db *DB) UpdateOrgMemberRole(ctx context.Context, orgID, userID, role string) (*OrgMember, error) {
+	tx, err := db.lockOrgOwners(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE org_members SET role = $1
+		WHERE org_id = $2 AND user_id = $3 AND ($1 = 'owner' OR ` + keepsOrgOwner + `)
+		RETURNING org_id, user_id, role, joined_at
+	`
+
+	var member OrgMember
+	err = tx.QueryRowContext(ctx, query, role, orgID, userID).Scan(
+		&member.OrgID,
+		&member.UserID,
+		&member.Role,
+		&member.JoinedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, unchangedOrgMemberError(ctx, tx, orgID, userID)
+		}
+		return nil, fmt.Errorf("failed to update org member role: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to update org member role: %w", err)
+	}
+	return &member, nil
+}
+
+// lockOrgOwners begins a transaction holding the lock on an organization's owners, a
+// transaction-scoped advisory lock on Postgres (SQLite serializes writers), so two
+// owners stepping down at once can't both count the other as the one left
+func (db *DB) lockOrgOwners(ctx context.Context, orgID string) (*sql.Tx, error) {
+	var version string
+	isSQLite := db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&version) == nil
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if !isSQLite {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('org_owners:' || $1))", orgID); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to lock org owners: %w", err)
+		}
+	}
+	return tx, nil
+}
+
+// unchangedOrgMemberError tells why a member change touched no row: the member was
+// the organization's last owner, or isn't a member
+func unchangedOrgMemberError(ctx context.Context, tx *sql.Tx, orgID, userID string) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM org_members WHERE org_id = $1 AND user_id = $2)`, orgID, userID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check org membership: %w", err)
+	}
+	if exists {
+		return ErrLastOrgOwner
+	}
+	return fmt.Errorf("member not found")
+}
+
 // IsUserInOrg checks if a user is a member of an organization
 func (db *DB) IsUserInOrg(ctx context.Context, orgID, userID string) (bool, error) {
 	query := `SELECT EXISTS(SELECT 1 FROM org_members WHERE org_id = $1 AND user_id = $2)`
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cloud resource types tracked by orphan reconciliation
+const (
+	CloudResourceInstance      = "instance"
+	CloudResourceFloatingIP    = "floating_ip"
+	CloudResourceSecurityGroup = "security_group"
+	CloudResourceVolume        = "volume"
+)
+
+// ownedCloudResourceQueries select the IDs of the cloud resources that records point at
+var ownedCloudResourceQueries = map[string]string{
+	CloudResourceInstance: `
+		SELECT openstack_instance_id FROM services WHERE openstack_instance_id IS NOT NULL
+		UNION SELECT openstack_instance_id FROM databases WHERE openstack_instance_id IS NOT NULL`,
+	CloudResourceFloatingIP: `
+		SELECT openstack_fip_id FROM services WHERE openstack_fip_id IS NOT NULL`,
+	CloudResourceSecurityGroup: `
+		SELECT security_group_id FROM services WHERE security_group_id IS NOT NULL
+		UNION SELECT security_group_id FROM databases WHERE security_group_id IS NOT NULL`,
+	CloudResourceVolume: `
+		SELECT openstack_volume_id FROM volumes WHERE openstack_volume_id IS NOT NULL`,
+}
+
+// ListOwnedCloudResources returns the IDs of the cloud resources of each type that
+// a service, database or volume record points at
+func (db *DB) ListOwnedCloudResources(ctx context.Context) (map[string]map[string]bool, error) {
+	owned := make(map[string]map[string]bool, len(ownedCloudResourceQueries))
+	for resourceType, query := range ownedCloudResourceQueries {
+		ids, err := db.queryIDSet(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list owned %s resources: %w", resourceType, err)
+		}
+		owned[resourceType] = ids
+	}
+	return owned, nil
+}
+
+func (db *DB) queryIDSet(ctx context.Context, query string) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		if id != "" {
+			ids[id] = true
+		}
+	}
+	return ids, rows.Err()
+}
+
+// RecordOrphanedCloudResource flags a cloud resource without an owning record, or
+// refreshes the flag if it was already orphaned. It returns when the resource was
+// first found orphaned.
+func (db *DB) RecordOrphanedCloudResource(ctx context.Context, tenantID, resourceType, resourceID, name string, seenAt time.Time) (time.Time, error) {
+	query := `
+		INSERT INTO orphaned_cloud_resources (id, tenant_id, resource_type, resource_id, name, first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		ON CONFLICT (resource_type, resource_id) DO UPDATE SET last_seen_at = $6
+	`
+	if _, err := db.ExecContext(ctx, query, uuid.New().String(), tenantID, resourceType, resourceID, StringToNullString(name), seenAt); err != nil {
+		return time.Time{}, err
+	}
+
+	var firstSeen time.Time
+	err := db.QueryRowContext(ctx,
+		"SELECT first_seen_at FROM orphaned_cloud_resources WHERE resource_type = $1 AND resource_id = $2",
+		resourceType, resourceID,
+	).Scan(&firstSeen)
+	return firstSeen, err
+}
+
+// PruneOrphanedCloudResources removes the flags of a tenant's resources of a type that
+// weren't seen orphaned since seenSince: they were deleted or got an owner again
+func (db *DB) PruneOrphanedCloudResources(ctx context.Context, tenantID, resourceType string, seenSince time.Time) error {
+	_, err := db.ExecContext(ctx,
+		"DELETE FROM orphaned_cloud_resources WHERE tenant_id = $1 AND resource_type = $2 AND last_seen_at < $3",
+		tenantID, resourceType, seenSince,
+	)
+	return err
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestDB_OrphanedCloudResources(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := &Project{CasdoorOrgID: "org-a", Name: "Project", Slug: "project", OpenStackTenantID: "tenant-a"}
+	if err := dbStore.CreateProject(ctx, project); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	service := &Service{ProjectID: project.ID, Name: "Service", Type: "app", Status: "running", InstanceSize: "small", Port: 8080}
+	if err := dbStore.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE services SET openstack_instance_id = 'instance-1', openstack_fip_id = 'fip-1' WHERE id = $1", service.ID); err != nil {
+		t.Fatalf("Failed to set cloud resource IDs: %v", err)
+	}
+
+	owned, err := dbStore.ListOwnedCloudResources(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list owned cloud resources: %v", err)
+	}
+	if !owned[CloudResourceInstance]["instance-1"] || !owned[CloudResourceFloatingIP]["fip-1"] {
+		t.Errorf("Expected the service's instance and floating IP to be owned, got %v", owned)
+	}
+	if len(owned[CloudResourceVolume]) != 0 {
+		t.Errorf("Expected no owned volumes, got %v", owned[CloudResourceVolume])
+	}
+
+	firstRun := time.Now().Add(-time.Hour).Truncate(time.Second)
+	secondRun := firstRun.Add(time.Hour)
+
+	for _, id := range []string{"instance-2", "instance-3"} {
+		if _, err := dbStore.RecordOrphanedCloudResource(ctx, "tenant-a", CloudResourceInstance, id, "leaked", firstRun); err != nil {
+			t.Fatalf("Failed to record orphan: %v", err)
+		}
+	}
+
+	// Seen again on the next run: first_seen_at is kept
+	firstSeen, err := dbStore.RecordOrphanedCloudResource(ctx, "tenant-a", CloudResourceInstance, "instance-2", "leaked", secondRun)
+	if err != nil {
+		t.Fatalf("Failed to record orphan: %v", err)
+	}
+	if !firstSeen.Equal(firstRun) {
+		t.Errorf("Expected first seen %v to be kept, got %v", firstRun, firstSeen)
+	}
+
+	// instance-3 wasn't seen on the second run, so its flag goes
+	if err := dbStore.PruneOrphanedCloudResources(ctx, "tenant-a", CloudResourceInstance, secondRun); err != nil {
+		t.Fatalf("Failed to prune orphans: %v", err)
+	}
+	var remaining []string
+	rows, err := db.QueryContext(ctx, "SELECT resource_id FROM orphaned_cloud_resources ORDER BY resource_id")
+	if err != nil {
+		t.Fatalf("Failed to query orphans: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		rows.Scan(&id)
+		remaining = append(remaining, id)
+	}
+	if len(remaining) != 1 || remaining[0] != "instance-2" {
+		t.Errorf("Expected only instance-2 to stay flagged, got %v", remaining)
+	}
+}
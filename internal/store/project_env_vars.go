@@ -0,0 +1,266 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProjectEnvVar is an environment variable scoped to a project.
+// Plain values are inherited by every service in the project, and service-level
+// env vars with the same key override them. Database-linked values are shared
+// secrets: services only receive them by referencing them from an EnvVar.
+type ProjectEnvVar struct {
+	ID               uuid.UUID
+	ProjectID        uuid.UUID
+	Key              string
+	Value            sql.NullString // NULL if linked to database
+	IsSecret         bool
+	LinkedDatabaseID sql.NullString
+	LinkType         sql.NullString // connection_url, host, port, username, password, database
+	Interpolate      bool           // ${VAR} references in Value are expanded at deploy time; never for secrets
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// IsShared reports whether the variable is a database-linked shared secret
+// that services must opt into by reference
+func (ev *ProjectEnvVar) IsShared() bool {
+	return ev.LinkedDatabaseID.Valid
+}
+
+// CreateProjectEnvVar creates a new project-level environment variable
+func (db *DB) CreateProjectEnvVar(ctx context.Context, ev *ProjectEnvVar) error {
+	// Generate UUID if not set (for SQLite compatibility)
+	if ev.ID == uuid.Nil {
+		ev.ID = uuid.New()
+	}
+
+	// Check if we're using SQLite (for compatibility)
+	var isSQLite bool
+	var versionStr string
+	err := db.QueryRow("SELECT sqlite_version()").Scan(&versionStr)
+	isSQLite = err == nil
+
+	var value interface{}
+	if ev.Value.Valid {
+		value = ev.Value.String
+	}
+
+	var linkedDatabaseID interface{}
+	if ev.LinkedDatabaseID.Valid {
+		linkedDatabaseID = ev.LinkedDatabaseID.String
+	}
+
+	var linkType interface{}
+	if ev.LinkType.Valid {
+		linkType = ev.LinkType.String
+	}
+
+	if isSQLite {
+		// SQLite: Insert with explicit UUID (no RETURNING support in older versions)
+		isSecret := 0
+		if ev.IsSecret {
+			isSecret = 1
+		}
+		interpolate := 0
+		if ev.Interpolate {
+			interpolate = 1
+		}
+		query := `
+			INSERT INTO project_env_vars (id, project_id, key, value, is_secret, linked_database_id, link_type, interpolate)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`
+		_, err = db.ExecContext(ctx, query,
+			ev.ID.String(), ev.ProjectID.String(), ev.Key, value, isSecret, linkedDatabaseID, linkType, interpolate,
+		)
+		if err != nil {
+			return err
+		}
+		// Get timestamps
+		err = db.QueryRowContext(ctx, "SELECT created_at, updated_at FROM project_env_vars WHERE id = $1", ev.ID.String()).
+			Scan(&ev.CreatedAt, &ev.UpdatedAt)
+		return err
+	}
+
+	// PostgreSQL: Use RETURNING clause
+	query := `
+		INSERT INTO project_env_vars (project_id, key, value, is_secret, linked_database_id, link_type, interpolate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`
+
+	return db.QueryRowContext(ctx, query,
+		ev.ProjectID,
+		ev.Key,
+		value,
+		ev.IsSecret,
+		linkedDatabaseID,
+		linkType,
+		ev.Interpolate,
+	).Scan(&ev.ID, &ev.CreatedAt, &ev.UpdatedAt)
+}
+
+// GetProjectEnvVar retrieves a project-level environment variable by ID
+func (db *DB) GetProjectEnvVar(ctx context.Context, id uuid.UUID) (*ProjectEnvVar, error) {
+	query := `
+		SELECT id, project_id, key, value, is_secret, linked_database_id, link_type, interpolate, created_at, updated_at
+		FROM project_env_vars
+		WHERE id = $1
+	`
+
+	var ev ProjectEnvVar
+	err := db.QueryRowContext(ctx, query, id).Scan(
+		&ev.ID,
+		&ev.ProjectID,
+		&ev.Key,
+		&ev.Value,
+		&ev.IsSecret,
+		&ev.LinkedDatabaseID,
+		&ev.LinkType,
+		&ev.Interpolate,
+		&ev.CreatedAt,
+		&ev.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ev, nil
+}
+
+// GetProjectEnvVarByKey retrieves a project-level environment variable by key
+func (db *DB) GetProjectEnvVarByKey(ctx context.Context, projectID uuid.UUID, key string) (*ProjectEnvVar, error) {
+	query := `
+		SELECT id, project_id, key, value, is_secret, linked_database_id, link_type, interpolate, created_at, updated_at
+		FROM project_env_vars
+		WHERE project_id = $1 AND key = $2
+	`
+
+	var ev ProjectEnvVar
+	err := db.QueryRowContext(ctx, query, projectID, key).Scan(
+		&ev.ID,
+		&ev.ProjectID,
+		&ev.Key,
+		&ev.Value,
+		&ev.IsSecret,
+		&ev.LinkedDatabaseID,
+		&ev.LinkType,
+		&ev.Interpolate,
+		&ev.CreatedAt,
+		&ev.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ev, nil
+}
+
+// ListProjectEnvVars lists the project-level environment variables of a project
+func (db *DB) ListProjectEnvVars(ctx context.Context, projectID uuid.UUID) ([]*ProjectEnvVar, error) {
+	query := `
+		SELECT id, project_id, key, value, is_secret, linked_database_id, link_type, interpolate, created_at, updated_at
+		FROM project_env_vars
+		WHERE project_id = $1
+		ORDER BY key ASC
+	`
+
+	rows, err := db.QueryContext(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var envVars []*ProjectEnvVar
+	for rows.Next() {
+		var ev ProjectEnvVar
+		err := rows.Scan(
+			&ev.ID,
+			&ev.ProjectID,
+			&ev.Key,
+			&ev.Value,
+			&ev.IsSecret,
+			&ev.LinkedDatabaseID,
+			&ev.LinkType,
+			&ev.Interpolate,
+			&ev.CreatedAt,
+			&ev.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		envVars = append(envVars, &ev)
+	}
+
+	return envVars, rows.Err()
+}
+
+// UpdateProjectEnvVar updates a project-level environment variable
+func (db *DB) UpdateProjectEnvVar(ctx context.Context, id uuid.UUID, ev *ProjectEnvVar) error {
+	query := `
+		UPDATE project_env_vars
+		SET value = $1, is_secret = $2, linked_database_id = $3, link_type = $4, interpolate = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6
+	`
+
+	var value interface{}
+	if ev.Value.Valid {
+		value = ev.Value.String
+	}
+
+	var linkedDatabaseID interface{}
+	if ev.LinkedDatabaseID.Valid {
+		linkedDatabaseID = ev.LinkedDatabaseID.String
+	}
+
+	var linkType interface{}
+	if ev.LinkType.Valid {
+		linkType = ev.LinkType.String
+	}
+
+	result, err := db.ExecContext(ctx, query, value, ev.IsSecret, linkedDatabaseID, linkType, ev.Interpolate, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteProjectEnvVar deletes a project-level environment variable
+func (db *DB) DeleteProjectEnvVar(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM project_env_vars WHERE id = $1`
+
+	result, err := db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
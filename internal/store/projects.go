@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -11,20 +12,24 @@ import (
 )
 
 type Project struct {
-	ID                 uuid.UUID
-	CasdoorOrgID       string
-	Name               string
-	Slug               string
-	Description        sql.NullString
-	OpenStackTenantID  string
-	OpenStackNetworkID sql.NullString
-	DefaultRegion      sql.NullString
-	AutoDeploy         bool
-	CreatedBy          sql.NullString
-	CreatedAt          time.Time
-	UpdatedAt          time.Time
-	OrgID              uuid.NullUUID // Custom auth organization ID
-	UserID             uuid.NullUUID // Custom auth user ID
+	ID                  uuid.UUID
+	CasdoorOrgID        string
+	Name                string
+	Slug                string
+	Description         sql.NullString
+	OpenStackTenantID   string
+	OpenStackNetworkID  sql.NullString
+	DefaultRegion       sql.NullString
+	AutoDeploy          bool
+	ImageRetentionCount sql.NullInt64  // successful deployment images kept in the registry (NULL = default)
+	ImageTagStrategy    sql.NullString // how build images are tagged, see build.ImageTagStrategies (NULL = commit)
+	DefaultInstanceSize sql.NullString // instance size of new services that don't set one (NULL = medium)
+	DefaultPort         sql.NullInt64  // port of new services that don't set one (NULL = 8080)
+	CreatedBy           sql.NullString
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	OrgID               uuid.NullUUID // Custom auth organization ID
+	UserID              uuid.NullUUID // Custom auth user ID
 }
 
 func (db *DB) CreateProject(ctx context.Context, p *Project) error {
@@ -45,13 +50,15 @@ func (db *DB) CreateProject(ctx context.Context, p *Project) error {
 			INSERT INTO projects (
 				id, casdoor_org_id, name, slug, description,
 				openstack_tenant_id, openstack_network_id,
-				default_region, auto_deploy, created_by, org_id, user_id
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+				default_region, auto_deploy, image_tag_strategy, default_instance_size, default_port,
+				created_by, org_id, user_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 		`
 		_, err = db.ExecContext(ctx, query,
 			p.ID.String(), p.CasdoorOrgID, p.Name, p.Slug, p.Description,
 			p.OpenStackTenantID, p.OpenStackNetworkID,
-			p.DefaultRegion, p.AutoDeploy, p.CreatedBy, p.OrgID, p.UserID,
+			p.DefaultRegion, p.AutoDeploy, p.ImageTagStrategy, p.DefaultInstanceSize, p.DefaultPort,
+			p.CreatedBy, p.OrgID, p.UserID,
 		)
 		if err != nil {
 			return err
@@ -67,15 +74,17 @@ func (db *DB) CreateProject(ctx context.Context, p *Project) error {
 		INSERT INTO projects (
 			casdoor_org_id, name, slug, description,
 			openstack_tenant_id, openstack_network_id,
-			default_region, auto_deploy, created_by, org_id, user_id
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			default_region, auto_deploy, image_tag_strategy, default_instance_size, default_port,
+			created_by, org_id, user_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		RETURNING id, created_at, updated_at
 	`
 
 	err = db.QueryRowContext(ctx, query,
 		p.CasdoorOrgID, p.Name, p.Slug, p.Description,
 		p.OpenStackTenantID, p.OpenStackNetworkID,
-		p.DefaultRegion, p.AutoDeploy, p.CreatedBy, p.OrgID, p.UserID,
+		p.DefaultRegion, p.AutoDeploy, p.ImageTagStrategy, p.DefaultInstanceSize, p.DefaultPort,
+		p.CreatedBy, p.OrgID, p.UserID,
 	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
 
 	return err
@@ -83,12 +92,13 @@ func (db *DB) CreateProject(ctx context.Context, p *Project) error {
 
 func (db *DB) GetProject(ctx context.Context, id uuid.UUID) (*Project, error) {
 	var p Project
-	query := `SELECT id, casdoor_org_id, name, slug, description, openstack_tenant_id, openstack_network_id, default_region, auto_deploy, created_by, created_at, updated_at, org_id, user_id FROM projects WHERE id = $1`
+	query := `SELECT id, casdoor_org_id, name, slug, description, openstack_tenant_id, openstack_network_id, default_region, auto_deploy, image_retention_count, image_tag_strategy, default_instance_size, default_port, created_by, created_at, updated_at, org_id, user_id FROM projects WHERE id = $1`
 
 	err := db.QueryRowContext(ctx, query, id).Scan(
 		&p.ID, &p.CasdoorOrgID, &p.Name, &p.Slug, &p.Description,
 		&p.OpenStackTenantID, &p.OpenStackNetworkID,
-		&p.DefaultRegion, &p.AutoDeploy, &p.CreatedBy,
+		&p.DefaultRegion, &p.AutoDeploy, &p.ImageRetentionCount, &p.ImageTagStrategy,
+			&p.DefaultInstanceSize, &p.DefaultPort, &p.CreatedBy,
 		&p.CreatedAt, &p.UpdatedAt, &p.OrgID, &p.UserID,
 	)
 
@@ -100,7 +110,7 @@ func (db *DB) GetProject(ctx context.Context, id uuid.UUID) (*Project, error) {
 }
 
 func (db *DB) ListProjectsByOrg(ctx context.Context, orgID string) ([]*Project, error) {
-	query := `SELECT id, casdoor_org_id, name, slug, description, openstack_tenant_id, openstack_network_id, default_region, auto_deploy, created_by, created_at, updated_at, org_id, user_id FROM projects WHERE casdoor_org_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, casdoor_org_id, name, slug, description, openstack_tenant_id, openstack_network_id, default_region, auto_deploy, image_retention_count, image_tag_strategy, default_instance_size, default_port, created_by, created_at, updated_at, org_id, user_id FROM projects WHERE casdoor_org_id = $1 ORDER BY created_at DESC`
 
 	rows, err := db.QueryContext(ctx, query, orgID)
 	if err != nil {
@@ -119,7 +129,8 @@ func (db *DB) ListProjectsByOrg(ctx context.Context, orgID string) ([]*Project,
 		err := rows.Scan(
 			&p.ID, &p.CasdoorOrgID, &p.Name, &p.Slug, &p.Description,
 			&p.OpenStackTenantID, &p.OpenStackNetworkID,
-			&p.DefaultRegion, &p.AutoDeploy, &p.CreatedBy,
+			&p.DefaultRegion, &p.AutoDeploy, &p.ImageRetentionCount, &p.ImageTagStrategy,
+			&p.DefaultInstanceSize, &p.DefaultPort, &p.CreatedBy,
 			&p.CreatedAt, &p.UpdatedAt, &p.OrgID, &p.UserID,
 		)
 		if err != nil {
@@ -137,7 +148,7 @@ func (db *DB) ListProjectsByOrg(ctx context.Context, orgID string) ([]*Project,
 
 // ListProjectsByOrgID lists projects by the new org_id column (for custom auth)
 func (db *DB) ListProjectsByOrgID(ctx context.Context, orgID uuid.UUID) ([]*Project, error) {
-	query := `SELECT id, casdoor_org_id, name, slug, description, openstack_tenant_id, openstack_network_id, default_region, auto_deploy, created_by, created_at, updated_at, org_id, user_id FROM projects WHERE org_id = $1 ORDER BY created_at DESC`
+	query := `SELECT id, casdoor_org_id, name, slug, description, openstack_tenant_id, openstack_network_id, default_region, auto_deploy, image_retention_count, image_tag_strategy, default_instance_size, default_port, created_by, created_at, updated_at, org_id, user_id FROM projects WHERE org_id = $1 ORDER BY created_at DESC`
 
 	rows, err := db.QueryContext(ctx, query, orgID)
 	if err != nil {
@@ -151,7 +162,41 @@ func (db *DB) ListProjectsByOrgID(ctx context.Context, orgID uuid.UUID) ([]*Proj
 		err := rows.Scan(
 			&p.ID, &p.CasdoorOrgID, &p.Name, &p.Slug, &p.Description,
 			&p.OpenStackTenantID, &p.OpenStackNetworkID,
-			&p.DefaultRegion, &p.AutoDeploy, &p.CreatedBy,
+			&p.DefaultRegion, &p.AutoDeploy, &p.ImageRetentionCount, &p.ImageTagStrategy,
+			&p.DefaultInstanceSize, &p.DefaultPort, &p.CreatedBy,
+			&p.CreatedAt, &p.UpdatedAt, &p.OrgID, &p.UserID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project row: %w", err)
+		}
+		projects = append(projects, &p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating project rows: %w", err)
+	}
+
+	return projects, nil
+}
+
+// ListAllProjects lists every project, for background jobs that run across orgs
+func (db *DB) ListAllProjects(ctx context.Context) ([]*Project, error) {
+	query := `SELECT id, casdoor_org_id, name, slug, description, openstack_tenant_id, openstack_network_id, default_region, auto_deploy, image_retention_count, image_tag_strategy, default_instance_size, default_port, created_by, created_at, updated_at, org_id, user_id FROM projects ORDER BY created_at ASC`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []*Project
+	for rows.Next() {
+		var p Project
+		err := rows.Scan(
+			&p.ID, &p.CasdoorOrgID, &p.Name, &p.Slug, &p.Description,
+			&p.OpenStackTenantID, &p.OpenStackNetworkID,
+			&p.DefaultRegion, &p.AutoDeploy, &p.ImageRetentionCount, &p.ImageTagStrategy,
+			&p.DefaultInstanceSize, &p.DefaultPort, &p.CreatedBy,
 			&p.CreatedAt, &p.UpdatedAt, &p.OrgID, &p.UserID,
 		)
 		if err != nil {
@@ -176,8 +221,12 @@ func (db *DB) UpdateProject(ctx context.Context, id uuid.UUID, updates *Project)
 		    description = $3,
 		    default_region = $4,
 		    auto_deploy = $5,
+		    image_retention_count = $6,
+		    image_tag_strategy = $7,
+		    default_instance_size = $8,
+		    default_port = $9,
 		    updated_at = now()
-		WHERE id = $6 AND casdoor_org_id = $7
+		WHERE id = $10 AND casdoor_org_id = $11
 		RETURNING updated_at
 	`
 
@@ -187,6 +236,10 @@ func (db *DB) UpdateProject(ctx context.Context, id uuid.UUID, updates *Project)
 		updates.Description,
 		updates.DefaultRegion,
 		updates.AutoDeploy,
+		updates.ImageRetentionCount,
+		updates.ImageTagStrategy,
+		updates.DefaultInstanceSize,
+		updates.DefaultPort,
 		id,
 		updates.CasdoorOrgID,
 	).Scan(&updates.UpdatedAt)
@@ -215,6 +268,134 @@ func (db *DB) DeleteProject(ctx context.Context, id uuid.UUID, orgID string) err
 	return nil
 }
 
+// ErrProjectHasSecretRefs is returned when transferring a project whose env vars
+// reference secrets of its organization
+var ErrProjectHasSecretRefs = errors.New("project has env vars referencing its organization's secrets")
+
+// TransferProject moves a project from one organization to another under a new slug.
+// It returns sql.ErrNoRows if the project doesn't belong to fromOrgID.
+//
+// Git sources use git connections of the source org, which the project can't keep.
+// In the same transaction, each one is re-bound to a connection of the target org
+// to the same provider, preferring one of the same account, and detached from its
+// service when the target org has none. It returns the number of git sources detached.
+// Secret references point into the source org's Vault path, a project with any is
+// refused with ErrProjectHasSecretRefs.
+func (db *DB) TransferProject(ctx context.Context, id uuid.UUID, fromOrgID, toOrgID, slug string) (int64, error) {
+	// Custom auth orgs are also referenced by UUID
+	var orgUUID uuid.NullUUID
+	if parsed, err := uuid.Parse(toOrgID); err == nil {
+		orgUUID = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE projects
+		SET casdoor_org_id = $1, org_id = $2, slug = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND casdoor_org_id = $5
+	`
+	result, err := tx.ExecContext(ctx, query, toOrgID, orgUUID, slug, id, fromOrgID)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected == 0 {
+		return 0, sql.ErrNoRows
+	}
+
+	var secretRefs int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM env_vars
+		WHERE secret_ref IS NOT NULL AND secret_ref != ''
+		  AND service_id IN (SELECT s.id FROM services s WHERE s.project_id = $1)
+	`, id).Scan(&secretRefs)
+	if err != nil {
+		return 0, err
+	}
+	if secretRefs > 0 {
+		return 0, ErrProjectHasSecretRefs
+	}
+
+	// Each source's provider and the account of its connection
+	rows, err := tx.QueryContext(ctx, `
+		SELECT gs.id, gs.provider, COALESCE(c.account_id, '')
+		FROM git_sources gs
+		JOIN services s ON s.id = gs.service_id
+		LEFT JOIN git_connections c ON c.id = gs.git_connection_id
+		WHERE s.project_id = $1
+	`, id)
+	if err != nil {
+		return 0, err
+	}
+	type boundSource struct {
+		id                  uuid.UUID
+		provider, accountID string
+	}
+	var sources []boundSource
+	for rows.Next() {
+		var source boundSource
+		if err := rows.Scan(&source.id, &source.provider, &source.accountID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		sources = append(sources, source)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	rebind := `
+		UPDATE git_sources
+		SET git_connection_id = (
+			SELECT c.id FROM git_connections c
+			WHERE c.casdoor_org_id = $1 AND c.provider = $2
+			ORDER BY CASE WHEN c.account_id = $3 THEN 0 ELSE 1 END, c.created_at
+			LIMIT 1
+		)
+		WHERE id = $4
+		  AND EXISTS (SELECT 1 FROM git_connections c WHERE c.casdoor_org_id = $1 AND c.provider = $2)
+	`
+	for _, source := range sources {
+		if _, err := tx.ExecContext(ctx, rebind, toOrgID, source.provider, source.accountID, source.id); err != nil {
+			return 0, err
+		}
+	}
+
+	// Sources still on a connection of another org are detached
+	stale := `
+		service_id IN (SELECT s.id FROM services s WHERE s.project_id = $1)
+		AND (git_connection_id IS NULL OR git_connection_id NOT IN (SELECT c.id FROM git_connections c WHERE c.casdoor_org_id = $2))
+	`
+	unlink := `UPDATE services SET git_source_id = NULL WHERE git_source_id IN (SELECT id FROM git_sources WHERE ` + stale + `)`
+	if _, err := tx.ExecContext(ctx, unlink, id, toOrgID); err != nil {
+		return 0, err
+	}
+	result, err = tx.ExecContext(ctx, `DELETE FROM git_sources WHERE `+stale, id, toOrgID)
+	if err != nil {
+		return 0, err
+	}
+	detached, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	InvalidateProjectCache(id)
+	return detached, nil
+}
+
 // ProjectExists checks if a project exists and belongs to the organization
 func (db *DB) ProjectExists(ctx context.Context, id uuid.UUID, orgID string) (bool, error) {
 	var exists bool
@@ -244,20 +425,35 @@ func (p *Project) BelongsToOrg(orgID string) bool {
 	return false
 }
 
+// MaxSlugBaseLength caps generated slugs, leaving room in the 100-char column for a "-N" collision suffix
+const MaxSlugBaseLength = 90
+
 // GenerateSlug generates a URL-friendly slug from a project name
 func GenerateSlug(name string) string {
-	// Simple slug generation - convert to lowercase and replace spaces with hyphens
-	// In production, use a proper slug library
-	slug := strings.ToLower(name)
-	slug = strings.ReplaceAll(slug, " ", "-")
-	slug = strings.ReplaceAll(slug, "_", "-")
-	// Remove special characters (keep only alphanumeric and hyphens)
+	// Lowercase, turn separators into hyphens and drop everything else
 	var result strings.Builder
-	for _, r := range slug {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
 			result.WriteRune(r)
+		case r == ' ' || r == '_' || r == '-' || r == '.':
+			result.WriteRune('-')
 		}
 	}
-	return result.String()
+
+	// Collapse repeated hyphens and trim them from the ends
+	slug := result.String()
+	for strings.Contains(slug, "--") {
+		slug = strings.ReplaceAll(slug, "--", "-")
+	}
+	slug = strings.Trim(slug, "-")
+
+	if len(slug) > MaxSlugBaseLength {
+		slug = strings.TrimRight(slug[:MaxSlugBaseLength], "-")
+	}
+	if slug == "" {
+		slug = "project"
+	}
+	return slug
 }
 
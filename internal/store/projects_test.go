@@ -17,12 +17,6 @@ func TestDB_CreateProject(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Check if we're using SQLite (for fast tests) or PostgreSQL
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
 	project := &Project{
 		CasdoorOrgID:      "test-org-123",
 		Name:              "Test Project",
@@ -30,37 +24,8 @@ func TestDB_CreateProject(t *testing.T) {
 		OpenStackTenantID: "test-tenant-123",
 		AutoDeploy:        true,
 	}
-
-	if isSQLite {
-		// SQLite: Insert with explicit UUID
-		project.ID = uuid.New()
-		query := `
-			INSERT INTO projects (
-				id, casdoor_org_id, name, slug, description,
-				openstack_tenant_id, openstack_network_id,
-				default_region, auto_deploy, created_by
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		`
-		_, err = db.ExecContext(ctx, query,
-			project.ID.String(), project.CasdoorOrgID, project.Name, project.Slug, project.Description,
-			project.OpenStackTenantID, project.OpenStackNetworkID,
-			project.DefaultRegion, project.AutoDeploy, project.CreatedBy,
-		)
-		if err != nil {
-			t.Fatalf("Failed to create project: %v", err)
-		}
-		// Get timestamps
-		err = db.QueryRowContext(ctx, "SELECT created_at, updated_at FROM projects WHERE id = $1", project.ID.String()).
-			Scan(&project.CreatedAt, &project.UpdatedAt)
-		if err != nil {
-			t.Fatalf("Failed to get timestamps: %v", err)
-		}
-	} else {
-		// PostgreSQL: Use the store method
-		err = dbStore.CreateProject(ctx, project)
-		if err != nil {
-			t.Fatalf("Failed to create project: %v", err)
-		}
+	if err := dbStore.CreateProject(ctx, project); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
 	}
 
 	if project.ID == uuid.Nil {
@@ -84,37 +49,7 @@ func TestDB_GetProject(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Check if we're using SQLite
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	// Create a project
-	project := &Project{
-		CasdoorOrgID:      "test-org-456",
-		Name:              "Test Project",
-		Slug:              "test-project",
-		OpenStackTenantID: "test-tenant-123",
-		AutoDeploy:        true,
-	}
-
-	if isSQLite {
-		project.ID = uuid.New()
-		query := `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id, auto_deploy) 
-		          VALUES ($1, $2, $3, $4, $5, $6)`
-		_, err = db.ExecContext(ctx, query, project.ID.String(), project.CasdoorOrgID, project.Name, 
-			project.Slug, project.OpenStackTenantID, project.AutoDeploy)
-		if err != nil {
-			t.Fatalf("Failed to create project: %v", err)
-		}
-		db.QueryRowContext(ctx, "SELECT created_at, updated_at FROM projects WHERE id = $1", project.ID.String()).
-			Scan(&project.CreatedAt, &project.UpdatedAt)
-	} else {
-		if err := dbStore.CreateProject(ctx, project); err != nil {
-			t.Fatalf("Failed to create project: %v", err)
-		}
-	}
+	project := testutil.NewProject(t, db, func(p *testutil.Project) { p.OrgID = "test-org-456" })
 
 	// Retrieve the project
 	retrieved, err := dbStore.GetProject(ctx, project.ID)
@@ -134,8 +69,8 @@ func TestDB_GetProject(t *testing.T) {
 		t.Errorf("Expected project name %s, got %s", project.Name, retrieved.Name)
 	}
 
-	if retrieved.CasdoorOrgID != project.CasdoorOrgID {
-		t.Errorf("Expected org ID %s, got %s", project.CasdoorOrgID, retrieved.CasdoorOrgID)
+	if retrieved.CasdoorOrgID != project.OrgID {
+		t.Errorf("Expected org ID %s, got %s", project.OrgID, retrieved.CasdoorOrgID)
 	}
 
 	// Test non-existent project
@@ -157,76 +92,23 @@ func TestDB_ListProjectsByOrg(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Check if we're using SQLite
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
 	orgID := "test-org-789"
 
 	// Create multiple projects for the same org
-	project1 := &Project{
-		CasdoorOrgID:      orgID,
-		Name:              "Project 1",
-		Slug:              "project-1",
-		OpenStackTenantID: "test-tenant-123",
-		AutoDeploy:        true,
-	}
-	project2 := &Project{
-		CasdoorOrgID:      orgID,
-		Name:              "Project 2",
-		Slug:              "project-2",
-		OpenStackTenantID: "test-tenant-123",
-		AutoDeploy:        true,
-	}
-
-	if isSQLite {
-		project1.ID = uuid.New()
-		project2.ID = uuid.New()
-		query := `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id, auto_deploy) 
-		          VALUES ($1, $2, $3, $4, $5, $6)`
-		_, err = db.ExecContext(ctx, query, project1.ID.String(), project1.CasdoorOrgID, project1.Name, 
-			project1.Slug, project1.OpenStackTenantID, project1.AutoDeploy)
-		if err != nil {
-			t.Fatalf("Failed to create project 1: %v", err)
-		}
-		_, err = db.ExecContext(ctx, query, project2.ID.String(), project2.CasdoorOrgID, project2.Name, 
-			project2.Slug, project2.OpenStackTenantID, project2.AutoDeploy)
-		if err != nil {
-			t.Fatalf("Failed to create project 2: %v", err)
-		}
-	} else {
-		if err := dbStore.CreateProject(ctx, project1); err != nil {
-			t.Fatalf("Failed to create project 1: %v", err)
-		}
-		if err := dbStore.CreateProject(ctx, project2); err != nil {
-			t.Fatalf("Failed to create project 2: %v", err)
-		}
-	}
+	testutil.NewProject(t, db, func(p *testutil.Project) {
+		p.OrgID = orgID
+		p.Name = "Project 1"
+	})
+	testutil.NewProject(t, db, func(p *testutil.Project) {
+		p.OrgID = orgID
+		p.Name = "Project 2"
+	})
 
 	// Create a project for a different org
-	otherOrgProject := &Project{
-		CasdoorOrgID:      "other-org",
-		Name:              "Other Project",
-		Slug:              "other-project",
-		OpenStackTenantID: "test-tenant-123",
-		AutoDeploy:        true,
-	}
-	if isSQLite {
-		otherOrgProject.ID = uuid.New()
-		query := `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id, auto_deploy) 
-		          VALUES ($1, $2, $3, $4, $5, $6)`
-		_, err = db.ExecContext(ctx, query, otherOrgProject.ID.String(), otherOrgProject.CasdoorOrgID, 
-			otherOrgProject.Name, otherOrgProject.Slug, otherOrgProject.OpenStackTenantID, otherOrgProject.AutoDeploy)
-		if err != nil {
-			t.Fatalf("Failed to create other org project: %v", err)
-		}
-	} else {
-		if err := dbStore.CreateProject(ctx, otherOrgProject); err != nil {
-			t.Fatalf("Failed to create other org project: %v", err)
-		}
-	}
+	testutil.NewProject(t, db, func(p *testutil.Project) {
+		p.OrgID = "other-org"
+		p.Name = "Other Project"
+	})
 
 	// List projects for the org
 	projects, err := dbStore.ListProjectsByOrg(ctx, orgID)
@@ -254,43 +136,18 @@ func TestDB_UpdateProject(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Check if we're using SQLite
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
+	project := testutil.NewProject(t, db, func(p *testutil.Project) {
+		p.OrgID = "test-org-101"
+		p.Name = "Original Name"
+		p.Slug = "original-slug"
+	})
 
-	// Create a project
-	project := &Project{
-		CasdoorOrgID:      "test-org-101",
-		Name:              "Original Name",
-		Slug:              "original-slug",
-		OpenStackTenantID: "test-tenant-123",
-		AutoDeploy:        true,
-	}
-
-	if isSQLite {
-		project.ID = uuid.New()
-		query := `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id, auto_deploy) 
-		          VALUES ($1, $2, $3, $4, $5, $6)`
-		_, err = db.ExecContext(ctx, query, project.ID.String(), project.CasdoorOrgID, project.Name, 
-			project.Slug, project.OpenStackTenantID, project.AutoDeploy)
-		if err != nil {
-			t.Fatalf("Failed to create project: %v", err)
-		}
-		db.QueryRowContext(ctx, "SELECT created_at, updated_at FROM projects WHERE id = $1", project.ID.String()).
-			Scan(&project.CreatedAt, &project.UpdatedAt)
-	} else {
-		if err := dbStore.CreateProject(ctx, project); err != nil {
-			t.Fatalf("Failed to create project: %v", err)
-		}
-	}
-
-	originalUpdatedAt := project.UpdatedAt
+	// A new project's updated_at is its created_at
+	originalUpdatedAt := project.CreatedAt
 
 	// Update the project
 	updates := &Project{
-		CasdoorOrgID:  project.CasdoorOrgID, // Required for UpdateProject
+		CasdoorOrgID:  project.OrgID, // Required for UpdateProject
 		Name:          "Updated Name",
 		Slug:          "updated-slug",
 		AutoDeploy:    false,
@@ -298,7 +155,8 @@ func TestDB_UpdateProject(t *testing.T) {
 		DefaultRegion: sql.NullString{String: "us-east-1", Valid: true},
 	}
 
-	if isSQLite {
+	var err error
+	if testutil.IsSQLite(db) {
 		// SQLite: Use datetime('now') instead of now()
 		query := `
 			UPDATE projects 
@@ -364,38 +222,10 @@ func TestDB_DeleteProject(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Check if we're using SQLite
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	// Create a project
-	project := &Project{
-		CasdoorOrgID:      "test-org-202",
-		Name:              "Test Project",
-		Slug:              "test-project",
-		OpenStackTenantID: "test-tenant-123",
-		AutoDeploy:        true,
-	}
-
-	if isSQLite {
-		project.ID = uuid.New()
-		query := `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id, auto_deploy) 
-		          VALUES ($1, $2, $3, $4, $5, $6)`
-		_, err = db.ExecContext(ctx, query, project.ID.String(), project.CasdoorOrgID, project.Name, 
-			project.Slug, project.OpenStackTenantID, project.AutoDeploy)
-		if err != nil {
-			t.Fatalf("Failed to create project: %v", err)
-		}
-	} else {
-		if err := dbStore.CreateProject(ctx, project); err != nil {
-			t.Fatalf("Failed to create project: %v", err)
-		}
-	}
+	project := testutil.NewProject(t, db, func(p *testutil.Project) { p.OrgID = "test-org-202" })
 
 	// Delete the project (requires orgID for verification)
-	err = dbStore.DeleteProject(ctx, project.ID, project.CasdoorOrgID)
+	err := dbStore.DeleteProject(ctx, project.ID, project.OrgID)
 	if err != nil {
 		// DeleteProject returns error if no rows affected, which is expected behavior
 		// but we should check if the project was actually deleted
@@ -413,3 +243,121 @@ func TestDB_DeleteProject(t *testing.T) {
 	}
 }
 
+func TestDB_TransferProject(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	newConnection := func(orgID, provider, accountID string) *GitConnection {
+		gc := &GitConnection{
+			CasdoorOrgID: orgID,
+			Provider:     provider,
+			AccessToken:  "token",
+			AccountID:    sql.NullString{String: accountID, Valid: true},
+		}
+		if err := dbStore.CreateGitConnection(ctx, gc); err != nil {
+			t.Fatalf("Failed to create git connection: %v", err)
+		}
+		return gc
+	}
+	newGitSource := func(serviceID uuid.UUID, gc *GitConnection) *GitSource {
+		gs := &GitSource{ServiceID: serviceID, GitConnectionID: gc.ID, Provider: gc.Provider, RepoOwner: "acme", RepoName: "api", Branch: "main"}
+		if err := dbStore.CreateGitSource(ctx, gs); err != nil {
+			t.Fatalf("Failed to create git source: %v", err)
+		}
+		if _, err := db.Exec(`UPDATE services SET git_source_id = $1 WHERE id = $2`, gs.ID.String(), serviceID.String()); err != nil {
+			t.Fatalf("Failed to link git source: %v", err)
+		}
+		return gs
+	}
+
+	project := testutil.NewProject(t, db)
+	githubService := testutil.NewService(t, db, project.ID)
+	gitlabService := testutil.NewService(t, db, project.ID)
+
+	githubSource := newGitSource(githubService.ID, newConnection("test-org", "github", "acme"))
+	gitlabSource := newGitSource(gitlabService.ID, newConnection("test-org", "gitlab", "acme"))
+
+	// The target org has GitHub connections, the one of the same account is preferred
+	newConnection("target-org", "github", "someone-else")
+	sameAccount := newConnection("target-org", "github", "acme")
+
+	if _, err := dbStore.TransferProject(ctx, project.ID, "other-org", "target-org", project.Slug); err != sql.ErrNoRows {
+		t.Fatalf("Expected sql.ErrNoRows transferring from an org the project isn't in, got %v", err)
+	}
+
+	detached, err := dbStore.TransferProject(ctx, project.ID, "test-org", "target-org", project.Slug)
+	if err != nil {
+		t.Fatalf("TransferProject failed: %v", err)
+	}
+	if detached != 1 {
+		t.Errorf("Expected 1 git source detached, got %d", detached)
+	}
+
+	transferred, err := dbStore.GetProject(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("Failed to get project: %v", err)
+	}
+	if transferred.CasdoorOrgID != "target-org" {
+		t.Errorf("Expected project of target-org, got %s", transferred.CasdoorOrgID)
+	}
+
+	rebound, err := dbStore.GetGitSource(ctx, githubSource.ID)
+	if err != nil {
+		t.Fatalf("Failed to get git source: %v", err)
+	}
+	if rebound == nil || rebound.GitConnectionID != sameAccount.ID {
+		t.Errorf("Expected the GitHub source on connection %s of target-org, got %+v", sameAccount.ID, rebound)
+	}
+
+	gone, err := dbStore.GetGitSource(ctx, gitlabSource.ID)
+	if err != nil {
+		t.Fatalf("Failed to get git source: %v", err)
+	}
+	if gone != nil {
+		t.Error("Expected the GitLab source to be detached: target-org has no GitLab connection")
+	}
+	service, err := dbStore.GetService(ctx, gitlabService.ID)
+	if err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+	if service.GitSourceID.Valid {
+		t.Errorf("Expected the service's git source unset, got %s", service.GitSourceID.String)
+	}
+}
+
+func TestDB_TransferProject_SecretRefs(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	service := testutil.NewService(t, db, project.ID)
+	envVar := &EnvVar{
+		ServiceID: service.ID,
+		Key:       "API_KEY",
+		IsSecret:  true,
+		SecretRef: sql.NullString{String: `{"path":"secret/data/zyndra/test-org/api","key":"key"}`, Valid: true},
+	}
+	if err := dbStore.CreateEnvVar(ctx, envVar); err != nil {
+		t.Fatalf("Failed to create env var: %v", err)
+	}
+
+	if _, err := dbStore.TransferProject(ctx, project.ID, "test-org", "target-org", project.Slug); err != ErrProjectHasSecretRefs {
+		t.Fatalf("Expected ErrProjectHasSecretRefs, got %v", err)
+	}
+
+	unchanged, err := dbStore.GetProject(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("Failed to get project: %v", err)
+	}
+	if unchanged.CasdoorOrgID != "test-org" {
+		t.Errorf("Expected the project to stay in test-org, got %s", unchanged.CasdoorOrgID)
+	}
+}
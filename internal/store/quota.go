@@ -0,0 +1,185 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// OrgResources are the resources of an org's projects that count against its quota
+type OrgResources struct {
+	Services      int
+	AppSizes      map[string]int // Number of app services of each instance size
+	DatabaseSizes map[string]int // Number of databases of each size
+	StorageMB     int            // Includes the volumes auto-created for databases
+}
+
+// orgProjectsFilter selects the projects of an org, by org_id for custom auth with a
+// fallback to casdoor_org_id, like the usage endpoints. $1 is the org's UUID, $2 its
+// ID as given.
+const orgProjectsFilter = `
+	SELECT id FROM projects
+	WHERE org_id = $1
+	   OR (casdoor_org_id = $2 AND NOT EXISTS (SELECT 1 FROM projects WHERE org_id = $1))
+`
+
+// queryer runs queries on the database or in a transaction
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// GetOrgResources returns the resources of an org that count against its quota
+func (db *DB) GetOrgResources(ctx context.Context, orgID string) (*OrgResources, error) {
+	return getOrgResources(ctx, db, orgID)
+}
+
+func getOrgResources(ctx context.Context, q queryer, orgID string) (*OrgResources, error) {
+	// Custom auth orgs are referenced by UUID, Casdoor orgs only by casdoor_org_id
+	var orgUUID uuid.NullUUID
+	if parsed, err := uuid.Parse(orgID); err == nil {
+		orgUUID = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+
+	resources := &OrgResources{AppSizes: map[string]int{}, DatabaseSizes: map[string]int{}}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT type, instance_size, COUNT(*)
+		FROM services
+		WHERE project_id IN (`+orgProjectsFilter+`)
+		GROUP BY type, instance_size
+	`, orgUUID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count org services: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var serviceType, size string
+		var count int
+		if err := rows.Scan(&serviceType, &size, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan org services: %w", err)
+		}
+		resources.Services += count
+		if serviceType == "app" {
+			resources.AppSizes[size] += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count org services: %w", err)
+	}
+	rows.Close()
+
+	// Databases reach their project through the linked service or their volume
+	rows, err = q.QueryContext(ctx, `
+		SELECT d.size, COUNT(*)
+		FROM databases d
+		LEFT JOIN services s ON s.id = d.service_id
+		LEFT JOIN volumes v ON v.id = d.volume_id
+		WHERE COALESCE(s.project_id, v.project_id) IN (`+orgProjectsFilter+`)
+		GROUP BY d.size
+	`, orgUUID, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count org databases: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var size string
+		var count int
+		if err := rows.Scan(&size, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan org databases: %w", err)
+		}
+		resources.DatabaseSizes[size] += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count org databases: %w", err)
+	}
+
+	err = q.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(size_mb), 0)
+		FROM volumes
+		WHERE project_id IN (`+orgProjectsFilter+`)
+	`, orgUUID, orgID).Scan(&resources.StorageMB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum org storage: %w", err)
+	}
+
+	return resources, nil
+}
+
+// QuotaTx is a transaction holding the lock on an org's quota. The org's resources
+// read in it and those created in it are checked and added as one step, so
+// concurrent creates of the org can't all pass the check and go over its quota.
+type QuotaTx struct {
+	tx       *sql.Tx
+	isSQLite bool
+	orgID    string
+	projects []uuid.UUID // Of the services created, whose caches are invalidated on commit
+}
+
+// BeginQuotaTx begins a transaction holding the lock on an org's quota, a
+// transaction-scoped advisory lock on Postgres (SQLite serializes writers)
+func (db *DB) BeginQuotaTx(ctx context.Context, orgID string) (*QuotaTx, error) {
+	var version string
+	isSQLite := db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&version) == nil
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if !isSQLite {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('org_quota:' || $1))", orgID); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to lock org quota: %w", err)
+		}
+	}
+	return &QuotaTx{tx: tx, isSQLite: isSQLite, orgID: orgID}, nil
+}
+
+// Resources returns the org's resources that count against its quota, including
+// those created in the transaction
+func (q *QuotaTx) Resources(ctx context.Context) (*OrgResources, error) {
+	return getOrgResources(ctx, q.tx, q.orgID)
+}
+
+// CreateService creates a service in the transaction
+func (q *QuotaTx) CreateService(ctx context.Context, s *Service) error {
+	if err := insertService(ctx, q.tx, q.isSQLite, s); err != nil {
+		return err
+	}
+	q.projects = append(q.projects, s.ProjectID)
+	return nil
+}
+
+// CreateGitSource creates the git source of a service created in the transaction
+func (q *QuotaTx) CreateGitSource(ctx context.Context, gs *GitSource) error {
+	return insertGitSource(ctx, q.tx, q.isSQLite, gs)
+}
+
+// CreateVolume creates a volume in the transaction
+func (q *QuotaTx) CreateVolume(ctx context.Context, v *Volume) error {
+	return insertVolume(ctx, q.tx, q.isSQLite, v)
+}
+
+// CreateDatabase creates a database in the transaction
+func (q *QuotaTx) CreateDatabase(ctx context.Context, d *Database) error {
+	return insertDatabase(ctx, q.tx, q.isSQLite, d)
+}
+
+// Commit commits the transaction, releasing the org's quota lock
+func (q *QuotaTx) Commit() error {
+	if err := q.tx.Commit(); err != nil {
+		return err
+	}
+	for _, projectID := range q.projects {
+		InvalidateProjectCache(projectID)
+	}
+	return nil
+}
+
+// Rollback discards the transaction, releasing the org's quota lock. It does nothing
+// after Commit.
+func (q *QuotaTx) Rollback() error {
+	return q.tx.Rollback()
+}
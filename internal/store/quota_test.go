@@ -0,0 +1,127 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestDB_GetOrgResources(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	other := testutil.NewProject(t, db, func(p *testutil.Project) { p.OrgID = "other-org" })
+
+	service := testutil.NewService(t, db, project.ID, func(s *testutil.Service) { s.InstanceSize = "large" })
+	testutil.NewService(t, db, project.ID, func(s *testutil.Service) { s.Type = "database" })
+	testutil.NewService(t, db, other.ID)
+	testutil.NewVolume(t, db, project.ID, func(v *testutil.Volume) { v.SizeMB = 1024 })
+	testutil.NewVolume(t, db, other.ID, func(v *testutil.Volume) { v.SizeMB = 4096 })
+	testutil.NewDatabase(t, db, service.ID, func(d *testutil.Database) { d.Size = "medium" })
+
+	// A database without service reaches its project through its volume
+	volume := testutil.NewVolume(t, db, project.ID, func(v *testutil.Volume) { v.SizeMB = 512 })
+	standalone := testutil.NewDatabase(t, db, uuid.Nil)
+	if _, err := db.Exec("UPDATE databases SET volume_id = $1 WHERE id = $2", volume.ID.String(), standalone.ID.String()); err != nil {
+		t.Fatalf("Failed to link database volume: %v", err)
+	}
+
+	resources, err := dbStore.GetOrgResources(ctx, project.OrgID)
+	if err != nil {
+		t.Fatalf("Failed to get org resources: %v", err)
+	}
+
+	if resources.Services != 2 {
+		t.Errorf("Expected 2 services, got %d", resources.Services)
+	}
+	if resources.AppSizes["large"] != 1 || len(resources.AppSizes) != 1 {
+		t.Errorf("Expected one large app service, got %v", resources.AppSizes)
+	}
+	if resources.DatabaseSizes["medium"] != 1 || resources.DatabaseSizes["small"] != 1 {
+		t.Errorf("Expected one medium and one small database, got %v", resources.DatabaseSizes)
+	}
+	if resources.StorageMB != 1536 {
+		t.Errorf("Expected 1536 MB of storage, got %d", resources.StorageMB)
+	}
+}
+
+func TestQuotaTx(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+
+	create := func(t *testing.T) *QuotaTx {
+		t.Helper()
+
+		tx, err := dbStore.BeginQuotaTx(ctx, project.OrgID)
+		if err != nil {
+			t.Fatalf("Failed to begin quota transaction: %v", err)
+		}
+		service := &Service{ProjectID: project.ID, Name: "Test Service", Type: "app", Status: "pending", InstanceSize: "small", Port: 8080}
+		if err := tx.CreateService(ctx, service); err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+		volume := &Volume{ProjectID: project.ID, Name: "Test Volume", SizeMB: 2048, VolumeType: "database", Status: "pending"}
+		if err := tx.CreateVolume(ctx, volume); err != nil {
+			t.Fatalf("Failed to create volume: %v", err)
+		}
+		database := &Database{Name: "test-db", Engine: "postgresql", Version: StringToNullString("16"), Size: "small", VolumeID: StringToNullString(volume.ID.String()), Status: "pending"}
+		if err := tx.CreateDatabase(ctx, database); err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+
+		// Resources created in the transaction count against the quota checked in it
+		resources, err := tx.Resources(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get resources: %v", err)
+		}
+		if resources.Services != 1 || resources.DatabaseSizes["small"] != 1 || resources.StorageMB != 2048 {
+			t.Errorf("Expected the created resources, got %+v", resources)
+		}
+		return tx
+	}
+
+	t.Run("rollback", func(t *testing.T) {
+		tx := create(t)
+		if err := tx.Rollback(); err != nil {
+			t.Fatalf("Failed to roll back: %v", err)
+		}
+
+		resources, err := dbStore.GetOrgResources(ctx, project.OrgID)
+		if err != nil {
+			t.Fatalf("Failed to get org resources: %v", err)
+		}
+		if resources.Services != 0 || len(resources.DatabaseSizes) != 0 || resources.StorageMB != 0 {
+			t.Errorf("Expected no resources after rollback, got %+v", resources)
+		}
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		tx := create(t)
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+		// Does nothing after Commit
+		tx.Rollback()
+
+		resources, err := dbStore.GetOrgResources(ctx, project.OrgID)
+		if err != nil {
+			t.Fatalf("Failed to get org resources: %v", err)
+		}
+		if resources.Services != 1 || resources.DatabaseSizes["small"] != 1 || resources.StorageMB != 2048 {
+			t.Errorf("Expected the committed resources, got %+v", resources)
+		}
+	})
+}
@@ -9,7 +9,8 @@ import (
 	"time"
 )
 
-// RefreshToken represents a refresh token in the database
+// RefreshToken represents a refresh token in the database. Each is a session of its
+// user: refreshing rotates the token but keeps the row.
 type RefreshToken struct {
 	ID        string    `json:"id"`
 	UserID    string    `json:"user_id"`
@@ -17,59 +18,71 @@ type RefreshToken struct {
 	ExpiresAt time.Time `json:"expires_at"`
 	Revoked   bool      `json:"revoked"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// Device the session was started from, and when it was last refreshed
+	UserAgent  sql.NullString `json:"-"`
+	IPAddress  sql.NullString `json:"-"`
+	LastUsedAt sql.NullTime   `json:"-"`
 }
 
-// hashToken creates a SHA-256 hash of a token
-func hashToken(token string) string {
-	hash := sha256.Sum256([]byte(token))
-	return hex.EncodeToString(hash[:])
+// SessionInfo describes the device a refresh token is issued to
+type SessionInfo struct {
+	UserAgent string
+	IPAddress string
 }
 
-// CreateRefreshToken creates a new refresh token
-func (db *DB) CreateRefreshToken(ctx context.Context, userID, token string, expiresAt time.Time) (*RefreshToken, error) {
-	tokenHash := hashToken(token)
-	
-	query := `
-		INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
-		VALUES ($1, $2, $3)
-		RETURNING id, user_id, token_hash, expires_at, revoked, created_at
-	`
+const refreshTokenColumns = `id, user_id, token_hash, expires_at, revoked, created_at, user_agent, ip_address, last_used_at`
 
+func scanRefreshToken(row interface{ Scan(...any) error }) (*RefreshToken, error) {
 	var rt RefreshToken
-	err := db.QueryRowContext(ctx, query, userID, tokenHash, expiresAt).Scan(
+	err := row.Scan(
 		&rt.ID,
 		&rt.UserID,
 		&rt.TokenHash,
 		&rt.ExpiresAt,
 		&rt.Revoked,
 		&rt.CreatedAt,
+		&rt.UserAgent,
+		&rt.IPAddress,
+		&rt.LastUsedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return &rt, nil
+}
+
+// hashToken creates a SHA-256 hash of a token
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// CreateRefreshToken creates a new refresh token, starting session id
+func (db *DB) CreateRefreshToken(ctx context.Context, id, userID, token string, expiresAt time.Time, session SessionInfo) (*RefreshToken, error) {
+	tokenHash := hashToken(token)
+	
+	query := `
+		INSERT INTO refresh_tokens (id, user_id, token_hash, expires_at, user_agent, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + refreshTokenColumns
+
+	rt, err := scanRefreshToken(db.QueryRowContext(ctx, query, id, userID, tokenHash, expiresAt,
+		StringToNullString(session.UserAgent), StringToNullString(session.IPAddress)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create refresh token: %w", err)
 	}
 
-	return &rt, nil
+	return rt, nil
 }
 
 // GetRefreshToken retrieves a refresh token by its value (hashes and looks up)
 func (db *DB) GetRefreshToken(ctx context.Context, token string) (*RefreshToken, error) {
 	tokenHash := hashToken(token)
 	
-	query := `
-		SELECT id, user_id, token_hash, expires_at, revoked, created_at
-		FROM refresh_tokens
-		WHERE token_hash = $1
-	`
+	query := `SELECT ` + refreshTokenColumns + ` FROM refresh_tokens WHERE token_hash = $1`
 
-	var rt RefreshToken
-	err := db.QueryRowContext(ctx, query, tokenHash).Scan(
-		&rt.ID,
-		&rt.UserID,
-		&rt.TokenHash,
-		&rt.ExpiresAt,
-		&rt.Revoked,
-		&rt.CreatedAt,
-	)
+	rt, err := scanRefreshToken(db.QueryRowContext(ctx, query, tokenHash))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("refresh token not found")
@@ -77,7 +90,7 @@ func (db *DB) GetRefreshToken(ctx context.Context, token string) (*RefreshToken,
 		return nil, fmt.Errorf("failed to get refresh token: %w", err)
 	}
 
-	return &rt, nil
+	return rt, nil
 }
 
 // ValidateRefreshToken checks if a refresh token is valid (exists, not expired, not revoked)
@@ -146,15 +159,82 @@ func (db *DB) DeleteExpiredRefreshTokens(ctx context.Context) (int64, error) {
 	return rowsAffected, nil
 }
 
-// RotateRefreshToken creates a new refresh token and revokes the old one
-func (db *DB) RotateRefreshToken(ctx context.Context, oldToken, newToken string, userID string, expiresAt time.Time) (*RefreshToken, error) {
-	// Revoke old token
-	err := db.RevokeRefreshToken(ctx, oldToken)
+// RotateRefreshToken replaces a session's refresh token with a new one, which
+// invalidates the old token, and records the session as used
+func (db *DB) RotateRefreshToken(ctx context.Context, oldToken, newToken string, expiresAt time.Time) (*RefreshToken, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET token_hash = $1, expires_at = $2, last_used_at = $3
+		WHERE token_hash = $4 AND revoked = false
+		RETURNING ` + refreshTokenColumns
+
+	rt, err := scanRefreshToken(db.QueryRowContext(ctx, query, hashToken(newToken), expiresAt, time.Now().UTC(), hashToken(oldToken)))
 	if err != nil {
-		return nil, fmt.Errorf("failed to revoke old token: %w", err)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("refresh token not found")
+		}
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
 	}
 
-	// Create new token
-	return db.CreateRefreshToken(ctx, userID, newToken, expiresAt)
+	return rt, nil
 }
 
+// ListUserSessions lists a user's sessions, its unexpired and unrevoked refresh
+// tokens, most recently used first
+func (db *DB) ListUserSessions(ctx context.Context, userID string) ([]*RefreshToken, error) {
+	query := `
+		SELECT ` + refreshTokenColumns + `
+		FROM refresh_tokens
+		WHERE user_id = $1 AND revoked = false AND expires_at > $2
+		ORDER BY COALESCE(last_used_at, created_at) DESC, id
+	`
+
+	rows, err := db.QueryContext(ctx, query, userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*RefreshToken
+	for rows.Next() {
+		rt, err := scanRefreshToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, rt)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RevokeUserSession revokes one of a user's sessions. Returns sql.ErrNoRows if the
+// user has no such active session.
+func (db *DB) RevokeUserSession(ctx context.Context, userID, sessionID string) error {
+	query := `UPDATE refresh_tokens SET revoked = true WHERE id = $1 AND user_id = $2 AND revoked = false`
+	result, err := db.ExecContext(ctx, query, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// RevokeOtherUserSessions revokes all of a user's sessions except keepSessionID,
+// returning how many were revoked
+func (db *DB) RevokeOtherUserSessions(ctx context.Context, userID, keepSessionID string) (int64, error) {
+	query := `UPDATE refresh_tokens SET revoked = true WHERE user_id = $1 AND id <> $2 AND revoked = false`
+	result, err := db.ExecContext(ctx, query, userID, keepSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestDB_UserSessions(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+	userID := uuid.NewString()
+	expiresAt := time.Now().Add(time.Hour).UTC()
+
+	newSession := func(token, userAgent string) string {
+		id := uuid.NewString()
+		if _, err := dbStore.CreateRefreshToken(ctx, id, userID, token, expiresAt, SessionInfo{UserAgent: userAgent, IPAddress: "203.0.113.7"}); err != nil {
+			t.Fatalf("CreateRefreshToken error: %v", err)
+		}
+		return id
+	}
+	laptop := newSession("laptop-token", "Firefox")
+	phone := newSession("phone-token", "Safari")
+	tablet := newSession("tablet-token", "Chrome")
+
+	// Refreshing keeps the session and retires the old token
+	rotated, err := dbStore.RotateRefreshToken(ctx, "laptop-token", "laptop-token-2", expiresAt)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken error: %v", err)
+	}
+	if rotated.ID != laptop || !rotated.LastUsedAt.Valid || rotated.UserAgent.String != "Firefox" {
+		t.Errorf("RotateRefreshToken = %+v, want session %s marked used", rotated, laptop)
+	}
+	if _, err := dbStore.ValidateRefreshToken(ctx, "laptop-token"); err == nil {
+		t.Error("old refresh token still valid after rotation")
+	}
+
+	if err := dbStore.RevokeUserSession(ctx, userID, phone); err != nil {
+		t.Fatalf("RevokeUserSession error: %v", err)
+	}
+	if err := dbStore.RevokeUserSession(ctx, uuid.NewString(), tablet); err == nil {
+		t.Error("RevokeUserSession revoked another user's session")
+	}
+
+	sessions, err := dbStore.ListUserSessions(ctx, userID)
+	if err != nil {
+		t.Fatalf("ListUserSessions error: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != laptop || sessions[1].ID != tablet {
+		t.Errorf("ListUserSessions = %d sessions, want the laptop's then the tablet's", len(sessions))
+	}
+
+	revoked, err := dbStore.RevokeOtherUserSessions(ctx, userID, laptop)
+	if err != nil {
+		t.Fatalf("RevokeOtherUserSessions error: %v", err)
+	}
+	if revoked != 1 {
+		t.Errorf("RevokeOtherUserSessions revoked %d sessions, want 1", revoked)
+	}
+	if _, err := dbStore.ValidateRefreshToken(ctx, "laptop-token-2"); err != nil {
+		t.Errorf("current session revoked: %v", err)
+	}
+}
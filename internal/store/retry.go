@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/intelifox/click-deploy/internal/retry"
+)
+
+// PostgreSQL error codes of transient failures: the same statement can succeed
+// when run again
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	pgConnectionException  = "08" // class 08, e.g. a connection reset during failover
+)
+
+// dbRetryConfig bounds the retries of a statement that failed with a transient
+// error. A failover usually completes within a couple of seconds.
+var dbRetryConfig = retry.RetryConfig{
+	MaxAttempts:  4,
+	InitialDelay: 100 * time.Millisecond,
+	MaxDelay:     2 * time.Second,
+	Multiplier:   3,
+	Jitter:       true,
+}
+
+// ExecContext executes a statement, retrying it on transient PostgreSQL errors
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := withRetry(ctx, func() error {
+		var err error
+		result, err = db.DB.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// QueryContext runs a query, retrying it on transient PostgreSQL errors. Errors
+// while reading the rows aren't retried: part of them may have been handled already.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := withRetry(ctx, func() error {
+		var err error
+		rows, err = db.DB.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRowContext runs a query returning at most one row, retrying it on transient
+// PostgreSQL errors
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	var row *sql.Row
+	withRetry(ctx, func() error {
+		row = db.DB.QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	if row == nil {
+		// The context was done before the first attempt, the row carries its error
+		row = db.DB.QueryRowContext(ctx, query, args...)
+	}
+	return row
+}
+
+// withRetry runs fn until it succeeds, fails with an error that isn't transient,
+// or runs out of attempts
+func withRetry(ctx context.Context, fn func() error) error {
+	return retry.Do(ctx, dbRetryConfig, func() error {
+		err := fn()
+		if isTransientError(err) {
+			return retry.NewRetryableError(err)
+		}
+		return err
+	})
+}
+
+// isTransientError reports whether err is a PostgreSQL serialization failure,
+// deadlock or connection error, after which the statement can be run again.
+// SQLite errors are never transient.
+func isTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected ||
+			strings.HasPrefix(pgErr.Code, pgConnectionException)
+	}
+
+	// Failing to connect, or losing the connection before the statement was sent
+	var connectErr *pgconn.ConnectError
+	return errors.As(err, &connectErr) || pgconn.SafeToRetry(err)
+}
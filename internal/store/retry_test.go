@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, true},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, true},
+		{"wrapped admin shutdown of the connection", fmt.Errorf("query: %w", &pgconn.PgError{Code: "08003"}), true},
+		{"unique violation", &pgconn.PgError{Code: pgUniqueViolation}, false},
+		{"syntax error", &pgconn.PgError{Code: "42601"}, false},
+		{"sqlite error", errors.New("UNIQUE constraint failed: projects.slug"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientError(tt.err); got != tt.want {
+				t.Errorf("isTransientError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	ctx := context.Background()
+
+	// Keep the backoff short
+	defaultConfig := dbRetryConfig
+	dbRetryConfig.InitialDelay = time.Millisecond
+	defer func() { dbRetryConfig = defaultConfig }()
+
+	t.Run("retries transient errors", func(t *testing.T) {
+		calls := 0
+		err := withRetry(ctx, func() error {
+			calls++
+			if calls < 3 {
+				return &pgconn.PgError{Code: pgSerializationFailure}
+			}
+			return nil
+		})
+		if err != nil || calls != 3 {
+			t.Errorf("Expected success on the third call, got %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		calls := 0
+		err := withRetry(ctx, func() error {
+			calls++
+			return &pgconn.PgError{Code: pgDeadlockDetected}
+		})
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || calls != dbRetryConfig.MaxAttempts {
+			t.Errorf("Expected the deadlock error after %d calls, got %v after %d calls", dbRetryConfig.MaxAttempts, err, calls)
+		}
+	})
+
+	t.Run("returns other errors right away", func(t *testing.T) {
+		calls := 0
+		uniqueErr := &pgconn.PgError{Code: pgUniqueViolation}
+		err := withRetry(ctx, func() error {
+			calls++
+			return uniqueErr
+		})
+		if err != uniqueErr || calls != 1 {
+			t.Errorf("Expected the unique violation after 1 call, got %v after %d calls", err, calls)
+		}
+	})
+}
+
+func TestDB_QueryRowContextCancelled(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+
+	dbStore := &DB{DB: db}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var one int
+	if err := dbStore.QueryRowContext(ctx, "SELECT 1").Scan(&one); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScalingScheduleEntry scales a service to Replicas whenever CronExpression fires in Timezone
+type ScalingScheduleEntry struct {
+	ID             uuid.UUID
+	ServiceID      uuid.UUID
+	ProjectID      uuid.UUID // Of the service; not stored
+	CronExpression string
+	Replicas       int
+	Timezone       string // IANA time zone, e.g. Europe/Paris
+	CreatedAt      time.Time
+}
+
+const scalingScheduleColumns = `
+	s.id, s.service_id, svc.project_id, s.cron_expression, s.replicas, s.timezone, s.created_at
+	FROM service_scaling_schedules s
+	JOIN services svc ON svc.id = s.service_id
+`
+
+// ListScalingSchedule lists the scaling schedule entries of a service, in order
+func (db *DB) ListScalingSchedule(ctx context.Context, serviceID uuid.UUID) ([]*ScalingScheduleEntry, error) {
+	return db.queryScalingSchedules(ctx, "SELECT"+scalingScheduleColumns+"WHERE s.service_id = $1 ORDER BY s.position", serviceID)
+}
+
+// ListAllScalingSchedules lists the scaling schedule entries of every service
+func (db *DB) ListAllScalingSchedules(ctx context.Context) ([]*ScalingScheduleEntry, error) {
+	return db.queryScalingSchedules(ctx, "SELECT"+scalingScheduleColumns+"ORDER BY s.service_id, s.position")
+}
+
+// ReplaceScalingSchedule replaces the scaling schedule of a service with entries
+func (db *DB) ReplaceScalingSchedule(ctx context.Context, serviceID uuid.UUID, entries []*ScalingScheduleEntry) error {
+	if _, err := db.ExecContext(ctx, "DELETE FROM service_scaling_schedules WHERE service_id = $1", serviceID); err != nil {
+		return err
+	}
+
+	for i, e := range entries {
+		if e.ID == uuid.Nil {
+			e.ID = uuid.New()
+		}
+		e.ServiceID = serviceID
+
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO service_scaling_schedules (id, service_id, cron_expression, replicas, timezone, position)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, e.ID.String(), serviceID.String(), e.CronExpression, e.Replicas, e.Timezone, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DB) queryScalingSchedules(ctx context.Context, query string, args ...interface{}) ([]*ScalingScheduleEntry, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*ScalingScheduleEntry
+	for rows.Next() {
+		var e ScalingScheduleEntry
+		if err := rows.Scan(&e.ID, &e.ServiceID, &e.ProjectID, &e.CronExpression, &e.Replicas, &e.Timezone, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
@@ -3,11 +3,20 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Statuses of a deployed service, kept in line with its k8s Deployment by the status
+// reconciliation
+const (
+	ServiceStatusRunning  = "running"
+	ServiceStatusDegraded = "degraded" // Fewer replicas ready than desired
+	ServiceStatusMissing  = "missing"  // The Deployment is gone, e.g. deleted out-of-band
+)
+
 type Service struct {
 	ID                  uuid.UUID
 	ProjectID           uuid.UUID
@@ -26,23 +35,44 @@ type Service struct {
 	CurrentImageTag     sql.NullString
 	CanvasX             int
 	CanvasY             int
-	CreatedAt           time.Time
-	UpdatedAt           time.Time
+	RequiresApproval    bool           // Deployments wait for approval by a second user
+	SupportsWebsockets  bool           // Routes keep WebSocket/streaming connections open, unbuffered
+	BuildCommand        sql.NullString // Overrides the build command Railpack detects
+	StartCommand        sql.NullString // Overrides the start command of the image
+	// Health checks: HTTP probes on HealthCheckPath when set, otherwise a TCP-connect
+	// readiness probe on the port unless DefaultProbeDisabled
+	HealthCheckPath         sql.NullString
+	HealthCheckInitialDelay sql.NullInt64 // Seconds before the first probe, NULL uses the default
+	DefaultProbeDisabled    bool
+	CreatedBy               sql.NullString // User who created the service
+	UpdatedBy               sql.NullString // User who last changed its config
+	CreatedAt               time.Time
+	UpdatedAt               time.Time
 }
 
 // CreateService creates a new service
 func (db *DB) CreateService(ctx context.Context, s *Service) error {
+	// Check if we're using SQLite (for compatibility)
+	var version string
+	isSQLite := db.QueryRow("SELECT sqlite_version()").Scan(&version) == nil
+
+	defer InvalidateProjectCache(s.ProjectID)
+	return insertService(ctx, db, isSQLite, s)
+}
+
+// ServiceWithGitSource is a service to create along with its git source
+type ServiceWithGitSource struct {
+	Service   *Service
+	GitSource *GitSource // nil for a service without git source
+}
+
+// insertService inserts a service with q, the database or a transaction
+func insertService(ctx context.Context, q execer, isSQLite bool, s *Service) error {
 	// Generate UUID if not set (for SQLite compatibility)
 	if s.ID == uuid.Nil {
 		s.ID = uuid.New()
 	}
 
-	// Check if we're using SQLite (for compatibility)
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
 	var gitSourceID interface{}
 	if s.GitSourceID.Valid {
 		gitSourceID = s.GitSourceID.String
@@ -55,18 +85,22 @@ func (db *DB) CreateService(ctx context.Context, s *Service) error {
 		query := `
 			INSERT INTO services (
 				id, project_id, git_source_id, name, type, status,
-				instance_size, port, canvas_x, canvas_y
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				instance_size, port, canvas_x, canvas_y, requires_approval,
+				supports_websockets, build_command, start_command, health_check_path,
+				health_check_initial_delay, default_probe_disabled, created_by, updated_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 		`
-		_, err = db.ExecContext(ctx, query,
+		_, err := q.ExecContext(ctx, query,
 			s.ID.String(), s.ProjectID.String(), gitSourceID, s.Name, s.Type, s.Status,
-			s.InstanceSize, s.Port, s.CanvasX, s.CanvasY,
+			s.InstanceSize, s.Port, s.CanvasX, s.CanvasY, s.RequiresApproval,
+			s.SupportsWebsockets, s.BuildCommand, s.StartCommand, s.HealthCheckPath,
+			s.HealthCheckInitialDelay, s.DefaultProbeDisabled, s.CreatedBy, s.UpdatedBy,
 		)
 		if err != nil {
 			return err
 		}
 		// Get timestamps
-		err = db.QueryRowContext(ctx, "SELECT created_at, updated_at FROM services WHERE id = $1", s.ID.String()).
+		err = q.QueryRowContext(ctx, "SELECT created_at, updated_at FROM services WHERE id = $1", s.ID.String()).
 			Scan(&s.CreatedAt, &s.UpdatedAt)
 		return err
 	}
@@ -75,12 +109,14 @@ func (db *DB) CreateService(ctx context.Context, s *Service) error {
 	query := `
 		INSERT INTO services (
 			project_id, git_source_id, name, type, status,
-			instance_size, port, canvas_x, canvas_y
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			instance_size, port, canvas_x, canvas_y, requires_approval,
+			supports_websockets, build_command, start_command, health_check_path,
+			health_check_initial_delay, default_probe_disabled, created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
 		RETURNING id, created_at, updated_at
 	`
 
-	err = db.QueryRowContext(ctx, query,
+	return q.QueryRowContext(ctx, query,
 		s.ProjectID,
 		gitSourceID,
 		s.Name,
@@ -90,9 +126,16 @@ func (db *DB) CreateService(ctx context.Context, s *Service) error {
 		s.Port,
 		s.CanvasX,
 		s.CanvasY,
+		s.RequiresApproval,
+		s.SupportsWebsockets,
+		s.BuildCommand,
+		s.StartCommand,
+		s.HealthCheckPath,
+		s.HealthCheckInitialDelay,
+		s.DefaultProbeDisabled,
+		s.CreatedBy,
+		s.UpdatedBy,
 	).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
-
-	return err
 }
 
 // GetService retrieves a service by ID
@@ -103,7 +146,9 @@ func (db *DB) GetService(ctx context.Context, id uuid.UUID) (*Service, error) {
 		       instance_size, port, openstack_instance_id, openstack_fip_id,
 		       openstack_fip_address, security_group_id, subdomain,
 		       generated_url, current_image_tag, canvas_x, canvas_y,
-		       created_at, updated_at
+		       requires_approval, supports_websockets, build_command, start_command,
+		       health_check_path, health_check_initial_delay, default_probe_disabled,
+		       created_by, updated_by, created_at, updated_at
 		FROM services
 		WHERE id = $1
 	`
@@ -135,6 +180,15 @@ func (db *DB) GetService(ctx context.Context, id uuid.UUID) (*Service, error) {
 		&currentImageTag,
 		&s.CanvasX,
 		&s.CanvasY,
+		&s.RequiresApproval,
+		&s.SupportsWebsockets,
+		&s.BuildCommand,
+		&s.StartCommand,
+		&s.HealthCheckPath,
+		&s.HealthCheckInitialDelay,
+		&s.DefaultProbeDisabled,
+		&s.CreatedBy,
+		&s.UpdatedBy,
 		&s.CreatedAt,
 		&s.UpdatedAt,
 	)
@@ -166,7 +220,9 @@ func (db *DB) ListServicesByProject(ctx context.Context, projectID uuid.UUID) ([
 		       instance_size, port, openstack_instance_id, openstack_fip_id,
 		       openstack_fip_address, security_group_id, subdomain,
 		       generated_url, current_image_tag, canvas_x, canvas_y,
-		       created_at, updated_at
+		       requires_approval, supports_websockets, build_command, start_command,
+		       health_check_path, health_check_initial_delay, default_probe_disabled,
+		       created_by, updated_by, created_at, updated_at
 		FROM services
 		WHERE project_id = $1
 		ORDER BY created_at DESC
@@ -208,6 +264,15 @@ func (db *DB) ListServicesByProject(ctx context.Context, projectID uuid.UUID) ([
 			&currentImageTag,
 			&s.CanvasX,
 			&s.CanvasY,
+			&s.RequiresApproval,
+			&s.SupportsWebsockets,
+			&s.BuildCommand,
+			&s.StartCommand,
+			&s.HealthCheckPath,
+			&s.HealthCheckInitialDelay,
+			&s.DefaultProbeDisabled,
+			&s.CreatedBy,
+			&s.UpdatedBy,
 			&s.CreatedAt,
 			&s.UpdatedAt,
 		)
@@ -232,6 +297,8 @@ func (db *DB) ListServicesByProject(ctx context.Context, projectID uuid.UUID) ([
 
 // UpdateService updates a service
 func (db *DB) UpdateService(ctx context.Context, id uuid.UUID, updates *Service) error {
+	defer db.cacheInvalidator(ctx, serviceProjectQuery, id)()
+
 	// Check if we're using SQLite (for compatibility)
 	var isSQLite bool
 	var version string
@@ -254,8 +321,16 @@ func (db *DB) UpdateService(ctx context.Context, id uuid.UUID, updates *Service)
 			    canvas_x = $6,
 			    canvas_y = $7,
 			    openstack_fip_address = $8,
+			    requires_approval = $9,
+			    supports_websockets = $10,
+			    build_command = $11,
+			    start_command = $12,
+			    health_check_path = $13,
+			    health_check_initial_delay = $14,
+			    default_probe_disabled = $15,
+			    updated_by = $16,
 			    updated_at = datetime('now')
-			WHERE id = $9
+			WHERE id = $17
 		`
 		_, err = db.ExecContext(ctx, query,
 			updates.Name,
@@ -266,6 +341,14 @@ func (db *DB) UpdateService(ctx context.Context, id uuid.UUID, updates *Service)
 			updates.CanvasX,
 			updates.CanvasY,
 			fipAddress,
+			updates.RequiresApproval,
+			updates.SupportsWebsockets,
+			updates.BuildCommand,
+			updates.StartCommand,
+			updates.HealthCheckPath,
+			updates.HealthCheckInitialDelay,
+			updates.DefaultProbeDisabled,
+			updates.UpdatedBy,
 			id.String(),
 		)
 		if err != nil {
@@ -292,8 +375,16 @@ func (db *DB) UpdateService(ctx context.Context, id uuid.UUID, updates *Service)
 		    canvas_x = $6,
 		    canvas_y = $7,
 		    openstack_fip_address = $8,
+		    requires_approval = $9,
+		    supports_websockets = $10,
+		    build_command = $11,
+		    start_command = $12,
+		    health_check_path = $13,
+		    health_check_initial_delay = $14,
+		    default_probe_disabled = $15,
+		    updated_by = $16,
 		    updated_at = now()
-		WHERE id = $9
+		WHERE id = $17
 		RETURNING updated_at
 	`
 
@@ -306,6 +397,14 @@ func (db *DB) UpdateService(ctx context.Context, id uuid.UUID, updates *Service)
 		updates.CanvasX,
 		updates.CanvasY,
 		fipAddress,
+		updates.RequiresApproval,
+		updates.SupportsWebsockets,
+		updates.BuildCommand,
+		updates.StartCommand,
+		updates.HealthCheckPath,
+		updates.HealthCheckInitialDelay,
+		updates.DefaultProbeDisabled,
+		updates.UpdatedBy,
 		id,
 	).Scan(&updates.UpdatedAt)
 
@@ -314,6 +413,8 @@ func (db *DB) UpdateService(ctx context.Context, id uuid.UUID, updates *Service)
 
 // UpdateServicePosition updates the canvas position of a service
 func (db *DB) UpdateServicePosition(ctx context.Context, id uuid.UUID, x, y int) error {
+	defer db.cacheInvalidator(ctx, serviceProjectQuery, id)()
+
 	query := `
 		UPDATE services
 		SET canvas_x = $1,
@@ -326,8 +427,28 @@ func (db *DB) UpdateServicePosition(ctx context.Context, id uuid.UUID, x, y int)
 	return err
 }
 
+// ReconcileServiceStatus sets a service's status to status if it's still from, so
+// a deploy that changed it meanwhile isn't overwritten. Returns whether it changed.
+func (db *DB) ReconcileServiceStatus(ctx context.Context, id uuid.UUID, from, status string) (bool, error) {
+	defer db.cacheInvalidator(ctx, serviceProjectQuery, id)()
+
+	query := `UPDATE services SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND status = $3`
+
+	result, err := db.ExecContext(ctx, query, status, id, from)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
 // DeleteService deletes a service
 func (db *DB) DeleteService(ctx context.Context, id uuid.UUID) error {
+	defer db.cacheInvalidator(ctx, serviceProjectQuery, id)()
+
 	query := `DELETE FROM services WHERE id = $1`
 
 	result, err := db.ExecContext(ctx, query, id)
@@ -365,3 +486,135 @@ func (db *DB) GetProjectIDForService(ctx context.Context, serviceID uuid.UUID) (
 	return projectID, err
 }
 
+
+// GetServiceErrorPage gets the custom error page HTML for a service.
+// Returns an empty string if none is configured.
+func (db *DB) GetServiceErrorPage(ctx context.Context, serviceID uuid.UUID) (string, error) {
+	var html sql.NullString
+	query := `SELECT error_page_html FROM services WHERE id = $1`
+
+	err := db.QueryRowContext(ctx, query, serviceID).Scan(&html)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return html.String, nil
+}
+
+// SetServiceErrorPage sets the custom error page HTML for a service.
+// An empty string clears it.
+func (db *DB) SetServiceErrorPage(ctx context.Context, serviceID uuid.UUID, html string) error {
+	query := `UPDATE services SET error_page_html = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+
+	result, err := db.ExecContext(ctx, query, StringToNullString(html), serviceID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetServiceImport gets the deployment a service was imported from, which keeps running
+// until the service's first deploy. Returns empty strings for a service that wasn't
+// imported or was deployed since.
+func (db *DB) GetServiceImport(ctx context.Context, serviceID uuid.UUID) (namespace, deployment string, err error) {
+	var ns, name sql.NullString
+	query := `SELECT imported_namespace, imported_deployment FROM services WHERE id = $1`
+
+	err = db.QueryRowContext(ctx, query, serviceID).Scan(&ns, &name)
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return ns.String, name.String, nil
+}
+
+// SetServiceImport records the deployment a service was imported from.
+// Empty strings clear it.
+func (db *DB) SetServiceImport(ctx context.Context, serviceID uuid.UUID, namespace, deployment string) error {
+	query := `UPDATE services SET imported_namespace = $1, imported_deployment = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`
+
+	result, err := db.ExecContext(ctx, query, StringToNullString(namespace), StringToNullString(deployment), serviceID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// SetServiceSubdomain sets a service's generated subdomain and the URL it serves at.
+// A subdomain taken by another service fails with a unique violation.
+func (db *DB) SetServiceSubdomain(ctx context.Context, serviceID uuid.UUID, subdomain, generatedURL, updatedBy string) error {
+	defer db.cacheInvalidator(ctx, serviceProjectQuery, serviceID)()
+
+	query := `UPDATE services SET subdomain = $1, generated_url = $2, updated_by = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4`
+
+	result, err := db.ExecContext(ctx, query, StringToNullString(subdomain), StringToNullString(generatedURL), StringToNullString(updatedBy), serviceID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// MaxSubdomainAttempts bounds how many generated subdomains AllocateSubdomain tries
+const MaxSubdomainAttempts = 5
+
+// ErrSubdomainUnavailable is returned when every generated subdomain tried was taken
+var ErrSubdomainUnavailable = errors.New("no free subdomain could be allocated")
+
+// SubdomainGenerator returns a new candidate subdomain and the URL a service would
+// serve at under it
+type SubdomainGenerator func() (subdomain, generatedURL string, err error)
+
+// AllocateSubdomain gives a service a generated subdomain. The subdomain column is
+// unique, so a candidate another service claimed first, concurrently included, fails
+// the update and the next one is tried, up to MaxSubdomainAttempts.
+func (db *DB) AllocateSubdomain(ctx context.Context, serviceID uuid.UUID, generate SubdomainGenerator, updatedBy string) (string, string, error) {
+	for attempt := 0; attempt < MaxSubdomainAttempts; attempt++ {
+		subdomain, generatedURL, err := generate()
+		if err != nil {
+			return "", "", err
+		}
+
+		err = db.SetServiceSubdomain(ctx, serviceID, subdomain, generatedURL, updatedBy)
+		if IsUniqueViolationOn(err, "services", "subdomain") {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		return subdomain, generatedURL, nil
+	}
+	return "", "", ErrSubdomainUnavailable
+}
@@ -16,37 +16,11 @@ func TestDB_CreateService(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Create a project first (required for foreign key)
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	projectID := uuid.New()
-	if isSQLite {
-		_, err = db.ExecContext(ctx, `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id) 
-			VALUES ($1, $2, $3, $4, $5)`, 
-			projectID.String(), "test-org", "Test Project", "test-project", "test-tenant")
-		if err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-	} else {
-		project := &Project{
-			ID:                projectID,
-			CasdoorOrgID:      "test-org",
-			Name:              "Test Project",
-			Slug:              "test-project",
-			OpenStackTenantID: "test-tenant",
-			AutoDeploy:        true,
-		}
-		if err := dbStore.CreateProject(ctx, project); err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-	}
+	project := testutil.NewProject(t, db)
 
 	// Create a service
 	service := &Service{
-		ProjectID:    projectID,
+		ProjectID:    project.ID,
 		Name:         "Test Service",
 		Type:         "app",
 		Status:       "pending",
@@ -55,24 +29,8 @@ func TestDB_CreateService(t *testing.T) {
 		CanvasX:      100,
 		CanvasY:      200,
 	}
-
-	if isSQLite {
-		service.ID = uuid.New()
-		query := `INSERT INTO services (id, project_id, name, type, status, instance_size, port, canvas_x, canvas_y) 
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-		_, err = db.ExecContext(ctx, query,
-			service.ID.String(), service.ProjectID.String(), service.Name, service.Type,
-			service.Status, service.InstanceSize, service.Port, service.CanvasX, service.CanvasY)
-		if err != nil {
-			t.Fatalf("Failed to create service: %v", err)
-		}
-		db.QueryRowContext(ctx, "SELECT created_at, updated_at FROM services WHERE id = $1", service.ID.String()).
-			Scan(&service.CreatedAt, &service.UpdatedAt)
-	} else {
-		err = dbStore.CreateService(ctx, service)
-		if err != nil {
-			t.Fatalf("Failed to create service: %v", err)
-		}
+	if err := dbStore.CreateService(ctx, service); err != nil {
+		t.Fatalf("Failed to create service: %v", err)
 	}
 
 	if service.ID == uuid.Nil {
@@ -92,53 +50,8 @@ func TestDB_GetService(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Setup: Create project and service
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	projectID := uuid.New()
-	serviceID := uuid.New()
-
-	if isSQLite {
-		_, err = db.ExecContext(ctx, `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id) 
-			VALUES ($1, $2, $3, $4, $5)`, 
-			projectID.String(), "test-org", "Test Project", "test-project", "test-tenant")
-		if err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		_, err = db.ExecContext(ctx, `INSERT INTO services (id, project_id, name, type, status, instance_size, port) 
-			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-			serviceID.String(), projectID.String(), "Test Service", "app", "pending", "medium", 8080)
-		if err != nil {
-			t.Fatalf("Failed to create test service: %v", err)
-		}
-	} else {
-		project := &Project{
-			ID:                projectID,
-			CasdoorOrgID:      "test-org",
-			Name:              "Test Project",
-			Slug:              "test-project",
-			OpenStackTenantID: "test-tenant",
-			AutoDeploy:        true,
-		}
-		if err := dbStore.CreateProject(ctx, project); err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		service := &Service{
-			ID:          serviceID,
-			ProjectID:   projectID,
-			Name:        "Test Service",
-			Type:        "app",
-			Status:      "pending",
-			InstanceSize: "medium",
-			Port:        8080,
-		}
-		if err := dbStore.CreateService(ctx, service); err != nil {
-			t.Fatalf("Failed to create test service: %v", err)
-		}
-	}
+	project := testutil.NewProject(t, db)
+	serviceID := testutil.NewService(t, db, project.ID).ID
 
 	// Retrieve the service
 	retrieved, err := dbStore.GetService(ctx, serviceID)
@@ -177,87 +90,16 @@ func TestDB_ListServicesByProject(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Setup
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	projectID := uuid.New()
-	otherProjectID := uuid.New()
-
-	if isSQLite {
-		_, err = db.ExecContext(ctx, `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id) 
-			VALUES ($1, $2, $3, $4, $5)`, 
-			projectID.String(), "test-org", "Test Project", "test-project", "test-tenant")
-		if err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		_, err = db.ExecContext(ctx, `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id) 
-			VALUES ($1, $2, $3, $4, $5)`, 
-			otherProjectID.String(), "test-org", "Other Project", "other-project", "test-tenant")
-		if err != nil {
-			t.Fatalf("Failed to create other project: %v", err)
-		}
-
-		service1ID := uuid.New()
-		service2ID := uuid.New()
-		otherServiceID := uuid.New()
-
-		_, err = db.ExecContext(ctx, `INSERT INTO services (id, project_id, name, type, status) 
-			VALUES ($1, $2, $3, $4, $5)`,
-			service1ID.String(), projectID.String(), "Service 1", "app", "pending")
-		if err != nil {
-			t.Fatalf("Failed to create service 1: %v", err)
-		}
-		_, err = db.ExecContext(ctx, `INSERT INTO services (id, project_id, name, type, status) 
-			VALUES ($1, $2, $3, $4, $5)`,
-			service2ID.String(), projectID.String(), "Service 2", "app", "pending")
-		if err != nil {
-			t.Fatalf("Failed to create service 2: %v", err)
-		}
-		_, err = db.ExecContext(ctx, `INSERT INTO services (id, project_id, name, type, status) 
-			VALUES ($1, $2, $3, $4, $5)`,
-			otherServiceID.String(), otherProjectID.String(), "Other Service", "app", "pending")
-		if err != nil {
-			t.Fatalf("Failed to create other service: %v", err)
-		}
-	} else {
-		project := &Project{
-			ID:                projectID,
-			CasdoorOrgID:      "test-org",
-			Name:              "Test Project",
-			Slug:              "test-project",
-			OpenStackTenantID: "test-tenant",
-			AutoDeploy:        true,
-		}
-		if err := dbStore.CreateProject(ctx, project); err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
+	project := testutil.NewProject(t, db)
+	otherProject := testutil.NewProject(t, db, func(p *testutil.Project) { p.Name = "Other Project" })
+	projectID := project.ID
 
-		service1 := &Service{
-			ProjectID:    projectID,
-			Name:         "Service 1",
-			Type:         "app",
-			Status:       "pending",
-			InstanceSize: "medium",
-			Port:         8080,
-		}
-		service2 := &Service{
-			ProjectID:    projectID,
-			Name:         "Service 2",
-			Type:         "app",
-			Status:       "pending",
-			InstanceSize: "medium",
-			Port:         8081,
-		}
-		if err := dbStore.CreateService(ctx, service1); err != nil {
-			t.Fatalf("Failed to create service 1: %v", err)
-		}
-		if err := dbStore.CreateService(ctx, service2); err != nil {
-			t.Fatalf("Failed to create service 2: %v", err)
-		}
-	}
+	testutil.NewService(t, db, projectID, func(s *testutil.Service) { s.Name = "Service 1" })
+	testutil.NewService(t, db, projectID, func(s *testutil.Service) {
+		s.Name = "Service 2"
+		s.Port = 8081
+	})
+	testutil.NewService(t, db, otherProject.ID, func(s *testutil.Service) { s.Name = "Other Service" })
 
 	// List services for the project
 	services, err := dbStore.ListServicesByProject(ctx, projectID)
@@ -285,53 +127,8 @@ func TestDB_UpdateService(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Setup
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	projectID := uuid.New()
-	serviceID := uuid.New()
-
-	if isSQLite {
-		_, err = db.ExecContext(ctx, `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id) 
-			VALUES ($1, $2, $3, $4, $5)`, 
-			projectID.String(), "test-org", "Test Project", "test-project", "test-tenant")
-		if err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		_, err = db.ExecContext(ctx, `INSERT INTO services (id, project_id, name, type, status, instance_size, port) 
-			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-			serviceID.String(), projectID.String(), "Original Name", "app", "pending", "medium", 8080)
-		if err != nil {
-			t.Fatalf("Failed to create test service: %v", err)
-		}
-	} else {
-		project := &Project{
-			ID:                projectID,
-			CasdoorOrgID:      "test-org",
-			Name:              "Test Project",
-			Slug:              "test-project",
-			OpenStackTenantID: "test-tenant",
-			AutoDeploy:        true,
-		}
-		if err := dbStore.CreateProject(ctx, project); err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		service := &Service{
-			ID:           serviceID,
-			ProjectID:    projectID,
-			Name:         "Original Name",
-			Type:         "app",
-			Status:       "pending",
-			InstanceSize: "medium",
-			Port:         8080,
-		}
-		if err := dbStore.CreateService(ctx, service); err != nil {
-			t.Fatalf("Failed to create test service: %v", err)
-		}
-	}
+	project := testutil.NewProject(t, db)
+	serviceID := testutil.NewService(t, db, project.ID, func(s *testutil.Service) { s.Name = "Original Name" }).ID
 
 	// Update the service
 	updates := &Service{
@@ -341,7 +138,8 @@ func TestDB_UpdateService(t *testing.T) {
 		Port:         9090,
 	}
 
-	if isSQLite {
+	var err error
+	if testutil.IsSQLite(db) {
 		query := `UPDATE services SET name = $1, status = $2, instance_size = $3, port = $4, 
 			updated_at = datetime('now') WHERE id = $5`
 		_, err = db.ExecContext(ctx, query, updates.Name, updates.Status, updates.InstanceSize, 
@@ -387,56 +185,11 @@ func TestDB_DeleteService(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Setup
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	projectID := uuid.New()
-	serviceID := uuid.New()
-
-	if isSQLite {
-		_, err = db.ExecContext(ctx, `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id) 
-			VALUES ($1, $2, $3, $4, $5)`, 
-			projectID.String(), "test-org", "Test Project", "test-project", "test-tenant")
-		if err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		_, err = db.ExecContext(ctx, `INSERT INTO services (id, project_id, name, type, status) 
-			VALUES ($1, $2, $3, $4, $5)`,
-			serviceID.String(), projectID.String(), "Test Service", "app", "pending")
-		if err != nil {
-			t.Fatalf("Failed to create test service: %v", err)
-		}
-	} else {
-		project := &Project{
-			ID:                projectID,
-			CasdoorOrgID:      "test-org",
-			Name:              "Test Project",
-			Slug:              "test-project",
-			OpenStackTenantID: "test-tenant",
-			AutoDeploy:        true,
-		}
-		if err := dbStore.CreateProject(ctx, project); err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		service := &Service{
-			ID:           serviceID,
-			ProjectID:    projectID,
-			Name:         "Test Service",
-			Type:         "app",
-			Status:       "pending",
-			InstanceSize: "medium",
-			Port:         8080,
-		}
-		if err := dbStore.CreateService(ctx, service); err != nil {
-			t.Fatalf("Failed to create test service: %v", err)
-		}
-	}
+	project := testutil.NewProject(t, db)
+	serviceID := testutil.NewService(t, db, project.ID).ID
 
 	// Delete the service
-	err = dbStore.DeleteService(ctx, serviceID)
+	err := dbStore.DeleteService(ctx, serviceID)
 	if err != nil {
 		t.Fatalf("Failed to delete service: %v", err)
 	}
@@ -452,3 +205,85 @@ func TestDB_DeleteService(t *testing.T) {
 	}
 }
 
+func TestDB_ReconcileServiceStatus(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	serviceID := testutil.NewService(t, db, project.ID, func(s *testutil.Service) { s.Status = ServiceStatusRunning }).ID
+
+	changed, err := dbStore.ReconcileServiceStatus(ctx, serviceID, ServiceStatusRunning, ServiceStatusMissing)
+	if err != nil {
+		t.Fatalf("Failed to reconcile service status: %v", err)
+	}
+	if !changed {
+		t.Error("Status should change from running to missing")
+	}
+
+	// The status moved on since it was read, e.g. a deploy started
+	changed, err = dbStore.ReconcileServiceStatus(ctx, serviceID, ServiceStatusRunning, ServiceStatusDegraded)
+	if err != nil {
+		t.Fatalf("Failed to reconcile service status: %v", err)
+	}
+	if changed {
+		t.Error("Status should not change when it's no longer the one read")
+	}
+
+	service, err := dbStore.GetService(ctx, serviceID)
+	if err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+	if service.Status != ServiceStatusMissing {
+		t.Errorf("Status = %q, want %q", service.Status, ServiceStatusMissing)
+	}
+}
+
+
+func TestDB_AllocateSubdomain(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	first := testutil.NewService(t, db, project.ID)
+	second := testutil.NewService(t, db, project.ID)
+
+	if err := dbStore.SetServiceSubdomain(ctx, first.ID, "web-aaaaaa", "https://web-aaaaaa.example.com", ""); err != nil {
+		t.Fatalf("Failed to set subdomain: %v", err)
+	}
+
+	// A taken candidate is skipped for the next one
+	candidates := []string{"web-aaaaaa", "web-bbbbbb"}
+	generate := func() (string, string, error) {
+		label := candidates[0]
+		candidates = candidates[1:]
+		return label, "https://" + label + ".example.com", nil
+	}
+	subdomain, generatedURL, err := dbStore.AllocateSubdomain(ctx, second.ID, generate, "")
+	if err != nil {
+		t.Fatalf("AllocateSubdomain error: %v", err)
+	}
+	if subdomain != "web-bbbbbb" || generatedURL != "https://web-bbbbbb.example.com" {
+		t.Errorf("AllocateSubdomain = %q, %q, want web-bbbbbb", subdomain, generatedURL)
+	}
+
+	// Only taken candidates give up after the bounded attempts
+	attempts := 0
+	alwaysTaken := func() (string, string, error) {
+		attempts++
+		return "web-aaaaaa", "", nil
+	}
+	if _, _, err := dbStore.AllocateSubdomain(ctx, second.ID, alwaysTaken, ""); err != ErrSubdomainUnavailable {
+		t.Errorf("AllocateSubdomain error = %v, want ErrSubdomainUnavailable", err)
+	}
+	if attempts != MaxSubdomainAttempts {
+		t.Errorf("AllocateSubdomain tried %d candidates, want %d", attempts, MaxSubdomainAttempts)
+	}
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// OrgSummary holds aggregate resource counts of an org
+type OrgSummary struct {
+	Projects              int
+	ServicesByStatus      map[string]int
+	DatabasesByEngine     map[string]int
+	Volumes               int
+	VolumeSizeMB          int64
+	CustomDomainsByStatus map[string]int
+}
+
+// GetOrgSummary counts the resources of an org's projects in the database, without
+// loading them. Databases count towards the project of their service, or of their
+// volume when they have no service.
+func (db *DB) GetOrgSummary(ctx context.Context, orgID string) (*OrgSummary, error) {
+	summary := &OrgSummary{}
+
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM projects WHERE casdoor_org_id = $1`, orgID).Scan(&summary.Projects); err != nil {
+		return nil, fmt.Errorf("failed to count projects: %w", err)
+	}
+
+	var err error
+	summary.ServicesByStatus, err = db.countGrouped(ctx, `
+		SELECT COALESCE(s.status, 'pending'), COUNT(*)
+		FROM services s
+		JOIN projects p ON p.id = s.project_id
+		WHERE p.casdoor_org_id = $1
+		GROUP BY COALESCE(s.status, 'pending')`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count services: %w", err)
+	}
+
+	summary.DatabasesByEngine, err = db.countGrouped(ctx, `
+		SELECT d.engine, COUNT(*)
+		FROM databases d
+		LEFT JOIN services s ON s.id = d.service_id
+		LEFT JOIN volumes v ON v.id = d.volume_id
+		JOIN projects p ON p.id = COALESCE(s.project_id, v.project_id)
+		WHERE p.casdoor_org_id = $1
+		GROUP BY d.engine`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count databases: %w", err)
+	}
+
+	// Includes the volumes auto-created for databases
+	err = db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(v.size_mb), 0)
+		FROM volumes v
+		JOIN projects p ON p.id = v.project_id
+		WHERE p.casdoor_org_id = $1`, orgID).Scan(&summary.Volumes, &summary.VolumeSizeMB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count volumes: %w", err)
+	}
+
+	summary.CustomDomainsByStatus, err = db.countGrouped(ctx, `
+		SELECT cd.status, COUNT(*)
+		FROM custom_domains cd
+		JOIN services s ON s.id = cd.service_id
+		JOIN projects p ON p.id = s.project_id
+		WHERE p.casdoor_org_id = $1
+		GROUP BY cd.status`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count custom domains: %w", err)
+	}
+
+	return summary, nil
+}
+
+// countGrouped runs a query selecting (key, count) rows and returns the counts by key
+func (db *DB) countGrouped(ctx context.Context, query string, args ...interface{}) (map[string]int, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var key string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			return nil, err
+		}
+		counts[key] = count
+	}
+	return counts, rows.Err()
+}
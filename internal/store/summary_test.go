@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestDB_GetOrgSummary(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &DB{DB: db}
+	ctx := context.Background()
+
+	project := testutil.NewProject(t, db)
+	running := testutil.NewService(t, db, project.ID, func(s *testutil.Service) { s.Status = "running" })
+	testutil.NewService(t, db, project.ID, func(s *testutil.Service) { s.Status = "running" })
+	testutil.NewService(t, db, project.ID)
+	testutil.NewDatabase(t, db, running.ID)
+	testutil.NewDatabase(t, db, running.ID, func(d *testutil.Database) { d.Engine = "redis" })
+	testutil.NewVolume(t, db, project.ID, func(v *testutil.Volume) { v.SizeMB = 1024 })
+	testutil.NewVolume(t, db, project.ID, func(v *testutil.Volume) { v.SizeMB = 2048 })
+	if _, err := db.ExecContext(ctx, "INSERT INTO custom_domains (id, service_id, domain, status) VALUES ('d1', $1, 'app.example.com', 'active')", running.ID); err != nil {
+		t.Fatalf("Failed to create custom domain: %v", err)
+	}
+
+	// Resources of another org aren't counted
+	other := testutil.NewProject(t, db, func(p *testutil.Project) { p.OrgID = "other-org" })
+	testutil.NewService(t, db, other.ID)
+	testutil.NewVolume(t, db, other.ID)
+
+	summary, err := dbStore.GetOrgSummary(ctx, "test-org")
+	if err != nil {
+		t.Fatalf("Failed to get org summary: %v", err)
+	}
+	if summary.Projects != 1 {
+		t.Errorf("Projects = %d, want 1", summary.Projects)
+	}
+	if summary.ServicesByStatus["running"] != 2 || summary.ServicesByStatus["pending"] != 1 || len(summary.ServicesByStatus) != 2 {
+		t.Errorf("ServicesByStatus = %v, want running: 2, pending: 1", summary.ServicesByStatus)
+	}
+	if summary.DatabasesByEngine["postgresql"] != 1 || summary.DatabasesByEngine["redis"] != 1 {
+		t.Errorf("DatabasesByEngine = %v, want postgresql: 1, redis: 1", summary.DatabasesByEngine)
+	}
+	if summary.Volumes != 2 || summary.VolumeSizeMB != 3072 {
+		t.Errorf("Volumes = %d (%d MB), want 2 (3072 MB)", summary.Volumes, summary.VolumeSizeMB)
+	}
+	if summary.CustomDomainsByStatus["active"] != 1 {
+		t.Errorf("CustomDomainsByStatus = %v, want active: 1", summary.CustomDomainsByStatus)
+	}
+
+	empty, err := dbStore.GetOrgSummary(ctx, "no-such-org")
+	if err != nil {
+		t.Fatalf("Failed to get org summary: %v", err)
+	}
+	if empty.Projects != 0 || len(empty.ServicesByStatus) != 0 || empty.VolumeSizeMB != 0 {
+		t.Errorf("Expected an empty summary, got %+v", empty)
+	}
+}
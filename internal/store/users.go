@@ -230,3 +230,24 @@ func hashPassword(password string) (string, error) {
 	return string(bytes), nil
 }
 
+
+// GetUserLoginAlerts returns whether a user gets emails about new sign-ins and
+// account lockouts
+func (db *DB) GetUserLoginAlerts(ctx context.Context, userID string) (bool, error) {
+	var enabled bool
+	err := db.QueryRowContext(ctx, `SELECT login_alerts_enabled FROM users WHERE id = $1`, userID).Scan(&enabled)
+	return enabled, err
+}
+
+// SetUserLoginAlerts opts a user in or out of emails about new sign-ins and account
+// lockouts. Returns sql.ErrNoRows if the user doesn't exist.
+func (db *DB) SetUserLoginAlerts(ctx context.Context, userID string, enabled bool) error {
+	result, err := db.ExecContext(ctx, `UPDATE users SET login_alerts_enabled = $2, updated_at = now() WHERE id = $1`, userID, enabled)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -1,6 +1,15 @@
 package store
 
-import "database/sql"
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pgUniqueViolation is the PostgreSQL error code for unique constraint violations
+const pgUniqueViolation = "23505"
 
 // StringToNullString converts a string to sql.NullString
 func StringToNullString(s string) sql.NullString {
@@ -10,3 +19,28 @@ func StringToNullString(s string) sql.NullString {
 	return sql.NullString{String: s, Valid: true}
 }
 
+// IsUniqueViolation reports whether err is a unique constraint violation (PostgreSQL or SQLite)
+func IsUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// IsUniqueViolationOn reports whether err is a unique constraint violation of the
+// column of table: by the constraint's name on PostgreSQL, which defaults to
+// <table>_<column>_key, and by the message on SQLite
+func IsUniqueViolationOn(err error, table, column string) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation && pgErr.ConstraintName == table+"_"+column+"_key"
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed: "+table+"."+column)
+}
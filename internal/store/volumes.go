@@ -20,22 +20,27 @@ type Volume struct {
 	OpenStackAttachmentID sql.NullString
 	Status              string // pending, available, attached, error
 	VolumeType          string // user, database_auto
+	CreatedBy           sql.NullString // User who created the volume
+	UpdatedBy           sql.NullString // User who last attached or detached it
 	CreatedAt           time.Time
 }
 
 // CreateVolume creates a new volume
 func (db *DB) CreateVolume(ctx context.Context, v *Volume) error {
+	// Check if we're using SQLite (for compatibility)
+	var versionStr string
+	isSQLite := db.QueryRow("SELECT sqlite_version()").Scan(&versionStr) == nil
+
+	return insertVolume(ctx, db, isSQLite, v)
+}
+
+// insertVolume inserts a volume with q, the database or a transaction
+func insertVolume(ctx context.Context, q execer, isSQLite bool, v *Volume) error {
 	// Generate UUID if not set (for SQLite compatibility)
 	if v.ID == uuid.Nil {
 		v.ID = uuid.New()
 	}
 
-	// Check if we're using SQLite (for compatibility)
-	var isSQLite bool
-	var versionStr string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&versionStr)
-	isSQLite = err == nil
-
 	var mountPath interface{}
 	if v.MountPath.Valid {
 		mountPath = v.MountPath.String
@@ -45,18 +50,19 @@ func (db *DB) CreateVolume(ctx context.Context, v *Volume) error {
 		// SQLite: Insert with explicit UUID (no RETURNING support in older versions)
 		query := `
 			INSERT INTO volumes (
-				id, project_id, name, size_mb, mount_path, volume_type, status
-			) VALUES ($1, $2, $3, $4, $5, $6, $7)
+				id, project_id, name, size_mb, mount_path, volume_type, status,
+				created_by, updated_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		`
-		_, err = db.ExecContext(ctx, query,
+		_, err := q.ExecContext(ctx, query,
 			v.ID.String(), v.ProjectID.String(), v.Name, v.SizeMB,
-			mountPath, v.VolumeType, v.Status,
+			mountPath, v.VolumeType, v.Status, v.CreatedBy, v.UpdatedBy,
 		)
 		if err != nil {
 			return err
 		}
 		// Get timestamp
-		err = db.QueryRowContext(ctx, "SELECT created_at FROM volumes WHERE id = $1", v.ID.String()).
+		err = q.QueryRowContext(ctx, "SELECT created_at FROM volumes WHERE id = $1", v.ID.String()).
 			Scan(&v.CreatedAt)
 		return err
 	}
@@ -64,18 +70,21 @@ func (db *DB) CreateVolume(ctx context.Context, v *Volume) error {
 	// PostgreSQL: Use RETURNING clause
 	query := `
 		INSERT INTO volumes (
-			project_id, name, size_mb, mount_path, volume_type, status
-		) VALUES ($1, $2, $3, $4, $5, $6)
+			project_id, name, size_mb, mount_path, volume_type, status,
+			created_by, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at
 	`
 
-	err = db.QueryRowContext(ctx, query,
+	err := q.QueryRowContext(ctx, query,
 		v.ProjectID,
 		v.Name,
 		v.SizeMB,
 		mountPath,
 		v.VolumeType,
 		v.Status,
+		v.CreatedBy,
+		v.UpdatedBy,
 	).Scan(&v.ID, &v.CreatedAt)
 
 	return err
@@ -87,7 +96,7 @@ func (db *DB) GetVolume(ctx context.Context, id uuid.UUID) (*Volume, error) {
 		SELECT id, project_id, name, size_mb, mount_path,
 		       attached_to_service_id, attached_to_database_id,
 		       openstack_volume_id, openstack_attachment_id,
-		       status, volume_type, created_at
+		       status, volume_type, created_by, updated_by, created_at
 		FROM volumes
 		WHERE id = $1
 	`
@@ -111,6 +120,8 @@ func (db *DB) GetVolume(ctx context.Context, id uuid.UUID) (*Volume, error) {
 		&openstackAttachmentID,
 		&v.Status,
 		&v.VolumeType,
+		&v.CreatedBy,
+		&v.UpdatedBy,
 		&v.CreatedAt,
 	)
 
@@ -137,7 +148,7 @@ func (db *DB) ListVolumesByProject(ctx context.Context, projectID uuid.UUID) ([]
 		SELECT id, project_id, name, size_mb, mount_path,
 		       attached_to_service_id, attached_to_database_id,
 		       openstack_volume_id, openstack_attachment_id,
-		       status, volume_type, created_at
+		       status, volume_type, created_by, updated_by, created_at
 		FROM volumes
 		WHERE project_id = $1
 		ORDER BY created_at DESC
@@ -170,6 +181,8 @@ func (db *DB) ListVolumesByProject(ctx context.Context, projectID uuid.UUID) ([]
 			&openstackAttachmentID,
 			&v.Status,
 			&v.VolumeType,
+			&v.CreatedBy,
+			&v.UpdatedBy,
 			&v.CreatedAt,
 		)
 		if err != nil {
@@ -236,26 +249,26 @@ func (db *DB) UpdateVolume(ctx context.Context, id uuid.UUID, v *Volume) error {
 }
 
 // AttachVolumeToService attaches a volume to a service
-func (db *DB) AttachVolumeToService(ctx context.Context, volumeID uuid.UUID, serviceID uuid.UUID, mountPath string) error {
+func (db *DB) AttachVolumeToService(ctx context.Context, volumeID uuid.UUID, serviceID uuid.UUID, mountPath, updatedBy string) error {
 	query := `
 		UPDATE volumes
-		SET attached_to_service_id = $1, mount_path = $2, status = 'attached'
-		WHERE id = $3
+		SET attached_to_service_id = $1, mount_path = $2, status = 'attached', updated_by = $3
+		WHERE id = $4
 	`
 
-	_, err := db.ExecContext(ctx, query, serviceID.String(), mountPath, volumeID)
+	_, err := db.ExecContext(ctx, query, serviceID.String(), mountPath, StringToNullString(updatedBy), volumeID)
 	return err
 }
 
 // DetachVolumeFromService detaches a volume from a service
-func (db *DB) DetachVolumeFromService(ctx context.Context, volumeID uuid.UUID) error {
+func (db *DB) DetachVolumeFromService(ctx context.Context, volumeID uuid.UUID, updatedBy string) error {
 	query := `
 		UPDATE volumes
-		SET attached_to_service_id = NULL, mount_path = NULL, status = 'available'
-		WHERE id = $1
+		SET attached_to_service_id = NULL, mount_path = NULL, status = 'available', updated_by = $1
+		WHERE id = $2
 	`
 
-	_, err := db.ExecContext(ctx, query, volumeID)
+	_, err := db.ExecContext(ctx, query, StringToNullString(updatedBy), volumeID)
 	return err
 }
 
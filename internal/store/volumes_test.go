@@ -17,60 +17,18 @@ func TestDB_CreateVolume(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Setup: Create a project first
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	projectID := uuid.New()
-	if isSQLite {
-		_, err = db.ExecContext(ctx, `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id) 
-			VALUES ($1, $2, $3, $4, $5)`, 
-			projectID.String(), "test-org", "Test Project", "test-project", "test-tenant")
-		if err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-	} else {
-		project := &Project{
-			ID:                projectID,
-			CasdoorOrgID:      "test-org",
-			Name:              "Test Project",
-			Slug:              "test-project",
-			OpenStackTenantID: "test-tenant",
-			AutoDeploy:        true,
-		}
-		if err := dbStore.CreateProject(ctx, project); err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-	}
+	project := testutil.NewProject(t, db)
 
 	// Create a volume
 	volume := &Volume{
-		ProjectID:  projectID,
+		ProjectID:  project.ID,
 		Name:       "Test Volume",
 		SizeMB:     10240,
 		VolumeType: "user",
 		Status:     "pending",
 	}
-
-	if isSQLite {
-		volume.ID = uuid.New()
-		query := `INSERT INTO volumes (id, project_id, name, size_mb, volume_type, status) 
-			VALUES ($1, $2, $3, $4, $5, $6)`
-		_, err = db.ExecContext(ctx, query,
-			volume.ID.String(), volume.ProjectID.String(), volume.Name,
-			volume.SizeMB, volume.VolumeType, volume.Status)
-		if err != nil {
-			t.Fatalf("Failed to create volume: %v", err)
-		}
-		db.QueryRowContext(ctx, "SELECT created_at FROM volumes WHERE id = $1", volume.ID.String()).
-			Scan(&volume.CreatedAt)
-	} else {
-		err = dbStore.CreateVolume(ctx, volume)
-		if err != nil {
-			t.Fatalf("Failed to create volume: %v", err)
-		}
+	if err := dbStore.CreateVolume(ctx, volume); err != nil {
+		t.Fatalf("Failed to create volume: %v", err)
 	}
 
 	if volume.ID == uuid.Nil {
@@ -90,52 +48,8 @@ func TestDB_GetVolume(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Setup
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	projectID := uuid.New()
-	volumeID := uuid.New()
-
-	if isSQLite {
-		_, err = db.ExecContext(ctx, `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id) 
-			VALUES ($1, $2, $3, $4, $5)`, 
-			projectID.String(), "test-org", "Test Project", "test-project", "test-tenant")
-		if err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		_, err = db.ExecContext(ctx, `INSERT INTO volumes (id, project_id, name, size_mb, volume_type, status) 
-			VALUES ($1, $2, $3, $4, $5, $6)`,
-			volumeID.String(), projectID.String(), "Test Volume", 10240, "user", "pending")
-		if err != nil {
-			t.Fatalf("Failed to create test volume: %v", err)
-		}
-	} else {
-		project := &Project{
-			ID:                projectID,
-			CasdoorOrgID:      "test-org",
-			Name:              "Test Project",
-			Slug:              "test-project",
-			OpenStackTenantID: "test-tenant",
-			AutoDeploy:        true,
-		}
-		if err := dbStore.CreateProject(ctx, project); err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		volume := &Volume{
-			ID:         volumeID,
-			ProjectID:  projectID,
-			Name:       "Test Volume",
-			SizeMB:     10240,
-			VolumeType: "user",
-			Status:     "pending",
-		}
-		if err := dbStore.CreateVolume(ctx, volume); err != nil {
-			t.Fatalf("Failed to create test volume: %v", err)
-		}
-	}
+	project := testutil.NewProject(t, db)
+	volumeID := testutil.NewVolume(t, db, project.ID).ID
 
 	// Retrieve the volume
 	retrieved, err := dbStore.GetVolume(ctx, volumeID)
@@ -174,85 +88,17 @@ func TestDB_ListVolumesByProject(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Setup
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	projectID := uuid.New()
-	otherProjectID := uuid.New()
-
-	if isSQLite {
-		_, err = db.ExecContext(ctx, `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id) 
-			VALUES ($1, $2, $3, $4, $5)`, 
-			projectID.String(), "test-org", "Test Project", "test-project", "test-tenant")
-		if err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		_, err = db.ExecContext(ctx, `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id) 
-			VALUES ($1, $2, $3, $4, $5)`, 
-			otherProjectID.String(), "test-org", "Other Project", "other-project", "test-tenant")
-		if err != nil {
-			t.Fatalf("Failed to create other project: %v", err)
-		}
-
-		volume1ID := uuid.New()
-		volume2ID := uuid.New()
-		otherVolumeID := uuid.New()
-
-		_, err = db.ExecContext(ctx, `INSERT INTO volumes (id, project_id, name, size_mb, volume_type, status) 
-			VALUES ($1, $2, $3, $4, $5, $6)`,
-			volume1ID.String(), projectID.String(), "Volume 1", 10240, "user", "pending")
-		if err != nil {
-			t.Fatalf("Failed to create volume 1: %v", err)
-		}
-		_, err = db.ExecContext(ctx, `INSERT INTO volumes (id, project_id, name, size_mb, volume_type, status) 
-			VALUES ($1, $2, $3, $4, $5, $6)`,
-			volume2ID.String(), projectID.String(), "Volume 2", 20480, "user", "available")
-		if err != nil {
-			t.Fatalf("Failed to create volume 2: %v", err)
-		}
-		_, err = db.ExecContext(ctx, `INSERT INTO volumes (id, project_id, name, size_mb, volume_type, status) 
-			VALUES ($1, $2, $3, $4, $5, $6)`,
-			otherVolumeID.String(), otherProjectID.String(), "Other Volume", 10240, "user", "pending")
-		if err != nil {
-			t.Fatalf("Failed to create other volume: %v", err)
-		}
-	} else {
-		project := &Project{
-			ID:                projectID,
-			CasdoorOrgID:      "test-org",
-			Name:              "Test Project",
-			Slug:              "test-project",
-			OpenStackTenantID: "test-tenant",
-			AutoDeploy:        true,
-		}
-		if err := dbStore.CreateProject(ctx, project); err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
+	project := testutil.NewProject(t, db)
+	otherProject := testutil.NewProject(t, db, func(p *testutil.Project) { p.Name = "Other Project" })
+	projectID := project.ID
 
-		volume1 := &Volume{
-			ProjectID:  projectID,
-			Name:       "Volume 1",
-			SizeMB:     10240,
-			VolumeType: "user",
-			Status:     "pending",
-		}
-		volume2 := &Volume{
-			ProjectID:  projectID,
-			Name:       "Volume 2",
-			SizeMB:     20480,
-			VolumeType: "user",
-			Status:     "available",
-		}
-		if err := dbStore.CreateVolume(ctx, volume1); err != nil {
-			t.Fatalf("Failed to create volume 1: %v", err)
-		}
-		if err := dbStore.CreateVolume(ctx, volume2); err != nil {
-			t.Fatalf("Failed to create volume 2: %v", err)
-		}
-	}
+	testutil.NewVolume(t, db, projectID, func(v *testutil.Volume) { v.Name = "Volume 1" })
+	testutil.NewVolume(t, db, projectID, func(v *testutil.Volume) {
+		v.Name = "Volume 2"
+		v.SizeMB = 20480
+		v.Status = "available"
+	})
+	testutil.NewVolume(t, db, otherProject.ID, func(v *testutil.Volume) { v.Name = "Other Volume" })
 
 	// List volumes for the project
 	volumes, err := dbStore.ListVolumesByProject(ctx, projectID)
@@ -280,52 +126,8 @@ func TestDB_UpdateVolume(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Setup
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	projectID := uuid.New()
-	volumeID := uuid.New()
-
-	if isSQLite {
-		_, err = db.ExecContext(ctx, `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id) 
-			VALUES ($1, $2, $3, $4, $5)`, 
-			projectID.String(), "test-org", "Test Project", "test-project", "test-tenant")
-		if err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		_, err = db.ExecContext(ctx, `INSERT INTO volumes (id, project_id, name, size_mb, volume_type, status) 
-			VALUES ($1, $2, $3, $4, $5, $6)`,
-			volumeID.String(), projectID.String(), "Original Name", 10240, "user", "pending")
-		if err != nil {
-			t.Fatalf("Failed to create test volume: %v", err)
-		}
-	} else {
-		project := &Project{
-			ID:                projectID,
-			CasdoorOrgID:      "test-org",
-			Name:              "Test Project",
-			Slug:              "test-project",
-			OpenStackTenantID: "test-tenant",
-			AutoDeploy:        true,
-		}
-		if err := dbStore.CreateProject(ctx, project); err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		volume := &Volume{
-			ID:         volumeID,
-			ProjectID:  projectID,
-			Name:       "Original Name",
-			SizeMB:     10240,
-			VolumeType: "user",
-			Status:     "pending",
-		}
-		if err := dbStore.CreateVolume(ctx, volume); err != nil {
-			t.Fatalf("Failed to create test volume: %v", err)
-		}
-	}
+	project := testutil.NewProject(t, db)
+	volumeID := testutil.NewVolume(t, db, project.ID, func(v *testutil.Volume) { v.Name = "Original Name" }).ID
 
 	// Update the volume
 	updates := &Volume{
@@ -334,7 +136,8 @@ func TestDB_UpdateVolume(t *testing.T) {
 		MountPath:  sql.NullString{String: "/mnt/data", Valid: true},
 	}
 
-	if isSQLite {
+	var err error
+	if testutil.IsSQLite(db) {
 		query := `UPDATE volumes SET name = $1, status = $2, mount_path = $3 WHERE id = $4`
 		_, err = db.ExecContext(ctx, query, updates.Name, updates.Status, updates.MountPath.String, volumeID.String())
 		if err != nil {
@@ -375,55 +178,11 @@ func TestDB_DeleteVolume(t *testing.T) {
 	dbStore := &DB{DB: db}
 	ctx := context.Background()
 
-	// Setup
-	var isSQLite bool
-	var version string
-	err := db.QueryRow("SELECT sqlite_version()").Scan(&version)
-	isSQLite = err == nil
-
-	projectID := uuid.New()
-	volumeID := uuid.New()
-
-	if isSQLite {
-		_, err = db.ExecContext(ctx, `INSERT INTO projects (id, casdoor_org_id, name, slug, openstack_tenant_id) 
-			VALUES ($1, $2, $3, $4, $5)`, 
-			projectID.String(), "test-org", "Test Project", "test-project", "test-tenant")
-		if err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		_, err = db.ExecContext(ctx, `INSERT INTO volumes (id, project_id, name, size_mb, volume_type, status) 
-			VALUES ($1, $2, $3, $4, $5, $6)`,
-			volumeID.String(), projectID.String(), "Test Volume", 10240, "user", "pending")
-		if err != nil {
-			t.Fatalf("Failed to create test volume: %v", err)
-		}
-	} else {
-		project := &Project{
-			ID:                projectID,
-			CasdoorOrgID:      "test-org",
-			Name:              "Test Project",
-			Slug:              "test-project",
-			OpenStackTenantID: "test-tenant",
-			AutoDeploy:        true,
-		}
-		if err := dbStore.CreateProject(ctx, project); err != nil {
-			t.Fatalf("Failed to create test project: %v", err)
-		}
-		volume := &Volume{
-			ID:         volumeID,
-			ProjectID:  projectID,
-			Name:       "Test Volume",
-			SizeMB:     10240,
-			VolumeType: "user",
-			Status:     "pending",
-		}
-		if err := dbStore.CreateVolume(ctx, volume); err != nil {
-			t.Fatalf("Failed to create test volume: %v", err)
-		}
-	}
+	project := testutil.NewProject(t, db)
+	volumeID := testutil.NewVolume(t, db, project.ID).ID
 
 	// Delete the volume
-	err = dbStore.DeleteVolume(ctx, volumeID)
+	err := dbStore.DeleteVolume(ctx, volumeID)
 	if err != nil {
 		t.Fatalf("Failed to delete volume: %v", err)
 	}
@@ -0,0 +1,261 @@
+package testutil
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Test data factories insert rows with plain SQL that runs on both the SQLite and
+// the PostgreSQL test database, so tests don't need a branch per dialect. They
+// can't use the store package: store's own tests import testutil.
+//
+// Each factory fills in sensible defaults, applies the overrides in order, inserts
+// the row and returns it with its generated timestamps.
+
+// Project is a projects row created by NewProject
+type Project struct {
+	ID        uuid.UUID
+	OrgID     string
+	Name      string
+	Slug      string
+	TenantID  string
+	CreatedAt time.Time
+}
+
+// Service is a services row created by NewService
+type Service struct {
+	ID           uuid.UUID
+	ProjectID    uuid.UUID
+	Name         string
+	Type         string
+	Status       string
+	InstanceSize string
+	Port         int
+	CreatedAt    time.Time
+}
+
+// Volume is a volumes row created by NewVolume
+type Volume struct {
+	ID         uuid.UUID
+	ProjectID  uuid.UUID
+	Name       string
+	SizeMB     int
+	VolumeType string
+	Status     string
+	CreatedAt  time.Time
+}
+
+// Database is a databases row created by NewDatabase
+type Database struct {
+	ID        uuid.UUID
+	ServiceID uuid.UUID // uuid.Nil for a database without service
+	Name      string
+	Engine    string
+	Version   string
+	Size      string
+	Status    string
+	CreatedAt time.Time
+}
+
+// Deployment is a deployments row created by NewDeployment
+type Deployment struct {
+	ID          uuid.UUID
+	ServiceID   uuid.UUID
+	CommitSHA   string
+	Status      string
+	TriggeredBy string
+	CreatedAt   time.Time
+}
+
+// IsSQLite reports whether db is the SQLite test database rather than PostgreSQL
+func IsSQLite(db *sql.DB) bool {
+	var version string
+	return db.QueryRow("SELECT sqlite_version()").Scan(&version) == nil
+}
+
+// NewProject creates a project in the test-org organization, with a unique slug
+func NewProject(t *testing.T, db *sql.DB, overrides ...func(*Project)) *Project {
+	t.Helper()
+
+	id := uuid.New()
+	p := &Project{
+		ID:       id,
+		OrgID:    "test-org",
+		Name:     "Test Project",
+		Slug:     "test-project-" + id.String()[:8],
+		TenantID: "test-tenant",
+	}
+	for _, override := range overrides {
+		override(p)
+	}
+
+	insertRow(t, db, "projects", map[string]interface{}{
+		"id":                  p.ID.String(),
+		"casdoor_org_id":      p.OrgID,
+		"name":                p.Name,
+		"slug":                p.Slug,
+		"openstack_tenant_id": p.TenantID,
+	})
+	p.CreatedAt = createdAt(t, db, "projects", p.ID)
+	return p
+}
+
+// NewService creates an app service in the project
+func NewService(t *testing.T, db *sql.DB, projectID uuid.UUID, overrides ...func(*Service)) *Service {
+	t.Helper()
+
+	s := &Service{
+		ID:           uuid.New(),
+		ProjectID:    projectID,
+		Name:         "Test Service",
+		Type:         "app",
+		Status:       "pending",
+		InstanceSize: "medium",
+		Port:         8080,
+	}
+	for _, override := range overrides {
+		override(s)
+	}
+
+	insertRow(t, db, "services", map[string]interface{}{
+		"id":            s.ID.String(),
+		"project_id":    s.ProjectID.String(),
+		"name":          s.Name,
+		"type":          s.Type,
+		"status":        s.Status,
+		"instance_size": s.InstanceSize,
+		"port":          s.Port,
+	})
+	s.CreatedAt = createdAt(t, db, "services", s.ID)
+	return s
+}
+
+// NewVolume creates a detached 10GB user volume in the project
+func NewVolume(t *testing.T, db *sql.DB, projectID uuid.UUID, overrides ...func(*Volume)) *Volume {
+	t.Helper()
+
+	v := &Volume{
+		ID:         uuid.New(),
+		ProjectID:  projectID,
+		Name:       "Test Volume",
+		SizeMB:     10240,
+		VolumeType: "user",
+		Status:     "pending",
+	}
+	for _, override := range overrides {
+		override(v)
+	}
+
+	insertRow(t, db, "volumes", map[string]interface{}{
+		"id":          v.ID.String(),
+		"project_id":  v.ProjectID.String(),
+		"name":        v.Name,
+		"size_mb":     v.SizeMB,
+		"volume_type": v.VolumeType,
+		"status":      v.Status,
+	})
+	v.CreatedAt = createdAt(t, db, "volumes", v.ID)
+	return v
+}
+
+// NewDatabase creates a small PostgreSQL database for the service
+func NewDatabase(t *testing.T, db *sql.DB, serviceID uuid.UUID, overrides ...func(*Database)) *Database {
+	t.Helper()
+
+	d := &Database{
+		ID:        uuid.New(),
+		ServiceID: serviceID,
+		Name:      "test-db",
+		Engine:    "postgresql",
+		Version:   "16",
+		Size:      "small",
+		Status:    "pending",
+	}
+	for _, override := range overrides {
+		override(d)
+	}
+
+	var serviceIDValue interface{}
+	if d.ServiceID != uuid.Nil {
+		serviceIDValue = d.ServiceID.String()
+	}
+	insertRow(t, db, "databases", map[string]interface{}{
+		"id":         d.ID.String(),
+		"service_id": serviceIDValue,
+		"name":       d.Name,
+		"engine":     d.Engine,
+		"version":    d.Version,
+		"size":       d.Size,
+		"status":     d.Status,
+	})
+	d.CreatedAt = createdAt(t, db, "databases", d.ID)
+	return d
+}
+
+// NewDeployment creates a queued manual deployment of the service
+func NewDeployment(t *testing.T, db *sql.DB, serviceID uuid.UUID, overrides ...func(*Deployment)) *Deployment {
+	t.Helper()
+
+	d := &Deployment{
+		ID:          uuid.New(),
+		ServiceID:   serviceID,
+		CommitSHA:   "0123456789abcdef0123456789abcdef01234567",
+		Status:      "queued",
+		TriggeredBy: "manual",
+	}
+	for _, override := range overrides {
+		override(d)
+	}
+
+	insertRow(t, db, "deployments", map[string]interface{}{
+		"id":           d.ID.String(),
+		"service_id":   d.ServiceID.String(),
+		"commit_sha":   d.CommitSHA,
+		"status":       d.Status,
+		"triggered_by": d.TriggeredBy,
+	})
+	d.CreatedAt = createdAt(t, db, "deployments", d.ID)
+	return d
+}
+
+// insertRow inserts a row into table, failing the test on error. Columns are
+// sorted so the generated statement is stable.
+func insertRow(t *testing.T, db *sql.DB, table string, values map[string]interface{}) {
+	t.Helper()
+
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	placeholders := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = values[column]
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("Failed to create test %s row: %v", table, err)
+	}
+}
+
+// createdAt reads back the created_at the database set for a row
+func createdAt(t *testing.T, db *sql.DB, table string, id uuid.UUID) time.Time {
+	t.Helper()
+
+	var created time.Time
+	if err := db.QueryRow(fmt.Sprintf("SELECT created_at FROM %s WHERE id = $1", table), id.String()).Scan(&created); err != nil {
+		t.Fatalf("Failed to read back test %s row: %v", table, err)
+	}
+	return created
+}
@@ -0,0 +1,67 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFactories(t *testing.T) {
+	db, cleanup := SetupTestDB(t)
+	defer cleanup()
+	RunMigrations(t, db)
+
+	project := NewProject(t, db)
+	if project.ID == uuid.Nil || project.CreatedAt.IsZero() {
+		t.Fatalf("Expected project ID and created_at to be set, got %+v", project)
+	}
+
+	// Default slugs are unique, so several projects fit in one organization
+	other := NewProject(t, db, func(p *Project) { p.Name = "Other Project" })
+	if other.Slug == project.Slug {
+		t.Errorf("Expected unique slugs, both are %q", project.Slug)
+	}
+
+	service := NewService(t, db, project.ID, func(s *Service) { s.Port = 3000 })
+	var port int
+	var projectID string
+	if err := db.QueryRow("SELECT port, project_id FROM services WHERE id = $1", service.ID.String()).Scan(&port, &projectID); err != nil {
+		t.Fatalf("Failed to read service: %v", err)
+	}
+	if port != 3000 || projectID != project.ID.String() {
+		t.Errorf("Expected service on port 3000 in project %s, got port %d in project %s", project.ID, port, projectID)
+	}
+
+	if !IsSQLite(db) {
+		// The PostgreSQL test schema has no deployments, volumes or databases tables
+		return
+	}
+
+	deployment := NewDeployment(t, db, service.ID, func(d *Deployment) { d.Status = "success" })
+	var status string
+	if err := db.QueryRow("SELECT status FROM deployments WHERE id = $1", deployment.ID.String()).Scan(&status); err != nil {
+		t.Fatalf("Failed to read deployment: %v", err)
+	}
+	if status != "success" {
+		t.Errorf("Expected deployment status success, got %s", status)
+	}
+
+	volume := NewVolume(t, db, project.ID, func(v *Volume) { v.SizeMB = 2048 })
+	var sizeMB int
+	if err := db.QueryRow("SELECT size_mb FROM volumes WHERE id = $1", volume.ID.String()).Scan(&sizeMB); err != nil {
+		t.Fatalf("Failed to read volume: %v", err)
+	}
+	if sizeMB != 2048 {
+		t.Errorf("Expected volume of 2048MB, got %d", sizeMB)
+	}
+
+	// A database without service stores a NULL service_id
+	database := NewDatabase(t, db, uuid.Nil)
+	var serviceID *string
+	if err := db.QueryRow("SELECT service_id FROM databases WHERE id = $1", database.ID.String()).Scan(&serviceID); err != nil {
+		t.Fatalf("Failed to read database: %v", err)
+	}
+	if serviceID != nil {
+		t.Errorf("Expected NULL service_id, got %s", *serviceID)
+	}
+}
@@ -107,9 +107,15 @@ func RunMigrations(t *testing.T, db *sql.DB) {
 				openstack_network_id TEXT,
 				default_region TEXT,
 				auto_deploy INTEGER DEFAULT 1,
+				image_retention_count INTEGER,
+				image_tag_strategy TEXT,
+				default_instance_size TEXT,
+				default_port INTEGER,
 				created_by TEXT,
 				created_at DATETIME DEFAULT (datetime('now')),
 				updated_at DATETIME DEFAULT (datetime('now')),
+				org_id TEXT,
+				user_id TEXT,
 				UNIQUE(casdoor_org_id, slug)
 			)`,
 			// Services table
@@ -129,8 +135,20 @@ func RunMigrations(t *testing.T, db *sql.DB) {
 				subdomain TEXT UNIQUE,
 				generated_url TEXT,
 				current_image_tag TEXT,
+				error_page_html TEXT,
 				canvas_x INTEGER DEFAULT 0,
 				canvas_y INTEGER DEFAULT 0,
+				requires_approval INTEGER NOT NULL DEFAULT 0,
+				supports_websockets INTEGER NOT NULL DEFAULT 0,
+				build_command TEXT,
+				start_command TEXT,
+				health_check_path TEXT,
+				health_check_initial_delay INTEGER,
+				default_probe_disabled INTEGER NOT NULL DEFAULT 0,
+				imported_namespace TEXT,
+				imported_deployment TEXT,
+				created_by TEXT,
+				updated_by TEXT,
 				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 			)`,
@@ -146,7 +164,8 @@ func RunMigrations(t *testing.T, db *sql.DB) {
 				completed_at DATETIME,
 				attempts INTEGER DEFAULT 0,
 				max_attempts INTEGER DEFAULT 3,
-				error_message TEXT,
+				error TEXT,
+				run_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 			)`,
@@ -171,6 +190,10 @@ func RunMigrations(t *testing.T, db *sql.DB) {
 				openstack_port_id TEXT,
 				security_group_id TEXT,
 				status TEXT DEFAULT 'pending',
+				parameters TEXT NOT NULL DEFAULT '{}',
+				storage_class TEXT,
+				created_by TEXT,
+				updated_by TEXT,
 				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 			)`,
 			// Volumes table
@@ -186,6 +209,8 @@ func RunMigrations(t *testing.T, db *sql.DB) {
 				openstack_attachment_id TEXT,
 				status TEXT DEFAULT 'pending',
 				volume_type TEXT DEFAULT 'user',
+				created_by TEXT,
+				updated_by TEXT,
 				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 			)`,
 			// Git connections table
@@ -212,8 +237,12 @@ func RunMigrations(t *testing.T, db *sql.DB) {
 				repo_name TEXT NOT NULL,
 				branch TEXT NOT NULL,
 				root_dir TEXT,
+				trigger_mode TEXT NOT NULL DEFAULT 'branch',
+				tag_pattern TEXT,
 				webhook_id TEXT,
 				webhook_secret TEXT,
+				previous_webhook_secret TEXT,
+				previous_webhook_secret_expires_at DATETIME,
 				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 			)`,
 			// Deployments table
@@ -225,12 +254,19 @@ func RunMigrations(t *testing.T, db *sql.DB) {
 				commit_author TEXT,
 				status TEXT NOT NULL DEFAULT 'queued',
 				image_tag TEXT,
+				git_tag TEXT,
 				build_duration INTEGER,
 				deploy_duration INTEGER,
 				error_message TEXT,
 				triggered_by TEXT NOT NULL DEFAULT 'manual',
+				requested_by TEXT,
+				approved_by TEXT,
+				approved_at DATETIME,
 				started_at DATETIME,
 				finished_at DATETIME,
+				config_snapshot TEXT,
+				rolled_back_from_id TEXT,
+				rollback_target_id TEXT,
 				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 			)`,
 			// Custom domains table
@@ -247,7 +283,19 @@ func RunMigrations(t *testing.T, db *sql.DB) {
 				validation_token TEXT,
 				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-				verified_at DATETIME
+				verified_at DATETIME,
+				awaiting_service_ready INTEGER NOT NULL DEFAULT 0,
+				dns_check_failures INTEGER NOT NULL DEFAULT 0,
+				status_before_dns_failure TEXT
+			)`,
+			// Custom domain path routes table
+			`CREATE TABLE IF NOT EXISTS custom_domain_routes (
+				id TEXT PRIMARY KEY,
+				domain_id TEXT NOT NULL REFERENCES custom_domains(id) ON DELETE CASCADE,
+				path_prefix TEXT NOT NULL,
+				service_id TEXT NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (domain_id, path_prefix)
 			)`,
 			// Environment variables table
 			`CREATE TABLE IF NOT EXISTS env_vars (
@@ -258,9 +306,130 @@ func RunMigrations(t *testing.T, db *sql.DB) {
 				is_secret INTEGER DEFAULT 0,
 				linked_database_id TEXT,
 				link_type TEXT,
+				project_env_var_id TEXT,
+				secret_ref TEXT,
+				linked_service_id TEXT,
+				interpolate INTEGER NOT NULL DEFAULT 0,
 				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 				UNIQUE(service_id, key)
 			)`,
+			// Project environment variables table
+			`CREATE TABLE IF NOT EXISTS project_env_vars (
+				id TEXT PRIMARY KEY,
+				project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+				key TEXT NOT NULL,
+				value TEXT,
+				is_secret INTEGER DEFAULT 0,
+				linked_database_id TEXT,
+				link_type TEXT,
+				interpolate INTEGER NOT NULL DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(project_id, key)
+			)`,
+			// Audit log table
+			`CREATE TABLE IF NOT EXISTS audit_log (
+				id TEXT PRIMARY KEY,
+				org_id TEXT NOT NULL,
+				actor_id TEXT,
+				action TEXT NOT NULL,
+				resource_type TEXT NOT NULL,
+				resource_id TEXT NOT NULL,
+				metadata TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			// Environment variable schema table
+			`CREATE TABLE IF NOT EXISTS env_schema (
+				id TEXT PRIMARY KEY,
+				service_id TEXT NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+				key TEXT NOT NULL,
+				required INTEGER NOT NULL DEFAULT 0,
+				type TEXT NOT NULL DEFAULT 'string',
+				pattern TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(service_id, key)
+			)`,
+			// Build args table
+			`CREATE TABLE IF NOT EXISTS build_args (
+				id TEXT PRIMARY KEY,
+				service_id TEXT NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+				key TEXT NOT NULL,
+				value TEXT NOT NULL,
+				is_secret INTEGER NOT NULL DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(service_id, key)
+			)`,
+			// Orphaned cloud resources table
+			`CREATE TABLE IF NOT EXISTS orphaned_cloud_resources (
+				id TEXT PRIMARY KEY,
+				tenant_id TEXT NOT NULL,
+				resource_type TEXT NOT NULL,
+				resource_id TEXT NOT NULL,
+				name TEXT,
+				first_seen_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				last_seen_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(resource_type, resource_id)
+			)`,
+			// Service scaling schedules table
+			`CREATE TABLE IF NOT EXISTS service_scaling_schedules (
+				id TEXT PRIMARY KEY,
+				service_id TEXT NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+				cron_expression TEXT NOT NULL,
+				replicas INTEGER NOT NULL,
+				timezone TEXT NOT NULL DEFAULT 'UTC',
+				position INTEGER NOT NULL DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			// Organizations table
+			`CREATE TABLE IF NOT EXISTS organizations (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL,
+				slug TEXT UNIQUE NOT NULL,
+				owner_id TEXT NOT NULL,
+				plan TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			// Organization members table
+			`CREATE TABLE IF NOT EXISTS org_members (
+				org_id TEXT NOT NULL,
+				user_id TEXT NOT NULL,
+				role TEXT DEFAULT 'member',
+				joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (org_id, user_id)
+			)`,
+			// Refresh tokens table, one row per session
+			`CREATE TABLE IF NOT EXISTS refresh_tokens (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				token_hash TEXT NOT NULL,
+				expires_at DATETIME NOT NULL,
+				revoked BOOLEAN DEFAULT 0,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				user_agent TEXT,
+				ip_address TEXT,
+				last_used_at DATETIME
+			)`,
+			// Login attempts table
+			`CREATE TABLE IF NOT EXISTS login_attempts (
+				key TEXT PRIMARY KEY,
+				failures INTEGER NOT NULL DEFAULT 0,
+				window_started_at DATETIME NOT NULL,
+				lockouts INTEGER NOT NULL DEFAULT 0,
+				locked_until DATETIME,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			// Known devices table
+			`CREATE TABLE IF NOT EXISTS user_known_devices (
+				user_id TEXT NOT NULL,
+				kind TEXT NOT NULL,
+				value TEXT NOT NULL,
+				first_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (user_id, kind, value)
+			)`,
 		}
 
 		for _, migration := range migrations {
@@ -285,9 +454,15 @@ func RunMigrations(t *testing.T, db *sql.DB) {
 				openstack_network_id VARCHAR(255),
 				default_region VARCHAR(100),
 				auto_deploy BOOLEAN DEFAULT true,
+				image_retention_count INT,
+				image_tag_strategy VARCHAR(32),
+				default_instance_size VARCHAR(20),
+				default_port INTEGER,
 				created_by VARCHAR(255),
 				created_at TIMESTAMPTZ DEFAULT now(),
 				updated_at TIMESTAMPTZ DEFAULT now(),
+				org_id UUID,
+				user_id UUID,
 				UNIQUE(casdoor_org_id, slug)
 			)`,
 			// Services table
@@ -307,8 +482,20 @@ func RunMigrations(t *testing.T, db *sql.DB) {
 				subdomain VARCHAR(100) UNIQUE,
 				generated_url TEXT,
 				current_image_tag VARCHAR(255),
+				error_page_html TEXT,
 				canvas_x INT DEFAULT 0,
 				canvas_y INT DEFAULT 0,
+				requires_approval BOOLEAN NOT NULL DEFAULT false,
+				supports_websockets BOOLEAN NOT NULL DEFAULT false,
+				build_command TEXT,
+				start_command TEXT,
+				health_check_path TEXT,
+				health_check_initial_delay INTEGER,
+				default_probe_disabled BOOLEAN NOT NULL DEFAULT false,
+				imported_namespace VARCHAR(253),
+				imported_deployment VARCHAR(253),
+				created_by VARCHAR(255),
+				updated_by VARCHAR(255),
 				created_at TIMESTAMPTZ DEFAULT now(),
 				updated_at TIMESTAMPTZ DEFAULT now()
 			)`,
@@ -324,7 +511,8 @@ func RunMigrations(t *testing.T, db *sql.DB) {
 				completed_at TIMESTAMPTZ,
 				attempts INTEGER DEFAULT 0,
 				max_attempts INTEGER DEFAULT 3,
-				error_message TEXT,
+				error TEXT,
+				run_at TIMESTAMPTZ DEFAULT now(),
 				created_at TIMESTAMPTZ DEFAULT now(),
 				updated_at TIMESTAMPTZ DEFAULT now()
 			)`,
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestJobPayloadRoundTrip(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), parent)
+
+	payload := map[string]interface{}{"deployment_id": "d1"}
+	InjectJobPayload(ctx, payload)
+
+	// Payloads are stored as JSON, so extract from a decoded copy
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var stored map[string]interface{}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	got := trace.SpanContextFromContext(ExtractJobPayload(context.Background(), stored))
+	if got.TraceID() != traceID || got.SpanID() != spanID {
+		t.Errorf("extracted span context = %s/%s, want %s/%s", got.TraceID(), got.SpanID(), traceID, spanID)
+	}
+	if !got.IsRemote() {
+		t.Error("extracted span context should be remote")
+	}
+}
+
+func TestExtractJobPayloadWithoutTraceContext(t *testing.T) {
+	ctx := ExtractJobPayload(context.Background(), map[string]interface{}{"deployment_id": "d1"})
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		t.Error("expected no span context for a payload without trace context")
+	}
+}
@@ -3,18 +3,23 @@ package worker
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/intelifox/click-deploy/internal/build"
 	"github.com/intelifox/click-deploy/internal/config"
 	"github.com/intelifox/click-deploy/internal/git"
 	"github.com/intelifox/click-deploy/internal/realtime"
+	"github.com/intelifox/click-deploy/internal/secrets"
 	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/tracing"
 )
 
 // BuildWorker processes build jobs
@@ -54,10 +59,7 @@ func NewBuildWorker(store *store.DB, cfg *config.Config) (*BuildWorker, error) {
 	)
 
 	// Create build directory
-	buildDir := cfg.BuildDir
-	if buildDir == "" {
-		buildDir = "/tmp/click-deploy-builds"
-	}
+	buildDir := buildDirectory(cfg)
 	if err := os.MkdirAll(buildDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create build directory: %w", err)
 	}
@@ -82,7 +84,10 @@ func (w *BuildWorker) Close() error {
 }
 
 // ProcessBuildJob processes a build job for a deployment
-func (w *BuildWorker) ProcessBuildJob(ctx context.Context, deploymentID uuid.UUID) error {
+func (w *BuildWorker) ProcessBuildJob(ctx context.Context, deploymentID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "build.ProcessBuildJob", attribute.String("deployment.id", deploymentID.String()))
+	defer func() { tracing.End(span, err) }()
+
 	// Get deployment
 	deployment, err := w.store.GetDeployment(ctx, deploymentID)
 	if err != nil {
@@ -100,76 +105,45 @@ func (w *BuildWorker) ProcessBuildJob(ctx context.Context, deploymentID uuid.UUI
 	if service == nil {
 		return fmt.Errorf("service not found: %s", deployment.ServiceID)
 	}
+	span.SetAttributes(attribute.String("service.id", service.ID.String()))
 
-	// Get git source
-	gitSource, err := w.store.GetGitSourceByService(ctx, deployment.ServiceID)
-	if err != nil {
-		return fmt.Errorf("failed to get git source: %w", err)
-	}
-	if gitSource == nil {
-		return fmt.Errorf("git source not found for service: %s", deployment.ServiceID)
-	}
-
-	// Get git connection
-	gitConnection, err := w.store.GetGitConnection(ctx, gitSource.GitConnectionID)
-	if err != nil {
-		return fmt.Errorf("failed to get git connection: %w", err)
-	}
-	if gitConnection == nil {
-		return fmt.Errorf("git connection not found: %s", gitSource.GitConnectionID)
+	// Check the env schema before spending time on a build that can't be deployed
+	if err := checkServiceEnv(ctx, w.store, service.ID); err != nil {
+		w.log(ctx, deploymentID, "validate", "error", err.Error(), nil)
+		w.setStatus(ctx, service.ID, deploymentID, "failed")
+		return err
 	}
 
 	// Update deployment status
-	w.store.UpdateDeploymentStatus(ctx, deploymentID, "building")
+	w.setStatus(ctx, service.ID, deploymentID, "building")
 	w.log(ctx, deploymentID, "clone", "info", "Starting build process", nil)
 
-	// Clone repository
-	clonePath := filepath.Join(w.buildDir, deploymentID.String())
-	defer os.RemoveAll(clonePath) // Clean up after build
-
-	cloneOpts := git.CloneOptions{
-		URL:      fmt.Sprintf("https://%s/%s/%s.git", gitSource.Provider, gitSource.RepoOwner, gitSource.RepoName),
-		Branch:   gitSource.Branch,
-		Token:    gitConnection.AccessToken,
-		Provider: gitSource.Provider,
+	// Uploaded archive deployments build the archive instead of a git checkout
+	var source *buildSource
+	if deployment.TriggeredBy == store.DeploymentTriggerArchive {
+		source, err = w.extractArchiveSource(ctx, deployment)
+	} else {
+		source, err = w.checkoutGitSource(ctx, deployment)
 	}
-
-	if deployment.CommitSHA.Valid {
-		cloneOpts.Commit = deployment.CommitSHA.String
+	if source != nil {
+		defer git.CleanupRepository(source.path) // Clean up after build
 	}
-
-	w.log(ctx, deploymentID, "clone", "info",
-		fmt.Sprintf("Cloning repository: %s/%s (branch: %s)", gitSource.RepoOwner, gitSource.RepoName, gitSource.Branch), nil)
-
-	cloneResult, err := git.CloneRepository(ctx, cloneOpts, w.buildDir)
 	if err != nil {
-		w.log(ctx, deploymentID, "clone", "error",
-			fmt.Sprintf("Failed to clone repository: %v", err), nil)
-		w.store.UpdateDeploymentStatus(ctx, deploymentID, "failed")
+		w.setStatus(ctx, service.ID, deploymentID, "failed")
 		w.store.UpdateDeploymentProgress(ctx, deploymentID, map[string]interface{}{
 			"error_message": err.Error(),
 			"finished_at":   time.Now(),
 		})
-		return fmt.Errorf("failed to clone repository: %w", err)
+		return err
 	}
+	buildContextPath := source.contextPath
 
-	w.log(ctx, deploymentID, "clone", "info",
-		fmt.Sprintf("Repository cloned successfully (commit: %s)", cloneResult.CommitSHA), nil)
-
-	// Determine build context path
-	buildContextPath := clonePath
-	if gitSource.RootDir.Valid && gitSource.RootDir.String != "" && gitSource.RootDir.String != "/" {
-		buildContextPath = filepath.Join(clonePath, gitSource.RootDir.String)
+	// Build image tag following the project's strategy
+	imageTag, err := w.resolveImageTag(ctx, deployment, service, source.branch, source.commitSHA, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to resolve image tag: %w", err)
 	}
 
-	// Build image tag
-	imageTag := build.BuildImageTag(
-		w.config.RegistryURL,
-		service.Name,
-		service.Name,
-		deployment.CommitSHA.String,
-	)
-
 	// Check if Dockerfile exists
 	dockerfilePath := filepath.Join(buildContextPath, "Dockerfile")
 	useRailpack := true
@@ -180,6 +154,23 @@ func (w *BuildWorker) ProcessBuildJob(ctx context.Context, deploymentID uuid.UUI
 			"Dockerfile detected, using Dockerfile build", nil)
 	}
 
+	// Build-time args and secrets, kept out of the runtime env
+	buildArgs, buildSecrets, err := w.loadBuildArgs(ctx, service.ID)
+	if err != nil {
+		w.log(ctx, deploymentID, "build", "error",
+			fmt.Sprintf("Failed to load build args: %v", err), nil)
+		w.setStatus(ctx, service.ID, deploymentID, "failed")
+		w.store.UpdateDeploymentProgress(ctx, deploymentID, map[string]interface{}{
+			"error_message": err.Error(),
+			"finished_at":   time.Now(),
+		})
+		return fmt.Errorf("failed to load build args: %w", err)
+	}
+	if len(buildArgs) > 0 || len(buildSecrets) > 0 {
+		w.log(ctx, deploymentID, "build", "info",
+			fmt.Sprintf("Using %d build args and %d build secrets", len(buildArgs), len(buildSecrets)), nil)
+	}
+
 	buildStartTime := time.Now()
 
 	// Build image
@@ -189,8 +180,16 @@ func (w *BuildWorker) ProcessBuildJob(ctx context.Context, deploymentID uuid.UUI
 			"Using Railpack for zero-config build", nil)
 
 		railpackOpts := build.RailpackBuildOptions{
-			ContextPath: buildContextPath,
-			ImageTag:    imageTag,
+			ContextPath:  buildContextPath,
+			ImageTag:     imageTag,
+			BuildCommand: service.BuildCommand.String,
+			StartCommand: service.StartCommand.String,
+			BuildArgs:    buildArgs,
+			Secrets:      buildSecrets,
+		}
+		if service.BuildCommand.Valid {
+			w.log(ctx, deploymentID, "build", "info",
+				fmt.Sprintf("Using custom build command: %s", service.BuildCommand.String), nil)
 		}
 
 		err = w.railpackClient.Build(ctx, railpackOpts)
@@ -198,11 +197,17 @@ func (w *BuildWorker) ProcessBuildJob(ctx context.Context, deploymentID uuid.UUI
 		// Use BuildKit with Dockerfile
 		w.log(ctx, deploymentID, "build", "info",
 			"Building with Dockerfile", nil)
+		if service.BuildCommand.Valid {
+			w.log(ctx, deploymentID, "build", "warn",
+				"Custom build command ignored: the Dockerfile defines the build", nil)
+		}
 
 		buildOpts := build.BuildOptions{
 			ContextPath:    buildContextPath,
 			DockerfilePath: "Dockerfile",
 			ImageTag:       imageTag,
+			BuildArgs:      buildArgs,
+			Secrets:        buildSecrets,
 			RegistryAuth: map[string]build.AuthConfig{
 				w.config.RegistryURL: w.registryClient.AuthConfig(),
 			},
@@ -212,9 +217,11 @@ func (w *BuildWorker) ProcessBuildJob(ctx context.Context, deploymentID uuid.UUI
 	}
 
 	if err != nil {
+		// Build output may echo a secret, it must not reach the logs
+		err = errors.New(redactSecrets(err.Error(), buildSecrets))
 		w.log(ctx, deploymentID, "build", "error",
 			fmt.Sprintf("Build failed: %v", err), nil)
-		w.store.UpdateDeploymentStatus(ctx, deploymentID, "failed")
+		w.setStatus(ctx, service.ID, deploymentID, "failed")
 		w.store.UpdateDeploymentProgress(ctx, deploymentID, map[string]interface{}{
 			"error_message": err.Error(),
 			"build_duration": int64(time.Since(buildStartTime).Seconds()),
@@ -238,9 +245,12 @@ func (w *BuildWorker) ProcessBuildJob(ctx context.Context, deploymentID uuid.UUI
 	w.log(ctx, deploymentID, "push", "info",
 		"Verifying image in registry", nil)
 
-	if err := w.registryClient.VerifyImage(ctx, imageTag); err != nil {
+	pushCtx, pushSpan := tracing.Start(ctx, "registry.push", attribute.String("image.tag", imageTag))
+	verifyErr := w.registryClient.VerifyImage(pushCtx, imageTag)
+	tracing.End(pushSpan, verifyErr)
+	if verifyErr != nil {
 		w.log(ctx, deploymentID, "push", "error",
-			fmt.Sprintf("Failed to verify image: %v", err), nil)
+			fmt.Sprintf("Failed to verify image: %v", verifyErr), nil)
 		// Don't fail here, BuildKit should have pushed it
 	}
 
@@ -260,18 +270,228 @@ func (w *BuildWorker) ProcessBuildJob(ctx context.Context, deploymentID uuid.UUI
 	return nil
 }
 
+// loadBuildArgs returns the service's build args and its decrypted build secrets,
+// by key
+func (w *BuildWorker) loadBuildArgs(ctx context.Context, serviceID uuid.UUID) (map[string]string, map[string]string, error) {
+	stored, err := w.store.ListBuildArgsByService(ctx, serviceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	buildArgs := make(map[string]string)
+	buildSecrets := make(map[string]string)
+	var cipher *secrets.Cipher
+	for _, arg := range stored {
+		if !arg.IsSecret {
+			buildArgs[arg.Key] = arg.Value
+			continue
+		}
+
+		if cipher == nil {
+			if cipher, err = secrets.NewCipher(w.config.EncryptionKey); err != nil {
+				return nil, nil, err
+			}
+		}
+		value, err := cipher.Decrypt(arg.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("build secret %s: %w", arg.Key, err)
+		}
+		buildSecrets[arg.Key] = value
+	}
+
+	return buildArgs, buildSecrets, nil
+}
+
+// redactSecrets masks the values of build secrets in build output
+func redactSecrets(output string, buildSecrets map[string]string) string {
+	for _, value := range buildSecrets {
+		if value != "" {
+			output = strings.ReplaceAll(output, value, "***")
+		}
+	}
+	return output
+}
+
+// resolveImageTag returns the full image tag of a build under the project's image tag
+// strategy. Tags an earlier deployment of the service already used get the build time
+// appended, so each deployment keeps its own image for rollbacks. The service's
+// repository is its own, so no other service's deployments can use its tags.
+func (w *BuildWorker) resolveImageTag(ctx context.Context, deployment *store.Deployment, service *store.Service, branch, clonedSHA string, builtAt time.Time) (string, error) {
+	project, err := w.store.GetProject(ctx, service.ProjectID)
+	if err != nil {
+		return "", err
+	}
+	strategy := build.ImageTagStrategyCommit
+	if project != nil && project.ImageTagStrategy.Valid {
+		strategy = project.ImageTagStrategy.String
+	}
+
+	src := build.ImageTagSource{
+		CommitSHA: deployment.CommitSHA.String,
+		Branch:    branch,
+		GitTag:    deployment.GitTag.String,
+		BuiltAt:   builtAt,
+	}
+	if src.CommitSHA == "" {
+		src.CommitSHA = clonedSHA
+	}
+	// Tag deployments are tagged after the commit the tag points at
+	if src.GitTag != "" {
+		src.CommitSHA = clonedSHA
+	}
+
+	tag := build.StrategyImageTag(strategy, src)
+	imageTag := build.ServiceImageTag(w.config.RegistryURL, service.Name, service.ID.String(), tag)
+
+	used, err := w.store.IsImageTagUsed(ctx, service.ID, deployment.ID, imageTag)
+	if err != nil {
+		return "", err
+	}
+	if used {
+		imageTag = build.ServiceImageTag(w.config.RegistryURL, service.Name, service.ID.String(), build.UniqueImageTag(tag, builtAt))
+	}
+	return imageTag, nil
+}
+
 func (w *BuildWorker) log(ctx context.Context, deploymentID uuid.UUID, phase, level, message string, metadata map[string]interface{}) {
 	_ = w.store.AddDeploymentLog(ctx, deploymentID, phase, level, message, metadata)
 
 	// Best-effort publish to Centrifugo (deployment:<id>)
 	if w.publisher != nil {
-		_ = w.publisher.Publish(ctx, "deployment:"+deploymentID.String(), map[string]any{
-			"timestamp": time.Now().UTC(),
-			"phase":     phase,
-			"level":     level,
-			"message":   message,
-			"metadata":  metadata,
-		})
+		_ = realtime.PublishDeploymentLog(ctx, w.publisher, deploymentID, phase, level, message, metadata)
+	}
+}
+
+// setStatus moves a deployment to status and publishes the change
+func (w *BuildWorker) setStatus(ctx context.Context, serviceID, deploymentID uuid.UUID, status string) {
+	if err := w.store.UpdateDeploymentStatus(ctx, deploymentID, status); err != nil {
+		return
 	}
+
+	// Best-effort publish to Centrifugo (deployment:<id> and service:<id>)
+	if w.publisher != nil {
+		_ = realtime.PublishDeploymentStatus(ctx, w.publisher, serviceID, deploymentID, status)
+	}
+}
+
+// buildSource is the source a deployment is built from
+type buildSource struct {
+	path        string // Checkout or extraction directory, removed after the build
+	contextPath string // Build context inside path
+	branch      string
+	commitSHA   string
 }
 
+// checkoutGitSource clones the service's repository at the deployment's commit, tag
+// or branch head
+func (w *BuildWorker) checkoutGitSource(ctx context.Context, deployment *store.Deployment) (*buildSource, error) {
+	deploymentID := deployment.ID
+
+	gitSource, err := w.store.GetGitSourceByService(ctx, deployment.ServiceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git source: %w", err)
+	}
+	if gitSource == nil {
+		return nil, fmt.Errorf("git source not found for service: %s", deployment.ServiceID)
+	}
+
+	gitConnection, err := w.store.GetGitConnection(ctx, gitSource.GitConnectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get git connection: %w", err)
+	}
+	if gitConnection == nil {
+		return nil, fmt.Errorf("git connection not found: %s", gitSource.GitConnectionID)
+	}
+
+	// Clone repository, private ones and self-managed GitLab included. The URL holds
+	// the token, so only the repository's name is logged.
+	var baseURL string
+	if gitSource.Provider == "gitlab" {
+		baseURL = w.config.GitLabBaseURL
+	}
+	cloneURL, err := git.CloneURL(gitSource.Provider, baseURL, gitSource.RepoOwner, gitSource.RepoName, gitConnection.AccessToken)
+	if err != nil {
+		w.log(ctx, deploymentID, "clone", "error", err.Error(), nil)
+		return nil, err
+	}
+	cloneOpts := git.CloneOptions{
+		URL:      cloneURL,
+		Branch:   gitSource.Branch,
+		Token:    gitConnection.AccessToken,
+		Provider: gitSource.Provider,
+	}
+
+	if deployment.CommitSHA.Valid {
+		cloneOpts.Commit = deployment.CommitSHA.String
+	}
+
+	// Tag deployments build the pushed tag rather than the branch head
+	cloneRef := fmt.Sprintf("branch: %s", gitSource.Branch)
+	if deployment.GitTag.Valid {
+		cloneOpts.Tag = deployment.GitTag.String
+		cloneRef = fmt.Sprintf("tag: %s", deployment.GitTag.String)
+	}
+
+	w.log(ctx, deploymentID, "clone", "info",
+		fmt.Sprintf("Cloning repository: %s/%s (%s)", gitSource.RepoOwner, gitSource.RepoName, cloneRef), nil)
+
+	cloneResult, err := git.CloneRepository(ctx, cloneOpts, w.buildDir)
+	if err != nil {
+		w.log(ctx, deploymentID, "clone", "error",
+			fmt.Sprintf("Failed to clone repository: %v", err), nil)
+		return nil, fmt.Errorf("failed to clone repository: %w", err)
+	}
+	source := &buildSource{path: cloneResult.Path, branch: gitSource.Branch, commitSHA: cloneResult.CommitSHA}
+
+	w.log(ctx, deploymentID, "clone", "info",
+		fmt.Sprintf("Repository cloned successfully (commit: %s)", cloneResult.CommitSHA), nil)
+
+	// Build from root_dir for monorepos; the full repo is cloned so the subdirectory
+	// becomes the build context and runtime detection root
+	source.contextPath, err = git.ResolveBuildContext(cloneResult.Path, gitSource.RootDir.String)
+	if err != nil {
+		w.log(ctx, deploymentID, "build", "error",
+			fmt.Sprintf("Invalid root directory: %v", err), nil)
+		return source, fmt.Errorf("invalid root directory: %w", err)
+	}
+	if source.contextPath != cloneResult.Path {
+		w.log(ctx, deploymentID, "build", "info",
+			fmt.Sprintf("Using root directory: %s", gitSource.RootDir.String), nil)
+	}
+
+	return source, nil
+}
+
+// extractArchiveSource extracts the source archive uploaded for the deployment. The
+// archive is removed either way, it's only kept until its build.
+func (w *BuildWorker) extractArchiveSource(ctx context.Context, deployment *store.Deployment) (*buildSource, error) {
+	deploymentID := deployment.ID
+	archivePath := DeploymentArchivePath(w.config, deploymentID)
+	defer RemoveDeploymentArchive(w.config, deploymentID)
+
+	checksum, err := fileSHA256(archivePath)
+	if err != nil {
+		w.log(ctx, deploymentID, "clone", "error",
+			"Uploaded archive not found; it is kept in BUILD_DIR of the server it was uploaded to", nil)
+		return nil, fmt.Errorf("failed to read uploaded archive: %w", err)
+	}
+
+	w.log(ctx, deploymentID, "clone", "info", "Extracting uploaded archive", nil)
+
+	path, err := os.MkdirTemp(w.buildDir, "archive-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create build directory: %w", err)
+	}
+	source := &buildSource{path: path, branch: "archive", commitSHA: checksum}
+
+	source.contextPath, err = build.ExtractArchive(archivePath, path, w.config.MaxDeployArchiveExtractedBytes)
+	if err != nil {
+		w.log(ctx, deploymentID, "clone", "error",
+			fmt.Sprintf("Failed to extract archive: %v", err), nil)
+		return source, fmt.Errorf("failed to extract archive: %w", err)
+	}
+
+	w.log(ctx, deploymentID, "clone", "info",
+		fmt.Sprintf("Archive extracted (sha256: %s)", checksum), nil)
+	return source, nil
+}
@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -12,6 +13,53 @@ import (
 	"github.com/intelifox/click-deploy/internal/store"
 )
 
+// CleanupAction is a cloud resource a cleanup deletes, detaches or releases
+type CleanupAction struct {
+	Operation    string `json:"operation"`     // delete, detach, unregister or manual (only logged, needs an operator)
+	ResourceType string `json:"resource_type"` // instance, container, floating_ip, security_group, volume, dns_record, webhook, prometheus_target
+	ResourceID   string `json:"resource_id"`
+	Owner        string `json:"owner,omitempty"` // e.g. service/<id>
+}
+
+func (a CleanupAction) String() string {
+	if a.Owner == "" {
+		return fmt.Sprintf("%s %s %s", a.Operation, a.ResourceType, a.ResourceID)
+	}
+	return fmt.Sprintf("%s %s %s (%s)", a.Operation, a.ResourceType, a.ResourceID, a.Owner)
+}
+
+// cleanupRun collects the actions of one cleanup. In a dry run the actions are
+// only collected, otherwise each is carried out as it's collected.
+type cleanupRun struct {
+	dryRun  bool
+	actions []CleanupAction
+	errs    []error
+}
+
+// do records action and, unless this is a dry run, carries it out with fn.
+// Resources that are already gone count as cleaned up, so that a cleanup can be
+// re-run after a partial failure.
+func (r *cleanupRun) do(action CleanupAction, fn func() error) error {
+	r.actions = append(r.actions, action)
+	if r.dryRun || fn == nil {
+		return nil
+	}
+	err := fn()
+	if err == nil || errors.Is(err, infra.ErrNotFound) {
+		return nil
+	}
+	r.errs = append(r.errs, fmt.Errorf("%s: %w", action, err))
+	return err
+}
+
+// err returns the failed actions, if any, after every action was attempted
+func (r *cleanupRun) err() error {
+	if len(r.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("cleanup incomplete: %w", errors.Join(r.errs...))
+}
+
 // CleanupWorker handles resource cleanup jobs
 type CleanupWorker struct {
 	store  *store.DB
@@ -26,8 +74,47 @@ func NewCleanupWorker(store *store.DB, cfg *config.Config) *CleanupWorker {
 	}
 }
 
+// DeleteCloudResource deletes a single cloud resource of one of the store.CloudResource*
+// types, or in a dry run only returns what it would delete
+func (w *CleanupWorker) DeleteCloudResource(ctx context.Context, client infra.Client, resourceType, resourceID string, dryRun bool) (CleanupAction, error) {
+	var deleteFn func() error
+	switch resourceType {
+	case store.CloudResourceInstance:
+		deleteFn = func() error { return client.DeleteInstance(ctx, resourceID) }
+	case store.CloudResourceFloatingIP:
+		deleteFn = func() error { return client.ReleaseFloatingIP(ctx, resourceID) }
+	case store.CloudResourceSecurityGroup:
+		deleteFn = func() error { return client.DeleteSecurityGroup(ctx, resourceID) }
+	case store.CloudResourceVolume:
+		deleteFn = func() error { return client.DeleteVolume(ctx, resourceID) }
+	default:
+		return CleanupAction{}, fmt.Errorf("unknown cloud resource type %q", resourceType)
+	}
+
+	action := CleanupAction{Operation: "delete", ResourceType: resourceType, ResourceID: resourceID}
+	run := &cleanupRun{dryRun: dryRun}
+	return action, run.do(action, deleteFn)
+}
+
 // CleanupServiceResources cleans up all resources associated with a service
 func (w *CleanupWorker) CleanupServiceResources(ctx context.Context, serviceID uuid.UUID) error {
+	run := &cleanupRun{}
+	if err := w.cleanupService(ctx, serviceID, run); err != nil {
+		return err
+	}
+	return run.err()
+}
+
+// CleanupServiceResourcesDryRun returns what CleanupServiceResources would do, without doing it
+func (w *CleanupWorker) CleanupServiceResourcesDryRun(ctx context.Context, serviceID uuid.UUID) ([]CleanupAction, error) {
+	run := &cleanupRun{dryRun: true}
+	if err := w.cleanupService(ctx, serviceID, run); err != nil {
+		return nil, err
+	}
+	return run.actions, nil
+}
+
+func (w *CleanupWorker) cleanupService(ctx context.Context, serviceID uuid.UUID, run *cleanupRun) error {
 	// Get service details
 	service, err := w.store.GetService(ctx, serviceID)
 	if err != nil {
@@ -53,11 +140,13 @@ func (w *CleanupWorker) CleanupServiceResources(ctx context.Context, serviceID u
 
 	baseClient := infra.NewClient(infraConfig)
 	client := infra.NewRetryClient(baseClient)
+	owner := "service/" + serviceID.String()
 
 	// 1. Unregister from Prometheus
 	if service.OpenStackInstanceID.Valid {
 		targetManager := metrics.NewTargetManager(w.config.PrometheusTargetsDir)
-		if err := targetManager.UnregisterInstance(service.OpenStackInstanceID.String); err != nil {
+		action := CleanupAction{Operation: "unregister", ResourceType: "prometheus_target", ResourceID: service.OpenStackInstanceID.String, Owner: owner}
+		if err := run.do(action, func() error { return targetManager.UnregisterInstance(service.OpenStackInstanceID.String) }); err != nil {
 			fmt.Printf("Warning: Failed to unregister service %s from Prometheus: %v\n", serviceID, err)
 		}
 	}
@@ -67,19 +156,22 @@ func (w *CleanupWorker) CleanupServiceResources(ctx context.Context, serviceID u
 		instanceID := service.OpenStackInstanceID.String
 
 		// Try to stop and delete container first
-		if err := client.StopContainer(ctx, instanceID); err != nil {
-			// Log but continue - container might already be stopped
-			fmt.Printf("Warning: failed to stop container %s: %v\n", instanceID, err)
-		}
+		action := CleanupAction{Operation: "delete", ResourceType: "container", ResourceID: instanceID, Owner: owner}
+		err := run.do(action, func() error {
+			if err := client.StopContainer(ctx, instanceID); err != nil && !errors.Is(err, infra.ErrNotFound) {
+				// Log but continue - container might already be stopped
+				fmt.Printf("Warning: failed to stop container %s: %v\n", instanceID, err)
+			}
 
-		if err := client.DeleteContainer(ctx, instanceID); err != nil {
-			// Log but continue - might be already deleted
-			fmt.Printf("Warning: failed to delete container %s: %v\n", instanceID, err)
-		} else {
-			// If container deletion failed, try instance deletion
-			if err := client.DeleteInstance(ctx, instanceID); err != nil {
-				fmt.Printf("Warning: failed to delete instance %s: %v\n", instanceID, err)
+			err := client.DeleteContainer(ctx, instanceID)
+			if errors.Is(err, infra.ErrNotFound) {
+				// Not a container (or already deleted), try instance deletion
+				return client.DeleteInstance(ctx, instanceID)
 			}
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Warning: failed to delete container/instance %s: %v\n", instanceID, err)
 		}
 	}
 
@@ -90,13 +182,19 @@ func (w *CleanupWorker) CleanupServiceResources(ctx context.Context, serviceID u
 		// If instance still exists, detach FIP first
 		if service.OpenStackInstanceID.Valid {
 			// Detach is handled by delete instance, but we try anyway
-			_ = client.DeleteInstance(ctx, service.OpenStackInstanceID.String)
+			run.do(CleanupAction{Operation: "detach", ResourceType: "floating_ip", ResourceID: fipID, Owner: owner}, func() error {
+				_ = client.DeleteInstance(ctx, service.OpenStackInstanceID.String)
+				return nil
+			})
 		}
 
 		// Note: In a real implementation, we'd need an API endpoint to release the FIP
 		// For now, we'll mark it for cleanup and rely on the infrastructure service
 		// to handle orphaned resources
-		fmt.Printf("Floating IP %s should be released\n", fipID)
+		run.do(CleanupAction{Operation: "manual", ResourceType: "floating_ip", ResourceID: fipID, Owner: owner}, func() error {
+			fmt.Printf("Floating IP %s should be released\n", fipID)
+			return nil
+		})
 	}
 
 	// 4. Delete security group if exists
@@ -104,14 +202,20 @@ func (w *CleanupWorker) CleanupServiceResources(ctx context.Context, serviceID u
 		sgID := service.SecurityGroupID.String
 		// Note: In a real implementation, we'd need a DeleteSecurityGroup method
 		// For now, we'll mark it for cleanup
-		fmt.Printf("Security Group %s should be deleted\n", sgID)
+		run.do(CleanupAction{Operation: "manual", ResourceType: "security_group", ResourceID: sgID, Owner: owner}, func() error {
+			fmt.Printf("Security Group %s should be deleted\n", sgID)
+			return nil
+		})
 	}
 
 	// 5. Delete DNS record if exists
 	if service.Subdomain.Valid {
 		// Note: In a real implementation, we'd need a DeleteDNSRecord method
 		// For now, we'll mark it for cleanup
-		fmt.Printf("DNS record for subdomain %s should be deleted\n", service.Subdomain.String)
+		run.do(CleanupAction{Operation: "manual", ResourceType: "dns_record", ResourceID: service.Subdomain.String, Owner: owner}, func() error {
+			fmt.Printf("DNS record for subdomain %s should be deleted\n", service.Subdomain.String)
+			return nil
+		})
 	}
 
 	// 6. Delete Git webhook if exists
@@ -123,7 +227,10 @@ func (w *CleanupWorker) CleanupServiceResources(ctx context.Context, serviceID u
 			if err == nil && gitSource != nil && gitSource.WebhookID.Valid {
 				// Note: Webhook deletion should be handled by Git client
 				// For now, we mark it for cleanup
-				fmt.Printf("Webhook %s should be deleted\n", gitSource.WebhookID.String)
+				run.do(CleanupAction{Operation: "manual", ResourceType: "webhook", ResourceID: gitSource.WebhookID.String, Owner: owner}, func() error {
+					fmt.Printf("Webhook %s should be deleted\n", gitSource.WebhookID.String)
+					return nil
+				})
 			}
 		}
 	}
@@ -137,6 +244,23 @@ func (w *CleanupWorker) CleanupServiceResources(ctx context.Context, serviceID u
 
 // CleanupProjectResources cleans up all resources associated with a project
 func (w *CleanupWorker) CleanupProjectResources(ctx context.Context, projectID uuid.UUID) error {
+	run := &cleanupRun{}
+	if err := w.cleanupProject(ctx, projectID, run); err != nil {
+		return err
+	}
+	return run.err()
+}
+
+// CleanupProjectResourcesDryRun returns what CleanupProjectResources would do, without doing it
+func (w *CleanupWorker) CleanupProjectResourcesDryRun(ctx context.Context, projectID uuid.UUID) ([]CleanupAction, error) {
+	run := &cleanupRun{dryRun: true}
+	if err := w.cleanupProject(ctx, projectID, run); err != nil {
+		return nil, err
+	}
+	return run.actions, nil
+}
+
+func (w *CleanupWorker) cleanupProject(ctx context.Context, projectID uuid.UUID, run *cleanupRun) error {
 	// Get project
 	project, err := w.store.GetProject(ctx, projectID)
 	if err != nil {
@@ -153,7 +277,7 @@ func (w *CleanupWorker) CleanupProjectResources(ctx context.Context, projectID u
 	}
 
 	for _, service := range services {
-		if err := w.CleanupServiceResources(ctx, service.ID); err != nil {
+		if err := w.cleanupService(ctx, service.ID, run); err != nil {
 			// Log but continue with other services
 			fmt.Printf("Warning: failed to cleanup service %s: %v\n", service.ID, err)
 		}
@@ -176,10 +300,13 @@ func (w *CleanupWorker) CleanupProjectResources(ctx context.Context, projectID u
 	client := infra.NewRetryClient(baseClient)
 
 	for _, db := range databases {
+		owner := "database/" + db.ID.String()
+
 		// Unregister from Prometheus
 		if db.OpenStackInstanceID.Valid {
 			targetManager := metrics.NewTargetManager(w.config.PrometheusTargetsDir)
-			if err := targetManager.UnregisterDatabase(db.ID.String()); err != nil {
+			action := CleanupAction{Operation: "unregister", ResourceType: "prometheus_target", ResourceID: db.ID.String(), Owner: owner}
+			if err := run.do(action, func() error { return targetManager.UnregisterDatabase(db.ID.String()) }); err != nil {
 				fmt.Printf("Warning: Failed to unregister database %s from Prometheus: %v\n", db.ID, err)
 			}
 		}
@@ -187,7 +314,8 @@ func (w *CleanupWorker) CleanupProjectResources(ctx context.Context, projectID u
 		// Delete database instance if exists
 		if db.OpenStackInstanceID.Valid {
 			instanceID := db.OpenStackInstanceID.String
-			if err := client.DeleteInstance(ctx, instanceID); err != nil {
+			action := CleanupAction{Operation: "delete", ResourceType: "instance", ResourceID: instanceID, Owner: owner}
+			if err := run.do(action, func() error { return client.DeleteInstance(ctx, instanceID) }); err != nil {
 				fmt.Printf("Warning: failed to delete database instance %s: %v\n", instanceID, err)
 			}
 		}
@@ -197,12 +325,14 @@ func (w *CleanupWorker) CleanupProjectResources(ctx context.Context, projectID u
 			volumeID := db.VolumeID.String
 			// First detach if attached
 			if db.OpenStackInstanceID.Valid {
-				if err := client.DetachVolume(ctx, volumeID); err != nil {
+				action := CleanupAction{Operation: "detach", ResourceType: "volume", ResourceID: volumeID, Owner: owner}
+				if err := run.do(action, func() error { return client.DetachVolume(ctx, volumeID) }); err != nil {
 					fmt.Printf("Warning: failed to detach volume %s: %v\n", volumeID, err)
 				}
 			}
 			// Then delete
-			if err := client.DeleteVolume(ctx, volumeID); err != nil {
+			action := CleanupAction{Operation: "delete", ResourceType: "volume", ResourceID: volumeID, Owner: owner}
+			if err := run.do(action, func() error { return client.DeleteVolume(ctx, volumeID) }); err != nil {
 				fmt.Printf("Warning: failed to delete volume %s: %v\n", volumeID, err)
 			}
 		}
@@ -210,7 +340,10 @@ func (w *CleanupWorker) CleanupProjectResources(ctx context.Context, projectID u
 		// Delete DNS record if exists
 		if db.InternalHostname.Valid {
 			// Note: DNS record deletion needed
-			fmt.Printf("DNS record for database hostname %s should be deleted\n", db.InternalHostname.String)
+			run.do(CleanupAction{Operation: "manual", ResourceType: "dns_record", ResourceID: db.InternalHostname.String, Owner: owner}, func() error {
+				fmt.Printf("DNS record for database hostname %s should be deleted\n", db.InternalHostname.String)
+				return nil
+			})
 		}
 	}
 
@@ -221,12 +354,15 @@ func (w *CleanupWorker) CleanupProjectResources(ctx context.Context, projectID u
 	}
 
 	for _, volume := range volumes {
+		owner := "volume/" + volume.ID.String()
+		detach := CleanupAction{Operation: "detach", ResourceType: "volume", ResourceID: volume.OpenStackVolumeID.String, Owner: owner}
+
 		// Detach volume if attached
 		if volume.AttachedToServiceID.Valid {
 			serviceID, _ := uuid.Parse(volume.AttachedToServiceID.String)
 			service, err := w.store.GetService(ctx, serviceID)
 			if err == nil && service != nil && service.OpenStackInstanceID.Valid {
-				if err := client.DetachVolume(ctx, volume.OpenStackVolumeID.String); err != nil {
+				if err := run.do(detach, func() error { return client.DetachVolume(ctx, volume.OpenStackVolumeID.String) }); err != nil {
 					fmt.Printf("Warning: failed to detach volume %s: %v\n", volume.ID, err)
 				}
 			}
@@ -234,7 +370,7 @@ func (w *CleanupWorker) CleanupProjectResources(ctx context.Context, projectID u
 			dbID, _ := uuid.Parse(volume.AttachedToDatabaseID.String)
 			db, err := w.store.GetDatabase(ctx, dbID)
 			if err == nil && db != nil && db.OpenStackInstanceID.Valid {
-				if err := client.DetachVolume(ctx, volume.OpenStackVolumeID.String); err != nil {
+				if err := run.do(detach, func() error { return client.DetachVolume(ctx, volume.OpenStackVolumeID.String) }); err != nil {
 					fmt.Printf("Warning: failed to detach volume %s: %v\n", volume.ID, err)
 				}
 			}
@@ -243,7 +379,8 @@ func (w *CleanupWorker) CleanupProjectResources(ctx context.Context, projectID u
 		// Delete volume
 		if volume.OpenStackVolumeID.Valid {
 			volumeID := volume.OpenStackVolumeID.String
-			if err := client.DeleteVolume(ctx, volumeID); err != nil {
+			action := CleanupAction{Operation: "delete", ResourceType: "volume", ResourceID: volumeID, Owner: owner}
+			if err := run.do(action, func() error { return client.DeleteVolume(ctx, volumeID) }); err != nil {
 				fmt.Printf("Warning: failed to delete volume %s: %v\n", volumeID, err)
 			}
 		}
@@ -0,0 +1,174 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/caddy"
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/tracing"
+)
+
+// CustomDomainRouteJobType is the job adding the Caddy routes of a custom domain that
+// couldn't be routed when it was added, e.g. while Caddy's admin API was down
+const CustomDomainRouteJobType = "custom_domain_route"
+
+const (
+	customDomainRouteInterval    = 10 * time.Second
+	customDomainRouteMaxAttempts = 10
+	// Retries back off from customDomainRouteRetryBase, doubling up to
+	// customDomainRouteRetryMax: about an hour and a half of retries in all
+	customDomainRouteRetryBase = 30 * time.Second
+	customDomainRouteRetryMax  = 15 * time.Minute
+)
+
+// QueueCustomDomainRoute queues a job adding the Caddy routes of a custom domain
+func QueueCustomDomainRoute(ctx context.Context, db *store.DB, domainID uuid.UUID) error {
+	job := &store.Job{
+		Type:        CustomDomainRouteJobType,
+		Payload:     map[string]interface{}{"custom_domain_id": domainID.String()},
+		Status:      "pending",
+		MaxAttempts: customDomainRouteMaxAttempts,
+	}
+	tracing.InjectJobPayload(ctx, job.Payload)
+	return db.CreateJob(ctx, job)
+}
+
+// customDomainRouteRetryDelay is how long to wait before the next attempt after the
+// given number of failed attempts
+func customDomainRouteRetryDelay(attempts int) time.Duration {
+	delay := customDomainRouteRetryBase
+	for i := 1; i < attempts && delay < customDomainRouteRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > customDomainRouteRetryMax {
+		delay = customDomainRouteRetryMax
+	}
+	return delay
+}
+
+// CustomDomainRouteWorker runs the custom domain route jobs, retrying with backoff
+// until Caddy takes the routes
+type CustomDomainRouteWorker struct {
+	store    *store.DB
+	config   *config.Config
+	caddy    *caddy.Client
+	workerID string
+}
+
+// NewCustomDomainRouteWorker creates a new custom domain route worker
+func NewCustomDomainRouteWorker(store *store.DB, cfg *config.Config) *CustomDomainRouteWorker {
+	return &CustomDomainRouteWorker{
+		store:    store,
+		config:   cfg,
+		caddy:    caddy.NewClient(cfg.CaddyAdminURL),
+		workerID: "custom-domain-route-" + uuid.New().String()[:8],
+	}
+}
+
+// Start runs due jobs every customDomainRouteInterval until ctx is cancelled
+func (w *CustomDomainRouteWorker) Start(ctx context.Context) {
+	if w.config.CaddyAdminURL == "" {
+		// Without Caddy (k3s mode uses ingress) domains aren't routed through it
+		return
+	}
+
+	ticker := time.NewTicker(customDomainRouteInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.RunDueJobs(ctx); err != nil {
+				log.Printf("Custom domain routes: %v", err)
+			}
+		}
+	}
+}
+
+// RunDueJobs claims and runs route jobs until none is due
+func (w *CustomDomainRouteWorker) RunDueJobs(ctx context.Context) error {
+	for {
+		job, err := w.store.ClaimNextJob(ctx, w.workerID, []string{CustomDomainRouteJobType})
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			return nil
+		}
+		w.runJob(ctx, job)
+	}
+}
+
+// runJob runs a claimed job and records how it went
+func (w *CustomDomainRouteWorker) runJob(ctx context.Context, job *store.Job) {
+	ctx = tracing.ExtractJobPayload(ctx, job.Payload)
+
+	err := w.routeDomain(ctx, job)
+	switch {
+	case err == nil:
+		if err := w.store.CompleteJob(ctx, job.ID); err != nil {
+			log.Printf("Custom domain routes: failed to complete job %s: %v", job.ID, err)
+		}
+	case job.Attempts+1 >= job.MaxAttempts:
+		log.Printf("Custom domain routes: job %s dead-lettered after %d attempts: %v", job.ID, job.Attempts+1, err)
+		if err := w.store.DeadLetterJob(ctx, job.ID, err.Error()); err != nil {
+			log.Printf("Custom domain routes: failed to dead-letter job %s: %v", job.ID, err)
+		}
+	default:
+		runAt := time.Now().Add(customDomainRouteRetryDelay(job.Attempts + 1))
+		log.Printf("Custom domain routes: job %s retries at %s: %v", job.ID, runAt.Format(time.RFC3339), err)
+		if err := w.store.RetryJob(ctx, job.ID, err.Error(), runAt); err != nil {
+			log.Printf("Custom domain routes: failed to reschedule job %s: %v", job.ID, err)
+		}
+	}
+}
+
+// routeDomain adds the routes of the job's domain and marks it active. Domains
+// that were deleted since, or that wait for their service to be ready, are left
+// alone: the domain activation worker routes the latter.
+func (w *CustomDomainRouteWorker) routeDomain(ctx context.Context, job *store.Job) error {
+	domainIDStr, ok := job.Payload["custom_domain_id"].(string)
+	if !ok {
+		return fmt.Errorf("missing custom_domain_id in job payload")
+	}
+	domainID, err := uuid.Parse(domainIDStr)
+	if err != nil {
+		return fmt.Errorf("invalid custom_domain_id: %w", err)
+	}
+
+	d, err := w.store.GetCustomDomain(ctx, domainID)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return nil
+	}
+	awaiting, err := w.store.IsCustomDomainAwaitingReady(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+	if awaiting {
+		return nil
+	}
+	service, err := w.store.GetService(ctx, d.ServiceID)
+	if err != nil {
+		return err
+	}
+	if service == nil {
+		return nil
+	}
+
+	if err := ActivateCustomDomain(ctx, w.store, w.caddy, d, service); err != nil {
+		return err
+	}
+	log.Printf("Custom domain routes: added the route of %s, it is active", d.Domain)
+	return nil
+}
@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestCustomDomainRouteWorker_ActivatesRoutedDomain(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	ctx := context.Background()
+
+	fake := &fakeCaddy{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	project := testutil.NewProject(t, db)
+	service := testutil.NewService(t, db, project.ID)
+	d := &store.CustomDomain{
+		ServiceID:   service.ID,
+		Domain:      "app.example.com",
+		Status:      "pending",
+		CNAMETarget: store.StringToNullString("target.example.com"),
+	}
+	if err := dbStore.CreateCustomDomain(ctx, d); err != nil {
+		t.Fatalf("Failed to create custom domain: %v", err)
+	}
+	if err := QueueCustomDomainRoute(ctx, dbStore, d.ID); err != nil {
+		t.Fatalf("Failed to queue route job: %v", err)
+	}
+
+	if err := NewCustomDomainRouteWorker(dbStore, &config.Config{CaddyAdminURL: server.URL}).RunDueJobs(ctx); err != nil {
+		t.Fatalf("RunDueJobs failed: %v", err)
+	}
+
+	if len(fake.pushed) == 0 {
+		t.Fatal("The route of the domain was not pushed")
+	}
+	routed, err := dbStore.GetCustomDomain(ctx, d.ID)
+	if err != nil {
+		t.Fatalf("Failed to get custom domain: %v", err)
+	}
+	// Left pending, later route updates of the service would skip it
+	if routed.Status != "active" {
+		t.Errorf("Expected the routed domain to be active, got %q", routed.Status)
+	}
+}
@@ -84,6 +84,7 @@ func (w *DatabaseWorker) ProcessProvisionDatabaseJob(ctx context.Context, databa
 		Name:       fmt.Sprintf("db-%s", databaseID.String()[:8]),
 		SizeGB:     volumeSizeGB,
 		VolumeType: "ssd",
+		Metadata:   infra.ManagedMetadata(map[string]string{"database_id": databaseID.String()}),
 	}
 
 	volume, err := client.CreateVolume(ctx, volumeReq)
@@ -112,6 +113,7 @@ func (w *DatabaseWorker) ProcessProvisionDatabaseJob(ctx context.Context, databa
 				RemoteIP:  "10.0.0.0/8", // Internal network only
 			},
 		},
+		Metadata: infra.ManagedMetadata(map[string]string{"database_id": databaseID.String()}),
 	}
 
 	sg, err := client.CreateSecurityGroup(ctx, sgReq)
@@ -144,10 +146,10 @@ func (w *DatabaseWorker) ProcessProvisionDatabaseJob(ctx context.Context, databa
 		NetworkID:     networkID,
 		SecurityGroups: []string{sg.ID},
 		UserData:      userData,
-		Metadata: map[string]string{
+		Metadata: infra.ManagedMetadata(map[string]string{
 			"database_id": databaseID.String(),
 			"engine":      database.Engine,
-		},
+		}),
 	}
 
 	instance, err := client.CreateInstance(ctx, instanceReq)
@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/config"
+)
+
+// defaultBuildDir is where builds happen when BUILD_DIR is unset
+const defaultBuildDir = "/tmp/click-deploy-builds"
+
+// buildDirectory is the directory builds happen in
+func buildDirectory(cfg *config.Config) string {
+	if cfg.BuildDir == "" {
+		return defaultBuildDir
+	}
+	return cfg.BuildDir
+}
+
+// DeploymentArchivesDir is where uploaded source archives wait for their build
+func DeploymentArchivesDir(cfg *config.Config) string {
+	return filepath.Join(buildDirectory(cfg), "archives")
+}
+
+// DeploymentArchivePath is where the source archive uploaded for a deployment is kept
+// until it's built
+func DeploymentArchivePath(cfg *config.Config, deploymentID uuid.UUID) string {
+	return filepath.Join(DeploymentArchivesDir(cfg), deploymentID.String()+".tar.gz")
+}
+
+// RemoveDeploymentArchive removes the source archive uploaded for a deployment, if any
+func RemoveDeploymentArchive(cfg *config.Config, deploymentID uuid.UUID) error {
+	err := os.Remove(DeploymentArchivePath(cfg, deploymentID))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// fileSHA256 returns the hex SHA-256 of a file's content
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
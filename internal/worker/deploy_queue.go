@@ -0,0 +1,150 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// deployQueueInterval is how often waiting deployments are checked for a free slot
+const deployQueueInterval = 5 * time.Second
+
+// OrgDeployLimit returns how many deployments an org may have in flight at once,
+// based on its plan. 0 means unlimited.
+func OrgDeployLimit(ctx context.Context, db *store.DB, cfg *config.Config, orgID string) (int, error) {
+	var plan string
+	// Only orgs managed by the platform (UUID IDs) have a plan
+	if _, err := uuid.Parse(orgID); err == nil {
+		plan, err = db.GetOrgPlan(ctx, orgID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return cfg.MaxConcurrentDeploysForPlan(plan), nil
+}
+
+// AdmitWaitingDeployment starts a waiting deployment of the org, moving it to queued,
+// if the org has a free deploy slot and nothing waiting ahead of it. Admission is
+// atomic per org, concurrent admissions can't overshoot the org's limit.
+func AdmitWaitingDeployment(ctx context.Context, db *store.DB, cfg *config.Config, id uuid.UUID, orgID string) (bool, error) {
+	limit, err := OrgDeployLimit(ctx, db, cfg, orgID)
+	if err != nil {
+		return false, err
+	}
+	if limit <= 0 {
+		return db.StartWaitingDeployment(ctx, id)
+	}
+	return db.AdmitWaitingDeployment(ctx, id, orgID, limit)
+}
+
+// DeployQueueWorker starts deployments that were held back by their org's
+// concurrent deployment limit, oldest first, as the org's deploy slots free up.
+// It also fails deployments in flight for longer than StaleDeploymentTimeout: their
+// pipeline died with the server running it, and they'd hold a slot forever.
+type DeployQueueWorker struct {
+	store       *store.DB
+	config      *config.Config
+	buildWorker *BuildWorker
+	k8sWorker   *K8sDeployWorker
+}
+
+// NewDeployQueueWorker creates a new deploy queue worker
+func NewDeployQueueWorker(store *store.DB, cfg *config.Config, buildWorker *BuildWorker, k8sClients *k8s.ClientRegistry) *DeployQueueWorker {
+	var k8sWorker *K8sDeployWorker
+	if k8sClients != nil {
+		k8sWorker = NewK8sDeployWorker(store, cfg, k8sClients)
+	}
+
+	return &DeployQueueWorker{
+		store:       store,
+		config:      cfg,
+		buildWorker: buildWorker,
+		k8sWorker:   k8sWorker,
+	}
+}
+
+// Start fails stale deployments right away, left over from a previous run, then
+// does so and dispatches waiting deployments every deployQueueInterval until ctx is
+// cancelled
+func (w *DeployQueueWorker) Start(ctx context.Context) {
+	if w.buildWorker == nil || w.k8sWorker == nil {
+		// Nothing could run the deployments
+		return
+	}
+
+	if err := w.FailStaleDeployments(ctx); err != nil {
+		log.Printf("Deploy queue: %v", err)
+	}
+
+	ticker := time.NewTicker(deployQueueInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.FailStaleDeployments(ctx); err != nil {
+				log.Printf("Deploy queue: %v", err)
+			}
+			if err := w.DispatchWaiting(ctx); err != nil {
+				log.Printf("Deploy queue: %v", err)
+			}
+		}
+	}
+}
+
+// FailStaleDeployments fails the deployments in flight for longer than
+// StaleDeploymentTimeout
+func (w *DeployQueueWorker) FailStaleDeployments(ctx context.Context) error {
+	timeout := w.config.StaleDeploymentTimeout
+	if timeout <= 0 {
+		return nil
+	}
+
+	message := fmt.Sprintf("Deployment made no progress for %s, its pipeline stopped", timeout)
+	failed, err := w.store.FailStaleDeployments(ctx, time.Now().Add(-timeout), message)
+	for _, id := range failed {
+		log.Printf("Deploy queue: failed stale deployment %s", id)
+		w.store.AddDeploymentLog(ctx, id, "queue", "error", message, nil)
+	}
+	return err
+}
+
+// DispatchWaiting starts waiting deployments, oldest first, as long as their orgs
+// have free slots
+func (w *DeployQueueWorker) DispatchWaiting(ctx context.Context) error {
+	waiting, err := w.store.ListWaitingDeployments(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Orgs out of slots, whose later deployments keep waiting too
+	full := map[string]bool{}
+	for _, d := range waiting {
+		if full[d.OrgID] {
+			continue
+		}
+
+		started, err := AdmitWaitingDeployment(ctx, w.store, w.config, d.ID, d.OrgID)
+		if err != nil {
+			return err
+		}
+		if !started {
+			full[d.OrgID] = true
+			continue
+		}
+		w.store.AddDeploymentLog(ctx, d.ID, "queue", "info", "Deploy slot available, starting deployment", nil)
+		go RunDeploymentPipeline(ctx, w.store, w.buildWorker, w.k8sWorker, d.ID)
+	}
+
+	return nil
+}
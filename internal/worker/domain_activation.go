@@ -0,0 +1,201 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/caddy"
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// domainActivationInterval is how often domains waiting for their service are checked
+const domainActivationInterval = 15 * time.Second
+
+// ServiceReady reports whether at least one of the service's pods is ready to take
+// traffic, in the cluster of the project's region
+func ServiceReady(ctx context.Context, clients *k8s.ClientRegistry, project *store.Project, service *store.Service) (bool, error) {
+	client, err := clients.Client(ProjectRegion(project))
+	if err != nil {
+		return false, err
+	}
+	status, err := client.GetDeploymentStatus(ctx, project.ID.String(), service.ID.String())
+	if err != nil {
+		return false, err
+	}
+	return status.Exists && status.ReadyReplicas > 0, nil
+}
+
+// ApplyCustomDomainRoutes pushes the Caddy routes of a custom domain: its path
+// routes to other services, and everything else to the domain's own service
+func ApplyCustomDomainRoutes(ctx context.Context, db *store.DB, caddyClient *caddy.Client, d *store.CustomDomain, service *store.Service) error {
+	errorPage, err := db.GetServiceErrorPage(ctx, service.ID)
+	if err != nil {
+		return err
+	}
+
+	pathRoutes, err := db.ListCustomDomainRoutes(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+	paths := make([]caddy.PathRoute, 0, len(pathRoutes))
+	for _, route := range pathRoutes {
+		target, err := db.GetService(ctx, route.ServiceID)
+		if err != nil {
+			return err
+		}
+		if target == nil {
+			continue
+		}
+		paths = append(paths, caddy.PathRoute{
+			PathPrefix: route.PathPrefix,
+			TargetHost: serviceRouteHost(target, d.CNAMETarget.String),
+			TargetPort: target.Port,
+			Streaming:  target.SupportsWebsockets,
+		})
+	}
+
+	if err := caddyClient.AddRoute(ctx, d.Domain, d.CNAMETarget.String, service.Port, true, service.SupportsWebsockets, errorPage, paths...); err != nil {
+		return fmt.Errorf("failed to add route: %w", err)
+	}
+	return nil
+}
+
+// SyncServiceCustomDomainRoutes re-pushes the Caddy routes of the service's active
+// custom domains, after a change to the service's route settings. Domains not yet
+// verified, or still waiting for the service to be ready, are skipped: they pick the
+// change up once live. Failures are logged, the next route update retries.
+func SyncServiceCustomDomainRoutes(ctx context.Context, db *store.DB, caddyClient *caddy.Client, service *store.Service) {
+	domains, err := db.ListCustomDomainsByService(ctx, service.ID)
+	if err != nil {
+		log.Printf("Failed to list custom domains for service %s: %v", service.ID, err)
+		return
+	}
+
+	for _, d := range domains {
+		if d.Status != "active" || !d.CNAMETarget.Valid {
+			continue
+		}
+		awaiting, err := db.IsCustomDomainAwaitingReady(ctx, d.ID)
+		if err != nil {
+			log.Printf("Failed to check custom domain %s: %v", d.Domain, err)
+			continue
+		}
+		if awaiting {
+			continue
+		}
+		if err := ApplyCustomDomainRoutes(ctx, db, caddyClient, d, service); err != nil {
+			log.Printf("Failed to update Caddy route for %s: %v", d.Domain, err)
+		}
+	}
+}
+
+// serviceRouteHost returns the host Caddy proxies a service's requests to, the same
+// way a custom domain's CNAME target is picked; fallback is used when the service
+// has neither a generated URL nor a floating IP (k3s mode)
+func serviceRouteHost(service *store.Service, fallback string) string {
+	if service.GeneratedURL.Valid && service.GeneratedURL.String != "" {
+		return service.GeneratedURL.String
+	}
+	if service.OpenStackFIPAddress.Valid {
+		return service.OpenStackFIPAddress.String
+	}
+	return fallback
+}
+
+// ActivateCustomDomain adds the Caddy routes of a custom domain and marks it active
+func ActivateCustomDomain(ctx context.Context, db *store.DB, caddyClient *caddy.Client, d *store.CustomDomain, service *store.Service) error {
+	if err := ApplyCustomDomainRoutes(ctx, db, caddyClient, d, service); err != nil {
+		return err
+	}
+
+	if err := db.SetCustomDomainAwaitingReady(ctx, d.ID, false); err != nil {
+		return err
+	}
+	d.Status = "active"
+	return db.UpdateCustomDomain(ctx, d.ID, d)
+}
+
+// DomainActivationWorker routes custom domains that were added while their service
+// had no ready pod, once it has one, so a domain never points at a service that
+// can only answer 502
+type DomainActivationWorker struct {
+	store      *store.DB
+	config     *config.Config
+	caddy      *caddy.Client
+	k8sClients *k8s.ClientRegistry
+}
+
+// NewDomainActivationWorker creates a new domain activation worker
+func NewDomainActivationWorker(store *store.DB, cfg *config.Config, k8sClients *k8s.ClientRegistry) *DomainActivationWorker {
+	return &DomainActivationWorker{
+		store:      store,
+		config:     cfg,
+		caddy:      caddy.NewClient(cfg.CaddyAdminURL),
+		k8sClients: k8sClients,
+	}
+}
+
+// Start activates ready domains every domainActivationInterval until ctx is cancelled
+func (w *DomainActivationWorker) Start(ctx context.Context) {
+	if w.k8sClients == nil || w.config.CaddyAdminURL == "" {
+		// Domains are only held back when both are configured
+		return
+	}
+
+	ticker := time.NewTicker(domainActivationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.ActivateReadyDomains(ctx); err != nil {
+				log.Printf("Domain activation: %v", err)
+			}
+		}
+	}
+}
+
+// ActivateReadyDomains activates the waiting domains whose service now has a ready
+// pod. Failures for one domain are logged and don't stop the others.
+func (w *DomainActivationWorker) ActivateReadyDomains(ctx context.Context) error {
+	domains, err := w.store.ListCustomDomainsAwaitingReady(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range domains {
+		service, err := w.store.GetService(ctx, d.ServiceID)
+		if err != nil || service == nil {
+			log.Printf("Domain activation: failed to get service of %s: %v", d.Domain, err)
+			continue
+		}
+		project, err := w.store.GetProject(ctx, service.ProjectID)
+		if err != nil || project == nil {
+			log.Printf("Domain activation: failed to get project of %s: %v", d.Domain, err)
+			continue
+		}
+
+		ready, err := ServiceReady(ctx, w.k8sClients, project, service)
+		if err != nil {
+			log.Printf("Domain activation: failed to check service of %s: %v", d.Domain, err)
+			continue
+		}
+		if !ready {
+			continue
+		}
+
+		if err := ActivateCustomDomain(ctx, w.store, w.caddy, d, service); err != nil {
+			log.Printf("Domain activation: %s: %v", d.Domain, err)
+			continue
+		}
+		log.Printf("Domain activation: %s is live, its service became ready", d.Domain)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/caddy"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+// fakeCaddy has no routes configured and records the route lists pushed to it
+type fakeCaddy struct {
+	mu     sync.Mutex
+	pushed []string
+}
+
+func (c *fakeCaddy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		io.WriteString(w, "[]")
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	c.mu.Lock()
+	c.pushed = append(c.pushed, string(body))
+	c.mu.Unlock()
+}
+
+func TestSyncServiceCustomDomainRoutes_OnlyActiveDomains(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	ctx := context.Background()
+
+	fake := &fakeCaddy{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	project := testutil.NewProject(t, db)
+	serviceRow := testutil.NewService(t, db, project.ID)
+	service, err := dbStore.GetService(ctx, serviceRow.ID)
+	if err != nil {
+		t.Fatalf("Failed to get service: %v", err)
+	}
+
+	for domain, status := range map[string]string{
+		"active.example.com":   "active",
+		"pending.example.com":  "pending",
+		"verified.example.com": "verified",
+	} {
+		d := &store.CustomDomain{
+			ServiceID:   service.ID,
+			Domain:      domain,
+			Status:      status,
+			CNAMETarget: store.StringToNullString("target.example.com"),
+		}
+		if err := dbStore.CreateCustomDomain(ctx, d); err != nil {
+			t.Fatalf("Failed to create custom domain %s: %v", domain, err)
+		}
+	}
+
+	SyncServiceCustomDomainRoutes(ctx, dbStore, caddy.NewClient(server.URL), service)
+
+	pushed := strings.Join(fake.pushed, "\n")
+	if !strings.Contains(pushed, "active.example.com") {
+		t.Error("Routes of the active domain were not pushed")
+	}
+	for _, domain := range []string{"pending.example.com", "verified.example.com"} {
+		if strings.Contains(pushed, domain) {
+			t.Errorf("Routes of %s were pushed before it is active", domain)
+		}
+	}
+}
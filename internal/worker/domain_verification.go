@@ -0,0 +1,130 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/caddy"
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// DomainVerificationWorker periodically re-validates custom domain DNS so
+// custom_domains.status stays fresh without users hitting the verify endpoint
+type DomainVerificationWorker struct {
+	store  *store.DB
+	config *config.Config
+	caddy  *caddy.Client
+}
+
+// NewDomainVerificationWorker creates a new domain verification worker
+func NewDomainVerificationWorker(store *store.DB, cfg *config.Config) *DomainVerificationWorker {
+	return &DomainVerificationWorker{
+		store:  store,
+		config: cfg,
+		caddy:  caddy.NewClient(cfg.CaddyAdminURL),
+	}
+}
+
+// Start re-validates domains every DomainRevalidationInterval until ctx is cancelled
+func (w *DomainVerificationWorker) Start(ctx context.Context) {
+	interval := w.config.DomainRevalidationInterval
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.RevalidateDomains(ctx); err != nil {
+				log.Printf("Domain verification: %v", err)
+			}
+		}
+	}
+}
+
+// RevalidateDomains checks the DNS of every pending, verified and active domain.
+// Pending domains whose records now resolve are marked verified, or get back the
+// status they had before failed checks moved them to pending. Verified or active
+// domains whose records are gone for DomainRevalidationFailures checks in a row go
+// back to pending. Lookup failures leave the status untouched.
+func (w *DomainVerificationWorker) RevalidateDomains(ctx context.Context) error {
+	for _, status := range []string{"pending", "verified", "active"} {
+		domains, err := w.store.ListCustomDomainsByStatus(ctx, status)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range domains {
+			if !d.CNAMETarget.Valid {
+				continue
+			}
+
+			valid, err := w.caddy.ValidateDomain(ctx, d.Domain, d.CNAMETarget.String)
+			if err != nil {
+				log.Printf("Domain verification: lookup failed for %s: %v", d.Domain, err)
+				continue
+			}
+
+			failures, statusBefore, err := w.store.GetCustomDomainDNSFailures(ctx, d.ID)
+			if err != nil {
+				log.Printf("Domain verification: failed to get failed checks of %s: %v", d.Domain, err)
+				continue
+			}
+			newStatus, newFailures, newStatusBefore := revalidatedStatus(d.Status, valid, failures, w.config.DomainRevalidationFailures, statusBefore)
+
+			if newFailures != failures || newStatusBefore != statusBefore {
+				if err := w.store.SetCustomDomainDNSFailures(ctx, d.ID, newFailures, newStatusBefore); err != nil {
+					log.Printf("Domain verification: failed to record failed checks of %s: %v", d.Domain, err)
+					continue
+				}
+			}
+			if newStatus == d.Status {
+				continue
+			}
+
+			if d.Status == "pending" {
+				d.VerifiedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			}
+			d.Status = newStatus
+			if err := w.store.UpdateCustomDomain(ctx, d.ID, d); err != nil {
+				log.Printf("Domain verification: failed to update %s: %v", d.Domain, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// revalidatedStatus returns the status of a domain after a DNS check, along with its
+// consecutive failed checks and the status it had before they moved it to pending.
+// A single failed check, e.g. a resolver blip, doesn't take a live domain out of
+// route sync: it takes maxFailures in a row.
+func revalidatedStatus(status string, valid bool, failures, maxFailures int, statusBefore sql.NullString) (string, int, sql.NullString) {
+	if valid {
+		switch {
+		case statusBefore.Valid && status != "active":
+			return statusBefore.String, 0, sql.NullString{}
+		case status == "pending":
+			return "verified", 0, sql.NullString{}
+		default:
+			return status, 0, sql.NullString{}
+		}
+	}
+
+	if status == "pending" {
+		return status, failures, statusBefore
+	}
+	failures++
+	if failures < maxFailures {
+		return status, failures, statusBefore
+	}
+	return "pending", 0, sql.NullString{String: status, Valid: true}
+}
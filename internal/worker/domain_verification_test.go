@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestRevalidatedStatus(t *testing.T) {
+	const maxFailures = 3
+	status, failures, statusBefore := "active", 0, sql.NullString{}
+
+	// A DNS blip doesn't demote a live domain
+	for i := 1; i < maxFailures; i++ {
+		status, failures, statusBefore = revalidatedStatus(status, false, failures, maxFailures, statusBefore)
+		if status != "active" || failures != i {
+			t.Fatalf("After %d failed checks: status %q with %d failures, want active with %d", i, status, failures, i)
+		}
+	}
+	// A successful check starts the count over
+	status, failures, statusBefore = revalidatedStatus(status, true, failures, maxFailures, statusBefore)
+	if status != "active" || failures != 0 {
+		t.Fatalf("After a passing check: status %q with %d failures, want active with 0", status, failures)
+	}
+
+	for i := 0; i < maxFailures; i++ {
+		status, failures, statusBefore = revalidatedStatus(status, false, failures, maxFailures, statusBefore)
+	}
+	if status != "pending" || statusBefore.String != "active" {
+		t.Fatalf("After %d failed checks: status %q demoted from %q, want pending demoted from active", maxFailures, status, statusBefore.String)
+	}
+	// Further failures keep it pending and remember where it came from
+	status, failures, statusBefore = revalidatedStatus(status, false, failures, maxFailures, statusBefore)
+	if status != "pending" || statusBefore.String != "active" {
+		t.Fatalf("Failed check of a demoted domain: status %q demoted from %q, want pending demoted from active", status, statusBefore.String)
+	}
+
+	// Once the records resolve again the domain is back where it was
+	status, failures, statusBefore = revalidatedStatus(status, true, failures, maxFailures, statusBefore)
+	if status != "active" || failures != 0 || statusBefore.Valid {
+		t.Errorf("After records resolve again: status %q with %d failures, want active with 0", status, failures)
+	}
+
+	// A pending domain that never was live is verified
+	if status, _, _ := revalidatedStatus("pending", true, 0, maxFailures, sql.NullString{}); status != "verified" {
+		t.Errorf("New pending domain resolving: status %q, want verified", status)
+	}
+}
@@ -0,0 +1,30 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// checkServiceEnv validates a service's resolved env against its env schema.
+// It returns an error naming every violation so the deploy fails before the
+// app can crash-loop on a missing or malformed variable.
+func checkServiceEnv(ctx context.Context, db *store.DB, serviceID uuid.UUID) error {
+	violations, err := db.ValidateServiceEnv(ctx, serviceID)
+	if err != nil {
+		return fmt.Errorf("failed to validate env vars: %w", err)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(violations))
+	for i, v := range violations {
+		messages[i] = v.Message
+	}
+	return fmt.Errorf("env validation failed: %s", strings.Join(messages, "; "))
+}
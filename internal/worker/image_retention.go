@@ -0,0 +1,179 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/build"
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// imageRetentionGracePeriod protects images pushed by builds that have not finished deploying yet
+const imageRetentionGracePeriod = time.Hour
+
+// ImageRetentionWorker periodically prunes service images from the registry.
+// It keeps the images of the last N successful deployments of each service (so
+// rollbacks keep working) and the image that is currently running.
+type ImageRetentionWorker struct {
+	store    *store.DB
+	config   *config.Config
+	registry *build.RegistryClient
+}
+
+// NewImageRetentionWorker creates a new image retention worker
+func NewImageRetentionWorker(store *store.DB, cfg *config.Config) *ImageRetentionWorker {
+	return &ImageRetentionWorker{
+		store:    store,
+		config:   cfg,
+		registry: build.NewRegistryClient(cfg.RegistryURL, cfg.RegistryUsername, cfg.RegistryPassword),
+	}
+}
+
+// Start prunes images every ImageRetentionInterval until ctx is cancelled
+func (w *ImageRetentionWorker) Start(ctx context.Context) {
+	interval := w.config.ImageRetentionInterval
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.PruneImages(ctx); err != nil {
+				log.Printf("Image retention: %v", err)
+			}
+		}
+	}
+}
+
+// PruneImages prunes the images of every app service. Failures for one service
+// are logged and don't stop the others.
+func (w *ImageRetentionWorker) PruneImages(ctx context.Context) error {
+	projects, err := w.store.ListAllProjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, project := range projects {
+		keep := w.config.ImageRetentionCount
+		if project.ImageRetentionCount.Valid && project.ImageRetentionCount.Int64 > 0 {
+			keep = int(project.ImageRetentionCount.Int64)
+		}
+		if keep <= 0 {
+			continue
+		}
+
+		services, err := w.store.ListServicesByProject(ctx, project.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, service := range services {
+			if service.Type != "app" {
+				continue
+			}
+			deleted, err := w.pruneServiceImages(ctx, service, keep)
+			if err != nil {
+				log.Printf("Image retention: service %s: %v", service.ID, err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Image retention: deleted %d image(s) of service %s", deleted, service.ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// pruneServiceImages deletes the images of a service that are neither running nor
+// among its last keep successful deployments. Returns the number of deleted images.
+// Only the service's own repository is pruned: images it ran from elsewhere, such as
+// the repository named after the service that same-named services used to share,
+// may belong to other services.
+func (w *ImageRetentionWorker) pruneServiceImages(ctx context.Context, service *store.Service, keep int) (int, error) {
+	deployments, err := w.store.GetSuccessfulDeploymentsByService(ctx, service.ID, keep)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list deployments: %w", err)
+	}
+
+	keepRefs := make([]string, 0, len(deployments)+1)
+	if service.CurrentImageTag.Valid {
+		keepRefs = append(keepRefs, service.CurrentImageTag.String)
+	}
+	for _, d := range deployments {
+		keepRefs = append(keepRefs, d.ImageTag.String)
+	}
+
+	project, repository, keepTags, err := serviceRepositoryKeepTags(w.config.RegistryURL, service, keepRefs)
+	if err != nil {
+		return 0, err
+	}
+	// Without a known good image of the repository there is nothing we can safely prune
+	if len(keepTags) == 0 {
+		return 0, nil
+	}
+
+	artifacts, err := w.registry.ListArtifacts(ctx, project, repository)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, a := range prunableArtifacts(artifacts, keepTags, time.Now()) {
+		if err := w.registry.DeleteArtifact(ctx, project, repository, a.Digest); err != nil {
+			return deleted, fmt.Errorf("failed to delete %s/%s@%s: %w", project, repository, a.Digest, err)
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// serviceRepositoryKeepTags returns the registry project and repository the build
+// worker pushes a service's images to, and the tags of keepRefs in it
+func serviceRepositoryKeepTags(registryURL string, service *store.Service, keepRefs []string) (project, repository string, keepTags map[string]bool, err error) {
+	project, repository, _, err = build.ParseImageReference(build.ServiceImageTag(registryURL, service.Name, service.ID.String(), ""))
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	keepTags = make(map[string]bool)
+	for _, ref := range keepRefs {
+		p, r, tag, err := build.ParseImageReference(ref)
+		if err == nil && p == project && r == repository {
+			keepTags[tag] = true
+		}
+	}
+	return project, repository, keepTags, nil
+}
+
+// prunableArtifacts returns the artifacts that carry none of the kept tags and
+// were pushed before the grace period. Untagged artifacts are prunable too.
+func prunableArtifacts(artifacts []build.Artifact, keepTags map[string]bool, now time.Time) []build.Artifact {
+	var prunable []build.Artifact
+	for _, a := range artifacts {
+		if now.Sub(a.PushedAt) < imageRetentionGracePeriod {
+			continue
+		}
+		kept := false
+		for _, t := range a.Tags {
+			if keepTags[t] {
+				kept = true
+				break
+			}
+		}
+		if !kept {
+			prunable = append(prunable, a)
+		}
+	}
+	return prunable
+}
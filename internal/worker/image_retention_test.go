@@ -0,0 +1,125 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/build"
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+// fakeHarbor serves the artifacts of repositories, keyed by "project/repository",
+// and records the artifacts deleted
+type fakeHarbor struct {
+	mu        sync.Mutex
+	artifacts map[string][]build.Artifact
+	deleted   []string
+}
+
+func (h *fakeHarbor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// /api/v2.0/projects/{project}/repositories/{repository}/artifacts[/{reference}]
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v2.0/projects/"), "/")
+	if len(parts) < 4 || parts[1] != "repositories" || parts[3] != "artifacts" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	repo := parts[0] + "/" + parts[2]
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		type tag struct {
+			Name string `json:"name"`
+		}
+		result := []map[string]interface{}{}
+		for _, a := range h.artifacts[repo] {
+			tags := []tag{}
+			for _, t := range a.Tags {
+				tags = append(tags, tag{Name: t})
+			}
+			result = append(result, map[string]interface{}{"digest": a.Digest, "push_time": a.PushedAt, "tags": tags})
+		}
+		json.NewEncoder(w).Encode(result)
+	case http.MethodDelete:
+		h.deleted = append(h.deleted, repo+"@"+parts[4])
+	}
+}
+
+func TestImageRetentionWorker_PrunesOnlyTheServicesRepository(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	dbStore := &store.DB{DB: db}
+	ctx := context.Background()
+
+	harbor := &fakeHarbor{}
+	registry := httptest.NewServer(harbor)
+	defer registry.Close()
+	host := strings.TrimPrefix(registry.URL, "http://")
+
+	// Two services of the same name in projects of different orgs
+	projectA := testutil.NewProject(t, db)
+	projectB := testutil.NewProject(t, db, func(p *testutil.Project) { p.OrgID = "other-org" })
+	serviceA := testutil.NewService(t, db, projectA.ID, func(s *testutil.Service) { s.Name = "web" })
+	serviceB := testutil.NewService(t, db, projectB.ID, func(s *testutil.Service) { s.Name = "web" })
+	repoA := "web/" + serviceA.ID.String()
+	repoB := "web/" + serviceB.ID.String()
+
+	deploy := func(image string, age time.Duration) {
+		d := testutil.NewDeployment(t, db, serviceA.ID, func(d *testutil.Deployment) { d.Status = "success" })
+		if _, err := db.Exec(`UPDATE deployments SET image_tag = $1, created_at = $2 WHERE id = $3`,
+			image, time.Now().Add(-age).UTC().Format("2006-01-02 15:04:05"), d.ID.String()); err != nil {
+			t.Fatalf("Failed to set deployment image: %v", err)
+		}
+	}
+	deploy(host+"/"+repoA+":new", time.Minute)
+	deploy(host+"/"+repoA+":old", 2*time.Hour)
+	// Deployed before services had their own repository
+	deploy(host+"/web/web:legacy", 3*time.Hour)
+
+	pushed := time.Now().Add(-2 * time.Hour)
+	harbor.artifacts = map[string][]build.Artifact{
+		repoA:     {{Digest: "sha256:a-new", Tags: []string{"new"}, PushedAt: pushed}, {Digest: "sha256:a-old", Tags: []string{"old"}, PushedAt: pushed}},
+		repoB:     {{Digest: "sha256:b-running", Tags: []string{"v1.0.0"}, PushedAt: pushed}},
+		"web/web": {{Digest: "sha256:legacy", Tags: []string{"legacy"}, PushedAt: pushed}, {Digest: "sha256:other-tenant", Tags: []string{"v1.0.0"}, PushedAt: pushed}},
+	}
+
+	cfg := &config.Config{RegistryURL: registry.URL, ImageRetentionCount: 1}
+	if err := NewImageRetentionWorker(dbStore, cfg).PruneImages(ctx); err != nil {
+		t.Fatalf("PruneImages failed: %v", err)
+	}
+
+	if len(harbor.deleted) != 1 || harbor.deleted[0] != repoA+"@sha256:a-old" {
+		t.Errorf("Deleted %v, want only %s@sha256:a-old", harbor.deleted, repoA)
+	}
+}
+
+func TestServiceRepositoryKeepTags(t *testing.T) {
+	service := &store.Service{ID: uuid.New(), Name: "web"}
+	project, repository, keepTags, err := serviceRepositoryKeepTags("https://registry.example.com", service, []string{
+		"registry.example.com/web/" + service.ID.String() + ":abc123",
+		"registry.example.com/web/web:abc123", // Shared by same-named services
+		"not-an-image",
+	})
+	if err != nil {
+		t.Fatalf("serviceRepositoryKeepTags failed: %v", err)
+	}
+	if project != "web" || repository != service.ID.String() {
+		t.Errorf("Repository = %s/%s, want web/%s", project, repository, service.ID)
+	}
+	if len(keepTags) != 1 || !keepTags["abc123"] {
+		t.Errorf("Kept tags = %v, want only abc123", keepTags)
+	}
+}
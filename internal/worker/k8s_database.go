@@ -2,7 +2,9 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,6 +13,10 @@ import (
 	"github.com/intelifox/click-deploy/internal/store"
 )
 
+// databaseUpgradeTimeout bounds a database upgrade, dumping and restoring large
+// databases included
+const databaseUpgradeTimeout = 2 * time.Hour
+
 // K8sDatabaseWorker handles database provisioning on k8s
 type K8sDatabaseWorker struct {
 	store     *store.DB
@@ -59,18 +65,36 @@ func (w *K8sDatabaseWorker) ProvisionDatabase(ctx context.Context, databaseID uu
 	w.store.UpdateDatabaseStatus(ctx, databaseID, "provisioning")
 
 	// Ensure namespace exists
-	if err := w.k8sClient.CreateNamespace(ctx, project.ID.String(), project.Name); err != nil {
+	if err := w.k8sClient.CreateNamespace(ctx, project.ID.String(), project.Name, project.CasdoorOrgID); err != nil {
 		return fmt.Errorf("failed to create namespace: %w", err)
 	}
 
+	params, err := w.store.GetDatabaseParameters(ctx, databaseID)
+	if err != nil {
+		return fmt.Errorf("failed to get database parameters: %w", err)
+	}
+
+	resources, err := k8s.DatabaseSizePreset(db.Size)
+	if err != nil {
+		w.store.UpdateDatabaseStatus(ctx, databaseID, "failed")
+		return err
+	}
+
 	// Create database on k8s
 	spec := k8s.DatabaseSpec{
-		DatabaseID:   databaseID.String(),
-		DatabaseName: db.DatabaseName.String,
-		ProjectID:    project.ID.String(),
-		Engine:       db.Engine,
-		Version:      db.Version.String,
-		SizeMB:       int64(db.VolumeSizeMB),
+		DatabaseID:    databaseID.String(),
+		DatabaseName:  db.DatabaseName.String,
+		Username:      db.Username.String,
+		ProjectID:     project.ID.String(),
+		Engine:        db.Engine,
+		Version:       db.Version.String,
+		SizeMB:        int64(db.VolumeSizeMB),
+		CPURequest:    resources.CPURequest,
+		CPULimit:      resources.CPULimit,
+		MemoryRequest: resources.MemoryRequest,
+		MemoryLimit:   resources.MemoryLimit,
+		Parameters:    params,
+		StorageClass:  db.StorageClass.String,
 	}
 
 	creds, err := w.k8sClient.CreateDatabase(ctx, spec)
@@ -162,3 +186,39 @@ func (w *K8sDatabaseWorker) DeleteDatabase(ctx context.Context, databaseID uuid.
 
 	return nil
 }
+
+// UpgradeDatabase upgrades a database of project, marked upgrading by
+// StartDatabaseUpgrade, to targetVersion and stores its new version. A failed upgrade
+// that left the old version running marks it active again; one that may have changed
+// its data marks it failed, the backup taken before kept to restore it.
+func (w *K8sDatabaseWorker) UpgradeDatabase(ctx context.Context, database *store.Database, projectID uuid.UUID, targetVersion, updatedBy string) error {
+	spec := k8s.DatabaseSpec{
+		DatabaseID:   database.ID.String(),
+		DatabaseName: database.DatabaseName.String,
+		Username:     database.Username.String,
+		ProjectID:    projectID.String(),
+		Engine:       database.Engine,
+		Version:      database.Version.String,
+		SizeMB:       int64(database.VolumeSizeMB),
+		StorageClass: database.StorageClass.String,
+	}
+
+	upgradeCtx, cancel := context.WithTimeout(ctx, databaseUpgradeTimeout)
+	defer cancel()
+
+	upgrade, err := w.k8sClient.UpgradeDatabase(upgradeCtx, spec, targetVersion)
+	if errors.Is(err, k8s.ErrDatabaseNotUpgraded) {
+		w.store.FinishDatabaseUpgrade(ctx, database.ID, "", updatedBy)
+		return fmt.Errorf("failed to upgrade database: %w", err)
+	}
+	if err != nil {
+		w.store.UpdateDatabaseStatus(ctx, database.ID, "failed")
+		return fmt.Errorf("failed to upgrade database: %w", err)
+	}
+
+	log.Printf("Database %s upgraded to %s %s (%s), backup kept in PVC %s", database.ID, database.Engine, targetVersion, upgrade.Strategy, upgrade.BackupPVC)
+	if err := w.store.FinishDatabaseUpgrade(ctx, database.ID, targetVersion, updatedBy); err != nil {
+		return fmt.Errorf("failed to update database version: %w", err)
+	}
+	return nil
+}
@@ -3,30 +3,54 @@ package worker
 import (
 	"context"
 	"fmt"
+	"log"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/intelifox/click-deploy/internal/config"
 	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/realtime"
+	"github.com/intelifox/click-deploy/internal/secrets"
 	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/tracing"
 )
 
-// K8sDeployWorker handles k8s deployments after builds complete
+// K8sDeployWorker handles k8s deployments after builds complete. Services are
+// deployed to the cluster of their project's region.
 type K8sDeployWorker struct {
 	store     *store.DB
-	k8sClient *k8s.Client
+	clients   *k8s.ClientRegistry
+	secrets   secrets.Resolver // Reads env vars kept in an external secret manager
+	publisher realtime.Publisher
+	// Template of the path prefix an org's secret references are confined to
+	secretPathPrefix string
 }
 
 // NewK8sDeployWorker creates a new k8s deployment worker
-func NewK8sDeployWorker(store *store.DB, k8sClient *k8s.Client) *K8sDeployWorker {
+func NewK8sDeployWorker(store *store.DB, cfg *config.Config, clients *k8s.ClientRegistry) *K8sDeployWorker {
 	return &K8sDeployWorker{
 		store:     store,
-		k8sClient: k8sClient,
+		clients:   clients,
+		secrets:   secrets.NewResolver(cfg.VaultAddr, cfg.VaultToken, cfg.SecretCacheTTL),
+		publisher: realtime.NewCentrifugoPublisher(cfg.CentrifugoAPIURL, cfg.CentrifugoAPIKey),
+
+		secretPathPrefix: cfg.VaultOrgPathPrefix,
 	}
 }
 
+// ProjectRegion returns the region a project's services run in, empty for the default region
+func ProjectRegion(project *store.Project) string {
+	return project.DefaultRegion.String
+}
+
 // DeployToK8s deploys a service to Kubernetes after a successful build
-func (w *K8sDeployWorker) DeployToK8s(ctx context.Context, deploymentID uuid.UUID) error {
+func (w *K8sDeployWorker) DeployToK8s(ctx context.Context, deploymentID uuid.UUID) (err error) {
+	ctx, span := tracing.Start(ctx, "k8s.DeployToK8s", attribute.String("deployment.id", deploymentID.String()))
+	defer func() { tracing.End(span, err) }()
+
 	// Get deployment
 	deployment, err := w.store.GetDeployment(ctx, deploymentID)
 	if err != nil {
@@ -54,51 +78,67 @@ func (w *K8sDeployWorker) DeployToK8s(ctx context.Context, deploymentID uuid.UUI
 		return fmt.Errorf("project not found: %s", service.ProjectID)
 	}
 
+	// Env vars may have changed since the build started
+	if err := checkServiceEnv(ctx, w.store, service.ID); err != nil {
+		w.log(ctx, deploymentID, "deploy", "error", err.Error(), nil)
+		return err
+	}
+
+	client, err := w.clients.Client(ProjectRegion(project))
+	if err != nil {
+		w.log(ctx, deploymentID, "deploy", "error", fmt.Sprintf("No cluster for the project's region: %v", err), nil)
+		return err
+	}
+
 	// Update deployment status to deploying
-	w.store.UpdateDeploymentStatus(ctx, deploymentID, "deploying")
-	w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "info", "Starting Kubernetes deployment", nil)
+	w.setStatus(ctx, service.ID, deploymentID, "deploying")
+	w.log(ctx, deploymentID, "deploy", "info", "Starting Kubernetes deployment", nil)
 
 	// Ensure namespace exists
-	if err := w.k8sClient.CreateNamespace(ctx, project.ID.String(), project.Name); err != nil {
-		w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "error", fmt.Sprintf("Failed to create namespace: %v", err), nil)
+	if err := client.CreateNamespace(ctx, project.ID.String(), project.Name, project.CasdoorOrgID); err != nil {
+		w.log(ctx, deploymentID, "deploy", "error", fmt.Sprintf("Failed to create namespace: %v", err), nil)
 		return fmt.Errorf("failed to create namespace: %w", err)
 	}
 
 	projectID := project.ID.String()
 	serviceID := service.ID.String()
 
-	// Get environment variables for the service
-	envVars, err := w.store.ListEnvVarsByService(ctx, service.ID)
+	// Resolve environment variables (project defaults overridden by service vars)
+	envVars, err := w.store.ResolveEnvVarsWithSource(ctx, service.ID)
 	if err != nil {
-		w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "warn", fmt.Sprintf("Failed to get env vars: %v", err), nil)
-		envVars = []*store.EnvVar{} // Continue with empty env vars
+		w.log(ctx, deploymentID, "deploy", "warn", fmt.Sprintf("Failed to get env vars: %v", err), nil)
+		envVars = nil // Continue with empty env vars
 	}
-
-	// Create/update secret with environment variables
-	envMap := make(map[string]string)
-	for _, ev := range envVars {
-		if ev.Value.Valid {
-			envMap[ev.Key] = ev.Value.String
-		}
+	envMap, err := resolveSecretRefs(ctx, w.secrets, secrets.OrgPathPrefix(w.secretPathPrefix, project.CasdoorOrgID), envVars)
+	if err != nil {
+		w.log(ctx, deploymentID, "deploy", "error", err.Error(), nil)
+		return err
+	}
+	resolveServiceLinks(client, envVars, envMap)
+	// Expand ${VAR} references of the vars that opted in now that every value is known
+	envMap, err = store.InterpolateEnvVars(envVars, envMap)
+	if err != nil {
+		w.log(ctx, deploymentID, "deploy", "error", err.Error(), nil)
+		return err
 	}
 
 	if len(envMap) > 0 {
-		_, err = w.k8sClient.UpdateSecret(ctx, k8s.SecretSpec{
+		_, err = client.UpdateSecret(ctx, k8s.SecretSpec{
 			ServiceID:   serviceID,
 			ServiceName: service.Name,
 			ProjectID:   projectID,
 			EnvVars:     envMap,
 		})
 		if err != nil {
-			w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "error", fmt.Sprintf("Failed to create secret: %v", err), nil)
+			w.log(ctx, deploymentID, "deploy", "error", fmt.Sprintf("Failed to create secret: %v", err), nil)
 			return fmt.Errorf("failed to create secret: %w", err)
 		}
 	}
 
 	// Check if deployment exists
-	deployStatus, err := w.k8sClient.GetDeploymentStatus(ctx, projectID, serviceID)
+	deployStatus, err := client.GetDeploymentStatus(ctx, projectID, serviceID)
 	if err != nil {
-		w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "error", fmt.Sprintf("Failed to check deployment status: %v", err), nil)
+		w.log(ctx, deploymentID, "deploy", "error", fmt.Sprintf("Failed to check deployment status: %v", err), nil)
 		return fmt.Errorf("failed to check deployment status: %w", err)
 	}
 
@@ -116,23 +156,43 @@ func (w *K8sDeployWorker) DeployToK8s(ctx context.Context, deploymentID uuid.UUI
 		Image:         imageTag,
 		Port:          int32(service.Port),
 		Replicas:      1,
-		EnvSecretName: w.k8sClient.SecretName(serviceID),
-		HealthCheckPath: "/health", // Default health check path
+		EnvSecretName: client.SecretName(serviceID),
+		// Without a health check path the pods get a TCP-connect readiness probe
+		HealthCheckPath:      service.HealthCheckPath.String,
+		ProbeInitialDelay:    int32(service.HealthCheckInitialDelay.Int64),
+		DefaultProbeDisabled: service.DefaultProbeDisabled,
+	}
+	if resources, err := k8s.InstanceSizePreset(service.InstanceSize); err == nil {
+		deploySpec.CPURequest = resources.CPURequest
+		deploySpec.CPULimit = resources.CPULimit
+		deploySpec.MemoryRequest = resources.MemoryRequest
+		deploySpec.MemoryLimit = resources.MemoryLimit
+	} else {
+		log.Printf("Service %s: %v, using default resources", serviceID, err)
+	}
+	if service.StartCommand.Valid {
+		// Overrides the start command of the image, whether Railpack or a Dockerfile built it
+		deploySpec.Command = []string{"sh", "-c", service.StartCommand.String}
+	}
+
+	// Keep the config this deployment rolls out, so it can be reviewed and compared later
+	if err := w.store.SetDeploymentConfigSnapshot(ctx, deploymentID, deploymentConfig(service, deploySpec, envMap)); err != nil {
+		log.Printf("Failed to store config snapshot of deployment %s: %v", deploymentID, err)
 	}
 
 	if deployStatus.Exists {
 		// Update existing deployment
-		w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "info", "Updating existing deployment", nil)
-		_, err = w.k8sClient.UpdateDeployment(ctx, deploySpec)
+		w.log(ctx, deploymentID, "deploy", "info", "Updating existing deployment", nil)
+		_, err = client.UpdateDeployment(ctx, deploySpec)
 	} else {
 		// Create new deployment
-		w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "info", "Creating new deployment", nil)
-		_, err = w.k8sClient.CreateDeployment(ctx, deploySpec)
+		w.log(ctx, deploymentID, "deploy", "info", "Creating new deployment", nil)
+		_, err = client.CreateDeployment(ctx, deploySpec)
 	}
 
 	if err != nil {
-		w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "error", fmt.Sprintf("Failed to deploy: %v", err), nil)
-		w.store.UpdateDeploymentStatus(ctx, deploymentID, "failed")
+		w.log(ctx, deploymentID, "deploy", "error", fmt.Sprintf("Failed to deploy: %v", err), nil)
+		w.setStatus(ctx, service.ID, deploymentID, "failed")
 		return fmt.Errorf("failed to deploy: %w", err)
 	}
 
@@ -145,16 +205,31 @@ func (w *K8sDeployWorker) DeployToK8s(ctx context.Context, deploymentID uuid.UUI
 		TargetPort:  int32(service.Port),
 	}
 
-	_, err = w.k8sClient.GetService(ctx, projectID, serviceID)
+	_, err = client.GetService(ctx, projectID, serviceID)
 	if err != nil {
 		// Service doesn't exist, create it
-		_, err = w.k8sClient.CreateService(ctx, svcSpec)
+		_, err = client.CreateService(ctx, svcSpec)
 		if err != nil {
-			w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "error", fmt.Sprintf("Failed to create service: %v", err), nil)
+			w.log(ctx, deploymentID, "deploy", "error", fmt.Sprintf("Failed to create service: %v", err), nil)
 			return fmt.Errorf("failed to create k8s service: %w", err)
 		}
 	}
 
+	// Services created without a subdomain claim one now, rather than a host derived
+	// from the name alone that another service of the same name would share
+	if !service.Subdomain.Valid {
+		subdomain, generatedURL, err := w.store.AllocateSubdomain(ctx, service.ID, func() (string, string, error) {
+			return client.SubdomainCandidate(service.Name)
+		}, service.UpdatedBy.String)
+		if err != nil {
+			w.log(ctx, deploymentID, "deploy", "error", fmt.Sprintf("Failed to allocate subdomain: %v", err), nil)
+			w.setStatus(ctx, service.ID, deploymentID, "failed")
+			return fmt.Errorf("failed to allocate subdomain: %w", err)
+		}
+		service.Subdomain = store.StringToNullString(subdomain)
+		service.GeneratedURL = store.StringToNullString(generatedURL)
+	}
+
 	// Create/update Ingress
 	environment := "prod" // Could be dynamic based on project environment
 	ingressSpec := k8s.IngressSpec{
@@ -163,6 +238,8 @@ func (w *K8sDeployWorker) DeployToK8s(ctx context.Context, deploymentID uuid.UUI
 		ProjectID:   projectID,
 		Environment: environment,
 		Port:        int32(service.Port),
+		Streaming:   service.SupportsWebsockets,
+		Subdomain:   service.Subdomain.String,
 	}
 
 	// Get custom domains for this service
@@ -175,34 +252,40 @@ func (w *K8sDeployWorker) DeployToK8s(ctx context.Context, deploymentID uuid.UUI
 		}
 	}
 
-	_, err = w.k8sClient.GetIngress(ctx, projectID, serviceID)
+	_, err = client.GetIngress(ctx, projectID, serviceID)
 	if err != nil {
 		// Ingress doesn't exist, create it
-		_, err = w.k8sClient.CreateIngress(ctx, ingressSpec)
+		_, err = client.CreateIngress(ctx, ingressSpec)
 	} else {
 		// Update existing ingress
-		_, err = w.k8sClient.UpdateIngress(ctx, ingressSpec)
+		_, err = client.UpdateIngress(ctx, ingressSpec)
 	}
 
 	if err != nil {
-		w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "warn", fmt.Sprintf("Failed to configure ingress: %v", err), nil)
+		w.log(ctx, deploymentID, "deploy", "warn", fmt.Sprintf("Failed to configure ingress: %v", err), nil)
 		// Don't fail deployment for ingress issues
 	}
 
 	// Wait for deployment to be ready
-	w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "info", "Waiting for deployment to be ready", nil)
+	w.log(ctx, deploymentID, "deploy", "info", "Waiting for deployment to be ready", nil)
 	
 	readyCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
-	if err := w.waitForDeploymentReady(readyCtx, projectID, serviceID, deploymentID); err != nil {
-		w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "error", fmt.Sprintf("Deployment failed to become ready: %v", err), nil)
-		w.store.UpdateDeploymentStatus(ctx, deploymentID, "failed")
+	if err := w.waitForDeploymentReady(readyCtx, client, projectID, serviceID, deploymentID); err != nil {
+		w.log(ctx, deploymentID, "deploy", "error", fmt.Sprintf("Deployment failed to become ready: %v", err), nil)
+		w.setStatus(ctx, service.ID, deploymentID, "failed")
 		return fmt.Errorf("deployment failed to become ready: %w", err)
 	}
 
+	// An imported service ran as the deployment it was imported from until now
+	w.retireImportedDeployment(ctx, client, service.ID, deploymentID)
+
 	// Update service status and URL
-	generatedURL := w.k8sClient.GetServiceURL(service.Name, environment)
+	generatedURL := client.GetServiceURL(service.Name, environment)
+	if service.Subdomain.Valid {
+		generatedURL = client.SubdomainURL(service.Subdomain.String)
+	}
 	if service.GeneratedURL.Valid {
 		service.GeneratedURL.String = generatedURL
 	}
@@ -210,27 +293,129 @@ func (w *K8sDeployWorker) DeployToK8s(ctx context.Context, deploymentID uuid.UUI
 	w.store.UpdateService(ctx, service.ID, service)
 
 	// Update deployment status
-	w.store.UpdateDeploymentStatus(ctx, deploymentID, "success")
+	w.setStatus(ctx, service.ID, deploymentID, "success")
 	w.store.UpdateDeploymentProgress(ctx, deploymentID, map[string]interface{}{
 		"finished_at": time.Now(),
 	})
-	w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "info", 
+	w.log(ctx, deploymentID, "deploy", "info", 
 		fmt.Sprintf("Deployment successful! Service available at %s", generatedURL), nil)
 
 	return nil
 }
 
+// log adds a line to the deployment's log and publishes it
+func (w *K8sDeployWorker) log(ctx context.Context, deploymentID uuid.UUID, phase, level, message string, metadata map[string]interface{}) {
+	_ = w.store.AddDeploymentLog(ctx, deploymentID, phase, level, message, metadata)
+
+	// Best-effort publish to Centrifugo (deployment:<id>)
+	if w.publisher != nil {
+		_ = realtime.PublishDeploymentLog(ctx, w.publisher, deploymentID, phase, level, message, metadata)
+	}
+}
+
+// setStatus moves a deployment to status and publishes the change
+func (w *K8sDeployWorker) setStatus(ctx context.Context, serviceID, deploymentID uuid.UUID, status string) {
+	if err := w.store.UpdateDeploymentStatus(ctx, deploymentID, status); err != nil {
+		return
+	}
+
+	// Best-effort publish to Centrifugo (deployment:<id> and service:<id>)
+	if w.publisher != nil {
+		_ = realtime.PublishDeploymentStatus(ctx, w.publisher, serviceID, deploymentID, status)
+	}
+}
+
+// retireImportedDeployment deletes the deployment an imported service was imported
+// from, now that the service's own deployment is ready to take its place
+func (w *K8sDeployWorker) retireImportedDeployment(ctx context.Context, client *k8s.Client, serviceID, deploymentID uuid.UUID) {
+	namespace, name, err := w.store.GetServiceImport(ctx, serviceID)
+	if err != nil || name == "" {
+		return
+	}
+
+	if err := client.DeleteImportedDeployment(ctx, namespace, name); err != nil {
+		w.log(ctx, deploymentID, "deploy", "warn", fmt.Sprintf("Failed to delete imported deployment %s/%s: %v", namespace, name, err), nil)
+		return
+	}
+	w.store.SetServiceImport(ctx, serviceID, "", "")
+	w.log(ctx, deploymentID, "deploy", "info", fmt.Sprintf("Deleted imported deployment %s/%s", namespace, name), nil)
+}
+
+// resolveSecretRefs returns the values of env vars, reading the ones that reference
+// an external secret manager. Their values only go into the deployment's Secret.
+// References outside pathPrefix, the org's part of the secret manager, are refused
+// even if they were stored before it was enforced.
+func resolveSecretRefs(ctx context.Context, resolver secrets.Resolver, pathPrefix string, envVars []*store.ResolvedEnvVar) (map[string]string, error) {
+	env := make(map[string]string, len(envVars))
+	for _, ev := range envVars {
+		if ev.SecretRef == "" {
+			env[ev.Key] = ev.Value
+			continue
+		}
+
+		ref, err := secrets.ParseSecretRef(ev.SecretRef)
+		if err == nil {
+			err = ref.ValidateScope(pathPrefix)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("env var %s: %w", ev.Key, err)
+		}
+		value, err := resolver.Resolve(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("env var %s: %w", ev.Key, err)
+		}
+		env[ev.Key] = value
+	}
+	return env, nil
+}
+
+// resolveServiceLinks sets the values of env vars linked to another service to the
+// service's in-cluster address
+func resolveServiceLinks(client *k8s.Client, envVars []*store.ResolvedEnvVar, env map[string]string) {
+	for _, ev := range envVars {
+		if ev.ServiceLink != nil {
+			host := client.ServiceHost(ev.ServiceLink.ProjectID.String(), ev.ServiceLink.ServiceID.String())
+			env[ev.Key] = ev.ServiceLink.Value(host)
+		}
+	}
+}
+
+// deploymentConfig is the config snapshot of a deployment rolling out spec
+func deploymentConfig(service *store.Service, spec k8s.DeploymentSpec, envVars map[string]string) *store.DeploymentConfig {
+	keys := make([]string, 0, len(envVars))
+	for key := range envVars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return &store.DeploymentConfig{
+		Image:           spec.Image,
+		EnvVarKeys:      keys,
+		InstanceSize:    service.InstanceSize,
+		Replicas:        int(spec.Replicas),
+		Port:            int(spec.Port),
+		HealthCheckPath: spec.HealthCheckPath,
+	}
+}
+
+// rolloutStallAfter is how long a rollout may take before pod warnings are copied
+// into the deployment log
+const rolloutStallAfter = 30 * time.Second
+
 // waitForDeploymentReady polls the deployment status until it's ready
-func (w *K8sDeployWorker) waitForDeploymentReady(ctx context.Context, projectID, serviceID string, deploymentID uuid.UUID) error {
+func (w *K8sDeployWorker) waitForDeploymentReady(ctx context.Context, client *k8s.Client, projectID, serviceID string, deploymentID uuid.UUID) error {
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
+	started := time.Now()
+	loggedEvents := map[string]bool{}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-ticker.C:
-			status, err := w.k8sClient.GetDeploymentStatus(ctx, projectID, serviceID)
+			status, err := client.GetDeploymentStatus(ctx, projectID, serviceID)
 			if err != nil {
 				return fmt.Errorf("failed to get deployment status: %w", err)
 			}
@@ -239,33 +424,64 @@ func (w *K8sDeployWorker) waitForDeploymentReady(ctx context.Context, projectID,
 				return nil
 			}
 
-			w.store.AddDeploymentLog(ctx, deploymentID, "deploy", "info",
+			w.log(ctx, deploymentID, "deploy", "info",
 				fmt.Sprintf("Waiting for pods... (%d/%d ready)", status.ReadyReplicas, status.Replicas), nil)
+
+			if time.Since(started) >= rolloutStallAfter {
+				w.logPodWarnings(ctx, client, projectID, serviceID, deploymentID, started, loggedEvents)
+			}
 		}
 	}
 }
 
-// CleanupK8sResources removes all k8s resources for a service
-func (w *K8sDeployWorker) CleanupK8sResources(ctx context.Context, projectID, serviceID string) error {
+// logPodWarnings adds the service's pod warnings since the rollout started to the
+// deployment log, skipping those already in logged
+func (w *K8sDeployWorker) logPodWarnings(ctx context.Context, client *k8s.Client, projectID, serviceID string, deploymentID uuid.UUID, since time.Time, logged map[string]bool) {
+	events, err := client.GetPodEvents(ctx, projectID, serviceID)
+	if err != nil {
+		log.Printf("Failed to get pod events for service %s: %v", serviceID, err)
+		return
+	}
+
+	for _, e := range events {
+		// Events of pods from earlier rollouts are older than this one
+		if e.Type != "Warning" || e.LastSeen.Before(since) || logged[e.Key()] {
+			continue
+		}
+		logged[e.Key()] = true
+
+		w.log(ctx, deploymentID, "deploy", "warn",
+			fmt.Sprintf("%s: %s", e.Reason, e.Message),
+			map[string]interface{}{"pod": e.PodName, "reason": e.Reason, "count": e.Count})
+	}
+}
+
+// CleanupK8sResources removes all k8s resources for a service from its region's cluster
+func (w *K8sDeployWorker) CleanupK8sResources(ctx context.Context, region, projectID, serviceID string) error {
+	client, err := w.clients.Client(region)
+	if err != nil {
+		return err
+	}
+
 	var errs []error
 
 	// Delete Ingress
-	if err := w.k8sClient.DeleteIngress(ctx, projectID, serviceID); err != nil {
+	if err := client.DeleteIngress(ctx, projectID, serviceID); err != nil {
 		errs = append(errs, fmt.Errorf("ingress: %w", err))
 	}
 
 	// Delete Service
-	if err := w.k8sClient.DeleteService(ctx, projectID, serviceID); err != nil {
+	if err := client.DeleteService(ctx, projectID, serviceID); err != nil {
 		errs = append(errs, fmt.Errorf("service: %w", err))
 	}
 
 	// Delete Deployment
-	if err := w.k8sClient.DeleteDeployment(ctx, projectID, serviceID); err != nil {
+	if err := client.DeleteDeployment(ctx, projectID, serviceID); err != nil {
 		errs = append(errs, fmt.Errorf("deployment: %w", err))
 	}
 
 	// Delete Secret
-	if err := w.k8sClient.DeleteSecret(ctx, projectID, serviceID); err != nil {
+	if err := client.DeleteSecret(ctx, projectID, serviceID); err != nil {
 		errs = append(errs, fmt.Errorf("secret: %w", err))
 	}
 
@@ -276,8 +492,12 @@ func (w *K8sDeployWorker) CleanupK8sResources(ctx context.Context, projectID, se
 	return nil
 }
 
-// CleanupK8sProject removes the entire namespace for a project
-func (w *K8sDeployWorker) CleanupK8sProject(ctx context.Context, projectID string) error {
-	return w.k8sClient.DeleteNamespace(ctx, projectID)
+// CleanupK8sProject removes the entire namespace for a project from its region's cluster
+func (w *K8sDeployWorker) CleanupK8sProject(ctx context.Context, region, projectID string) error {
+	client, err := w.clients.Client(region)
+	if err != nil {
+		return err
+	}
+	return client.DeleteNamespace(ctx, projectID)
 }
 
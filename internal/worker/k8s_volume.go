@@ -43,7 +43,7 @@ func (w *K8sVolumeWorker) CreateVolume(ctx context.Context, volumeID uuid.UUID)
 	}
 
 	// Ensure namespace exists
-	if err := w.k8sClient.CreateNamespace(ctx, project.ID.String(), project.Name); err != nil {
+	if err := w.k8sClient.CreateNamespace(ctx, project.ID.String(), project.Name, project.CasdoorOrgID); err != nil {
 		return fmt.Errorf("failed to create namespace: %w", err)
 	}
 
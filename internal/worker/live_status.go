@@ -0,0 +1,130 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/realtime"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// LiveStatusWorker pushes the live status of services to the Centrifugo channels of
+// their projects, so dashboards update without polling. Only projects someone is
+// subscribed to are looked up.
+type LiveStatusWorker struct {
+	store      *store.DB
+	config     *config.Config
+	k8sClients *k8s.ClientRegistry
+	publisher  *realtime.CentrifugoPublisher
+}
+
+// NewLiveStatusWorker creates a new live status worker
+func NewLiveStatusWorker(store *store.DB, cfg *config.Config, clients *k8s.ClientRegistry) *LiveStatusWorker {
+	return &LiveStatusWorker{
+		store:      store,
+		config:     cfg,
+		k8sClients: clients,
+		publisher:  realtime.NewCentrifugoPublisher(cfg.CentrifugoAPIURL, cfg.CentrifugoAPIKey),
+	}
+}
+
+// Start publishes every LiveStatusPublishInterval until ctx is cancelled
+func (w *LiveStatusWorker) Start(ctx context.Context) {
+	interval := w.config.LiveStatusPublishInterval
+	if interval <= 0 || w.k8sClients == nil || !w.publisher.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Publish(ctx); err != nil {
+				log.Printf("Live status: %v", err)
+			}
+		}
+	}
+}
+
+// Publish pushes the live status of the services of every project with subscribers.
+// Failures for one project are logged and don't stop the others.
+func (w *LiveStatusWorker) Publish(ctx context.Context) error {
+	channels, err := w.publisher.ActiveChannels(ctx, realtime.ProjectChannelPattern)
+	if err != nil {
+		return err
+	}
+
+	for _, channel := range channels {
+		projectID, ok := realtime.ParseProjectChannel(channel)
+		if !ok {
+			continue
+		}
+		if err := w.publishProject(ctx, projectID); err != nil {
+			log.Printf("Live status: project %s: %v", projectID, err)
+		}
+	}
+	return nil
+}
+
+// publishProject pushes the live status of a project's services
+func (w *LiveStatusWorker) publishProject(ctx context.Context, projectID uuid.UUID) error {
+	project, err := w.store.GetProject(ctx, projectID)
+	if err != nil || project == nil {
+		return err
+	}
+	services, err := w.store.ListServicesByProject(ctx, projectID)
+	if err != nil || len(services) == 0 {
+		return err
+	}
+
+	region := ProjectRegion(project)
+	client, err := w.k8sClients.Client(region)
+	if err != nil {
+		return err
+	}
+
+	serviceIDs := make([]string, 0, len(services))
+	for _, s := range services {
+		serviceIDs = append(serviceIDs, s.ID.String())
+	}
+	live, err := client.GetDeploymentStatuses(ctx, projectID.String(), serviceIDs)
+	if err != nil {
+		return err
+	}
+	// Health and metrics are best effort, the replica counts are useful without them
+	healths, _ := client.GetServiceHealths(ctx, projectID.String())
+	metricsClient, _ := w.k8sClients.MetricsClient(region)
+
+	statuses := make([]realtime.ServiceLiveStatus, 0, len(services))
+	for _, s := range services {
+		status := realtime.ServiceLiveStatus{
+			ServiceID: s.ID,
+			Status:    s.Status,
+			K8sStatus: "unknown",
+			Health:    healths[s.ID.String()],
+		}
+		if ds, ok := live[s.ID.String()]; ok {
+			status.K8sStatus = ds.Phase()
+			status.ReadyReplicas = ds.ReadyReplicas
+			status.DesiredReplicas = ds.DesiredReplicas
+		}
+		if metricsClient != nil {
+			if metrics, err := metricsClient.GetServiceMetrics(ctx, projectID.String(), s.ID.String(), s.Name); err == nil {
+				status.CPUCores = &metrics.TotalCPU
+				status.MemoryMB = &metrics.TotalMemory
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	return realtime.PublishServiceStatuses(ctx, w.publisher, projectID, statuses)
+}
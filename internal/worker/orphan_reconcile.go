@@ -0,0 +1,217 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/infra"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// cloudResource is a resource listed from the infra service
+type cloudResource struct {
+	ID        string
+	Name      string
+	Managed   bool // Created by Zyndra, see infra.IsManaged; other resources are never touched
+	Deletable bool // False for resources that must not be deleted (yet), e.g. attached volumes
+}
+
+// cloudResourceKind lists one type of cloud resource
+type cloudResourceKind struct {
+	resourceType string
+	list         func(ctx context.Context, client infra.Client) ([]cloudResource, error)
+}
+
+// cloudResourceKinds are the cloud resources checked for an owning record
+var cloudResourceKinds = []cloudResourceKind{
+	{
+		resourceType: store.CloudResourceInstance,
+		list: func(ctx context.Context, client infra.Client) ([]cloudResource, error) {
+			instances, err := client.ListInstances(ctx)
+			if err != nil {
+				return nil, err
+			}
+			resources := make([]cloudResource, 0, len(instances))
+			for _, i := range instances {
+				resources = append(resources, cloudResource{ID: i.ID, Name: i.Name, Managed: infra.IsManaged(i.Name, i.Metadata), Deletable: true})
+			}
+			return resources, nil
+		},
+	},
+	{
+		resourceType: store.CloudResourceFloatingIP,
+		list: func(ctx context.Context, client infra.Client) ([]cloudResource, error) {
+			fips, err := client.ListFloatingIPs(ctx)
+			if err != nil {
+				return nil, err
+			}
+			resources := make([]cloudResource, 0, len(fips))
+			for _, fip := range fips {
+				resources = append(resources, cloudResource{ID: fip.ID, Name: fip.IPAddress, Managed: infra.IsManaged("", fip.Metadata), Deletable: true})
+			}
+			return resources, nil
+		},
+	},
+	{
+		resourceType: store.CloudResourceSecurityGroup,
+		list: func(ctx context.Context, client infra.Client) ([]cloudResource, error) {
+			groups, err := client.ListSecurityGroups(ctx)
+			if err != nil {
+				return nil, err
+			}
+			resources := make([]cloudResource, 0, len(groups))
+			for _, sg := range groups {
+				resources = append(resources, cloudResource{ID: sg.ID, Name: sg.Name, Managed: infra.IsManaged(sg.Name, sg.Metadata), Deletable: true})
+			}
+			return resources, nil
+		},
+	},
+	{
+		resourceType: store.CloudResourceVolume,
+		list: func(ctx context.Context, client infra.Client) ([]cloudResource, error) {
+			volumes, err := client.ListVolumes(ctx)
+			if err != nil {
+				return nil, err
+			}
+			resources := make([]cloudResource, 0, len(volumes))
+			for _, v := range volumes {
+				resources = append(resources, cloudResource{ID: v.ID, Name: v.Name, Managed: infra.IsManaged(v.Name, v.Metadata), Deletable: v.AttachedTo == ""})
+			}
+			return resources, nil
+		},
+	},
+}
+
+// OrphanReconcileWorker periodically looks for cloud resources that no service,
+// database or volume record points at, which a half-failed delete can leave
+// behind. Only resources Zyndra created are considered, so whatever else shares
+// the tenant (the default security group, resources made by hand) is left alone. Orphans are flagged in the database and logged; unless running dry,
+// they are deleted once they've been orphaned for the grace period. The grace
+// period also covers resources whose provisioning hasn't stored their ID yet.
+type OrphanReconcileWorker struct {
+	store   *store.DB
+	config  *config.Config
+	cleanup *CleanupWorker
+}
+
+// NewOrphanReconcileWorker creates a new orphan reconcile worker
+func NewOrphanReconcileWorker(store *store.DB, cfg *config.Config) *OrphanReconcileWorker {
+	return &OrphanReconcileWorker{
+		store:   store,
+		config:  cfg,
+		cleanup: NewCleanupWorker(store, cfg),
+	}
+}
+
+// Start reconciles every OrphanReconcileInterval until ctx is cancelled
+func (w *OrphanReconcileWorker) Start(ctx context.Context) {
+	interval := w.config.OrphanReconcileInterval
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Reconcile(ctx); err != nil {
+				log.Printf("Orphan reconcile: %v", err)
+			}
+		}
+	}
+}
+
+// Reconcile checks the cloud resources of every tenant. Failures for one tenant
+// are logged and don't stop the others.
+func (w *OrphanReconcileWorker) Reconcile(ctx context.Context) error {
+	owned, err := w.store.ListOwnedCloudResources(ctx)
+	if err != nil {
+		return err
+	}
+
+	projects, err := w.store.ListAllProjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	tenants := make(map[string]bool)
+	for _, project := range projects {
+		if project.OpenStackTenantID == "" || tenants[project.OpenStackTenantID] {
+			continue
+		}
+		tenants[project.OpenStackTenantID] = true
+
+		client := infra.NewRetryClient(infra.NewClient(infra.Config{
+			BaseURL:  w.config.InfraServiceURL,
+			APIKey:   w.config.InfraServiceAPIKey,
+			TenantID: project.OpenStackTenantID,
+			UseMock:  w.config.UseMockInfra,
+		}))
+		w.reconcileTenant(ctx, client, project.OpenStackTenantID, owned)
+	}
+
+	return nil
+}
+
+// reconcileTenant flags the tenant's orphaned resources and deletes those past the grace period
+func (w *OrphanReconcileWorker) reconcileTenant(ctx context.Context, client infra.Client, tenantID string, owned map[string]map[string]bool) {
+	for _, kind := range cloudResourceKinds {
+		// Whole seconds, so the database stores the exact value PruneOrphanedCloudResources compares against
+		startedAt := time.Now().Truncate(time.Second)
+
+		resources, err := kind.list(ctx, client)
+		if err != nil {
+			log.Printf("Orphan reconcile: tenant %s: failed to list %s resources: %v", tenantID, kind.resourceType, err)
+			continue
+		}
+
+		for _, r := range resources {
+			if !r.Managed || owned[kind.resourceType][r.ID] {
+				continue
+			}
+			if err := w.handleOrphan(ctx, client, tenantID, kind, r, startedAt); err != nil {
+				log.Printf("Orphan reconcile: tenant %s: %s %s: %v", tenantID, kind.resourceType, r.ID, err)
+			}
+		}
+
+		// Resources that were deleted or got an owner since the last run aren't orphans anymore
+		if err := w.store.PruneOrphanedCloudResources(ctx, tenantID, kind.resourceType, startedAt); err != nil {
+			log.Printf("Orphan reconcile: tenant %s: failed to prune %s flags: %v", tenantID, kind.resourceType, err)
+		}
+	}
+}
+
+func (w *OrphanReconcileWorker) handleOrphan(ctx context.Context, client infra.Client, tenantID string, kind cloudResourceKind, r cloudResource, seenAt time.Time) error {
+	firstSeen, err := w.store.RecordOrphanedCloudResource(ctx, tenantID, kind.resourceType, r.ID, r.Name, seenAt)
+	if err != nil {
+		return fmt.Errorf("failed to flag as orphaned: %w", err)
+	}
+
+	orphanedFor := seenAt.Sub(firstSeen)
+	if !r.Deletable || orphanedFor < w.config.OrphanDeleteGracePeriod {
+		log.Printf("Orphan reconcile: tenant %s: %s %s (%s) has no owning record (orphaned for %s)",
+			tenantID, kind.resourceType, r.ID, r.Name, orphanedFor.Round(time.Minute))
+		return nil
+	}
+
+	dryRun := w.config.OrphanReconcileDryRun
+	action, err := w.cleanup.DeleteCloudResource(ctx, client, kind.resourceType, r.ID, dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to delete: %w", err)
+	}
+	if dryRun {
+		log.Printf("Orphan reconcile: tenant %s: dry run, would %s (%s), orphaned for %s",
+			tenantID, action, r.Name, orphanedFor.Round(time.Minute))
+		return nil
+	}
+	log.Printf("Orphan reconcile: tenant %s: deleted %s %s (%s), orphaned for %s",
+		tenantID, kind.resourceType, r.ID, r.Name, orphanedFor.Round(time.Minute))
+	return nil
+}
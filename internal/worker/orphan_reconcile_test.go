@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/infra"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestOrphanReconcileWorker_OnlyDeletesManagedResources(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	ctx := context.Background()
+	client := infra.NewMockClient(infra.Config{UseMock: true})
+
+	create := func(name string, metadata map[string]string) string {
+		volume, err := client.CreateVolume(ctx, infra.CreateVolumeRequest{Name: name, SizeGB: 1, Metadata: metadata})
+		if err != nil {
+			t.Fatalf("Failed to create volume %s: %v", name, err)
+		}
+		return volume.ID
+	}
+	tagged := create("data", infra.ManagedMetadata(nil))
+	prefixed := create(infra.ResourceNamePrefix+"data", nil)
+	foreign := create("someone-elses", map[string]string{"owner": "ops"})
+	if _, err := client.CreateSecurityGroup(ctx, infra.CreateSecurityGroupRequest{Name: "default"}); err != nil {
+		t.Fatalf("Failed to create security group: %v", err)
+	}
+
+	// No grace period, so orphans are deleted on the first run
+	w := NewOrphanReconcileWorker(&store.DB{DB: db}, &config.Config{})
+	w.reconcileTenant(ctx, client, "tenant-a", map[string]map[string]bool{})
+
+	volumes, err := client.ListVolumes(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list volumes: %v", err)
+	}
+	left := make(map[string]bool)
+	for _, v := range volumes {
+		left[v.ID] = true
+	}
+	if left[tagged] || left[prefixed] {
+		t.Error("Orphaned Zyndra volumes were not deleted")
+	}
+	if !left[foreign] {
+		t.Error("A volume Zyndra didn't create was deleted")
+	}
+
+	groups, err := client.ListSecurityGroups(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list security groups: %v", err)
+	}
+	if len(groups) != 1 {
+		t.Errorf("The tenant's default security group was deleted")
+	}
+}
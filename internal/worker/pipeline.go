@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/tracing"
+)
+
+// RunDeploymentPipeline builds a queued deployment and rolls it out to k8s.
+// ctx should be detached from the request (see tracing.Detach) so the trace carries over.
+func RunDeploymentPipeline(ctx context.Context, db *store.DB, buildWorker *BuildWorker, k8sWorker *K8sDeployWorker, deploymentID uuid.UUID) {
+	ctx, span := tracing.Start(ctx, "deploy.pipeline", attribute.String("deployment.id", deploymentID.String()))
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	// Run build
+	if err = buildWorker.ProcessBuildJob(ctx, deploymentID); err != nil {
+		db.UpdateDeploymentStatus(ctx, deploymentID, "failed")
+		return
+	}
+
+	// Deploy to k8s after successful build
+	if err = k8sWorker.DeployToK8s(ctx, deploymentID); err != nil {
+		db.UpdateDeploymentStatus(ctx, deploymentID, "failed")
+		return
+	}
+}
@@ -8,11 +8,19 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/infra"
+	"github.com/intelifox/click-deploy/internal/secrets"
 	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/tracing"
 )
 
+// infraRetryJitter is added to the circuit breaker's retry-after so a rescheduled job
+// doesn't hit the breaker again right before it lets a trial call through
+const infraRetryJitter = 5 * time.Second
+
 // Pool manages a pool of workers that process jobs
 type Pool struct {
 	store         *store.DB
@@ -128,6 +136,13 @@ func (w *Worker) processNextJob() {
 		return
 	}
 
+	// Continue the trace of the request that queued the job
+	ctx = tracing.ExtractJobPayload(ctx, job.Payload)
+	ctx, span := tracing.Start(ctx, "job."+job.Type,
+		attribute.String("job.id", job.ID.String()),
+		attribute.Int("job.attempt", job.Attempts+1),
+	)
+
 	// Process job based on type
 	var processErr error
 	switch job.Type {
@@ -138,20 +153,31 @@ func (w *Worker) processNextJob() {
 	default:
 		processErr = fmt.Errorf("unknown job type: %s", job.Type)
 	}
+	tracing.End(span, processErr)
 
 	// Update job status
-	if processErr != nil {
+	if retryAfter, ok := infra.RetryAfter(processErr); ok {
+		// Infra is down (circuit breaker open): retry later without using up an attempt
+		runAt := time.Now().Add(retryAfter + infraRetryJitter)
+		if err := w.pool.store.RescheduleJob(ctx, job.ID, runAt); err != nil {
+			log.Printf("Worker %d: Error rescheduling job %s: %v", w.id, job.ID, err)
+		}
+		log.Printf("Worker %d: Job %s rescheduled for %s, infra unavailable: %v", w.id, job.ID, runAt.Format(time.RFC3339), processErr)
+	} else if processErr != nil {
 		// Increment attempts
 		w.pool.store.IncrementJobAttempts(ctx, job.ID)
 
+		// The error may quote a database's connection URL
+		errMsg := secrets.RedactConnectionURL(processErr.Error())
+
 		// Check if max attempts reached
 		if job.Attempts+1 >= job.MaxAttempts {
-			w.pool.store.FailJob(ctx, job.ID, processErr.Error())
-			log.Printf("Worker %d: Job %s failed after %d attempts: %v", w.id, job.ID, job.Attempts+1, processErr)
+			w.pool.store.DeadLetterJob(ctx, job.ID, errMsg)
+			log.Printf("Worker %d: Job %s dead-lettered after %d attempts: %s", w.id, job.ID, job.Attempts+1, errMsg)
 		} else {
 			// Requeue job
 			w.pool.store.UpdateJobStatus(ctx, job.ID, "queued")
-			log.Printf("Worker %d: Job %s requeued (attempt %d/%d): %v", w.id, job.ID, job.Attempts+1, job.MaxAttempts, processErr)
+			log.Printf("Worker %d: Job %s requeued (attempt %d/%d): %s", w.id, job.ID, job.Attempts+1, job.MaxAttempts, errMsg)
 		}
 	} else {
 		w.pool.store.CompleteJob(ctx, job.ID)
@@ -0,0 +1,127 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/cron"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// scalingScheduleInterval is how often scaling schedules are checked. Cron
+// expressions have minute resolution.
+const scalingScheduleInterval = time.Minute
+
+// ScalingScheduleWorker scales services to the replica counts of their scaling
+// schedules when the entries' cron expressions fire
+type ScalingScheduleWorker struct {
+	store      *store.DB
+	k8sClients *k8s.ClientRegistry
+}
+
+// NewScalingScheduleWorker creates a new scaling schedule worker
+func NewScalingScheduleWorker(store *store.DB, clients *k8s.ClientRegistry) *ScalingScheduleWorker {
+	return &ScalingScheduleWorker{
+		store:      store,
+		k8sClients: clients,
+	}
+}
+
+// Start applies scaling schedules every scalingScheduleInterval until ctx is cancelled
+func (w *ScalingScheduleWorker) Start(ctx context.Context) {
+	if w.k8sClients == nil {
+		// Nothing to scale
+		return
+	}
+
+	ticker := time.NewTicker(scalingScheduleInterval)
+	defer ticker.Stop()
+
+	since := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := w.Apply(ctx, since, now); err != nil {
+				log.Printf("Scaling schedule: %v", err)
+			}
+			since = now
+		}
+	}
+}
+
+// Apply scales the services with schedule entries that fired after since, up to now,
+// on the cluster of their project's region
+func (w *ScalingScheduleWorker) Apply(ctx context.Context, since, now time.Time) error {
+	entries, err := w.store.ListAllScalingSchedules(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range dueScalings(entries, since, now) {
+		client, err := w.projectClient(ctx, e.ProjectID)
+		if err != nil {
+			log.Printf("Scaling schedule: service %s: %v", e.ServiceID, err)
+			continue
+		}
+		if err := client.ScaleDeployment(ctx, e.ProjectID.String(), e.ServiceID.String(), int32(e.Replicas)); err != nil {
+			log.Printf("Scaling schedule: failed to scale service %s to %d replicas: %v", e.ServiceID, e.Replicas, err)
+			continue
+		}
+		log.Printf("Scaling schedule: scaled service %s to %d replicas (%q %s)", e.ServiceID, e.Replicas, e.CronExpression, e.Timezone)
+	}
+	return nil
+}
+
+// projectClient returns the client of the cluster of a project's region
+func (w *ScalingScheduleWorker) projectClient(ctx context.Context, projectID uuid.UUID) (*k8s.Client, error) {
+	project, err := w.store.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, fmt.Errorf("project not found: %s", projectID)
+	}
+	return w.k8sClients.Client(ProjectRegion(project))
+}
+
+// dueScalings returns, per service, the entry that fired last after since, up to
+// now. Of entries firing at the same time the later one in the schedule wins.
+func dueScalings(entries []*store.ScalingScheduleEntry, since, now time.Time) map[uuid.UUID]*store.ScalingScheduleEntry {
+	due := make(map[uuid.UUID]*store.ScalingScheduleEntry)
+	firedAt := make(map[uuid.UUID]time.Time)
+
+	for _, e := range entries {
+		schedule, err := cron.Parse(e.CronExpression)
+		if err != nil {
+			log.Printf("Scaling schedule: service %s: %v", e.ServiceID, err)
+			continue
+		}
+		loc, err := time.LoadLocation(e.Timezone)
+		if err != nil {
+			log.Printf("Scaling schedule: service %s: invalid time zone %q", e.ServiceID, e.Timezone)
+			continue
+		}
+
+		// The last time the entry fired in the window
+		var last time.Time
+		for t := schedule.Next(since.In(loc)); !t.IsZero() && !t.After(now); t = schedule.Next(t) {
+			last = t
+		}
+		if last.IsZero() {
+			continue
+		}
+
+		if prev, ok := firedAt[e.ServiceID]; !ok || !last.Before(prev) {
+			firedAt[e.ServiceID] = last
+			due[e.ServiceID] = e
+		}
+	}
+	return due
+}
@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/store"
+	"github.com/intelifox/click-deploy/internal/testutil"
+)
+
+func TestScalingScheduleWorker_ProjectClient(t *testing.T) {
+	db, cleanup := testutil.SetupTestDB(t)
+	defer cleanup()
+	testutil.RunMigrations(t, db)
+
+	ctx := context.Background()
+	eu, us := &k8s.Client{}, &k8s.Client{}
+	clients := k8s.NewClientRegistry("eu")
+	clients.Register("eu", eu, nil)
+	clients.Register("us", us, nil)
+	w := NewScalingScheduleWorker(&store.DB{DB: db}, clients)
+
+	inUS := testutil.NewProject(t, db)
+	if _, err := db.Exec(`UPDATE projects SET default_region = 'us' WHERE id = $1`, inUS.ID.String()); err != nil {
+		t.Fatalf("Failed to set project region: %v", err)
+	}
+	withoutRegion := testutil.NewProject(t, db)
+
+	if client, err := w.projectClient(ctx, inUS.ID); err != nil || client != us {
+		t.Errorf("Project in us got client %p (%v), want the us cluster's", client, err)
+	}
+	if client, err := w.projectClient(ctx, withoutRegion.ID); err != nil || client != eu {
+		t.Errorf("Project without a region got client %p (%v), want the default region's", client, err)
+	}
+	if _, err := w.projectClient(ctx, uuid.New()); err == nil {
+		t.Error("Expected an error for a missing project")
+	}
+}
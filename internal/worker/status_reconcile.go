@@ -0,0 +1,160 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/intelifox/click-deploy/internal/config"
+	"github.com/intelifox/click-deploy/internal/cron"
+	"github.com/intelifox/click-deploy/internal/k8s"
+	"github.com/intelifox/click-deploy/internal/store"
+)
+
+// reconciledServiceStatuses are the statuses of deployed services, which the
+// reconciliation may change. Services being built or deployed, or that never were,
+// are left to their deploy.
+var reconciledServiceStatuses = map[string]bool{
+	store.ServiceStatusRunning:  true,
+	store.ServiceStatusDegraded: true,
+	store.ServiceStatusMissing:  true,
+}
+
+// ServiceStatusReconcileWorker keeps the stored status of deployed services in line
+// with their k8s Deployments, which change without a deploy: a Deployment deleted
+// out-of-band leaves its service running in the database. Whenever the schedule
+// fires, the managed Deployments of every project's namespace are listed and compared
+// to its services. Services whose Deployment vanished are flagged missing.
+type ServiceStatusReconcileWorker struct {
+	store      *store.DB
+	config     *config.Config
+	k8sClients *k8s.ClientRegistry
+}
+
+// NewServiceStatusReconcileWorker creates a new service status reconcile worker
+func NewServiceStatusReconcileWorker(store *store.DB, cfg *config.Config, clients *k8s.ClientRegistry) *ServiceStatusReconcileWorker {
+	return &ServiceStatusReconcileWorker{
+		store:      store,
+		config:     cfg,
+		k8sClients: clients,
+	}
+}
+
+// Start reconciles whenever ServiceStatusReconcileSchedule fires until ctx is cancelled
+func (w *ServiceStatusReconcileWorker) Start(ctx context.Context) {
+	if w.k8sClients == nil || w.config.ServiceStatusReconcileSchedule == "" {
+		return
+	}
+	schedule, err := cron.Parse(w.config.ServiceStatusReconcileSchedule)
+	if err != nil {
+		log.Printf("Service status reconcile: invalid schedule, not running: %v", err)
+		return
+	}
+
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			if err := w.Reconcile(ctx); err != nil {
+				log.Printf("Service status reconcile: %v", err)
+			}
+		}
+	}
+}
+
+// Reconcile updates the status of the deployed services of every project. Failures
+// for one project are logged and don't stop the others.
+func (w *ServiceStatusReconcileWorker) Reconcile(ctx context.Context) error {
+	projects, err := w.store.ListAllProjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, project := range projects {
+		if err := w.reconcileProject(ctx, project); err != nil {
+			log.Printf("Service status reconcile: project %s: %v", project.ID, err)
+		}
+	}
+	return nil
+}
+
+// reconcileProject compares a project's deployed services to the Deployments of its
+// namespace, listed with one k8s call
+func (w *ServiceStatusReconcileWorker) reconcileProject(ctx context.Context, project *store.Project) error {
+	services, err := w.store.ListServicesByProject(ctx, project.ID)
+	if err != nil {
+		return err
+	}
+	deployed := make([]*store.Service, 0, len(services))
+	serviceIDs := make([]string, 0, len(services))
+	for _, s := range services {
+		if reconciledServiceStatuses[s.Status] {
+			deployed = append(deployed, s)
+			serviceIDs = append(serviceIDs, s.ID.String())
+		}
+	}
+	if len(deployed) == 0 {
+		return nil
+	}
+
+	client, err := w.k8sClients.Client(ProjectRegion(project))
+	if err != nil {
+		return err
+	}
+	live, err := client.GetDeploymentStatuses(ctx, project.ID.String(), serviceIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range deployed {
+		status := reconciledServiceStatus(live[s.ID.String()])
+		if status == s.Status {
+			continue
+		}
+		if status == store.ServiceStatusMissing {
+			// An imported service runs the deployment it was imported from until its first deploy
+			_, imported, err := w.store.GetServiceImport(ctx, s.ID)
+			if err != nil || imported != "" {
+				continue
+			}
+		}
+
+		changed, err := w.store.ReconcileServiceStatus(ctx, s.ID, s.Status, status)
+		if err != nil {
+			log.Printf("Service status reconcile: service %s: failed to update status: %v", s.ID, err)
+			continue
+		}
+		if !changed {
+			// A deploy changed it meanwhile
+			continue
+		}
+		if status == store.ServiceStatusMissing {
+			log.Printf("Service status reconcile: service %s (%s) of project %s has no Deployment anymore, flagged %s",
+				s.ID, s.Name, project.ID, status)
+			continue
+		}
+		log.Printf("Service status reconcile: service %s (%s) of project %s is %s, was %s", s.ID, s.Name, project.ID, status, s.Status)
+	}
+	return nil
+}
+
+// reconciledServiceStatus returns the stored status of a deployed service whose
+// Deployment is in the live state. A Deployment scaled to zero is running as intended.
+func reconciledServiceStatus(live *k8s.DeploymentStatus) string {
+	if live == nil || !live.Exists {
+		return store.ServiceStatusMissing
+	}
+	switch live.Phase() {
+	case "degraded", "unavailable":
+		return store.ServiceStatusDegraded
+	default:
+		return store.ServiceStatusRunning
+	}
+}
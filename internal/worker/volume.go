@@ -62,6 +62,7 @@ func (w *VolumeWorker) ProcessCreateVolumeJob(ctx context.Context, volumeID uuid
 		Name:       volume.Name,
 		SizeGB:     volumeSizeGB,
 		VolumeType: volume.VolumeType,
+		Metadata:   infra.ManagedMetadata(map[string]string{"volume_id": volume.ID.String()}),
 	}
 
 	openstackVolume, err := client.CreateVolume(ctx, volumeReq)
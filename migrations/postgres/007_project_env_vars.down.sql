@@ -0,0 +1,2 @@
+-- Remove project-level environment variables
+DROP TABLE IF EXISTS project_env_vars;
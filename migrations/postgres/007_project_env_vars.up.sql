@@ -0,0 +1,14 @@
+-- Project-level environment variables, inherited by every service in the project.
+-- Service-level env_vars with the same key take precedence.
+CREATE TABLE IF NOT EXISTS project_env_vars (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id      UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    key             VARCHAR(255) NOT NULL,
+    value           TEXT NOT NULL,
+    is_secret       BOOLEAN DEFAULT false,
+    created_at      TIMESTAMPTZ DEFAULT now(),
+    updated_at      TIMESTAMPTZ DEFAULT now(),
+    UNIQUE(project_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_env_vars_project ON project_env_vars(project_id);
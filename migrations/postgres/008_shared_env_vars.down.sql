@@ -0,0 +1,8 @@
+-- Remove shared (database-linked) project variables
+DROP INDEX IF EXISTS idx_env_vars_project_env_var;
+ALTER TABLE env_vars DROP COLUMN IF EXISTS project_env_var_id;
+
+DELETE FROM project_env_vars WHERE value IS NULL;
+ALTER TABLE project_env_vars DROP COLUMN IF EXISTS link_type;
+ALTER TABLE project_env_vars DROP COLUMN IF EXISTS linked_database_id;
+ALTER TABLE project_env_vars ALTER COLUMN value SET NOT NULL;
@@ -0,0 +1,10 @@
+-- Project-scoped shared variables linked to a managed database.
+-- Database-linked project vars are not inherited automatically; services opt in
+-- by referencing them from env_vars.project_env_var_id.
+ALTER TABLE project_env_vars ALTER COLUMN value DROP NOT NULL;
+ALTER TABLE project_env_vars ADD COLUMN IF NOT EXISTS linked_database_id UUID REFERENCES databases(id) ON DELETE CASCADE;
+ALTER TABLE project_env_vars ADD COLUMN IF NOT EXISTS link_type VARCHAR(50);
+
+ALTER TABLE env_vars ADD COLUMN IF NOT EXISTS project_env_var_id UUID REFERENCES project_env_vars(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_env_vars_project_env_var ON env_vars(project_env_var_id);
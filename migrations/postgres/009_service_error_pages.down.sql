@@ -0,0 +1,2 @@
+-- Remove custom error page from services table
+ALTER TABLE services DROP COLUMN IF EXISTS error_page_html;
@@ -0,0 +1,2 @@
+-- Add custom error page served by Caddy when a service's pods are unavailable
+ALTER TABLE services ADD COLUMN IF NOT EXISTS error_page_html TEXT;
@@ -0,0 +1,2 @@
+-- Remove plan from organizations table
+ALTER TABLE organizations DROP COLUMN IF EXISTS plan;
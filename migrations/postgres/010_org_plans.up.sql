@@ -0,0 +1,2 @@
+-- Add plan to organizations for quota enforcement (NULL = no plan, unlimited)
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS plan VARCHAR(50);
@@ -0,0 +1,4 @@
+-- Remove tag-based deploy triggers
+ALTER TABLE deployments DROP COLUMN IF EXISTS git_tag;
+ALTER TABLE git_sources DROP COLUMN IF EXISTS tag_pattern;
+ALTER TABLE git_sources DROP COLUMN IF EXISTS trigger_mode;
@@ -0,0 +1,4 @@
+-- Add tag-based deploy triggers to git sources and record the deployed tag
+ALTER TABLE git_sources ADD COLUMN IF NOT EXISTS trigger_mode VARCHAR(20) NOT NULL DEFAULT 'branch';
+ALTER TABLE git_sources ADD COLUMN IF NOT EXISTS tag_pattern VARCHAR(255);
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS git_tag VARCHAR(255);
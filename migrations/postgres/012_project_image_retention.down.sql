@@ -0,0 +1,2 @@
+-- Remove per-project registry image retention
+ALTER TABLE projects DROP COLUMN IF EXISTS image_retention_count;
@@ -0,0 +1,2 @@
+-- Add per-project registry image retention (NULL = server default)
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS image_retention_count INT;
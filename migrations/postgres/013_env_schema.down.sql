@@ -0,0 +1,2 @@
+-- Remove per-service environment variable schemas
+DROP TABLE IF EXISTS env_schema;
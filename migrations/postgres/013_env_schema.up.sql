@@ -0,0 +1,14 @@
+-- Per-service environment variable schema, checked against the resolved env before deploying
+CREATE TABLE IF NOT EXISTS env_schema (
+    id          UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    service_id  UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    key         VARCHAR(255) NOT NULL,
+    required    BOOLEAN NOT NULL DEFAULT false,
+    type        VARCHAR(20) NOT NULL DEFAULT 'string', -- string, int, bool, url
+    pattern     TEXT,                                  -- Optional regex the value must match
+    created_at  TIMESTAMPTZ DEFAULT now(),
+    updated_at  TIMESTAMPTZ DEFAULT now(),
+    UNIQUE(service_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_env_schema_service ON env_schema(service_id);
@@ -0,0 +1,6 @@
+-- Remove deployment approvals and the audit log
+DROP TABLE IF EXISTS audit_log;
+ALTER TABLE deployments DROP COLUMN IF EXISTS approved_at;
+ALTER TABLE deployments DROP COLUMN IF EXISTS approved_by;
+ALTER TABLE deployments DROP COLUMN IF EXISTS requested_by;
+ALTER TABLE services DROP COLUMN IF EXISTS requires_approval;
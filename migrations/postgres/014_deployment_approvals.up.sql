@@ -0,0 +1,21 @@
+-- Optional approval gate before a service's deployments start building
+ALTER TABLE services ADD COLUMN IF NOT EXISTS requires_approval BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS requested_by VARCHAR(255);
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS approved_by VARCHAR(255);
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS approved_at TIMESTAMPTZ;
+
+-- Audit log of sensitive actions, scoped to an organization
+CREATE TABLE IF NOT EXISTS audit_log (
+    id              UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    org_id          VARCHAR(255) NOT NULL,
+    actor_id        VARCHAR(255),
+    action          VARCHAR(100) NOT NULL, -- e.g. deployment.approve
+    resource_type   VARCHAR(50) NOT NULL,
+    resource_id     VARCHAR(255) NOT NULL,
+    metadata        JSONB,
+    created_at      TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_org ON audit_log(org_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log(resource_type, resource_id);
@@ -0,0 +1,3 @@
+-- Remove webhook secret rotation
+ALTER TABLE git_sources DROP COLUMN IF EXISTS previous_webhook_secret_expires_at;
+ALTER TABLE git_sources DROP COLUMN IF EXISTS previous_webhook_secret;
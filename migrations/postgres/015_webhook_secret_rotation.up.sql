@@ -0,0 +1,4 @@
+-- Keep the previous webhook secret valid for a grace window after a rotation,
+-- so deliveries signed while the provider is being updated still verify
+ALTER TABLE git_sources ADD COLUMN IF NOT EXISTS previous_webhook_secret TEXT;
+ALTER TABLE git_sources ADD COLUMN IF NOT EXISTS previous_webhook_secret_expires_at TIMESTAMPTZ;
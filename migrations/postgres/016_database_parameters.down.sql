@@ -0,0 +1,2 @@
+-- Remove database parameter overrides
+ALTER TABLE databases DROP COLUMN IF EXISTS parameters;
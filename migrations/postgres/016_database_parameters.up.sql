@@ -0,0 +1,2 @@
+-- Allowlisted engine config overrides (e.g. max_connections), passed to the server on start
+ALTER TABLE databases ADD COLUMN IF NOT EXISTS parameters JSONB NOT NULL DEFAULT '{}';
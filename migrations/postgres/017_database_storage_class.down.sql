@@ -0,0 +1,2 @@
+-- Remove per-database storage class override
+ALTER TABLE databases DROP COLUMN IF EXISTS storage_class;
@@ -0,0 +1,2 @@
+-- Per-database storage class override (NULL uses the cluster default)
+ALTER TABLE databases ADD COLUMN IF NOT EXISTS storage_class VARCHAR(253);
@@ -0,0 +1,2 @@
+-- Remove orphaned cloud resource tracking
+DROP TABLE IF EXISTS orphaned_cloud_resources;
@@ -0,0 +1,13 @@
+-- Cloud resources found without an owning service, database or volume record
+CREATE TABLE IF NOT EXISTS orphaned_cloud_resources (
+    id             UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id      VARCHAR(255) NOT NULL,
+    resource_type  VARCHAR(50) NOT NULL, -- instance, floating_ip, security_group, volume
+    resource_id    VARCHAR(255) NOT NULL,
+    name           VARCHAR(255),
+    first_seen_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE(resource_type, resource_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_orphaned_cloud_resources_tenant ON orphaned_cloud_resources(tenant_id, resource_type);
@@ -0,0 +1,2 @@
+-- Remove organization invitations
+DROP TABLE IF EXISTS org_invitations;
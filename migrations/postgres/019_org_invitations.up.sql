@@ -0,0 +1,15 @@
+-- Invitations to join an organization. The token itself is only handed to the
+-- inviter; like refresh tokens, only its hash is stored.
+CREATE TABLE IF NOT EXISTS org_invitations (
+    id           UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    org_id       UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    email        VARCHAR(255) NOT NULL,
+    role         VARCHAR(50) NOT NULL DEFAULT 'member', -- admin, member
+    token_hash   VARCHAR(255) UNIQUE NOT NULL,
+    invited_by   UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    accepted_at  TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_invitations_org ON org_invitations(org_id);
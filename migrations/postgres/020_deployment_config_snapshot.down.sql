@@ -0,0 +1,2 @@
+-- Remove deployment config snapshots
+ALTER TABLE deployments DROP COLUMN IF EXISTS config_snapshot;
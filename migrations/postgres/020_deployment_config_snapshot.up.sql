@@ -0,0 +1,2 @@
+-- Effective service config (image, env var keys, size, replicas) a deployment rolled out
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS config_snapshot JSONB;
@@ -0,0 +1,2 @@
+-- Remove scheduled scaling
+DROP TABLE IF EXISTS service_scaling_schedules;
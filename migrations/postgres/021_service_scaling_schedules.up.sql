@@ -0,0 +1,12 @@
+-- Time-based scaling: each entry scales a service to a replica count when its cron expression fires
+CREATE TABLE IF NOT EXISTS service_scaling_schedules (
+    id               UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    service_id       UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    cron_expression  VARCHAR(100) NOT NULL, -- minute hour day-of-month month day-of-week
+    replicas         INTEGER NOT NULL,
+    timezone         VARCHAR(64) NOT NULL DEFAULT 'UTC', -- IANA time zone the cron expression is evaluated in
+    position         INTEGER NOT NULL DEFAULT 0, -- Order of the entries within the schedule
+    created_at       TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_scaling_schedules_service ON service_scaling_schedules(service_id);
@@ -0,0 +1,2 @@
+-- Remove per-project image tag strategy
+ALTER TABLE projects DROP COLUMN IF EXISTS image_tag_strategy;
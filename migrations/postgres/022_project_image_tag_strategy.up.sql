@@ -0,0 +1,2 @@
+-- Add per-project image tag strategy (NULL = commit, the default scheme)
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS image_tag_strategy VARCHAR(32);
@@ -0,0 +1,2 @@
+-- Remove readiness-gated custom domain activation
+ALTER TABLE custom_domains DROP COLUMN IF EXISTS awaiting_service_ready;
@@ -0,0 +1,2 @@
+-- Custom domains whose route waits for the service to have a ready pod
+ALTER TABLE custom_domains ADD COLUMN IF NOT EXISTS awaiting_service_ready BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+-- Remove path-based routing of custom domains
+DROP TABLE IF EXISTS custom_domain_routes;
@@ -0,0 +1,12 @@
+-- Path-based routing: requests to a custom domain whose path starts with path_prefix
+-- go to another service; everything else goes to the domain's own service
+CREATE TABLE IF NOT EXISTS custom_domain_routes (
+    id             UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    domain_id      UUID NOT NULL REFERENCES custom_domains(id) ON DELETE CASCADE,
+    path_prefix    VARCHAR(255) NOT NULL, -- e.g. /api, without trailing slash
+    service_id     UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    created_at     TIMESTAMPTZ DEFAULT now(),
+    UNIQUE (domain_id, path_prefix)
+);
+
+CREATE INDEX IF NOT EXISTS idx_custom_domain_routes_service ON custom_domain_routes(service_id);
@@ -0,0 +1,2 @@
+-- Remove the WebSocket/streaming route setting of services
+ALTER TABLE services DROP COLUMN IF EXISTS supports_websockets;
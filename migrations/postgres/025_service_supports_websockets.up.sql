@@ -0,0 +1,2 @@
+-- Route WebSocket/streaming services with upgrade headers, no response buffering and long timeouts
+ALTER TABLE services ADD COLUMN IF NOT EXISTS supports_websockets BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+-- Remove job claim support
+DROP INDEX IF EXISTS idx_jobs_pending_type;
+ALTER TABLE jobs DROP COLUMN IF EXISTS updated_at;
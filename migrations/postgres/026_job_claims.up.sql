@@ -0,0 +1,5 @@
+-- Job claims: the store methods track job changes in updated_at, and workers look up
+-- the oldest pending job of their types
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS idx_jobs_pending_type ON jobs(type, created_at) WHERE status = 'pending';
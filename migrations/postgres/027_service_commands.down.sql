@@ -0,0 +1,3 @@
+-- Remove the custom build and start commands of services
+ALTER TABLE services DROP COLUMN IF EXISTS start_command;
+ALTER TABLE services DROP COLUMN IF EXISTS build_command;
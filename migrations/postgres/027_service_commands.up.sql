@@ -0,0 +1,3 @@
+-- Custom build and start commands, overriding the ones Railpack detects
+ALTER TABLE services ADD COLUMN IF NOT EXISTS build_command TEXT;
+ALTER TABLE services ADD COLUMN IF NOT EXISTS start_command TEXT;
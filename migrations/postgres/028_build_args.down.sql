@@ -0,0 +1,2 @@
+-- Remove per-service build args and secrets
+DROP TABLE IF EXISTS build_args;
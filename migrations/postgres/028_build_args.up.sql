@@ -0,0 +1,15 @@
+-- Per-service build-time args and secrets, kept apart from the runtime env.
+-- Args are passed as --build-arg, secrets are mounted into RUN steps and never
+-- baked into image layers; secret values are encrypted at rest.
+CREATE TABLE IF NOT EXISTS build_args (
+    id          UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    service_id  UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    key         VARCHAR(255) NOT NULL,
+    value       TEXT NOT NULL,                 -- Encrypted when is_secret
+    is_secret   BOOLEAN NOT NULL DEFAULT false,
+    created_at  TIMESTAMPTZ DEFAULT now(),
+    updated_at  TIMESTAMPTZ DEFAULT now(),
+    UNIQUE(service_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_build_args_service ON build_args(service_id);
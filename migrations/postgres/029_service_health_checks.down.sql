@@ -0,0 +1,4 @@
+-- Remove the health check config of services
+ALTER TABLE services DROP COLUMN IF EXISTS default_probe_disabled;
+ALTER TABLE services DROP COLUMN IF EXISTS health_check_initial_delay;
+ALTER TABLE services DROP COLUMN IF EXISTS health_check_path;
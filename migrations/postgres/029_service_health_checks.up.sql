@@ -0,0 +1,6 @@
+-- Health check config of services: an HTTP path probed for readiness and liveness,
+-- or by default a TCP-connect readiness probe on the service port, which services
+-- can opt out of
+ALTER TABLE services ADD COLUMN IF NOT EXISTS health_check_path TEXT;
+ALTER TABLE services ADD COLUMN IF NOT EXISTS health_check_initial_delay INTEGER; -- Seconds, NULL uses the default
+ALTER TABLE services ADD COLUMN IF NOT EXISTS default_probe_disabled BOOLEAN NOT NULL DEFAULT false;
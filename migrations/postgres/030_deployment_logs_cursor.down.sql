@@ -0,0 +1,2 @@
+-- Remove the deployment logs cursor index
+DROP INDEX IF EXISTS idx_deployment_logs_deployment_id;
@@ -0,0 +1,2 @@
+-- Deployment logs are fetched by deployment, after the last ID a client has seen
+CREATE INDEX IF NOT EXISTS idx_deployment_logs_deployment_id ON deployment_logs(deployment_id, id);
@@ -0,0 +1,3 @@
+-- Remove imported deployment tracking
+ALTER TABLE services DROP COLUMN IF EXISTS imported_deployment;
+ALTER TABLE services DROP COLUMN IF EXISTS imported_namespace;
@@ -0,0 +1,3 @@
+-- Deployments imported from outside Zyndra keep running where they are until the service's first deploy
+ALTER TABLE services ADD COLUMN IF NOT EXISTS imported_namespace VARCHAR(253);
+ALTER TABLE services ADD COLUMN IF NOT EXISTS imported_deployment VARCHAR(253);
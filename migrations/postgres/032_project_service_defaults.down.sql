@@ -0,0 +1,3 @@
+-- Remove the project service defaults
+ALTER TABLE projects DROP COLUMN IF EXISTS default_port;
+ALTER TABLE projects DROP COLUMN IF EXISTS default_instance_size;
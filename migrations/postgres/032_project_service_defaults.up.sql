@@ -0,0 +1,3 @@
+-- Instance size and port of new services that don't set theirs
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS default_instance_size VARCHAR(20);
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS default_port INTEGER;
@@ -0,0 +1,2 @@
+-- Remove the secret manager references of env vars
+ALTER TABLE env_vars DROP COLUMN IF EXISTS secret_ref;
@@ -0,0 +1,2 @@
+-- Env vars whose value is read from an external secret manager at deploy time
+ALTER TABLE env_vars ADD COLUMN IF NOT EXISTS secret_ref TEXT;
@@ -0,0 +1,7 @@
+-- Remove the creator and last editor of services, databases and volumes
+ALTER TABLE volumes DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE volumes DROP COLUMN IF EXISTS created_by;
+ALTER TABLE databases DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE databases DROP COLUMN IF EXISTS created_by;
+ALTER TABLE services DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE services DROP COLUMN IF EXISTS created_by;
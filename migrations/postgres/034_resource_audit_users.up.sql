@@ -0,0 +1,7 @@
+-- Users who created and last changed services, databases and volumes
+ALTER TABLE services ADD COLUMN IF NOT EXISTS created_by VARCHAR(255);
+ALTER TABLE services ADD COLUMN IF NOT EXISTS updated_by VARCHAR(255);
+ALTER TABLE databases ADD COLUMN IF NOT EXISTS created_by VARCHAR(255);
+ALTER TABLE databases ADD COLUMN IF NOT EXISTS updated_by VARCHAR(255);
+ALTER TABLE volumes ADD COLUMN IF NOT EXISTS created_by VARCHAR(255);
+ALTER TABLE volumes ADD COLUMN IF NOT EXISTS updated_by VARCHAR(255);
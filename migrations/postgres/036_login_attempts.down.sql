@@ -0,0 +1,2 @@
+-- Remove login attempt tracking
+DROP TABLE IF EXISTS login_attempts;
@@ -0,0 +1,10 @@
+-- Failed login attempts per email and per client IP, shared by all replicas so
+-- lockouts hold whichever one a request lands on
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key                 VARCHAR(320) PRIMARY KEY, -- email:<address> or ip:<address>
+    failures            INTEGER NOT NULL DEFAULT 0,
+    window_started_at   TIMESTAMPTZ NOT NULL,
+    lockouts            INTEGER NOT NULL DEFAULT 0, -- Consecutive lockouts, each doubling the last
+    locked_until        TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ DEFAULT now()
+);
@@ -0,0 +1,3 @@
+-- Remove sign-in tracking and login alert preferences
+DROP TABLE IF EXISTS user_known_devices;
+ALTER TABLE users DROP COLUMN IF EXISTS login_alerts_enabled;
@@ -0,0 +1,13 @@
+-- Users can opt out of emails about new sign-ins and account lockouts
+ALTER TABLE users ADD COLUMN IF NOT EXISTS login_alerts_enabled BOOLEAN NOT NULL DEFAULT true;
+
+-- IPs and devices (user agents) each user has signed in from, so sign-ins from new
+-- ones can be flagged
+CREATE TABLE IF NOT EXISTS user_known_devices (
+    user_id         UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind            VARCHAR(16) NOT NULL, -- ip or user_agent
+    value           TEXT NOT NULL,
+    first_seen_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, kind, value)
+);
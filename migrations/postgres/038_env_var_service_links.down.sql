@@ -0,0 +1,3 @@
+-- Remove the service links of env vars
+DROP INDEX IF EXISTS idx_env_vars_linked_service;
+ALTER TABLE env_vars DROP COLUMN IF EXISTS linked_service_id;
@@ -0,0 +1,4 @@
+-- Env vars linked to another service of the project, resolved at deploy time to its
+-- in-cluster address (link_type service_url or service_host)
+ALTER TABLE env_vars ADD COLUMN IF NOT EXISTS linked_service_id UUID REFERENCES services(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_env_vars_linked_service ON env_vars(linked_service_id);
@@ -0,0 +1,3 @@
+-- Remove the rollback lineage of deployments
+ALTER TABLE deployments DROP COLUMN IF EXISTS rollback_target_id;
+ALTER TABLE deployments DROP COLUMN IF EXISTS rolled_back_from_id;
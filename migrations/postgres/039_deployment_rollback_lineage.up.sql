@@ -0,0 +1,4 @@
+-- For rollback deployments: the deployment that was live when rolling back and the
+-- deployment whose image the rollback redeploys
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS rolled_back_from_id UUID REFERENCES deployments(id) ON DELETE SET NULL;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS rollback_target_id UUID REFERENCES deployments(id) ON DELETE SET NULL;
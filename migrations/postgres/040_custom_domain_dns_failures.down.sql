@@ -0,0 +1,3 @@
+-- Remove custom domain DNS failure tracking
+ALTER TABLE custom_domains DROP COLUMN IF EXISTS status_before_dns_failure;
+ALTER TABLE custom_domains DROP COLUMN IF EXISTS dns_check_failures;
@@ -0,0 +1,4 @@
+-- Consecutive failed DNS re-validations of a custom domain, and the status it had
+-- before enough of them moved it back to pending
+ALTER TABLE custom_domains ADD COLUMN IF NOT EXISTS dns_check_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE custom_domains ADD COLUMN IF NOT EXISTS status_before_dns_failure VARCHAR(50);
@@ -0,0 +1,3 @@
+-- Remove opt-in env var interpolation
+ALTER TABLE project_env_vars DROP COLUMN IF EXISTS interpolate;
+ALTER TABLE env_vars DROP COLUMN IF EXISTS interpolate;
@@ -0,0 +1,4 @@
+-- ${VAR} references are only expanded in the values of vars that opt in, so existing
+-- values, passwords and tokens included, keep being used as they are
+ALTER TABLE env_vars ADD COLUMN IF NOT EXISTS interpolate BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE project_env_vars ADD COLUMN IF NOT EXISTS interpolate BOOLEAN NOT NULL DEFAULT false;
@@ -15,6 +15,8 @@ export default function LogStream({ deploymentId, height = '400px' }: LogStreamP
   const [status, setStatus] = useState<'connecting' | 'live' | 'polling' | 'offline'>('connecting')
   const centrifugeRef = useRef<Centrifuge | null>(null)
   const subRef = useRef<Subscription | null>(null)
+  // ID of the last log line fetched, polling only asks for the lines after it
+  const lastLogIdRef = useRef(0)
 
   const channel = useMemo(() => `deployment:${deploymentId}`, [deploymentId])
 
@@ -22,8 +24,11 @@ export default function LogStream({ deploymentId, height = '400px' }: LogStreamP
     let cancelled = false
 
     async function loadInitial() {
-      const initial = await deploymentsApi.getLogs(deploymentId, 500, 0)
-      if (!cancelled) setLogs(initial)
+      const initial = await deploymentsApi.getLogs(deploymentId, 500)
+      if (!cancelled) {
+        setLogs(initial)
+        if (initial.length > 0) lastLogIdRef.current = initial[initial.length - 1].id
+      }
     }
 
     loadInitial().catch(() => {
@@ -43,8 +48,11 @@ export default function LogStream({ deploymentId, height = '400px' }: LogStreamP
       setStatus('polling')
       pollTimer = setInterval(async () => {
         try {
-          const latest = await deploymentsApi.getLogs(deploymentId, 500, 0)
-          if (!stopped) setLogs(latest)
+          const latest = await deploymentsApi.getLogs(deploymentId, 500, lastLogIdRef.current)
+          if (!stopped && latest.length > 0) {
+            lastLogIdRef.current = latest[latest.length - 1].id
+            setLogs((prev) => [...prev, ...latest])
+          }
         } catch {
           // ignore
         }
@@ -71,6 +79,8 @@ export default function LogStream({ deploymentId, height = '400px' }: LogStreamP
         sub.on('publication', (ctx) => {
           // ctx.data is whatever backend published
           const data: any = ctx.data
+          // Status changes share the channel with log lines
+          if (data.type && data.type !== 'log') return
           // normalize to DeploymentLog shape-ish
           const entry: DeploymentLog = {
             id: Date.now(),
@@ -46,6 +46,17 @@ export interface AuthResponse {
   user: User
 }
 
+// A device signed in to the account
+export interface Session {
+  id: string
+  user_agent?: string
+  ip_address?: string
+  created_at: string
+  last_used_at?: string
+  expires_at: string
+  current: boolean
+}
+
 export interface RegisterRequest {
   email: string
   password: string
@@ -153,6 +164,43 @@ export const authApi = {
     return response.data
   },
 
+  // List the devices signed in to the account
+  async listSessions(): Promise<Session[]> {
+    const response = await authClient.get<{ items: Session[] }>('/auth/sessions', {
+      headers: { Authorization: `Bearer ${this.getAccessToken()}` },
+    })
+    return response.data.items
+  },
+
+  // Sign a device out; its access token lasts until it expires
+  async revokeSession(id: string): Promise<void> {
+    await authClient.delete(`/auth/sessions/${id}`, {
+      headers: { Authorization: `Bearer ${this.getAccessToken()}` },
+    })
+  },
+
+  // Sign out every device but this one
+  async revokeOtherSessions(): Promise<void> {
+    await authClient.delete('/auth/sessions', {
+      headers: { Authorization: `Bearer ${this.getAccessToken()}` },
+    })
+  },
+
+  // Whether new sign-ins and lockouts are emailed to the user
+  async getLoginAlerts(): Promise<boolean> {
+    const response = await authClient.get<{ enabled: boolean }>('/auth/login-alerts', {
+      headers: { Authorization: `Bearer ${this.getAccessToken()}` },
+    })
+    return response.data.enabled
+  },
+
+  async setLoginAlerts(enabled: boolean): Promise<boolean> {
+    const response = await authClient.put<{ enabled: boolean }>('/auth/login-alerts', { enabled }, {
+      headers: { Authorization: `Bearer ${this.getAccessToken()}` },
+    })
+    return response.data.enabled
+  },
+
   // Token management
   setTokens(accessToken: string, refreshToken: string): void {
     if (typeof window === 'undefined') return
@@ -34,22 +34,48 @@ const getApiBaseURL = (): string => {
 export const API_BASE_URL = getApiBaseURL()
 
 export interface ApiError {
-  code: string
+  // The API sends the error code as `error`; `code` is kept for older responses
+  error?: string
+  code?: string
   message: string
   details?: string
+  fields?: ApiFieldError[] // One entry per invalid field of a VALIDATION_ERROR
+}
+
+export interface ApiFieldError {
+  field: string
+  message: string
+}
+
+// Body of every list endpoint
+export interface ListResponse<T> {
+  items: T[]
+  total: number
+  next_cursor?: string // Passed as ?cursor= to get the next page, absent on the last page
 }
 
+// Error codes of domain conditions the UI can explain to the user
+// (see ERROR_HANDLING_COMPLETE.md for the full code set)
+export const ApiErrorCode = {
+  GitNotConnected: 'GIT_NOT_CONNECTED',
+  QuotaExceeded: 'QUOTA_EXCEEDED',
+  ResourceProvisioning: 'RESOURCE_PROVISIONING',
+  DeployInProgress: 'DEPLOY_IN_PROGRESS',
+} as const
+
 export class ApiClientError extends Error {
   code: string
   details?: string
   status?: number
+  fields?: ApiFieldError[]
 
-  constructor(code: string, message: string, details?: string, status?: number) {
+  constructor(code: string, message: string, details?: string, status?: number, fields?: ApiFieldError[]) {
     super(message)
     this.name = 'ApiClientError'
     this.code = code
     this.details = details
     this.status = status
+    this.fields = fields
   }
 }
 
@@ -99,10 +125,11 @@ class ApiClient {
           
           const apiError = error.response.data
           throw new ApiClientError(
-            apiError?.code || 'UNKNOWN_ERROR',
+            apiError?.error || apiError?.code || 'UNKNOWN_ERROR',
             apiError?.message || error.message,
             apiError?.details,
-            error.response.status
+            error.response.status,
+            apiError?.fields
           )
         }
         throw new ApiClientError('NETWORK_ERROR', error.message || 'Network error')
@@ -128,10 +155,19 @@ class ApiClient {
     return this.client.get<T>(url, config).then((res) => res.data)
   }
 
+  // Gets a list endpoint's items
+  getList<T>(url: string, config?: any) {
+    return this.get<ListResponse<T>>(url, config).then((list) => list.items)
+  }
+
   post<T>(url: string, data?: any, config?: any) {
     return this.client.post<T>(url, data, config).then((res) => res.data)
   }
 
+  put<T>(url: string, data?: any, config?: any) {
+    return this.client.put<T>(url, data, config).then((res) => res.data)
+  }
+
   patch<T>(url: string, data?: any, config?: any) {
     return this.client.patch<T>(url, data, config).then((res) => res.data)
   }
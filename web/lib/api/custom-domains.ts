@@ -13,6 +13,7 @@ export interface CustomDomain {
 
 export interface CreateCustomDomainRequest {
   domain: string
+  force?: boolean // route the domain before the service has a ready pod
 }
 
 export interface VerifyDomainResponse {
@@ -21,10 +22,22 @@ export interface VerifyDomainResponse {
   message?: string
 }
 
+// Requests whose path starts with path_prefix go to service_id; the longest prefix wins
+export interface CustomDomainRoute {
+  path_prefix: string // e.g. /api
+  service_id: string
+}
+
+export interface CustomDomainRoutes {
+  domain_id: string
+  default_service_id: string // serves every path no route matches
+  routes: CustomDomainRoute[]
+}
+
 export const customDomainsApi = {
   // List custom domains for a service
   listByService: (serviceId: string) =>
-    apiClient.get<CustomDomain[]>(`/services/${serviceId}/domains`),
+    apiClient.getList<CustomDomain>(`/services/${serviceId}/domains`),
 
   // Add a custom domain to a service
   create: (serviceId: string, data: CreateCustomDomainRequest) =>
@@ -34,6 +47,14 @@ export const customDomainsApi = {
   verify: (domainId: string) =>
     apiClient.post<VerifyDomainResponse>(`/domains/${domainId}/verify`, {}),
 
+  // Get the path routing table of a domain
+  getRoutes: (domainId: string) =>
+    apiClient.get<CustomDomainRoutes>(`/domains/${domainId}/routes`),
+
+  // Replace the path routing table of a domain
+  replaceRoutes: (domainId: string, routes: CustomDomainRoute[]) =>
+    apiClient.put<CustomDomainRoutes>(`/domains/${domainId}/routes`, { routes }),
+
   // Delete a custom domain (backend uses /domains/{id})
   delete: (domainId: string) =>
     apiClient.delete(`/domains/${domainId}`),
@@ -16,6 +16,7 @@ export interface Database {
   password?: string
   database_name?: string
   connection_url?: string
+  storage_class?: string
   status: string
   created_at: string
 }
@@ -37,11 +38,33 @@ export interface CreateDatabaseRequest {
   version?: string
   size?: string
   volume_size_mb?: number
+  storage_class?: string
+  // The engine's defaults (app and admin) when omitted; not supported by redis
+  database_name?: string
+  username?: string
+}
+
+export interface DatabaseParameters {
+  database_id: string
+  engine: string
+  parameters: Record<string, string>
+  allowed_parameters: string[]
+  restarted?: boolean
+}
+
+// The database stays upgrading until the upgrade finishes in the background
+export interface DatabaseUpgrade {
+  database_id: string
+  engine: string
+  from_version: string
+  target_version: string
+  strategy: 'in_place' | 'dump_restore'
+  status: string
 }
 
 export const databasesApi = {
   listByProject: (projectId: string) =>
-    apiClient.get<Database[]>(`/projects/${projectId}/databases`),
+    apiClient.getList<Database>(`/projects/${projectId}/databases`),
 
   get: (id: string) => apiClient.get<Database>(`/databases/${id}`),
 
@@ -52,5 +75,20 @@ export const databasesApi = {
     apiClient.post<Database>(`/projects/${projectId}/databases`, data),
 
   delete: (id: string) => apiClient.delete(`/databases/${id}`),
+
+  getParameters: (id: string) =>
+    apiClient.get<DatabaseParameters>(`/databases/${id}/parameters`),
+
+  replaceParameters: (id: string, parameters: Record<string, string>) =>
+    apiClient.put<DatabaseParameters>(`/databases/${id}/parameters`, { parameters }),
+
+  setParameter: (id: string, name: string, value: string) =>
+    apiClient.put<DatabaseParameters>(`/databases/${id}/parameters/${encodeURIComponent(name)}`, { value }),
+
+  deleteParameter: (id: string, name: string) =>
+    apiClient.delete<DatabaseParameters>(`/databases/${id}/parameters/${encodeURIComponent(name)}`),
+
+  upgrade: (id: string, targetVersion: string) =>
+    apiClient.post<DatabaseUpgrade>(`/databases/${id}/upgrade`, { target_version: targetVersion }),
 }
 
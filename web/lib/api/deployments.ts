@@ -6,15 +6,51 @@ export interface Deployment {
   commit_sha?: string
   commit_message?: string
   commit_author?: string
-  status: 'queued' | 'building' | 'pushing' | 'deploying' | 'success' | 'failed' | 'cancelled'
+  status: 'awaiting_approval' | 'waiting' | 'queued' | 'building' | 'pushing' | 'deploying' | 'success' | 'failed' | 'cancelled'
   image_tag?: string
   build_duration?: number
   deploy_duration?: number
   error_message?: string
-  triggered_by: 'webhook' | 'manual' | 'rollback'
+  triggered_by: 'webhook' | 'manual' | 'rollback' | 'archive'
+  requested_by?: string
+  approved_by?: string
+  approved_at?: string
+  queue_position?: number // Set while waiting for a free deploy slot of the org
   started_at?: string
   finished_at?: string
   created_at: string
+  // For rollbacks, from GET /deployments/:id: the rollback, the deployment it rolled
+  // back from and so on while those are rollbacks too
+  rollback_lineage?: RollbackLineageEntry[]
+}
+
+export interface RollbackLineageEntry {
+  deployment_id: string
+  status: Deployment['status']
+  triggered_by: Deployment['triggered_by']
+  image_tag?: string
+  rolled_back_from_id?: string
+  rollback_target_id?: string // The deployment whose image a rollback redeployed
+  created_at: string
+}
+
+// Effective service config a deployment rolled out
+export interface DeploymentConfig {
+  image: string
+  env_var_keys: string[] // values are left out
+  instance_size: string
+  replicas: number
+  port: number
+  health_check_path?: string
+}
+
+export interface DeploymentDiff {
+  from_deployment_id: string
+  to_deployment_id: string
+  complete: boolean // false if either deployment predates config snapshots
+  changes: { field: string; from: string; to: string }[]
+  env_vars_added: string[]
+  env_vars_removed: string[]
 }
 
 export interface DeploymentLog {
@@ -36,6 +72,10 @@ export interface TriggerDeploymentRequest {
 // Map backend status to UI-friendly status
 export const getStatusDisplay = (status: Deployment['status']): { label: string; color: string } => {
   switch (status) {
+    case 'awaiting_approval':
+      return { label: 'Awaiting approval', color: 'text-amber-500' }
+    case 'waiting':
+      return { label: 'Queued', color: 'text-gray-400' }
     case 'queued':
       return { label: 'Initializing', color: 'text-yellow-500' }
     case 'building':
@@ -60,24 +100,44 @@ export const deploymentsApi = {
   trigger: (serviceId: string, data?: TriggerDeploymentRequest) =>
     apiClient.post<Deployment>(`/services/${serviceId}/deploy`, data || {}),
 
+  // Deploy a .tar.gz of the source instead of the service's git repository
+  deployArchive: (serviceId: string, archive: File) => {
+    const form = new FormData()
+    form.append('archive', archive)
+    return apiClient.post<Deployment>(`/services/${serviceId}/deploy-archive`, form)
+  },
+
   // Get a deployment by ID
   get: (deploymentId: string) =>
     apiClient.get<Deployment>(`/deployments/${deploymentId}`),
 
-  // Get deployment logs
-  getLogs: (deploymentId: string, limit?: number, offset?: number) => {
+  // Get deployment logs. afterId is the ID of the last log line already fetched:
+  // only the lines logged since are returned
+  getLogs: (deploymentId: string, limit?: number, afterId?: number) => {
     const params = new URLSearchParams()
     if (limit) params.append('limit', limit.toString())
-    if (offset) params.append('offset', offset.toString())
+    if (afterId) params.append('after', afterId.toString())
     const queryString = params.toString()
     return apiClient.get<DeploymentLog[]>(`/deployments/${deploymentId}/logs${queryString ? `?${queryString}` : ''}`)
   },
 
+  // Compare the config of another deployment of the same service against this one
+  diff: (deploymentId: string, otherId: string) =>
+    apiClient.get<DeploymentDiff>(`/deployments/${deploymentId}/diff/${otherId}`),
+
   // Cancel a deployment
   cancel: (deploymentId: string) =>
     apiClient.post<void>(`/deployments/${deploymentId}/cancel`),
 
+  // Approve a deployment awaiting approval (must be a different user than the one who triggered it)
+  approve: (deploymentId: string) =>
+    apiClient.post<Deployment>(`/deployments/${deploymentId}/approve`),
+
+  // Reject a deployment awaiting approval
+  reject: (deploymentId: string, reason?: string) =>
+    apiClient.post<Deployment>(`/deployments/${deploymentId}/reject`, { reason }),
+
   // List deployments for a service
   listByService: (serviceId: string, limit?: number) =>
-    apiClient.get<Deployment[]>(`/services/${serviceId}/deployments${limit ? `?limit=${limit}` : ''}`),
+    apiClient.getList<Deployment>(`/services/${serviceId}/deployments${limit ? `?limit=${limit}` : ''}`),
 }
@@ -1,5 +1,12 @@
 import { apiClient } from './client'
 
+// Points at a secret kept in an external secret manager, read when the service deploys
+export interface SecretRef {
+  provider: 'vault'
+  path: string
+  key: string
+}
+
 export interface EnvVar {
   id: string
   service_id: string
@@ -8,6 +15,11 @@ export interface EnvVar {
   is_secret: boolean
   linked_database_id?: string
   link_type?: string
+  secret_ref?: SecretRef
+  // Another service of the project, link_type service_url or service_host
+  linked_service_id?: string
+  // ${VAR} references in the value are expanded at deploy time, never for secrets
+  interpolate: boolean
   created_at: string
 }
 
@@ -17,11 +29,69 @@ export interface CreateEnvVarRequest {
   is_secret?: boolean
   linked_database_id?: string
   link_type?: string
+  secret_ref?: SecretRef
+  linked_service_id?: string
+  interpolate?: boolean
+}
+
+export type EnvSchemaType = 'string' | 'int' | 'bool' | 'url'
+
+export interface EnvSchemaEntry {
+  id: string
+  service_id: string
+  key: string
+  required: boolean
+  type: EnvSchemaType
+  pattern?: string
+  created_at: string
+  updated_at: string
+}
+
+export interface CreateEnvSchemaRequest {
+  key: string
+  required?: boolean
+  type?: EnvSchemaType
+  pattern?: string
+}
+
+export interface EnvValidationResult {
+  valid: boolean
+  violations: { key: string; message: string }[]
+}
+
+// Keys the target already has are kept unless mode is overwrite
+export interface CopyEnvVarsRequest {
+  keys?: string[]
+  mode?: 'skip' | 'overwrite'
+}
+
+export interface CopyEnvVarsResult {
+  env_vars: EnvVar[]
+  copied: string[]
+  skipped: { key: string; reason: string }[]
+}
+
+// Build-time args and secrets, not part of the runtime env. Secret values are
+// write-only: they're returned as ***.
+export interface BuildArg {
+  id: string
+  service_id: string
+  key: string
+  value: string
+  is_secret: boolean
+  created_at: string
+  updated_at: string
+}
+
+export interface CreateBuildArgRequest {
+  key: string
+  value: string
+  is_secret?: boolean
 }
 
 export const envVarsApi = {
   listByService: (serviceId: string) =>
-    apiClient.get<EnvVar[]>(`/services/${serviceId}/env`),
+    apiClient.getList<EnvVar>(`/services/${serviceId}/env`),
 
   create: (serviceId: string, data: CreateEnvVarRequest) =>
     apiClient.post<EnvVar>(`/services/${serviceId}/env`, data),
@@ -31,5 +101,35 @@ export const envVarsApi = {
 
   delete: (serviceId: string, key: string) =>
     apiClient.delete(`/services/${serviceId}/env/${key}`),
+
+  validate: (serviceId: string) =>
+    apiClient.post<EnvValidationResult>(`/services/${serviceId}/env/validate`),
+
+  copyFrom: (serviceId: string, sourceServiceId: string, data: CopyEnvVarsRequest = {}) =>
+    apiClient.post<CopyEnvVarsResult>(`/services/${serviceId}/env/copy-from/${sourceServiceId}`, data),
+
+  listSchema: (serviceId: string) =>
+    apiClient.getList<EnvSchemaEntry>(`/services/${serviceId}/env-schema`),
+
+  createSchemaEntry: (serviceId: string, data: CreateEnvSchemaRequest) =>
+    apiClient.post<EnvSchemaEntry>(`/services/${serviceId}/env-schema`, data),
+
+  updateSchemaEntry: (serviceId: string, key: string, data: Partial<Omit<CreateEnvSchemaRequest, 'key'>>) =>
+    apiClient.patch<EnvSchemaEntry>(`/services/${serviceId}/env-schema/${key}`, data),
+
+  deleteSchemaEntry: (serviceId: string, key: string) =>
+    apiClient.delete(`/services/${serviceId}/env-schema/${key}`),
+
+  listBuildArgs: (serviceId: string) =>
+    apiClient.getList<BuildArg>(`/services/${serviceId}/build-args`),
+
+  createBuildArg: (serviceId: string, data: CreateBuildArgRequest) =>
+    apiClient.post<BuildArg>(`/services/${serviceId}/build-args`, data),
+
+  updateBuildArg: (serviceId: string, key: string, data: Partial<Omit<CreateBuildArgRequest, 'key'>>) =>
+    apiClient.patch<BuildArg>(`/services/${serviceId}/build-args/${key}`, data),
+
+  deleteBuildArg: (serviceId: string, key: string) =>
+    apiClient.delete(`/services/${serviceId}/build-args/${key}`),
 }
 
@@ -1,4 +1,4 @@
-import { apiClient } from './client'
+import { apiClient, ListResponse } from './client'
 
 export interface GitRepository {
   id: number
@@ -16,6 +16,9 @@ export interface GitConnection {
   id: string
   provider: string
   account_name?: string
+  token_expires_at?: string
+  token_valid: boolean
+  status: 'valid' | 'expired'
   created_at: string
 }
 
@@ -35,12 +38,23 @@ export interface TreeEntry {
   size: number
 }
 
+export interface RepositoryTree extends ListResponse<TreeEntry> {
+  truncated: boolean
+  truncated_reason?: 'provider_limit' | 'max_entries'
+}
+
 export interface Branch {
   name: string
   protected: boolean
   commit_sha: string
 }
 
+export interface RotateWebhookSecretResponse {
+  git_source_id: string
+  rotated_at: string
+  previous_secret_valid_until: string
+}
+
 // Helper to get base URL for OAuth redirects
 const getBaseURL = (): string => {
   if (typeof window === 'undefined') return 'http://localhost:8080'
@@ -61,10 +75,11 @@ const getBaseURL = (): string => {
 }
 
 export const gitApi = {
-  listConnections: () => apiClient.get<GitConnection[]>('/git/connections'),
+  listConnections: () => apiClient.getList<GitConnection>('/git/connections'),
 
-  listRepositories: (provider: string = 'github') =>
-    apiClient.get<GitRepository[]>(`/git/repos?provider=${provider}`),
+  // Listings are cached for a minute, refresh bypasses the cache
+  listRepositories: (provider: string = 'github', refresh: boolean = false) =>
+    apiClient.getList<GitRepository>(`/git/repos?provider=${provider}${refresh ? '&refresh=true' : ''}`),
 
   connectGitHub: async (): Promise<Window | null> => {
     // Get OAuth URL via authenticated API call, then open in popup
@@ -114,8 +129,28 @@ export const gitApi = {
     }
   },
 
+  // Re-authorize an existing connection (e.g. after its token was revoked); its services keep their git sources
+  reconnect: async (id: string): Promise<Window | null> => {
+    const response = await apiClient.get<{ auth_url: string; connection_id: string }>(`/git/connections/${id}/reconnect-url`)
+
+    const width = 600
+    const height = 700
+    const left = (window.screen.width - width) / 2
+    const top = (window.screen.height - height) / 2
+
+    return window.open(
+      response.auth_url,
+      'git-reconnect',
+      `width=${width},height=${height},left=${left},top=${top},toolbar=no,menubar=no,scrollbars=yes,resizable=yes`
+    )
+  },
+
   deleteConnection: (id: string) => apiClient.delete(`/git/connections/${id}`),
 
+  // Rotate a service's webhook secret; the old one is still accepted during a grace window
+  rotateWebhookSecret: (serviceId: string) =>
+    apiClient.post<RotateWebhookSecretResponse>(`/services/${serviceId}/git-source/rotate-webhook-secret`),
+
   // GitHub App methods (Railway-style per-repo access)
   getGitHubAppInstallURL: async (): Promise<{ install_url: string; app_name: string }> => {
     const response = await apiClient.get<{ install_url: string; app_name: string }>('/git/app/github/install-url')
@@ -147,19 +182,23 @@ export const gitApi = {
   },
 
   listGitHubAppInstallations: () =>
-    apiClient.get<GitHubAppInstallation[]>('/git/app/github/installations'),
+    apiClient.getList<GitHubAppInstallation>('/git/app/github/installations'),
 
   listGitHubAppInstallationRepos: (installationId: number) =>
-    apiClient.get<GitRepository[]>(`/git/app/github/installations/${installationId}/repos`),
+    apiClient.getList<GitRepository>(`/git/app/github/installations/${installationId}/repos`),
 
-  // Get repository tree (directories and files)
+  // Get the entries of a directory of a repository (not recursive)
   getRepoTree: (owner: string, repo: string, branch: string, path?: string) =>
-    apiClient.get<TreeEntry[]>(`/git/repos/${owner}/${repo}/tree?branch=${branch}${path ? `&path=${encodeURIComponent(path)}` : ''}`),
+    apiClient.getList<TreeEntry>(`/git/repos/${owner}/${repo}/tree?branch=${branch}&recursive=false${path ? `&path=${encodeURIComponent(path)}` : ''}`),
+
+  // Get a page of a repository tree; truncated is set when entries are missing
+  getRepoTreePage: (owner: string, repo: string, branch: string, params?: { path?: string; recursive?: boolean; max_depth?: number; limit?: number; cursor?: string }) =>
+    apiClient.get<RepositoryTree>(`/git/repos/${owner}/${repo}/tree`, { params: { branch, ...params } }),
 
   // Get branches for a repository
   getRepoBranches: async (owner: string, repo: string): Promise<string[]> => {
     try {
-      const branches = await apiClient.get<Branch[]>(`/git/repos/${owner}/${repo}/branches`)
+      const branches = await apiClient.getList<Branch>(`/git/repos/${owner}/${repo}/branches`)
       return branches.map(b => b.name)
     } catch (error) {
       console.error('Failed to fetch branches:', error)
@@ -0,0 +1,51 @@
+import { apiClient } from './client'
+
+export interface Job {
+  id: string
+  type: string
+  status: string
+  attempts: number
+  max_attempts: number
+  error?: string
+  resource_type: string
+  resource_id: string
+  project_id: string
+  created_at: string
+  started_at?: string
+  completed_at?: string
+}
+
+export interface ListJobsParams {
+  type?: string
+  status?: string
+  limit?: number
+}
+
+export const jobsApi = {
+  // List the org's most recent background jobs
+  list: (params: ListJobsParams = {}) => {
+    const query = new URLSearchParams()
+    if (params.type) query.set('type', params.type)
+    if (params.status) query.set('status', params.status)
+    if (params.limit) query.set('limit', String(params.limit))
+    const qs = query.toString()
+    return apiClient.getList<Job>(qs ? `/jobs?${qs}` : '/jobs')
+  },
+
+  get: (id: string) => apiClient.get<Job>(`/jobs/${id}`),
+
+  // Cancel a job no worker has picked up yet
+  cancel: (id: string) => apiClient.post<Job>(`/jobs/${id}/cancel`),
+
+  // Jobs that ran out of attempts, across orgs (admin only)
+  listDeadLetter: (params: Omit<ListJobsParams, 'status'> = {}) => {
+    const query = new URLSearchParams()
+    if (params.type) query.set('type', params.type)
+    if (params.limit) query.set('limit', String(params.limit))
+    const qs = query.toString()
+    return apiClient.getList<Job>(qs ? `/jobs/dead-letter?${qs}` : '/jobs/dead-letter')
+  },
+
+  // Retry a dead-letter job with its attempts reset (admin only)
+  requeue: (id: string) => apiClient.post<Job>(`/jobs/${id}/requeue`),
+}
@@ -15,6 +15,32 @@ export interface MetricsResponse {
   error_rate?: DataPoint[]
 }
 
+export interface InstanceSizeResources {
+  instance_size: string
+  cpu_request: string
+  cpu_limit: string
+  memory_request: string
+  memory_limit: string
+}
+
+export interface RecommendationResponse {
+  service_id: string
+  window: string
+  available: boolean
+  current: InstanceSizeResources
+  usage?: {
+    cpu_p95_cores: number
+    cpu_p99_cores: number
+    memory_p95_bytes: number
+    memory_p99_bytes: number
+  }
+  recommended?: InstanceSizeResources
+  autoscaling?: {
+    target_cpu_utilization: number
+    target_memory_utilization: number
+  }
+}
+
 export const metricsApi = {
   getServiceMetrics: (serviceId: string, start?: string, end?: string, step?: string) => {
     const params = new URLSearchParams()
@@ -45,5 +71,12 @@ export const metricsApi = {
       `/volumes/${volumeId}/metrics?${params.toString()}`
     )
   },
-}
 
+  getServiceRecommendations: (serviceId: string, window?: string) => {
+    const params = new URLSearchParams()
+    if (window) params.append('window', window)
+    return apiClient.get<RecommendationResponse>(
+      `/services/${serviceId}/recommendations?${params.toString()}`
+    )
+  },
+}
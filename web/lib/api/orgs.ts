@@ -0,0 +1,43 @@
+import { apiClient } from './client'
+
+export type OrgRole = 'owner' | 'admin' | 'member'
+
+export interface OrgMember {
+  org_id: string
+  user_id: string
+  role: OrgRole
+  joined_at: string
+  email: string
+  name: string
+}
+
+export interface CreateInvitationRequest {
+  email: string
+  role?: OrgRole // defaults to member
+}
+
+// The token is only returned when the invitation is created
+export interface Invitation {
+  id: string
+  org_id: string
+  email: string
+  role: OrgRole
+  token: string
+  expires_at: string
+}
+
+export const orgsApi = {
+  listMembers: (orgId: string) => apiClient.getList<OrgMember>(`/orgs/${orgId}/members`),
+
+  invite: (orgId: string, data: CreateInvitationRequest) =>
+    apiClient.post<Invitation>(`/orgs/${orgId}/invitations`, data),
+
+  acceptInvitation: (token: string) =>
+    apiClient.post<OrgMember>('/orgs/invitations/accept', { token }),
+
+  updateMemberRole: (orgId: string, userId: string, role: OrgRole) =>
+    apiClient.patch<OrgMember>(`/orgs/${orgId}/members/${userId}`, { role }),
+
+  removeMember: (orgId: string, userId: string) =>
+    apiClient.delete(`/orgs/${orgId}/members/${userId}`),
+}
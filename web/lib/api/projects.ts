@@ -1,4 +1,11 @@
 import { apiClient } from './client'
+import type { CustomDomain } from './custom-domains'
+import type { Database } from './databases'
+import type { Service } from './services'
+import type { Volume } from './volumes'
+
+// How build images are tagged; commit is the default
+export type ImageTagStrategy = 'commit' | 'git_sha' | 'branch_timestamp' | 'git_tag'
 
 export interface Project {
   id: string
@@ -8,31 +15,66 @@ export interface Project {
   casdoor_org_id: string
   openstack_tenant_id?: string
   openstack_network_id?: string
+  image_tag_strategy: ImageTagStrategy
+  // Instance size and port of new services that don't set theirs
+  default_instance_size: string
+  default_port: number
   created_at: string
   updated_at: string
   service_count?: number
 }
 
+export interface ServiceLiveStatus {
+  service_id: string
+  status: string
+  k8s_status: string
+  ready_replicas: number
+  desired_replicas: number
+  health?: 'healthy' | 'crash_looping' | 'oom_killed' | 'unschedulable'
+}
+
+// Everything the project dashboard renders, fetched in one request
+export interface ProjectOverview {
+  project: Project
+  services: (Service & { live: ServiceLiveStatus })[]
+  databases: Database[] // credentials are masked
+  volumes: Volume[]
+  custom_domains: CustomDomain[]
+}
+
 export interface CreateProjectRequest {
   name: string
+  slug?: string // generated from the name when omitted
   description?: string
+  image_tag_strategy?: ImageTagStrategy
+  default_instance_size?: string
+  default_port?: number
 }
 
 export interface UpdateProjectRequest {
   name?: string
   description?: string
+  image_tag_strategy?: ImageTagStrategy | '' // '' resets to commit
+  default_instance_size?: string // '' resets to medium
+  default_port?: number // 0 resets to 8080
 }
 
 export const projectsApi = {
-  list: () => apiClient.get<Project[]>('/projects'),
+  list: () => apiClient.getList<Project>('/projects'),
 
   get: (id: string) => apiClient.get<Project>(`/projects/${id}`),
 
+  overview: (id: string) => apiClient.get<ProjectOverview>(`/projects/${id}/overview`),
+
   create: (data: CreateProjectRequest) => apiClient.post<Project>('/projects', data),
 
   update: (id: string, data: UpdateProjectRequest) =>
     apiClient.patch<Project>(`/projects/${id}`, data),
 
   delete: (id: string) => apiClient.delete(`/projects/${id}`),
+
+  // Requires the owner role in both organizations
+  transfer: (id: string, targetOrgId: string) =>
+    apiClient.post<Project>(`/projects/${id}/transfer`, { target_org_id: targetOrgId }),
 }
 
@@ -9,6 +9,32 @@ export interface SubscriptionTokenResponse {
   token: string
 }
 
+// Published on deployment:<id>, and on service:<id> for status changes
+export interface DeploymentStatusEvent {
+  type: 'status'
+  timestamp: string
+  deployment_id: string
+  service_id: string
+  status: string
+}
+
+// Published on project:<id> every few seconds while someone is subscribed
+export interface ServiceStatusesEvent {
+  type: 'service_statuses'
+  timestamp: string
+  project_id: string
+  services: Array<{
+    service_id: string
+    status: string
+    k8s_status: string
+    ready_replicas: number
+    desired_replicas: number
+    health?: string
+    cpu_cores?: number
+    memory_mb?: number
+  }>
+}
+
 export const realtimeApi = {
   getConnectToken: () => apiClient.get<ConnectTokenResponse>('/realtime/connect-token'),
 
@@ -8,7 +8,7 @@ export interface RollbackCandidate extends Deployment {
 export const rollbackApi = {
   // Get rollback candidates (successful deployments)
   getRollbackCandidates: (serviceId: string) =>
-    apiClient.get<RollbackCandidate[]>(
+    apiClient.getList<RollbackCandidate>(
       `/services/${serviceId}/rollback-candidates`
     ),
 
@@ -9,12 +9,16 @@ export interface Service {
   status: string
   instance_size: string
   port?: number
+  requires_approval: boolean
+  supports_websockets: boolean // routes pass WebSocket upgrades on, unbuffered
   
   // Git source info
   repo_owner?: string
   repo_name?: string
   branch?: string
   root_dir?: string
+  trigger_mode?: 'branch' | 'tag'
+  tag_pattern?: string
   
   // Resource limits
   cpu_limit?: string
@@ -24,6 +28,11 @@ export interface Service {
   start_command?: string
   build_command?: string
   
+  // Health checks: HTTP probes on the path, otherwise a TCP readiness probe on the port
+  health_check_path?: string
+  health_check_initial_delay?: number
+  disable_default_probe: boolean
+  
   // Infrastructure
   openstack_instance_id?: string
   openstack_fip_id?: string
@@ -39,7 +48,12 @@ export interface Service {
   
   // Deployment status
   deployment_status?: 'idle' | 'initializing' | 'building' | 'pushing' | 'deploying' | 'post_deploy' | 'online' | 'failed'
+  // A deployment isn't over yet: disable deploy buttons until it is
+  is_deploying?: boolean
+  active_deployment_id?: string
   
+  created_by?: string // User who created the service
+  updated_by?: string // User who last changed its config
   created_at: string
   updated_at: string
 }
@@ -50,6 +64,8 @@ export interface GitSourceInfo {
   repo_name: string
   branch: string
   root_dir?: string
+  trigger_mode?: 'branch' | 'tag'
+  tag_pattern?: string
 }
 
 export interface CreateServiceRequest {
@@ -60,18 +76,32 @@ export interface CreateServiceRequest {
   git_source?: GitSourceInfo
   canvas_x?: number
   canvas_y?: number
+  requires_approval?: boolean
+  supports_websockets?: boolean
+  start_command?: string
+  build_command?: string
+  health_check_path?: string
+  health_check_initial_delay?: number
+  disable_default_probe?: boolean
 }
 
 export interface UpdateServiceRequest {
   name?: string
   instance_size?: string
   port?: number
+  requires_approval?: boolean
+  supports_websockets?: boolean
   branch?: string
   root_dir?: string
+  trigger_mode?: 'branch' | 'tag'
+  tag_pattern?: string
   cpu_limit?: string
   memory_limit?: string
   start_command?: string
   build_command?: string
+  health_check_path?: string
+  health_check_initial_delay?: number
+  disable_default_probe?: boolean
 }
 
 export interface UpdateServicePositionRequest {
@@ -79,15 +109,78 @@ export interface UpdateServicePositionRequest {
   canvas_y: number
 }
 
+export interface ImportServiceRequest {
+  namespace: string
+  deployment_name: string
+  name?: string // Defaults to the deployment's name
+}
+
+export interface ImportedService extends Service {
+  // Variables set from secrets, config maps or fields, to add before the first deploy
+  skipped_env_vars?: string[]
+}
+
+export interface ServiceSubdomain {
+  subdomain: string;
+  generated_url: string;
+  previous_subdomain?: string;
+}
+
+// What the service's live Deployment runs, read from the cluster; env values are masked
+export interface RuntimeConfig {
+  image: string
+  command?: string[]
+  replicas: number
+  ready_replicas: number
+  updated_replicas: number
+  resources: {
+    cpu_request?: string
+    cpu_limit?: string
+    memory_request?: string
+    memory_limit?: string
+  }
+  env_vars: { key: string; value: string; source: 'literal' | 'secret' | 'configmap' | 'field'; from?: string }[]
+  missing_env_sources?: string[]
+}
+
+export interface PodEvent {
+  type: 'Normal' | 'Warning'
+  reason: string
+  message: string
+  pod_name: string
+  count: number
+  last_seen: string
+}
+
+export interface ScalingScheduleEntry {
+  id?: string
+  cron: string // e.g. "0 8 * * mon-fri"
+  replicas: number
+  timezone?: string // IANA time zone, defaults to UTC
+  next_run?: string
+}
+
+export interface ScalingSchedule {
+  entries: ScalingScheduleEntry[]
+}
+
 export const servicesApi = {
   listByProject: (projectId: string) =>
-    apiClient.get<Service[]>(`/projects/${projectId}/services`),
+    apiClient.getList<Service>(`/projects/${projectId}/services`),
 
   get: (id: string) => apiClient.get<Service>(`/services/${id}`),
 
   create: (projectId: string, data: CreateServiceRequest) =>
     apiClient.post<Service>(`/projects/${projectId}/services`, data),
 
+  // Create several services at once: either all of them are created or none is
+  createBatch: (projectId: string, data: CreateServiceRequest[]) =>
+    apiClient.post<Service[]>(`/projects/${projectId}/services/batch`, data),
+
+  // Bring an existing Kubernetes deployment under management without redeploying it
+  import: (projectId: string, data: ImportServiceRequest) =>
+    apiClient.post<ImportedService>(`/projects/${projectId}/services/import`, data),
+
   update: (id: string, data: UpdateServiceRequest) =>
     apiClient.patch<Service>(`/services/${id}`, data),
 
@@ -99,5 +192,22 @@ export const servicesApi = {
   // Trigger deployment for a service
   triggerDeployment: (serviceId: string, data?: { commit_sha?: string; branch?: string }) =>
     apiClient.post<any>(`/services/${serviceId}/deploy`, data || {}),
-}
 
+  // Kubernetes events of the service's pods, e.g. to explain a stuck deploy
+  listEvents: (serviceId: string, type?: 'Warning') =>
+    apiClient.getList<PodEvent>(`/services/${serviceId}/events`, { params: type ? { type } : undefined }),
+
+  getRuntimeConfig: (serviceId: string) =>
+    apiClient.get<RuntimeConfig>(`/services/${serviceId}/runtime-config`),
+
+  // Omit the subdomain to have a new one generated; the previous one stops routing
+  updateSubdomain: (serviceId: string, subdomain?: string) =>
+    apiClient.post<ServiceSubdomain>(`/services/${serviceId}/subdomain`, subdomain ? { subdomain } : {}),
+
+  getScalingSchedule: (serviceId: string) =>
+    apiClient.get<ScalingSchedule>(`/services/${serviceId}/scaling-schedule`),
+
+  // Replaces the whole schedule; pass no entries to remove it
+  replaceScalingSchedule: (serviceId: string, data: ScalingSchedule) =>
+    apiClient.put<ScalingSchedule>(`/services/${serviceId}/scaling-schedule`, data),
+}
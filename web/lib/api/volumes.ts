@@ -27,7 +27,7 @@ export interface AttachVolumeRequest {
 
 export const volumesApi = {
   listByProject: (projectId: string) =>
-    apiClient.get<Volume[]>(`/projects/${projectId}/volumes`),
+    apiClient.getList<Volume>(`/projects/${projectId}/volumes`),
 
   get: (id: string) => apiClient.get<Volume>(`/volumes/${id}`),
 